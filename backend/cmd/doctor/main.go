@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/database"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/doctor"
+)
+
+// Checks the environment the backend needs (Docker, networks, Traefik,
+// wildcard DNS, the instances directory and the database schema) and prints
+// how to fix what is missing. Exits with status 1 if any check fails.
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	failed := 0
+
+	db, err := database.New(cfg.GetDSN(), database.Options{MaxOpenConns: 2, MaxIdleConns: 1})
+	if err != nil {
+		failed++
+		fmt.Printf("✗ Database: %v\n    → check the DB_* settings and that PostgreSQL is running\n", err)
+		db = nil
+	} else {
+		defer db.Close()
+		fmt.Println("✓ Database connection")
+	}
+
+	dockerClient, dockerErr := docker.NewClient(cfg)
+	if dockerErr == nil {
+		defer dockerClient.Close()
+	}
+
+	for _, result := range doctor.Run(ctx, cfg, db, dockerClient, dockerErr) {
+		if result.OK() {
+			fmt.Printf("✓ %s\n", result.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("✗ %s: %v\n    → %s\n", result.Name, result.Err, result.Hint)
+	}
+
+	if failed > 0 {
+		fmt.Printf("❌ %d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("✅ All checks passed")
+}
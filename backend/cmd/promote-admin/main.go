@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/database"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("Usage: go run cmd/promote-admin/main.go <email> [--revoke]")
+		fmt.Println("Example: go run cmd/promote-admin/main.go admin@example.com")
+		os.Exit(1)
+	}
+
+	email := os.Args[1]
+	isAdmin := !(len(os.Args) > 2 && os.Args[2] == "--revoke")
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Connect to database
+	db, err := database.New(cfg.GetDSN(), database.Options{})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Update the admin flag in database
+	query := `UPDATE users SET is_admin = $1, updated_at = NOW() WHERE email = $2`
+	result, err := db.Exec(query, isAdmin, email)
+	if err != nil {
+		log.Fatalf("Failed to update user: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Fatalf("Failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		log.Fatalf("No user found with email: %s", email)
+	}
+
+	if isAdmin {
+		fmt.Printf("✅ %s is now a platform administrator\n", email)
+	} else {
+		fmt.Printf("✅ Admin access revoked for %s\n", email)
+	}
+}
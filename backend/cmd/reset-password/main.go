@@ -31,7 +31,7 @@ func main() {
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode)
 
 	// Connect to database
-	db, err := database.New(dsn)
+	db, err := database.New(dsn, database.Options{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
+)
+
+// Older releases wrote the PocketBase admin email and password in plaintext into
+// <instances>/<username>/<slug>/entrypoint.sh. This tool rewrites those scripts
+// so they only start the server. The superuser already exists in pb_data, so
+// running instances keep working after a restart.
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	pattern := filepath.Join(cfg.InstancesBasePath, "*", "*", "entrypoint.sh")
+	scripts, err := filepath.Glob(pattern)
+	if err != nil {
+		log.Fatalf("Failed to search for entrypoint scripts: %v", err)
+	}
+
+	scrubbed := 0
+	for _, script := range scripts {
+		storagePath := filepath.Dir(script)
+
+		changed, err := docker.ScrubEntrypointScript(storagePath)
+		if err != nil {
+			log.Printf("✗ %s: %v", storagePath, err)
+			continue
+		}
+
+		if changed {
+			scrubbed++
+			fmt.Printf("✓ Scrubbed credentials from %s\n", script)
+		}
+	}
+
+	fmt.Printf("✅ Checked %d entrypoint scripts, scrubbed %d\n", len(scripts), scrubbed)
+}
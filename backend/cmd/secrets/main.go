@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/database"
+	"pocketploy/internal/secrets"
+)
+
+// secretColumns are the columns holding vault-encrypted secrets
+var secretColumns = []struct {
+	table  string
+	column string
+}{
+	{"instances", "encryption_key"},
+	{"webhook_endpoints", "secret"},
+}
+
+// Manages the secrets vault: generate-key creates a SECRETS_MASTER_KEY,
+// encrypt seals a value (read from stdin) for an encrypted configuration
+// variable, and rotate re-encrypts stored secrets that are in plaintext or
+// under a previous master key.
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("Usage: go run cmd/secrets/main.go <generate-key|encrypt|rotate>")
+		fmt.Println("Example: echo -n 'smtp password' | go run cmd/secrets/main.go encrypt")
+		os.Exit(1)
+	}
+
+	if os.Args[1] == "generate-key" {
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	vault, err := secrets.NewVault(secrets.Config{
+		MasterKey:          cfg.SecretsMasterKey,
+		PreviousMasterKeys: cfg.SecretsPreviousMasterKeys,
+		KMSKeyID:           cfg.SecretsKMSKeyID,
+		KMSRegion:          cfg.SecretsKMSRegion,
+		AWSAccessKeyID:     cfg.AWSAccessKeyID,
+		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
+		AWSSessionToken:    cfg.AWSSessionToken,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize secrets vault: %v", err)
+	}
+	if !vault.Enabled() {
+		log.Fatalf("Set SECRETS_MASTER_KEY or SECRETS_KMS_KEY_ID first")
+	}
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "encrypt":
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && value == "" {
+			log.Fatalf("Failed to read the value from stdin: %v", err)
+		}
+		encrypted, err := vault.Encrypt(ctx, strings.TrimRight(value, "\r\n"))
+		if err != nil {
+			log.Fatalf("Failed to encrypt: %v", err)
+		}
+		fmt.Println(encrypted)
+
+	case "rotate":
+		// Build database DSN
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode)
+
+		// Connect to database
+		db, err := database.New(dsn, database.Options{})
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+
+		for _, target := range secretColumns {
+			rotated, err := rotateColumn(ctx, db, vault, target.table, target.column)
+			if err != nil {
+				log.Fatalf("Failed to rotate %s.%s: %v", target.table, target.column, err)
+			}
+			fmt.Printf("✓ Re-encrypted %d values of %s.%s\n", rotated, target.table, target.column)
+		}
+		fmt.Println("✅ Secrets rotated; keys in SECRETS_PREVIOUS_MASTER_KEYS can now be removed")
+
+	default:
+		log.Fatalf("Unknown command: %s", os.Args[1])
+	}
+}
+
+// rotateColumn re-encrypts the values of a column with the current master
+// key. A value is only replaced if it did not change meanwhile, so the tool
+// can run while the server does.
+func rotateColumn(ctx context.Context, db *database.DB, vault *secrets.Vault, table, column string) (int, error) {
+	var rows []struct {
+		ID    string `db:"id"`
+		Value string `db:"value"`
+	}
+	query := fmt.Sprintf(`SELECT id, %s AS value FROM %s WHERE %s IS NOT NULL`, column, table, column)
+	if err := db.SelectContext(ctx, &rows, query); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	update := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE id = $2 AND %s = $3`, table, column, column)
+	for _, row := range rows {
+		if !vault.NeedsRotation(row.Value) {
+			continue
+		}
+
+		plaintext, err := vault.Decrypt(ctx, row.Value)
+		if err != nil {
+			return rotated, fmt.Errorf("row %s: %w", row.ID, err)
+		}
+		encrypted, err := vault.Encrypt(ctx, plaintext)
+		if err != nil {
+			return rotated, err
+		}
+		if _, err := db.ExecContext(ctx, update, encrypted, row.ID, row.Value); err != nil {
+			return rotated, fmt.Errorf("row %s: %w", row.ID, err)
+		}
+		rotated++
+	}
+
+	return rotated, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/billing"
+	"pocketploy/internal/cache"
+	"pocketploy/internal/captcha"
+	"pocketploy/internal/config"
+	"pocketploy/internal/database"
+	"pocketploy/internal/dns"
+	"pocketploy/internal/events"
+	"pocketploy/internal/geoip"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/mail"
+	"pocketploy/internal/metrics"
+	"pocketploy/internal/models"
+	"pocketploy/internal/objectstore"
+	"pocketploy/internal/repositories"
+	"pocketploy/internal/router"
+	"pocketploy/internal/scanner"
+	"pocketploy/internal/secrets"
+	"pocketploy/internal/services"
+)
+
+// container holds the repositories and services the server is assembled from.
+// Services receive their dependencies as interfaces where it matters
+// (services.InstanceStore, services.ContainerRuntime), so an implementation is
+// swapped here and nowhere else.
+type container struct {
+	runtime  services.ContainerRuntime
+	broker   *events.Broker
+	notifier services.EventNotifier
+	jobQueue *jobs.Queue
+
+	instanceRepo *repositories.InstanceRepository
+
+	authService      *services.AuthService
+	auditService     *services.AuditService
+	abuseService     *services.AbuseService
+	approvalService  *services.AdminApprovalService
+	anomalyDetector  *services.AnomalyDetector
+	deployService    *services.DeployService
+	tokenService     *services.TokenService
+	userService      *services.UserService
+	instanceService  *services.InstanceService
+	inviteService    *services.InviteService
+	platformService  *services.PlatformService
+	statsService     *services.StatsService
+	exportService    *services.ExportService
+	webhookService   *services.WebhookService
+	regionService    *services.RegionService
+	imageService     *services.ImageService
+	dnsService       *services.DNSService
+	bandwidthService *services.BandwidthService
+	usageService     *services.UsageService
+	creditService    *services.CreditService
+	billingService   *services.BillingService  // nil when billing is disabled
+	meteringService  *services.MeteringService // nil when metering is disabled
+	statusMonitor    *services.StatusMonitor
+	cronService      *services.CronService
+	manifestService  *services.ManifestService
+	storageService   *services.StorageService
+	downloadSigner   *services.DownloadSigner
+	readiness        *services.ReadinessChecker
+}
+
+// newContainer creates the repositories and services
+func newContainer(cfg *config.Config, db *database.DB, store cache.Store, runtime services.ContainerRuntime, metricsRegistry *metrics.Registry) (*container, error) {
+	c := &container{runtime: runtime}
+
+	// Envelope encryption for stored secrets; configuration secrets may be
+	// given encrypted and are decrypted here, before anything uses them
+	vault, err := secrets.NewVault(secrets.Config{
+		MasterKey:          cfg.SecretsMasterKey,
+		PreviousMasterKeys: cfg.SecretsPreviousMasterKeys,
+		KMSKeyID:           cfg.SecretsKMSKeyID,
+		KMSRegion:          cfg.SecretsKMSRegion,
+		AWSAccessKeyID:     cfg.AWSAccessKeyID,
+		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
+		AWSSessionToken:    cfg.AWSSessionToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize secrets vault: %w", err)
+	}
+	if !vault.Enabled() {
+		log.Printf("Warning: SECRETS_MASTER_KEY is not set; webhook secrets and encryption keys are stored in plaintext")
+	}
+	err = vault.DecryptAll(context.Background(), &cfg.SMTPPassword, &cfg.StripeSecretKey, &cfg.StripeWebhookSecret,
+		&cfg.CloudflareAPIToken, &cfg.CaptchaSecret, &cfg.MetricsToken, &cfg.AWSSecretAccessKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt configuration secrets: %w", err)
+	}
+
+	// Repositories (Data Access Layer)
+	userRepo := repositories.NewUserRepository(db)
+	tokenRepo := repositories.NewTokenRepository(db)
+	inviteRepo := repositories.NewInviteRepository(db)
+	platformRepo := repositories.NewPlatformRepository(db)
+	statsRepo := repositories.NewStatsRepository(db)
+	instanceRepo := repositories.NewInstanceRepository(db, vault)
+	c.instanceRepo = instanceRepo
+
+	// Captcha verifier (nil when no provider is configured)
+	captchaVerifier, err := captcha.NewVerifier(cfg.CaptchaProvider, cfg.CaptchaSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize captcha: %w", err)
+	}
+
+	// GeoIP database (nil when none is configured)
+	geoLocator, err := geoip.NewLocator(cfg.GeoIPDatabasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize GeoIP: %w", err)
+	}
+
+	// Malware scanner for uploaded bundles (nil when no provider is configured)
+	bundleScanner, err := scanner.NewScanner(cfg.ScannerProvider, cfg.ClamAVAddress, cfg.ScannerTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize scanner: %w", err)
+	}
+
+	// DNS provider for wildcard records (nil when none is configured)
+	dnsProvider, err := dns.NewProvider(cfg.DNSProvider, dns.Credentials{
+		CloudflareAPIToken:  cfg.CloudflareAPIToken,
+		CloudflareZoneID:    cfg.CloudflareZoneID,
+		Route53HostedZoneID: cfg.Route53HostedZoneID,
+		AWSAccessKeyID:      cfg.AWSAccessKeyID,
+		AWSSecretAccessKey:  cfg.AWSSecretAccessKey,
+		AWSSessionToken:     cfg.AWSSessionToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize DNS provider: %w", err)
+	}
+
+	// Bucket provisioning for managed file storage (nil when disabled)
+	storageProvider, err := objectstore.NewProvider(cfg.StorageProvider, objectstore.Config{
+		Region:             cfg.StorageRegion,
+		Endpoint:           cfg.StorageEndpoint,
+		AWSAccessKeyID:     cfg.AWSAccessKeyID,
+		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
+		AWSSessionToken:    cfg.AWSSessionToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage provider: %w", err)
+	}
+
+	// Outgoing email (logged when no SMTP relay is configured)
+	mailer, err := mail.NewMailer(mail.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+
+	// Services (Business Logic Layer)
+	revocationList := services.NewRevocationList(store)
+	c.auditService = services.NewAuditService(db.DB, geoLocator)
+	c.authService = services.NewAuthService(userRepo, tokenRepo, inviteRepo, revocationList, captchaVerifier, geoLocator, store, mailer, c.auditService, cfg)
+	c.tokenService = services.NewTokenService(tokenRepo, revocationList, cfg)
+	c.userService = services.NewUserService(userRepo, c.tokenService, mailer, cfg)
+	operationLimiter := services.NewOperationLimiter(func() int { return cfg.Settings().MaxConcurrentOperationsPerUser }, metricsRegistry)
+
+	// Real-time events for WebSocket clients, shared across replicas via LISTEN/NOTIFY
+	c.broker = events.NewBroker(db.DB)
+	models.ObserveInstanceChanges(func(ctx context.Context, instance *models.Instance, deleted bool) {
+		eventType := events.TypeInstanceUpdated
+		if deleted {
+			eventType = events.TypeInstanceDeleted
+		}
+		c.broker.Publish(ctx, instance.UserID.String(), eventType, instance)
+		if c.webhookService != nil {
+			c.webhookService.InstanceChanged(ctx, instance, deleted)
+		}
+	})
+	c.notifier = services.EventNotifier{Broker: c.broker}
+
+	// Durable background jobs (handlers are registered on the pool in main)
+	c.jobQueue = jobs.NewQueue(db.DB)
+
+	// Instance access: owners, plus read-only access for platform admins
+	authorizer := authz.NewEvaluator(authz.DefaultPolicy, authz.AdminResolver{Checker: c.userService})
+
+	// Regions instances can be placed in (the default one follows BASE_DOMAIN)
+	c.regionService = services.NewRegionService(db.DB, cfg)
+	if err := c.regionService.SyncDefaultRegion(context.Background()); err != nil {
+		return nil, err
+	}
+	c.dnsService = services.NewDNSService(dnsProvider, c.regionService, cfg)
+
+	// Approved images users may run instead of POCKETBASE_IMAGE
+	c.imageService = services.NewImageService(db.DB, runtime, cfg)
+
+	// Confirmation tokens and re-authentication for destructive actions
+	deletionGuard := services.NewDeletionGuard(store, c.userService, cfg)
+
+	c.instanceService = services.NewInstanceService(instanceRepo, runtime, operationLimiter, c.broker, c.notifier, authorizer, c.jobQueue, c.regionService, c.imageService, c.userService, bundleScanner, deletionGuard, cfg)
+	c.inviteService = services.NewInviteService(inviteRepo, cfg)
+	c.platformService = services.NewPlatformService(platformRepo, cfg)
+	if err := c.platformService.LoadSettings(); err != nil {
+		log.Printf("Warning: using environment settings: %v", err)
+	}
+	c.statsService = services.NewStatsService(statsRepo, store, cfg)
+	c.exportService = services.NewExportService(db.DB)
+	c.webhookService = services.NewWebhookService(db.DB, c.jobQueue, store, vault, cfg)
+	c.bandwidthService = services.NewBandwidthService(instanceRepo, userRepo, runtime, c.notifier, cfg)
+	c.usageService = services.NewUsageService(db.DB, instanceRepo, c.userService, c.bandwidthService, c.tokenService, cfg)
+	c.creditService = services.NewCreditService(db.DB, userRepo)
+	if cfg.MeteringEnabled {
+		c.meteringService = services.NewMeteringService(db.DB, cfg.InstanceMetricsInterval)
+	}
+	if cfg.BillingEnabled() {
+		stripeClient := billing.NewClient(cfg.StripeSecretKey, cfg.StripeWebhookSecret)
+		c.billingService = services.NewBillingService(db.DB, stripeClient, userRepo, instanceRepo, c.instanceService, c.creditService, cfg)
+	}
+	c.statusMonitor = services.NewStatusMonitor(db.DB, runtime)
+	c.cronService = services.NewCronService(instanceRepo, runtime, c.instanceService, c.notifier, cfg)
+	c.manifestService = services.NewManifestService(c.instanceService, c.cronService, c.imageService, cfg)
+	c.storageService = services.NewStorageService(db.DB, c.instanceService, storageProvider, cfg)
+	c.downloadSigner = services.NewDownloadSigner(store, cfg)
+	c.abuseService = services.NewAbuseService(db.DB, c.instanceService, userRepo, captchaVerifier, c.notifier, mailer, c.auditService, cfg)
+	c.approvalService = services.NewAdminApprovalService(db.DB, c.instanceService, c.userService, c.auditService, cfg)
+	c.anomalyDetector = services.NewAnomalyDetector(instanceRepo, runtime, c.instanceService, c.auditService, cfg)
+	c.deployService = services.NewDeployService(db.DB, c.instanceService)
+	c.readiness = services.NewReadinessChecker(db, runtime)
+
+	return c, nil
+}
+
+// routes returns the services the router serves requests with
+func (c *container) routes(metricsRegistry *metrics.Registry) router.Services {
+	return router.Services{
+		AuthService:      c.authService,
+		UserService:      c.userService,
+		TokenService:     c.tokenService,
+		InstanceService:  c.instanceService,
+		InviteService:    c.inviteService,
+		BandwidthService: c.bandwidthService,
+		UsageService:     c.usageService,
+		CreditService:    c.creditService,
+		AuditService:     c.auditService,
+		AbuseService:     c.abuseService,
+		ApprovalService:  c.approvalService,
+		AnomalyDetector:  c.anomalyDetector,
+		DeployService:    c.deployService,
+		BillingService:   c.billingService,
+		MeteringService:  c.meteringService,
+		PlatformService:  c.platformService,
+		StatsService:     c.statsService,
+		ExportService:    c.exportService,
+		WebhookService:   c.webhookService,
+		StatusMonitor:    c.statusMonitor,
+		CronService:      c.cronService,
+		ManifestService:  c.manifestService,
+		StorageService:   c.storageService,
+		DownloadSigner:   c.downloadSigner,
+		RegionService:    c.regionService,
+		ImageService:     c.imageService,
+		DNSService:       c.dnsService,
+		Readiness:        c.readiness,
+		JobQueue:         c.jobQueue,
+		Broker:           c.broker,
+		MetricsRegistry:  metricsRegistry,
+	}
+}
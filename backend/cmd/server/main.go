@@ -10,12 +10,18 @@ import (
 	"syscall"
 	"time"
 
+	"pocketploy/internal/cache"
 	"pocketploy/internal/config"
 	"pocketploy/internal/database"
 	"pocketploy/internal/docker"
-	"pocketploy/internal/repositories"
+	"pocketploy/internal/doctor"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/logship"
+	"pocketploy/internal/metrics"
+	"pocketploy/internal/models"
 	"pocketploy/internal/router"
 	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
 )
 
 func main() {
@@ -27,8 +33,20 @@ func main() {
 
 	log.Printf("Starting pocketploy backend in %s mode", cfg.Env)
 
+	// Initialize metrics registry
+	var metricsRegistry *metrics.Registry
+	if cfg.MetricsEnabled {
+		metricsRegistry = metrics.NewRegistry()
+	}
+
 	// Connect to database
-	db, err := database.New(cfg.GetDSN())
+	db, err := database.New(cfg.GetDSN(), database.Options{
+		Metrics:            metricsRegistry,
+		SlowQueryThreshold: cfg.SlowQueryThreshold,
+		MaxOpenConns:       cfg.DBMaxOpenConns,
+		MaxIdleConns:       cfg.DBMaxIdleConns,
+		ConnMaxLifetime:    cfg.DBConnMaxLifetime,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -36,6 +54,25 @@ func main() {
 
 	log.Println("Database connection established")
 
+	// Initialize shared store (Redis when configured, otherwise in-process)
+	var store cache.Store = cache.NewMemoryStore()
+	if cfg.RedisURL != "" {
+		redisStore, err := cache.NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			log.Printf("Warning: %v, falling back to in-process store", err)
+		} else {
+			defer redisStore.Close()
+			store = redisStore
+			log.Println("Redis connection established")
+		}
+	}
+
+	// Enable read-through caching for hot instance lookups
+	models.ConfigureInstanceCache(store, cfg.InstanceCacheTTL)
+
+	// Resolve client IPs through the configured reverse proxies
+	utils.ConfigureTrustedProxies(cfg.TrustedProxies)
+
 	// Initialize Docker client
 	dockerClient, err := docker.NewClient(cfg)
 	if err != nil {
@@ -45,23 +82,133 @@ func main() {
 
 	log.Println("Docker client initialized")
 
-	// Initialize repositories (Data Access Layer)
-	userRepo := repositories.NewUserRepository(db)
-	tokenRepo := repositories.NewTokenRepository(db)
-	// instanceRepo := repositories.NewInstanceRepository(db) // Will be used in Phase 3.4
+	// Create the instance and Traefik networks on first start
+	if err := dockerClient.EnsureNetworks(context.Background()); err != nil {
+		log.Fatalf("Failed to set up Docker networks: %v", err)
+	}
 
-	log.Println("Repositories initialized")
+	// Report environment problems (missing networks, DNS, schema) up front
+	// rather than at the first instance creation; `go run ./cmd/doctor` runs
+	// the same checks on demand
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, result := range doctor.Run(ctx, cfg, db, dockerClient, nil) {
+			if !result.OK() {
+				log.Printf("Warning: startup check %q failed: %v (%s)", result.Name, result.Err, result.Hint)
+			}
+		}
+	}()
 
-	// Initialize services (Business Logic Layer)
-	authService := services.NewAuthService(userRepo, tokenRepo, cfg)
-	userService := services.NewUserService(userRepo, cfg)
-	tokenService := services.NewTokenService(tokenRepo, cfg)
-	instanceService := services.NewInstanceService(db.DB, dockerClient, cfg)
+	// Initialize repositories and services
+	deps, err := newContainer(cfg, db, store, dockerClient, metricsRegistry)
+	if err != nil {
+		log.Fatalf("Failed to initialize services: %v", err)
+	}
 
 	log.Println("Services initialized")
 
+	// Create the wildcard records of the base and region domains
+	if cfg.DNSAutoCreate {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if _, err := deps.dnsService.EnsureWildcards(ctx); err != nil {
+				log.Printf("Warning: failed to set up wildcard DNS records: %v", err)
+			}
+		}()
+	}
+
+	// Start background workers
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Receive events published by other backend processes
+	go deps.broker.Listen(backgroundCtx, cfg.GetDSN())
+
+	// Singleton workers run in one backend process at a time (leader election
+	// through PostgreSQL advisory locks) so replicas do not double-execute them
+	locker := jobs.NewLocker(db.DB)
+
+	// Pre-pull instance images at startup and keep them fresh. This runs in every
+	// process rather than under leader election because each process may talk to
+	// its own Docker host.
+	imageWarmer := services.NewImageWarmer(deps.runtime, cfg.PrepullImageList, cfg.ImagePullInterval, cfg.WarmContainerEnabled)
+	go imageWarmer.Run(backgroundCtx)
+
+	// Apply setting overrides changed through other backend processes
+	go deps.platformService.RunSettingsSync(backgroundCtx)
+
+	// Keep instance egress policies applied across container restarts
+	var containerStarts services.ContainerStartHandler
+	if cfg.EgressFirewallImage != "" {
+		egressEnforcer := services.NewEgressEnforcer(deps.instanceRepo, deps.runtime, deps.instanceService, cfg.EgressEnforceInterval)
+		containerStarts = egressEnforcer
+		go locker.RunAsLeader(backgroundCtx, "egress-enforcer", egressEnforcer.Run)
+	}
+
+	// Watch Docker events for crash-looping instances and container restarts
+	crashMonitor := services.NewCrashMonitor(deps.instanceRepo, deps.runtime, deps.notifier, containerStarts, cfg.CrashLoopMaxRestarts, cfg.CrashLoopWindow)
+	go locker.RunAsLeader(backgroundCtx, "crash-monitor", crashMonitor.Run)
+
+	// Archive instances whose deletion grace period has ended
+	go locker.RunAsLeader(backgroundCtx, "pending-deletion", deps.instanceService.RunPendingDeletionWorker)
+
+	// Check instance databases for corruption (INTEGRITY_CHECK_INTERVAL)
+	go locker.RunAsLeader(backgroundCtx, "integrity-checks", deps.instanceService.RunIntegrityCheckWorker)
+
+	// Record resource usage history for running instances
+	metricsCollector := services.NewMetricsCollector(deps.instanceRepo, deps.runtime, cfg.InstanceMetricsInterval, cfg.InstanceMetricsRetention)
+	go locker.RunAsLeader(backgroundCtx, "metrics-collector", metricsCollector.Run)
+
+	// Record managed bucket usage and delete detached buckets
+	go locker.RunAsLeader(backgroundCtx, "managed-storage", deps.storageService.Run)
+
+	// Queue suspicious instance behavior for review (ANOMALY_CHECK_INTERVAL)
+	go locker.RunAsLeader(backgroundCtx, "anomaly-detector", deps.anomalyDetector.Run)
+
+	// Copy container logs to the configured sink (LOG_SHIPPER)
+	logSink, err := logship.NewSink(logship.Config{Shipper: cfg.LogShipper, LokiURL: cfg.LokiURL, FileMaxSize: cfg.LogShipFileMaxSize})
+	if err != nil {
+		log.Fatalf("Failed to initialize log shipping: %v", err)
+	}
+	logShipper := services.NewLogShipper(deps.instanceRepo, deps.runtime, store, logSink, cfg.LogShipInterval)
+	go locker.RunAsLeader(backgroundCtx, "log-shipper", logShipper.Run)
+
+	// Aggregate Traefik access logs into per-instance request analytics
+	trafficIngester := services.NewTrafficIngester(deps.instanceRepo, cfg.TraefikAccessLogPath, cfg.TrafficIngestInterval)
+	go locker.RunAsLeader(backgroundCtx, "traffic-ingester", trafficIngester.Run)
+
+	// Enforce monthly bandwidth quotas (usage comes from the access log)
+	if cfg.TraefikAccessLogPath != "" {
+		go locker.RunAsLeader(backgroundCtx, "bandwidth-quotas", deps.bandwidthService.Run)
+	}
+
+	// Record hourly usage per user for the metering export
+	if deps.meteringService != nil {
+		go locker.RunAsLeader(backgroundCtx, "metering", deps.meteringService.Run)
+	}
+
+	// Downgrade accounts whose subscription lapsed beyond the grace period
+	if deps.billingService != nil {
+		go locker.RunAsLeader(backgroundCtx, "billing", deps.billingService.Run)
+	}
+
+	// Sample platform health for the public status page
+	go locker.RunAsLeader(backgroundCtx, "status-monitor", deps.statusMonitor.Run)
+
+	// Run user-defined scheduled tasks (each run is claimed atomically, so every replica can take part)
+	go deps.cronService.Run(backgroundCtx)
+
+	// Process background jobs (features register their handlers on the pool)
+	jobPool := jobs.NewPool(deps.jobQueue, cfg.JobWorkers, cfg.JobPollInterval)
+	jobPool.Register(services.JobInstanceCleanup, deps.instanceService.HandleCleanupJob)
+	jobPool.Register(services.JobInstanceBuild, deps.instanceService.HandleBuildJob)
+	jobPool.Register(services.JobWebhookDelivery, deps.webhookService.HandleDeliveryJob)
+	go jobPool.Run(backgroundCtx)
+
 	// Create router with all routes
-	handler := router.New(cfg, db, authService, userService, tokenService, instanceService)
+	handler := router.New(cfg, db, store, deps.routes(metricsRegistry))
 
 	// Configure HTTP server
 	addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
@@ -73,6 +220,19 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Reload limits and policies (config.Settings) on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if _, err := cfg.Reload(); err != nil {
+				log.Printf("Warning: configuration reload failed, keeping current settings: %v", err)
+				continue
+			}
+			log.Println("Configuration reloaded")
+		}
+	}()
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Server starting on http://%s", addr)
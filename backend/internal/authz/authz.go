@@ -0,0 +1,146 @@
+// Package authz decides what a user may do with a resource. Roles come from
+// resolvers (ownership, platform admin, and later collaborators and
+// organizations) and a policy maps each action to the roles allowed to perform it.
+package authz
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoAccess is returned when the user has no role on the resource at all.
+// Callers should report the resource as not found so its existence isn't leaked.
+var ErrNoAccess = errors.New("no access to resource")
+
+// ErrForbidden is returned when the user has a role on the resource that does
+// not allow the action
+var ErrForbidden = errors.New("permission denied")
+
+// Role is a relationship between a user and a resource
+type Role string
+
+const (
+	RoleOwner        Role = "owner"
+	RoleCollaborator Role = "collaborator"
+	RoleOrgAdmin     Role = "org_admin"
+	RoleOrgMember    Role = "org_member"
+	RoleAdmin        Role = "admin" // platform administrator
+)
+
+// Action is an operation on a resource
+type Action string
+
+const (
+	// ActionInstanceView covers reading an instance, its logs, stats and analytics
+	ActionInstanceView Action = "instance:view"
+	// ActionInstanceOperate covers starting, stopping and restarting an instance and its scheduled tasks
+	ActionInstanceOperate Action = "instance:operate"
+	// ActionInstanceManage covers admin credentials, maintenance commands and restoring deletions
+	ActionInstanceManage Action = "instance:manage"
+	// ActionInstanceDelete covers deleting an instance
+	ActionInstanceDelete Action = "instance:delete"
+)
+
+// Policy lists the roles allowed to perform each action
+type Policy map[Action][]Role
+
+// DefaultPolicy is the platform's authorization policy. Platform admins may
+// look at any instance but only owners can change it unless granted a role.
+var DefaultPolicy = Policy{
+	ActionInstanceView:    {RoleOwner, RoleCollaborator, RoleOrgAdmin, RoleOrgMember, RoleAdmin},
+	ActionInstanceOperate: {RoleOwner, RoleCollaborator, RoleOrgAdmin},
+	ActionInstanceManage:  {RoleOwner, RoleOrgAdmin},
+	ActionInstanceDelete:  {RoleOwner},
+}
+
+// allows reports whether role may perform action
+func (p Policy) allows(action Action, role Role) bool {
+	for _, allowed := range p[action] {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource identifies what is being accessed
+type Resource struct {
+	Kind    string
+	ID      uuid.UUID
+	OwnerID uuid.UUID
+}
+
+// RoleResolver returns the roles a user holds on a resource
+type RoleResolver interface {
+	Roles(ctx context.Context, userID uuid.UUID, resource Resource) ([]Role, error)
+}
+
+// Evaluator checks actions against a policy
+type Evaluator struct {
+	policy    Policy
+	resolvers []RoleResolver
+}
+
+// NewEvaluator creates an evaluator; ownership is always resolved, further
+// roles come from the given resolvers in order
+func NewEvaluator(policy Policy, resolvers ...RoleResolver) *Evaluator {
+	return &Evaluator{
+		policy:    policy,
+		resolvers: resolvers,
+	}
+}
+
+// Authorize returns nil if the user may perform the action on the resource,
+// ErrNoAccess if they have no role on it and ErrForbidden otherwise. Resolvers
+// are consulted only until a role allows the action.
+func (e *Evaluator) Authorize(ctx context.Context, userID uuid.UUID, resource Resource, action Action) error {
+	hasRole := false
+
+	if resource.OwnerID == userID {
+		if e.policy.allows(action, RoleOwner) {
+			return nil
+		}
+		hasRole = true
+	}
+
+	for _, resolver := range e.resolvers {
+		roles, err := resolver.Roles(ctx, userID, resource)
+		if err != nil {
+			return err
+		}
+
+		for _, role := range roles {
+			if e.policy.allows(action, role) {
+				return nil
+			}
+			hasRole = true
+		}
+	}
+
+	if hasRole {
+		return ErrForbidden
+	}
+	return ErrNoAccess
+}
+
+// AdminChecker reports whether a user is a platform administrator
+type AdminChecker interface {
+	IsAdmin(userID string) (bool, error)
+}
+
+// AdminResolver grants RoleAdmin on every resource to platform administrators
+type AdminResolver struct {
+	Checker AdminChecker
+}
+
+// Roles returns RoleAdmin if the user is a platform administrator
+func (r AdminResolver) Roles(ctx context.Context, userID uuid.UUID, resource Resource) ([]Role, error) {
+	isAdmin, err := r.Checker.IsAdmin(userID.String())
+	if err != nil || !isAdmin {
+		// Unknown users simply hold no role
+		return nil, nil
+	}
+	return []Role{RoleAdmin}, nil
+}
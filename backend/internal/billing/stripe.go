@@ -0,0 +1,270 @@
+// Package billing talks to Stripe: it creates Checkout sessions for
+// subscriptions and verifies the webhook events Stripe sends back.
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stripeAPI = "https://api.stripe.com/v1"
+
+	// signatureTolerance is how old a webhook event may be, against replays
+	signatureTolerance = 5 * time.Minute
+)
+
+// ErrInvalidSignature is returned for webhook payloads that were not signed
+// with the webhook secret (or are too old)
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Client calls the Stripe API with a secret key
+type Client struct {
+	secretKey     string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+// NewClient creates a Stripe client
+func NewClient(secretKey, webhookSecret string) *Client {
+	return &Client{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// CheckoutParams describes a subscription Checkout session
+type CheckoutParams struct {
+	PriceID    string
+	CustomerID string // reuses an existing customer when set
+	Email      string // prefills the customer email otherwise
+	SuccessURL string
+	CancelURL  string
+
+	// Metadata is copied onto the session and the subscription it creates
+	Metadata map[string]string
+}
+
+// CheckoutSession is a created Checkout session
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// CreateCheckoutSession starts a hosted Checkout for a subscription
+func (c *Client) CreateCheckoutSession(ctx context.Context, params CheckoutParams) (*CheckoutSession, error) {
+	form := url.Values{
+		"mode":                    {"subscription"},
+		"line_items[0][price]":    {params.PriceID},
+		"line_items[0][quantity]": {"1"},
+		"success_url":             {params.SuccessURL},
+		"cancel_url":              {params.CancelURL},
+	}
+	if params.CustomerID != "" {
+		form.Set("customer", params.CustomerID)
+	} else if params.Email != "" {
+		form.Set("customer_email", params.Email)
+	}
+	if userID, ok := params.Metadata["user_id"]; ok {
+		form.Set("client_reference_id", userID)
+	}
+	for key, value := range params.Metadata {
+		form.Set("metadata["+key+"]", value)
+		form.Set("subscription_data[metadata]["+key+"]", value)
+	}
+
+	var session CheckoutSession
+	if err := c.post(ctx, "/checkout/sessions", form, "", &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// InvoiceItemParams describes a line added to a draft invoice
+type InvoiceItemParams struct {
+	Customer    string
+	Invoice     string
+	AmountCents int64 // negative for a credit
+	Currency    string
+	Description string
+
+	// IdempotencyKey makes retries of the same request create the item once
+	IdempotencyKey string
+}
+
+// CreateInvoiceItem adds a line to a draft invoice
+func (c *Client) CreateInvoiceItem(ctx context.Context, params InvoiceItemParams) error {
+	form := url.Values{
+		"customer":    {params.Customer},
+		"invoice":     {params.Invoice},
+		"amount":      {strconv.FormatInt(params.AmountCents, 10)},
+		"currency":    {params.Currency},
+		"description": {params.Description},
+	}
+
+	var item struct {
+		ID string `json:"id"`
+	}
+	return c.post(ctx, "/invoiceitems", form, params.IdempotencyKey, &item)
+}
+
+// post sends a form-encoded request and decodes the JSON response into out
+func (c *Client) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, stripeAPI+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build Stripe request: %w", err)
+	}
+	req.SetBasicAuth(c.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("Stripe API error: %s", apiErr.Error.Message)
+		}
+		return fmt.Errorf("Stripe API error: HTTP %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Stripe response: %w", err)
+	}
+	return nil
+}
+
+// Event is a webhook event; Object holds the resource it is about
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Webhook event types the billing subsystem handles
+const (
+	EventCheckoutCompleted   = "checkout.session.completed"
+	EventSubscriptionCreated = "customer.subscription.created"
+	EventSubscriptionUpdated = "customer.subscription.updated"
+	EventSubscriptionDeleted = "customer.subscription.deleted"
+	EventInvoiceCreated      = "invoice.created"
+)
+
+// CompletedCheckout is the session object of a checkout.session.completed event
+type CompletedCheckout struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Subscription      string            `json:"subscription"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	PaymentStatus     string            `json:"payment_status"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// Subscription is the object of customer.subscription.* events
+type Subscription struct {
+	ID               string            `json:"id"`
+	Customer         string            `json:"customer"`
+	Status           string            `json:"status"`
+	CurrentPeriodEnd int64             `json:"current_period_end"`
+	Metadata         map[string]string `json:"metadata"`
+	Items            struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// PriceID returns the price of the subscription's first item
+func (s *Subscription) PriceID() string {
+	if len(s.Items.Data) == 0 {
+		return ""
+	}
+	return s.Items.Data[0].Price.ID
+}
+
+// Invoice is the object of invoice.* events
+type Invoice struct {
+	ID        string `json:"id"`
+	Customer  string `json:"customer"`
+	Status    string `json:"status"`
+	AmountDue int64  `json:"amount_due"`
+	Currency  string `json:"currency"`
+}
+
+// ParseEvent verifies the Stripe-Signature header of a webhook payload and decodes it
+func (c *Client) ParseEvent(payload []byte, signatureHeader string) (*Event, error) {
+	if err := c.verifySignature(payload, signatureHeader, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook event: %w", err)
+	}
+	return &event, nil
+}
+
+// verifySignature checks "t=<timestamp>,v1=<signature>,...": any v1 entry must
+// be the HMAC-SHA256 of "<timestamp>.<payload>" under the webhook secret
+func (c *Client) verifySignature(payload []byte, header string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
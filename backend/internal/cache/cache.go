@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store is a key/value cache with per-entry expiry
+type Store interface {
+	// Get returns the value for key and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores value under key for ttl (0 means no expiry)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes the given keys, ignoring keys that do not exist
+	Delete(ctx context.Context, keys ...string) error
+
+	// Incr increments a fixed-window counter, starting the window on first use
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// sweepInterval is how many writes happen between expired-entry sweeps
+const sweepInterval = 1000
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStore is an in-process Store used when no shared cache is configured
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	writes  int
+}
+
+// NewMemoryStore creates a new in-process cache store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+// Get returns the value for key if present and not expired
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+// Set stores value under key for ttl
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	s.entries[key] = entry
+
+	// Periodically drop expired entries so unread keys don't pile up
+	s.writes++
+	if s.writes >= sweepInterval {
+		s.writes = 0
+		now := time.Now()
+		for k, e := range s.entries {
+			if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the given keys
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+
+	return nil
+}
+
+// Incr increments the counter at key, starting its expiry window on first use
+func (s *MemoryStore) Incr(_ context.Context, key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	entry, ok := s.entries[key]
+	if ok && (entry.expiresAt.IsZero() || time.Now().Before(entry.expiresAt)) {
+		count, _ = strconv.ParseInt(string(entry.value), 10, 64)
+	} else {
+		entry = memoryEntry{expiresAt: time.Now().Add(window)}
+	}
+
+	count++
+	entry.value = []byte(strconv.FormatInt(count, 10))
+	s.entries[key] = entry
+
+	return count, nil
+}
@@ -0,0 +1,294 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// redisPoolSize is the maximum number of idle connections kept open
+	redisPoolSize = 10
+
+	// redisDialTimeout bounds connection setup
+	redisDialTimeout = 5 * time.Second
+
+	// redisIOTimeout bounds a single command round trip
+	redisIOTimeout = 3 * time.Second
+)
+
+// errRedisNil is returned for RESP null replies (missing keys)
+var errRedisNil = errors.New("redis: nil")
+
+// RedisStore is a Store backed by a Redis server. It speaks the RESP protocol
+// directly and only implements the handful of commands the backend needs.
+type RedisStore struct {
+	addr     string
+	password string
+	db       int
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore connects to the Redis server at rawURL (redis://[:password@]host:port[/db])
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid redis URL")
+	}
+
+	store := &RedisStore{
+		addr: u.Host,
+		idle: make(chan *redisConn, redisPoolSize),
+	}
+
+	if !strings.Contains(store.addr, ":") {
+		store.addr += ":6379"
+	}
+	if u.User != nil {
+		store.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if store.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database number: %s", db)
+		}
+	}
+
+	// Verify the connection up front so misconfiguration is caught at startup
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+	if _, err := store.do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return store, nil
+}
+
+// Get returns the value for key and whether it was found
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if errors.Is(err, errRedisNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected reply to GET")
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for ttl
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+// Delete removes the given keys
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := s.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// incrScript increments a counter and sets its expiry in one step, so a
+// failure between the two can't leave a counter that never expires. Counters
+// without an expiry (from before the script) get one on their next increment.
+const incrScript = `
+local count = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`
+
+// Incr increments the counter at key, starting its expiry window on first use
+func (s *RedisStore) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	// PEXPIRE with 0 would delete the counter instead of expiring it
+	expiry := max(window.Milliseconds(), 1)
+
+	reply, err := s.do(ctx, "EVAL", incrScript, "1", key, strconv.FormatInt(expiry, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply to INCR")
+	}
+
+	return count, nil
+}
+
+// Close closes all idle connections
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends a single command and returns its decoded reply
+func (s *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	c, err := s.getConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(redisIOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+
+	reply, err := c.roundTrip(args)
+	if err != nil && !errors.Is(err, errRedisNil) && !isRedisError(err) {
+		// The connection state is unknown after an I/O error
+		c.conn.Close()
+		return nil, err
+	}
+
+	s.putConn(c)
+	return reply, err
+}
+
+func (s *RedisStore) getConn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	_ = conn.SetDeadline(time.Now().Add(redisIOTimeout))
+
+	if s.password != "" {
+		if _, err := c.roundTrip([]string{"AUTH", s.password}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(s.db)}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (s *RedisStore) putConn(c *redisConn) {
+	select {
+	case s.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// redisError is an error reply sent by the server (the connection stays usable)
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func isRedisError(err error) bool {
+	var re redisError
+	return errors.As(err, &re)
+}
+
+// roundTrip writes a command as a RESP array of bulk strings and reads the reply
+func (c *redisConn) roundTrip(args []string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis answers RESP commands with the replies of a handler and records
+// the commands it received
+type fakeRedis struct {
+	mu       sync.Mutex
+	commands [][]string
+	reply    func(args []string) string
+}
+
+// newFakeRedis starts a fake server and returns a store connected to it
+func newFakeRedis(t *testing.T, reply func(args []string) string) (*fakeRedis, *RedisStore) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{reply: reply}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	store, err := NewRedisStore("redis://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewRedisStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return server, store
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args)
+		f.mu.Unlock()
+
+		reply := "+PONG\r\n"
+		if args[0] != "PING" {
+			reply = f.reply(args)
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisIncr(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		window     time.Duration
+		want       int64
+		wantErr    bool
+		wantExpiry string
+	}{
+		{"first increment", ":1\r\n", time.Minute, 1, false, "60000"},
+		{"sub-millisecond window", ":3\r\n", time.Microsecond, 3, false, "1"},
+		{"error reply", "-ERR script failed\r\n", time.Minute, 0, true, "60000"},
+		{"unexpected reply", "+OK\r\n", time.Minute, 0, true, "60000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, store := newFakeRedis(t, func(args []string) string { return tt.reply })
+
+			count, err := store.Incr(context.Background(), "rate:key", tt.window)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Incr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if count != tt.want {
+				t.Errorf("Incr() = %d, want %d", count, tt.want)
+			}
+
+			// The counter and its expiry are set by a single command
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			last := server.commands[len(server.commands)-1]
+			want := fmt.Sprint([]string{"EVAL", incrScript, "1", "rate:key", tt.wantExpiry})
+			if len(server.commands) != 2 || fmt.Sprint(last) != want {
+				t.Errorf("commands = %q, want PING and %q", server.commands, want)
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net/netip"
 	"os"
+	"path/filepath"
 	"strconv"
-
-	"github.com/joho/godotenv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Config holds all configuration for the application
@@ -24,37 +30,358 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
+	// Connection pool (0 max open connections or lifetime means unlimited)
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+
+	// /health/db reports unhealthy once this fraction of the pool is in use (0 disables)
+	DBPoolHealthThreshold float64
+
+	// Observability Configuration
+	MetricsEnabled     bool
+	MetricsToken       string // bearer token Prometheus scrapes /metrics with
+	SlowQueryThreshold time.Duration
+	RequestLogFormat   string // "text" or "json"
+
 	// JWT Configuration
 	JWTAccessSecret  string
 	JWTRefreshSecret string
-	JWTAccessExpiry  string
-	JWTRefreshExpiry string
+	JWTAccessExpiry  time.Duration
+	JWTRefreshExpiry time.Duration
+
+	// Lifetime of the tokens admins mint to act as a user (not refreshable)
+	ImpersonationTTL time.Duration
+
+	// How long a destructive admin operation waits for a second admin's approval
+	AdminApprovalTTL time.Duration
+
+	// How long a device a user marked as trusted skips the login challenge
+	TrustedDeviceTTL time.Duration
+
+	// Deleting an instance needs a confirmation token (issued for the instance
+	// and consumed by the delete request) and its name typed back
+	DeleteConfirmationRequired bool
+
+	// Deleting an instance or the account needs the account password
+	ReauthRequiredForDeletion bool
 
-	// CORS Configuration
-	AllowedOrigins string
+	// Refresh token delivery: "body" returns it in the JSON response, "cookie"
+	// sets an HttpOnly cookie protected by a CSRF token bound to it
+	RefreshTokenDelivery string
+	CookieDomain         string
+	CookieSecure         bool
+	CookieSameSite       string
+
+	// CORS Configuration (exact origins or https://*.example.com patterns)
+	AllowedOrigins []string
+
+	// Reverse proxies whose X-Forwarded-For header is trusted (addresses or CIDRs)
+	TrustedProxies []netip.Prefix
+
+	// Redis Configuration (optional, falls back to in-process stores)
+	RedisURL string
+
+	// Rate Limit Configuration
+	RateLimitAuthRequests int
+	RateLimitAuthWindow   time.Duration
 
 	// Bcrypt Configuration
 	BcryptCost int
 
+	// Captcha Configuration (optional, "hcaptcha" or "turnstile")
+	CaptchaProvider string
+	CaptchaSecret   string
+	CaptchaSiteKey  string
+
+	// Malware scanning of uploaded bundles (optional, "clamav"). Infected
+	// bundles are moved to QuarantinePath, which must be on the same
+	// filesystem as the instances and is never mounted into containers.
+	ScannerProvider string
+	ClamAVAddress   string
+	ScannerTimeout  time.Duration
+	QuarantinePath  string
+
 	// Docker Configuration
-	DockerHost      string
-	DockerNetwork   string
-	PocketBaseImage string
-	TraefikNetwork  string
+	DockerHost     string
+	DockerNetwork  string
+	TraefikNetwork string
+
+	// Proxies between clients and Traefik (nginx, CDN); instance IP allowlists
+	// read the client address from X-Forwarded-For at this depth
+	TraefikForwardedDepth int
+
+	// Let's Encrypt through Traefik (optional): routers request certificates
+	// from TraefikCertResolver, and instance details report the certificate
+	// found in the resolver's storage file (acme.json) at TraefikACMEPath
+	TraefikCertResolver string
+	TraefikACMEPath     string
+
+	// How instances are reached: "traefik" routes their subdomains through
+	// Traefik, "caddy" and "nginx" write a config fragment per instance to
+	// RoutesDir and run RoutesReloadCommand, "port" publishes each container
+	// on a host port from the range and links to it on InstancePortHost
+	RoutingMode         string
+	RoutesDir           string
+	RoutesReloadCommand string
+	InstancePortMin     int
+	InstancePortMax     int
+	InstancePortHost    string
+
+	// Wildcard DNS automation (optional, "cloudflare" or "route53"): the
+	// records of the base domain and region domains point at DNSTarget, an IP
+	// address or hostname, and are created at startup when DNSAutoCreate is set
+	DNSProvider         string
+	DNSTarget           string
+	DNSAutoCreate       bool
+	CloudflareAPIToken  string
+	CloudflareZoneID    string
+	Route53HostedZoneID string
+	AWSAccessKeyID      string
+	AWSSecretAccessKey  string
+	AWSSessionToken     string // set with temporary credentials
+
+	// Managed S3 file storage (optional, "aws"): instances can store uploads
+	// in their own bucket (named StorageBucketPrefix-<id>) with IAM
+	// credentials limited to it, created with AWS_ACCESS_KEY_ID.
+	// StorageEndpoint overrides the S3 endpoint of StorageRegion; usage is
+	// recorded every StorageUsageInterval.
+	StorageProvider      string
+	StorageBucketPrefix  string
+	StorageRegion        string
+	StorageEndpoint      string
+	StorageUsageInterval time.Duration
+
+	// Secrets encryption: webhook signing secrets and settings encryption keys
+	// are stored encrypted with data keys that are wrapped by SecretsMasterKey
+	// (base64, 32 bytes) or, when SecretsKMSKeyID is set, by that AWS KMS key.
+	// SecretsPreviousMasterKeys only decrypt values written before a rotation.
+	SecretsMasterKey          string
+	SecretsPreviousMasterKeys []string
+	SecretsKMSKeyID           string
+	SecretsKMSRegion          string
+
+	// Image pre-pulling (extra images besides POCKETBASE_IMAGE, refresh interval
+	// with 0 pulling only at startup, and a stopped container that pins each image)
+	PrepullImages        string
+	ImagePullInterval    time.Duration
+	WarmContainerEnabled bool
+
+	// Container Security Configuration
+	ContainerUser            string
+	ContainerUsernsMode      string
+	ContainerReadOnlyRootFS  bool
+	ContainerNoNewPrivileges bool
+	ContainerCapDrop         string
+	ContainerPidsLimit       int64
+
+	// Rotation of the json-file logs Docker keeps for each container
+	// (0 CONTAINER_LOG_MAX_SIZE keeps everything)
+	ContainerLogMaxSize  int64
+	ContainerLogMaxFiles int
+
+	// Copies container logs so they survive container recreation: "file"
+	// appends to logs/container.log in the instance's data directory (rotated
+	// at LOG_SHIP_FILE_MAX_SIZE), "loki" pushes to LOKI_URL, empty disables
+	LogShipper         string
+	LokiURL            string
+	LogShipInterval    time.Duration
+	LogShipFileMaxSize int64
+
+	// SQLite integrity checks of instance databases: the image providing
+	// sqlite3 (also used to copy collections between instances) and how often
+	// every instance is checked (0 disables scheduling)
+	IntegrityCheckImage    string
+	IntegrityCheckInterval time.Duration
+
+	// Outbound network policies of instances, enforced with iptables rules
+	// set in the instance's network namespace by a short-lived container of
+	// EgressFirewallImage (empty disables policies). Rules are re-applied
+	// every EgressEnforceInterval, e.g. after a container restarted or the
+	// addresses of allowed hosts changed.
+	EgressFirewallImage   string
+	EgressEnforceInterval time.Duration
+
+	// Custom PocketBase builds from users' Go hooks modules: the Go toolchain
+	// image the module is compiled in, the image the binary is layered onto,
+	// limits of a build, and where uploaded sources are kept
+	BuildsEnabled      bool
+	BuilderImage       string
+	BuildBaseImage     string
+	BuildTimeout       time.Duration
+	BuildMemory        int64
+	BuildSourceMaxSize int64
+	BuildsPath         string
 
 	// Instance Configuration
-	BaseDomain          string
-	InstancesBasePath   string
+	BaseDomain        string
+	InstancesBasePath string
+	InstanceCacheTTL  time.Duration
+
+	// Comma-separated extra directories (e.g. mounts of other disks) that
+	// administrators may move instance data to
+	StorageVolumes string
+
+	// Free space kept in reserve on the volume an instance is created on,
+	// restored on or relocated to (0 disables the check)
+	MinFreeDiskSpace int64
+
+	// Largest pb_hooks, pb_migrations and pb_public bundles an instance may upload (compressed and extracted)
+	HooksMaxSize      int64
+	MigrationsMaxSize int64
+	PublicMaxSize     int64
+
+	// Where resized profile pictures are stored and the largest upload accepted
+	AvatarsPath   string
+	AvatarMaxSize int64
+
+	// Resource usage history (sampling interval, 0 disables, and how long samples are kept)
+	InstanceMetricsInterval  time.Duration
+	InstanceMetricsRetention time.Duration
+
+	// Anomaly detection over the usage history (check interval, 0 disables):
+	// instances whose CPU use averages AnomalyCPUPercent (of one core) over
+	// AnomalyWindow after being far below it, whose outbound traffic exceeds
+	// AnomalyEgressRate bytes per second, or whose data grows by more than
+	// AnomalyDiskGrowth within the window are queued for admin review, and
+	// limited to AnomalyThrottleCPUs cores when AnomalyAutoThrottle is on
+	AnomalyCheckInterval time.Duration
+	AnomalyWindow        time.Duration
+	AnomalyCPUPercent    int
+	AnomalyEgressRate    int64
+	AnomalyDiskGrowth    int64
+	AnomalyAutoThrottle  bool
+	AnomalyThrottleCPUs  float64
+
+	// Request analytics from the Traefik JSON access log (empty path disables)
+	TraefikAccessLogPath  string
+	TrafficIngestInterval time.Duration
+
+	// How often bandwidth quotas are enforced (0 disables enforcement)
+	BandwidthCheckInterval time.Duration
+
+	// Crash-loop detection (an instance that crashes N times within the window is marked failed)
+	CrashLoopMaxRestarts int
+	CrashLoopWindow      time.Duration
+
+	// Background job queue (workers per process, 0 disables job processing)
+	JobWorkers      int
+	JobPollInterval time.Duration
+
+	// Hourly usage metering from the resource samples, exported for billing
+	MeteringEnabled bool
+
+	// Stripe billing (disabled without a secret key): the Stripe price of each
+	// paid plan, where Checkout returns to, and how long a lapsed subscription
+	// keeps its plan before the account is downgraded
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripePlanPrices    map[string]string
+	BillingSuccessURL   string
+	BillingCancelURL    string
+	BillingGracePeriod  time.Duration
+
+	// Outgoing email (without SMTP_HOST messages are only logged), and the
+	// frontend page confirmation links point to (?token= is appended)
+	SMTPHost              string
+	SMTPPort              int
+	SMTPUsername          string
+	SMTPPassword          string
+	SMTPFrom              string
+	EmailChangeConfirmURL string
+	EmailChangeTTL        time.Duration
+
+	// Email users when they log in from a device (IP address and user agent)
+	// not seen on their account before, with a link to the frontend page that
+	// signs that session out (?token= is appended)
+	LoginNotificationsEnabled bool
+	SessionRevokeURL          string
+
+	// MaxMind database (GeoLite2/GeoIP2 Country or City, .mmdb) used to show
+	// where sessions and audited actions came from; empty disables lookups
+	GeoIPDatabasePath string
+
+	// Outgoing webhooks: per-request timeout, and whether endpoints may
+	// resolve to loopback or private addresses (only for development)
+	WebhookTimeout             time.Duration
+	WebhookAllowPrivateTargets bool
+
+	// Lifetime of the signed URLs backups and exports are downloaded with,
+	// so browsers fetch them without a bearer token
+	DownloadURLTTL time.Duration
+
+	// settings holds the values that can change while the server runs: the
+	// environment (envSettings) with the administrators' overrides applied
+	settings    atomic.Pointer[Settings]
+	settingsMu  sync.Mutex
+	envSettings *Settings
+	overrides   Overrides
+}
+
+// Routing modes (ROUTING_MODE)
+const (
+	RoutingModeTraefik = "traefik"
+	RoutingModeCaddy   = "caddy"
+	RoutingModeNginx   = "nginx"
+	RoutingModePort    = "port"
+)
+
+// Settings are the limits and policies that are re-read on reload (SIGHUP or
+// POST /api/v1/admin/config/reload). Everything else in Config needs a restart.
+// A Settings value is never modified once published, so it can be read without locks.
+type Settings struct {
+	// Signup
+	SignupEnabled                bool
+	SignupRequireInvite          bool
+	ReservedNames                string // Extra comma-separated usernames/slugs to reserve
+	CaptchaLoginFailureThreshold int
+
+	// Instances
+	PocketBaseImage     string
 	MaxInstancesPerUser int
+
+	// Instance limit per plan (plans not listed get MaxInstancesPerUser)
+	PlanInstanceLimits map[string]int
+
+	// Days an archived instance's data is kept before cleanup (users may request less)
+	InstanceDataRetentionDays int
+
+	// How long a deleted instance can be restored before it is archived (0 disables)
+	InstanceDeletionGracePeriod time.Duration
+
+	// Longest lifetime an expiring (ephemeral) instance may be created with or extended to
+	InstanceMaxTTL time.Duration
+
+	// Maximum concurrent create/delete/start/stop operations per user (0 disables the limit)
+	MaxConcurrentOperationsPerUser int
+
+	// Scheduled tasks allowed per instance (0 disables scheduled tasks)
+	MaxCronsPerInstance int
+
+	// Monthly bandwidth quota in bytes per plan (plans not listed are unlimited)
+	PlanBandwidthQuotas      map[string]int64
+	BandwidthWarningPercent  int
+	BandwidthSuspendOnExceed bool
+
+	// Request logging: "none", "errors" (status >= 400), "info" or "debug"
+	// (adds redacted headers and JSON bodies). The sample rate is the fraction
+	// of successful requests logged; errors are always logged.
+	RequestLogLevel      string
+	RequestLogSampleRate float64
 }
 
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
+	// Remember which variables come from the real environment: they take
+	// precedence over .env, also when the file is re-read on reload
+	recordEnvironment()
+
 	// Load .env file if it exists (ignore error in production)
-	if err := godotenv.Load(); err != nil {
+	if err := loadEnvFile(); err != nil {
 		log.Println("Warning: .env file not found, using environment variables")
 	}
 
+	p := &envParser{}
 	config := &Config{
 		// Server Configuration
 		Port: getEnv("PORT", "8080"),
@@ -69,38 +396,334 @@ func Load() (*Config, error) {
 		DBName:     getEnv("DB_NAME", "pocketploy"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 
+		DBMaxOpenConns:        getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:        getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
+		DBConnMaxLifetime:     p.duration("DB_CONN_MAX_LIFETIME", "5m"),
+		DBPoolHealthThreshold: p.fraction("DB_POOL_HEALTH_THRESHOLD", "0.9"),
+
+		// Observability Configuration
+		MetricsEnabled:     getEnvAsBool("METRICS_ENABLED", false),
+		MetricsToken:       getEnv("METRICS_TOKEN", ""),
+		SlowQueryThreshold: p.duration("SLOW_QUERY_THRESHOLD", "200ms"),
+		RequestLogFormat:   strings.ToLower(getEnv("REQUEST_LOG_FORMAT", "text")),
+
 		// JWT Configuration
 		JWTAccessSecret:  getEnv("JWT_ACCESS_SECRET", ""),
 		JWTRefreshSecret: getEnv("JWT_REFRESH_SECRET", ""),
-		JWTAccessExpiry:  getEnv("JWT_ACCESS_EXPIRY", "15m"),
-		JWTRefreshExpiry: getEnv("JWT_REFRESH_EXPIRY", "168h"),
+		JWTAccessExpiry:  p.duration("JWT_ACCESS_EXPIRY", "15m"),
+		JWTRefreshExpiry: p.duration("JWT_REFRESH_EXPIRY", "7d"),
+		ImpersonationTTL: p.duration("IMPERSONATION_TTL", "30m"),
+		AdminApprovalTTL: p.duration("ADMIN_APPROVAL_TTL", "24h"),
+		TrustedDeviceTTL: p.duration("TRUSTED_DEVICE_TTL", "30d"),
+
+		DeleteConfirmationRequired: getEnvAsBool("DELETE_CONFIRMATION_REQUIRED", false),
+		ReauthRequiredForDeletion:  getEnvAsBool("REAUTH_REQUIRED_FOR_DELETION", false),
+
+		RefreshTokenDelivery: strings.ToLower(getEnv("REFRESH_TOKEN_DELIVERY", "body")),
+		CookieDomain:         getEnv("COOKIE_DOMAIN", ""),
+		CookieSecure:         getEnvAsBool("COOKIE_SECURE", true),
+		CookieSameSite:       strings.ToLower(getEnv("COOKIE_SAMESITE", "strict")),
 
 		// CORS Configuration
-		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:3000"),
+		AllowedOrigins: p.origins("ALLOWED_ORIGINS", "http://localhost:3000"),
+
+		// Loopback and private networks, where the nginx/Traefik containers run
+		TrustedProxies: p.prefixes("TRUSTED_PROXIES", "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"),
+
+		// Redis Configuration
+		RedisURL: getEnv("REDIS_URL", ""),
+
+		// Rate Limit Configuration
+		RateLimitAuthRequests: getEnvAsInt("RATE_LIMIT_AUTH_REQUESTS", 10),
+		RateLimitAuthWindow:   p.duration("RATE_LIMIT_AUTH_WINDOW", "1m"),
 
 		// Bcrypt Configuration
 		BcryptCost: getEnvAsInt("BCRYPT_COST", 12),
 
+		// Captcha Configuration
+		CaptchaProvider: getEnv("CAPTCHA_PROVIDER", ""),
+		CaptchaSecret:   getEnv("CAPTCHA_SECRET", ""),
+		CaptchaSiteKey:  getEnv("CAPTCHA_SITE_KEY", ""),
+
+		// Malware scanning
+		ScannerProvider: getEnv("SCANNER_PROVIDER", ""),
+		ClamAVAddress:   getEnv("CLAMAV_ADDRESS", "localhost:3310"),
+		ScannerTimeout:  p.duration("SCANNER_TIMEOUT", "30s"),
+		QuarantinePath:  getEnv("QUARANTINE_PATH", "./quarantine"),
+
 		// Docker Configuration
-		DockerHost:      getEnv("DOCKER_HOST", "unix:///var/run/docker.sock"),
-		DockerNetwork:   getEnv("DOCKER_NETWORK", "pocketploy-network"),
-		PocketBaseImage: getEnv("POCKETBASE_IMAGE", "ghcr.io/muchobien/pocketbase:latest"),
-		TraefikNetwork:  getEnv("TRAEFIK_NETWORK", "pocketploy-network"),
+		DockerHost:     getEnv("DOCKER_HOST", "unix:///var/run/docker.sock"),
+		DockerNetwork:  getEnv("DOCKER_NETWORK", "pocketploy-network"),
+		TraefikNetwork: getEnv("TRAEFIK_NETWORK", "pocketploy-network"),
+
+		TraefikForwardedDepth: getEnvAsInt("TRAEFIK_FORWARDED_DEPTH", 1),
+
+		TraefikCertResolver: getEnv("TRAEFIK_CERT_RESOLVER", ""),
+		TraefikACMEPath:     getEnv("TRAEFIK_ACME_PATH", ""),
+
+		RoutingMode:         strings.ToLower(getEnv("ROUTING_MODE", RoutingModeTraefik)),
+		RoutesDir:           getEnv("ROUTES_DIR", "./routes"),
+		RoutesReloadCommand: getEnv("ROUTES_RELOAD_COMMAND", ""),
+		InstancePortMin:     getEnvAsInt("INSTANCE_PORT_MIN", 20000),
+		InstancePortMax:     getEnvAsInt("INSTANCE_PORT_MAX", 20999),
+
+		// DNS automation
+		DNSProvider:         strings.ToLower(getEnv("DNS_PROVIDER", "")),
+		DNSTarget:           getEnv("DNS_TARGET", ""),
+		DNSAutoCreate:       getEnvAsBool("DNS_AUTO_CREATE", false),
+		CloudflareAPIToken:  getEnv("CLOUDFLARE_API_TOKEN", ""),
+		CloudflareZoneID:    getEnv("CLOUDFLARE_ZONE_ID", ""),
+		Route53HostedZoneID: getEnv("ROUTE53_HOSTED_ZONE_ID", ""),
+		AWSAccessKeyID:      getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey:  getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:     getEnv("AWS_SESSION_TOKEN", ""),
+
+		// Managed file storage
+		StorageProvider:      strings.ToLower(getEnv("STORAGE_PROVIDER", "")),
+		StorageBucketPrefix:  strings.ToLower(getEnv("STORAGE_BUCKET_PREFIX", "pocketploy")),
+		StorageRegion:        getEnv("STORAGE_REGION", "us-east-1"),
+		StorageEndpoint:      strings.TrimSuffix(getEnv("STORAGE_ENDPOINT", ""), "/"),
+		StorageUsageInterval: p.duration("STORAGE_USAGE_INTERVAL", "1h"),
+
+		// Secrets encryption
+		SecretsMasterKey:          getEnv("SECRETS_MASTER_KEY", ""),
+		SecretsPreviousMasterKeys: p.list("SECRETS_PREVIOUS_MASTER_KEYS"),
+		SecretsKMSKeyID:           getEnv("SECRETS_KMS_KEY_ID", ""),
+		SecretsKMSRegion:          getEnv("SECRETS_KMS_REGION", "us-east-1"),
+
+		PrepullImages:        getEnv("PREPULL_IMAGES", ""),
+		ImagePullInterval:    p.duration("IMAGE_PULL_INTERVAL", "6h"),
+		WarmContainerEnabled: getEnvAsBool("WARM_CONTAINER_ENABLED", false),
+
+		// Container Security Configuration
+		ContainerUser:            getEnv("CONTAINER_USER", "1000:1000"),
+		ContainerUsernsMode:      getEnv("CONTAINER_USERNS_MODE", ""),
+		ContainerReadOnlyRootFS:  getEnvAsBool("CONTAINER_READ_ONLY_ROOTFS", true),
+		ContainerNoNewPrivileges: getEnvAsBool("CONTAINER_NO_NEW_PRIVILEGES", true),
+		ContainerCapDrop:         getEnv("CONTAINER_CAP_DROP", "ALL"),
+		ContainerPidsLimit:       int64(getEnvAsInt("CONTAINER_PIDS_LIMIT", 256)),
+
+		// Container logs
+		ContainerLogMaxSize:  p.size("CONTAINER_LOG_MAX_SIZE", "10MB"),
+		ContainerLogMaxFiles: getEnvAsInt("CONTAINER_LOG_MAX_FILES", 3),
+		LogShipper:           getEnv("LOG_SHIPPER", ""),
+		LokiURL:              getEnv("LOKI_URL", ""),
+		LogShipInterval:      p.duration("LOG_SHIP_INTERVAL", "30s"),
+		LogShipFileMaxSize:   p.size("LOG_SHIP_FILE_MAX_SIZE", "50MB"),
+
+		// Database integrity checks
+		IntegrityCheckImage:    getEnv("INTEGRITY_CHECK_IMAGE", "keinos/sqlite3:latest"),
+		IntegrityCheckInterval: p.duration("INTEGRITY_CHECK_INTERVAL", "7d"),
+
+		EgressFirewallImage:   getEnv("EGRESS_FIREWALL_IMAGE", "nicolaka/netshoot:latest"),
+		EgressEnforceInterval: p.duration("EGRESS_ENFORCE_INTERVAL", "30s"),
+
+		// Custom builds
+		BuildsEnabled:      getEnvAsBool("BUILDS_ENABLED", false),
+		BuilderImage:       getEnv("BUILDER_IMAGE", "golang:1.23-alpine"),
+		BuildBaseImage:     getEnv("BUILD_BASE_IMAGE", "alpine:3.20"),
+		BuildTimeout:       p.duration("BUILD_TIMEOUT", "10m"),
+		BuildMemory:        p.size("BUILD_MEMORY", "2GB"),
+		BuildSourceMaxSize: p.size("BUILD_SOURCE_MAX_SIZE", "10MB"),
+		BuildsPath:         getEnv("BUILDS_PATH", "./builds"),
 
 		// Instance Configuration
-		BaseDomain:          getEnv("BASE_DOMAIN", "127.0.0.1.nip.io"),
-		InstancesBasePath:   getEnv("INSTANCES_BASE_PATH", "./instances"),
-		MaxInstancesPerUser: getEnvAsInt("MAX_INSTANCES_PER_USER", 5),
+		BaseDomain:        getEnv("BASE_DOMAIN", "127.0.0.1.nip.io"),
+		InstancesBasePath: getEnv("INSTANCES_BASE_PATH", "./instances"),
+		InstanceCacheTTL:  p.duration("INSTANCE_CACHE_TTL", "30s"),
+		StorageVolumes:    getEnv("STORAGE_VOLUMES", ""),
+		MinFreeDiskSpace:  p.size("MIN_FREE_DISK_SPACE", "1GB"),
+		HooksMaxSize:      p.size("HOOKS_MAX_SIZE", "5MB"),
+		MigrationsMaxSize: p.size("MIGRATIONS_MAX_SIZE", "5MB"),
+		PublicMaxSize:     p.size("PUBLIC_MAX_SIZE", "50MB"),
+		AvatarsPath:       getEnv("AVATARS_PATH", "./avatars"),
+		AvatarMaxSize:     p.size("AVATAR_MAX_SIZE", "5MB"),
+
+		InstanceMetricsInterval:  p.duration("INSTANCE_METRICS_INTERVAL", "1m"),
+		InstanceMetricsRetention: p.duration("INSTANCE_METRICS_RETENTION", "30d"),
+
+		AnomalyCheckInterval: p.duration("ANOMALY_CHECK_INTERVAL", "0"),
+		AnomalyWindow:        p.duration("ANOMALY_WINDOW", "15m"),
+		AnomalyCPUPercent:    getEnvAsInt("ANOMALY_CPU_PERCENT", 90),
+		AnomalyEgressRate:    p.size("ANOMALY_EGRESS_RATE", "10MB"),
+		AnomalyDiskGrowth:    p.size("ANOMALY_DISK_GROWTH", "1GB"),
+		AnomalyAutoThrottle:  getEnvAsBool("ANOMALY_AUTO_THROTTLE", false),
+		AnomalyThrottleCPUs:  p.fraction("ANOMALY_THROTTLE_CPUS", "0.25"),
+
+		// Request analytics
+		TraefikAccessLogPath:  getEnv("TRAEFIK_ACCESS_LOG_PATH", ""),
+		TrafficIngestInterval: p.duration("TRAFFIC_INGEST_INTERVAL", "1m"),
+
+		BandwidthCheckInterval: p.duration("BANDWIDTH_CHECK_INTERVAL", "5m"),
+
+		// Crash-loop detection
+		CrashLoopMaxRestarts: getEnvAsInt("CRASH_LOOP_MAX_RESTARTS", 5),
+		CrashLoopWindow:      p.duration("CRASH_LOOP_WINDOW", "5m"),
+
+		// Background job queue
+		JobWorkers:      getEnvAsInt("JOB_WORKERS", 4),
+		JobPollInterval: p.duration("JOB_POLL_INTERVAL", "2s"),
+
+		// Usage metering
+		MeteringEnabled: getEnvAsBool("METERING_ENABLED", false),
+
+		// Stripe billing
+		StripeSecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripePlanPrices:    p.planPrices("STRIPE_PLAN_PRICES", ""),
+		BillingSuccessURL:   getEnv("BILLING_SUCCESS_URL", "http://localhost:3000/billing?checkout=success"),
+		BillingCancelURL:    getEnv("BILLING_CANCEL_URL", "http://localhost:3000/billing?checkout=cancelled"),
+		BillingGracePeriod:  p.duration("BILLING_GRACE_PERIOD", "7d"),
+
+		// Email
+		SMTPHost:              getEnv("SMTP_HOST", ""),
+		SMTPPort:              getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:          getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:          getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:              getEnv("SMTP_FROM", "Pocketploy <no-reply@localhost>"),
+		EmailChangeConfirmURL: getEnv("EMAIL_CHANGE_CONFIRM_URL", "http://localhost:3000/account/confirm-email"),
+		EmailChangeTTL:        p.duration("EMAIL_CHANGE_TTL", "24h"),
+
+		LoginNotificationsEnabled: getEnvAsBool("LOGIN_NOTIFICATIONS_ENABLED", true),
+		SessionRevokeURL:          getEnv("SESSION_REVOKE_URL", "http://localhost:3000/account/revoke-session"),
+		GeoIPDatabasePath:         getEnv("GEOIP_DATABASE_PATH", ""),
+
+		// Webhooks
+		WebhookTimeout:             p.duration("WEBHOOK_TIMEOUT", "10s"),
+		WebhookAllowPrivateTargets: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+
+		DownloadURLTTL: p.duration("DOWNLOAD_URL_TTL", "5m"),
+	}
+
+	if p.err != nil {
+		return nil, p.err
 	}
 
+	// Instances are linked on the base domain unless another host is given
+	config.InstancePortHost = getEnv("INSTANCE_PORT_HOST", config.BaseDomain)
+
 	// Validate required fields
 	if err := config.validate(); err != nil {
 		return nil, err
 	}
 
+	settings, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	config.publishSettings(settings, Overrides{})
+
 	return config, nil
 }
 
+// Overrides are settings stored in the database by administrators. They take
+// precedence over the environment; nil fields keep the environment value.
+type Overrides struct {
+	MaxInstancesPerUser       *int
+	InstanceDataRetentionDays *int
+	PocketBaseImage           *string
+	SignupEnabled             *bool
+}
+
+// Settings returns the current reloadable settings
+func (c *Config) Settings() *Settings {
+	return c.settings.Load()
+}
+
+// SetOverrides applies administrator overrides on top of the environment settings
+func (c *Config) SetOverrides(overrides Overrides) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+
+	c.publishSettings(c.envSettings, overrides)
+}
+
+// publishSettings stores the environment settings and overrides and publishes
+// their combination. The caller must hold settingsMu (or own c exclusively).
+func (c *Config) publishSettings(envSettings *Settings, overrides Overrides) {
+	c.envSettings = envSettings
+	c.overrides = overrides
+
+	settings := *envSettings
+	if overrides.MaxInstancesPerUser != nil {
+		settings.MaxInstancesPerUser = *overrides.MaxInstancesPerUser
+	}
+	if overrides.InstanceDataRetentionDays != nil {
+		settings.InstanceDataRetentionDays = *overrides.InstanceDataRetentionDays
+	}
+	if overrides.PocketBaseImage != nil {
+		settings.PocketBaseImage = *overrides.PocketBaseImage
+	}
+	if overrides.SignupEnabled != nil {
+		settings.SignupEnabled = *overrides.SignupEnabled
+	}
+
+	c.settings.Store(&settings)
+}
+
+// Reload re-reads .env and the environment and publishes new Settings, keeping
+// the administrators' overrides. On error the current settings stay in effect.
+func (c *Config) Reload() (*Settings, error) {
+	if err := loadEnvFile(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+
+	settings, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+
+	c.publishSettings(settings, c.overrides)
+
+	return c.Settings(), nil
+}
+
+// loadSettings reads and validates the reloadable settings
+func loadSettings() (*Settings, error) {
+	p := &envParser{}
+	settings := &Settings{
+		// Signup Configuration
+		SignupEnabled:                getEnvAsBool("SIGNUP_ENABLED", true),
+		SignupRequireInvite:          getEnvAsBool("SIGNUP_REQUIRE_INVITE", false),
+		ReservedNames:                getEnv("RESERVED_NAMES", ""),
+		CaptchaLoginFailureThreshold: getEnvAsInt("CAPTCHA_LOGIN_FAILURE_THRESHOLD", 3),
+
+		// Instance Configuration
+		PocketBaseImage:             getEnv("POCKETBASE_IMAGE", "ghcr.io/muchobien/pocketbase:latest"),
+		MaxInstancesPerUser:         getEnvAsInt("MAX_INSTANCES_PER_USER", 5),
+		PlanInstanceLimits:          p.planLimits("PLAN_INSTANCE_LIMITS", ""),
+		InstanceDataRetentionDays:   getEnvAsInt("INSTANCE_DATA_RETENTION_DAYS", 30),
+		InstanceDeletionGracePeriod: p.duration("INSTANCE_DELETION_GRACE_PERIOD", "1h"),
+		InstanceMaxTTL:              p.duration("INSTANCE_MAX_TTL", "30d"),
+
+		MaxConcurrentOperationsPerUser: getEnvAsInt("MAX_CONCURRENT_OPERATIONS_PER_USER", 2),
+
+		MaxCronsPerInstance: getEnvAsInt("MAX_CRONS_PER_INSTANCE", 10),
+
+		// Bandwidth quotas (PLAN_BANDWIDTH_QUOTAS_GB is the older name)
+		PlanBandwidthQuotas:      p.planQuotas("PLAN_BANDWIDTH_QUOTAS", getEnv("PLAN_BANDWIDTH_QUOTAS_GB", "")),
+		BandwidthWarningPercent:  getEnvAsInt("BANDWIDTH_WARNING_PERCENT", 80),
+		BandwidthSuspendOnExceed: getEnvAsBool("BANDWIDTH_SUSPEND_ON_EXCEED", false),
+
+		// Request logging
+		RequestLogLevel:      strings.ToLower(getEnv("REQUEST_LOG_LEVEL", "info")),
+		RequestLogSampleRate: p.fraction("REQUEST_LOG_SAMPLE_RATE", "1"),
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
 // validate checks if all required configuration values are set
 func (c *Config) validate() error {
 	if c.DBPassword == "" {
@@ -123,13 +746,242 @@ func (c *Config) validate() error {
 		return fmt.Errorf("JWT_REFRESH_SECRET must be at least 32 characters long")
 	}
 
+	if c.MetricsEnabled && len(c.MetricsToken) < 32 {
+		return fmt.Errorf("METRICS_TOKEN must be at least 32 characters long when METRICS_ENABLED is on")
+	}
+
+	if c.JWTAccessExpiry <= 0 || c.JWTRefreshExpiry <= 0 {
+		return fmt.Errorf("JWT_ACCESS_EXPIRY and JWT_REFRESH_EXPIRY must be positive durations")
+	}
+
+	if c.ImpersonationTTL <= 0 {
+		return fmt.Errorf("IMPERSONATION_TTL must be a positive duration (e.g. 30m)")
+	}
+
+	if c.AdminApprovalTTL <= 0 {
+		return fmt.Errorf("ADMIN_APPROVAL_TTL must be a positive duration (e.g. 24h)")
+	}
+
+	if c.TrustedDeviceTTL <= 0 {
+		return fmt.Errorf("TRUSTED_DEVICE_TTL must be a positive duration (e.g. 30d)")
+	}
+
+	if c.RefreshTokenDelivery != "body" && c.RefreshTokenDelivery != "cookie" {
+		return fmt.Errorf("REFRESH_TOKEN_DELIVERY must be body or cookie")
+	}
+
+	if c.CookieSameSite != "strict" && c.CookieSameSite != "lax" && c.CookieSameSite != "none" {
+		return fmt.Errorf("COOKIE_SAMESITE must be strict, lax or none")
+	}
+
+	if c.CookieSameSite == "none" && !c.CookieSecure {
+		return fmt.Errorf("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+	}
+
+	if origin := c.WildcardOriginFor(c.BaseDomain); origin != "" {
+		return fmt.Errorf("ALLOWED_ORIGINS: %s would allow every instance under BASE_DOMAIN, list the frontend origins instead", origin)
+	}
+
+	if c.RequestLogFormat != "text" && c.RequestLogFormat != "json" {
+		return fmt.Errorf("REQUEST_LOG_FORMAT must be text or json")
+	}
+
+	if c.DBMaxOpenConns < 0 || c.DBMaxIdleConns < 0 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative")
+	}
+
+	if c.DBMaxOpenConns > 0 && c.DBMaxIdleConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	}
+
+	if c.TraefikForwardedDepth < 0 {
+		return fmt.Errorf("TRAEFIK_FORWARDED_DEPTH must not be negative")
+	}
+
+	switch c.RoutingMode {
+	case RoutingModeTraefik, RoutingModeCaddy, RoutingModeNginx, RoutingModePort:
+	default:
+		return fmt.Errorf("ROUTING_MODE must be traefik, caddy, nginx or port")
+	}
+
+	if c.RoutingMode == RoutingModePort && (c.InstancePortMin < 1 || c.InstancePortMax > 65535 || c.InstancePortMin > c.InstancePortMax) {
+		return fmt.Errorf("INSTANCE_PORT_MIN and INSTANCE_PORT_MAX must be a port range between 1 and 65535")
+	}
+
 	if c.BcryptCost < 10 || c.BcryptCost > 14 {
 		return fmt.Errorf("BCRYPT_COST must be between 10 and 14")
 	}
 
+	// Redis expires the counters in whole milliseconds (a shorter window
+	// deletes them right away) and Retry-After is given in whole seconds
+	if c.RateLimitAuthWindow < time.Second {
+		return fmt.Errorf("RATE_LIMIT_AUTH_WINDOW must be at least 1s (e.g. 1m)")
+	}
+
+	if c.CaptchaProvider != "" && c.CaptchaSecret == "" {
+		return fmt.Errorf("CAPTCHA_SECRET is required when CAPTCHA_PROVIDER is set")
+	}
+
+	if c.ScannerProvider != "" && c.ScannerTimeout <= 0 {
+		return fmt.Errorf("SCANNER_TIMEOUT must be a positive duration (e.g. 30s)")
+	}
+
+	if c.DNSProvider == "cloudflare" && (c.CloudflareAPIToken == "" || c.CloudflareZoneID == "") {
+		return fmt.Errorf("CLOUDFLARE_API_TOKEN and CLOUDFLARE_ZONE_ID are required when DNS_PROVIDER is cloudflare")
+	}
+
+	if c.DNSProvider == "route53" && (c.Route53HostedZoneID == "" || c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "") {
+		return fmt.Errorf("ROUTE53_HOSTED_ZONE_ID, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when DNS_PROVIDER is route53")
+	}
+
+	switch c.StorageProvider {
+	case "":
+	case "aws":
+		if c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "" {
+			return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when STORAGE_PROVIDER is aws")
+		}
+		prefix := c.StorageBucketPrefix
+		if len(prefix) < 3 || len(prefix) > 40 || strings.Trim(prefix, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" ||
+			strings.HasPrefix(prefix, "-") || strings.HasSuffix(prefix, "-") {
+			return fmt.Errorf("STORAGE_BUCKET_PREFIX must be 3-40 lowercase letters, digits and hyphens")
+		}
+		if c.StorageUsageInterval <= 0 {
+			return fmt.Errorf("STORAGE_USAGE_INTERVAL must be a positive duration (e.g. 1h)")
+		}
+	default:
+		return fmt.Errorf("unsupported STORAGE_PROVIDER: %s", c.StorageProvider)
+	}
+
+	if c.SecretsKMSKeyID != "" && (c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "") {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when SECRETS_KMS_KEY_ID is set")
+	}
+
+	if c.DNSProvider != "" && c.DNSTarget == "" {
+		return fmt.Errorf("DNS_TARGET is required when DNS_PROVIDER is set")
+	}
+
+	if c.JobPollInterval <= 0 {
+		return fmt.Errorf("JOB_POLL_INTERVAL must be a positive duration (e.g. 2s)")
+	}
+
+	if c.AnomalyCheckInterval > 0 {
+		if c.InstanceMetricsInterval <= 0 {
+			return fmt.Errorf("ANOMALY_CHECK_INTERVAL requires INSTANCE_METRICS_INTERVAL to be set")
+		}
+		if c.AnomalyWindow < 2*c.InstanceMetricsInterval {
+			return fmt.Errorf("ANOMALY_WINDOW must cover at least two INSTANCE_METRICS_INTERVAL samples")
+		}
+		if c.AnomalyCPUPercent <= 0 {
+			return fmt.Errorf("ANOMALY_CPU_PERCENT must be positive")
+		}
+		if c.AnomalyAutoThrottle && c.AnomalyThrottleCPUs <= 0 {
+			return fmt.Errorf("ANOMALY_THROTTLE_CPUS must be greater than 0 when ANOMALY_AUTO_THROTTLE is enabled")
+		}
+	}
+
+	if c.MeteringEnabled && c.InstanceMetricsInterval <= 0 {
+		return fmt.Errorf("METERING_ENABLED requires INSTANCE_METRICS_INTERVAL to be set")
+	}
+
+	if c.StripeSecretKey != "" && c.StripeWebhookSecret == "" {
+		return fmt.Errorf("STRIPE_WEBHOOK_SECRET is required when STRIPE_SECRET_KEY is set")
+	}
+
+	if c.ContainerPidsLimit < 0 {
+		return fmt.Errorf("CONTAINER_PIDS_LIMIT must not be negative")
+	}
+
+	if c.ContainerLogMaxFiles < 1 {
+		return fmt.Errorf("CONTAINER_LOG_MAX_FILES must be at least 1")
+	}
+
+	switch c.LogShipper {
+	case "", "file":
+	case "loki":
+		if c.LokiURL == "" {
+			return fmt.Errorf("LOKI_URL is required when LOG_SHIPPER is loki")
+		}
+	default:
+		return fmt.Errorf("LOG_SHIPPER must be file, loki or empty (got %q)", c.LogShipper)
+	}
+
+	if c.LogShipper != "" && c.LogShipInterval <= 0 {
+		return fmt.Errorf("LOG_SHIP_INTERVAL must be a positive duration (e.g. 30s)")
+	}
+
+	if c.IntegrityCheckImage == "" {
+		return fmt.Errorf("INTEGRITY_CHECK_IMAGE must not be empty")
+	}
+
+	if c.EgressFirewallImage != "" && c.EgressEnforceInterval <= 0 {
+		return fmt.Errorf("EGRESS_ENFORCE_INTERVAL must be a positive duration (e.g. 30s)")
+	}
+
+	if c.BuildsEnabled {
+		if c.BuilderImage == "" || c.BuildBaseImage == "" {
+			return fmt.Errorf("BUILDER_IMAGE and BUILD_BASE_IMAGE must not be empty when BUILDS_ENABLED is set")
+		}
+		// Builds run as background jobs, which are released after 15 minutes
+		if c.BuildTimeout <= 0 || c.BuildTimeout > 14*time.Minute {
+			return fmt.Errorf("BUILD_TIMEOUT must be between 1s and 14m (got %s)", c.BuildTimeout)
+		}
+	}
+
+	if c.EmailChangeTTL <= 0 {
+		return fmt.Errorf("EMAIL_CHANGE_TTL must be a positive duration (e.g. 24h)")
+	}
+
+	if c.WebhookTimeout <= 0 {
+		return fmt.Errorf("WEBHOOK_TIMEOUT must be a positive duration (e.g. 10s)")
+	}
+
+	if c.DownloadURLTTL <= 0 || c.DownloadURLTTL > 24*time.Hour {
+		return fmt.Errorf("DOWNLOAD_URL_TTL must be a duration between 1s and 24h (e.g. 5m)")
+	}
+
 	return nil
 }
 
+// validate checks the reloadable settings
+func (s *Settings) validate() error {
+	if s.PocketBaseImage == "" {
+		return fmt.Errorf("POCKETBASE_IMAGE must not be empty")
+	}
+
+	if s.InstanceDataRetentionDays < 0 {
+		return fmt.Errorf("INSTANCE_DATA_RETENTION_DAYS must not be negative")
+	}
+
+	if s.InstanceMaxTTL < time.Hour {
+		return fmt.Errorf("INSTANCE_MAX_TTL must be at least 1h")
+	}
+
+	if s.BandwidthWarningPercent < 1 || s.BandwidthWarningPercent > 100 {
+		return fmt.Errorf("BANDWIDTH_WARNING_PERCENT must be between 1 and 100")
+	}
+
+	switch s.RequestLogLevel {
+	case "none", "errors", "info", "debug":
+	default:
+		return fmt.Errorf("REQUEST_LOG_LEVEL must be none, errors, info or debug")
+	}
+
+	return nil
+}
+
+// InstanceLimit returns the most instances a user on plan may have (0 means unlimited)
+func (s *Settings) InstanceLimit(plan string) int {
+	if limit, ok := s.PlanInstanceLimits[plan]; ok {
+		return limit
+	}
+	return s.MaxInstancesPerUser
+}
+
+// BillingEnabled reports whether Stripe billing is configured
+func (c *Config) BillingEnabled() bool {
+	return c.StripeSecretKey != ""
+}
+
 // GetDSN returns the PostgreSQL connection string
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf(
@@ -143,6 +995,41 @@ func (c *Config) GetDSN() string {
 	)
 }
 
+// StorageRoots returns the directories instance data may live in:
+// INSTANCES_BASE_PATH followed by the STORAGE_VOLUMES entries, as absolute paths
+func (c *Config) StorageRoots() []string {
+	var roots []string
+	for _, dir := range append([]string{c.InstancesBasePath}, strings.Split(c.StorageVolumes, ",")...) {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			roots = append(roots, abs)
+		}
+	}
+
+	return roots
+}
+
+// PrepullImageList returns the current PocketBase image followed by the PREPULL_IMAGES entries, without duplicates
+func (c *Config) PrepullImageList() []string {
+	pocketBaseImage := c.Settings().PocketBaseImage
+	images := []string{pocketBaseImage}
+	seen := map[string]bool{pocketBaseImage: true}
+
+	for _, ref := range strings.Split(c.PrepullImages, ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		images = append(images, ref)
+	}
+
+	return images
+}
+
 // getEnv reads an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -166,3 +1053,159 @@ func getEnvAsInt(key string, defaultValue int) int {
 
 	return value
 }
+
+// getEnvAsBool reads an environment variable as boolean or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Warning: Invalid boolean value for %s, using default: %t", key, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
+
+// envParser reads typed environment variables, remembering the first invalid
+// one. Unlike getEnvAsInt it does not fall back to the default: a mistyped
+// duration or size is reported at startup (or rejected on reload).
+type envParser struct {
+	err error
+}
+
+// duration reads a duration such as "90s", "1h" or "30d"
+func (p *envParser) duration(key, defaultValue string) time.Duration {
+	value := getEnv(key, defaultValue)
+	d, err := ParseDuration(value)
+	if err != nil || d < 0 {
+		p.fail(fmt.Errorf("%s must be a duration such as 90s, 1h or 30d (got %q)", key, value))
+		return 0
+	}
+	return d
+}
+
+// fraction reads a number between 0 and 1
+func (p *envParser) fraction(key, defaultValue string) float64 {
+	value := getEnv(key, defaultValue)
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || f < 0 || f > 1 {
+		p.fail(fmt.Errorf("%s must be a number between 0 and 1 (got %q)", key, value))
+		return 0
+	}
+	return f
+}
+
+// prefixes reads a comma-separated list of IP addresses and CIDR ranges
+func (p *envParser) prefixes(key, defaultValue string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(getEnv(key, defaultValue), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				p.fail(fmt.Errorf("%s must be a list of IP addresses or CIDR ranges (got %q)", key, entry))
+				return nil
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// list reads a comma-separated list, dropping empty entries
+func (p *envParser) list(key string) []string {
+	var entries []string
+	for _, entry := range strings.Split(getEnv(key, ""), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// size reads a size such as "512MB" or "10GB"; bare numbers are bytes
+func (p *envParser) size(key, defaultValue string) int64 {
+	value := getEnv(key, defaultValue)
+	n, err := ParseSize(value, 1)
+	if err != nil {
+		p.fail(fmt.Errorf("%s must be a size such as 512MB or 10GB (got %q)", key, value))
+		return 0
+	}
+	return n
+}
+
+// planQuotas reads "plan=size" pairs ("free=10GB,pro=1TB"); bare numbers are GB
+func (p *envParser) planQuotas(key, defaultValue string) map[string]int64 {
+	quotas := make(map[string]int64)
+	for _, pair := range strings.Split(getEnv(key, defaultValue), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		plan, value, ok := strings.Cut(pair, "=")
+		size, err := ParseSize(value, 1<<30)
+		if !ok || err != nil {
+			p.fail(fmt.Errorf("%s must be a list of plan=size pairs (e.g. free=10GB,pro=1TB)", key))
+			return nil
+		}
+		quotas[strings.TrimSpace(plan)] = size
+	}
+	return quotas
+}
+
+// planLimits reads "plan=count" pairs ("free=1,pro=20")
+func (p *envParser) planLimits(key, defaultValue string) map[string]int {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(getEnv(key, defaultValue), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		plan, value, ok := strings.Cut(pair, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil || limit < 0 {
+			p.fail(fmt.Errorf("%s must be a list of plan=count pairs (e.g. free=1,pro=20)", key))
+			return nil
+		}
+		limits[strings.TrimSpace(plan)] = limit
+	}
+	return limits
+}
+
+// planPrices reads "plan=price" pairs ("pro=price_123,team=price_456")
+func (p *envParser) planPrices(key, defaultValue string) map[string]string {
+	prices := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, defaultValue), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		plan, price, ok := strings.Cut(pair, "=")
+		plan, price = strings.TrimSpace(plan), strings.TrimSpace(price)
+		if !ok || plan == "" || price == "" {
+			p.fail(fmt.Errorf("%s must be a list of plan=price pairs (e.g. pro=price_123)", key))
+			return nil
+		}
+		prices[plan] = price
+	}
+	return prices
+}
+
+func (p *envParser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
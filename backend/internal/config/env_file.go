@@ -0,0 +1,66 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	envMu sync.Mutex
+
+	// environmentKeys are the variables set before .env was first read
+	environmentKeys map[string]bool
+
+	// fileKeys are the variables last applied from .env
+	fileKeys = make(map[string]bool)
+)
+
+// recordEnvironment remembers the variables of the real environment
+func recordEnvironment() {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	environmentKeys = make(map[string]bool)
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		environmentKeys[key] = true
+	}
+}
+
+// loadEnvFile applies .env to the process environment. Variables from the real
+// environment are never overridden, and variables removed from the file since
+// the last call are unset, so reading the file again picks up edits.
+func loadEnvFile() error {
+	values, err := godotenv.Read()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		values = map[string]string{}
+	}
+
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	for key := range fileKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(fileKeys, key)
+		}
+	}
+
+	for key, value := range values {
+		if environmentKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		fileKeys[key] = true
+	}
+
+	return err
+}
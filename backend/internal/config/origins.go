@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// origins reads a comma-separated list of CORS origins. An origin is
+// "scheme://host[:port]"; the host may start with "*." to allow any subdomain
+// (https://*.app.pocketploy.dev allows https://eu.app.pocketploy.dev and
+// https://a.b.app.pocketploy.dev, but not https://app.pocketploy.dev itself).
+func (p *envParser) origins(key, defaultValue string) []string {
+	var origins []string
+	for _, entry := range strings.Split(getEnv(key, defaultValue), ",") {
+		entry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(entry), "/"))
+		if entry == "" {
+			continue
+		}
+
+		if err := validateOrigin(entry); err != nil {
+			p.fail(fmt.Errorf("%s: %w", key, err))
+			return nil
+		}
+		origins = append(origins, entry)
+	}
+	return origins
+}
+
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		// Credentials are allowed, which browsers refuse with a wildcard origin
+		return fmt.Errorf("* cannot be used, list the origins or use a pattern such as https://*.example.com")
+	}
+
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return fmt.Errorf("origin %q must start with http:// or https://", origin)
+	}
+
+	host = strings.TrimPrefix(host, "*.")
+	if host == "" || strings.ContainsAny(host, "*/?#@") {
+		return fmt.Errorf("origin %q must be scheme://host[:port], optionally with a *. subdomain wildcard", origin)
+	}
+
+	return nil
+}
+
+// WildcardOriginFor returns the ALLOWED_ORIGINS pattern that matches hosts
+// under domain, or "" if there is none. Instances are served from subdomains
+// of BASE_DOMAIN and region domains, so such a pattern would let any tenant's
+// PocketBase make credentialed requests to the API.
+func (c *Config) WildcardOriginFor(domain string) string {
+	domain = strings.ToLower(domain)
+
+	for _, allowed := range c.AllowedOrigins {
+		_, host, wildcard := strings.Cut(allowed, "*.")
+		if !wildcard {
+			continue
+		}
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+
+		if domain == host || strings.HasSuffix(domain, "."+host) {
+			return allowed
+		}
+	}
+
+	return ""
+}
+
+// OriginAllowed reports whether a request's Origin header matches ALLOWED_ORIGINS
+func (c *Config) OriginAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+
+	for _, allowed := range c.AllowedOrigins {
+		prefix, suffix, wildcard := strings.Cut(allowed, "*.")
+		if !wildcard {
+			if origin == allowed {
+				return true
+			}
+			continue
+		}
+
+		// The wildcard matches one or more subdomain labels
+		if len(origin) > len(prefix)+len(suffix)+1 &&
+			strings.HasPrefix(origin, prefix) &&
+			strings.HasSuffix(origin, "."+suffix) {
+			subdomain := origin[len(prefix) : len(origin)-len(suffix)-1]
+			if !strings.ContainsAny(subdomain, "/:?#@") && !strings.HasPrefix(subdomain, ".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
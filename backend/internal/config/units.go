@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a Go duration ("90s", "1h30m") that may also start with
+// a number of days ("30d", "1d12h"). "0" disables features that accept it.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		return time.ParseDuration(s)
+	}
+
+	n, err := strconv.ParseFloat(days, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	d := time.Duration(n * float64(24*time.Hour))
+	if rest != "" {
+		extra, err := time.ParseDuration(rest)
+		if err != nil || extra < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += extra
+	}
+
+	return d, nil
+}
+
+// sizeUnits are the multipliers accepted by ParseSize. Like the rest of the
+// backend (and Docker), KB/MB/GB/TB are binary multiples.
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "512MB", "1.5GB" or "10GiB" into bytes.
+// defaultUnit applies to bare numbers.
+func ParseSize(s string, defaultUnit int64) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+
+	multiplier := defaultUnit
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 || n*float64(multiplier) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,35 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDayFields(t *testing.T) {
+	// Thursday, January 1st 2026
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		// Both day fields restricted: either one matching is enough
+		{"0 0 13 * 5", time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		// A day field starting with "*" is unrestricted, so both must match
+		{"0 0 */2 * 1", time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 2 * */3", time.Date(2026, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1", time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
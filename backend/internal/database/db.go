@@ -1,12 +1,16 @@
 package database
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"pocketploy/internal/metrics"
 )
 
 // DB holds the database connection
@@ -14,17 +18,46 @@ type DB struct {
 	*sqlx.DB
 }
 
+// Options controls optional database instrumentation
+type Options struct {
+	// Metrics receives query duration and error metrics when set
+	Metrics *metrics.Registry
+
+	// SlowQueryThreshold logs queries taking at least this long (0 disables)
+	SlowQueryThreshold time.Duration
+
+	// Connection pool limits (0 MaxOpenConns and ConnMaxLifetime mean unlimited)
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
 // New creates a new database connection
-func New(dsn string) (*DB, error) {
-	db, err := sqlx.Connect("postgres", dsn)
+func New(dsn string, opts Options) (*DB, error) {
+	connector, err := pq.NewConnector(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to database: %w", err)
 	}
 
+	// Wrap the driver so every query is timed, regardless of which layer issues it
+	var dbConnector driver.Connector = connector
+	if opts.Metrics != nil || opts.SlowQueryThreshold > 0 {
+		dbConnector = &instrumentedConnector{
+			Connector: connector,
+			observer:  newQueryObserver(opts.Metrics, opts.SlowQueryThreshold),
+		}
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(dbConnector), "postgres")
+
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
+	if opts.Metrics != nil {
+		registerPoolMetrics(opts.Metrics, db)
+	}
 
 	// Test connection
 	if err := db.Ping(); err != nil {
@@ -36,6 +69,44 @@ func New(dsn string) (*DB, error) {
 	return &DB{db}, nil
 }
 
+// registerPoolMetrics exposes the connection pool statistics
+func registerPoolMetrics(registry *metrics.Registry, db *sqlx.DB) {
+	registry.NewGaugeFunc(
+		"pocketploy_db_connections_max_open",
+		"Maximum number of open database connections (0 is unlimited).",
+		func() float64 { return float64(db.Stats().MaxOpenConnections) },
+	)
+	registry.NewGaugeFunc(
+		"pocketploy_db_connections_in_use",
+		"Database connections currently in use.",
+		func() float64 { return float64(db.Stats().InUse) },
+	)
+	registry.NewGaugeFunc(
+		"pocketploy_db_connections_idle",
+		"Idle database connections.",
+		func() float64 { return float64(db.Stats().Idle) },
+	)
+	registry.NewCounterFunc(
+		"pocketploy_db_connection_waits_total",
+		"Number of times a query waited for a free database connection.",
+		func() float64 { return float64(db.Stats().WaitCount) },
+	)
+	registry.NewCounterFunc(
+		"pocketploy_db_connection_wait_seconds_total",
+		"Total time spent waiting for a free database connection.",
+		func() float64 { return db.Stats().WaitDuration.Seconds() },
+	)
+}
+
+// PoolUsage returns the fraction of the connection pool in use (0 when the pool is unlimited)
+func (db *DB) PoolUsage() float64 {
+	stats := db.Stats()
+	if stats.MaxOpenConnections <= 0 {
+		return 0
+	}
+	return float64(stats.InUse) / float64(stats.MaxOpenConnections)
+}
+
 // Close closes the database connection
 func (db *DB) Close() error {
 	return db.DB.Close()
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"pocketploy/internal/metrics"
+)
+
+// queryObserver records query latency and errors, and logs slow queries
+type queryObserver struct {
+	duration      *metrics.HistogramVec
+	errors        *metrics.CounterVec
+	slowThreshold time.Duration
+}
+
+func newQueryObserver(registry *metrics.Registry, slowThreshold time.Duration) *queryObserver {
+	o := &queryObserver{slowThreshold: slowThreshold}
+	if registry != nil {
+		o.duration = registry.NewHistogramVec(
+			"pocketploy_db_query_duration_seconds",
+			"Duration of database queries in seconds.",
+			metrics.DefaultBuckets,
+			"operation",
+		)
+		o.errors = registry.NewCounterVec(
+			"pocketploy_db_query_errors_total",
+			"Number of database queries that returned an error.",
+			"operation",
+		)
+	}
+	return o
+}
+
+// observe is called after every statement with its start time and result
+func (o *queryObserver) observe(query string, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	elapsed := time.Since(start)
+	operation := queryOperation(query)
+
+	if o.duration != nil {
+		o.duration.Observe(elapsed.Seconds(), operation)
+	}
+	if err != nil && o.errors != nil {
+		o.errors.Inc(operation)
+	}
+
+	if o.slowThreshold > 0 && elapsed >= o.slowThreshold {
+		log.Printf("Slow query (%v): %s", elapsed, compactQuery(query))
+	}
+}
+
+// queryOperation returns the leading SQL keyword, e.g. "select" or "update"
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+
+	switch operation := strings.ToLower(fields[0]); operation {
+	case "select", "insert", "update", "delete", "with", "begin", "commit", "rollback":
+		return operation
+	default:
+		return "other"
+	}
+}
+
+// compactQuery collapses whitespace so multi-line queries fit on one log line
+func compactQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// instrumentedConnector wraps a driver connector so every connection it opens is observed
+type instrumentedConnector struct {
+	driver.Connector
+	observer *queryObserver
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, observer: c.observer}, nil
+}
+
+// instrumentedConn times Exec and Query calls and forwards everything else
+type instrumentedConn struct {
+	driver.Conn
+	observer *queryObserver
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.observer.observe(query, start, err)
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.observer.observe(query, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// migrationFiles are the migrations this build expects to be applied
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// PendingMigrations returns the migrations of this build that the database
+// has not recorded in schema_migrations, in order
+func (db *DB) PendingMigrations(ctx context.Context) ([]string, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var applied []string
+	if err := db.SelectContext(ctx, &applied, `SELECT version FROM schema_migrations`); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	isApplied := make(map[string]bool, len(applied))
+	for _, version := range applied {
+		isApplied[version] = true
+	}
+
+	pending := []string{}
+	for _, entry := range entries {
+		version := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		if !isApplied[version] {
+			pending = append(pending, version)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending, nil
+}
@@ -0,0 +1,21 @@
+-- Platform administrators (manage invite codes and other platform settings)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- Invitation codes for closed-beta / private deployments
+CREATE TABLE invite_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(64) NOT NULL UNIQUE,
+    note TEXT,
+    max_uses INTEGER NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+    use_count INTEGER NOT NULL DEFAULT 0,
+    created_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_invite_codes_code ON invite_codes(code);
+
+COMMENT ON TABLE invite_codes IS 'Invitation codes required at signup when SIGNUP_REQUIRE_INVITE is enabled';
+COMMENT ON COLUMN invite_codes.max_uses IS 'Number of signups the code can be redeemed for';
+COMMENT ON COLUMN users.is_admin IS 'Platform administrator flag (grant with cmd/promote-admin)';
@@ -0,0 +1,7 @@
+-- Crash-loop detection: remember why an instance was marked failed
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS failure_logs TEXT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP;
+
+COMMENT ON COLUMN instances.failure_reason IS 'Why the instance was marked failed (e.g. crash loop), cleared when it is started again';
+COMMENT ON COLUMN instances.failure_logs IS 'Last container log lines captured when the instance was marked failed';
@@ -0,0 +1,6 @@
+-- Trigram indexes so ILIKE '%term%' instance search stays fast
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_instances_name_trgm ON instances USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_instances_slug_trgm ON instances USING GIN (slug gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_instances_subdomain_trgm ON instances USING GIN (subdomain gin_trgm_ops);
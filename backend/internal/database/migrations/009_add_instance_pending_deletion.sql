@@ -0,0 +1,15 @@
+-- Trash-style deletion: instances wait in pending_deletion for a grace period
+-- before they are archived, and can be restored during that window
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS status_before_deletion VARCHAR(20);
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS deletion_scheduled_for TIMESTAMP;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS deletion_retention_days INTEGER;
+
+ALTER TABLE instances DROP CONSTRAINT IF EXISTS instances_status_check;
+ALTER TABLE instances ADD CONSTRAINT instances_status_check
+    CHECK (status IN ('creating', 'running', 'stopped', 'failed', 'pending_deletion'));
+
+CREATE INDEX IF NOT EXISTS idx_instances_deletion_scheduled_for
+    ON instances(deletion_scheduled_for) WHERE status = 'pending_deletion';
+
+COMMENT ON COLUMN instances.status IS 'Current status: creating, running, stopped, failed, or pending_deletion. Deleted instances move to instances_archive table';
+COMMENT ON COLUMN instances.deletion_scheduled_for IS 'When a pending deletion is carried out (cancel before then to restore the instance)';
@@ -0,0 +1,18 @@
+-- Periodic resource usage samples per instance, used for dashboard charts
+CREATE TABLE instance_metrics (
+    id BIGSERIAL PRIMARY KEY,
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    recorded_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    memory_bytes BIGINT NOT NULL DEFAULT 0,
+    memory_limit_bytes BIGINT NOT NULL DEFAULT 0,
+    disk_bytes BIGINT NOT NULL DEFAULT 0,
+    network_rx_bytes BIGINT NOT NULL DEFAULT 0,
+    network_tx_bytes BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_instance_metrics_instance_recorded ON instance_metrics(instance_id, recorded_at);
+CREATE INDEX idx_instance_metrics_recorded_at ON instance_metrics(recorded_at);
+
+COMMENT ON TABLE instance_metrics IS 'Resource usage samples collected every INSTANCE_METRICS_INTERVAL, pruned after INSTANCE_METRICS_RETENTION';
+COMMENT ON COLUMN instance_metrics.network_rx_bytes IS 'Cumulative bytes received since the container started';
@@ -0,0 +1,23 @@
+-- Daily request analytics per instance, aggregated from Traefik access logs
+CREATE TABLE instance_traffic_daily (
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    bytes_out BIGINT NOT NULL DEFAULT 0,
+    status_2xx BIGINT NOT NULL DEFAULT 0,
+    status_3xx BIGINT NOT NULL DEFAULT 0,
+    status_4xx BIGINT NOT NULL DEFAULT 0,
+    status_5xx BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (instance_id, day)
+);
+
+-- Read position in each access log so ingestion resumes without double counting
+CREATE TABLE access_log_offsets (
+    path TEXT PRIMARY KEY,
+    file_offset BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE instance_traffic_daily IS 'Per-instance request counts, egress bytes and status classes per UTC day';
+COMMENT ON COLUMN instance_traffic_daily.bytes_out IS 'Response bytes sent to clients (DownstreamContentSize)';
@@ -0,0 +1,19 @@
+-- Plans determine monthly bandwidth quotas (see PLAN_BANDWIDTH_QUOTAS_GB)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS plan VARCHAR(50) NOT NULL DEFAULT 'free';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bandwidth_quota_override_gb INTEGER CHECK (bandwidth_quota_override_gb >= 0);
+
+-- Instances whose routing was cut off because the owner exceeded their quota
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS routing_suspended BOOLEAN NOT NULL DEFAULT false;
+
+-- Quota notices already sent, so each level is only reported once per month
+CREATE TABLE bandwidth_notices (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, month, level)
+);
+
+COMMENT ON COLUMN users.plan IS 'Subscription plan name, mapped to quotas through configuration';
+COMMENT ON COLUMN users.bandwidth_quota_override_gb IS 'Admin override of the plan bandwidth quota in GB per month (0 means unlimited)';
+COMMENT ON COLUMN instances.routing_suspended IS 'Disconnected from the proxy network because the owner exceeded their bandwidth quota';
@@ -0,0 +1,14 @@
+-- Platform-wide status managed by administrators (single row)
+CREATE TABLE platform_status (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    maintenance_enabled BOOLEAN NOT NULL DEFAULT false,
+    maintenance_message TEXT,
+    announcement TEXT,
+    updated_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO platform_status (id) VALUES (1);
+
+COMMENT ON TABLE platform_status IS 'Maintenance mode and announcement banner shown to all users';
+COMMENT ON COLUMN platform_status.maintenance_enabled IS 'When true, mutating instance endpoints return 503';
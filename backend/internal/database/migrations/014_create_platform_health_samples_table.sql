@@ -0,0 +1,13 @@
+-- Periodic platform health samples backing the public status page
+CREATE TABLE platform_health_samples (
+    id BIGSERIAL PRIMARY KEY,
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    docker_healthy BOOLEAN NOT NULL,
+    instances_running INTEGER NOT NULL DEFAULT 0,
+    instances_failed INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_platform_health_samples_checked_at ON platform_health_samples(checked_at);
+
+COMMENT ON TABLE platform_health_samples IS 'Control-plane view of Docker and instance health, sampled every minute';
+COMMENT ON COLUMN platform_health_samples.instances_failed IS 'Instances that should be running but are failed';
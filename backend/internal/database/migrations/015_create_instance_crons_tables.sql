@@ -0,0 +1,38 @@
+-- User-defined scheduled tasks run against an instance
+CREATE TABLE instance_crons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    schedule VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('command', 'http')),
+    command_args TEXT[],
+    http_method VARCHAR(10),
+    http_path TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    last_run_success BOOLEAN,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_instance_crons_instance_id ON instance_crons(instance_id);
+CREATE INDEX idx_instance_crons_next_run_at ON instance_crons(next_run_at) WHERE enabled;
+
+-- Execution history of scheduled tasks
+CREATE TABLE instance_cron_runs (
+    id BIGSERIAL PRIMARY KEY,
+    cron_id UUID NOT NULL REFERENCES instance_crons(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    success BOOLEAN NOT NULL,
+    output TEXT,
+    error TEXT
+);
+
+CREATE INDEX idx_instance_cron_runs_cron_started ON instance_cron_runs(cron_id, started_at DESC);
+
+COMMENT ON TABLE instance_crons IS 'Scheduled pocketbase commands or HTTP requests, evaluated in UTC';
+COMMENT ON COLUMN instance_crons.command_args IS 'Whitelisted pocketbase subcommand for kind=command, e.g. {migrate,up}';
+COMMENT ON COLUMN instance_crons.http_path IS 'Path requested on the instance''s own domain for kind=http';
+COMMENT ON TABLE instance_cron_runs IS 'Run history, trimmed to the most recent runs per task';
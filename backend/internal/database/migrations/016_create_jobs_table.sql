@@ -0,0 +1,26 @@
+-- Persistent background job queue (see internal/jobs)
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    unique_key VARCHAR(255),
+    run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMP,
+    locked_by VARCHAR(100),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_pending_run_at ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_status_updated_at ON jobs(status, updated_at);
+CREATE UNIQUE INDEX idx_jobs_unique_key ON jobs(unique_key) WHERE unique_key IS NOT NULL;
+
+COMMENT ON TABLE jobs IS 'Background jobs claimed by worker pools with FOR UPDATE SKIP LOCKED';
+COMMENT ON COLUMN jobs.status IS 'dead = attempts exhausted; visible to admins and can be requeued';
+COMMENT ON COLUMN jobs.unique_key IS 'Optional deduplication key, e.g. for recurring jobs';
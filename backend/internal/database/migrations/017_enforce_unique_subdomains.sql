@@ -0,0 +1,24 @@
+-- Subdomains must be unique across live instances (instances.subdomain is
+-- UNIQUE) and archived instances whose data is still retained, so a deleted
+-- instance can come back under its old address. The archive check is a
+-- trigger because uniqueness spans two tables.
+CREATE INDEX IF NOT EXISTS idx_instances_archive_subdomain_retained
+    ON instances_archive(subdomain) WHERE data_available = true;
+
+CREATE OR REPLACE FUNCTION instances_check_retained_subdomain() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM instances_archive
+        WHERE subdomain = NEW.subdomain AND data_available = true AND id <> NEW.id
+    ) THEN
+        RAISE EXCEPTION 'subdomain % is retained by an archived instance', NEW.subdomain
+            USING ERRCODE = 'unique_violation', CONSTRAINT = 'instances_subdomain_retained';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS instances_subdomain_retained ON instances;
+CREATE TRIGGER instances_subdomain_retained
+    BEFORE INSERT OR UPDATE OF subdomain ON instances
+    FOR EACH ROW EXECUTE FUNCTION instances_check_retained_subdomain();
@@ -0,0 +1,16 @@
+-- Runtime settings managed by administrators (single row). NULL keeps the
+-- value from the environment.
+CREATE TABLE platform_settings (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    max_instances_per_user INTEGER CHECK (max_instances_per_user >= 0),
+    instance_data_retention_days INTEGER CHECK (instance_data_retention_days >= 0),
+    pocketbase_image VARCHAR(255) CHECK (pocketbase_image <> ''),
+    signup_enabled BOOLEAN,
+    updated_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO platform_settings (id) VALUES (1);
+
+COMMENT ON TABLE platform_settings IS 'Administrator overrides of environment settings, applied without a redeploy';
+COMMENT ON COLUMN platform_settings.pocketbase_image IS 'Image used for newly created instances';
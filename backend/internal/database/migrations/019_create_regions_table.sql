@@ -0,0 +1,22 @@
+-- Regions instances can be placed in: each has its own base domain, Traefik
+-- entrypoint and (optionally) Docker network
+CREATE TABLE regions (
+    id VARCHAR(32) PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9-]*$'),
+    name VARCHAR(100) NOT NULL,
+    base_domain VARCHAR(253) NOT NULL,
+    traefik_entrypoint VARCHAR(64) NOT NULL DEFAULT 'web',
+    docker_network VARCHAR(255),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- The default region's base domain is kept in sync with BASE_DOMAIN at startup
+INSERT INTO regions (id, name, base_domain) VALUES ('default', 'Default', '');
+
+ALTER TABLE instances
+    ADD COLUMN region_id VARCHAR(32) NOT NULL DEFAULT 'default' REFERENCES regions(id);
+
+COMMENT ON TABLE regions IS 'Placement options offered when creating an instance';
+COMMENT ON COLUMN regions.docker_network IS 'Network instances join and Traefik routes through (NULL uses DOCKER_NETWORK/TRAEFIK_NETWORK)';
+COMMENT ON COLUMN instances.region_id IS 'Region whose base domain and network the instance was created with';
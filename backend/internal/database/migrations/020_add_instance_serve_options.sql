@@ -0,0 +1,7 @@
+-- Per-instance `pocketbase serve` flags, rendered into the container command
+ALTER TABLE instances
+    ADD COLUMN serve_options JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN encryption_key VARCHAR(64);
+
+COMMENT ON COLUMN instances.serve_options IS 'Owner-configurable pocketbase serve flags (see models.ServeOptions)';
+COMMENT ON COLUMN instances.encryption_key IS 'Key passed to PocketBase through --encryptionEnv once settings encryption is enabled';
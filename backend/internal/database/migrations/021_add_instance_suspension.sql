@@ -0,0 +1,11 @@
+-- Suspension: admins (or quota enforcement) stop an instance and cut it off
+-- from the proxy; the owner cannot start it until the suspension is lifted
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+
+ALTER TABLE instances DROP CONSTRAINT IF EXISTS instances_status_check;
+ALTER TABLE instances ADD CONSTRAINT instances_status_check
+    CHECK (status IN ('creating', 'running', 'stopped', 'failed', 'pending_deletion', 'suspended'));
+
+COMMENT ON COLUMN instances.status IS 'Current status: creating, running, stopped, failed, pending_deletion, or suspended. Deleted instances move to instances_archive table';
+COMMENT ON COLUMN instances.suspension_reason IS 'Why the instance was suspended, shown to its owner';
@@ -0,0 +1,7 @@
+-- Per-instance access protection, enforced by Traefik middlewares
+ALTER TABLE instances
+    ADD COLUMN access_protection JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN access_password_hash VARCHAR(72);
+
+COMMENT ON COLUMN instances.access_protection IS 'Basic auth username and client IP allowlist (see models.AccessProtection)';
+COMMENT ON COLUMN instances.access_password_hash IS 'Bcrypt hash of the basic auth password, rendered into the Traefik labels';
@@ -0,0 +1,35 @@
+-- Records applied migrations so the backend can tell whether its schema is
+-- current (/health/ready). Existing databases are backfilled up to this
+-- migration; every later migration ends by inserting its own version.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version) VALUES
+    ('001_create_users_table'),
+    ('002_create_refresh_tokens_table'),
+    ('003_create_instances_table'),
+    ('004_create_instances_archive_table'),
+    ('005_update_instances_status_constraint'),
+    ('006_create_invite_codes_table'),
+    ('007_add_instance_failure_details'),
+    ('008_add_instance_search_indexes'),
+    ('009_add_instance_pending_deletion'),
+    ('010_create_instance_metrics_table'),
+    ('011_create_instance_traffic_tables'),
+    ('012_add_bandwidth_quotas'),
+    ('013_create_platform_status_table'),
+    ('014_create_platform_health_samples_table'),
+    ('015_create_instance_crons_tables'),
+    ('016_create_jobs_table'),
+    ('017_enforce_unique_subdomains'),
+    ('018_create_platform_settings_table'),
+    ('019_create_regions_table'),
+    ('020_add_instance_serve_options'),
+    ('021_add_instance_suspension'),
+    ('022_add_instance_access_protection'),
+    ('023_create_schema_migrations_table')
+ON CONFLICT (version) DO NOTHING;
+
+COMMENT ON TABLE schema_migrations IS 'Applied migrations, named after their file without the .sql extension';
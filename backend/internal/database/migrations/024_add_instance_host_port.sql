@@ -0,0 +1,10 @@
+-- Host port an instance is published on when ROUTING_MODE=port
+ALTER TABLE instances
+    ADD COLUMN host_port INTEGER;
+
+CREATE UNIQUE INDEX instances_host_port_key ON instances (host_port) WHERE host_port IS NOT NULL;
+
+COMMENT ON COLUMN instances.host_port IS 'Host port the container is published on (NULL when routed through Traefik)';
+
+INSERT INTO schema_migrations (version) VALUES ('024_add_instance_host_port')
+ON CONFLICT (version) DO NOTHING;
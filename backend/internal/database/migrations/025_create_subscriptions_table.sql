@@ -0,0 +1,20 @@
+-- Stripe subscriptions behind paid plans (see STRIPE_PLAN_PRICES)
+CREATE TABLE subscriptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    stripe_customer_id VARCHAR(255) NOT NULL,
+    stripe_subscription_id VARCHAR(255) UNIQUE,
+    plan VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    current_period_end TIMESTAMP,
+    grace_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_subscriptions_grace_until ON subscriptions (grace_until) WHERE grace_until IS NOT NULL;
+
+COMMENT ON COLUMN subscriptions.status IS 'Stripe subscription status (active, trialing, past_due, unpaid, canceled, ...)';
+COMMENT ON COLUMN subscriptions.grace_until IS 'When a lapsed subscription''s plan is downgraded to the free plan (NULL while in good standing)';
+
+INSERT INTO schema_migrations (version) VALUES ('025_create_subscriptions_table')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,20 @@
+-- Hourly metering records per user, exported for billing outside Stripe
+CREATE TABLE usage_metering (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hour TIMESTAMP NOT NULL,
+    instance_hours DOUBLE PRECISION NOT NULL DEFAULT 0,
+    storage_gb_hours DOUBLE PRECISION NOT NULL DEFAULT 0,
+    egress_gb DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, hour)
+);
+
+CREATE INDEX idx_usage_metering_hour ON usage_metering (hour);
+
+COMMENT ON TABLE usage_metering IS 'Usage per user and UTC hour, derived from the instance_metrics samples';
+COMMENT ON COLUMN usage_metering.instance_hours IS 'Hours instances were running (one instance running all hour counts 1)';
+COMMENT ON COLUMN usage_metering.storage_gb_hours IS 'Instance data size in GB held over the hour';
+COMMENT ON COLUMN usage_metering.egress_gb IS 'GB sent by the instance containers during the hour';
+
+INSERT INTO schema_migrations (version) VALUES ('026_create_usage_metering_table')
+ON CONFLICT (version) DO NOTHING;
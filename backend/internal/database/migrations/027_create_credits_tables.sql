@@ -0,0 +1,37 @@
+-- Promo codes users redeem for account credit
+CREATE TABLE promo_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(64) NOT NULL UNIQUE,
+    credit_cents BIGINT NOT NULL CHECK (credit_cents > 0),
+    note TEXT,
+    max_redemptions INTEGER NOT NULL DEFAULT 1 CHECK (max_redemptions > 0),
+    redemption_count INTEGER NOT NULL DEFAULT 0,
+    created_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Account credit ledger: grants and redemptions add credit, invoices deduct it
+CREATE TABLE credit_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount_cents BIGINT NOT NULL CHECK (amount_cents <> 0),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('grant', 'promo', 'deduction')),
+    reason TEXT,
+    promo_code_id UUID REFERENCES promo_codes(id) ON DELETE SET NULL,
+    reference VARCHAR(255),
+    created_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_credit_ledger_user ON credit_ledger (user_id, created_at);
+CREATE UNIQUE INDEX credit_ledger_promo_key ON credit_ledger (user_id, promo_code_id) WHERE promo_code_id IS NOT NULL;
+CREATE UNIQUE INDEX credit_ledger_reference_key ON credit_ledger (reference) WHERE reference IS NOT NULL;
+
+COMMENT ON TABLE credit_ledger IS 'Account credit movements; the balance is the sum of amount_cents';
+COMMENT ON COLUMN credit_ledger.amount_cents IS 'Credit in the smallest unit of the billing currency (negative for deductions)';
+COMMENT ON COLUMN credit_ledger.reference IS 'External reference of a deduction (the Stripe invoice), so each is applied once';
+
+INSERT INTO schema_migrations (version) VALUES ('027_create_credits_tables')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,14 @@
+-- Free-form key=value tags users attach to instances (env=prod, project=acme)
+CREATE TABLE instance_tags (
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    key VARCHAR(63) NOT NULL,
+    value VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (instance_id, key)
+);
+
+CREATE INDEX idx_instance_tags_key_value ON instance_tags (key, value);
+
+COMMENT ON TABLE instance_tags IS 'Instance tags, used to filter the instance list and for bulk operations';
+
+INSERT INTO schema_migrations (version) VALUES ('028_create_instance_tags_table')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,12 @@
+-- Favorites and a user-defined position in the instance list
+ALTER TABLE instances
+    ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN sort_order INTEGER;
+
+CREATE INDEX idx_instances_user_ordering ON instances (user_id, pinned DESC, sort_order ASC NULLS LAST, created_at DESC);
+
+COMMENT ON COLUMN instances.pinned IS 'Pinned instances are listed first';
+COMMENT ON COLUMN instances.sort_order IS 'Position set by the owner, NULL lists the instance after ordered ones by created_at';
+
+INSERT INTO schema_migrations (version) VALUES ('029_add_instance_ordering')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,7 @@
+-- Markdown notes documenting what an instance is for
+ALTER TABLE instances ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN instances.description IS 'Markdown description, at most 4000 characters';
+
+INSERT INTO schema_migrations (version) VALUES ('030_add_instance_description')
+ON CONFLICT (version) DO NOTHING;
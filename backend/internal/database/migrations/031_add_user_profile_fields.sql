@@ -0,0 +1,13 @@
+-- Profile metadata shown in the dashboard header
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_file VARCHAR(64),
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en';
+
+COMMENT ON COLUMN users.avatar_file IS 'Resized profile picture in AVATARS_PATH, served at /api/v1/avatars/<file>';
+COMMENT ON COLUMN users.timezone IS 'IANA time zone name, e.g. Europe/Berlin';
+COMMENT ON COLUMN users.locale IS 'BCP 47 language tag, e.g. en or pt-BR';
+
+INSERT INTO schema_migrations (version) VALUES ('031_add_user_profile_fields')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,19 @@
+-- Pending email address changes. The change is applied once the links sent
+-- to both the current and the new address have been opened.
+CREATE TABLE email_changes (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    old_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    new_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    old_confirmed_at TIMESTAMP,
+    new_confirmed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE email_changes IS 'Email changes awaiting confirmation from the old and the new address (one per user)';
+COMMENT ON COLUMN email_changes.old_token_hash IS 'SHA-256 of the token mailed to the current address';
+COMMENT ON COLUMN email_changes.new_token_hash IS 'SHA-256 of the token mailed to the new address';
+
+INSERT INTO schema_migrations (version) VALUES ('032_create_email_changes_table')
+ON CONFLICT (version) DO NOTHING;
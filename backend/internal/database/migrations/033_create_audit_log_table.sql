@@ -0,0 +1,19 @@
+-- Audit trail of sensitive actions, such as admins impersonating users
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(64) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_user_id ON audit_log (user_id, created_at DESC);
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id, created_at DESC);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at DESC);
+
+COMMENT ON TABLE audit_log IS 'Sensitive actions: actor_id acted, user_id is the account acted on or as';
+
+INSERT INTO schema_migrations (version) VALUES ('033_create_audit_log_table')
+ON CONFLICT (version) DO NOTHING;
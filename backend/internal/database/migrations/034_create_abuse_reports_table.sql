@@ -0,0 +1,24 @@
+-- Abuse reports about instances, submitted by anyone and reviewed by admins
+CREATE TABLE abuse_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subdomain VARCHAR(255) NOT NULL,
+    instance_id UUID REFERENCES instances(id) ON DELETE SET NULL,
+    category VARCHAR(32) NOT NULL,
+    description TEXT NOT NULL,
+    reporter_email VARCHAR(255),
+    reporter_ip VARCHAR(45) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    resolution_note TEXT,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT abuse_reports_status_check CHECK (status IN ('open', 'actioned', 'dismissed'))
+);
+
+CREATE INDEX idx_abuse_reports_status ON abuse_reports (status, created_at);
+CREATE INDEX idx_abuse_reports_instance_id ON abuse_reports (instance_id);
+
+COMMENT ON TABLE abuse_reports IS 'Abuse reports: open until an admin takes the instance down (actioned) or dismisses them';
+
+INSERT INTO schema_migrations (version) VALUES ('034_create_abuse_reports_table')
+ON CONFLICT (version) DO NOTHING;
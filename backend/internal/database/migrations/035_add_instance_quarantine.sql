@@ -0,0 +1,10 @@
+-- Set while an uploaded bundle that failed the malware scan awaits admin review
+ALTER TABLE instances
+    ADD COLUMN quarantine_bundle VARCHAR(16),
+    ADD COLUMN quarantine_reason TEXT,
+    ADD COLUMN quarantined_at TIMESTAMP;
+
+COMMENT ON COLUMN instances.quarantine_bundle IS 'Kind of the quarantined bundle (hooks or public), kept in QUARANTINE_PATH/<instance id>';
+
+INSERT INTO schema_migrations (version) VALUES ('035_add_instance_quarantine')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,22 @@
+-- Deploy tokens: long-lived credentials for CI pipelines, scoped to one
+-- instance and a set of deploy operations
+CREATE TABLE deploy_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deploy_tokens_instance_id ON deploy_tokens (instance_id);
+
+COMMENT ON COLUMN deploy_tokens.token_hash IS 'SHA-256 of the token; the token itself is only shown once, when created';
+COMMENT ON COLUMN deploy_tokens.scopes IS 'Deploy operations the token allows: hooks, public, migrate, restart';
+
+INSERT INTO schema_migrations (version) VALUES ('036_create_deploy_tokens_table')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,20 @@
+-- History of `pocketbase migrate up` runs triggered through the API
+CREATE TABLE instance_migration_runs (
+    id BIGSERIAL PRIMARY KEY,
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    triggered_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    success BOOLEAN NOT NULL,
+    applied TEXT[] NOT NULL DEFAULT '{}',
+    output TEXT,
+    error TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_instance_migration_runs_instance_started ON instance_migration_runs(instance_id, started_at DESC);
+
+COMMENT ON COLUMN instance_migration_runs.applied IS 'Migration files the run applied, parsed from the PocketBase output';
+COMMENT ON TABLE instance_migration_runs IS 'Run history, trimmed to the most recent runs per instance';
+
+INSERT INTO schema_migrations (version) VALUES ('037_create_instance_migration_runs_table')
+ON CONFLICT (version) DO NOTHING;
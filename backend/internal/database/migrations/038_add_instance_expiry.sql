@@ -0,0 +1,7 @@
+-- Ephemeral instances (e.g. preview environments) are archived once they expire
+ALTER TABLE instances ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX idx_instances_expires_at ON instances(expires_at) WHERE expires_at IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES ('038_add_instance_expiry')
+ON CONFLICT (version) DO NOTHING;
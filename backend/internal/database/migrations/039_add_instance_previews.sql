@@ -0,0 +1,10 @@
+-- Preview environments: short-lived clones of an instance keyed by an external
+-- ref such as a pull request number. A preview outlives its source instance
+-- (until it expires), so the link is cleared rather than cascaded.
+ALTER TABLE instances ADD COLUMN preview_of UUID REFERENCES instances(id) ON DELETE SET NULL;
+ALTER TABLE instances ADD COLUMN preview_ref VARCHAR(64);
+
+CREATE UNIQUE INDEX instances_preview_ref_key ON instances(preview_of, preview_ref) WHERE preview_of IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES ('039_add_instance_previews')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,6 @@
+-- Protected instances can't be deleted until the owner turns protection off,
+-- guarding production backends against accidental deletion.
+ALTER TABLE instances ADD COLUMN protected BOOLEAN NOT NULL DEFAULT FALSE;
+
+INSERT INTO schema_migrations (version) VALUES ('040_add_instance_deletion_protection')
+ON CONFLICT (version) DO NOTHING;
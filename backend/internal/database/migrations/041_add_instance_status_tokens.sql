@@ -0,0 +1,10 @@
+-- Read-only tokens for an instance's public status endpoint, polled by
+-- external uptime monitors. Only the SHA-256 hash is stored; the prefix
+-- identifies the token in the dashboard.
+ALTER TABLE instances ADD COLUMN status_token_hash VARCHAR(64);
+ALTER TABLE instances ADD COLUMN status_token_prefix VARCHAR(16);
+
+CREATE UNIQUE INDEX instances_status_token_hash_key ON instances(status_token_hash) WHERE status_token_hash IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES ('041_add_instance_status_tokens')
+ON CONFLICT (version) DO NOTHING;
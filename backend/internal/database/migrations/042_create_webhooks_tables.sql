@@ -0,0 +1,38 @@
+-- Webhook endpoints users register for instance lifecycle events, and the
+-- deliveries made to them. Deliveries are sent by background jobs and signed
+-- with the endpoint secret; the delivery ID is kept on redelivery so
+-- consumers can drop duplicates.
+CREATE TABLE webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(80) NOT NULL,
+    events TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_endpoints_user_id ON webhook_endpoints (user_id);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    response_body TEXT,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id, created_at DESC);
+
+COMMENT ON COLUMN webhook_endpoints.secret IS 'HMAC-SHA256 key deliveries are signed with (X-Pocketploy-Signature)';
+COMMENT ON COLUMN webhook_endpoints.events IS 'Event types delivered to the endpoint; empty means all';
+
+INSERT INTO schema_migrations (version) VALUES ('042_create_webhooks_tables')
+ON CONFLICT (version) DO NOTHING;
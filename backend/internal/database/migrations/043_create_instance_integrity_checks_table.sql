@@ -0,0 +1,20 @@
+-- History of `PRAGMA integrity_check` runs against instance databases
+CREATE TABLE instance_integrity_checks (
+    id BIGSERIAL PRIMARY KEY,
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    triggered_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ok BOOLEAN NOT NULL,
+    result TEXT,
+    error TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_instance_integrity_checks_instance_started ON instance_integrity_checks(instance_id, started_at DESC);
+
+COMMENT ON COLUMN instance_integrity_checks.triggered_by IS 'User who ran the check; NULL for scheduled checks';
+COMMENT ON COLUMN instance_integrity_checks.ok IS 'Whether SQLite reported "ok"; false with an error when the check could not run';
+COMMENT ON TABLE instance_integrity_checks IS 'Check history, trimmed to the most recent checks per instance';
+
+INSERT INTO schema_migrations (version) VALUES ('043_create_instance_integrity_checks_table')
+ON CONFLICT (version) DO NOTHING;
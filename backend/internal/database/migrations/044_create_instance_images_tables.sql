@@ -0,0 +1,34 @@
+-- Container images administrators approved besides POCKETBASE_IMAGE, e.g.
+-- PocketBase forks with Go hooks compiled in. Images are pinned to the digest
+-- they had when registered and offered to every user or only to granted ones.
+CREATE TABLE instance_images (
+    id VARCHAR(32) PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9-]*$'),
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    reference VARCHAR(255) NOT NULL,
+    pinned_reference VARCHAR(400) NOT NULL,
+    all_users BOOLEAN NOT NULL DEFAULT false,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE instance_image_grants (
+    image_id VARCHAR(32) NOT NULL REFERENCES instance_images(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (image_id, user_id)
+);
+
+CREATE INDEX idx_instance_image_grants_user_id ON instance_image_grants(user_id);
+
+ALTER TABLE instances ADD COLUMN image_id VARCHAR(32) REFERENCES instance_images(id);
+
+COMMENT ON TABLE instance_images IS 'Approved container images users may choose instead of POCKETBASE_IMAGE';
+COMMENT ON COLUMN instance_images.reference IS 'Image reference as registered (e.g. ghcr.io/acme/pocketbase-go:1.4)';
+COMMENT ON COLUMN instance_images.pinned_reference IS 'repository@digest containers are created from';
+COMMENT ON COLUMN instance_images.all_users IS 'Offered to every user rather than only to those in instance_image_grants';
+COMMENT ON COLUMN instances.image_id IS 'Approved image the instance was created with (NULL for POCKETBASE_IMAGE)';
+
+INSERT INTO schema_migrations (version) VALUES ('044_create_instance_images_tables')
+ON CONFLICT (version) DO NOTHING;
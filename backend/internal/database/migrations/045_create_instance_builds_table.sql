@@ -0,0 +1,25 @@
+-- Custom PocketBase builds: a Go module a user uploaded, compiled into an
+-- image in a throwaway builder container and deployed as their instance
+CREATE TABLE instance_builds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'building', 'succeeded', 'failed')),
+    image VARCHAR(255),
+    log TEXT,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    deployed_at TIMESTAMP
+);
+
+CREATE INDEX idx_instance_builds_instance_id ON instance_builds (instance_id, created_at DESC);
+
+COMMENT ON COLUMN instance_builds.image IS 'Local image tag the build produced';
+COMMENT ON COLUMN instance_builds.log IS 'Tail of the compiler and image build output';
+COMMENT ON COLUMN instance_builds.deployed_at IS 'When the instance container was switched to the image';
+
+INSERT INTO schema_migrations (version) VALUES ('045_create_instance_builds_table')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,26 @@
+-- Destructive admin operations (hard-deleting users, purging retained data)
+-- wait here until a second admin approves them
+CREATE TABLE admin_operations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(32) NOT NULL,
+    target_id UUID NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP,
+    error TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT admin_operations_kind_check CHECK (kind IN ('user.delete', 'archive.purge')),
+    CONSTRAINT admin_operations_status_check CHECK (status IN ('pending', 'approved', 'rejected', 'expired', 'failed'))
+);
+
+-- One open request per target
+CREATE UNIQUE INDEX idx_admin_operations_pending ON admin_operations (kind, target_id) WHERE status = 'pending';
+CREATE INDEX idx_admin_operations_status ON admin_operations (status, created_at);
+
+COMMENT ON TABLE admin_operations IS 'Destructive admin operations: pending until another admin approves (then approved or failed), rejects or they expire';
+
+INSERT INTO schema_migrations (version) VALUES ('046_create_admin_operations_table')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,12 @@
+-- Session labels and trusted devices. A trusted session holds the SHA-256
+-- hash of the device cookie; logging in again on the device moves the trust
+-- (and the label) to the new session, so the trust follows the device's
+-- chain of sessions until it expires or is revoked.
+ALTER TABLE refresh_tokens ADD COLUMN name VARCHAR(100);
+ALTER TABLE refresh_tokens ADD COLUMN device_token_hash VARCHAR(64);
+ALTER TABLE refresh_tokens ADD COLUMN trusted_until TIMESTAMP;
+
+CREATE UNIQUE INDEX refresh_tokens_device_token_hash_key ON refresh_tokens(device_token_hash) WHERE device_token_hash IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES ('047_add_session_devices')
+ON CONFLICT (version) DO NOTHING;
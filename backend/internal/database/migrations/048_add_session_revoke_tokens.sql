@@ -0,0 +1,11 @@
+-- One-click sign-out links mailed with new device login notifications. Only
+-- the SHA-256 hash of the link's token is stored, on the session it revokes.
+ALTER TABLE refresh_tokens ADD COLUMN revoke_token_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX refresh_tokens_revoke_token_hash_key ON refresh_tokens(revoke_token_hash) WHERE revoke_token_hash IS NOT NULL;
+
+-- Looked up at every login to tell whether the device was seen before
+CREATE INDEX idx_refresh_tokens_user_device ON refresh_tokens(user_id, ip_address);
+
+INSERT INTO schema_migrations (version) VALUES ('048_add_session_revoke_tokens')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,10 @@
+-- Country (ISO 3166-1 alpha-2 code) and city of the IP address of sessions
+-- and audit log entries, when a GeoIP database is configured
+ALTER TABLE refresh_tokens ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN city VARCHAR(100) NOT NULL DEFAULT '';
+
+ALTER TABLE audit_log ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '';
+ALTER TABLE audit_log ADD COLUMN city VARCHAR(100) NOT NULL DEFAULT '';
+
+INSERT INTO schema_migrations (version) VALUES ('049_add_geoip_locations')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,25 @@
+-- Suspicious instance behavior found in the usage history, reviewed by admins
+CREATE TABLE instance_anomalies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    description TEXT NOT NULL,
+    value DOUBLE PRECISION NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    throttled BOOLEAN NOT NULL DEFAULT FALSE,
+    resolution_note TEXT,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT instance_anomalies_kind_check CHECK (kind IN ('cpu_saturation', 'network_egress', 'disk_growth')),
+    CONSTRAINT instance_anomalies_status_check CHECK (status IN ('open', 'dismissed', 'suspended'))
+);
+
+-- An instance has at most one open anomaly of each kind
+CREATE UNIQUE INDEX idx_instance_anomalies_open ON instance_anomalies (instance_id, kind) WHERE status = 'open';
+CREATE INDEX idx_instance_anomalies_status ON instance_anomalies (status, created_at);
+
+COMMENT ON TABLE instance_anomalies IS 'Anomalies: open until an admin dismisses them (lifting any throttle) or suspends the instance';
+
+INSERT INTO schema_migrations (version) VALUES ('050_create_instance_anomalies_table')
+ON CONFLICT (version) DO NOTHING;
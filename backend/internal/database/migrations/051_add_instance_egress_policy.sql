@@ -0,0 +1,9 @@
+-- Per-instance outbound network policy, enforced with iptables rules in the
+-- instance's network namespace
+ALTER TABLE instances
+    ADD COLUMN egress_policy JSONB NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN instances.egress_policy IS 'Outbound connection mode and allowed hosts and ports (see models.EgressPolicy)';
+
+INSERT INTO schema_migrations (version) VALUES ('051_add_instance_egress_policy')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,23 @@
+-- Managed S3 buckets holding instance file uploads. Rows outlive their
+-- instance (instance_id becomes NULL) until the bucket has been deleted.
+CREATE TABLE instance_storage (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    instance_id UUID UNIQUE REFERENCES instances(id) ON DELETE SET NULL,
+    bucket VARCHAR(63) NOT NULL UNIQUE,
+    region VARCHAR(32) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    access_key_id VARCHAR(128),
+    status VARCHAR(16) NOT NULL DEFAULT 'provisioning',
+    bytes_used BIGINT NOT NULL DEFAULT 0,
+    objects BIGINT NOT NULL DEFAULT 0,
+    usage_updated_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT instance_storage_status_check CHECK (status IN ('provisioning', 'active', 'deleting'))
+);
+
+CREATE INDEX idx_instance_storage_status ON instance_storage (status);
+
+COMMENT ON TABLE instance_storage IS 'Managed S3 buckets: provisioning, active (set as the instance''s PocketBase S3 storage), deleting';
+
+INSERT INTO schema_migrations (version) VALUES ('052_create_instance_storage_table')
+ON CONFLICT (version) DO NOTHING;
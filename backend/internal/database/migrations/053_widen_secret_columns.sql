@@ -0,0 +1,13 @@
+-- Secrets are stored as envelope-encrypted values (see internal/secrets),
+-- which are longer than the plaintext they replace
+ALTER TABLE instances
+    ALTER COLUMN encryption_key TYPE TEXT;
+
+ALTER TABLE webhook_endpoints
+    ALTER COLUMN secret TYPE TEXT;
+
+COMMENT ON COLUMN instances.encryption_key IS 'PocketBase settings encryption key, encrypted with the secrets vault';
+COMMENT ON COLUMN webhook_endpoints.secret IS 'Delivery signing secret, encrypted with the secrets vault';
+
+INSERT INTO schema_migrations (version) VALUES ('053_widen_secret_columns')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,17 @@
+-- An instance has at most one queued or running build, also when uploads race
+-- past the check in the API. Older duplicates are failed first.
+UPDATE instance_builds b
+SET status = 'failed', error = 'superseded by a newer build', finished_at = NOW()
+WHERE status IN ('queued', 'building')
+  AND EXISTS (
+      SELECT 1 FROM instance_builds newer
+      WHERE newer.instance_id = b.instance_id
+        AND newer.status IN ('queued', 'building')
+        AND (newer.created_at, newer.id) > (b.created_at, b.id)
+  );
+
+CREATE UNIQUE INDEX idx_instance_builds_in_progress ON instance_builds (instance_id)
+    WHERE status IN ('queued', 'building');
+
+INSERT INTO schema_migrations (version) VALUES ('054_add_instance_builds_in_progress_index')
+ON CONFLICT (version) DO NOTHING;
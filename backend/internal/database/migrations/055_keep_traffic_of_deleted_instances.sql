@@ -0,0 +1,19 @@
+-- Egress counts against the owner's monthly quota also after the instance is
+-- deleted or archived, so traffic rows are kept and attributed to the user
+-- rather than cascading away with the instance.
+ALTER TABLE instance_traffic_daily ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+UPDATE instance_traffic_daily t
+SET user_id = i.user_id
+FROM instances i
+WHERE i.id = t.instance_id;
+
+ALTER TABLE instance_traffic_daily ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE instance_traffic_daily DROP CONSTRAINT instance_traffic_daily_instance_id_fkey;
+
+CREATE INDEX idx_instance_traffic_daily_user_day ON instance_traffic_daily (user_id, day);
+
+COMMENT ON COLUMN instance_traffic_daily.user_id IS 'Owner the egress is billed to; rows outlive the instance';
+
+INSERT INTO schema_migrations (version) VALUES ('055_keep_traffic_of_deleted_instances')
+ON CONFLICT (version) DO NOTHING;
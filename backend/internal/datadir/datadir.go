@@ -0,0 +1,42 @@
+// Package datadir opens files inside instance data directories. Tenants can
+// write to those (PocketBase, hooks and custom builds run there), so symlinks
+// in them are never followed: a planted link must not redirect the backend,
+// which runs as root, to a host file or another instance's data.
+package datadir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrUnsafePath is returned for entries that are symlinks, or that aren't the
+// regular file or directory they are opened as
+var ErrUnsafePath = errors.New("not a regular file or directory")
+
+// Dir is a directory opened inside a data directory. Files are opened and
+// renamed relative to it, so replacing its path with a symlink afterwards has
+// no effect.
+type Dir struct {
+	file *os.File
+}
+
+// Close closes the directory
+func (d *Dir) Close() error {
+	return d.file.Close()
+}
+
+// Open opens a regular file in the directory for reading
+func (d *Dir) Open(name string) (*os.File, error) {
+	return d.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// ReadDir lists the entries of the directory
+func (d *Dir) ReadDir() ([]os.DirEntry, error) {
+	return d.file.ReadDir(-1)
+}
+
+// validName reports whether name is a single path component
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
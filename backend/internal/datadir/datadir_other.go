@@ -0,0 +1,28 @@
+//go:build !unix
+
+package datadir
+
+import (
+	"fmt"
+	"os"
+)
+
+// OpenDir is not supported on this platform
+func OpenDir(dataPath, name string, create bool) (*Dir, error) {
+	return nil, fmt.Errorf("data directories are not supported on this platform")
+}
+
+// OpenFile is not supported on this platform
+func (d *Dir) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, fmt.Errorf("data directories are not supported on this platform")
+}
+
+// Rename is not supported on this platform
+func (d *Dir) Rename(oldName, newName string) error {
+	return fmt.Errorf("data directories are not supported on this platform")
+}
+
+// MoveIn is not supported on this platform
+func (d *Dir) MoveIn(path, name string) error {
+	return fmt.Errorf("data directories are not supported on this platform")
+}
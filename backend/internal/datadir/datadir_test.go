@@ -0,0 +1,120 @@
+//go:build unix
+
+package datadir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestOpenDirRejectsSymlinks(t *testing.T) {
+	dataPath, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dataPath, "logs")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenDir(dataPath, "logs", true); !errors.Is(err, ErrUnsafePath) {
+		t.Fatalf("OpenDir() through a symlink error = %v, want ErrUnsafePath", err)
+	}
+}
+
+func TestOpenDirCreates(t *testing.T) {
+	dataPath := t.TempDir()
+
+	dir, err := OpenDir(dataPath, "logs", true)
+	if err != nil {
+		t.Fatalf("OpenDir() error = %v", err)
+	}
+	defer dir.Close()
+
+	if info, err := os.Lstat(filepath.Join(dataPath, "logs")); err != nil || !info.IsDir() {
+		t.Fatalf("logs directory was not created: %v", err)
+	}
+	if _, err := OpenDir(dataPath, "missing", false); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenDir() of a missing directory error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestOpenFileRejectsUnsafeEntries(t *testing.T) {
+	dataPath := t.TempDir()
+	target := filepath.Join(t.TempDir(), "host-file")
+	if err := os.WriteFile(target, []byte("host"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := OpenDir(dataPath, "backups", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	backups := filepath.Join(dataPath, "backups")
+	if err := os.Symlink(target, filepath.Join(backups, "link.zip")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(backups, "fifo.zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(backups, "dir.zip"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(target, filepath.Join(backups, "hardlink.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"link.zip", "fifo.zip", "dir.zip", "../backups", ""} {
+		if file, err := dir.Open(name); !errors.Is(err, ErrUnsafePath) {
+			if file != nil {
+				file.Close()
+			}
+			t.Errorf("Open(%q) error = %v, want ErrUnsafePath", name, err)
+		}
+	}
+	if _, err := dir.OpenFile("hardlink.log", os.O_APPEND|os.O_WRONLY, 0); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("OpenFile() of a hard link for writing error = %v, want ErrUnsafePath", err)
+	}
+	if _, err := dir.OpenFile("link.zip", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("OpenFile() of a symlink for writing error = %v, want ErrUnsafePath", err)
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "host" {
+		t.Errorf("the symlink target was changed to %q", data)
+	}
+}
+
+func TestOpenFileAndRename(t *testing.T) {
+	dataPath := t.TempDir()
+	dir, err := OpenDir(dataPath, "logs", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	file, err := dir.OpenFile("container.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	file.WriteString("line\n")
+	file.Close()
+
+	// Renaming replaces a symlink rather than writing through it
+	target := filepath.Join(t.TempDir(), "host-file")
+	if err := os.Symlink(target, filepath.Join(dataPath, "logs", "container.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.Rename("container.log", "container.log.1"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+
+	rotated, err := dir.Open("container.log.1")
+	if err != nil {
+		t.Fatalf("Open() of the rotated file error = %v", err)
+	}
+	rotated.Close()
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("the symlink target was created: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+//go:build unix
+
+package datadir
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenDir opens the directory name (a single path component) of a data
+// directory, creating it when create is set and it doesn't exist
+func OpenDir(dataPath, name string, create bool) (*Dir, error) {
+	if !validName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	root, err := unix.Open(dataPath, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, pathError("open", dataPath, err)
+	}
+	defer unix.Close(root)
+
+	fd, err := unix.Openat(root, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) && create {
+		if err := unix.Mkdirat(root, name, 0755); err != nil && !errors.Is(err, unix.EEXIST) {
+			return nil, pathError("mkdir", filepath.Join(dataPath, name), err)
+		}
+		fd, err = unix.Openat(root, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	}
+	if err != nil {
+		return nil, pathError("open", filepath.Join(dataPath, name), err)
+	}
+
+	return &Dir{file: os.NewFile(uintptr(fd), filepath.Join(dataPath, name))}, nil
+}
+
+// OpenFile opens a regular file in the directory. Files opened for writing
+// must not have other hard links either.
+func (d *Dir) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	path := filepath.Join(d.file.Name(), name)
+	if !validName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsafePath, path)
+	}
+
+	// O_NONBLOCK keeps a planted FIFO from blocking the open
+	fd, err := unix.Openat(int(d.file.Fd()), name, flag|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		return nil, pathError("open", path, err)
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		unix.Close(fd)
+		return nil, pathError("stat", path, err)
+	}
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if stat.Mode&unix.S_IFMT != unix.S_IFREG || (writable && stat.Nlink > 1) {
+		unix.Close(fd)
+		return nil, fmt.Errorf("%w: %s", ErrUnsafePath, path)
+	}
+	if err := unix.SetNonblock(fd, false); err != nil {
+		unix.Close(fd)
+		return nil, pathError("open", path, err)
+	}
+
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// Rename renames an entry of the directory, replacing newName (which is not
+// followed if it is a symlink)
+func (d *Dir) Rename(oldName, newName string) error {
+	if !validName(oldName) || !validName(newName) {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, filepath.Join(d.file.Name(), oldName))
+	}
+	fd := int(d.file.Fd())
+	if err := unix.Renameat(fd, oldName, fd, newName); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+	}
+	return nil
+}
+
+// MoveIn moves the file at path, outside the data directory but on the same
+// filesystem, into the directory as name. It fails if name exists.
+func (d *Dir) MoveIn(path, name string) error {
+	if !validName(name) {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, filepath.Join(d.file.Name(), name))
+	}
+	// Unlike a rename, a hard link never replaces an entry (symlinks included)
+	if err := unix.Linkat(unix.AT_FDCWD, path, int(d.file.Fd()), name, 0); err != nil {
+		return &os.LinkError{Op: "link", Old: path, New: filepath.Join(d.file.Name(), name), Err: err}
+	}
+	return os.Remove(path)
+}
+
+// pathError reports a failed operation, with symlinks as ErrUnsafePath
+func pathError(op, path string, err error) error {
+	if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.ENOTDIR) {
+		return fmt.Errorf("%w: %s", ErrUnsafePath, path)
+	}
+	return &os.PathError{Op: op, Path: path, Err: err}
+}
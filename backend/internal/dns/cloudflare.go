@@ -0,0 +1,116 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflare manages records of one zone with an API token that has DNS edit permission
+type cloudflare struct {
+	token      string
+	zoneID     string
+	httpClient *http.Client
+}
+
+// cloudflareRecord is a record as the API returns it
+type cloudflareRecord struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// cloudflareResponse is the envelope of every API response
+type cloudflareResponse struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (c *cloudflare) FindRecord(ctx context.Context, name string) (*Record, error) {
+	var records []cloudflareRecord
+	if err := c.do(ctx, http.MethodGet, "/dns_records?name="+url.QueryEscape(name), nil, &records); err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		if r.Type == "A" || r.Type == "AAAA" || r.Type == "CNAME" {
+			return &Record{Type: r.Type, Name: r.Name, Content: r.Content}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *cloudflare) CreateRecord(ctx context.Context, record Record) error {
+	var existing []cloudflareRecord
+	query := "/dns_records?type=" + url.QueryEscape(record.Type) + "&name=" + url.QueryEscape(record.Name)
+	if err := c.do(ctx, http.MethodGet, query, nil, &existing); err != nil {
+		return err
+	}
+
+	// DNS only (not proxied): Cloudflare's proxy would hide the origin from Traefik's host rules
+	body := map[string]interface{}{
+		"type":    record.Type,
+		"name":    record.Name,
+		"content": record.Content,
+		"ttl":     1,
+		"proxied": false,
+	}
+	if len(existing) > 0 {
+		return c.do(ctx, http.MethodPut, "/dns_records/"+existing[0].ID, body, nil)
+	}
+	return c.do(ctx, http.MethodPost, "/dns_records", body, nil)
+}
+
+// do calls a zone endpoint and decodes the result into out (if not nil)
+func (c *cloudflare) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode Cloudflare request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+"/zones/"+c.zoneID+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build Cloudflare request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Cloudflare: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode Cloudflare response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if !envelope.Success {
+		message := fmt.Sprintf("HTTP %d", resp.StatusCode)
+		if len(envelope.Errors) > 0 {
+			message = envelope.Errors[0].Message
+		}
+		return fmt.Errorf("Cloudflare API error: %s", message)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return fmt.Errorf("failed to decode Cloudflare response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package dns manages the wildcard records instance subdomains resolve
+// through, at a DNS provider's API, and checks whether they have propagated.
+package dns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"pocketploy/internal/sigv4"
+)
+
+// Supported providers
+const (
+	ProviderCloudflare = "cloudflare"
+	ProviderRoute53    = "route53"
+)
+
+// Record is an address record at the provider
+type Record struct {
+	Type    string `json:"type"` // A, AAAA or CNAME
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Provider reads and writes records through a DNS provider's API
+type Provider interface {
+	// FindRecord returns the A, AAAA or CNAME record of name (nil if there is none)
+	FindRecord(ctx context.Context, name string) (*Record, error)
+
+	// CreateRecord creates the record, replacing one of the same name and type
+	CreateRecord(ctx context.Context, record Record) error
+}
+
+// Credentials configure the provider; only the fields of the chosen provider are used
+type Credentials struct {
+	CloudflareAPIToken string
+	CloudflareZoneID   string
+
+	Route53HostedZoneID string
+	AWSAccessKeyID      string
+	AWSSecretAccessKey  string
+	AWSSessionToken     string
+}
+
+// NewProvider creates the client for the given provider.
+// It returns nil when provider is empty, which disables DNS automation.
+func NewProvider(provider string, credentials Credentials) (Provider, error) {
+	httpClient := &http.Client{Timeout: 15 * time.Second}
+
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "":
+		return nil, nil
+	case ProviderCloudflare:
+		return &cloudflare{token: credentials.CloudflareAPIToken, zoneID: credentials.CloudflareZoneID, httpClient: httpClient}, nil
+	case ProviderRoute53:
+		return &route53{
+			hostedZoneID: strings.TrimPrefix(credentials.Route53HostedZoneID, "/hostedzone/"),
+			credentials: sigv4.Credentials{
+				AccessKeyID:     credentials.AWSAccessKeyID,
+				SecretAccessKey: credentials.AWSSecretAccessKey,
+				SessionToken:    credentials.AWSSessionToken,
+			},
+			httpClient: httpClient,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DNS provider: %s", provider)
+	}
+}
+
+// WildcardRecord returns the record pointing every subdomain of domain at
+// target: an A or AAAA record for an IP address, a CNAME otherwise
+func WildcardRecord(domain, target string) Record {
+	record := Record{Type: "CNAME", Name: "*." + domain, Content: target}
+	if ip := net.ParseIP(target); ip != nil {
+		record.Type = "AAAA"
+		if ip.To4() != nil {
+			record.Type = "A"
+		}
+	}
+	return record
+}
+
+// Propagation is what public resolvers return for a host
+type Propagation struct {
+	Host       string   `json:"host"`
+	Resolves   bool     `json:"resolves"`
+	Addresses  []string `json:"addresses,omitempty"`
+	Propagated bool     `json:"propagated"` // resolves to the target (or at all, without one)
+	Error      string   `json:"error,omitempty"`
+}
+
+// CheckHost resolves host and compares the answer with target (an IP
+// address or a hostname the record points at; empty accepts any answer)
+func CheckHost(ctx context.Context, host, target string) Propagation {
+	result := Propagation{Host: host}
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Resolves = true
+	result.Addresses = addresses
+
+	if target == "" {
+		result.Propagated = true
+		return result
+	}
+
+	expected := []string{target}
+	if net.ParseIP(target) == nil {
+		expected, err = net.DefaultResolver.LookupHost(ctx, target)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to resolve target %s: %v", target, err)
+			return result
+		}
+	}
+	for _, address := range addresses {
+		for _, want := range expected {
+			if net.ParseIP(address).Equal(net.ParseIP(want)) {
+				result.Propagated = true
+				return result
+			}
+		}
+	}
+
+	result.Error = fmt.Sprintf("resolves to %s instead of %s", strings.Join(addresses, ", "), target)
+	return result
+}
+
+// CheckWildcard resolves a random subdomain of domain, as new instances get one
+func CheckWildcard(ctx context.Context, domain, target string) Propagation {
+	label := make([]byte, 6)
+	_, _ = rand.Read(label)
+	return CheckHost(ctx, "dns-check-"+hex.EncodeToString(label)+"."+domain, target)
+}
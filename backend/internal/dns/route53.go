@@ -0,0 +1,137 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pocketploy/internal/sigv4"
+)
+
+const (
+	route53Host   = "route53.amazonaws.com"
+	route53Region = "us-east-1" // Route 53 is global; requests are signed for us-east-1
+	route53TTL    = 300
+)
+
+// route53 manages records of one hosted zone with IAM access keys
+// (route53:ListResourceRecordSets and route53:ChangeResourceRecordSets)
+type route53 struct {
+	hostedZoneID string
+	credentials  sigv4.Credentials
+	httpClient   *http.Client
+}
+
+type route53RecordSet struct {
+	Name            string   `xml:"Name"`
+	Type            string   `xml:"Type"`
+	TTL             int      `xml:"TTL,omitempty"`
+	ResourceRecords []string `xml:"ResourceRecords>ResourceRecord>Value"`
+}
+
+type route53ListResponse struct {
+	RecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name         `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Action  string           `xml:"ChangeBatch>Changes>Change>Action"`
+	Set     route53RecordSet `xml:"ChangeBatch>Changes>Change>ResourceRecordSet"`
+}
+
+type route53Error struct {
+	Message string `xml:"Error>Message"`
+}
+
+func (r *route53) FindRecord(ctx context.Context, name string) (*Record, error) {
+	query := url.Values{"name": {name}, "maxitems": {"10"}}
+
+	var list route53ListResponse
+	if err := r.do(ctx, http.MethodGet, "/rrset", query, nil, &list); err != nil {
+		return nil, err
+	}
+
+	// Sets are listed from name onwards, so later ones may belong to other names
+	for _, set := range list.RecordSets {
+		if route53Name(set.Name) != strings.TrimSuffix(name, ".") {
+			break
+		}
+		if (set.Type == "A" || set.Type == "AAAA" || set.Type == "CNAME") && len(set.ResourceRecords) > 0 {
+			return &Record{Type: set.Type, Name: name, Content: set.ResourceRecords[0]}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *route53) CreateRecord(ctx context.Context, record Record) error {
+	change := route53ChangeRequest{
+		Action: "UPSERT",
+		Set: route53RecordSet{
+			Name:            record.Name,
+			Type:            record.Type,
+			TTL:             route53TTL,
+			ResourceRecords: []string{record.Content},
+		},
+	}
+	body, err := xml.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to encode Route 53 request: %w", err)
+	}
+
+	return r.do(ctx, http.MethodPost, "/rrset/", nil, append([]byte(xml.Header), body...), nil)
+}
+
+// route53Name undoes the octal escaping Route 53 applies to names ("\052" is "*")
+func route53Name(name string) string {
+	return strings.TrimSuffix(strings.ReplaceAll(name, `\052`, "*"), ".")
+}
+
+// do calls a hosted zone endpoint with a SigV4-signed request and decodes the XML response into out (if not nil)
+func (r *route53) do(ctx context.Context, method, path string, query url.Values, body []byte, out interface{}) error {
+	uri := "/2013-04-01/hostedzone/" + r.hostedZoneID + path
+	rawQuery := sigv4.CanonicalQuery(query)
+
+	endpoint := "https://" + route53Host + uri
+	if rawQuery != "" {
+		endpoint += "?" + rawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build Route 53 request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	sigv4.Sign(req, body, r.credentials, route53Region, "route53", time.Now())
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Route 53: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Route 53 response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr route53Error
+		if xml.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("Route 53 API error: %s", apiErr.Message)
+		}
+		return fmt.Errorf("Route 53 API error: HTTP %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := xml.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode Route 53 response: %w", err)
+		}
+	}
+	return nil
+}
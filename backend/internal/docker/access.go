@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"pocketploy/internal/config"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// AccessRules restrict who reaches an instance through Traefik
+type AccessRules struct {
+	// BasicAuthUser is "username:bcrypt-hash"; empty disables basic auth.
+	// Traefik strips the header, so PocketBase never sees the credentials.
+	BasicAuthUser string
+
+	// AllowedIPs are the CIDR ranges clients must connect from (everyone if empty)
+	AllowedIPs []string
+}
+
+// accessLabels renders the middlewares of a router: the error pages and the
+// instance's access rules
+func accessLabels(cfg *config.Config, routerName string, rules AccessRules) map[string]string {
+	labels := make(map[string]string)
+	middlewares := []string{errorPagesMiddleware}
+
+	if len(rules.AllowedIPs) > 0 {
+		name := routerName + "-allowlist"
+		prefix := "traefik.http.middlewares." + name + ".ipallowlist."
+		labels[prefix+"sourcerange"] = strings.Join(rules.AllowedIPs, ",")
+		if cfg.TraefikForwardedDepth > 0 {
+			labels[prefix+"ipstrategy.depth"] = strconv.Itoa(cfg.TraefikForwardedDepth)
+		}
+		middlewares = append(middlewares, name+"@docker")
+	}
+
+	if rules.BasicAuthUser != "" {
+		name := routerName + "-basicauth"
+		prefix := "traefik.http.middlewares." + name + ".basicauth."
+		labels[prefix+"users"] = rules.BasicAuthUser
+		labels[prefix+"removeheader"] = "true"
+		middlewares = append(middlewares, name+"@docker")
+	}
+
+	labels[fmt.Sprintf("traefik.http.routers.%s.middlewares", routerName)] = strings.Join(middlewares, ",")
+	return labels
+}
+
+// UpdateAccessRules recreates a container with new access middlewares. It
+// returns the new container ID.
+func (c *Client) UpdateAccessRules(ctx context.Context, containerID string, rules AccessRules) (string, error) {
+	id, err := c.recreateContainer(ctx, containerID, func(config *container.Config, _ *container.HostConfig) {
+		routerName := labelRouterName(config.Labels)
+		for key := range config.Labels {
+			if strings.HasPrefix(key, "traefik.http.middlewares."+routerName+"-") {
+				delete(config.Labels, key)
+			}
+		}
+		for key, value := range accessLabels(c.config, routerName, rules) {
+			config.Labels[key] = value
+		}
+	})
+	if err == nil {
+		log.Printf("Recreated container %s with new access rules", id)
+	}
+	return id, err
+}
+
+// labelRouterName finds the Traefik router a container's labels define
+func labelRouterName(labels map[string]string) string {
+	for key := range labels {
+		if name, ok := strings.CutPrefix(key, "traefik.http.routers."); ok {
+			if name, ok := strings.CutSuffix(name, ".rule"); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
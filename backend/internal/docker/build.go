@@ -0,0 +1,317 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// Where the source is copied in the builder container and where the
+	// compiled binary is written
+	buildSourceDir = "/tmp/src"
+	buildOutput    = "/tmp/out/pocketbase"
+
+	// buildPidsLimit bounds the processes of a builder container
+	buildPidsLimit = 512
+)
+
+// buildScript compiles the module in a writable copy of the source (the
+// copied files belong to root, the build runs as the container user)
+const buildScript = `set -e
+cp -R ` + buildSourceDir + ` /tmp/work
+cd /tmp/work
+go mod download
+mkdir -p /tmp/out
+CGO_ENABLED=0 go build -trimpath -ldflags '-s -w' -o ` + buildOutput + ` .
+`
+
+// ErrBuildFailed is returned when the uploaded module doesn't compile; the
+// returned log has the compiler output
+var ErrBuildFailed = errors.New("build failed")
+
+// BuildPocketBase compiles the Go module in sourceDir into a PocketBase binary
+// and tags an image of BUILD_BASE_IMAGE with it. The compiler runs in a
+// throwaway container of BUILDER_IMAGE without mounts, capabilities or
+// privileges; it keeps network access so module dependencies can be
+// downloaded. It returns the build output.
+func (c *Client) BuildPocketBase(ctx context.Context, sourceDir, tag string) (string, error) {
+	var output bytes.Buffer
+
+	binary, err := c.compilePocketBase(ctx, sourceDir, &output)
+	if err != nil {
+		return output.String(), err
+	}
+
+	if err := c.buildImage(ctx, binary, tag, &output); err != nil {
+		return output.String(), err
+	}
+
+	log.Printf("Built image %s", tag)
+	return output.String(), nil
+}
+
+// compilePocketBase runs the builder container and returns the binary it built
+func (c *Client) compilePocketBase(ctx context.Context, sourceDir string, output *bytes.Buffer) ([]byte, error) {
+	source, err := tarDirectory(sourceDir, path.Base(buildSourceDir))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.pullImageIfNeeded(ctx, c.config.BuilderImage); err != nil {
+		return nil, fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	pidsLimit := int64(buildPidsLimit)
+	containerConfig := &container.Config{
+		Image:      c.config.BuilderImage,
+		Entrypoint: []string{"sh", "-c"},
+		Cmd:        []string{buildScript},
+		User:       c.config.ContainerUser,
+		Env: []string{
+			"HOME=/tmp",
+			"GOPATH=/tmp/go",
+			"GOCACHE=/tmp/cache",
+			"GOFLAGS=-mod=mod",
+		},
+	}
+	hostConfig := &container.HostConfig{
+		CapDrop:     []string{"ALL"},
+		SecurityOpt: []string{"no-new-privileges:true"},
+		Resources: container.Resources{
+			Memory:    c.config.BuildMemory,
+			PidsLimit: &pidsLimit,
+		},
+	}
+	if c.config.ContainerUsernsMode != "" {
+		hostConfig.UsernsMode = container.UsernsMode(c.config.ContainerUsernsMode)
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create builder container: %w", err)
+	}
+	defer func() {
+		_ = c.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := c.cli.CopyToContainer(ctx, resp.ID, path.Dir(buildSourceDir), source, container.CopyToContainerOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to copy source to builder container: %w", err)
+	}
+
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start builder container: %w", err)
+	}
+
+	var exitCode int64
+	waitCh, errCh := c.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case result := <-waitCh:
+		exitCode = result.StatusCode
+	case err := <-errCh:
+		return nil, fmt.Errorf("failed to wait for build: %w", err)
+	}
+
+	reader, err := c.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build output: %w", err)
+	}
+	defer reader.Close()
+
+	if _, err := stdcopy.StdCopy(output, output, reader); err != nil {
+		return nil, fmt.Errorf("failed to read build output: %w", err)
+	}
+
+	if exitCode != 0 {
+		return nil, fmt.Errorf("%w: compiler exited with code %d", ErrBuildFailed, exitCode)
+	}
+
+	return c.copyBinary(ctx, resp.ID)
+}
+
+// copyBinary reads the compiled binary out of the builder container
+func (c *Client) copyBinary(ctx context.Context, containerID string) ([]byte, error) {
+	reader, _, err := c.cli.CopyFromContainer(ctx, containerID, buildOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy binary from builder container: %w", err)
+	}
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: no binary was produced", ErrBuildFailed)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read binary: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		binary, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read binary: %w", err)
+		}
+		return binary, nil
+	}
+}
+
+// buildImage tags an image of BUILD_BASE_IMAGE with the binary as the
+// PocketBase executable
+func (c *Client) buildImage(ctx context.Context, binary []byte, tag string, output *bytes.Buffer) error {
+	base := c.config.BuildBaseImage
+	if err := c.pullImageIfNeeded(ctx, base); err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	dockerfile := "FROM " + base + "\n" +
+		"COPY pocketbase " + pocketBaseBinary + "\n" +
+		"EXPOSE 8090\n" +
+		"ENTRYPOINT [\"" + pocketBaseBinary + "\"]\n" +
+		"CMD [\"serve\", \"--http=0.0.0.0:8090\", \"--dir=" + pocketBaseDataDir + "\"]\n"
+
+	var buildContext bytes.Buffer
+	archive := tar.NewWriter(&buildContext)
+	files := []struct {
+		name    string
+		mode    int64
+		content []byte
+	}{
+		{"Dockerfile", 0644, []byte(dockerfile)},
+		{"pocketbase", 0755, binary},
+	}
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: file.mode, Size: int64(len(file.content))}
+		if err := archive.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write build context: %w", err)
+		}
+		if _, err := archive.Write(file.content); err != nil {
+			return fmt.Errorf("failed to write build context: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write build context: %w", err)
+	}
+
+	resp, err := c.cli.ImageBuild(ctx, &buildContext, build.ImageBuildOptions{
+		Tags:        []string{tag},
+		Remove:      true,
+		ForceRemove: true,
+		NetworkMode: "none",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The daemon streams JSON messages; a failed step is reported in one of
+	// them, not as an error of the request
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read image build output: %w", err)
+		}
+
+		output.WriteString(message.Stream)
+		if message.Error != "" {
+			output.WriteString(message.Error + "\n")
+			return fmt.Errorf("failed to build image: %s", message.Error)
+		}
+	}
+}
+
+// RemoveImage deletes a local image, e.g. a build that was replaced. Images
+// still used by a container are left alone.
+func (c *Client) RemoveImage(ctx context.Context, ref string) error {
+	if _, err := c.cli.ImageRemove(ctx, ref, image.RemoveOptions{PruneChildren: true}); err != nil {
+		return fmt.Errorf("failed to remove image %s: %w", ref, err)
+	}
+	return nil
+}
+
+// UpdateImage recreates a container from another image, keeping its
+// command, environment, mounts and networks. It returns the new container ID.
+func (c *Client) UpdateImage(ctx context.Context, containerID, ref string) (string, error) {
+	id, err := c.recreateContainer(ctx, containerID, func(config *container.Config, _ *container.HostConfig) {
+		config.Image = ref
+	})
+	if err == nil {
+		log.Printf("Recreated container %s from image %s", id, ref)
+	}
+	return id, err
+}
+
+// ContainerImage returns the image reference a container was created from
+func (c *Client) ContainerImage(ctx context.Context, containerID string) (string, error) {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return inspect.Config.Image, nil
+}
+
+// tarDirectory archives the regular files and directories under dir, with
+// their paths prefixed by prefix
+func tarDirectory(dir, prefix string) (io.Reader, error) {
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+
+		if entry.IsDir() {
+			return archive.WriteHeader(&tar.Header{Name: name + "/", Typeflag: tar.TypeDir, Mode: 0755})
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			return err
+		}
+		_, err = archive.Write(content)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive source: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to archive source: %w", err)
+	}
+
+	return &buf, nil
+}
@@ -1,20 +1,26 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"pocketploy/internal/config"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -22,6 +28,7 @@ import (
 type Client struct {
 	cli    *client.Client
 	config *config.Config
+	router Router
 }
 
 // NewClient creates a new Docker client
@@ -45,55 +52,113 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	return &Client{
 		cli:    cli,
 		config: cfg,
+		router: newRouter(cfg),
 	}, nil
 }
 
+const (
+	// pocketBaseBinary is the PocketBase executable inside the image
+	pocketBaseBinary = "/usr/local/bin/pocketbase"
+
+	// pocketBaseDataDir is where the instance storage path is mounted
+	pocketBaseDataDir = "/pb_data"
+
+	// superuserUpsertAttempts bounds retries while PocketBase finishes booting
+	superuserUpsertAttempts = 5
+
+	// commandTimeout bounds how long a maintenance command may run
+	commandTimeout = 2 * time.Minute
+)
+
+// allowedPocketBaseCommands whitelists the pocketbase subcommands that users may
+// run inside their containers, keyed by command and then subcommand
+var allowedPocketBaseCommands = map[string]map[string]bool{
+	"migrate": {
+		"up":           true,
+		"down":         true,
+		"collections":  true,
+		"history-sync": true,
+	},
+	"superuser": {
+		"upsert": true,
+		"create": true,
+		"update": true,
+		"delete": true,
+		"otp":    true,
+	},
+}
+
 // ContainerConfig holds configuration for creating a PocketBase container
 type ContainerConfig struct {
 	ContainerName string
 	Subdomain     string
-	StoragePath   string
-	Username      string
-	InstanceSlug  string
+
+	// Traefik entrypoint ("web" if empty) and the network the container joins
+	// and Traefik routes through (DOCKER_NETWORK/TRAEFIK_NETWORK if empty)
+	TraefikEntrypoint string
+	Network           string
+
+	// HostPort publishes the container on this host port instead of routing
+	// it through Traefik (ROUTING_MODE=port)
+	HostPort int
+
+	StoragePath  string
+	Username     string
+	InstanceSlug string
+
+	// Superuser to create; empty for clones, which keep their source's superusers
 	AdminEmail    string
 	AdminPassword string
+
+	// Optional pocketbase serve flags and proxy access rules
+	Serve  ServeFlags
+	Access AccessRules
+
+	// Image to run instead of POCKETBASE_IMAGE (an approved, digest-pinned image)
+	Image string
 }
 
-// CreatePocketBaseContainer creates and starts a new PocketBase container with Traefik labels
+// CreatePocketBaseContainer creates and starts a new PocketBase container and publishes its route
 func (c *Client) CreatePocketBaseContainer(ctx context.Context, cfg ContainerConfig) (string, error) {
 	// Ensure storage directory exists
 	if err := os.MkdirAll(cfg.StoragePath, 0755); err != nil {
 		return "", fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
-	// Pull the PocketBase image if not already present
-	if err := c.pullImageIfNeeded(ctx); err != nil {
-		return "", fmt.Errorf("failed to pull image: %w", err)
-	}
-
-	// Create entrypoint script that sets up admin and starts server
-	entrypointScript := fmt.Sprintf(`#!/bin/sh
-set -e
-echo "Setting up PocketBase superuser..."
-/usr/local/bin/pocketbase superuser upsert %s %s || true
-echo "Starting PocketBase server..."
-exec /usr/local/bin/pocketbase serve --http=0.0.0.0:8090
-`, cfg.AdminEmail, cfg.AdminPassword)
+	// Hand the data directory to the unprivileged container user
+	c.ChownStoragePath(cfg.StoragePath)
 
-	// Write entrypoint script to storage directory
-	entrypointPath := filepath.Join(cfg.StoragePath, "entrypoint.sh")
-	if err := os.WriteFile(entrypointPath, []byte(entrypointScript), 0755); err != nil {
-		return "", fmt.Errorf("failed to create entrypoint script: %w", err)
+	// Pull the PocketBase image if not already present (admins can change the
+	// image at runtime, so read it once for this container)
+	image := cfg.Image
+	if image == "" {
+		image = c.config.Settings().PocketBaseImage
+	}
+	if err := c.pullImageIfNeeded(ctx, image); err != nil {
+		return "", fmt.Errorf("failed to pull image: %w", err)
 	}
 
 	// Prepare container configuration
 	containerConfig := &container.Config{
-		Image:      c.config.PocketBaseImage,
-		Entrypoint: []string{"/pb_data/entrypoint.sh"},
+		Image:      image,
+		Entrypoint: []string{pocketBaseBinary},
+		Cmd:        serveCommand(cfg.Serve),
+		Env:        serveEnv(nil, cfg.Serve),
 		ExposedPorts: nat.PortSet{
 			"8090/tcp": struct{}{},
 		},
-		Labels: c.buildTraefikLabels(cfg),
+		User: c.config.ContainerUser,
+	}
+
+	route := Route{
+		Name:       cfg.ContainerName,
+		Host:       cfg.Subdomain,
+		Entrypoint: cfg.TraefikEntrypoint,
+		Network:    cfg.Network,
+		Access:     cfg.Access,
+	}
+	if cfg.HostPort == 0 {
+		containerConfig.Labels = c.router.Labels(route)
 	}
 
 	// Prepare host configuration with volume mount
@@ -110,15 +175,27 @@ exec /usr/local/bin/pocketbase serve --http=0.0.0.0:8090
 			{
 				Type:   mount.TypeBind,
 				Source: absStoragePath,
-				Target: "/pb_data",
+				Target: pocketBaseDataDir,
 			},
 		},
 	}
+	c.applySecurityOptions(hostConfig)
+	c.applyLogOptions(hostConfig)
+
+	if cfg.HostPort > 0 {
+		hostConfig.PortBindings = nat.PortMap{
+			"8090/tcp": []nat.PortBinding{{HostPort: strconv.Itoa(cfg.HostPort)}},
+		}
+	}
 
 	// Network configuration
+	containerNetwork := c.config.DockerNetwork
+	if cfg.Network != "" {
+		containerNetwork = cfg.Network
+	}
 	networkConfig := &network.NetworkingConfig{
 		EndpointsConfig: map[string]*network.EndpointSettings{
-			c.config.DockerNetwork: {},
+			containerNetwork: {},
 		},
 	}
 
@@ -142,10 +219,125 @@ exec /usr/local/bin/pocketbase serve --http=0.0.0.0:8090
 		return "", fmt.Errorf("failed to start container: %w", err)
 	}
 
+	// Create the superuser through a one-shot exec so the credentials never
+	// touch the instance's bind mount or the container's environment
+	if cfg.AdminEmail != "" {
+		if err := c.UpsertSuperuser(ctx, resp.ID, cfg.AdminEmail, cfg.AdminPassword); err != nil {
+			_ = c.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+			return "", fmt.Errorf("failed to set up superuser: %w", err)
+		}
+	}
+
+	if cfg.HostPort == 0 {
+		if err := c.router.AddRoute(ctx, route); err != nil {
+			_ = c.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+			return "", fmt.Errorf("failed to publish route: %w", err)
+		}
+	}
+
 	log.Printf("Created and started PocketBase container: %s (ID: %s)", cfg.ContainerName, resp.ID)
 	return resp.ID, nil
 }
 
+// UpsertSuperuser creates or updates the PocketBase superuser inside a running container
+func (c *Client) UpsertSuperuser(ctx context.Context, containerID, email, password string) error {
+	cmd := []string{pocketBaseBinary, "superuser", "upsert", email, password, "--dir=" + pocketBaseDataDir}
+
+	var lastErr error
+	for attempt := 1; attempt <= superuserUpsertAttempts; attempt++ {
+		output, err := c.exec(ctx, containerID, cmd)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%w: %s", err, strings.TrimSpace(output))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	return fmt.Errorf("failed to upsert superuser: %w", lastErr)
+}
+
+// ValidatePocketBaseCommand checks args against the subcommand whitelist.
+// Flags are rejected so callers cannot point PocketBase at another data directory.
+func ValidatePocketBaseCommand(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("command is not allowed")
+	}
+
+	subcommands, ok := allowedPocketBaseCommands[args[0]]
+	if !ok || !subcommands[args[1]] {
+		return fmt.Errorf("command is not allowed")
+	}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("command is not allowed")
+		}
+	}
+
+	return nil
+}
+
+// RunPocketBaseCommand runs a whitelisted pocketbase subcommand inside a container
+func (c *Client) RunPocketBaseCommand(ctx context.Context, containerID string, args []string) (string, error) {
+	if err := ValidatePocketBaseCommand(args); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := append([]string{pocketBaseBinary}, args...)
+	cmd = append(cmd, "--dir="+pocketBaseDataDir)
+
+	output, err := c.exec(ctx, containerID, cmd)
+	if err != nil {
+		return output, fmt.Errorf("failed to run command: %w", err)
+	}
+
+	log.Printf("Ran pocketbase %s %s in container: %s", args[0], args[1], containerID)
+	return output, nil
+}
+
+// exec runs a command inside a container and returns its combined output
+func (c *Client) exec(ctx context.Context, containerID string, cmd []string) (string, error) {
+	execResp, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := c.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attach.Close()
+
+	// Docker multiplexes stdout and stderr on the same stream
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, attach.Reader); err != nil {
+		return "", fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := c.cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return output.String(), fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	if inspect.ExitCode != 0 {
+		return output.String(), fmt.Errorf("command exited with code %d", inspect.ExitCode)
+	}
+
+	return output.String(), nil
+}
+
 // StopContainer stops a running container
 func (c *Client) StopContainer(ctx context.Context, containerID string) error {
 	timeout := 10 // seconds
@@ -161,17 +353,33 @@ func (c *Client) StopContainer(ctx context.Context, containerID string) error {
 	return nil
 }
 
-// RemoveContainer removes a container
+// RemoveContainer removes a container and its route. Removing a container
+// that no longer exists succeeds, so cleanup can safely be retried.
 func (c *Client) RemoveContainer(ctx context.Context, containerID string) error {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+
 	removeOptions := container.RemoveOptions{
 		Force:         true,
 		RemoveVolumes: true, // Clean up Docker volumes to save disk space
 	}
 
 	if err := c.cli.ContainerRemove(ctx, containerID, removeOptions); err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil
+		}
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
 
+	if err := c.router.RemoveRoute(ctx, strings.TrimPrefix(inspect.Name, "/")); err != nil {
+		return err
+	}
+
 	log.Printf("Removed container: %s", containerID)
 	return nil
 }
@@ -216,27 +424,57 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (st
 	return "stopped", nil
 }
 
-// GetContainerLogs retrieves logs from a container
-func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail string) (string, error) {
-	options := container.LogsOptions{
+// LogOptions selects which container log lines are returned
+type LogOptions struct {
+	Tail  string // e.g., "100" for last 100 lines, "all" for all logs
+	Since string // Unix timestamp or RFC3339 time; empty for no lower bound
+	Until string // Unix timestamp or RFC3339 time; empty for no upper bound
+}
+
+func (o LogOptions) dockerOptions() container.LogsOptions {
+	return container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
-		Tail:       tail, // e.g., "100" for last 100 lines, "all" for all logs
+		Tail:       o.Tail,
+		Since:      o.Since,
+		Until:      o.Until,
 		Timestamps: true,
 	}
+}
 
-	reader, err := c.cli.ContainerLogs(ctx, containerID, options)
+// GetContainerLogs retrieves logs from a container
+func (c *Client) GetContainerLogs(ctx context.Context, containerID string, opts LogOptions) (string, error) {
+	reader, err := c.cli.ContainerLogs(ctx, containerID, opts.dockerOptions())
 	if err != nil {
 		return "", fmt.Errorf("failed to get container logs: %w", err)
 	}
 	defer reader.Close()
 
-	logs, err := io.ReadAll(reader)
-	if err != nil {
+	// Docker multiplexes stdout and stderr on the same stream
+	var logs bytes.Buffer
+	if _, err := stdcopy.StdCopy(&logs, &logs, reader); err != nil {
 		return "", fmt.Errorf("failed to read logs: %w", err)
 	}
 
-	return string(logs), nil
+	return logs.String(), nil
+}
+
+// StreamContainerLogs returns a reader over a container's logs with stdout and
+// stderr demultiplexed into plain text. The caller must close it.
+func (c *Client) StreamContainerLogs(ctx context.Context, containerID string, opts LogOptions) (io.ReadCloser, error) {
+	reader, err := c.cli.ContainerLogs(ctx, containerID, opts.dockerOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container logs: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer reader.Close()
+		_, err := stdcopy.StdCopy(pw, pw, reader)
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
 }
 
 // StartContainer starts a stopped container
@@ -297,43 +535,145 @@ type ContainerStats struct {
 	CreatedAt   string `json:"created_at"`
 }
 
-// buildTraefikLabels creates the necessary Traefik labels for routing
-// Traefik only handles HTTP routing - SSL is terminated at Nginx in production
-func (c *Client) buildTraefikLabels(cfg ContainerConfig) map[string]string {
-	routerName := cfg.ContainerName
-	return map[string]string{
-		"traefik.enable": "true",
-		fmt.Sprintf("traefik.http.routers.%s.rule", routerName):                      fmt.Sprintf("Host(`%s`)", cfg.Subdomain),
-		fmt.Sprintf("traefik.http.routers.%s.entrypoints", routerName):               "web",
-		fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port", routerName): "8090",
-		"traefik.docker.network": c.config.TraefikNetwork,
+// proxyNetworkLabel names the network Traefik reaches a container through
+const proxyNetworkLabel = "traefik.docker.network"
+
+// errorPagesMiddleware replaces gateway errors of an instance with the
+// backend's unavailable page (defined in traefik.dynamic.yml)
+const errorPagesMiddleware = "instance-errors@file"
+
+// traefikEnableLabel makes Traefik route to a container (exposedByDefault is off)
+const traefikEnableLabel = "traefik.enable"
+
+// applySecurityOptions hardens the host configuration so a compromised instance
+// stays contained: read-only root filesystem, no privilege escalation, dropped
+// capabilities and a PID limit. Each option can be relaxed through config.
+func (c *Client) applySecurityOptions(hostConfig *container.HostConfig) {
+	if c.config.ContainerUsernsMode != "" {
+		hostConfig.UsernsMode = container.UsernsMode(c.config.ContainerUsernsMode)
+	}
+
+	if c.config.ContainerReadOnlyRootFS {
+		hostConfig.ReadonlyRootfs = true
+		// PocketBase still needs a writable scratch directory
+		hostConfig.Tmpfs = map[string]string{
+			"/tmp": "rw,noexec,nosuid,size=64m",
+		}
+	}
+
+	if c.config.ContainerNoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+
+	for _, capability := range strings.Split(c.config.ContainerCapDrop, ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			hostConfig.CapDrop = append(hostConfig.CapDrop, capability)
+		}
+	}
+
+	if c.config.ContainerPidsLimit > 0 {
+		pidsLimit := c.config.ContainerPidsLimit
+		hostConfig.Resources.PidsLimit = &pidsLimit
+	}
+}
+
+// applyLogOptions makes Docker rotate the container's json-file log, so a
+// chatty instance can't fill the host's disk
+func (c *Client) applyLogOptions(hostConfig *container.HostConfig) {
+	if c.config.ContainerLogMaxSize <= 0 {
+		return
+	}
+
+	hostConfig.LogConfig = container.LogConfig{
+		Type: "json-file",
+		Config: map[string]string{
+			"max-size": strconv.FormatInt(c.config.ContainerLogMaxSize, 10),
+			"max-file": strconv.Itoa(c.config.ContainerLogMaxFiles),
+		},
 	}
 }
 
-// pullImageIfNeeded pulls the PocketBase image if it's not already present
-func (c *Client) pullImageIfNeeded(ctx context.Context) error {
+// ChownStoragePath gives ownership of the instance data directory and its
+// contents to the configured container user (only numeric "uid:gid" values are supported)
+func (c *Client) ChownStoragePath(storagePath string) {
+	if c.config.ContainerUser == "" {
+		return
+	}
+
+	parts := strings.SplitN(c.config.ContainerUser, ":", 2)
+	uid, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return
+	}
+	gid := uid
+	if len(parts) == 2 {
+		if gid, err = strconv.Atoi(parts[1]); err != nil {
+			return
+		}
+	}
+
+	// Walk the directory, as it already holds files when an instance is cloned
+	err = filepath.WalkDir(storagePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+	if err != nil {
+		// Not fatal - the backend may not run as root in development
+		log.Printf("Warning: failed to chown %s to %d:%d: %v", storagePath, uid, gid, err)
+	}
+}
+
+// pullImageIfNeeded pulls an image if it's not already present
+func (c *Client) pullImageIfNeeded(ctx context.Context, image string) error {
 	// Check if image exists
-	_, _, err := c.cli.ImageInspectWithRaw(ctx, c.config.PocketBaseImage)
+	_, _, err := c.cli.ImageInspectWithRaw(ctx, image)
 	if err == nil {
 		// Image already exists
 		return nil
 	}
 
-	// Pull the image
-	log.Printf("Pulling PocketBase image: %s", c.config.PocketBaseImage)
-	reader, err := c.cli.ImagePull(ctx, c.config.PocketBaseImage, image.PullOptions{})
+	return c.PullImage(ctx, image)
+}
+
+// legacyEntrypointScript replaces entrypoint.sh files written by older releases.
+// Those containers still use /pb_data/entrypoint.sh as their entrypoint, so the
+// file must keep working - it just no longer carries the admin credentials.
+const legacyEntrypointScript = `#!/bin/sh
+set -e
+exec /usr/local/bin/pocketbase serve --http=0.0.0.0:8090
+`
+
+// ScrubEntrypointScript removes plaintext admin credentials from a legacy
+// entrypoint.sh in an instance's storage path. It reports whether the file was rewritten.
+func ScrubEntrypointScript(storagePath string) (bool, error) {
+	entrypointPath := filepath.Join(storagePath, "entrypoint.sh")
+
+	content, err := os.ReadFile(entrypointPath)
 	if err != nil {
-		return fmt.Errorf("failed to pull image: %w", err)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read entrypoint script: %w", err)
 	}
-	defer reader.Close()
 
-	// Wait for pull to complete
-	_, err = io.Copy(io.Discard, reader)
-	if err != nil {
-		return fmt.Errorf("failed to wait for image pull: %w", err)
+	if !strings.Contains(string(content), "superuser upsert") {
+		return false, nil
+	}
+
+	if err := os.WriteFile(entrypointPath, []byte(legacyEntrypointScript), 0755); err != nil {
+		return false, fmt.Errorf("failed to rewrite entrypoint script: %w", err)
 	}
 
-	log.Printf("Successfully pulled image: %s", c.config.PocketBaseImage)
+	return true, nil
+}
+
+// Ping checks that the Docker daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	if _, err := c.cli.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping Docker daemon: %w", err)
+	}
 	return nil
 }
 
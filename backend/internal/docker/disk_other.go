@@ -0,0 +1,15 @@
+//go:build !unix
+
+package docker
+
+import "fmt"
+
+// FreeDiskSpace is not supported on this platform
+func FreeDiskSpace(path string) (int64, error) {
+	return 0, fmt.Errorf("free disk space is not supported on this platform")
+}
+
+// DiskSize is not supported on this platform
+func DiskSize(path string) (int64, error) {
+	return 0, fmt.Errorf("disk size is not supported on this platform")
+}
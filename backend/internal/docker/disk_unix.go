@@ -0,0 +1,30 @@
+//go:build unix
+
+package docker
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// FreeDiskSpace returns the bytes available to unprivileged users on the
+// filesystem holding path (or its nearest existing parent)
+func FreeDiskSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &stat); err != nil {
+		return 0, fmt.Errorf("failed to read free disk space: %w", err)
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// DiskSize returns the total size of the filesystem holding path (or its
+// nearest existing parent)
+func DiskSize(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(existingParent(path), &stat); err != nil {
+		return 0, fmt.Errorf("failed to read disk size: %w", err)
+	}
+
+	return int64(stat.Blocks) * int64(stat.Bsize), nil
+}
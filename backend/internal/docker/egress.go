@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// EgressRules restrict the outbound connections of a container. Connections
+// to loopback and replies to inbound connections (e.g. from Traefik) are
+// always allowed.
+type EgressRules struct {
+	// Restricted enables the rules; when false all connections are allowed
+	Restricted bool
+
+	// AllowDNS allows queries to any DNS server, which the Docker resolver
+	// forwards from inside the container's network namespace
+	AllowDNS bool
+
+	// Destinations are the addresses connections may be made to
+	Destinations []EgressDestination
+}
+
+// EgressDestination allows connections to a range of addresses, on the given
+// TCP and UDP ports (all if empty)
+type EgressDestination struct {
+	Prefix netip.Prefix
+	Ports  []int
+}
+
+// egressScript loads the rulesets into iptables and ip6tables. ip6tables may
+// only fail when the namespace has no IPv6 at all.
+const egressScript = `printf '%s\n' "$RULES_V4" | iptables-restore && { printf '%s\n' "$RULES_V6" | ip6tables-restore || [ ! -e /proc/net/if_inet6 ]; }`
+
+// ApplyEgressRules replaces the filter rules of a running container's network
+// namespace. The rules are lost when the container restarts, so callers apply
+// them again (see ContainerStartedAt).
+func (c *Client) ApplyEgressRules(ctx context.Context, containerID string, rules EgressRules) error {
+	image := c.config.EgressFirewallImage
+	if image == "" {
+		return fmt.Errorf("egress policies are not enabled")
+	}
+	if err := c.pullImageIfNeeded(ctx, image); err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	containerConfig := &container.Config{
+		Image:      image,
+		Entrypoint: []string{"sh", "-c", egressScript},
+		Env: []string{
+			"RULES_V4=" + egressRuleset(rules, false),
+			"RULES_V6=" + egressRuleset(rules, true),
+		},
+	}
+	// Joining the instance's network namespace with NET_ADMIN lets the rules
+	// apply to it without giving the instance itself the capability
+	hostConfig := &container.HostConfig{
+		NetworkMode: container.NetworkMode("container:" + containerID),
+		CapAdd:      []string{"NET_ADMIN", "NET_RAW"},
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to create firewall container: %w", err)
+	}
+	defer func() {
+		_ = c.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start firewall container: %w", err)
+	}
+
+	var exitCode int64
+	waitCh, errCh := c.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case result := <-waitCh:
+		exitCode = result.StatusCode
+	case err := <-errCh:
+		return fmt.Errorf("failed to wait for firewall container: %w", err)
+	}
+
+	if exitCode != 0 {
+		var output bytes.Buffer
+		if reader, err := c.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true}); err == nil {
+			_, _ = stdcopy.StdCopy(&output, &output, reader)
+			reader.Close()
+		}
+		return fmt.Errorf("failed to apply egress rules (exit code %d): %s", exitCode, strings.TrimSpace(output.String()))
+	}
+
+	log.Printf("Applied egress rules to container %s (restricted: %t)", containerID, rules.Restricted)
+	return nil
+}
+
+// ContainerStartedAt returns when a container was last started, which
+// identifies its current network namespace
+func (c *Client) ContainerStartedAt(ctx context.Context, containerID string) (time.Time, error) {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if inspect.State == nil || !inspect.State.Running {
+		return time.Time{}, fmt.Errorf("container is not running")
+	}
+
+	startedAt, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid container start time: %w", err)
+	}
+	return startedAt, nil
+}
+
+// egressRuleset renders the filter table for iptables-restore (or
+// ip6tables-restore when v6 is set)
+func egressRuleset(rules EgressRules, v6 bool) string {
+	var b strings.Builder
+	b.WriteString("*filter\n:INPUT ACCEPT [0:0]\n:FORWARD ACCEPT [0:0]\n")
+
+	if !rules.Restricted {
+		b.WriteString(":OUTPUT ACCEPT [0:0]\nCOMMIT\n")
+		return b.String()
+	}
+
+	b.WriteString(":OUTPUT DROP [0:0]\n")
+	b.WriteString("-A OUTPUT -o lo -j ACCEPT\n")
+	b.WriteString("-A OUTPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT\n")
+	if rules.AllowDNS {
+		b.WriteString("-A OUTPUT -p udp --dport 53 -j ACCEPT\n")
+		b.WriteString("-A OUTPUT -p tcp --dport 53 -j ACCEPT\n")
+	}
+
+	for _, destination := range rules.Destinations {
+		if destination.Prefix.Addr().Is6() != v6 {
+			continue
+		}
+		if len(destination.Ports) == 0 {
+			fmt.Fprintf(&b, "-A OUTPUT -d %s -j ACCEPT\n", destination.Prefix)
+			continue
+		}
+
+		ports := make([]string, len(destination.Ports))
+		for i, port := range destination.Ports {
+			ports[i] = strconv.Itoa(port)
+		}
+		for _, protocol := range []string{"tcp", "udp"} {
+			fmt.Fprintf(&b, "-A OUTPUT -d %s -p %s -m multiport --dports %s -j ACCEPT\n", destination.Prefix, protocol, strings.Join(ports, ","))
+		}
+	}
+
+	// Reject rather than drop so blocked connections fail right away
+	b.WriteString("-A OUTPUT -p tcp -j REJECT --reject-with tcp-reset\n")
+	b.WriteString("-A OUTPUT -j REJECT\n")
+	b.WriteString("COMMIT\n")
+	return b.String()
+}
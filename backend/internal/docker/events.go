@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ContainerEvent describes a container that started or whose process exited
+type ContainerEvent struct {
+	ContainerID string
+	Action      events.Action // events.ActionStart or events.ActionDie
+	ExitCode    string        // set for events.ActionDie
+	Time        time.Time
+}
+
+// WatchContainerEvents streams container "start" and "die" events until ctx
+// is cancelled. The error channel receives a value when the event stream breaks.
+func (c *Client) WatchContainerEvents(ctx context.Context) (<-chan ContainerEvent, <-chan error) {
+	messages, errs := c.cli.Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("event", string(events.ActionStart)),
+			filters.Arg("event", string(events.ActionDie)),
+		),
+	})
+
+	containerEvents := make(chan ContainerEvent)
+	go func() {
+		defer close(containerEvents)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				event := ContainerEvent{
+					ContainerID: msg.Actor.ID,
+					Action:      msg.Action,
+					ExitCode:    msg.Actor.Attributes["exitCode"],
+					Time:        time.Unix(0, msg.TimeNano),
+				}
+				select {
+				case containerEvents <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return containerEvents, errs
+}
+
+// SetRestartPolicy changes the restart policy of an existing container
+func (c *Client) SetRestartPolicy(ctx context.Context, containerID string, policy container.RestartPolicyMode) error {
+	_, err := c.cli.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		RestartPolicy: container.RestartPolicy{Name: policy},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update restart policy: %w", err)
+	}
+
+	log.Printf("Set restart policy of container %s to %s", containerID, policy)
+	return nil
+}
+
+// SetCPULimit limits a running container to a number of CPUs; 0 lifts the
+// limit. Docker ignores a zero NanoCPUs on update, so lifting sets the limit to
+// all of the host's CPUs.
+func (c *Client) SetCPULimit(ctx context.Context, containerID string, cpus float64) error {
+	if cpus <= 0 {
+		info, err := c.cli.Info(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get docker info: %w", err)
+		}
+		cpus = float64(info.NCPU)
+	}
+
+	_, err := c.cli.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		Resources: container.Resources{NanoCPUs: int64(cpus * 1e9)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update CPU limit: %w", err)
+	}
+
+	log.Printf("Set CPU limit of container %s to %.2f", containerID, cpus)
+	return nil
+}
+
+// proxyNetwork returns the network Traefik reaches a container through (it
+// depends on the container's region)
+func (c *Client) proxyNetwork(ctx context.Context, containerID string) string {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err == nil && inspect.Config != nil && inspect.Config.Labels[proxyNetworkLabel] != "" {
+		return inspect.Config.Labels[proxyNetworkLabel]
+	}
+	return c.config.TraefikNetwork
+}
+
+// SuspendRouting disconnects a container from the proxy network so Traefik stops routing to it
+func (c *Client) SuspendRouting(ctx context.Context, containerID string) error {
+	if err := c.cli.NetworkDisconnect(ctx, c.proxyNetwork(ctx, containerID), containerID, true); err != nil {
+		return fmt.Errorf("failed to disconnect container from proxy network: %w", err)
+	}
+
+	log.Printf("Suspended routing for container: %s", containerID)
+	return nil
+}
+
+// ResumeRouting reconnects a container to the proxy network
+func (c *Client) ResumeRouting(ctx context.Context, containerID string) error {
+	if err := c.cli.NetworkConnect(ctx, c.proxyNetwork(ctx, containerID), containerID, nil); err != nil {
+		return fmt.Errorf("failed to connect container to proxy network: %w", err)
+	}
+
+	log.Printf("Resumed routing for container: %s", containerID)
+	return nil
+}
+
+// SetProxyEnabled switches a container's route on or off. Traefik reads the
+// switch from the labels, so a labelled container is recreated. Unlike
+// SuspendRouting this holds even if the container is started outside the
+// platform. It returns the (new) container ID.
+func (c *Client) SetProxyEnabled(ctx context.Context, containerID string, enabled bool) (string, error) {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	if err := c.router.SetRouteEnabled(ctx, strings.TrimPrefix(inspect.Name, "/"), enabled); err != nil {
+		return "", err
+	}
+	if _, ok := inspect.Config.Labels[traefikEnableLabel]; !ok {
+		return containerID, nil
+	}
+
+	id, err := c.recreateContainer(ctx, containerID, func(config *container.Config, _ *container.HostConfig) {
+		config.Labels[traefikEnableLabel] = strconv.FormatBool(enabled)
+	})
+	if err == nil {
+		log.Printf("Set Traefik routing of container %s to %t", id, enabled)
+	}
+	return id, err
+}
@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+)
+
+// warmContainerLabel marks the stopped containers that pin pre-pulled images
+const warmContainerLabel = "pocketploy.warm"
+
+// PullImage pulls an image, fetching a newer version of an existing tag if there is one
+func (c *Client) PullImage(ctx context.Context, ref string) error {
+	log.Printf("Pulling image: %s", ref)
+	reader, err := c.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer reader.Close()
+
+	// Wait for pull to complete
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to wait for image pull %s: %w", ref, err)
+	}
+
+	log.Printf("Successfully pulled image: %s", ref)
+	return nil
+}
+
+// EnsureWarmContainer keeps a stopped container of the current version of an
+// image so that the image counts as in use and is not removed by image
+// pruning. Creating it with the instance security options also surfaces
+// configuration problems before a user creates an instance.
+func (c *Client) EnsureWarmContainer(ctx context.Context, ref string) error {
+	inspect, _, err := c.cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+
+	sum := sha256.Sum256([]byte(ref))
+	name := "pocketploy-warm-" + hex.EncodeToString(sum[:6])
+
+	existing, err := c.cli.ContainerInspect(ctx, name)
+	switch {
+	case err == nil && existing.Image == inspect.ID:
+		return nil
+	case err == nil:
+		// The tag moved to a new image; release the old one
+		if err := c.cli.ContainerRemove(ctx, existing.ID, container.RemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("failed to remove outdated warm container: %w", err)
+		}
+	case !cerrdefs.IsNotFound(err):
+		return fmt.Errorf("failed to inspect warm container: %w", err)
+	}
+
+	containerConfig := &container.Config{
+		Image:      ref,
+		Entrypoint: []string{pocketBaseBinary},
+		Cmd:        []string{"--help"},
+		Labels: map[string]string{
+			warmContainerLabel: "true",
+		},
+		User: c.config.ContainerUser,
+	}
+
+	hostConfig := &container.HostConfig{}
+	c.applySecurityOptions(hostConfig)
+
+	if _, err := c.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, name); err != nil {
+		return fmt.Errorf("failed to create warm container for %s: %w", ref, err)
+	}
+
+	log.Printf("Created warm container %s for image %s", name, ref)
+	return nil
+}
+
+// ImageInfo describes a local image
+type ImageInfo struct {
+	ID           string
+	RepoDigests  []string
+	ExposedPorts []string // e.g. "8090/tcp"
+}
+
+// Exposes reports whether the image declares a port (e.g. "8090/tcp")
+func (i *ImageInfo) Exposes(port string) bool {
+	for _, exposed := range i.ExposedPorts {
+		if exposed == port {
+			return true
+		}
+	}
+	return false
+}
+
+// InspectImage describes a local image; pull it first
+func (c *Client) InspectImage(ctx context.Context, ref string) (*ImageInfo, error) {
+	inspect, _, err := c.cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+
+	info := &ImageInfo{ID: inspect.ID, RepoDigests: inspect.RepoDigests}
+	if inspect.Config != nil {
+		for port := range inspect.Config.ExposedPorts {
+			info.ExposedPorts = append(info.ExposedPorts, string(port))
+		}
+	}
+
+	return info, nil
+}
+
+// PinnedReference returns ref's repository pinned to the digest the registry
+// served it with (repository@sha256:...), or "" if the image has no digest
+// from that repository (e.g. it was only built locally)
+func PinnedReference(ref string, repoDigests []string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+
+	for _, repoDigest := range repoDigests {
+		candidate, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		if canonical, ok := candidate.(reference.Canonical); ok && candidate.Name() == named.Name() {
+			return reference.FamiliarString(canonical), nil
+		}
+	}
+
+	return "", nil
+}
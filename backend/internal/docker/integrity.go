@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// integrityCheckTimeout bounds a check; large databases take a while
+const integrityCheckTimeout = 10 * time.Minute
+
+// CheckDatabaseIntegrity runs `PRAGMA integrity_check` against the data.db in
+// storagePath and returns SQLite's report ("ok" when the database is sound).
+// The database is opened read-only, which is safe while PocketBase is running.
+func (c *Client) CheckDatabaseIntegrity(ctx context.Context, storagePath string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, integrityCheckTimeout)
+	defer cancel()
+
+	output, exitCode, err := c.RunSQLite(ctx, map[string]string{pocketBaseDataDir: storagePath},
+		[]string{"-readonly", pocketBaseDataDir + "/data.db", "PRAGMA integrity_check;"})
+	if err != nil {
+		return "", err
+	}
+
+	// Damage bad enough that the pragma can't run is a report too
+	result := strings.TrimSpace(output)
+	if exitCode != 0 && !strings.Contains(result, "malformed") && !strings.Contains(result, "not a database") {
+		return result, fmt.Errorf("integrity check exited with code %d: %s", exitCode, result)
+	}
+
+	return result, nil
+}
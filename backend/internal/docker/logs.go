@@ -0,0 +1,171 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Log levels, ordered from least to most severe
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+var logLevelSeverity = map[string]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// LogEntry is a single parsed container log line
+type LogEntry struct {
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Stream    string     `json:"stream"`
+	Level     string     `json:"level"`
+	Message   string     `json:"message"`
+}
+
+// String formats the entry as a single log line
+func (e LogEntry) String() string {
+	if e.Timestamp == nil {
+		return e.Message
+	}
+	return e.Timestamp.Format(time.RFC3339Nano) + " " + e.Message
+}
+
+// ValidLogLevel reports whether level is a known log level
+func ValidLogLevel(level string) bool {
+	_, ok := logLevelSeverity[level]
+	return ok
+}
+
+// AtLeast reports whether the entry is at least as severe as level
+func (e LogEntry) AtLeast(level string) bool {
+	return logLevelSeverity[e.Level] >= logLevelSeverity[level]
+}
+
+// ReadContainerLogs retrieves a container's logs as parsed entries in output order
+func (c *Client) ReadContainerLogs(ctx context.Context, containerID string, opts LogOptions) ([]LogEntry, error) {
+	reader, err := c.cli.ContainerLogs(ctx, containerID, opts.dockerOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer reader.Close()
+
+	var entries []LogEntry
+	stdout := &logLineWriter{stream: "stdout", entries: &entries}
+	stderr := &logLineWriter{stream: "stderr", entries: &entries}
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, reader); err != nil {
+		return nil, fmt.Errorf("failed to read logs: %w", err)
+	}
+	stdout.flush()
+	stderr.flush()
+
+	return entries, nil
+}
+
+// logLineWriter splits one demultiplexed stream into entries. Docker frames can
+// end mid-line, so incomplete lines are buffered until the next write.
+type logLineWriter struct {
+	stream  string
+	entries *[]LogEntry
+	partial []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.add(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *logLineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.add(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *logLineWriter) add(line string) {
+	*w.entries = append(*w.entries, parseLogLine(w.stream, strings.TrimRight(line, "\r")))
+}
+
+// parseLogLine splits off the Docker timestamp prefix and detects the level
+func parseLogLine(stream, line string) LogEntry {
+	entry := LogEntry{Stream: stream, Message: line}
+
+	if ts, rest, ok := strings.Cut(line, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			entry.Timestamp = &t
+			entry.Message = rest
+		}
+	}
+
+	entry.Level = detectLogLevel(entry.Message)
+	return entry
+}
+
+// detectLogLevel guesses the level of a log message from JSON "level" fields,
+// logfmt "level=" pairs or common level keywords, defaulting to info
+func detectLogLevel(message string) string {
+	trimmed := strings.TrimSpace(message)
+	if strings.HasPrefix(trimmed, "{") {
+		var fields struct {
+			Level string `json:"level"`
+		}
+		if json.Unmarshal([]byte(trimmed), &fields) == nil && fields.Level != "" {
+			if level := normalizeLogLevel(fields.Level); level != "" {
+				return level
+			}
+		}
+	}
+
+	upper := strings.ToUpper(message)
+	if i := strings.Index(upper, "LEVEL="); i >= 0 {
+		if value := strings.Fields(upper[i+len("LEVEL="):]); len(value) > 0 {
+			if level := normalizeLogLevel(strings.Trim(value[0], `"`)); level != "" {
+				return level
+			}
+		}
+	}
+
+	for _, word := range strings.FieldsFunc(upper, func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z')
+	}) {
+		if level := normalizeLogLevel(word); level != "" {
+			return level
+		}
+	}
+
+	return LogLevelInfo
+}
+
+func normalizeLogLevel(value string) string {
+	switch strings.ToUpper(value) {
+	case "DEBUG", "TRACE":
+		return LogLevelDebug
+	case "INFO":
+		return LogLevelInfo
+	case "WARN", "WARNING":
+		return LogLevelWarn
+	case "ERROR", "ERR", "FATAL", "PANIC":
+		return LogLevelError
+	}
+	return ""
+}
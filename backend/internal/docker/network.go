@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/network"
+)
+
+// NetworkContainers returns the names of the containers attached to a network
+func (c *Client) NetworkContainers(ctx context.Context, name string) ([]string, error) {
+	inspect, err := c.cli.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("network %s does not exist", name)
+		}
+		return nil, fmt.Errorf("failed to inspect network %s: %w", name, err)
+	}
+
+	names := make([]string, 0, len(inspect.Containers))
+	for _, endpoint := range inspect.Containers {
+		names = append(names, strings.TrimPrefix(endpoint.Name, "/"))
+	}
+	return names, nil
+}
+
+// managedNetworkLabel marks networks the backend created
+const managedNetworkLabel = "pocketploy.managed"
+
+// EnsureNetwork creates a bridge network if it does not exist yet and reports
+// whether it did
+func (c *Client) EnsureNetwork(ctx context.Context, name string) (bool, error) {
+	_, err := c.cli.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err == nil {
+		return false, nil
+	}
+	if !cerrdefs.IsNotFound(err) {
+		return false, fmt.Errorf("failed to inspect network %s: %w", name, err)
+	}
+
+	_, err = c.cli.NetworkCreate(ctx, name, network.CreateOptions{
+		Driver: "bridge",
+		Labels: map[string]string{managedNetworkLabel: "true"},
+	})
+	if cerrdefs.IsConflict(err) {
+		// Created concurrently by another backend process
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create network %s: %w", name, err)
+	}
+
+	log.Printf("Created Docker network: %s", name)
+	return true, nil
+}
+
+// EnsureNetworks creates the configured instance and Traefik networks if
+// needed and warns when no Traefik container is attached to the latter, as
+// instances would then be unreachable
+func (c *Client) EnsureNetworks(ctx context.Context) error {
+	for _, name := range []string{c.config.DockerNetwork, c.config.TraefikNetwork} {
+		if _, err := c.EnsureNetwork(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	attached, err := c.TraefikAttached(ctx, c.config.TraefikNetwork)
+	if err != nil {
+		return err
+	}
+	if !attached {
+		log.Printf("Warning: no Traefik container is attached to network %s; instances will not be reachable until it is", c.config.TraefikNetwork)
+	}
+
+	return nil
+}
+
+// TraefikAttached reports whether a Traefik container (by name) is attached to a network
+func (c *Client) TraefikAttached(ctx context.Context, name string) (bool, error) {
+	containers, err := c.NetworkContainers(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, container := range containers {
+		if strings.Contains(container, "traefik") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
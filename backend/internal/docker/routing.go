@@ -0,0 +1,259 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"pocketploy/internal/config"
+)
+
+// Route describes how the proxy reaches an instance container
+type Route struct {
+	// Name is the container name; it also names the route
+	Name string
+	Host string
+
+	// Traefik entrypoint and the network the proxy reaches the container through
+	Entrypoint string
+	Network    string
+
+	// Access rules (only the Traefik driver enforces them)
+	Access AccessRules
+}
+
+// Router publishes the routes of instance containers. Traefik discovers them
+// from container labels; the Caddy and nginx drivers write a config fragment
+// per container and run a reload hook.
+type Router interface {
+	// Labels returns the labels a new container carries (none for file drivers)
+	Labels(route Route) map[string]string
+
+	// AddRoute publishes the route of a container that was just created
+	AddRoute(ctx context.Context, route Route) error
+
+	// SetRouteEnabled takes a container's route off the proxy or back on
+	SetRouteEnabled(ctx context.Context, name string, enabled bool) error
+
+	// RemoveRoute deletes a container's route; a missing route is not an error
+	RemoveRoute(ctx context.Context, name string) error
+}
+
+// routesReloadTimeout bounds how long ROUTES_RELOAD_COMMAND may run
+const routesReloadTimeout = 30 * time.Second
+
+// newRouter returns the driver for ROUTING_MODE
+func newRouter(cfg *config.Config) Router {
+	switch cfg.RoutingMode {
+	case config.RoutingModeCaddy:
+		return &fileRouter{dir: cfg.RoutesDir, ext: ".caddy", render: caddyRoute, reloadCommand: cfg.RoutesReloadCommand}
+	case config.RoutingModeNginx:
+		return &fileRouter{dir: cfg.RoutesDir, ext: ".conf", render: nginxRoute, reloadCommand: cfg.RoutesReloadCommand}
+	case config.RoutingModePort:
+		return directRouter{}
+	default:
+		return &traefikRouter{config: cfg}
+	}
+}
+
+// traefikRouter routes through Traefik's Docker provider
+type traefikRouter struct {
+	config *config.Config
+}
+
+// Labels creates the necessary Traefik labels for routing. SSL is terminated
+// at Nginx in production unless TRAEFIK_CERT_RESOLVER lets Traefik obtain
+// certificates itself.
+func (r *traefikRouter) Labels(route Route) map[string]string {
+	routerName := route.Name
+
+	entrypoint := route.Entrypoint
+	if entrypoint == "" {
+		entrypoint = "web"
+	}
+	proxyNetwork := r.config.TraefikNetwork
+	if route.Network != "" {
+		proxyNetwork = route.Network
+	}
+
+	labels := map[string]string{
+		traefikEnableLabel: "true",
+		fmt.Sprintf("traefik.http.routers.%s.rule", routerName):                      fmt.Sprintf("Host(`%s`)", route.Host),
+		fmt.Sprintf("traefik.http.routers.%s.entrypoints", routerName):               entrypoint,
+		fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port", routerName): "8090",
+		proxyNetworkLabel: proxyNetwork,
+	}
+	if r.config.TraefikCertResolver != "" {
+		labels[fmt.Sprintf("traefik.http.routers.%s.tls", routerName)] = "true"
+		labels[fmt.Sprintf("traefik.http.routers.%s.tls.certresolver", routerName)] = r.config.TraefikCertResolver
+	}
+	for key, value := range accessLabels(r.config, routerName, route.Access) {
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// Traefik picks label changes up by itself; switching a route needs the
+// container to be recreated, which SetProxyEnabled does
+func (r *traefikRouter) AddRoute(ctx context.Context, route Route) error { return nil }
+
+func (r *traefikRouter) SetRouteEnabled(ctx context.Context, name string, enabled bool) error {
+	return nil
+}
+
+func (r *traefikRouter) RemoveRoute(ctx context.Context, name string) error { return nil }
+
+// directRouter publishes nothing: in port mode containers are reached on
+// their host port
+type directRouter struct{}
+
+func (directRouter) Labels(route Route) map[string]string { return nil }
+
+func (directRouter) AddRoute(ctx context.Context, route Route) error { return nil }
+
+func (directRouter) SetRouteEnabled(ctx context.Context, name string, enabled bool) error {
+	return nil
+}
+
+func (directRouter) RemoveRoute(ctx context.Context, name string) error { return nil }
+
+// fileRouter keeps one config fragment per container in dir, which the
+// proxy includes (e.g. "import routes/*.caddy" or "include routes/*.conf").
+// Disabled routes keep their file with a ".disabled" suffix.
+type fileRouter struct {
+	dir           string
+	ext           string
+	render        func(Route) string
+	reloadCommand string
+
+	// mu serializes changes so reloads see complete fragments
+	mu sync.Mutex
+}
+
+func (r *fileRouter) Labels(route Route) map[string]string { return nil }
+
+func (r *fileRouter) AddRoute(ctx context.Context, route Route) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create routes directory: %w", err)
+	}
+
+	// Write to a temporary file first so the proxy never reads half a fragment
+	path := r.path(route.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(r.render(route)), 0644); err != nil {
+		return fmt.Errorf("failed to write route: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write route: %w", err)
+	}
+	_ = os.Remove(path + ".disabled")
+
+	return r.reload(ctx)
+}
+
+func (r *fileRouter) SetRouteEnabled(ctx context.Context, name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, to := r.path(name)+".disabled", r.path(name)
+	if !enabled {
+		from, to = to, from
+	}
+
+	if err := os.Rename(from, to); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to switch route: %w", err)
+	}
+
+	return r.reload(ctx)
+}
+
+func (r *fileRouter) RemoveRoute(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := false
+	for _, path := range []string{r.path(name), r.path(name) + ".disabled"} {
+		err := os.Remove(path)
+		if err == nil {
+			removed = true
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove route: %w", err)
+		}
+	}
+
+	if !removed {
+		return nil
+	}
+	return r.reload(ctx)
+}
+
+func (r *fileRouter) path(name string) string {
+	return filepath.Join(r.dir, name+r.ext)
+}
+
+// reload runs ROUTES_RELOAD_COMMAND so the proxy picks up the changed fragments
+func (r *fileRouter) reload(ctx context.Context) error {
+	if r.reloadCommand == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, routesReloadTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "sh", "-c", r.reloadCommand).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to reload proxy: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	log.Printf("Reloaded proxy routes in %s", r.dir)
+	return nil
+}
+
+// caddyRoute renders a Caddyfile site block. Like Traefik's "web" entrypoint
+// it serves plain HTTP; TLS is terminated in front of the proxy.
+func caddyRoute(route Route) string {
+	return fmt.Sprintf(`http://%s {
+	reverse_proxy %s:8090
+}
+`, route.Host, route.Name)
+}
+
+// nginxRoute renders an nginx server block. The upstream is resolved per
+// request through Docker's DNS, so nginx still loads while a container is down.
+func nginxRoute(route Route) string {
+	return fmt.Sprintf(`server {
+    listen 80;
+    server_name %s;
+
+    location / {
+        resolver 127.0.0.11 valid=10s;
+        set $upstream http://%s:8090;
+        proxy_pass $upstream;
+        proxy_http_version 1.1;
+        proxy_buffering off;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection 'upgrade';
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+    }
+}
+`, route.Host, route.Name)
+}
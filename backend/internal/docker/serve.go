@@ -0,0 +1,184 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+)
+
+// encryptionKeyEnv is the variable PocketBase reads the settings encryption key from
+const encryptionKeyEnv = "PB_ENCRYPTION_KEY"
+
+// ServeFlags are the optional `pocketbase serve` flags of an instance. The
+// listen address and data directory are always set by the client.
+type ServeFlags struct {
+	// EncryptionKey enables settings encryption; it reaches PocketBase through
+	// the container environment and --encryptionEnv
+	EncryptionKey string
+
+	// Directories relative to the data directory (validated by the caller)
+	HooksDir      string
+	MigrationsDir string
+	PublicDir     string
+
+	Origins      []string
+	QueryTimeout int // seconds, PocketBase's default if 0
+}
+
+// serveCommand renders the container command for the given flags
+func serveCommand(flags ServeFlags) []string {
+	cmd := []string{"serve", "--http=0.0.0.0:8090"}
+	cmd = append(cmd, appFlags(flags)...)
+
+	if flags.PublicDir != "" {
+		cmd = append(cmd, "--publicDir="+path.Join(pocketBaseDataDir, flags.PublicDir))
+	}
+	if len(flags.Origins) > 0 {
+		cmd = append(cmd, "--origins="+strings.Join(flags.Origins, ","))
+	}
+	if flags.QueryTimeout > 0 {
+		cmd = append(cmd, "--queryTimeout="+strconv.Itoa(flags.QueryTimeout))
+	}
+
+	return cmd
+}
+
+// appFlags renders the flags every pocketbase command of an instance needs to
+// open the same app: the data directory, settings encryption and the JS
+// hooks and migrations
+func appFlags(flags ServeFlags) []string {
+	cmd := []string{"--dir=" + pocketBaseDataDir}
+
+	if flags.EncryptionKey != "" {
+		cmd = append(cmd, "--encryptionEnv="+encryptionKeyEnv)
+	}
+	if flags.HooksDir != "" {
+		cmd = append(cmd, "--hooksDir="+path.Join(pocketBaseDataDir, flags.HooksDir))
+	}
+	if flags.MigrationsDir != "" {
+		cmd = append(cmd, "--migrationsDir="+path.Join(pocketBaseDataDir, flags.MigrationsDir))
+	}
+
+	return cmd
+}
+
+// MigrateUp applies pending migrations with `pocketbase migrate up` inside a
+// running container, opening the app with its serve flags so JS migrations
+// are found. It returns the combined output.
+func (c *Client) MigrateUp(ctx context.Context, containerID string, flags ServeFlags) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := append([]string{pocketBaseBinary, "migrate", "up"}, appFlags(flags)...)
+
+	output, err := c.exec(ctx, containerID, cmd)
+	if err != nil {
+		return output, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	log.Printf("Ran pocketbase migrate up in container: %s", containerID)
+	return output, nil
+}
+
+// serveEnv replaces the encryption key in a container environment
+func serveEnv(env []string, flags ServeFlags) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		if !strings.HasPrefix(entry, encryptionKeyEnv+"=") {
+			result = append(result, entry)
+		}
+	}
+	if flags.EncryptionKey != "" {
+		result = append(result, encryptionKeyEnv+"="+flags.EncryptionKey)
+	}
+	return result
+}
+
+// UpdateServeFlags recreates a container with a new serve command. Containers
+// of older releases that still run /pb_data/entrypoint.sh move to the
+// PocketBase binary. It returns the new container ID.
+func (c *Client) UpdateServeFlags(ctx context.Context, containerID string, flags ServeFlags) (string, error) {
+	id, err := c.recreateContainer(ctx, containerID, func(config *container.Config, _ *container.HostConfig) {
+		config.Entrypoint = []string{pocketBaseBinary}
+		config.Cmd = serveCommand(flags)
+		config.Env = serveEnv(config.Env, flags)
+	})
+	if err == nil {
+		log.Printf("Recreated container %s with new serve flags", id)
+	}
+	return id, err
+}
+
+// recreateContainer replaces a container with one whose config (and host
+// config) was changed by update, keeping its name, image, labels, mounts and
+// networks unless update changes them (so suspended routing stays suspended).
+// The container is started again if it was running. It returns the new
+// container ID.
+func (c *Client) recreateContainer(ctx context.Context, containerID string, update func(config *container.Config, hostConfig *container.HostConfig)) (string, error) {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	name := strings.TrimPrefix(inspect.Name, "/")
+	wasRunning := inspect.State != nil && inspect.State.Running
+
+	endpoints := make(map[string]*network.EndpointSettings)
+	if inspect.NetworkSettings != nil {
+		for networkName := range inspect.NetworkSettings.Networks {
+			endpoints[networkName] = &network.EndpointSettings{}
+		}
+	}
+	networkConfig := &network.NetworkingConfig{EndpointsConfig: endpoints}
+
+	updated := *inspect.Config
+	updated.Env = append([]string(nil), inspect.Config.Env...)
+	updated.Labels = make(map[string]string, len(inspect.Config.Labels))
+	for key, value := range inspect.Config.Labels {
+		updated.Labels[key] = value
+	}
+
+	// Containers created before log rotation was configured pick it up here
+	hostConfig := *inspect.HostConfig
+	hostConfig.Mounts = append([]mount.Mount(nil), inspect.HostConfig.Mounts...)
+	c.applyLogOptions(&hostConfig)
+
+	update(&updated, &hostConfig)
+
+	if wasRunning {
+		if err := c.StopContainer(ctx, containerID); err != nil {
+			return "", err
+		}
+	}
+	if err := c.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+		return "", fmt.Errorf("failed to remove container: %w", err)
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, &updated, &hostConfig, networkConfig, nil, name)
+	if err != nil {
+		// Put the previous container back so the instance is not left without one
+		restored, restoreErr := c.cli.ContainerCreate(ctx, inspect.Config, inspect.HostConfig, networkConfig, nil, name)
+		if restoreErr != nil {
+			return "", fmt.Errorf("failed to recreate container: %w (restoring the previous container failed: %v)", err, restoreErr)
+		}
+		if wasRunning {
+			_ = c.cli.ContainerStart(ctx, restored.ID, container.StartOptions{})
+		}
+		return restored.ID, fmt.Errorf("failed to recreate container: %w", err)
+	}
+
+	if wasRunning {
+		if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+			return resp.ID, fmt.Errorf("failed to start container: %w", err)
+		}
+	}
+
+	return resp.ID, nil
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ErrNoDatabase is returned when an instance has no data.db yet
+var ErrNoDatabase = fmt.Errorf("instance has no database yet")
+
+// RunSQLite runs the sqlite3 shell with args in a short-lived container of
+// INTEGRITY_CHECK_IMAGE (the PocketBase image has no sqlite3). dataDirs maps
+// directories inside the container to instance data directories on the host;
+// each must already hold a data.db. The output is returned along with the
+// exit code, which the caller interprets.
+func (c *Client) RunSQLite(ctx context.Context, dataDirs map[string]string, args []string) (string, int64, error) {
+	targets := make([]string, 0, len(dataDirs))
+	for target := range dataDirs {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	// The mounts stay writable: readers of a WAL database update its -shm file
+	mounts := make([]mount.Mount, 0, len(targets))
+	for _, target := range targets {
+		source, err := filepath.Abs(dataDirs[target])
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		if _, err := os.Stat(filepath.Join(source, "data.db")); os.IsNotExist(err) {
+			return "", 0, ErrNoDatabase
+		}
+		mounts = append(mounts, mount.Mount{Type: mount.TypeBind, Source: source, Target: target})
+	}
+
+	image := c.config.IntegrityCheckImage
+	if err := c.pullImageIfNeeded(ctx, image); err != nil {
+		return "", 0, fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	containerConfig := &container.Config{
+		Image:      image,
+		Entrypoint: []string{"sqlite3"},
+		Cmd:        args,
+		User:       c.config.ContainerUser,
+	}
+	hostConfig := &container.HostConfig{
+		NetworkMode: "none",
+		Mounts:      mounts,
+	}
+	c.applySecurityOptions(hostConfig)
+
+	resp, err := c.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create sqlite container: %w", err)
+	}
+	defer func() {
+		_ = c.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", 0, fmt.Errorf("failed to start sqlite container: %w", err)
+	}
+
+	var exitCode int64
+	waitCh, errCh := c.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case result := <-waitCh:
+		exitCode = result.StatusCode
+	case err := <-errCh:
+		return "", 0, fmt.Errorf("failed to wait for sqlite: %w", err)
+	}
+
+	reader, err := c.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", exitCode, fmt.Errorf("failed to get sqlite output: %w", err)
+	}
+	defer reader.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, reader); err != nil {
+		return "", exitCode, fmt.Errorf("failed to read sqlite output: %w", err)
+	}
+
+	return output.String(), exitCode, nil
+}
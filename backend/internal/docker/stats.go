@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ResourceUsage is a point-in-time sample of a container's resource consumption
+type ResourceUsage struct {
+	CPUPercent       float64
+	MemoryBytes      uint64
+	MemoryLimitBytes uint64
+	NetworkRxBytes   uint64
+	NetworkTxBytes   uint64
+}
+
+// GetResourceUsage samples CPU, memory and network usage of a running container.
+// Docker takes two readings about a second apart so CPU usage can be computed.
+func (c *Client) GetResourceUsage(ctx context.Context, containerID string) (*ResourceUsage, error) {
+	resp, err := c.cli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	usage := &ResourceUsage{
+		CPUPercent:       cpuPercent(stats),
+		MemoryBytes:      stats.MemoryStats.Usage,
+		MemoryLimitBytes: stats.MemoryStats.Limit,
+	}
+
+	// Page cache is reclaimable, so report memory the way `docker stats` does
+	if cache, ok := stats.MemoryStats.Stats["inactive_file"]; ok && cache < usage.MemoryBytes {
+		usage.MemoryBytes -= cache
+	}
+
+	for _, network := range stats.Networks {
+		usage.NetworkRxBytes += network.RxBytes
+		usage.NetworkTxBytes += network.TxBytes
+	}
+
+	return usage, nil
+}
+
+// cpuPercent computes CPU usage across all cores, matching `docker stats`
+func cpuPercent(stats container.StatsResponse) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+// DirectorySize returns the total size in bytes of the regular files under path
+func DirectorySize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure directory size: %w", err)
+	}
+
+	return size, nil
+}
+
+// existingParent returns path, or its nearest ancestor that exists (the
+// instances directory is only created with the first instance)
+func existingParent(path string) string {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,38 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// RelocateStorage recreates a container with its data directory mounted from
+// storagePath instead. The data must already be there. It returns the new
+// container ID.
+func (c *Client) RelocateStorage(ctx context.Context, containerID, storagePath string) (string, error) {
+	absStoragePath, err := filepath.Abs(storagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	found := false
+	id, err := c.recreateContainer(ctx, containerID, func(_ *container.Config, hostConfig *container.HostConfig) {
+		for i, m := range hostConfig.Mounts {
+			if m.Type == mount.TypeBind && m.Target == pocketBaseDataDir {
+				hostConfig.Mounts[i].Source = absStoragePath
+				found = true
+			}
+		}
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("container %s has no data directory mount", containerID)
+	}
+	if err == nil {
+		log.Printf("Recreated container %s with data directory %s", id, absStoragePath)
+	}
+	return id, err
+}
@@ -0,0 +1,186 @@
+// Package doctor checks the environment the backend depends on, so
+// misconfigurations are reported with a fix instead of surfacing at the
+// first instance creation.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/database"
+	"pocketploy/internal/dns"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name string
+	Err  error
+	Hint string // how to fix a failed check
+}
+
+// OK reports whether the check passed
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Run performs all checks. db and dockerClient may be nil when connecting
+// failed (dockerErr explains why for Docker); the checks needing them fail.
+func Run(ctx context.Context, cfg *config.Config, db *database.DB, dockerClient *docker.Client, dockerErr error) []Result {
+	var results []Result
+
+	// Regions add base domains and networks besides the configured ones
+	domains := []string{cfg.BaseDomain}
+	networks := []string{cfg.DockerNetwork}
+	if db != nil {
+		if regions, err := models.FindRegions(ctx, db.DB, true); err == nil {
+			for _, region := range regions {
+				domains = appendUnique(domains, region.BaseDomain)
+				if region.DockerNetwork != nil {
+					networks = appendUnique(networks, *region.DockerNetwork)
+				}
+			}
+		}
+	}
+
+	results = append(results, checkDocker(ctx, dockerClient, dockerErr))
+	if dockerClient != nil {
+		for _, name := range networks {
+			results = append(results, checkNetwork(ctx, dockerClient, name))
+		}
+		if cfg.RoutingMode == config.RoutingModeTraefik {
+			results = append(results, checkTraefikNetwork(ctx, cfg, dockerClient))
+		}
+	}
+	// Port routing links instances by host and port, not by subdomain
+	if cfg.RoutingMode != config.RoutingModePort {
+		for _, domain := range domains {
+			results = append(results, checkWildcardDNS(ctx, domain, cfg.DNSTarget))
+		}
+	}
+	results = append(results, checkInstancesPath(cfg.InstancesBasePath))
+	if cfg.RoutingMode == config.RoutingModeCaddy || cfg.RoutingMode == config.RoutingModeNginx {
+		results = append(results, checkRoutesDir(cfg.RoutesDir))
+	}
+	results = append(results, checkSchema(ctx, db))
+
+	return results
+}
+
+func checkDocker(ctx context.Context, dockerClient *docker.Client, connectErr error) Result {
+	result := Result{Name: "Docker daemon", Hint: "start Docker or set DOCKER_HOST to a reachable daemon"}
+	if dockerClient == nil {
+		result.Err = connectErr
+		return result
+	}
+	result.Err = dockerClient.Ping(ctx)
+	return result
+}
+
+func checkNetwork(ctx context.Context, dockerClient *docker.Client, name string) Result {
+	_, err := dockerClient.NetworkContainers(ctx, name)
+	return Result{
+		Name: "Docker network " + name,
+		Err:  err,
+		Hint: "create it with: docker network create " + name,
+	}
+}
+
+// checkTraefikNetwork verifies that instances are created on the network
+// Traefik routes through, and that a Traefik container is attached to it
+func checkTraefikNetwork(ctx context.Context, cfg *config.Config, dockerClient *docker.Client) Result {
+	result := Result{Name: "Traefik network " + cfg.TraefikNetwork}
+
+	if cfg.TraefikNetwork != cfg.DockerNetwork {
+		result.Err = fmt.Errorf("instances join %s but Traefik routes through %s", cfg.DockerNetwork, cfg.TraefikNetwork)
+		result.Hint = "set DOCKER_NETWORK and TRAEFIK_NETWORK to the same network"
+		return result
+	}
+
+	attached, err := dockerClient.TraefikAttached(ctx, cfg.TraefikNetwork)
+	if err != nil {
+		result.Err = err
+		result.Hint = "create it with: docker network create " + cfg.TraefikNetwork
+		return result
+	}
+	if attached {
+		return result
+	}
+
+	result.Err = fmt.Errorf("no Traefik container is attached")
+	result.Hint = "start Traefik with docker-compose, or run: docker network connect " + cfg.TraefikNetwork + " <traefik container>"
+	return result
+}
+
+// checkWildcardDNS resolves a random subdomain, as new instances get one
+// (and expects DNS_TARGET when it is set)
+func checkWildcardDNS(ctx context.Context, domain, target string) Result {
+	propagation := dns.CheckWildcard(ctx, domain, target)
+
+	var err error
+	if !propagation.Propagated {
+		err = fmt.Errorf("%s: %s", propagation.Host, propagation.Error)
+	}
+	return Result{
+		Name: "Wildcard DNS *." + domain,
+		Err:  err,
+		Hint: "add a wildcard DNS record *." + domain + " pointing at this server, set DNS_PROVIDER to create it (or use a nip.io BASE_DOMAIN in development)",
+	}
+}
+
+func checkInstancesPath(path string) Result {
+	return checkWritableDir(Result{Name: "Instances directory " + path, Hint: "create INSTANCES_BASE_PATH and make it writable by the backend user"}, path)
+}
+
+// checkRoutesDir verifies the Caddy/nginx drivers can write route fragments
+func checkRoutesDir(path string) Result {
+	return checkWritableDir(Result{Name: "Routes directory " + path, Hint: "create ROUTES_DIR, make it writable by the backend user and include it from the proxy config"}, path)
+}
+
+func checkWritableDir(result Result, path string) Result {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		result.Err = err
+		return result
+	}
+	file, err := os.CreateTemp(path, ".doctor-")
+	if err != nil {
+		result.Err = fmt.Errorf("not writable: %w", err)
+		return result
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return result
+}
+
+func checkSchema(ctx context.Context, db *database.DB) Result {
+	result := Result{Name: "Database schema", Hint: "apply the missing migrations from internal/database/migrations with psql"}
+	if db == nil {
+		result.Err = fmt.Errorf("database unreachable")
+		result.Hint = "check the DB_* settings and that PostgreSQL is running"
+		return result
+	}
+
+	pending, err := db.PendingMigrations(ctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(pending) > 0 {
+		result.Err = fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+	}
+	return result
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
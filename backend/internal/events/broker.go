@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the PostgreSQL NOTIFY channel shared by all backend processes
+const notifyChannel = "pocketploy_events"
+
+// subscriberBuffer is the number of events queued per subscriber before new
+// events are dropped for it
+const subscriberBuffer = 32
+
+// Event types pushed to clients
+const (
+	TypeInstanceUpdated      = "instance.updated"
+	TypeInstanceDeleted      = "instance.deleted"
+	TypeInstanceProvisioning = "instance.provisioning"
+	TypeInstanceRelocation   = "instance.relocation"
+	TypeInstanceBuild        = "instance.build"
+	TypeNotification         = "notification"
+)
+
+// Event is a message for a single user
+type Event struct {
+	Type   string          `json:"type"`
+	UserID string          `json:"-"`
+	Data   json.RawMessage `json:"data"`
+	Time   time.Time       `json:"time"`
+}
+
+// envelope is the NOTIFY payload; UserID is hidden from clients but must cross processes
+type envelope struct {
+	Event
+	UserID string `json:"user_id"`
+}
+
+// Broker delivers events to the subscribers of a user. While Listen is
+// running, events are fanned out to every backend process through PostgreSQL
+// LISTEN/NOTIFY; otherwise they stay in process.
+type Broker struct {
+	db        *sqlx.DB
+	listening atomic.Bool
+
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewBroker creates a broker; db may be nil for in-process delivery only
+func NewBroker(db *sqlx.DB) *Broker {
+	return &Broker{
+		db:          db,
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the user's events and a function to unsubscribe
+func (b *Broker) Subscribe(userID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[userID], ch)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends an event to all of the user's subscribers. Delivery is best
+// effort: errors are logged and slow subscribers miss events.
+func (b *Broker) Publish(ctx context.Context, userID, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Warning: failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	event := Event{Type: eventType, UserID: userID, Data: payload, Time: time.Now().UTC()}
+
+	if b.listening.Load() {
+		message, err := json.Marshal(envelope{Event: event, UserID: userID})
+		if err == nil {
+			if _, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, string(message)); err == nil {
+				return
+			}
+		}
+		log.Printf("Warning: failed to broadcast %s event, delivering locally: %v", eventType, err)
+	}
+
+	b.deliver(event)
+}
+
+// deliver hands an event to this process's subscribers
+func (b *Broker) deliver(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Listen receives events published by any backend process until ctx is cancelled
+func (b *Broker) Listen(ctx context.Context, dsn string) {
+	if b.db == nil {
+		return
+	}
+
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Warning: event listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		log.Printf("Warning: failed to listen for events: %v", err)
+		return
+	}
+
+	// Only broadcast through PostgreSQL while this process receives the notifications
+	b.listening.Store(true)
+	defer b.listening.Store(false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// nil is sent after a reconnect; events sent meanwhile are lost
+			if n == nil {
+				continue
+			}
+
+			var message envelope
+			if err := json.Unmarshal([]byte(n.Extra), &message); err != nil {
+				log.Printf("Warning: invalid event payload: %v", err)
+				continue
+			}
+
+			message.Event.UserID = message.UserID
+			b.deliver(message.Event)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package geoip
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+)
+
+// Location is where an IP address is, as far as the database knows. Fields
+// are empty when unknown (e.g. the city for country databases).
+type Location struct {
+	CountryCode string `json:"country_code,omitempty"` // ISO 3166-1 alpha-2
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// String describes the location for people, e.g. "Berlin, Germany"
+func (l Location) String() string {
+	switch {
+	case l.City != "" && l.Country != "":
+		return l.City + ", " + l.Country
+	case l.Country != "":
+		return l.Country
+	default:
+		return "unknown location"
+	}
+}
+
+// Locator resolves IP addresses to locations
+type Locator interface {
+	Lookup(ip string) (Location, bool)
+}
+
+// NewLocator opens a MaxMind database (GeoLite2 or GeoIP2, Country or City).
+// It returns nil when path is empty, which disables lookups.
+func NewLocator(path string) (Locator, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+
+	db, err := openDatabase(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GeoIP database %s: %w", path, err)
+	}
+
+	log.Printf("GeoIP database loaded: %s (%d nodes)", db.databaseType, db.nodeCount)
+	return db, nil
+}
+
+// Lookup returns the location of an IP address. Private, invalid and unknown
+// addresses are not found.
+func (db *database) Lookup(ip string) (Location, bool) {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil || addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() {
+		return Location{}, false
+	}
+
+	record, err := db.find(addr)
+	if err != nil {
+		log.Printf("Warning: GeoIP lookup of %s failed: %v", ip, err)
+		return Location{}, false
+	}
+	if record == nil {
+		return Location{}, false
+	}
+
+	location := Location{
+		CountryCode: stringAt(record, "country", "iso_code"),
+		Country:     stringAt(record, "country", "names", "en"),
+		City:        stringAt(record, "city", "names", "en"),
+	}
+	if location.CountryCode == "" && location.Country == "" {
+		return Location{}, false
+	}
+	return location, true
+}
+
+// stringAt reads a string from nested maps of a decoded record
+func stringAt(value interface{}, path ...string) string {
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
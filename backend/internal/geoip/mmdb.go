@@ -0,0 +1,340 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+)
+
+// The MaxMind DB format (https://maxmind.github.io/MaxMind-DB/): a binary
+// search tree over the address bits whose leaves point into a data section,
+// followed by a metadata map. Only what lookups need is decoded.
+
+// metadataMarker precedes the metadata map at the end of the file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the gap between the search tree and the data section
+const dataSectionSeparator = 16
+
+// Data field types
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEndMarker = 13
+	typeBoolean   = 14
+	typeFloat     = 15
+)
+
+// maxDecodeDepth bounds the nesting of decoded values (against corrupt files)
+const maxDecodeDepth = 32
+
+var errCorrupt = errors.New("corrupt database")
+
+// database is an opened MaxMind DB held in memory
+type database struct {
+	tree         []byte
+	data         []byte
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	ipv4Start    uint // node where IPv4 addresses start in an IPv6 tree
+}
+
+func openDatabase(file []byte) (*database, error) {
+	markerAt := bytes.LastIndex(file, metadataMarker)
+	if markerAt < 0 {
+		return nil, errors.New("metadata not found")
+	}
+
+	meta := &decoder{buf: file[markerAt+len(metadataMarker):]}
+	value, _, err := meta.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	metadata, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errCorrupt
+	}
+
+	db := &database{
+		nodeCount:  uintValue(metadata["node_count"]),
+		recordSize: uintValue(metadata["record_size"]),
+		ipVersion:  uintValue(metadata["ip_version"]),
+	}
+	db.databaseType, _ = metadata["database_type"].(string)
+
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(markerAt) {
+		return nil, errCorrupt
+	}
+	db.tree = file[:treeSize]
+	db.data = file[treeSize+dataSectionSeparator : markerAt]
+
+	// IPv4 addresses live under ::/96 in IPv6 trees
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			if node, err = db.record(node, 0); err != nil {
+				return nil, err
+			}
+		}
+		db.ipv4Start = node
+	}
+
+	return db, nil
+}
+
+// find returns the decoded record of an address, or nil when there is none
+func (db *database) find(ip net.IP) (interface{}, error) {
+	node := uint(0)
+	bits := ip.To16()
+	if v4 := ip.To4(); v4 != nil {
+		bits = v4
+		node = db.ipv4Start
+	} else if db.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		next, err := db.record(node, bit)
+		if err != nil {
+			return nil, err
+		}
+		node = next
+	}
+
+	if node <= db.nodeCount {
+		return nil, nil // no data for the address (node_count is the empty marker)
+	}
+
+	offset := node - db.nodeCount - dataSectionSeparator
+	value, _, err := (&decoder{buf: db.data}).decode(offset, 0)
+	return value, err
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a tree node
+func (db *database) record(node, bit uint) (uint, error) {
+	size := db.recordSize / 4 // bytes per node
+	start := node * size
+	if start+size > uint(len(db.tree)) {
+		return 0, errCorrupt
+	}
+	b := db.tree[start : start+size]
+
+	switch db.recordSize {
+	case 24:
+		if bit == 0 {
+			return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3])<<16 | uint(b[4])<<8 | uint(b[5]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		if bit == 0 {
+			return uint(binary.BigEndian.Uint32(b[0:4])), nil
+		}
+		return uint(binary.BigEndian.Uint32(b[4:8])), nil
+	}
+}
+
+// decoder reads values of a data section (or the metadata map)
+type decoder struct {
+	buf []byte
+}
+
+// decode reads the value at offset and returns it with the offset after it
+func (d *decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errCorrupt
+	}
+
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if kind == typePointer {
+		// A pointer's value is decoded where it points; reading continues
+		// after the pointer
+		value, _, err := d.decode(size, depth+1)
+		return value, offset, err
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			m[name] = value
+		}
+		return m, offset, nil
+
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+
+	case typeBoolean:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errCorrupt
+	}
+	payload := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch kind {
+	case typeString:
+		return string(payload), next, nil
+	case typeBytes:
+		return payload, next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(payload))), next, nil
+	case typeUint16, typeUint32, typeUint64, typeInt32:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		var n uint64
+		for _, b := range payload {
+			n = n<<8 | uint64(b)
+		}
+		if kind == typeInt32 {
+			return int64(int32(uint32(n))), next, nil
+		}
+		return n, next, nil
+	case typeUint128:
+		return payload, next, nil // not needed for lookups
+	default:
+		return nil, 0, fmt.Errorf("%w: unexpected type %d", errCorrupt, kind)
+	}
+}
+
+// control reads a field's control byte(s). For pointers the returned size is
+// the pointer target.
+func (d *decoder) control(offset uint) (kind, size, next uint, err error) {
+	read := func(n uint) ([]byte, error) {
+		if offset+n > uint(len(d.buf)) {
+			return nil, errCorrupt
+		}
+		b := d.buf[offset : offset+n]
+		offset += n
+		return b, nil
+	}
+
+	b, err := read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	ctrl := uint(b[0])
+	kind = ctrl >> 5
+
+	if kind == typePointer {
+		pointerSize := (ctrl >> 3) & 0x3
+		p, err := read(pointerSize + 1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var target uint
+		switch pointerSize {
+		case 0:
+			target = (ctrl&0x7)<<8 | uint(p[0])
+		case 1:
+			target = ((ctrl&0x7)<<16 | uint(p[0])<<8 | uint(p[1])) + 2048
+		case 2:
+			target = ((ctrl&0x7)<<24 | uint(p[0])<<16 | uint(p[1])<<8 | uint(p[2])) + 526336
+		default:
+			target = uint(binary.BigEndian.Uint32(p))
+		}
+		return kind, target, offset, nil
+	}
+
+	if kind == typeExtended {
+		ext, err := read(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		kind = 7 + uint(ext[0])
+		if kind <= typeMap || kind == typeContainer || kind == typeEndMarker || kind > typeFloat {
+			return 0, 0, 0, fmt.Errorf("%w: invalid extended type %d", errCorrupt, kind)
+		}
+	}
+
+	size = ctrl & 0x1f
+	if size >= 29 {
+		extra := size - 28 // 1, 2 or 3 more bytes
+		s, err := read(extra)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var n uint
+		for _, c := range s {
+			n = n<<8 | uint(c)
+		}
+		switch extra {
+		case 1:
+			size = 29 + n
+		case 2:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+
+	return kind, size, offset, nil
+}
+
+// uintValue reads an unsigned metadata field
+func uintValue(value interface{}) uint {
+	n, _ := value.(uint64)
+	return uint(n)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ReviewAbuseReportRequest is the body of the admin takedown and dismiss endpoints
+type ReviewAbuseReportRequest struct {
+	Note string `json:"note,omitempty" validate:"omitempty,max=2000"`
+}
+
+// AbuseHandler handles abuse reports and their admin review
+type AbuseHandler struct {
+	abuseService *services.AbuseService
+}
+
+// NewAbuseHandler creates a new abuse handler
+func NewAbuseHandler(abuseService *services.AbuseService) *AbuseHandler {
+	return &AbuseHandler{abuseService: abuseService}
+}
+
+// CreateReport handles POST /api/v1/abuse-reports (no auth required)
+func (h *AbuseHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAbuseReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	report, err := h.abuseService.Report(r.Context(), req, utils.ClientIP(r))
+	if err != nil {
+		switch err.Error() {
+		case "captcha is required", "captcha verification failed", "invalid subdomain":
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case "no instance is served on this subdomain":
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case "this subdomain has already been reported":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to submit report")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Thank you, the report will be reviewed",
+		"data": map[string]interface{}{
+			"id": report.ID,
+		},
+	})
+}
+
+// ListReports handles GET /api/v1/admin/abuse-reports?status=
+func (h *AbuseHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.abuseService.List(r.Context(), strings.TrimSpace(r.URL.Query().Get("status")))
+	if err != nil {
+		if err.Error() == "status must be open, actioned or dismissed" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to list abuse reports")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"reports": reports,
+	})
+}
+
+// GetReport handles GET /api/v1/admin/abuse-reports/:id
+func (h *AbuseHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.abuseService.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAbuseError(w, err, "Failed to get abuse report")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"report":  report,
+	})
+}
+
+// TakeDown handles POST /api/v1/admin/abuse-reports/:id/takedown (suspends the
+// reported instance and notifies its owner)
+func (h *AbuseHandler) TakeDown(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.abuseService.TakeDown, "Instance taken down")
+}
+
+// Dismiss handles POST /api/v1/admin/abuse-reports/:id/dismiss
+func (h *AbuseHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.abuseService.Dismiss, "Report dismissed")
+}
+
+// review resolves a report with the admin's optional note
+func (h *AbuseHandler) review(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, id, adminID, note, ipAddress string) (*models.AbuseReport, error), message string) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req ReviewAbuseReportRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	report, err := resolve(r.Context(), mux.Vars(r)["id"], adminID, strings.TrimSpace(req.Note), utils.ClientIP(r))
+	if err != nil {
+		respondWithAbuseError(w, err, "Failed to resolve abuse report")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"report":  report,
+	})
+}
+
+// respondWithAbuseError maps abuse service errors to responses
+func respondWithAbuseError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, models.ErrAbuseReportNotFound):
+		respondWithError(w, http.StatusNotFound, "Abuse report not found")
+	case errors.Is(err, models.ErrAbuseReportResolved):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err.Error() == "the reported instance no longer exists", err.Error() == "instance is pending deletion":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
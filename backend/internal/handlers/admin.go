@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// InstanceSearcher finds, suspends, relocates and reviews quarantined instances across all users (implemented by *services.InstanceService)
+type InstanceSearcher interface {
+	SearchAllInstances(ctx context.Context, term string) ([]models.Instance, error)
+	SuspendInstance(ctx context.Context, instanceID uuid.UUID, reason string) (*models.Instance, error)
+	UnsuspendInstance(ctx context.Context, instanceID uuid.UUID) (*models.Instance, error)
+	ReleaseQuarantine(ctx context.Context, instanceID uuid.UUID, approve bool) (*models.Instance, error)
+	RelocateStorage(ctx context.Context, instanceID, adminID uuid.UUID, targetPath string) (*services.RelocationResult, error)
+}
+
+// AdminHandler handles platform administration endpoints
+type AdminHandler struct {
+	inviteService    *services.InviteService
+	instanceService  InstanceSearcher
+	bandwidthService *services.BandwidthService
+	platformService  *services.PlatformService
+	jobQueue         *jobs.Queue
+	config           *config.Config
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(inviteService *services.InviteService, instanceService InstanceSearcher, bandwidthService *services.BandwidthService, platformService *services.PlatformService, jobQueue *jobs.Queue, cfg *config.Config) *AdminHandler {
+	return &AdminHandler{
+		inviteService:    inviteService,
+		instanceService:  instanceService,
+		bandwidthService: bandwidthService,
+		platformService:  platformService,
+		jobQueue:         jobQueue,
+		config:           cfg,
+	}
+}
+
+// CreateInvite handles POST /api/v1/admin/invites
+func (h *AdminHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request
+	var req models.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	// Call service to generate the invite
+	invite, err := h.inviteService.GenerateInvite(services.GenerateInviteParams{
+		CreatedByUserID: userID,
+		MaxUses:         req.MaxUses,
+		ExpiresIn:       time.Duration(req.ExpiresInHours) * time.Hour,
+		Note:            req.Note,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create invite code")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Invite code created successfully",
+		"data": map[string]interface{}{
+			"invite": invite,
+		},
+	})
+}
+
+// ListInvites handles GET /api/v1/admin/invites
+func (h *AdminHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.inviteService.ListInvites()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list invite codes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"invites": invites,
+		},
+	})
+}
+
+// RevokeInvite handles DELETE /api/v1/admin/invites/:id
+func (h *AdminHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.inviteService.RevokeInvite(vars["id"]); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "invite code not found" || err.Error() == "invite code not found or already revoked" {
+			statusCode = http.StatusNotFound
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Invite code revoked successfully",
+	})
+}
+
+// SearchInstances handles GET /api/v1/admin/instances?q=term
+func (h *AdminHandler) SearchInstances(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "Search term is required")
+		return
+	}
+
+	instances, err := h.instanceService.SearchAllInstances(r.Context(), q)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search instances")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"instances": instances,
+	})
+}
+
+// SuspendInstanceRequest is the body of POST /api/v1/admin/instances/:id/suspend
+type SuspendInstanceRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// SuspendInstance handles POST /api/v1/admin/instances/:id/suspend
+func (h *AdminHandler) SuspendInstance(w http.ResponseWriter, r *http.Request) {
+	instanceID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	var req SuspendInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	instance, err := h.instanceService.SuspendInstance(r.Context(), instanceID, req.Reason)
+	if err != nil {
+		respondWithSuspensionError(w, err, "Failed to suspend instance")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Instance suspended",
+		"instance": instance,
+	})
+}
+
+// UnsuspendInstance handles POST /api/v1/admin/instances/:id/unsuspend
+func (h *AdminHandler) UnsuspendInstance(w http.ResponseWriter, r *http.Request) {
+	instanceID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	instance, err := h.instanceService.UnsuspendInstance(r.Context(), instanceID)
+	if err != nil {
+		respondWithSuspensionError(w, err, "Failed to lift instance suspension")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Instance suspension lifted, the owner can start it again",
+		"instance": instance,
+	})
+}
+
+// ReleaseQuarantineRequest is the body of POST /api/v1/admin/instances/:id/quarantine/release
+type ReleaseQuarantineRequest struct {
+	Action string `json:"action" validate:"required,oneof=approve discard"`
+}
+
+// ReleaseQuarantine handles POST /api/v1/admin/instances/:id/quarantine/release.
+// "approve" installs the quarantined bundle (a false positive), "discard" deletes it.
+func (h *AdminHandler) ReleaseQuarantine(w http.ResponseWriter, r *http.Request) {
+	instanceID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	var req ReleaseQuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	instance, err := h.instanceService.ReleaseQuarantine(r.Context(), instanceID, req.Action == "approve")
+	if err != nil {
+		switch err.Error() {
+		case "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case "instance is not quarantined", "instance has no container", "instance is pending deletion":
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to release quarantined bundle")
+		}
+		return
+	}
+
+	message := "Quarantined bundle discarded"
+	if req.Action == "approve" {
+		message = "Quarantined bundle approved and installed"
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"instance": instance,
+	})
+}
+
+// RelocateStorageRequest is the body of POST /api/v1/admin/instances/:id/relocate
+type RelocateStorageRequest struct {
+	DataPath string `json:"data_path" validate:"required,max=4096"`
+}
+
+// RelocateStorage handles POST /api/v1/admin/instances/:id/relocate. The data
+// directory is copied and verified before the container is switched over;
+// progress is pushed as instance.relocation events.
+func (h *AdminHandler) RelocateStorage(w http.ResponseWriter, r *http.Request) {
+	adminID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req RelocateStorageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	result, err := h.instanceService.RelocateStorage(r.Context(), instanceID, adminID, req.DataPath)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, services.ErrInvalidRelocationTarget):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrInsufficientDiskSpace):
+			respondWithError(w, http.StatusInsufficientStorage, err.Error())
+		case errors.Is(err, services.ErrRelocationFailed):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case err.Error() == "instance has no container" || err.Error() == "instance must be running or stopped to be relocated":
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to relocate instance")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":       true,
+		"message":       "Instance data relocated",
+		"instance":      result.Instance,
+		"previous_path": result.PreviousPath,
+		"files":         result.Files,
+		"bytes":         result.Bytes,
+	})
+}
+
+func respondWithSuspensionError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case "instance is pending deletion", "instance is already suspended", "instance is not suspended",
+		"instance is already suspended or pending deletion":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// SetBandwidthQuota handles PUT /api/v1/admin/users/:id/bandwidth-quota
+// A null quota_gb clears the override, 0 means unlimited
+func (h *AdminHandler) SetBandwidthQuota(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req struct {
+		QuotaGB *int `json:"quota_gb"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	bandwidth, err := h.bandwidthService.SetQuotaOverride(r.Context(), vars["id"], req.QuotaGB)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "user not found" {
+			statusCode = http.StatusNotFound
+		} else if err.Error() == "quota must not be negative" {
+			statusCode = http.StatusBadRequest
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Bandwidth quota updated successfully",
+		"data": map[string]interface{}{
+			"bandwidth": bandwidth,
+		},
+	})
+}
+
+// UpdatePlatformStatus handles PUT /api/v1/admin/status
+func (h *AdminHandler) UpdatePlatformStatus(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request
+	var req models.UpdatePlatformStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	status, err := h.platformService.UpdateStatus(userID, req)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update platform status")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Platform status updated successfully",
+		"data": map[string]interface{}{
+			"status": status,
+		},
+	})
+}
+
+// GetPlatformSettings handles GET /api/v1/admin/settings
+func (h *AdminHandler) GetPlatformSettings(w http.ResponseWriter, r *http.Request) {
+	overrides, effective, err := h.platformService.GetSettings()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get platform settings")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"settings":  effective,
+			"overrides": overrides,
+		},
+	})
+}
+
+// UpdatePlatformSettings handles PATCH /api/v1/admin/settings
+func (h *AdminHandler) UpdatePlatformSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request
+	var req models.UpdatePlatformSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Validate request
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	overrides, effective, err := h.platformService.UpdateSettings(userID, req)
+	if err != nil {
+		if err.Error() == "invalid image reference" || errors.Is(err, services.ErrInvalidSetting) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to update platform settings")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Platform settings updated successfully",
+		"data": map[string]interface{}{
+			"settings":  effective,
+			"overrides": overrides,
+		},
+	})
+}
+
+// maxListedJobs bounds the number of jobs returned by ListJobs
+const maxListedJobs = 200
+
+// ListJobs handles GET /api/v1/admin/jobs?status=dead&type=...&limit=50
+func (h *AdminHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	status := query.Get("status")
+	switch status {
+	case "", jobs.StatusPending, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusDead:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid job status")
+		return
+	}
+
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxListedJobs {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.jobQueue.List(r.Context(), status, query.Get("type"), limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list jobs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"jobs":    list,
+	})
+}
+
+// GetJob handles GET /api/v1/admin/jobs/:id
+func (h *AdminHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobQueue.Get(r.Context(), id)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "job not found" {
+			statusCode = http.StatusNotFound
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"job":     job,
+	})
+}
+
+// RequeueJob handles POST /api/v1/admin/jobs/:id/requeue
+func (h *AdminHandler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobQueue.Requeue(r.Context(), id)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "job not found" {
+			statusCode = http.StatusNotFound
+		} else if err.Error() == "only dead jobs can be requeued" {
+			statusCode = http.StatusConflict
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Job requeued successfully",
+		"job":     job,
+	})
+}
+
+// ReloadConfig handles POST /api/v1/admin/config/reload. Only the process that
+// serves the request reloads; with several replicas, send SIGHUP to each.
+func (h *AdminHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.config.Reload(); err != nil {
+		respondWithError(w, http.StatusUnprocessableEntity, "Configuration not reloaded: "+err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Configuration reloaded successfully",
+	})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestAdminOperationRequest is the body of the endpoints requesting a
+// destructive operation
+type RequestAdminOperationRequest struct {
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=2000"`
+}
+
+// AdminOperationHandler handles destructive admin operations and their
+// approval by a second admin
+type AdminOperationHandler struct {
+	approvalService *services.AdminApprovalService
+}
+
+// NewAdminOperationHandler creates a new admin operation handler
+func NewAdminOperationHandler(approvalService *services.AdminApprovalService) *AdminOperationHandler {
+	return &AdminOperationHandler{approvalService: approvalService}
+}
+
+// RequestUserDeletion handles DELETE /api/v1/admin/users/:id (deletes the user
+// and their instances once another admin approves)
+func (h *AdminOperationHandler) RequestUserDeletion(w http.ResponseWriter, r *http.Request) {
+	h.request(w, r, h.approvalService.RequestUserDeletion)
+}
+
+// RequestArchivePurge handles DELETE /api/v1/admin/archived-instances/:id
+// (deletes the retained data once another admin approves)
+func (h *AdminOperationHandler) RequestArchivePurge(w http.ResponseWriter, r *http.Request) {
+	h.request(w, r, h.approvalService.RequestArchivePurge)
+}
+
+// request stores a pending operation on the target with the admin's optional reason
+func (h *AdminOperationHandler) request(w http.ResponseWriter, r *http.Request, request func(ctx context.Context, targetID, adminID, reason, ipAddress string) (*models.AdminOperation, error)) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req RequestAdminOperationRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	op, err := request(r.Context(), mux.Vars(r)["id"], adminID, strings.TrimSpace(req.Reason), utils.ClientIP(r))
+	if err != nil {
+		respondWithAdminOperationError(w, err, "Failed to request operation")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success":   true,
+		"message":   "Operation requested, it runs once another admin approves it",
+		"operation": op,
+	})
+}
+
+// ListOperations handles GET /api/v1/admin/operations?status=
+func (h *AdminOperationHandler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	ops, err := h.approvalService.List(r.Context(), strings.TrimSpace(r.URL.Query().Get("status")))
+	if err != nil {
+		if err.Error() == "status must be pending, approved, rejected, expired or failed" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to list operations")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"operations": ops,
+	})
+}
+
+// Approve handles POST /api/v1/admin/operations/:id/approve (carries the
+// operation out)
+func (h *AdminOperationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	op, err := h.approvalService.Approve(r.Context(), mux.Vars(r)["id"], adminID, utils.ClientIP(r))
+	if err != nil {
+		respondWithAdminOperationError(w, err, "Failed to approve operation")
+		return
+	}
+
+	if op.Status == models.AdminOperationFailed {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":   false,
+			"message":   "Operation approved, but it failed: " + *op.Error,
+			"operation": op,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"message":   "Operation approved and carried out",
+		"operation": op,
+	})
+}
+
+// Reject handles POST /api/v1/admin/operations/:id/reject
+func (h *AdminOperationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	op, err := h.approvalService.Reject(r.Context(), mux.Vars(r)["id"], adminID, utils.ClientIP(r))
+	if err != nil {
+		respondWithAdminOperationError(w, err, "Failed to reject operation")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"message":   "Operation rejected",
+		"operation": op,
+	})
+}
+
+// respondWithAdminOperationError maps admin approval errors to responses
+func respondWithAdminOperationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, models.ErrAdminOperationNotFound):
+		respondWithError(w, http.StatusNotFound, "Operation not found")
+	case err.Error() == "user not found":
+		respondWithError(w, http.StatusNotFound, "User not found")
+	case err.Error() == "archived instance not found":
+		respondWithError(w, http.StatusNotFound, "Archived instance not found")
+	case errors.Is(err, models.ErrAdminOperationDecided), errors.Is(err, models.ErrAdminOperationExists),
+		err.Error() == "archived instance data already purged":
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrSelfApproval):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case err.Error() == "you cannot delete your own account":
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
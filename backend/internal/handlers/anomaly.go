@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// AnomalyHandler handles the admin review queue of suspicious instance behavior
+type AnomalyHandler struct {
+	anomalyDetector *services.AnomalyDetector
+}
+
+// NewAnomalyHandler creates a new anomaly handler
+func NewAnomalyHandler(anomalyDetector *services.AnomalyDetector) *AnomalyHandler {
+	return &AnomalyHandler{anomalyDetector: anomalyDetector}
+}
+
+// ListAnomalies handles GET /api/v1/admin/anomalies?status=
+func (h *AnomalyHandler) ListAnomalies(w http.ResponseWriter, r *http.Request) {
+	anomalies, err := h.anomalyDetector.List(r.Context(), strings.TrimSpace(r.URL.Query().Get("status")))
+	if err != nil {
+		if err.Error() == "status must be open, dismissed or suspended" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to list anomalies")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"anomalies": anomalies,
+	})
+}
+
+// Dismiss handles POST /api/v1/admin/anomalies/:id/dismiss
+func (h *AnomalyHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.anomalyDetector.Dismiss, "Anomaly dismissed")
+}
+
+// Suspend handles POST /api/v1/admin/anomalies/:id/suspend
+func (h *AnomalyHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.anomalyDetector.Suspend, "Instance suspended")
+}
+
+// review resolves an anomaly with the admin's optional note
+func (h *AnomalyHandler) review(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, id, adminID, note, ipAddress string) (*models.InstanceAnomaly, error), message string) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req ReviewAbuseReportRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	anomaly, err := resolve(r.Context(), mux.Vars(r)["id"], adminID, strings.TrimSpace(req.Note), utils.ClientIP(r))
+	if err != nil {
+		respondWithAnomalyError(w, err, "Failed to resolve anomaly")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"anomaly": anomaly,
+	})
+}
+
+// respondWithAnomalyError maps anomaly detector errors to responses
+func respondWithAnomalyError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, models.ErrAnomalyNotFound):
+		respondWithError(w, http.StatusNotFound, "Anomaly not found")
+	case errors.Is(err, models.ErrAnomalyResolved):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err.Error() == "instance is pending deletion":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
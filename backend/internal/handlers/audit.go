@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+)
+
+// AuditHandler handles the admin audit log endpoint
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// ListAuditLog handles GET /api/v1/admin/audit-log?actor_id=&user_id=&action=&limit=
+func (h *AuditHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			respondWithError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.auditService.List(r.Context(), models.AuditFilter{
+		ActorID: query.Get("actor_id"),
+		UserID:  query.Get("user_id"),
+		Action:  query.Get("action"),
+		Limit:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list audit log")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"entries": entries,
+	})
+}
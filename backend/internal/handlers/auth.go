@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"pocketploy/internal/config"
 	"pocketploy/internal/middleware"
 	"pocketploy/internal/models"
 	"pocketploy/internal/services"
@@ -12,13 +13,17 @@ import (
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	authService *services.AuthService
+	authService  *services.AuthService
+	tokenService *services.TokenService
+	config       *config.Config
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, tokenService *services.TokenService, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:  authService,
+		tokenService: tokenService,
+		config:       cfg,
 	}
 }
 
@@ -46,33 +51,34 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 
 	// Call service to create user
 	user, tokens, err := h.authService.RegisterUser(services.SignupParams{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: req.Password,
-		Request:  r,
+		Username:     req.Username,
+		Email:        req.Email,
+		Password:     req.Password,
+		InviteCode:   req.InviteCode,
+		CaptchaToken: req.CaptchaToken,
+		Request:      r,
 	})
 	if err != nil {
 		// Map service errors to HTTP status codes
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "username already exists" || err.Error() == "email already exists" {
 			statusCode = http.StatusConflict
-		} else if err.Error() == "validation failed" {
+		} else if err.Error() == "validation failed" || err.Error() == "username is reserved" || err.Error() == "captcha is required" || err.Error() == "captcha verification failed" {
 			statusCode = http.StatusBadRequest
+		} else if err.Error() == "invite code is required" || err.Error() == "invalid invite code" || err.Error() == "signups are disabled" {
+			statusCode = http.StatusForbidden
 		}
 		respondWithError(w, statusCode, err.Error())
 		return
 	}
 
+	data := h.tokenData(w, user, tokens)
+
 	// Return response
 	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
 		"success": true,
 		"message": "User created successfully",
-		"data": map[string]interface{}{
-			"user":          user.ToResponse(),
-			"access_token":  tokens.AccessToken,
-			"refresh_token": tokens.RefreshToken,
-			"expires_at":    tokens.ExpiresAt,
-		},
+		"data":    data,
 	})
 }
 
@@ -93,49 +99,57 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Call service to authenticate user
 	user, tokens, err := h.authService.AuthenticateUser(services.LoginParams{
-		Email:    req.Email,
-		Password: req.Password,
-		Request:  r,
+		Email:        req.Email,
+		Password:     req.Password,
+		CaptchaToken: req.CaptchaToken,
+		DeviceToken:  h.deviceToken(r, req.DeviceToken),
+		Request:      r,
 	})
 	if err != nil {
 		// Map service errors to HTTP status codes
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "invalid email or password" || err.Error() == "account is inactive" {
 			statusCode = http.StatusUnauthorized
+		} else if err.Error() == "captcha is required" || err.Error() == "captcha verification failed" {
+			statusCode = http.StatusBadRequest
 		}
 		respondWithError(w, statusCode, err.Error())
 		return
 	}
 
+	data := h.tokenData(w, user, tokens)
+
 	// Return response
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "Login successful",
+		"data":    data,
+	})
+}
+
+// Captcha returns the captcha settings for signup and login forms
+func (h *AuthHandler) Captcha(w http.ResponseWriter, r *http.Request) {
+	provider, siteKey, enabled := h.authService.CaptchaSettings()
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
 		"data": map[string]interface{}{
-			"user":          user.ToResponse(),
-			"access_token":  tokens.AccessToken,
-			"refresh_token": tokens.RefreshToken,
-			"expires_at":    tokens.ExpiresAt,
+			"enabled":  enabled,
+			"provider": provider,
+			"site_key": siteKey,
 		},
 	})
 }
 
 // Refresh handles token refresh
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-	// Parse request
-	var req models.RefreshRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if req.RefreshToken == "" {
-		respondWithError(w, http.StatusBadRequest, "Refresh token is required")
+	refreshToken, ok := h.refreshToken(w, r)
+	if !ok {
 		return
 	}
 
 	// Call service to refresh access token
-	accessToken, expiresAt, err := h.authService.RefreshAccessToken(req.RefreshToken)
+	accessToken, expiresAt, err := h.authService.RefreshAccessToken(refreshToken)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, err.Error())
 		return
@@ -153,24 +167,26 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 // Logout handles user logout
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// Parse request
-	var req models.LogoutRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if req.RefreshToken == "" {
-		respondWithError(w, http.StatusBadRequest, "Refresh token is required")
+	refreshToken, ok := h.refreshToken(w, r)
+	if !ok {
 		return
 	}
 
 	// Call service to revoke token
-	if err := h.authService.RevokeRefreshToken(req.RefreshToken); err != nil {
+	if err := h.authService.RevokeRefreshToken(refreshToken); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to revoke token")
 		return
 	}
 
+	if h.cookieDelivery() {
+		h.clearSessionCookies(w)
+	}
+
+	// Revoke the access token used for this request as well
+	if claims, ok := middleware.GetUserClaims(r); ok {
+		h.authService.RevokeAccessToken(claims)
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "Logged out successfully",
@@ -199,10 +215,17 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data := map[string]interface{}{
+		"user": user.ToResponse(),
+	}
+
+	// Lets the dashboard show that an admin is acting as the user
+	if claims, ok := middleware.GetUserClaims(r); ok && claims.Impersonated() {
+		data["impersonated_by"] = claims.ImpersonatorID
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data": map[string]interface{}{
-			"user": user.ToResponse(),
-		},
+		"data":    data,
 	})
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+)
+
+const (
+	// refreshCookieName holds the refresh token; it is HttpOnly and only sent to the auth routes
+	refreshCookieName = "pocketploy_refresh"
+	refreshCookiePath = "/api/v1/auth"
+
+	// csrfCookieName is readable by the frontend, which echoes it in csrfHeaderName:
+	// another site can make the browser send the cookies, but cannot read them
+	// to set the header. The token is an HMAC of the refresh token, so a cookie
+	// planted from a sibling subdomain (cookie tossing) doesn't pass either.
+	csrfCookieName = "pocketploy_csrf"
+	csrfHeaderName = "X-CSRF-Token"
+
+	// csrfKeyLabel derives the CSRF key from JWT_REFRESH_SECRET, so CSRF
+	// tokens can't be passed off as anything else signed with it
+	csrfKeyLabel = "pocketploy csrf tokens"
+
+	// deviceCookieName identifies a trusted device at login; it is HttpOnly
+	// and set in both refresh token delivery modes
+	deviceCookieName = "pocketploy_device"
+)
+
+// cookieDelivery reports whether refresh tokens are delivered as cookies
+func (h *AuthHandler) cookieDelivery() bool {
+	return h.config.RefreshTokenDelivery == "cookie"
+}
+
+// tokenData builds the token part of a login or signup response. In cookie
+// mode the refresh token is set as a cookie instead of being returned.
+func (h *AuthHandler) tokenData(w http.ResponseWriter, user *models.User, tokens *services.TokenPair) map[string]interface{} {
+	data := map[string]interface{}{
+		"user":         user.ToResponse(),
+		"access_token": tokens.AccessToken,
+		"expires_at":   tokens.ExpiresAt,
+	}
+	if tokens.TrustedUntil != nil {
+		data["trusted_until"] = tokens.TrustedUntil
+	}
+
+	if !h.cookieDelivery() {
+		data["refresh_token"] = tokens.RefreshToken
+		return data
+	}
+
+	h.setSessionCookies(w, tokens.RefreshToken)
+	return data
+}
+
+// setSessionCookies sets the refresh token cookie and its CSRF cookie
+func (h *AuthHandler) setSessionCookies(w http.ResponseWriter, refreshToken string) {
+	maxAge := int(h.config.JWTRefreshExpiry / time.Second)
+	http.SetCookie(w, h.cookie(refreshCookieName, refreshToken, refreshCookiePath, maxAge, true))
+	http.SetCookie(w, h.cookie(csrfCookieName, h.csrfToken(refreshToken), "/", maxAge, false))
+}
+
+// csrfToken returns the CSRF token bound to a refresh token
+func (h *AuthHandler) csrfToken(refreshToken string) string {
+	key := hmac.New(sha256.New, []byte(h.config.JWTRefreshSecret))
+	key.Write([]byte(csrfKeyLabel))
+
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	mac.Write([]byte(refreshToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// clearSessionCookies removes the refresh token and CSRF cookies
+func (h *AuthHandler) clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, h.cookie(refreshCookieName, "", refreshCookiePath, -1, true))
+	http.SetCookie(w, h.cookie(csrfCookieName, "", "/", -1, false))
+}
+
+// setDeviceCookie sets the device cookie of a trusted device
+func (h *AuthHandler) setDeviceCookie(w http.ResponseWriter, deviceToken string) {
+	maxAge := int(h.config.TrustedDeviceTTL / time.Second)
+	http.SetCookie(w, h.cookie(deviceCookieName, deviceToken, refreshCookiePath, maxAge, true))
+}
+
+// deviceToken reads the device token of a login: the device cookie, or the
+// one given in the request body by clients that can't keep cookies
+func (h *AuthHandler) deviceToken(r *http.Request, fromBody string) string {
+	if cookie, err := r.Cookie(deviceCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	return fromBody
+}
+
+func (h *AuthHandler) cookie(name, value, path string, maxAge int, httpOnly bool) *http.Cookie {
+	sameSite := http.SameSiteStrictMode
+	switch h.config.CookieSameSite {
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.config.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   h.config.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: sameSite,
+	}
+}
+
+// refreshToken reads the refresh token of a refresh or logout request: from
+// the cookie (after checking the CSRF token) in cookie mode, otherwise from
+// the JSON body. It writes the error response and returns false on failure.
+func (h *AuthHandler) refreshToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !h.cookieDelivery() {
+		var req models.RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return "", false
+		}
+
+		if req.RefreshToken == "" {
+			respondWithError(w, http.StatusBadRequest, "Refresh token is required")
+			return "", false
+		}
+
+		return req.RefreshToken, true
+	}
+
+	refreshCookie, err := r.Cookie(refreshCookieName)
+	if err != nil || refreshCookie.Value == "" {
+		respondWithError(w, http.StatusUnauthorized, "Refresh token is required")
+		return "", false
+	}
+
+	// The header must carry the token of this session, not merely match
+	// the CSRF cookie, which a sibling subdomain could have overwritten
+	csrfHeader := r.Header.Get(csrfHeaderName)
+	expected := h.csrfToken(refreshCookie.Value)
+	if csrfHeader == "" || subtle.ConstantTimeCompare([]byte(csrfHeader), []byte(expected)) != 1 {
+		respondWithError(w, http.StatusForbidden, "Invalid CSRF token")
+		return "", false
+	}
+
+	return refreshCookie.Value, true
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pocketploy/internal/config"
+)
+
+func TestRefreshTokenCSRF(t *testing.T) {
+	cfg := &config.Config{RefreshTokenDelivery: "cookie", JWTRefreshSecret: "refresh-secret"}
+	handler := &AuthHandler{config: cfg}
+
+	// The cookies of a session as set at login
+	w := httptest.NewRecorder()
+	handler.setSessionCookies(w, "refresh-token")
+	var csrfCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == csrfCookieName {
+			csrfCookie = cookie
+		}
+	}
+	if csrfCookie == nil {
+		t.Fatal("no CSRF cookie was set")
+	}
+
+	tests := []struct {
+		name     string
+		cookie   string // CSRF cookie sent by the browser
+		header   string
+		wantCode int
+	}{
+		{"session token", csrfCookie.Value, csrfCookie.Value, http.StatusOK},
+		{"missing header", csrfCookie.Value, "", http.StatusForbidden},
+		{"tossed cookie", "attacker-token", "attacker-token", http.StatusForbidden},
+		{"token of another session", handler.csrfToken("other-token"), handler.csrfToken("other-token"), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+			r.AddCookie(&http.Cookie{Name: refreshCookieName, Value: "refresh-token"})
+			r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tt.cookie})
+			if tt.header != "" {
+				r.Header.Set(csrfHeaderName, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			token, ok := handler.refreshToken(w, r)
+			if tt.wantCode == http.StatusOK {
+				if !ok || token != "refresh-token" {
+					t.Fatalf("refreshToken() = %q, %v, want the cookie's token (body %s)", token, ok, w.Body.String())
+				}
+				return
+			}
+			if ok || w.Code != tt.wantCode {
+				t.Errorf("refreshToken() ok = %v, status = %d, want %d", ok, w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ImpersonateRequest is the body of POST /api/v1/admin/users/:id/impersonate
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// Impersonate handles POST /api/v1/admin/users/:id/impersonate. It returns an
+// access token to act as the user; the reason is kept in the audit log.
+func (h *AuthHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// An impersonation token never belongs to an admin, but don't allow chaining
+	if claims, ok := middleware.GetUserClaims(r); ok && claims.Impersonated() {
+		respondWithError(w, http.StatusForbidden, "Not allowed while impersonating")
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	impersonation, err := h.authService.Impersonate(r.Context(), adminID, mux.Vars(r)["id"], req.Reason, utils.ClientIP(r))
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case "cannot impersonate yourself", "account is inactive", "administrators cannot be impersonated":
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to impersonate user")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Impersonation started. Requests made with this token are audited",
+		"data": map[string]interface{}{
+			"user":         impersonation.User.ToResponse(),
+			"access_token": impersonation.AccessToken,
+			"expires_at":   impersonation.ExpiresAt,
+		},
+	})
+}
+
+// EndImpersonation handles POST /api/v1/auth/impersonation/end, revoking the
+// impersonation token the request was made with
+func (h *AuthHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.authService.EndImpersonation(r.Context(), claims, utils.ClientIP(r)); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Impersonation ended",
+	})
+}
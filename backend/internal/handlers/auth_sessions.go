@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ListSessions handles GET /api/v1/auth/sessions
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	sessions, err := h.tokenService.ListSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"sessions": sessions,
+	})
+}
+
+// UpdateSession handles PATCH /api/v1/auth/sessions/:id (labels the session)
+func (h *AuthHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.UpdateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	if err := h.tokenService.RenameSession(claims.UserID, mux.Vars(r)["id"], req.Name); err != nil {
+		respondWithSessionError(w, err, "Failed to update session")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Session updated",
+	})
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/:id (signs the session
+// out and removes the trust of its device)
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	sessionID := mux.Vars(r)["id"]
+	if err := h.tokenService.RevokeUserSession(claims.UserID, sessionID); err != nil {
+		respondWithSessionError(w, err, "Failed to revoke session")
+		return
+	}
+
+	if sessionID == claims.SessionID && h.cookieDelivery() {
+		h.clearSessionCookies(w)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// RevokeSessionByLinkRequest is the body of POST /api/v1/auth/revoke-session
+type RevokeSessionByLinkRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+// RevokeSessionByLink handles POST /api/v1/auth/revoke-session with the token
+// of the sign-out link in a new device login notification. No session is
+// required, so the link works in any browser.
+func (h *AuthHandler) RevokeSessionByLink(w http.ResponseWriter, r *http.Request) {
+	var req RevokeSessionByLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	session, err := h.tokenService.RevokeSessionByLink(req.Token)
+	if err != nil {
+		if err.Error() == "invalid or used sign-out link" {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "The session has been signed out. Change your password if you don't recognize the login",
+		"data": map[string]interface{}{
+			"session_id": session.ID,
+			"ip_address": session.IPAddress,
+			"user_agent": session.UserAgent,
+			"created_at": session.CreatedAt,
+		},
+	})
+}
+
+// ListTrustedDevices handles GET /api/v1/auth/devices
+func (h *AuthHandler) ListTrustedDevices(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	devices, err := h.tokenService.ListTrustedDevices(claims.UserID, claims.SessionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list trusted devices")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"devices": devices,
+	})
+}
+
+// TrustDevice handles POST /api/v1/auth/devices (trusts the device of the
+// current session). The device token is set as a cookie and also returned for
+// clients that can't keep cookies; it is shown only once.
+func (h *AuthHandler) TrustDevice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Support staff acting as the user mustn't leave a trusted device behind
+	if claims.Impersonated() {
+		respondWithError(w, http.StatusForbidden, "Not allowed while impersonating")
+		return
+	}
+
+	deviceToken, trustedUntil, err := h.tokenService.TrustDevice(claims.UserID, claims.SessionID)
+	if err != nil {
+		respondWithSessionError(w, err, "Failed to trust device")
+		return
+	}
+
+	h.setDeviceCookie(w, deviceToken)
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Device trusted",
+		"data": map[string]interface{}{
+			"session_id":    claims.SessionID,
+			"device_token":  deviceToken,
+			"trusted_until": trustedUntil,
+		},
+	})
+}
+
+// UntrustDevice handles DELETE /api/v1/auth/devices/:id, where id is the
+// session holding the device's trust
+func (h *AuthHandler) UntrustDevice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.tokenService.UntrustDevice(claims.UserID, mux.Vars(r)["id"]); err != nil {
+		respondWithSessionError(w, err, "Failed to revoke device trust")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Device trust revoked",
+	})
+}
+
+// respondWithSessionError maps session and device errors to responses
+func respondWithSessionError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "session not found", "device not found":
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
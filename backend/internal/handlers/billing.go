@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"pocketploy/internal/billing"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/services"
+)
+
+// maxWebhookBytes bounds the size of a Stripe webhook payload
+const maxWebhookBytes = 1 << 20
+
+// BillingHandler handles plan checkout and Stripe webhooks
+type BillingHandler struct {
+	billingService *services.BillingService
+}
+
+// NewBillingHandler creates a new billing handler
+func NewBillingHandler(billingService *services.BillingService) *BillingHandler {
+	return &BillingHandler{billingService: billingService}
+}
+
+// CheckoutRequest is the body of POST /api/v1/billing/checkout
+type CheckoutRequest struct {
+	Plan string `json:"plan"`
+}
+
+// ListPlans handles GET /api/v1/billing/plans
+func (h *BillingHandler) ListPlans(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"plans":   h.billingService.Plans(),
+	})
+}
+
+// GetSubscription handles GET /api/v1/billing/subscription (null when the
+// user never subscribed)
+func (h *BillingHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	subscription, err := h.billingService.GetSubscription(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get subscription")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":      true,
+		"subscription": subscription,
+	})
+}
+
+// CreateCheckout handles POST /api/v1/billing/checkout (returns the Stripe
+// Checkout URL to redirect the user to)
+func (h *BillingHandler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req CheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session, err := h.billingService.CreateCheckout(r.Context(), userID, req.Plan)
+	if err != nil {
+		switch err.Error() {
+		case "unknown plan":
+			respondWithError(w, http.StatusBadRequest, "Unknown plan")
+		case "already subscribed":
+			respondWithError(w, http.StatusConflict, "Already subscribed")
+		case "user not found":
+			respondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			log.Printf("Checkout failed for user %s: %v", userID, err)
+			respondWithError(w, http.StatusBadGateway, "Failed to start checkout")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":      true,
+		"checkout_url": session.URL,
+	})
+}
+
+// Webhook handles POST /api/v1/billing/webhook (called by Stripe, authenticated
+// by the Stripe-Signature header). Errors make Stripe retry the event.
+func (h *BillingHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.billingService.HandleWebhook(r.Context(), payload, r.Header.Get("Stripe-Signature")); err != nil {
+		if errors.Is(err, billing.ErrInvalidSignature) {
+			respondWithError(w, http.StatusBadRequest, "Invalid signature")
+			return
+		}
+		log.Printf("Stripe webhook failed: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to process event")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// CreditHandler handles account credit and promo code endpoints
+type CreditHandler struct {
+	creditService *services.CreditService
+}
+
+// NewCreditHandler creates a new credit handler
+func NewCreditHandler(creditService *services.CreditService) *CreditHandler {
+	return &CreditHandler{creditService: creditService}
+}
+
+// RedeemPromoCodeRequest is the body of POST /api/v1/users/me/credits/redeem
+type RedeemPromoCodeRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+// GetCredits handles GET /api/v1/users/me/credits
+func (h *CreditHandler) GetCredits(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	balance, err := h.creditService.GetBalance(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get credits")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"credits": balance,
+	})
+}
+
+// RedeemPromoCode handles POST /api/v1/users/me/credits/redeem
+func (h *CreditHandler) RedeemPromoCode(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req RedeemPromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	entry, err := h.creditService.Redeem(r.Context(), userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPromoCodeInvalid):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrPromoCodeRedeemed):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to redeem promo code")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Promo code redeemed",
+		"credit":  entry,
+	})
+}
+
+// GrantCredit handles POST /api/v1/admin/users/:id/credits
+func (h *CreditHandler) GrantCredit(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.GrantCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	entry, err := h.creditService.Grant(r.Context(), adminID, mux.Vars(r)["id"], req.AmountCents, req.Reason)
+	if err != nil {
+		if err.Error() == "user not found" {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to grant credit")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Credit granted",
+		"credit":  entry,
+	})
+}
+
+// CreatePromoCode handles POST /api/v1/admin/promo-codes
+func (h *CreditHandler) CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.CreatePromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	promo, err := h.creditService.CreatePromoCode(r.Context(), services.CreatePromoCodeParams{
+		CreatedByUserID: adminID,
+		Code:            req.Code,
+		CreditCents:     req.CreditCents,
+		MaxRedemptions:  req.MaxRedemptions,
+		ExpiresIn:       time.Duration(req.ExpiresInHours) * time.Hour,
+		Note:            req.Note,
+	})
+	if err != nil {
+		if err.Error() == "promo code already exists" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to create promo code")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":    true,
+		"message":    "Promo code created successfully",
+		"promo_code": promo,
+	})
+}
+
+// ListPromoCodes handles GET /api/v1/admin/promo-codes
+func (h *CreditHandler) ListPromoCodes(w http.ResponseWriter, r *http.Request) {
+	promos, err := h.creditService.ListPromoCodes(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list promo codes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"promo_codes": promos,
+	})
+}
+
+// RevokePromoCode handles DELETE /api/v1/admin/promo-codes/:id
+func (h *CreditHandler) RevokePromoCode(w http.ResponseWriter, r *http.Request) {
+	if err := h.creditService.RevokePromoCode(r.Context(), mux.Vars(r)["id"]); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "promo code not found or already revoked" {
+			statusCode = http.StatusNotFound
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Promo code revoked successfully",
+	})
+}
+
+// respondWithValidationError writes the field errors of a failed validation
+func respondWithValidationError(w http.ResponseWriter, err error) {
+	respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"success": false,
+		"error":   "Validation failed",
+		"details": utils.GetValidationErrors(err),
+	})
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// CronHandler handles scheduled task endpoints for instances
+type CronHandler struct {
+	cronService *services.CronService
+}
+
+// NewCronHandler creates a new cron handler
+func NewCronHandler(cronService *services.CronService) *CronHandler {
+	return &CronHandler{cronService: cronService}
+}
+
+// cronValidationErrors are returned to the client as 400 Bad Request
+var cronValidationErrors = map[string]bool{
+	"task name must be between 1 and 100 characters": true,
+	"invalid schedule":                      true,
+	"task kind must be command or http":     true,
+	"command is not allowed":                true,
+	"http method must be GET, HEAD or POST": true,
+	"http path must start with /":           true,
+}
+
+// ListCrons handles GET /api/v1/instances/:id/crons
+func (h *CronHandler) ListCrons(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	crons, err := h.cronService.ListCrons(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithCronError(w, err, "Failed to list scheduled tasks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"crons":   crons,
+	})
+}
+
+// CreateCron handles POST /api/v1/instances/:id/crons
+func (h *CronHandler) CreateCron(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.InstanceCronParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cron, err := h.cronService.CreateCron(r.Context(), instanceID, userID, req)
+	if err != nil {
+		respondWithCronError(w, err, "Failed to create scheduled task")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Scheduled task created successfully",
+		"cron":    cron,
+	})
+}
+
+// UpdateCron handles PATCH /api/v1/instances/:id/crons/:cronId
+func (h *CronHandler) UpdateCron(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cronID, err := uuid.Parse(mux.Vars(r)["cronId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scheduled task ID")
+		return
+	}
+
+	var req services.InstanceCronParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cron, err := h.cronService.UpdateCron(r.Context(), instanceID, cronID, userID, req)
+	if err != nil {
+		respondWithCronError(w, err, "Failed to update scheduled task")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Scheduled task updated successfully",
+		"cron":    cron,
+	})
+}
+
+// DeleteCron handles DELETE /api/v1/instances/:id/crons/:cronId
+func (h *CronHandler) DeleteCron(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cronID, err := uuid.Parse(mux.Vars(r)["cronId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scheduled task ID")
+		return
+	}
+
+	if err := h.cronService.DeleteCron(r.Context(), instanceID, cronID, userID); err != nil {
+		respondWithCronError(w, err, "Failed to delete scheduled task")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Scheduled task deleted successfully",
+	})
+}
+
+// ListCronRuns handles GET /api/v1/instances/:id/crons/:cronId/runs
+func (h *CronHandler) ListCronRuns(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cronID, err := uuid.Parse(mux.Vars(r)["cronId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid scheduled task ID")
+		return
+	}
+
+	runs, err := h.cronService.ListCronRuns(r.Context(), instanceID, cronID, userID)
+	if err != nil {
+		respondWithCronError(w, err, "Failed to list scheduled task runs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"runs":    runs,
+	})
+}
+
+// parseInstanceRequest extracts the authenticated user and the instance ID of /instances/{id}/... routes
+func parseInstanceRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	instanceID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, instanceID, true
+}
+
+func respondWithCronError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "instance not found" || err.Error() == "scheduled task not found":
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case err.Error() == "scheduled task limit reached":
+		respondWithError(w, http.StatusConflict, err.Error())
+	case cronValidationErrors[err.Error()]:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// DeployHandler handles deploy tokens and the deploy endpoints CI pipelines
+// call with them, e.g. from a GitHub Actions workflow on push to main:
+//
+//	curl -X PUT -H "Authorization: Bearer $POCKETPLOY_DEPLOY_TOKEN" \
+//	  --data-binary @hooks.zip https://pocketploy.example/api/v1/deploy/hooks
+type DeployHandler struct {
+	deployService *services.DeployService
+	config        *config.Config
+}
+
+// NewDeployHandler creates a new deploy handler
+func NewDeployHandler(deployService *services.DeployService, cfg *config.Config) *DeployHandler {
+	return &DeployHandler{deployService: deployService, config: cfg}
+}
+
+// ListTokens handles GET /api/v1/instances/:id/deploy-tokens
+func (h *DeployHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.deployService.ListTokens(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithDeployTokenError(w, err, "Failed to list deploy tokens")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"tokens":  tokens,
+	})
+}
+
+// CreateToken handles POST /api/v1/instances/:id/deploy-tokens. The token
+// value is only returned in this response.
+func (h *DeployHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Support staff acting as the user mustn't mint credentials that outlive
+	// the impersonation
+	if claims, ok := middleware.GetUserClaims(r); ok && claims.Impersonated() {
+		respondWithError(w, http.StatusForbidden, "Not allowed while impersonating")
+		return
+	}
+
+	var req models.CreateDeployTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	token, value, err := h.deployService.CreateToken(r.Context(), instanceID, userID, req)
+	if err != nil {
+		respondWithDeployTokenError(w, err, "Failed to create deploy token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Deploy token created. Store it now, it won't be shown again",
+		"token":   token,
+		"value":   value,
+	})
+}
+
+// RevokeToken handles DELETE /api/v1/instances/:id/deploy-tokens/:tokenId
+func (h *DeployHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deployService.RevokeToken(r.Context(), instanceID, userID, mux.Vars(r)["tokenId"]); err != nil {
+		respondWithDeployTokenError(w, err, "Failed to revoke deploy token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Deploy token revoked",
+	})
+}
+
+// DeployHooks handles PUT /api/v1/deploy/hooks (zip body, like PUT /instances/:id/hooks)
+func (h *DeployHandler) DeployHooks(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	bundle, err := io.ReadAll(io.LimitReader(r.Body, h.config.HooksMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.deployService.DeployHooks(r.Context(), token, bundle)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to deploy hooks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Hooks deployed",
+		"hooks":   result,
+	})
+}
+
+// DeployPublicFiles handles PUT /api/v1/deploy/public (zip body, like PUT /instances/:id/public)
+func (h *DeployHandler) DeployPublicFiles(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	bundle, err := io.ReadAll(io.LimitReader(r.Body, h.config.PublicMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.deployService.DeployPublicFiles(r.Context(), token, bundle)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to deploy public files")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Public files deployed",
+		"public":  result,
+	})
+}
+
+// DeployMigrations handles PUT /api/v1/deploy/migrations (zip body, like PUT /instances/:id/migrations)
+func (h *DeployHandler) DeployMigrations(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	bundle, err := io.ReadAll(io.LimitReader(r.Body, h.config.MigrationsMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.deployService.DeployMigrations(r.Context(), token, bundle)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to deploy migrations")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"message":    "Migrations deployed",
+		"migrations": result,
+	})
+}
+
+// Migrate handles POST /api/v1/deploy/migrate (runs pocketbase migrate up)
+func (h *DeployHandler) Migrate(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	run, err := h.deployService.Migrate(r.Context(), token)
+	respondWithMigrationRun(w, run, err)
+}
+
+// Restart handles POST /api/v1/deploy/restart
+func (h *DeployHandler) Restart(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	if err := h.deployService.Restart(r.Context(), token); err != nil {
+		switch {
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case err.Error() == "instance is pending deletion" || err.Error() == "instance is suspended":
+			respondWithError(w, http.StatusConflict, err.Error())
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to restart instance")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Instance restarted",
+	})
+}
+
+// DeployPreviewRequest is the optional body of PUT /api/v1/deploy/previews/:ref
+type DeployPreviewRequest struct {
+	TTL string `json:"ttl,omitempty"`
+}
+
+// DeployPreview handles PUT /api/v1/deploy/previews/:ref, e.g. when a pull
+// request is opened or pushed to. The response includes a deploy token for
+// the preview, with the calling token's other scopes.
+func (h *DeployHandler) DeployPreview(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	var req DeployPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ttl, err := parseTTL(req.TTL)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deployment, err := h.deployService.DeployPreview(r.Context(), token, mux.Vars(r)["ref"], ttl)
+	if err != nil {
+		respondWithPreviewError(w, err, "Failed to deploy preview")
+		return
+	}
+
+	respondWithPreview(w, deployment.PreviewResult, deployment.Token, deployment.TokenValue)
+}
+
+// ClosePreview handles DELETE /api/v1/deploy/previews/:ref, e.g. when a pull
+// request is closed
+func (h *DeployHandler) ClosePreview(w http.ResponseWriter, r *http.Request) {
+	token, ok := middleware.GetDeployToken(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+		return
+	}
+
+	archived, err := h.deployService.ClosePreview(r.Context(), token, mux.Vars(r)["ref"])
+	if err != nil {
+		respondWithPreviewError(w, err, "Failed to delete preview")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Preview deleted",
+		"archived": archived,
+	})
+}
+
+// respondWithDeployTokenError maps deploy token management errors to responses
+func respondWithDeployTokenError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, models.ErrDeployTokenNotFound):
+		respondWithError(w, http.StatusNotFound, "Deploy token not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case errors.Is(err, services.ErrDeployTokenLimit):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+)
+
+// DNSHandler handles the DNS status endpoints
+type DNSHandler struct {
+	dnsService      *services.DNSService
+	instanceService *services.InstanceService
+}
+
+// NewDNSHandler creates a new DNS handler
+func NewDNSHandler(dnsService *services.DNSService, instanceService *services.InstanceService) *DNSHandler {
+	return &DNSHandler{dnsService: dnsService, instanceService: instanceService}
+}
+
+// GetInstanceDNS handles GET /api/v1/instances/:id/dns (whether the
+// instance's subdomain resolves to this server yet)
+func (h *DNSHandler) GetInstanceDNS(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	instance, err := h.instanceService.AuthorizeInstance(r.Context(), instanceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to get instance")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"dns":     h.dnsService.InstancePropagation(r.Context(), instance),
+	})
+}
+
+// ListDomains handles GET /api/v1/admin/dns (the wildcard record of the base
+// domain and each region domain, at the provider and in public DNS)
+func (h *DNSHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.dnsService.DomainStatuses(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check DNS")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"domains": domains,
+	})
+}
+
+// SyncDomains handles POST /api/v1/admin/dns/sync (creates missing wildcard records)
+func (h *DNSHandler) SyncDomains(w http.ResponseWriter, r *http.Request) {
+	created, err := h.dnsService.EnsureWildcards(r.Context())
+	if err != nil {
+		if err.Error() == "no DNS provider is configured" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusBadGateway, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+			"created": created,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"created": created,
+	})
+}
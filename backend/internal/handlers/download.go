@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DownloadHandler hands backups and exports to the browser through signed
+// URLs instead of authenticated API requests
+type DownloadHandler struct {
+	signer          *services.DownloadSigner
+	instanceService *services.InstanceService
+	userService     *services.UserService
+	exports         map[string]http.HandlerFunc
+}
+
+// DownloadLinkRequest is the optional body of the download-url endpoints
+type DownloadLinkRequest struct {
+	SingleUse bool `json:"single_use"`
+}
+
+// NewDownloadHandler creates a new download handler
+func NewDownloadHandler(signer *services.DownloadSigner, instanceService *services.InstanceService, userService *services.UserService, exportHandler *ExportHandler) *DownloadHandler {
+	return &DownloadHandler{
+		signer:          signer,
+		instanceService: instanceService,
+		userService:     userService,
+		exports: map[string]http.HandlerFunc{
+			"audit-log":        exportHandler.ExportAuditLog,
+			"metering":         exportHandler.ExportMetering,
+			"instance-history": exportHandler.ExportInstanceHistory,
+		},
+	}
+}
+
+// CreateBackupLink handles POST /api/v1/instances/:id/backups/:backupId/download-url
+func (h *DownloadHandler) CreateBackupLink(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+	req, ok := parseDownloadLinkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	backup, err := h.instanceService.FindBackup(r.Context(), instanceID, userID, mux.Vars(r)["backupId"])
+	if err != nil {
+		respondWithDownloadError(w, err, "Failed to create download link")
+		return
+	}
+
+	h.respondWithLink(w, services.SignedDownload{
+		Kind:       services.DownloadBackup,
+		UserID:     userID,
+		InstanceID: instanceID.String(),
+		Name:       backup.ID,
+	}, req.SingleUse)
+}
+
+// CreateExportLink handles POST /api/v1/admin/exports/:export/download-url,
+// with the export's filters as query parameters
+func (h *DownloadHandler) CreateExportLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+	req, ok := parseDownloadLinkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["export"]
+	if _, ok := h.exports[name]; !ok {
+		respondWithError(w, http.StatusNotFound, "Export not found")
+		return
+	}
+
+	h.respondWithLink(w, services.SignedDownload{
+		Kind:   services.DownloadExport,
+		UserID: uid,
+		Name:   name,
+		Query:  r.URL.RawQuery,
+	}, req.SingleUse)
+}
+
+// Download handles GET /api/v1/downloads/:token. The link stands in for the
+// access token, but the requester's access is still checked, so links stop
+// working when it is revoked.
+func (h *DownloadHandler) Download(w http.ResponseWriter, r *http.Request) {
+	// Keep the link out of Referer headers of anything the download opens
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+
+	download, err := h.signer.Verify(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		respondWithDownloadError(w, err, "Failed to check download link")
+		return
+	}
+
+	switch download.Kind {
+	case services.DownloadBackup:
+		instanceID, err := uuid.Parse(download.InstanceID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+			return
+		}
+
+		backup, file, err := h.instanceService.OpenBackup(r.Context(), instanceID, download.UserID, download.Name)
+		if err != nil {
+			respondWithDownloadError(w, err, "Failed to download backup")
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+backup.ID+`"`)
+		http.ServeContent(w, r, backup.ID, backup.CreatedAt, file)
+
+	case services.DownloadExport:
+		export, ok := h.exports[download.Name]
+		if !ok {
+			respondWithError(w, http.StatusNotFound, "Export not found")
+			return
+		}
+		if isAdmin, err := h.userService.IsAdmin(download.UserID.String()); err != nil || !isAdmin {
+			respondWithError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		exportRequest := r.Clone(r.Context())
+		exportRequest.URL.RawQuery = download.Query
+		export(w, exportRequest)
+
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid download link")
+	}
+}
+
+// respondWithLink signs a download and returns its link
+func (h *DownloadHandler) respondWithLink(w http.ResponseWriter, download services.SignedDownload, singleUse bool) {
+	link, err := h.signer.Sign(download, singleUse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create download link")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":  true,
+		"download": link,
+	})
+}
+
+// parseDownloadLinkRequest reads the optional body of the download-url endpoints
+func parseDownloadLinkRequest(w http.ResponseWriter, r *http.Request) (DownloadLinkRequest, bool) {
+	var req DownloadLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+	return req, true
+}
+
+// respondWithDownloadError maps download link and backup errors to responses
+func respondWithDownloadError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrDownloadLinkInvalid):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrDownloadLinkExpired):
+		respondWithError(w, http.StatusGone, err.Error())
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, services.ErrBackupNotFound):
+		respondWithError(w, http.StatusNotFound, "Backup not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
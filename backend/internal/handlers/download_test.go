@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// fakeInstanceStore serves a single instance. Methods it doesn't override
+// panic through the nil embedded interface.
+type fakeInstanceStore struct {
+	services.InstanceStore
+	instance *models.Instance
+}
+
+func (f *fakeInstanceStore) FindInstanceByID(ctx context.Context, id uuid.UUID) (*models.Instance, error) {
+	if id != f.instance.ID {
+		return nil, fmt.Errorf("instance not found")
+	}
+	copied := *f.instance
+	return &copied, nil
+}
+
+func TestDownloadBackup(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, backups, outside string)
+		wantCode int
+	}{
+		{
+			name: "backup",
+			setup: func(t *testing.T, backups, outside string) {
+				writeFile(t, filepath.Join(backups, "pb_backup.zip"), "backup")
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "symlinked backup",
+			setup: func(t *testing.T, backups, outside string) {
+				if err := os.Symlink(filepath.Join(outside, "pb_backup.zip"), filepath.Join(backups, "pb_backup.zip")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "symlinked backups directory",
+			setup: func(t *testing.T, backups, outside string) {
+				if err := os.Remove(backups); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(outside, backups); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			dataPath, outside := filepath.Join(base, "instance"), filepath.Join(base, "other")
+			backups := filepath.Join(dataPath, "backups")
+			writeFile(t, filepath.Join(outside, "pb_backup.zip"), "another instance's data")
+			if err := os.MkdirAll(backups, 0755); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(t, backups, outside)
+
+			userID := uuid.New()
+			instance := &models.Instance{ID: uuid.New(), UserID: userID, Name: "test", DataPath: dataPath}
+			cfg := &config.Config{JWTAccessSecret: "test-secret", DownloadURLTTL: time.Minute}
+			instanceService := services.NewInstanceService(&fakeInstanceStore{instance: instance}, nil, nil, nil, nil,
+				authz.NewEvaluator(authz.DefaultPolicy), nil, nil, nil, nil, nil, nil, cfg)
+			signer := services.NewDownloadSigner(nil, cfg)
+			handler := NewDownloadHandler(signer, instanceService, nil, nil)
+
+			link, err := signer.Sign(services.SignedDownload{
+				Kind:       services.DownloadBackup,
+				UserID:     userID,
+				InstanceID: instance.ID.String(),
+				Name:       "pb_backup.zip",
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, link.URL, nil)
+			r = mux.SetURLVars(r, map[string]string{"token": strings.TrimPrefix(link.URL, "/api/v1/downloads/")})
+			w := httptest.NewRecorder()
+			handler.Download(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "another instance") {
+				t.Error("the response contains the symlink target")
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultExportPeriod is exported when no from parameter is given
+	defaultExportPeriod = 30 * 24 * time.Hour
+
+	// csvFlushRows is how many rows are buffered before they are sent
+	csvFlushRows = 500
+)
+
+// ExportHandler streams CSV exports for operators (admin only)
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// ExportAuditLog handles GET /api/v1/admin/exports/audit-log?from=&to=&actor_id=&user_id=&action=
+func (h *ExportHandler) ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, ok := parseTimeRange(w, query, defaultExportPeriod)
+	if !ok {
+		return
+	}
+
+	filter := models.AuditFilter{
+		ActorID: query.Get("actor_id"),
+		UserID:  query.Get("user_id"),
+		Action:  query.Get("action"),
+	}
+
+	out := newCSVExport(w, "audit-log", from, to, []string{"created_at", "id", "action", "actor_id", "user_id", "ip_address", "country", "city", "details"})
+	err := h.exportService.ExportAuditLog(r.Context(), filter, from, to, func(entry *models.AuditEntry) error {
+		details, err := json.Marshal(entry.Details)
+		if err != nil {
+			return err
+		}
+		return out.write([]string{
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			entry.ID,
+			entry.Action,
+			stringValue(entry.ActorID),
+			stringValue(entry.UserID),
+			entry.IPAddress,
+			entry.Country,
+			entry.City,
+			string(details),
+		})
+	})
+	out.finish(err, "Failed to export audit log")
+}
+
+// ExportMetering handles GET /api/v1/admin/exports/metering?from=&to=&user_id=
+func (h *ExportHandler) ExportMetering(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, ok := parseTimeRange(w, query, defaultExportPeriod)
+	if !ok {
+		return
+	}
+
+	userID, ok := parseOptionalUUID(w, query.Get("user_id"), "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	out := newCSVExport(w, "metering", from, to, meteringCSVHeader)
+	err := h.exportService.ExportMetering(r.Context(), from, to, userID, func(record *models.MeteringRecord) error {
+		return out.write(meteringCSVRow(record))
+	})
+	out.finish(err, "Failed to export metering records")
+}
+
+// ExportInstanceHistory handles GET /api/v1/admin/exports/instance-history?from=&to=&user_id=&instance_id=
+// (creations, failures, suspensions, quarantines, migration and cron runs, deletions)
+func (h *ExportHandler) ExportInstanceHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, ok := parseTimeRange(w, query, defaultExportPeriod)
+	if !ok {
+		return
+	}
+
+	userID, ok := parseOptionalUUID(w, query.Get("user_id"), "Invalid user ID")
+	if !ok {
+		return
+	}
+	instanceID, ok := parseOptionalUUID(w, query.Get("instance_id"), "Invalid instance ID")
+	if !ok {
+		return
+	}
+
+	filter := models.InstanceHistoryFilter{From: from, To: to, UserID: userID, InstanceID: instanceID}
+
+	out := newCSVExport(w, "instance-history", from, to, []string{"occurred_at", "instance_id", "instance_name", "user_id", "event", "detail"})
+	err := h.exportService.ExportInstanceHistory(r.Context(), filter, func(event *models.InstanceHistoryEvent) error {
+		return out.write([]string{
+			event.OccurredAt.UTC().Format(time.RFC3339),
+			event.InstanceID.String(),
+			event.InstanceName,
+			event.UserID.String(),
+			event.Event,
+			event.Detail,
+		})
+	})
+	out.finish(err, "Failed to export instance history")
+}
+
+// csvExport writes a CSV download as rows arrive. The response starts with
+// the first row, so an export that fails before producing any still gets an
+// error response; after that a failure can only cut the file short.
+type csvExport struct {
+	w        http.ResponseWriter
+	csv      *csv.Writer
+	filename string
+	header   []string
+	rows     int
+}
+
+// newCSVExport prepares a CSV download named after the export and its period
+func newCSVExport(w http.ResponseWriter, name string, from, to time.Time, header []string) *csvExport {
+	filename := fmt.Sprintf("%s-%s-%s.csv", name, from.UTC().Format("20060102T15"), to.UTC().Format("20060102T15"))
+	return &csvExport{w: w, csv: csv.NewWriter(w), filename: filename, header: header}
+}
+
+// write adds a row, sending the buffered rows every csvFlushRows
+func (e *csvExport) write(record []string) error {
+	if e.rows == 0 {
+		e.start()
+	}
+
+	e.csv.Write(record)
+	e.rows++
+	if e.rows%csvFlushRows == 0 {
+		e.flush()
+	}
+	return e.csv.Error()
+}
+
+// finish completes the download, or responds with an error if nothing was sent yet
+func (e *csvExport) finish(err error, message string) {
+	if err != nil {
+		if e.rows == 0 {
+			respondWithError(e.w, http.StatusInternalServerError, message)
+			return
+		}
+		log.Printf("Warning: %s %s after %d rows: %v", message, e.filename, e.rows, err)
+	}
+
+	if e.rows == 0 {
+		e.start()
+	}
+	e.flush()
+}
+
+// start writes the response headers and the CSV header row
+func (e *csvExport) start() {
+	// Large exports can outlast the server's write timeout
+	_ = http.NewResponseController(e.w).SetWriteDeadline(time.Time{})
+
+	e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, e.filename))
+	e.w.WriteHeader(http.StatusOK)
+	e.csv.Write(e.header)
+}
+
+// flush sends the buffered rows to the client
+func (e *csvExport) flush() {
+	e.csv.Flush()
+	_ = http.NewResponseController(e.w).Flush()
+}
+
+// parseTimeRange reads the from and to parameters (RFC3339, Unix seconds or
+// a duration ago such as "24h"). to defaults to now and from to period before
+// to. It responds with an error and returns false when they are invalid.
+func parseTimeRange(w http.ResponseWriter, query url.Values, period time.Duration) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if value, err := parseLogTime(query.Get("to")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid to parameter")
+		return time.Time{}, time.Time{}, false
+	} else if value != nil {
+		to = *value
+	}
+
+	from := to.Add(-period)
+	if value, err := parseLogTime(query.Get("from")); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid from parameter")
+		return time.Time{}, time.Time{}, false
+	} else if value != nil {
+		from = *value
+	}
+
+	if !to.After(from) {
+		respondWithError(w, http.StatusBadRequest, "to must be after from")
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
+
+// parseOptionalUUID parses an optional ID parameter, responding with message
+// and returning false when it is invalid
+func parseOptionalUUID(w http.ResponseWriter, value, message string) (*uuid.UUID, bool) {
+	if value == "" {
+		return nil, true
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, message)
+		return nil, false
+	}
+	return &id, true
+}
+
+// stringValue returns the value of an optional string, "" if nil
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// meteringCSVHeader and meteringCSVRow lay out metering records in CSV exports
+var meteringCSVHeader = []string{"hour", "user_id", "username", "instance_hours", "storage_gb_hours", "egress_gb"}
+
+func meteringCSVRow(record *models.MeteringRecord) []string {
+	return []string{
+		record.Hour.UTC().Format(time.RFC3339),
+		record.UserID.String(),
+		record.Username,
+		strconv.FormatFloat(record.InstanceHours, 'f', 4, 64),
+		strconv.FormatFloat(record.StorageGBHours, 'f', 4, 64),
+		strconv.FormatFloat(record.EgressGB, 'f', 4, 64),
+	}
+}
@@ -6,19 +6,23 @@ import (
 	"time"
 
 	"pocketploy/internal/database"
+	"pocketploy/internal/services"
 )
 
 // HealthHandler handles health check endpoints
 type HealthHandler struct {
-	db *database.DB
+	db            *database.DB
+	readiness     *services.ReadinessChecker
+	poolThreshold float64
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(db *database.DB) *HealthHandler {
-	return &HealthHandler{db: db}
+// NewHealthHandler creates a new health handler. The database is reported
+// unhealthy once poolThreshold of its connection pool is in use (0 disables).
+func NewHealthHandler(db *database.DB, readiness *services.ReadinessChecker, poolThreshold float64) *HealthHandler {
+	return &HealthHandler{db: db, readiness: readiness, poolThreshold: poolThreshold}
 }
 
-// Health returns the API health status
+// Health reports that the process is up (liveness, also served at /health/live)
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"status":    "ok",
@@ -26,7 +30,24 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HealthDB checks database connection health
+// Ready reports whether the process can serve traffic: the database and
+// Docker daemon are reachable and all migrations are applied
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	readiness := h.readiness.Check(r.Context())
+
+	status, code := "ok", http.StatusOK
+	if !readiness.Ready {
+		status, code = "error", http.StatusServiceUnavailable
+	}
+
+	respondWithJSON(w, code, map[string]interface{}{
+		"status":    status,
+		"checks":    readiness.Checks,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// HealthDB checks database connection health and connection pool saturation
 func (h *HealthHandler) HealthDB(w http.ResponseWriter, r *http.Request) {
 	if err := h.db.Ping(); err != nil {
 		respondWithJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
@@ -38,9 +59,30 @@ func (h *HealthHandler) HealthDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	stats := h.db.Stats()
+	pool := map[string]interface{}{
+		"max_open":      stats.MaxOpenConnections,
+		"open":          stats.OpenConnections,
+		"in_use":        stats.InUse,
+		"idle":          stats.Idle,
+		"wait_count":    stats.WaitCount,
+		"wait_duration": stats.WaitDuration.String(),
+	}
+
+	if usage := h.db.PoolUsage(); h.poolThreshold > 0 && usage >= h.poolThreshold {
+		respondWithJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status":    "error",
+			"message":   "Database connection pool is saturated",
+			"pool":      pool,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"status":    "ok",
 		"message":   "Database connection successful",
+		"pool":      pool,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	})
 }
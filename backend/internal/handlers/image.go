@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ImageHandler handles the approved image endpoints
+type ImageHandler struct {
+	imageService *services.ImageService
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(imageService *services.ImageService) *ImageHandler {
+	return &ImageHandler{imageService: imageService}
+}
+
+// ListImages handles GET /api/v1/images (the images the user can create instances from)
+func (h *ImageHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	images, err := h.imageService.ListImages(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list images")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"images":  images,
+	})
+}
+
+// ListAllImages handles GET /api/v1/admin/images
+func (h *ImageHandler) ListAllImages(w http.ResponseWriter, r *http.Request) {
+	images, err := h.imageService.ListAllImages(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list images")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"images":  images,
+	})
+}
+
+// CreateImage handles POST /api/v1/admin/images. The image is pulled and
+// validated, so the request takes as long as the pull.
+func (h *ImageHandler) CreateImage(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateInstanceImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	image, err := h.imageService.CreateImage(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrImageExists):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrInvalidImage):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to create image")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Image approved",
+		"image":   image,
+	})
+}
+
+// UpdateImage handles PATCH /api/v1/admin/images/:id
+func (h *ImageHandler) UpdateImage(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateInstanceImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	image, err := h.imageService.UpdateImage(r.Context(), mux.Vars(r)["id"], req)
+	if err != nil {
+		respondWithImageError(w, err, "Failed to update image")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Image updated",
+		"image":   image,
+	})
+}
+
+// ListGrants handles GET /api/v1/admin/images/:id/users
+func (h *ImageHandler) ListGrants(w http.ResponseWriter, r *http.Request) {
+	grants, err := h.imageService.ListGrants(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithImageError(w, err, "Failed to list image users")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"users":   grants,
+	})
+}
+
+// GrantImage handles PUT /api/v1/admin/images/:id/users/:userId
+func (h *ImageHandler) GrantImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["userId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.imageService.GrantImage(r.Context(), vars["id"], userID); err != nil {
+		respondWithImageError(w, err, "Failed to grant image")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Image granted",
+	})
+}
+
+// RevokeImage handles DELETE /api/v1/admin/images/:id/users/:userId. The
+// user's existing instances keep running the image.
+func (h *ImageHandler) RevokeImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["userId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.imageService.RevokeImage(r.Context(), vars["id"], userID); err != nil {
+		respondWithImageError(w, err, "Failed to revoke image")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Image revoked",
+	})
+}
+
+func respondWithImageError(w http.ResponseWriter, err error, fallback string) {
+	switch err.Error() {
+	case "image not found", "user not found":
+		respondWithError(w, http.StatusNotFound, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
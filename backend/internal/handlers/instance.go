@@ -1,26 +1,105 @@
 package handlers
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
 	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
 	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
+// InstanceManager is the instance business logic the handler depends on
+// (implemented by *services.InstanceService)
+type InstanceManager interface {
+	CreateInstance(ctx context.Context, req services.CreateInstanceRequest) (*services.CreateInstanceResponse, error)
+	ValidateInstance(ctx context.Context, req services.CreateInstanceRequest) (*services.InstanceValidation, error)
+	GetInstance(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error)
+	InstanceCertificate(instance *models.Instance) services.CertificateStatus
+	ListUserInstances(ctx context.Context, userID uuid.UUID) ([]models.Instance, error)
+	SearchUserInstances(ctx context.Context, userID uuid.UUID, term string) ([]models.Instance, error)
+	DeleteInstance(ctx context.Context, instanceID, userID uuid.UUID, opts services.DeleteInstanceOptions) (*services.DeleteInstanceResult, error)
+	IssueDeletionConfirmation(ctx context.Context, instanceID, userID uuid.UUID) (*services.DeletionConfirmation, error)
+	CancelDeletion(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error)
+
+	ListArchivedInstances(ctx context.Context, userID uuid.UUID) ([]models.ArchivedInstance, error)
+	GetArchivedInstance(ctx context.Context, archivedID, userID uuid.UUID) (*models.ArchivedInstance, error)
+	PurgeArchivedInstance(ctx context.Context, archivedID, userID uuid.UUID) error
+
+	StartInstance(ctx context.Context, instanceID, userID uuid.UUID) error
+	StopInstance(ctx context.Context, instanceID, userID uuid.UUID) error
+	RestartInstance(ctx context.Context, instanceID, userID uuid.UUID) error
+	RotateAdminCredentials(ctx context.Context, instanceID, userID uuid.UUID, adminEmail string) (string, error)
+	RunInstanceCommand(ctx context.Context, instanceID, userID uuid.UUID, args []string) (string, error)
+	UpdateServeOptions(ctx context.Context, instanceID, userID uuid.UUID, options models.ServeOptions) (*models.Instance, error)
+	UpdateAccessProtection(ctx context.Context, instanceID, userID uuid.UUID, req services.UpdateAccessRequest) (*models.Instance, error)
+	UpdateEgressPolicy(ctx context.Context, instanceID, userID uuid.UUID, req services.UpdateEgressRequest) (*models.Instance, error)
+	SetInstanceTags(ctx context.Context, instanceID, userID uuid.UUID, tags models.Tags) (*models.Instance, error)
+	BulkOperation(ctx context.Context, userID uuid.UUID, action string, selectors []models.TagSelector) ([]services.BulkResult, error)
+	UpdateInstance(ctx context.Context, instanceID, userID uuid.UUID, params services.UpdateInstanceParams) (*models.Instance, error)
+	SetInstancePinned(ctx context.Context, instanceID, userID uuid.UUID, pinned bool) (*models.Instance, error)
+	ReorderInstances(ctx context.Context, userID uuid.UUID, instanceIDs []uuid.UUID) ([]models.Instance, error)
+	GetHooks(ctx context.Context, instanceID, userID uuid.UUID) (*services.Bundle, error)
+	UploadHooks(ctx context.Context, instanceID, userID uuid.UUID, bundle []byte) (*services.Bundle, error)
+	DeleteHooks(ctx context.Context, instanceID, userID uuid.UUID) error
+	GetPublicFiles(ctx context.Context, instanceID, userID uuid.UUID) (*services.Bundle, error)
+	UploadPublicFiles(ctx context.Context, instanceID, userID uuid.UUID, bundle []byte) (*services.Bundle, error)
+	DeletePublicFiles(ctx context.Context, instanceID, userID uuid.UUID) error
+	GetMigrations(ctx context.Context, instanceID, userID uuid.UUID) (*services.Bundle, error)
+	UploadMigrations(ctx context.Context, instanceID, userID uuid.UUID, bundle []byte) (*services.Bundle, error)
+	RunMigrations(ctx context.Context, instanceID, userID uuid.UUID) (*models.InstanceMigrationRun, error)
+	ListMigrationRuns(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceMigrationRun, error)
+	UploadBuild(ctx context.Context, instanceID, userID uuid.UUID, source []byte) (*models.InstanceBuild, error)
+	ListBuilds(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceBuild, error)
+	GetBuild(ctx context.Context, instanceID, buildID, userID uuid.UUID) (*models.InstanceBuild, error)
+	CheckIntegrity(ctx context.Context, instanceID, userID uuid.UUID) (*models.InstanceIntegrityCheck, error)
+	ListIntegrityChecks(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceIntegrityCheck, error)
+	ListBackups(ctx context.Context, instanceID, userID uuid.UUID) ([]services.InstanceBackup, error)
+	RestoreBackup(ctx context.Context, instanceID, userID uuid.UUID, backupID string) (*services.RestoreResult, error)
+	CopyCollections(ctx context.Context, sourceID, targetID, userID uuid.UUID, collections []string) (*services.DataCopyResult, error)
+	ExtendInstance(ctx context.Context, instanceID, userID uuid.UUID, ttl time.Duration) (*models.Instance, error)
+	CreatePreview(ctx context.Context, sourceID, userID uuid.UUID, req services.CreatePreviewRequest) (*services.PreviewResult, error)
+	ListPreviews(ctx context.Context, sourceID, userID uuid.UUID) ([]models.Instance, error)
+	DeletePreview(ctx context.Context, sourceID, userID uuid.UUID, ref string) (*models.ArchivedInstance, error)
+	RotateStatusToken(ctx context.Context, instanceID, userID uuid.UUID) (string, *models.Instance, error)
+	RevokeStatusToken(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error)
+	GetPublicStatus(ctx context.Context, token string) (*services.PublicInstanceStatus, error)
+
+	GetInstanceLogs(ctx context.Context, instanceID, userID uuid.UUID, opts docker.LogOptions) (string, error)
+	GetInstanceLogEntries(ctx context.Context, instanceID, userID uuid.UUID, opts docker.LogOptions, minLevel string) ([]docker.LogEntry, error)
+	StreamInstanceLogs(ctx context.Context, instanceID, userID uuid.UUID, opts docker.LogOptions) (*models.Instance, io.ReadCloser, error)
+	GetInstanceStats(ctx context.Context, instanceID, userID uuid.UUID) (*docker.ContainerStats, error)
+	GetInstanceMetrics(ctx context.Context, instanceID, userID uuid.UUID, timeRange time.Duration) ([]models.InstanceMetric, error)
+	GetInstanceAnalytics(ctx context.Context, instanceID, userID uuid.UUID, days int) ([]models.InstanceTrafficDay, error)
+}
+
 // InstanceHandler handles PocketBase instance endpoints
 type InstanceHandler struct {
-	instanceService *services.InstanceService
+	instanceService InstanceManager
+	config          *config.Config
 }
 
 // NewInstanceHandler creates a new instance handler
-func NewInstanceHandler(instanceService *services.InstanceService) *InstanceHandler {
+func NewInstanceHandler(instanceService InstanceManager, cfg *config.Config) *InstanceHandler {
 	return &InstanceHandler{
 		instanceService: instanceService,
+		config:          cfg,
 	}
 }
 
@@ -29,6 +108,26 @@ type CreateInstanceRequest struct {
 	Name          string `json:"name" validate:"required,min=3,max=100"`
 	AdminEmail    string `json:"admin_email" validate:"required,email"`
 	AdminPassword string `json:"admin_password" validate:"required,min=10"`
+	Region        string `json:"region,omitempty"` // region ID, the default region if empty
+	Image         string `json:"image,omitempty"`  // approved image ID, POCKETBASE_IMAGE if empty
+	TTL           string `json:"ttl,omitempty"`    // e.g. 48h or 7d, archives the instance once it runs out
+}
+
+// ValidateInstanceRequest represents the request to check an instance before creating it
+type ValidateInstanceRequest struct {
+	Name   string `json:"name"`
+	Region string `json:"region,omitempty"`
+	Image  string `json:"image,omitempty"`
+}
+
+// RotateAdminCredentialsRequest represents the request to reset an instance's admin credentials
+type RotateAdminCredentialsRequest struct {
+	AdminEmail string `json:"admin_email" validate:"required,email"`
+}
+
+// RunCommandRequest represents a pocketbase maintenance command, e.g. {"args": ["migrate", "up"]}
+type RunCommandRequest struct {
+	Args []string `json:"args" validate:"required,min=2,max=8"`
 }
 
 // CreateInstance handles POST /api/v1/instances
@@ -80,6 +179,12 @@ func (h *InstanceHandler) CreateInstance(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ttl, err := parseTTL(req.TTL)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Create instance
 	result, err := h.instanceService.CreateInstance(r.Context(), services.CreateInstanceRequest{
 		UserID:        userID,
@@ -87,6 +192,9 @@ func (h *InstanceHandler) CreateInstance(w http.ResponseWriter, r *http.Request)
 		Name:          req.Name,
 		AdminEmail:    req.AdminEmail,
 		AdminPassword: req.AdminPassword,
+		RegionID:      req.Region,
+		ImageID:       req.Image,
+		TTL:           ttl,
 	})
 
 	if err != nil {
@@ -94,14 +202,40 @@ func (h *InstanceHandler) CreateInstance(w http.ResponseWriter, r *http.Request)
 		fmt.Printf("Error creating instance: %v\n", err)
 
 		// Check for specific errors
-		if err.Error() == "maximum number of instances reached (5)" {
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		var limitErr *models.InstanceLimitError
+		if errors.As(err, &limitErr) {
 			respondWithError(w, http.StatusForbidden, err.Error())
 			return
 		}
-		if err.Error() == "instance with this name already exists" {
+		if errors.Is(err, models.ErrSubdomainTaken) {
+			respondWithError(w, http.StatusConflict, "Instance name is already taken, please try again")
+			return
+		}
+		if err.Error() == "failed to generate a unique slug" {
 			respondWithError(w, http.StatusConflict, err.Error())
 			return
 		}
+		if err.Error() == "instance name is reserved" || err.Error() == "region not found" || err.Error() == "region is not available" ||
+			err.Error() == "image not found" || err.Error() == "image is not available" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "ttl must be") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, models.ErrNoHostPort) {
+			respondWithError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInsufficientDiskSpace) {
+			respondWithError(w, http.StatusInsufficientStorage, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to create instance")
 		return
 	}
@@ -115,6 +249,45 @@ func (h *InstanceHandler) CreateInstance(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// ValidateInstance handles POST /api/v1/instances/validate, a dry run of
+// CreateInstance that reports the subdomain and any violations
+func (h *InstanceHandler) ValidateInstance(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	var req ValidateInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	validation, err := h.instanceService.ValidateInstance(r.Context(), services.CreateInstanceRequest{
+		UserID:   userID,
+		Username: claims.Username,
+		Name:     req.Name,
+		RegionID: req.Region,
+		ImageID:  req.Image,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to validate instance")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"validation": validation,
+	})
+}
+
 // ListInstances handles GET /api/v1/instances
 func (h *InstanceHandler) ListInstances(w http.ResponseWriter, r *http.Request) {
 	// Get user claims from context
@@ -131,8 +304,20 @@ func (h *InstanceHandler) ListInstances(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get user's instances
-	instances, err := h.instanceService.ListUserInstances(r.Context(), userID)
+	// Optional tag selectors (?tag=env=prod&tag=project), all of which must match
+	selectors, err := parseTagSelectors(r.URL.Query()["tag"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Get user's instances, optionally filtered by a search term
+	var instances []models.Instance
+	if q := r.URL.Query().Get("q"); q != "" {
+		instances, err = h.instanceService.SearchUserInstances(r.Context(), userID, q)
+	} else {
+		instances, err = h.instanceService.ListUserInstances(r.Context(), userID)
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to list instances")
 		return
@@ -141,7 +326,7 @@ func (h *InstanceHandler) ListInstances(w http.ResponseWriter, r *http.Request)
 	// Return instances
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success":   true,
-		"instances": instances,
+		"instances": services.FilterByTags(instances, selectors),
 	})
 }
 
@@ -176,13 +361,55 @@ func (h *InstanceHandler) GetInstance(w http.ResponseWriter, r *http.Request) {
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to get instance")
 		return
 	}
 
-	// Return instance
+	// Return instance with the state of its TLS certificate
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"instance":    instance,
+		"certificate": h.instanceService.InstanceCertificate(instance),
+	})
+}
+
+// UpdateInstance handles PATCH /api/v1/instances/:id
+func (h *InstanceHandler) UpdateInstance(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.UpdateInstanceParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	instance, err := h.instanceService.UpdateInstance(r.Context(), instanceID, userID, req)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "no fields to update" || strings.HasPrefix(err.Error(), "description must be"):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case err.Error() == "instance is pending deletion":
+			respondWithError(w, http.StatusConflict, "Instance is pending deletion; cancel the deletion instead of protecting it")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update instance")
+		}
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success":  true,
+		"message":  "Instance updated successfully",
 		"instance": instance,
 	})
 }
@@ -212,20 +439,79 @@ func (h *InstanceHandler) DeleteInstance(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Delete instance
-	err = h.instanceService.DeleteInstance(r.Context(), instanceID, userID)
+	// Optional retention override (?retention_days=0 deletes the data immediately)
+	var opts services.DeleteInstanceOptions
+	if value := r.URL.Query().Get("retention_days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid retention_days")
+			return
+		}
+		opts.RetentionDays = &days
+	}
+
+	// Optional confirmation and re-authentication (required by configuration)
+	var req DeleteInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	opts.Check = services.DeletionCheck{
+		ConfirmToken: req.ConfirmToken,
+		ConfirmName:  req.ConfirmName,
+		Password:     req.Password,
+	}
+
+	result, err := h.instanceService.DeleteInstance(r.Context(), instanceID, userID, opts)
 	if err != nil {
+		if err.Error() == "instance is already pending deletion" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "instance is protected from deletion" {
+			respondWithError(w, http.StatusConflict, "Instance is protected from deletion; disable protection first")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "retention days must be between") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err.Error() == "instance not found" {
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if respondWithDeletionCheckError(w, err) {
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete instance")
 		return
 	}
 
+	// Deletion is pending until the grace period ends
+	if result.Archived == nil {
+		respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"success":                true,
+			"message":                "Instance scheduled for deletion",
+			"instance":               result.Instance,
+			"deletion_scheduled_for": result.Instance.DeletionScheduledFor,
+		})
+		return
+	}
+
 	// Return success response
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Instance deleted successfully",
+		"success":             true,
+		"message":             "Instance deleted successfully",
+		"data_available":      result.Archived.DataAvailable,
+		"data_retained_until": result.Archived.DataRetainedUntil,
 	})
 }
 
@@ -253,28 +539,205 @@ func (h *InstanceHandler) GetInstanceLogs(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get tail parameter (default to 100 lines)
-	tail := r.URL.Query().Get("tail")
-	if tail == "" {
-		tail = "100"
+	// Get tail (default to 100 lines) and time range parameters
+	opts, err := parseLogOptions(r, "100")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Optional minimum level filter and output format (text or json)
+	level := strings.ToLower(r.URL.Query().Get("level"))
+	if level != "" && !docker.ValidLogLevel(level) {
+		respondWithError(w, http.StatusBadRequest, "level must be one of debug, info, warn, error")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "text" && format != "json" {
+		respondWithError(w, http.StatusBadRequest, "format must be text or json")
+		return
+	}
+
+	// Plain logs need no parsing
+	if level == "" && format != "json" {
+		logs, err := h.instanceService.GetInstanceLogs(r.Context(), instanceID, userID, opts)
+		if err != nil {
+			respondWithLogsError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"logs":    logs,
+		})
+		return
+	}
+
+	entries, err := h.instanceService.GetInstanceLogEntries(r.Context(), instanceID, userID, opts, level)
+	if err != nil {
+		respondWithLogsError(w, err)
+		return
+	}
+
+	if format == "json" {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"entries": entries,
+		})
+		return
+	}
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = entry.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"logs":    strings.Join(lines, "\n"),
+	})
+}
+
+func respondWithLogsError(w http.ResponseWriter, err error) {
+	if err.Error() == "instance not found" {
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+		return
+	}
+	if errors.Is(err, authz.ErrForbidden) {
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "Failed to retrieve logs")
+}
+
+// DownloadInstanceLogs streams an instance's logs as a file attachment
+func (h *InstanceHandler) DownloadInstanceLogs(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	// Downloads include all lines in the range unless tail is given
+	opts, err := parseLogOptions(r, "all")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// Get logs
-	logs, err := h.instanceService.GetInstanceLogs(r.Context(), instanceID, userID, tail)
+	compress := r.URL.Query().Get("gzip") == "true"
+
+	instance, logs, err := h.instanceService.StreamInstanceLogs(r.Context(), instanceID, userID, opts)
 	if err != nil {
 		if err.Error() == "instance not found" {
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve logs")
 		return
 	}
+	defer logs.Close()
 
-	// Return logs
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"logs":    logs,
-	})
+	// Large downloads can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("%s-logs-%s.log", instance.Slug, time.Now().UTC().Format("20060102-150405"))
+	if compress {
+		filename += ".gz"
+		w.Header().Set("Content-Type", "application/gzip")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	var out io.Writer = w
+	if compress {
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+
+	if _, err := io.Copy(out, logs); err != nil {
+		log.Printf("Warning: log download for instance %s interrupted: %v", instance.ID, err)
+	}
+}
+
+// parseLogOptions reads the tail, since and until query parameters.
+// Times may be RFC3339, Unix seconds, or a duration ago such as "15m".
+func parseLogOptions(r *http.Request, defaultTail string) (docker.LogOptions, error) {
+	query := r.URL.Query()
+
+	opts := docker.LogOptions{Tail: query.Get("tail")}
+	if opts.Tail == "" {
+		opts.Tail = defaultTail
+	}
+	if opts.Tail != "all" {
+		if n, err := strconv.Atoi(opts.Tail); err != nil || n < 0 {
+			return opts, fmt.Errorf("tail must be a non-negative number or \"all\"")
+		}
+	}
+
+	since, err := parseLogTime(query.Get("since"))
+	if err != nil {
+		return opts, fmt.Errorf("invalid since parameter")
+	}
+	until, err := parseLogTime(query.Get("until"))
+	if err != nil {
+		return opts, fmt.Errorf("invalid until parameter")
+	}
+	if since != nil && until != nil && !until.After(*since) {
+		return opts, fmt.Errorf("until must be after since")
+	}
+
+	if since != nil {
+		opts.Since = strconv.FormatInt(since.Unix(), 10)
+	}
+	if until != nil {
+		opts.Until = strconv.FormatInt(until.Unix(), 10)
+	}
+
+	return opts, nil
+}
+
+func parseLogTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t := time.Unix(seconds, 0)
+		return &t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		t := time.Now().Add(-d)
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("invalid time %q", value)
 }
 
 // GetInstanceStats retrieves statistics for a specific instance
@@ -308,6 +771,10 @@ func (h *InstanceHandler) GetInstanceStats(w http.ResponseWriter, r *http.Reques
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve stats")
 		return
 	}
@@ -319,8 +786,8 @@ func (h *InstanceHandler) GetInstanceStats(w http.ResponseWriter, r *http.Reques
 	})
 }
 
-// StartInstance starts a stopped instance
-func (h *InstanceHandler) StartInstance(w http.ResponseWriter, r *http.Request) {
+// GetInstanceMetrics handles GET /api/v1/instances/:id/metrics?range=24h
+func (h *InstanceHandler) GetInstanceMetrics(w http.ResponseWriter, r *http.Request) {
 	// Get user claims from context
 	claims, ok := middleware.GetUserClaims(r)
 	if !ok {
@@ -343,30 +810,43 @@ func (h *InstanceHandler) StartInstance(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Start instance
-	err = h.instanceService.StartInstance(r.Context(), instanceID, userID)
+	// Parse time range (default 24 hours)
+	timeRange := 24 * time.Hour
+	if value := r.URL.Query().Get("range"); value != "" {
+		timeRange, err = time.ParseDuration(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid range (e.g. 1h, 24h, 168h)")
+			return
+		}
+	}
+
+	metrics, err := h.instanceService.GetInstanceMetrics(r.Context(), instanceID, userID, timeRange)
 	if err != nil {
 		if err.Error() == "instance not found" {
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
-		if err.Error() == "instance is already running" {
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "range must be between") {
 			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to start instance")
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve metrics")
 		return
 	}
 
-	// Return success response
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Instance started successfully",
+		"range":   timeRange.String(),
+		"metrics": metrics,
 	})
 }
 
-// StopInstance stops a running instance
-func (h *InstanceHandler) StopInstance(w http.ResponseWriter, r *http.Request) {
+// GetInstanceAnalytics handles GET /api/v1/instances/:id/analytics?days=30
+func (h *InstanceHandler) GetInstanceAnalytics(w http.ResponseWriter, r *http.Request) {
 	// Get user claims from context
 	claims, ok := middleware.GetUserClaims(r)
 	if !ok {
@@ -389,30 +869,43 @@ func (h *InstanceHandler) StopInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Stop instance
-	err = h.instanceService.StopInstance(r.Context(), instanceID, userID)
+	// Parse number of days (default 30)
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid days")
+			return
+		}
+	}
+
+	analytics, err := h.instanceService.GetInstanceAnalytics(r.Context(), instanceID, userID, days)
 	if err != nil {
 		if err.Error() == "instance not found" {
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
-		if err.Error() == "instance is already stopped" {
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "days must be between") {
 			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to stop instance")
+		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve analytics")
 		return
 	}
 
-	// Return success response
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Instance stopped successfully",
+		"success":   true,
+		"days":      days,
+		"analytics": analytics,
 	})
 }
 
-// RestartInstance restarts an instance
-func (h *InstanceHandler) RestartInstance(w http.ResponseWriter, r *http.Request) {
+// StartInstance starts a stopped instance
+func (h *InstanceHandler) StartInstance(w http.ResponseWriter, r *http.Request) {
 	// Get user claims from context
 	claims, ok := middleware.GetUserClaims(r)
 	if !ok {
@@ -435,20 +928,524 @@ func (h *InstanceHandler) RestartInstance(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Restart instance
-	err = h.instanceService.RestartInstance(r.Context(), instanceID, userID)
+	// Start instance
+	err = h.instanceService.StartInstance(r.Context(), instanceID, userID)
 	if err != nil {
+		if err.Error() == "instance is pending deletion" || err.Error() == "instance is suspended" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
 		if err.Error() == "instance not found" {
 			respondWithError(w, http.StatusNotFound, "Instance not found")
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to restart instance")
-		return
-	}
-
-	// Return success response
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Instance restarted successfully",
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err.Error() == "instance is already running" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to start instance")
+		return
+	}
+
+	// Return success response
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Instance started successfully",
+	})
+}
+
+// StopInstance stops a running instance
+func (h *InstanceHandler) StopInstance(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	// Stop instance
+	err = h.instanceService.StopInstance(r.Context(), instanceID, userID)
+	if err != nil {
+		if err.Error() == "instance is pending deletion" || err.Error() == "instance is suspended" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err.Error() == "instance is already stopped" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to stop instance")
+		return
+	}
+
+	// Return success response
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Instance stopped successfully",
+	})
+}
+
+// RestartInstance restarts an instance
+func (h *InstanceHandler) RestartInstance(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	// Restart instance
+	err = h.instanceService.RestartInstance(r.Context(), instanceID, userID)
+	if err != nil {
+		if err.Error() == "instance is pending deletion" || err.Error() == "instance is suspended" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to restart instance")
+		return
+	}
+
+	// Return success response
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Instance restarted successfully",
+	})
+}
+
+// RotateAdminCredentials handles POST /api/v1/instances/:id/admin-credentials
+func (h *InstanceHandler) RotateAdminCredentials(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	// Parse request body
+	var req RotateAdminCredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "A valid admin email is required")
+		return
+	}
+
+	// Rotate credentials
+	password, err := h.instanceService.RotateAdminCredentials(r.Context(), instanceID, userID, req.AdminEmail)
+	if err != nil {
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "instance is not running" || err.Error() == "instance has no container" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		fmt.Printf("Error rotating admin credentials: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to rotate admin credentials")
+		return
+	}
+
+	// The password is only ever shown in this response
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":        true,
+		"message":        "Admin credentials rotated successfully",
+		"admin_email":    req.AdminEmail,
+		"admin_password": password,
+	})
+}
+
+// RunCommand handles POST /api/v1/instances/:id/commands
+func (h *InstanceHandler) RunCommand(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	// Parse request body
+	var req RunCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "A command and subcommand are required")
+		return
+	}
+
+	// Run command
+	output, err := h.instanceService.RunInstanceCommand(r.Context(), instanceID, userID, req.Args)
+	if err != nil {
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "command is not allowed" {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "instance is not running" || err.Error() == "instance has no container" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"success": false,
+			"error":   "Command failed",
+			"output":  output,
+		})
+		return
+	}
+
+	// Return command output
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"output":  output,
+	})
+}
+
+// UpdateServeOptions handles PUT /api/v1/instances/:id/serve-options
+func (h *InstanceHandler) UpdateServeOptions(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	// Parse request body
+	var req models.ServeOptions
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	instance, err := h.instanceService.UpdateServeOptions(r.Context(), instanceID, userID, req)
+	if err != nil {
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err.Error() == "directories must be relative paths inside the data directory" || err.Error() == "origins must be http(s)://host[:port] URLs" || err.Error() == "settings encryption cannot be disabled" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err.Error() == "instance has no container" || err.Error() == "instance is pending deletion" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to update serve options")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Serve options updated, the instance was recreated with them",
+		"instance": instance,
+	})
+}
+
+// ListArchivedInstances handles GET /api/v1/instances/archive
+func (h *InstanceHandler) ListArchivedInstances(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	archived, err := h.instanceService.ListArchivedInstances(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list archived instances")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"instances": archived,
+	})
+}
+
+// GetArchivedInstance handles GET /api/v1/instances/archive/:id
+func (h *InstanceHandler) GetArchivedInstance(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get archived instance ID from URL
+	vars := mux.Vars(r)
+	archivedID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	archived, err := h.instanceService.GetArchivedInstance(r.Context(), archivedID, userID)
+	if err != nil {
+		if err.Error() == "archived instance not found" {
+			respondWithError(w, http.StatusNotFound, "Archived instance not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get archived instance")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"instance": archived,
+	})
+}
+
+// PurgeArchivedInstance handles DELETE /api/v1/instances/archive/:id
+func (h *InstanceHandler) PurgeArchivedInstance(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get archived instance ID from URL
+	vars := mux.Vars(r)
+	archivedID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	err = h.instanceService.PurgeArchivedInstance(r.Context(), archivedID, userID)
+	if err != nil {
+		if err.Error() == "archived instance not found" {
+			respondWithError(w, http.StatusNotFound, "Archived instance not found")
+			return
+		}
+		if err.Error() == "archived instance data already purged" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		fmt.Printf("Error purging archived instance: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to purge archived instance")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Archived instance data purged",
+	})
+}
+
+// CancelDeletion handles POST /api/v1/instances/:id/cancel-deletion
+func (h *InstanceHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	// Get user claims from context
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse user ID
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	// Get instance ID from URL
+	vars := mux.Vars(r)
+	instanceID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid instance ID")
+		return
+	}
+
+	instance, err := h.instanceService.CancelDeletion(r.Context(), instanceID, userID)
+	if err != nil {
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+			return
+		}
+		if errors.Is(err, authz.ErrForbidden) {
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+			return
+		}
+		if err.Error() == "instance is not pending deletion" {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "too many concurrent operations" {
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel deletion")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Instance deletion cancelled",
+		"instance": instance,
 	})
 }
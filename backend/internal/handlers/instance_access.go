@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+)
+
+// UpdateAccessProtection handles PUT /api/v1/instances/:id/access
+func (h *InstanceHandler) UpdateAccessProtection(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.UpdateAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	instance, err := h.instanceService.UpdateAccessProtection(r.Context(), instanceID, userID, req)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		case err.Error() == "allowed IPs must be IP addresses or CIDR ranges" || err.Error() == "a password is required for basic auth":
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case err.Error() == "instance has no container" || err.Error() == "instance is pending deletion" || err.Error() == "access protection requires Traefik routing":
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update access protection")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Access protection updated",
+		"instance": instance,
+	})
+}
+
+// UpdateEgressPolicy handles PUT /api/v1/instances/:id/egress
+func (h *InstanceHandler) UpdateEgressPolicy(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req services.UpdateEgressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	instance, err := h.instanceService.UpdateEgressPolicy(r.Context(), instanceID, userID, req)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		case err.Error() == "the allowlist needs at least one host" || err.Error() == "hosts must be hostnames, IP addresses or CIDR ranges" ||
+			strings.HasPrefix(err.Error(), "failed to resolve "):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case err.Error() == "instance is pending deletion" || err.Error() == "egress policies are not enabled":
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update egress policy")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Egress policy updated",
+		"instance": instance,
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// UploadBuild handles POST /api/v1/instances/:id/builds. The body is a zip
+// archive of a Go module whose main package runs PocketBase; it is built in
+// the background and deployed once it compiles.
+func (h *InstanceHandler) UploadBuild(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Read one byte past the limit so oversized sources are reported as such
+	source, err := io.ReadAll(io.LimitReader(r.Body, h.config.BuildSourceMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	build, err := h.instanceService.UploadBuild(r.Context(), instanceID, userID, source)
+	if err != nil {
+		respondWithBuildError(w, err, "Failed to queue build")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"message": "Build queued, the instance switches to it once it compiles",
+		"build":   build,
+	})
+}
+
+// ListBuilds handles GET /api/v1/instances/:id/builds
+func (h *InstanceHandler) ListBuilds(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	builds, err := h.instanceService.ListBuilds(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithBuildError(w, err, "Failed to read builds")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"builds":  builds,
+	})
+}
+
+// GetBuild handles GET /api/v1/instances/:id/builds/:buildId, including the build log
+func (h *InstanceHandler) GetBuild(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	buildID, err := uuid.Parse(mux.Vars(r)["buildId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid build ID")
+		return
+	}
+
+	build, err := h.instanceService.GetBuild(r.Context(), instanceID, buildID, userID)
+	if err != nil {
+		respondWithBuildError(w, err, "Failed to read build")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"build":   build,
+	})
+}
+
+// respondWithBuildError maps errors of the build endpoints
+func respondWithBuildError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case err.Error() == "build not found":
+		respondWithError(w, http.StatusNotFound, "Build not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case errors.Is(err, services.ErrBuildsDisabled):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidBundle):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrBuildInProgress):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err.Error() == "instance has no container" || err.Error() == "instance is pending deletion" ||
+		err.Error() == "instance is quarantined":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// CopyDataRequest selects the instance to copy into and the collections to copy
+type CopyDataRequest struct {
+	TargetID    uuid.UUID `json:"target_id" validate:"required"`
+	Collections []string  `json:"collections" validate:"required,min=1,max=100,dive,required,max=100"`
+}
+
+// CopyData handles POST /api/v1/instances/:id/copy-data, which replaces the
+// selected collections (schema and records) in the target instance with the
+// ones of this instance
+func (h *InstanceHandler) CopyData(w http.ResponseWriter, r *http.Request) {
+	userID, sourceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req CopyDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	result, err := h.instanceService.CopyCollections(r.Context(), sourceID, req.TargetID, userID, req.Collections)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, services.ErrCollectionNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrNoDatabase):
+			respondWithError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrDataCopyFailed):
+			respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case err.Error() == "instance has no container" || err.Error() == "target instance must be running or stopped":
+			respondWithError(w, http.StatusConflict, err.Error())
+		case err.Error() == "source and target must be different instances" ||
+			strings.HasPrefix(err.Error(), "invalid collection name") ||
+			strings.HasPrefix(err.Error(), "collection ") ||
+			strings.HasPrefix(err.Error(), "between 1 and"):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to copy data")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Data copied, the target's previous data was saved as backup " + result.SafetyBackup.ID,
+		"copy":    result,
+	})
+}
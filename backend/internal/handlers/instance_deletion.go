@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+)
+
+// DeleteInstanceRequest is the optional body of DELETE /api/v1/instances/:id
+type DeleteInstanceRequest struct {
+	ConfirmToken string `json:"confirm_token"` // from GET /instances/:id/delete-confirmation
+	ConfirmName  string `json:"confirm_name"`  // the instance name, typed by the user
+	Password     string `json:"password"`      // when REAUTH_REQUIRED_FOR_DELETION is set
+}
+
+// GetDeletionConfirmation handles GET /api/v1/instances/:id/delete-confirmation
+// (issues the single-use token a DELETE of the instance hands back)
+func (h *InstanceHandler) GetDeletionConfirmation(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	confirmation, err := h.instanceService.IssueDeletionConfirmation(r.Context(), instanceID, userID)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to issue deletion confirmation")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":               true,
+		"confirmation":          confirmation,
+		"confirmation_required": h.config.DeleteConfirmationRequired,
+		"password_required":     h.config.ReauthRequiredForDeletion,
+	})
+}
+
+// respondWithDeletionCheckError responds to a failed deletion confirmation or
+// re-authentication, reporting whether err was one
+func respondWithDeletionCheckError(w http.ResponseWriter, err error) bool {
+	switch err.Error() {
+	case "deletion confirmation is required", "password is required":
+		respondWithError(w, http.StatusPreconditionRequired, err.Error())
+	case "deletion confirmation is invalid or expired", "confirmation name does not match the instance name":
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case "password is incorrect":
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	default:
+		return false
+	}
+	return true
+}
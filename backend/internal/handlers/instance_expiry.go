@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+)
+
+// ExtendInstanceRequest is the body of POST /api/v1/instances/:id/extend
+type ExtendInstanceRequest struct {
+	TTL string `json:"ttl"` // added to the current expiry, e.g. 24h or 7d
+}
+
+// ExtendInstance handles POST /api/v1/instances/:id/extend (pushes back the
+// expiry of an ephemeral instance)
+func (h *InstanceHandler) ExtendInstance(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req ExtendInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ttl, err := parseTTL(req.TTL)
+	if err != nil || ttl == 0 {
+		respondWithError(w, http.StatusBadRequest, "ttl must be a duration such as 48h or 7d")
+		return
+	}
+
+	instance, err := h.instanceService.ExtendInstance(r.Context(), instanceID, userID, ttl)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "instance does not expire" || err.Error() == "instance has expired":
+			respondWithError(w, http.StatusConflict, err.Error())
+		case strings.HasPrefix(err.Error(), "ttl must be") || strings.HasPrefix(err.Error(), "instance lifetime cannot exceed"):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to extend instance")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Instance expiry extended",
+		"instance": instance,
+	})
+}
+
+// parseTTL parses an instance lifetime, 0 if empty
+func parseTTL(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := config.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("ttl must be a duration such as 48h or 7d")
+	}
+	return ttl, nil
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+)
+
+// GetHooks handles GET /api/v1/instances/:id/hooks
+func (h *InstanceHandler) GetHooks(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.instanceService.GetHooks(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to read hooks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"hooks":   bundle,
+	})
+}
+
+// UploadHooks handles PUT /api/v1/instances/:id/hooks. The body is a zip
+// archive of .js and .json files (optionally inside a pb_hooks/ folder).
+func (h *InstanceHandler) UploadHooks(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Read one byte past the limit so oversized bundles are reported as such
+	bundle, err := io.ReadAll(io.LimitReader(r.Body, h.config.HooksMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.instanceService.UploadHooks(r.Context(), instanceID, userID, bundle)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to upload hooks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Hooks uploaded, the instance was restarted to load them",
+		"hooks":   result,
+	})
+}
+
+// DeleteHooks handles DELETE /api/v1/instances/:id/hooks
+func (h *InstanceHandler) DeleteHooks(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.instanceService.DeleteHooks(r.Context(), instanceID, userID); err != nil {
+		respondWithBundleError(w, err, "Failed to delete hooks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Hooks deleted",
+	})
+}
+
+// respondWithBundleError maps errors of the hooks and public file endpoints
+func respondWithBundleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case err.Error() == "too many concurrent operations":
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrInvalidBundle):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrBundleQuarantined):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error()+"; an administrator will review it")
+	case errors.Is(err, services.ErrScannerUnavailable):
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+	case err.Error() == "instance has no container" || err.Error() == "instance is pending deletion" ||
+		err.Error() == "instance is quarantined":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+)
+
+// ListIntegrityChecks handles GET /api/v1/instances/:id/integrity-checks
+func (h *InstanceHandler) ListIntegrityChecks(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	checks, err := h.instanceService.ListIntegrityChecks(r.Context(), instanceID, userID)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to read integrity checks")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"checks":  checks,
+	})
+}
+
+// CheckIntegrity handles POST /api/v1/instances/:id/integrity-checks. A check
+// that finds corruption is returned with 422 so scripts can tell it apart.
+func (h *InstanceHandler) CheckIntegrity(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	check, err := h.instanceService.CheckIntegrity(r.Context(), instanceID, userID)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrNoDatabase):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to check database integrity")
+		}
+		return
+	}
+
+	if check.Corrupt() {
+		respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"success": false,
+			"error":   "Database integrity check found problems",
+			"check":   check,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Database integrity check passed",
+		"check":   check,
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+)
+
+// GetMigrations handles GET /api/v1/instances/:id/migrations (the uploaded
+// files and the run history)
+func (h *InstanceHandler) GetMigrations(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.instanceService.GetMigrations(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to read migrations")
+		return
+	}
+
+	runs, err := h.instanceService.ListMigrationRuns(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to read migration runs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"migrations": bundle,
+		"runs":       runs,
+	})
+}
+
+// UploadMigrations handles PUT /api/v1/instances/:id/migrations. The body is
+// a zip archive of .js migrations (optionally inside a pb_migrations/ folder).
+func (h *InstanceHandler) UploadMigrations(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Read one byte past the limit so oversized bundles are reported as such
+	bundle, err := io.ReadAll(io.LimitReader(r.Body, h.config.MigrationsMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.instanceService.UploadMigrations(r.Context(), instanceID, userID, bundle)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to upload migrations")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"message":    "Migrations uploaded, run them with POST /migrations/run",
+		"migrations": result,
+	})
+}
+
+// RunMigrations handles POST /api/v1/instances/:id/migrations/run
+func (h *InstanceHandler) RunMigrations(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := h.instanceService.RunMigrations(r.Context(), instanceID, userID)
+	respondWithMigrationRun(w, run, err)
+}
+
+// respondWithMigrationRun reports a migration run; failed runs are returned
+// with 422 so scripts can tell them apart
+func respondWithMigrationRun(w http.ResponseWriter, run *models.InstanceMigrationRun, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMigrationsFailed):
+			respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"success": false,
+				"error":   "Migrations failed",
+				"run":     run,
+			})
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case err.Error() == "too many concurrent operations":
+			respondWithError(w, http.StatusTooManyRequests, err.Error())
+		case err.Error() == "instance has no container" || err.Error() == "instance is not running":
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to run migrations")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Migrations applied",
+		"run":     run,
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/middleware"
+
+	"github.com/google/uuid"
+)
+
+// SetPinnedRequest is the body of PUT /api/v1/instances/:id/pin
+type SetPinnedRequest struct {
+	Pinned bool `json:"pinned"`
+}
+
+// ReorderInstancesRequest is the body of PUT /api/v1/instances/order
+type ReorderInstancesRequest struct {
+	InstanceIDs []uuid.UUID `json:"instance_ids"`
+}
+
+// SetPinned handles PUT /api/v1/instances/:id/pin
+func (h *InstanceHandler) SetPinned(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetPinnedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	instance, err := h.instanceService.SetInstancePinned(r.Context(), instanceID, userID, req.Pinned)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update instance")
+		}
+		return
+	}
+
+	message := "Instance unpinned"
+	if instance.Pinned {
+		message = "Instance pinned"
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"instance": instance,
+	})
+}
+
+// ReorderInstances handles PUT /api/v1/instances/order and returns the
+// instances in their new order
+func (h *InstanceHandler) ReorderInstances(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	var req ReorderInstancesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	instances, err := h.instanceService.ReorderInstances(r.Context(), userID, req.InstanceIDs)
+	if err != nil {
+		switch err.Error() {
+		case "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case "instance_ids must not contain duplicates":
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to reorder instances")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"instances": instances,
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// CreatePreviewRequest is the body of POST /api/v1/instances/:id/previews
+type CreatePreviewRequest struct {
+	Ref string `json:"ref"`           // e.g. the pull request number
+	TTL string `json:"ttl,omitempty"` // e.g. 48h or 7d, 7 days if empty
+}
+
+// ListPreviews handles GET /api/v1/instances/:id/previews
+func (h *InstanceHandler) ListPreviews(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	previews, err := h.instanceService.ListPreviews(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithPreviewError(w, err, "Failed to list previews")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"previews": previews,
+	})
+}
+
+// CreatePreview handles POST /api/v1/instances/:id/previews (clones the
+// instance for a ref, or returns the ref's existing preview)
+func (h *InstanceHandler) CreatePreview(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreatePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ttl, err := parseTTL(req.TTL)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.instanceService.CreatePreview(r.Context(), instanceID, userID, services.CreatePreviewRequest{Ref: req.Ref, TTL: ttl})
+	if err != nil {
+		respondWithPreviewError(w, err, "Failed to create preview")
+		return
+	}
+
+	respondWithPreview(w, result, nil, "")
+}
+
+// DeletePreview handles DELETE /api/v1/instances/:id/previews/:ref
+func (h *InstanceHandler) DeletePreview(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	archived, err := h.instanceService.DeletePreview(r.Context(), instanceID, userID, mux.Vars(r)["ref"])
+	if err != nil {
+		respondWithPreviewError(w, err, "Failed to delete preview")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Preview deleted",
+		"archived": archived,
+	})
+}
+
+// respondWithPreview writes a created (201) or existing (200) preview, with
+// the deploy token issued for it, if any
+func respondWithPreview(w http.ResponseWriter, result *services.PreviewResult, token *models.DeployToken, tokenValue string) {
+	status, message := http.StatusOK, "Preview already exists"
+	if result.Created {
+		status, message = http.StatusCreated, "Preview created"
+	}
+
+	response := map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"instance": result.Instance,
+		"url":      result.URL,
+	}
+	if token != nil {
+		response["deploy_token"] = token
+		response["deploy_token_value"] = tokenValue
+	}
+
+	respondWithJSON(w, status, response)
+}
+
+// respondWithPreviewError maps preview errors to responses
+func respondWithPreviewError(w http.ResponseWriter, err error, fallback string) {
+	var limitErr *models.InstanceLimitError
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, models.ErrPreviewNotFound):
+		respondWithError(w, http.StatusNotFound, "Preview not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.As(err, &limitErr):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case strings.HasPrefix(err.Error(), "ref must be") || strings.HasPrefix(err.Error(), "ttl must be"):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case err.Error() == "previews cannot be created from a preview" || err.Error() == "instance is pending deletion":
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrSubdomainTaken):
+		respondWithError(w, http.StatusConflict, "Preview name is already taken, please try again")
+	case errors.Is(err, services.ErrDeployTokenLimit):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err.Error() == "too many concurrent operations":
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrInsufficientDiskSpace):
+		respondWithError(w, http.StatusInsufficientStorage, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
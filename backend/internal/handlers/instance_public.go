@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+)
+
+// GetPublicFiles handles GET /api/v1/instances/:id/public
+func (h *InstanceHandler) GetPublicFiles(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bundle, err := h.instanceService.GetPublicFiles(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to read public files")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"public":  bundle,
+	})
+}
+
+// UploadPublicFiles handles POST /api/v1/instances/:id/public. The body is a
+// zip archive of a static site (optionally inside a pb_public/ folder).
+func (h *InstanceHandler) UploadPublicFiles(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Read one byte past the limit so oversized bundles are reported as such
+	bundle, err := io.ReadAll(io.LimitReader(r.Body, h.config.PublicMaxSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.instanceService.UploadPublicFiles(r.Context(), instanceID, userID, bundle)
+	if err != nil {
+		respondWithBundleError(w, err, "Failed to upload public files")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Public files uploaded",
+		"public":  result,
+	})
+}
+
+// DeletePublicFiles handles DELETE /api/v1/instances/:id/public
+func (h *InstanceHandler) DeletePublicFiles(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.instanceService.DeletePublicFiles(r.Context(), instanceID, userID); err != nil {
+		respondWithBundleError(w, err, "Failed to delete public files")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Public files deleted",
+	})
+}
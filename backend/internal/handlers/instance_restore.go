@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+)
+
+// RestoreBackupRequest selects the backup to restore (an ID from GET /backups)
+type RestoreBackupRequest struct {
+	BackupID string `json:"backup_id" validate:"required,max=255"`
+}
+
+// ListBackups handles GET /api/v1/instances/:id/backups
+func (h *InstanceHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	backups, err := h.instanceService.ListBackups(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithRestoreError(w, err, "Failed to list backups")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"backups": backups,
+	})
+}
+
+// RestoreBackup handles POST /api/v1/instances/:id/restore
+func (h *InstanceHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req RestoreBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	result, err := h.instanceService.RestoreBackup(r.Context(), instanceID, userID, req.BackupID)
+	if err != nil {
+		respondWithRestoreError(w, err, "Failed to restore backup")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Backup restored, the previous data was saved as " + result.SafetyBackup.ID,
+		"restore": result,
+	})
+}
+
+// respondWithRestoreError maps backup and restore errors to responses
+func respondWithRestoreError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, services.ErrBackupNotFound):
+		respondWithError(w, http.StatusNotFound, "Backup not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case err.Error() == "too many concurrent operations":
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrInvalidBundle):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, services.ErrRestoreFailed):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, services.ErrInsufficientDiskSpace):
+		respondWithError(w, http.StatusInsufficientStorage, err.Error())
+	case err.Error() == "instance has no container" || err.Error() == "instance must be running or stopped to restore a backup":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+
+	"github.com/gorilla/mux"
+)
+
+// RotateStatusToken handles POST /api/v1/instances/:id/status-token (creates
+// or replaces the token of the public status endpoint)
+func (h *InstanceHandler) RotateStatusToken(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	token, instance, err := h.instanceService.RotateStatusToken(r.Context(), instanceID, userID)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to create status token")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":    true,
+		"message":    "Status token created. Store it now, it won't be shown again",
+		"token":      token,
+		"status_url": "/api/v1/public/instances/" + token + "/status",
+		"instance":   instance,
+	})
+}
+
+// RevokeStatusToken handles DELETE /api/v1/instances/:id/status-token
+func (h *InstanceHandler) RevokeStatusToken(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	instance, err := h.instanceService.RevokeStatusToken(r.Context(), instanceID, userID)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found" || err.Error() == "instance has no status token":
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke status token")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Status token revoked",
+		"instance": instance,
+	})
+}
+
+// GetPublicStatus handles GET /api/v1/public/instances/:token/status (no
+// auth; responds 503 while the instance is unhealthy, so uptime monitors can
+// go by the status code alone)
+func (h *InstanceHandler) GetPublicStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	status, err := h.instanceService.GetPublicStatus(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		if err.Error() == "instance not found" {
+			respondWithError(w, http.StatusNotFound, "Status token not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get instance status")
+		return
+	}
+
+	statusCode := http.StatusOK
+	if !status.Healthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	respondWithJSON(w, statusCode, map[string]interface{}{
+		"success": true,
+		"data":    status,
+	})
+}
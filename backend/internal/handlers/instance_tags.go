@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// UpdateTagsRequest is the body of PUT /api/v1/instances/:id/tags
+type UpdateTagsRequest struct {
+	Tags models.Tags `json:"tags"`
+}
+
+// BulkOperationRequest is the body of POST /api/v1/instances/bulk
+type BulkOperationRequest struct {
+	Action string   `json:"action"` // start, stop or restart
+	Tags   []string `json:"tags"`   // selectors ("env=staging" or "project"), all must match
+}
+
+// UpdateTags handles PUT /api/v1/instances/:id/tags (replaces all tags)
+func (h *InstanceHandler) UpdateTags(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	instance, err := h.instanceService.SetInstanceTags(r.Context(), instanceID, userID, req.Tags)
+	if err != nil {
+		switch {
+		case err.Error() == "instance not found":
+			respondWithError(w, http.StatusNotFound, "Instance not found")
+		case errors.Is(err, authz.ErrForbidden):
+			respondWithError(w, http.StatusForbidden, "Permission denied")
+		case errors.Is(err, models.ErrInvalidTags):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update tags")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Tags updated",
+		"instance": instance,
+	})
+}
+
+// BulkOperation handles POST /api/v1/instances/bulk (starts, stops or
+// restarts every instance matching the tag selectors)
+func (h *InstanceHandler) BulkOperation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	var req BulkOperationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	selectors, err := parseTagSelectors(req.Tags)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := h.instanceService.BulkOperation(r.Context(), userID, req.Action, selectors)
+	if err != nil {
+		if err.Error() == "action must be start, stop or restart" || err.Error() == "at least one tag selector is required" {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to run bulk operation")
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": failed == 0,
+		"failed":  failed,
+		"results": results,
+	})
+}
+
+// parseTagSelectors parses "key=value" and "key" selectors
+func parseTagSelectors(values []string) ([]models.TagSelector, error) {
+	selectors := make([]models.TagSelector, 0, len(values))
+	for _, value := range values {
+		selector, err := models.ParseTagSelector(value)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// fakeInstanceManager answers StartInstance with a fixed error. Methods it
+// doesn't override panic through the nil embedded interface.
+type fakeInstanceManager struct {
+	InstanceManager
+	startErr    error
+	startedID   uuid.UUID
+	startedUser uuid.UUID
+}
+
+func (f *fakeInstanceManager) StartInstance(ctx context.Context, instanceID, userID uuid.UUID) error {
+	f.startedID, f.startedUser = instanceID, userID
+	return f.startErr
+}
+
+// newInstanceRequest builds a request for an instance route as the given user
+func newInstanceRequest(method, instanceID string, userID uuid.UUID) *http.Request {
+	r := httptest.NewRequest(method, "/api/v1/instances/"+instanceID+"/start", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": instanceID})
+	if userID != uuid.Nil {
+		claims := &utils.Claims{UserID: userID.String()}
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserClaimsKey, claims))
+	}
+	return r
+}
+
+func TestStartInstanceResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"started", nil, http.StatusOK},
+		{"not found", fmt.Errorf("instance not found"), http.StatusNotFound},
+		{"forbidden", fmt.Errorf("start: %w", authz.ErrForbidden), http.StatusForbidden},
+		{"suspended", fmt.Errorf("instance is suspended"), http.StatusConflict},
+		{"pending deletion", fmt.Errorf("instance is pending deletion"), http.StatusConflict},
+		{"already running", fmt.Errorf("instance is already running"), http.StatusBadRequest},
+		{"busy", fmt.Errorf("too many concurrent operations"), http.StatusTooManyRequests},
+		{"docker failure", errors.New("failed to start container: boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeInstanceManager{startErr: tt.err}
+			handler := NewInstanceHandler(manager, &config.Config{})
+			userID, instanceID := uuid.New(), uuid.New()
+
+			w := httptest.NewRecorder()
+			handler.StartInstance(w, newInstanceRequest(http.MethodPost, instanceID.String(), userID))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if manager.startedID != instanceID || manager.startedUser != userID {
+				t.Errorf("started %s as %s, want %s as %s", manager.startedID, manager.startedUser, instanceID, userID)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if tt.err != nil && body["error"] == nil {
+				t.Errorf("error response without an error message: %v", body)
+			}
+		})
+	}
+}
+
+func TestStartInstanceRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		instanceID string
+		userID     uuid.UUID
+		wantCode   int
+	}{
+		{"unauthenticated", uuid.NewString(), uuid.Nil, http.StatusUnauthorized},
+		{"invalid instance ID", "not-a-uuid", uuid.New(), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeInstanceManager{}
+			handler := NewInstanceHandler(manager, &config.Config{})
+
+			w := httptest.NewRecorder()
+			handler.StartInstance(w, newInstanceRequest(http.MethodPost, tt.instanceID, tt.userID))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if manager.startedID != uuid.Nil {
+				t.Error("the service was called for a rejected request")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// maxManifestBytes bounds an uploaded manifest
+const maxManifestBytes = 1 << 20
+
+// ManifestHandler handles instance manifest export and import
+type ManifestHandler struct {
+	manifestService *services.ManifestService
+}
+
+// NewManifestHandler creates a new manifest handler
+func NewManifestHandler(manifestService *services.ManifestService) *ManifestHandler {
+	return &ManifestHandler{manifestService: manifestService}
+}
+
+// ImportManifestRequest represents the request to create an instance from a
+// manifest. It is JSON, or YAML when sent with a YAML content type.
+type ImportManifestRequest struct {
+	AdminEmail    string                    `json:"admin_email" yaml:"admin_email" validate:"required,email"`
+	AdminPassword string                    `json:"admin_password" yaml:"admin_password" validate:"required,min=10"`
+	Name          string                    `json:"name,omitempty" yaml:"name,omitempty" validate:"omitempty,min=3,max=100"`
+	Region        string                    `json:"region,omitempty" yaml:"region,omitempty"`
+	Manifest      services.InstanceManifest `json:"manifest" yaml:"manifest"`
+}
+
+// ExportManifest handles GET /api/v1/instances/:id/manifest?format=json|yaml.
+// YAML is sent as a file download.
+func (h *ManifestHandler) ExportManifest(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "yaml" {
+		respondWithError(w, http.StatusBadRequest, "format must be json or yaml")
+		return
+	}
+
+	manifest, err := h.manifestService.ExportManifest(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithManifestError(w, err, "Failed to export manifest")
+		return
+	}
+
+	if format != "yaml" {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"manifest": manifest,
+		})
+		return
+	}
+
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export manifest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.yaml"`, manifest.Subdomain))
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// ImportManifest handles POST /api/v1/instances/import
+func (h *ManifestHandler) ImportManifest(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxManifestBytes))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var req ImportManifestRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		err = yaml.Unmarshal(body, &req)
+	default:
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	result, err := h.manifestService.ImportManifest(r.Context(), services.ImportManifestRequest{
+		UserID:        userID,
+		Username:      claims.Username,
+		AdminEmail:    req.AdminEmail,
+		AdminPassword: req.AdminPassword,
+		Name:          req.Name,
+		Region:        req.Region,
+		Manifest:      req.Manifest,
+	})
+	if err != nil {
+		respondWithManifestError(w, err, "Failed to import manifest")
+		return
+	}
+
+	message := "Instance created from manifest"
+	if len(result.Warnings) > 0 {
+		message = "Instance created, but some settings of the manifest could not be applied"
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":  true,
+		"message":  message,
+		"instance": result.Instance,
+		"url":      result.URL,
+		"warnings": result.Warnings,
+	})
+}
+
+// respondWithManifestError maps errors of the manifest endpoints, including
+// those of creating the instance
+func respondWithManifestError(w http.ResponseWriter, err error, fallback string) {
+	var limitErr *models.InstanceLimitError
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case err.Error() == "too many concurrent operations":
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.As(err, &limitErr):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrInvalidManifest):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrSubdomainTaken) || err.Error() == "failed to generate a unique slug":
+		respondWithError(w, http.StatusConflict, "Instance name is already taken, please try again")
+	case err.Error() == "instance name is reserved" || err.Error() == "region not found" || err.Error() == "region is not available" ||
+		err.Error() == "image not found" || err.Error() == "image is not available":
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrNoHostPort):
+		respondWithError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, services.ErrInsufficientDiskSpace):
+		respondWithError(w, http.StatusInsufficientStorage, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pocketploy/internal/services"
+)
+
+// MeteringHandler exports the hourly usage records
+type MeteringHandler struct {
+	meteringService *services.MeteringService
+}
+
+// NewMeteringHandler creates a new metering handler
+func NewMeteringHandler(meteringService *services.MeteringService) *MeteringHandler {
+	return &MeteringHandler{meteringService: meteringService}
+}
+
+// Export handles GET /api/v1/admin/metering. from and to (RFC3339, Unix
+// seconds or a duration ago such as "24h") default to the last 24 hours;
+// user_id limits the export to one user and format=csv downloads a CSV file.
+func (h *MeteringHandler) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, ok := parseTimeRange(w, query, 24*time.Hour)
+	if !ok {
+		return
+	}
+
+	userID, ok := parseOptionalUUID(w, query.Get("user_id"), "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respondWithError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	records, err := h.meteringService.Export(r.Context(), from, to, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export metering records")
+		return
+	}
+
+	if format != "csv" {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"from":    from.UTC(),
+			"to":      to.UTC(),
+			"records": records,
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("metering-%s-%s.csv", from.UTC().Format("20060102T15"), to.UTC().Format("20060102T15"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write(meteringCSVHeader)
+	for i := range records {
+		out.Write(meteringCSVRow(&records[i]))
+	}
+	out.Flush()
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// RegionHandler handles the region endpoints
+type RegionHandler struct {
+	regionService *services.RegionService
+}
+
+// NewRegionHandler creates a new region handler
+func NewRegionHandler(regionService *services.RegionService) *RegionHandler {
+	return &RegionHandler{regionService: regionService}
+}
+
+// ListRegions handles GET /api/v1/regions (the regions users can create instances in)
+func (h *RegionHandler) ListRegions(w http.ResponseWriter, r *http.Request) {
+	regions, err := h.regionService.ListRegions(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list regions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"regions": regions,
+	})
+}
+
+// ListAllRegions handles GET /api/v1/admin/regions
+func (h *RegionHandler) ListAllRegions(w http.ResponseWriter, r *http.Request) {
+	regions, err := h.regionService.ListAllRegions(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list regions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"regions": regions,
+	})
+}
+
+// CreateRegion handles POST /api/v1/admin/regions
+func (h *RegionHandler) CreateRegion(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRegionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !validateRegionRequest(w, req) {
+		return
+	}
+
+	region, err := h.regionService.CreateRegion(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, models.ErrRegionExists) {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrRegionDomainAllowed) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to create region")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Region created successfully",
+		"region":  region,
+	})
+}
+
+// UpdateRegion handles PATCH /api/v1/admin/regions/:id
+func (h *RegionHandler) UpdateRegion(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateRegionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !validateRegionRequest(w, req) {
+		return
+	}
+
+	region, err := h.regionService.UpdateRegion(r.Context(), mux.Vars(r)["id"], req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		message := "Failed to update region"
+		if err.Error() == "region not found" {
+			statusCode, message = http.StatusNotFound, err.Error()
+		} else if err.Error() == "the default region cannot be disabled" {
+			statusCode, message = http.StatusBadRequest, err.Error()
+		}
+		respondWithError(w, statusCode, message)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Region updated successfully",
+		"region":  region,
+	})
+}
+
+// validateRegionRequest validates a region request body, writing a 400 response on failure
+func validateRegionRequest(w http.ResponseWriter, req interface{}) bool {
+	if err := utils.ValidateStruct(req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pocketploy/internal/services"
+)
+
+// StatsHandler serves the platform statistics of the admin dashboard
+type StatsHandler struct {
+	statsService *services.StatsService
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(statsService *services.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: statsService}
+}
+
+// GetStats handles GET /api/v1/admin/stats?days=30 (admin only). Stats are
+// cached for a minute; ?refresh=true recomputes them.
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	days := services.DefaultStatsDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "days must be a number")
+			return
+		}
+		days = parsed
+	}
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+
+	stats, err := h.statsService.GetStats(r.Context(), days, refresh)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "days must be") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get platform stats")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"stats":   stats,
+	})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"pocketploy/internal/services"
+)
+
+// StatusHandler exposes platform status for the frontend
+type StatusHandler struct {
+	platformService *services.PlatformService
+	statusMonitor   *services.StatusMonitor
+}
+
+// NewStatusHandler creates a new status handler
+func NewStatusHandler(platformService *services.PlatformService, statusMonitor *services.StatusMonitor) *StatusHandler {
+	return &StatusHandler{
+		platformService: platformService,
+		statusMonitor:   statusMonitor,
+	}
+}
+
+// GetStatus handles GET /api/v1/status
+func (h *StatusHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.platformService.GetStatus()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get platform status")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"status": status,
+		},
+	})
+}
+
+// GetPlatformHealth handles GET /api/v1/platform/status
+func (h *StatusHandler) GetPlatformHealth(w http.ResponseWriter, r *http.Request) {
+	health := h.statusMonitor.Summary(r.Context())
+
+	statusCode := http.StatusOK
+	if health.Status == services.StatusOutage {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	respondWithJSON(w, statusCode, map[string]interface{}{
+		"success": true,
+		"data":    health,
+	})
+}
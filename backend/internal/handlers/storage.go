@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+)
+
+// StorageHandler handles the managed file storage of instances
+type StorageHandler struct {
+	storageService *services.StorageService
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(storageService *services.StorageService) *StorageHandler {
+	return &StorageHandler{storageService: storageService}
+}
+
+// GetStorage handles GET /api/v1/instances/:id/storage
+func (h *StorageHandler) GetStorage(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	storage, err := h.storageService.GetStorage(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithStorageError(w, err, "Failed to get storage")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"storage": storage,
+	})
+}
+
+// EnableStorage handles POST /api/v1/instances/:id/storage
+func (h *StorageHandler) EnableStorage(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	storage, err := h.storageService.EnableStorage(r.Context(), instanceID, userID)
+	if err != nil {
+		respondWithStorageError(w, err, "Failed to enable managed storage")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Managed storage enabled; new uploads are stored in the bucket",
+		"storage": storage,
+	})
+}
+
+// DisableStorage handles DELETE /api/v1/instances/:id/storage
+func (h *StorageHandler) DisableStorage(w http.ResponseWriter, r *http.Request) {
+	userID, instanceID, ok := parseInstanceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storageService.DisableStorage(r.Context(), instanceID, userID); err != nil {
+		respondWithStorageError(w, err, "Failed to disable managed storage")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Managed storage disabled; the bucket and its files will be deleted",
+	})
+}
+
+// respondWithStorageError maps storage service errors to responses
+func respondWithStorageError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case err.Error() == "instance not found":
+		respondWithError(w, http.StatusNotFound, "Instance not found")
+	case errors.Is(err, models.ErrStorageNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, authz.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, "Permission denied")
+	case err.Error() == "too many concurrent operations":
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, models.ErrStorageExists) || err.Error() == "instance has no container" ||
+		err.Error() == "instance must be running or stopped" || err.Error() == "managed storage is not enabled" ||
+		err.Error() == "managed storage can't be configured while settings encryption is enabled":
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
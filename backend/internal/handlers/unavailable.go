@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+
+	"pocketploy/internal/models"
+)
+
+// InstanceFinder looks up the instance behind a request host (implemented by *services.InstanceService)
+type InstanceFinder interface {
+	FindInstanceByHost(ctx context.Context, host string) (*models.Instance, error)
+}
+
+// UnavailableHandler renders the page shown on instance subdomains that
+// Traefik cannot serve, with a message for the instance's state
+type UnavailableHandler struct {
+	instanceService InstanceFinder
+}
+
+// NewUnavailableHandler creates a new unavailable page handler
+func NewUnavailableHandler(instanceService InstanceFinder) *UnavailableHandler {
+	return &UnavailableHandler{instanceService: instanceService}
+}
+
+var unavailablePage = template.Must(template.New("unavailable").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; min-height: 100vh; margin: 0; align-items: center; justify-content: center; background: #f8fafc; color: #0f172a; }
+main { max-width: 32rem; padding: 2rem; text-align: center; }
+p { color: #475569; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+</main>
+</body>
+</html>
+`))
+
+// unavailableState is the page content for an instance status
+type unavailableState struct {
+	Status  int
+	Title   string
+	Message string
+}
+
+// unavailableStates maps instance statuses to the page shown on their subdomain
+var unavailableStates = map[string]unavailableState{
+	models.InstanceStatusCreating: {http.StatusServiceUnavailable, "Instance starting", "This instance is being created. Try again in a moment."},
+	models.InstanceStatusRunning:  {http.StatusServiceUnavailable, "Instance not responding", "This instance is running but did not respond. It may still be starting up."},
+	models.InstanceStatusStopped:  {http.StatusServiceUnavailable, "Instance stopped", "This instance has been stopped by its owner."},
+	models.InstanceStatusFailed:   {http.StatusServiceUnavailable, "Instance unavailable", "This instance stopped after repeated crashes. Its owner has been notified."},
+
+	models.InstanceStatusPendingDeletion: {http.StatusNotFound, "Instance deleted", "This instance has been deleted by its owner."},
+	models.InstanceStatusSuspended:       {http.StatusNotFound, "Instance suspended", "This instance has been suspended by the platform administrators."},
+}
+
+// unknownInstance is shown for hosts without an instance
+var unknownInstance = unavailableState{http.StatusNotFound, "Instance not found", "There is no PocketBase instance at this address."}
+
+// ServePage handles requests Traefik sends to /unavailable, keeping the
+// original Host: subdomains without a routable container (fallback router)
+// and 502-504 responses of instances (errors middleware, which keeps the
+// original status code)
+func (h *UnavailableHandler) ServePage(w http.ResponseWriter, r *http.Request) {
+	page := unknownInstance
+	if instance, err := h.instanceService.FindInstanceByHost(r.Context(), r.Host); err == nil {
+		if state, ok := unavailableStates[instance.Status]; ok {
+			page = state
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if page.Status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "30")
+	}
+	w.WriteHeader(page.Status)
+	if r.Method != http.MethodHead {
+		_ = unavailablePage.Execute(w, page)
+	}
+}
@@ -2,22 +2,36 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"os"
 
 	"pocketploy/internal/middleware"
 	"pocketploy/internal/models"
 	"pocketploy/internal/services"
 	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 // UserHandler handles user-related endpoints
 type UserHandler struct {
-	userService *services.UserService
+	userService      *services.UserService
+	instanceService  InstanceManager
+	bandwidthService *services.BandwidthService
+	usageService     *services.UsageService
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userService *services.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *services.UserService, instanceService InstanceManager, bandwidthService *services.BandwidthService, usageService *services.UsageService) *UserHandler {
+	return &UserHandler{
+		userService:      userService,
+		instanceService:  instanceService,
+		bandwidthService: bandwidthService,
+		usageService:     usageService,
+	}
 }
 
 // GetMe returns the current user's profile
@@ -80,11 +94,17 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if there are any fields to update
-	if req.Username == "" && req.Email == "" {
+	if req.Username == "" && req.Email == "" && req.DisplayName == nil && req.Timezone == nil && req.Locale == nil {
 		respondWithError(w, http.StatusBadRequest, "No fields to update")
 		return
 	}
 
+	// Changing the login email is left to the user themselves
+	if claims, ok := middleware.GetUserClaims(r); ok && claims.Impersonated() && req.Email != "" {
+		respondWithError(w, http.StatusForbidden, "Email cannot be changed while impersonating")
+		return
+	}
+
 	// Prepare update parameters
 	params := services.UpdateProfileParams{}
 	if req.Username != "" {
@@ -93,9 +113,12 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	if req.Email != "" {
 		params.Email = &req.Email
 	}
+	params.DisplayName = req.DisplayName
+	params.Timezone = req.Timezone
+	params.Locale = req.Locale
 
 	// Call service to update user profile
-	user, err := h.userService.UpdateUserProfile(userID, params)
+	user, emailChange, err := h.userService.UpdateUserProfile(r.Context(), userID, params)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "username already exists" || err.Error() == "email already exists" {
@@ -104,6 +127,239 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 			statusCode = http.StatusNotFound
 		} else if err.Error() == "account is inactive" {
 			statusCode = http.StatusUnauthorized
+		} else if err.Error() == "invalid timezone" {
+			statusCode = http.StatusBadRequest
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	message := "Profile updated successfully"
+	if emailChange != nil {
+		message = "Profile updated. Open the links sent to your current and new email address to change your email"
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"data": map[string]interface{}{
+			"user":         user.ToResponse(),
+			"email_change": emailChange,
+		},
+	})
+}
+
+// UpdatePassword handles PATCH /api/v1/users/me/password. Every other session
+// is signed out; the one making the request stays signed in.
+func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Support staff acting as the user mustn't lock them out
+	if claims.Impersonated() {
+		respondWithError(w, http.StatusForbidden, "Not allowed while impersonating")
+		return
+	}
+
+	var req models.UpdatePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	err := h.userService.UpdateUserPassword(r.Context(), claims.UserID, services.UpdatePasswordParams{
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+		SessionID:       claims.SessionID,
+		IPAddress:       utils.ClientIP(r),
+	})
+	if err != nil {
+		switch err.Error() {
+		case "current password is incorrect":
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+		case "new password must be at least 8 characters long", "new password must be different from the current password":
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case "user not found":
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case "account is inactive":
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to update password")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Password updated successfully. Other sessions have been signed out",
+	})
+}
+
+// DeleteMe deactivates the current user's account and signs out every session.
+// Instances have to be deleted first.
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if claims.Impersonated() {
+		respondWithError(w, http.StatusForbidden, "Not allowed while impersonating")
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return
+	}
+
+	instances, err := h.instanceService.ListUserInstances(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+	if len(instances) > 0 {
+		respondWithError(w, http.StatusConflict, "Delete your instances before deleting the account")
+		return
+	}
+
+	if err := h.userService.DeleteAccount(claims.UserID, req.Password); err != nil {
+		switch err.Error() {
+		case "password is required":
+			respondWithError(w, http.StatusPreconditionRequired, err.Error())
+		case "password is incorrect", "account is already inactive":
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+		case "user not found":
+			respondWithError(w, http.StatusNotFound, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete account")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Account deleted",
+	})
+}
+
+// GetEmailChange handles GET /api/v1/users/me/email-change
+func (h *UserHandler) GetEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	change, err := h.userService.GetEmailChange(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get email change")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"email_change": change,
+		},
+	})
+}
+
+// CancelEmailChange handles DELETE /api/v1/users/me/email-change
+func (h *UserHandler) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.userService.CancelEmailChange(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel email change")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Email change cancelled",
+	})
+}
+
+// ConfirmEmailChangeRequest is the body of POST /api/v1/auth/confirm-email
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+// ConfirmEmailChange handles POST /api/v1/auth/confirm-email with a token
+// from one of the email change links. No session is required, so the links
+// work in any browser.
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	change, applied, err := h.userService.ConfirmEmailChange(r.Context(), req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEmailChangeNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, models.ErrEmailTaken):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to confirm email change")
+		}
+		return
+	}
+
+	message := "Confirmed. The change is applied once the other address has been confirmed too"
+	if applied {
+		message = "Your email address has been changed. Please log in again"
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": message,
+		"data": map[string]interface{}{
+			"applied":      applied,
+			"email_change": change,
+		},
+	})
+}
+
+// GetBandwidth returns the current user's bandwidth usage for this month
+func (h *UserHandler) GetBandwidth(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	bandwidth, err := h.bandwidthService.GetUserBandwidth(r.Context(), userID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "user not found" {
+			statusCode = http.StatusNotFound
 		}
 		respondWithError(w, statusCode, err.Error())
 		return
@@ -111,9 +367,125 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Profile updated successfully",
+		"data": map[string]interface{}{
+			"bandwidth": bandwidth,
+		},
+	})
+}
+
+// GetUsage handles GET /api/v1/users/me/usage: instances, disk, bandwidth
+// and sessions in one response for the dashboard home page
+func (h *UserHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	usage, err := h.usageService.GetAccountUsage(r.Context(), userID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "user not found" {
+			statusCode = http.StatusNotFound
+		}
+		respondWithError(w, statusCode, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"usage": usage,
+		},
+	})
+}
+
+// UploadAvatar handles PUT /api/v1/users/me/avatar. The body is the image
+// itself (JPEG, PNG or GIF).
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	user, err := h.userService.UploadAvatar(userID, r.Body)
+	if err != nil {
+		respondWithAvatarError(w, err, "Failed to upload avatar")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Avatar updated successfully",
+		"data": map[string]interface{}{
+			"user": user.ToResponse(),
+		},
+	})
+}
+
+// DeleteAvatar handles DELETE /api/v1/users/me/avatar
+func (h *UserHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	user, err := h.userService.DeleteAvatar(userID)
+	if err != nil {
+		respondWithAvatarError(w, err, "Failed to delete avatar")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Avatar removed successfully",
 		"data": map[string]interface{}{
 			"user": user.ToResponse(),
 		},
 	})
 }
+
+// GetAvatar handles GET /api/v1/avatars/:file (no auth, so it can be used
+// as an <img> src). File names are random and never reused, so responses
+// can be cached indefinitely.
+func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	path, ok := h.userService.AvatarPath(mux.Vars(r)["file"])
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Avatar not found")
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Avatar not found")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to read avatar")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// respondWithAvatarError maps errors of the avatar endpoints
+func respondWithAvatarError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAvatar):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case err.Error() == "user not found":
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case err.Error() == "account is inactive":
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
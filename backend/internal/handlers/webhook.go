@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/models"
+	"pocketploy/internal/services"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// WebhookHandler handles the webhook endpoints users register for instance
+// lifecycle events. See package webhook for the delivery headers and how
+// consumers verify signatures.
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// ListWebhooks handles GET /api/v1/webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserRequest(w, r)
+	if !ok {
+		return
+	}
+
+	endpoints, err := h.webhookService.ListEndpoints(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list webhooks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"webhooks": endpoints,
+	})
+}
+
+// CreateWebhook handles POST /api/v1/webhooks. The signing secret is only
+// returned in this response.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	endpoint, secret, err := h.webhookService.CreateEndpoint(r.Context(), userID, req)
+	if err != nil {
+		respondWithWebhookError(w, err, "Failed to create webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Webhook created. Store the secret now, it won't be shown again",
+		"webhook": endpoint,
+		"secret":  secret,
+	})
+}
+
+// DeleteWebhook handles DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, endpointID, ok := parseWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteEndpoint(r.Context(), endpointID, userID); err != nil {
+		respondWithWebhookError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Webhook deleted",
+	})
+}
+
+// RotateSecret handles POST /api/v1/webhooks/:id/rotate-secret
+func (h *WebhookHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	userID, endpointID, ok := parseWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := h.webhookService.RotateSecret(r.Context(), endpointID, userID)
+	if err != nil {
+		respondWithWebhookError(w, err, "Failed to rotate webhook secret")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Webhook secret rotated. Store it now, it won't be shown again",
+		"secret":  secret,
+	})
+}
+
+// ListDeliveries handles GET /api/v1/webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, endpointID, ok := parseWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), endpointID, userID)
+	if err != nil {
+		respondWithWebhookError(w, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"deliveries": deliveries,
+	})
+}
+
+// Redeliver handles POST /api/v1/webhooks/:id/deliveries/:deliveryId/redeliver
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	userID, endpointID, ok := parseWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(mux.Vars(r)["deliveryId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), endpointID, deliveryID, userID)
+	if err != nil {
+		respondWithWebhookError(w, err, "Failed to redeliver webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success":  true,
+		"message":  "Redelivery queued",
+		"delivery": delivery,
+	})
+}
+
+// parseUserRequest extracts the authenticated user's ID
+func parseUserRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	claims, ok := middleware.GetUserClaims(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user ID")
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// parseWebhookRequest extracts the authenticated user's ID and the webhook ID from the URL
+func parseWebhookRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := parseUserRequest(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	endpointID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, endpointID, true
+}
+
+// respondWithWebhookError maps webhook service errors to responses
+func respondWithWebhookError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound):
+		respondWithError(w, http.StatusNotFound, "Webhook not found")
+	case errors.Is(err, models.ErrWebhookDeliveryNotFound):
+		respondWithError(w, http.StatusNotFound, "Webhook delivery not found")
+	case errors.Is(err, services.ErrWebhookLimit) || err.Error() == "delivery is already pending":
+		respondWithError(w, http.StatusConflict, err.Error())
+	case err.Error() == "url must be an http or https URL" || err.Error() == "url must not contain credentials":
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
+	}
+}
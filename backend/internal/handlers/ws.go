@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"pocketploy/internal/events"
+	"pocketploy/internal/middleware"
+	"pocketploy/internal/ws"
+)
+
+const (
+	wsPingInterval = 25 * time.Second
+	wsIdleTimeout  = 60 * time.Second
+)
+
+// WebSocketHandler pushes real-time events to the signed-in user
+type WebSocketHandler struct {
+	broker   *events.Broker
+	upgrader *ws.Upgrader
+}
+
+// NewWebSocketHandler creates a new WebSocket handler. Browsers may connect
+// from the origins originAllowed accepts.
+func NewWebSocketHandler(broker *events.Broker, originAllowed func(origin string) bool) *WebSocketHandler {
+	return &WebSocketHandler{broker: broker, upgrader: ws.NewUpgrader(originAllowed)}
+}
+
+// Serve handles GET /api/v1/ws
+func (h *WebSocketHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Subscribe before upgrading so no event is missed in between
+	eventsCh, unsubscribe := h.broker.Subscribe(userID)
+	defer unsubscribe()
+
+	// Echo the subprotocol when the token was sent that way, as browsers require
+	protocol := ""
+	if _, ok := middleware.WebSocketToken(r); ok {
+		protocol = "bearer"
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, protocol)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.ReadLoop(wsIdleTimeout)
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case event := <-eventsCh:
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				log.Printf("WebSocket ping to user %s failed: %v", userID, err)
+				return
+			}
+		}
+	}
+}
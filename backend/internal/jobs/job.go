@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job states
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead" // attempts exhausted; can be requeued by an admin
+)
+
+// DefaultMaxAttempts is used when a job is enqueued without MaxAttempts
+const DefaultMaxAttempts = 5
+
+// Job is a unit of background work stored in the jobs table
+type Job struct {
+	ID          uuid.UUID       `db:"id" json:"id"`
+	Type        string          `db:"type" json:"type"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	Status      string          `db:"status" json:"status"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	MaxAttempts int             `db:"max_attempts" json:"max_attempts"`
+	UniqueKey   *string         `db:"unique_key" json:"unique_key,omitempty"`
+	RunAt       time.Time       `db:"run_at" json:"run_at"`
+	LockedAt    *time.Time      `db:"locked_at" json:"locked_at,omitempty"`
+	LockedBy    *string         `db:"locked_by" json:"locked_by,omitempty"`
+	LastError   *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at"`
+	CompletedAt *time.Time      `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// Decode unmarshals the job payload into v
+func (j *Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// EnqueueOptions controls when and how often a job runs
+type EnqueueOptions struct {
+	// RunAt delays the job until the given time (zero runs it as soon as possible)
+	RunAt time.Time
+
+	// MaxAttempts is the number of tries before the job is dead (0 uses DefaultMaxAttempts)
+	MaxAttempts int
+
+	// UniqueKey deduplicates jobs: enqueueing a second job with the same key is a no-op
+	UniqueKey string
+}
+
+// backoff returns the delay before retrying a job that failed attempts times
+func backoff(attempts int) time.Duration {
+	delay := 10 * time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// leaderRetryInterval is how often a follower tries to become leader
+	leaderRetryInterval = 15 * time.Second
+
+	// leaderCheckInterval is how often a leader verifies it still holds its lock
+	leaderCheckInterval = 30 * time.Second
+)
+
+// Locker provides cluster-wide mutual exclusion between backend processes
+// using PostgreSQL session-level advisory locks. A lock is tied to the
+// database connection that took it, so it is released automatically if the
+// holding process dies.
+type Locker struct {
+	db *sqlx.DB
+}
+
+// NewLocker creates a new advisory lock helper
+func NewLocker(db *sqlx.DB) *Locker {
+	return &Locker{db: db}
+}
+
+// lockKey maps a lock name to an advisory lock key
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("pocketploy:" + name))
+	return int64(h.Sum64())
+}
+
+// tryLock takes the named lock on a dedicated connection, returning nil if
+// another process holds it
+func (l *Locker) tryLock(ctx context.Context, name string) (*sql.Conn, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lock connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockKey(name)).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+
+	return conn, nil
+}
+
+// unlock releases the named lock and returns the connection to the pool
+func (l *Locker) unlock(conn *sql.Conn, name string) {
+	if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey(name)); err != nil {
+		log.Printf("Warning: failed to release lock %s: %v", name, err)
+	}
+	conn.Close()
+}
+
+// RunExclusive runs fn only if no other process is running it under the same
+// name, reporting whether fn ran. Use it for work that must happen at most
+// once per tick across replicas.
+func (l *Locker) RunExclusive(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := l.tryLock(ctx, name)
+	if err != nil || conn == nil {
+		return false, err
+	}
+	defer l.unlock(conn, name)
+
+	return true, fn(ctx)
+}
+
+// RunAsLeader runs fn in exactly one process at a time. Other processes wait
+// and take over if the leader stops or loses its database connection. fn must
+// return when its context is cancelled. RunAsLeader returns when ctx is
+// cancelled or when fn returns on its own (e.g. because it is disabled).
+func (l *Locker) RunAsLeader(ctx context.Context, name string, fn func(ctx context.Context)) {
+	for {
+		conn, err := l.tryLock(ctx, name)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		if conn != nil {
+			log.Printf("Became leader for %s", name)
+			if finished := l.lead(ctx, conn, name, fn); finished {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(leaderRetryInterval):
+		}
+	}
+}
+
+// lead runs fn while the lock connection stays healthy, reporting whether fn
+// returned by itself rather than because leadership was lost
+func (l *Locker) lead(ctx context.Context, conn *sql.Conn, name string, fn func(ctx context.Context)) bool {
+	defer l.unlock(conn, name)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(leaderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return true
+		case <-ticker.C:
+			if err := conn.PingContext(ctx); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Warning: lost leadership for %s: %v", name, err)
+				}
+				cancel()
+				<-done
+				return false
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const jobColumns = `id, type, payload, status, attempts, max_attempts, unique_key, run_at,
+		       locked_at, locked_by, last_error, created_at, updated_at, completed_at`
+
+// Queue stores jobs in PostgreSQL so they survive restarts and can be shared
+// between backend processes
+type Queue struct {
+	db *sqlx.DB
+}
+
+// NewQueue creates a new job queue
+func NewQueue(db *sqlx.DB) *Queue {
+	return &Queue{db: db}
+}
+
+// Enqueue adds a job of the given type with a JSON-encoded payload. When a job
+// with the same unique key already exists, nil is returned without an error.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:          uuid.New(),
+		Type:        jobType,
+		Payload:     data,
+		Status:      StatusPending,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt.UTC(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	query := `
+		INSERT INTO jobs (id, type, payload, status, max_attempts, unique_key, run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL DO NOTHING
+	`
+
+	// The payload is passed as a string: lib/pq would encode []byte as bytea
+	result, err := q.db.ExecContext(ctx, query,
+		job.ID,
+		job.Type,
+		string(job.Payload),
+		job.Status,
+		job.MaxAttempts,
+		job.UniqueKey,
+		job.RunAt,
+		job.CreatedAt,
+		job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return nil, nil
+	}
+
+	return job, nil
+}
+
+// claim locks the next due job of one of the given types, or returns nil if there is none
+func (q *Queue) claim(ctx context.Context, types []string, workerID string) (*Job, error) {
+	var job Job
+	query := `
+		UPDATE jobs
+		SET status = $1, attempts = attempts + 1, locked_at = $2, locked_by = $3, updated_at = $2
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = $4 AND run_at <= $2 AND type = ANY($5)
+			ORDER BY run_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	err := q.db.GetContext(ctx, &job, query, StatusRunning, time.Now().UTC(), workerID, StatusPending, pq.Array(types))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// complete marks a job as succeeded
+func (q *Queue) complete(ctx context.Context, job *Job) error {
+	now := time.Now().UTC()
+	query := `
+		UPDATE jobs
+		SET status = $1, locked_at = NULL, locked_by = NULL, last_error = NULL, completed_at = $2, updated_at = $2
+		WHERE id = $3
+	`
+
+	if _, err := q.db.ExecContext(ctx, query, StatusSucceeded, now, job.ID); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+
+	return nil
+}
+
+// fail schedules a retry with exponential backoff, or marks the job dead once
+// its attempts are exhausted
+func (q *Queue) fail(ctx context.Context, job *Job, jobErr error) error {
+	now := time.Now().UTC()
+	status := StatusPending
+	if job.Attempts >= job.MaxAttempts {
+		status = StatusDead
+	}
+
+	query := `
+		UPDATE jobs
+		SET status = $1, run_at = $2, locked_at = NULL, locked_by = NULL, last_error = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	if _, err := q.db.ExecContext(ctx, query, status, now.Add(backoff(job.Attempts)), jobErr.Error(), now, job.ID); err != nil {
+		return fmt.Errorf("failed to record job failure: %w", err)
+	}
+
+	return nil
+}
+
+// releaseStale returns jobs locked for longer than timeout (e.g. by a crashed
+// process) to the queue, or marks them dead if they have no attempts left
+func (q *Queue) releaseStale(ctx context.Context, timeout time.Duration) (int64, error) {
+	now := time.Now().UTC()
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN $1 ELSE $2 END,
+		    locked_at = NULL, locked_by = NULL, last_error = $3, updated_at = $4
+		WHERE status = $5 AND locked_at < $6
+	`
+
+	result, err := q.db.ExecContext(ctx, query, StatusDead, StatusPending, "job timed out", now, StatusRunning, now.Add(-timeout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale jobs: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// pruneSucceeded deletes succeeded jobs completed before the given time
+func (q *Queue) pruneSucceeded(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM jobs WHERE status = $1 AND completed_at < $2`, StatusSucceeded, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune jobs: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// Get retrieves a job by ID
+func (q *Queue) Get(ctx context.Context, id uuid.UUID) (*Job, error) {
+	var job Job
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	if err := q.db.GetContext(ctx, &job, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// List retrieves the most recently updated jobs, optionally filtered by status and type
+func (q *Queue) List(ctx context.Context, status, jobType string, limit int) ([]Job, error) {
+	jobs := []Job{}
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE ($1::text = '' OR status = $1) AND ($2::text = '' OR type = $2)
+		ORDER BY updated_at DESC
+		LIMIT $3
+	`
+
+	if err := q.db.SelectContext(ctx, &jobs, query, status, jobType, limit); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// Requeue resets a dead job so it runs again immediately with fresh attempts
+func (q *Queue) Requeue(ctx context.Context, id uuid.UUID) (*Job, error) {
+	now := time.Now().UTC()
+	query := `
+		UPDATE jobs
+		SET status = $1, attempts = 0, run_at = $2, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := q.db.ExecContext(ctx, query, StatusPending, now, id, StatusDead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue job: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		// Distinguish a missing job from one that is not dead
+		if _, err := q.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("only dead jobs can be requeued")
+	}
+
+	return q.Get(ctx, id)
+}
@@ -0,0 +1,227 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"pocketploy/internal/cron"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// lockTimeout bounds how long a handler may run before its job is released
+	lockTimeout = 15 * time.Minute
+
+	// maintenanceInterval is how often stale jobs are released and old ones pruned
+	maintenanceInterval = time.Minute
+
+	// succeededRetention is how long succeeded jobs are kept for inspection
+	succeededRetention = 7 * 24 * time.Hour
+)
+
+// Handler processes a job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+// recurring is a job enqueued on a cron schedule
+type recurring struct {
+	jobType  string
+	schedule *cron.Schedule
+	next     time.Time
+}
+
+// Pool runs registered job handlers with a fixed number of workers
+type Pool struct {
+	queue        *Queue
+	locker       *Locker
+	workers      int
+	pollInterval time.Duration
+	id           string
+
+	mu        sync.Mutex
+	handlers  map[string]Handler
+	recurring []*recurring
+}
+
+// NewPool creates a worker pool that polls the queue every pollInterval
+func NewPool(queue *Queue, workers int, pollInterval time.Duration) *Pool {
+	hostname, _ := os.Hostname()
+
+	return &Pool{
+		queue:        queue,
+		locker:       NewLocker(queue.db),
+		workers:      workers,
+		pollInterval: pollInterval,
+		id:           fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		handlers:     make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for a job type; it must be called before Run
+func (p *Pool) Register(jobType string, handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers[jobType] = handler
+}
+
+// Every enqueues a job of the given type on a cron schedule (UTC). Each run is
+// deduplicated by its scheduled time, so several processes can share a schedule.
+func (p *Pool) Every(spec, jobType string) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recurring = append(p.recurring, &recurring{
+		jobType:  jobType,
+		schedule: schedule,
+		next:     schedule.Next(time.Now()),
+	})
+
+	return nil
+}
+
+// Run processes jobs until ctx is cancelled, then waits for running handlers
+func (p *Pool) Run(ctx context.Context) {
+	if p.workers <= 0 || p.pollInterval <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	types := make([]string, 0, len(p.handlers))
+	for jobType := range p.handlers {
+		types = append(types, jobType)
+	}
+	p.mu.Unlock()
+
+	if len(types) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			p.work(ctx, types, fmt.Sprintf("%s/%d", p.id, n))
+		}(i)
+	}
+
+	p.maintain(ctx)
+	wg.Wait()
+}
+
+// work claims and runs jobs, sleeping for the poll interval when the queue is empty
+func (p *Pool) work(ctx context.Context, types []string, workerID string) {
+	for {
+		job, err := p.queue.claim(ctx, types, workerID)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		if job != nil {
+			p.process(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.pollInterval):
+		}
+	}
+}
+
+// process runs a job's handler and records the outcome. Outcomes are stored
+// even during shutdown so interrupted jobs are retried rather than left locked.
+func (p *Pool) process(ctx context.Context, job *Job) {
+	p.mu.Lock()
+	handler := p.handlers[job.Type]
+	p.mu.Unlock()
+
+	jobCtx, cancel := context.WithTimeout(ctx, lockTimeout)
+	err := p.safeRun(jobCtx, handler, job)
+	cancel()
+
+	recordCtx := context.Background()
+	if err != nil {
+		log.Printf("Job %s (%s) attempt %d/%d failed: %v", job.ID, job.Type, job.Attempts, job.MaxAttempts, err)
+		if err := p.queue.fail(recordCtx, job, err); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+
+	if err := p.queue.complete(recordCtx, job); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// safeRun turns a handler panic into a job failure
+func (p *Pool) safeRun(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// maintain enqueues recurring jobs and cleans up the queue until ctx is cancelled
+func (p *Pool) maintain(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.enqueueRecurring(ctx, now)
+
+			if now.Sub(lastCleanup) >= maintenanceInterval {
+				lastCleanup = now
+				_, err := p.locker.RunExclusive(ctx, "jobs-maintenance", func(ctx context.Context) error {
+					if _, err := p.queue.releaseStale(ctx, lockTimeout); err != nil {
+						return err
+					}
+					_, err := p.queue.pruneSucceeded(ctx, now.UTC().Add(-succeededRetention))
+					return err
+				})
+				if err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (p *Pool) enqueueRecurring(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, r := range p.recurring {
+		if r.next.IsZero() || now.Before(r.next) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s@%s", r.jobType, r.next.UTC().Format(time.RFC3339))
+		if _, err := p.queue.Enqueue(ctx, r.jobType, struct{}{}, EnqueueOptions{RunAt: r.next, UniqueKey: key}); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+
+		r.next = r.schedule.Next(now)
+	}
+}
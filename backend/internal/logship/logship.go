@@ -0,0 +1,191 @@
+// Package logship copies container logs somewhere that outlives the
+// container: an append-only file in the instance's data directory or Loki.
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"pocketploy/internal/datadir"
+	"pocketploy/internal/docker"
+)
+
+// Shippers
+const (
+	ShipperFile = "file"
+	ShipperLoki = "loki"
+)
+
+// Stream identifies the instance a batch of log entries belongs to
+type Stream struct {
+	InstanceID string
+	Subdomain  string
+	DataPath   string
+}
+
+// Sink stores shipped log entries
+type Sink interface {
+	Ship(ctx context.Context, stream Stream, entries []docker.LogEntry) error
+}
+
+// Config selects and configures the sink
+type Config struct {
+	Shipper     string // "file", "loki" or empty to disable shipping
+	LokiURL     string
+	FileMaxSize int64 // size at which a log file is rotated (0 never rotates)
+}
+
+// NewSink creates the configured sink, or nil when shipping is disabled
+func NewSink(cfg Config) (Sink, error) {
+	switch cfg.Shipper {
+	case "":
+		return nil, nil
+	case ShipperFile:
+		return &FileSink{maxSize: cfg.FileMaxSize}, nil
+	case ShipperLoki:
+		if cfg.LokiURL == "" {
+			return nil, fmt.Errorf("a Loki URL is required")
+		}
+		return &LokiSink{
+			pushURL:    strings.TrimRight(cfg.LokiURL, "/") + "/loki/api/v1/push",
+			httpClient: &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown log shipper %q", cfg.Shipper)
+}
+
+// FileSink appends entries to logs/container.log in the instance's data
+// directory, so they are kept (and removed) together with the instance data.
+// A file over the size limit is renamed to container.log.1, replacing the
+// previous one. The instance can write to the directory too, so neither it
+// nor the files are followed if they are symlinks.
+type FileSink struct {
+	maxSize int64
+}
+
+const (
+	logDir     = "logs"
+	logFile    = "container.log"
+	rotatedLog = logFile + ".1"
+)
+
+// LogFile returns the path of an instance's shipped log file
+func LogFile(dataPath string) string {
+	return filepath.Join(dataPath, logDir, logFile)
+}
+
+// Ship appends the entries to the instance's log file
+func (s *FileSink) Ship(ctx context.Context, stream Stream, entries []docker.LogEntry) error {
+	dir, err := datadir.OpenDir(stream.DataPath, logDir, true)
+	if err != nil {
+		return fmt.Errorf("failed to open log directory: %w", err)
+	}
+	defer dir.Close()
+
+	if s.maxSize > 0 {
+		if current, err := dir.Open(logFile); err == nil {
+			info, err := current.Stat()
+			current.Close()
+			if err == nil && info.Size() >= s.maxSize {
+				if err := dir.Rename(logFile, rotatedLog); err != nil {
+					return fmt.Errorf("failed to rotate log file: %w", err)
+				}
+			}
+		}
+	}
+
+	file, err := dir.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.WriteString(entry.String())
+		buf.WriteByte('\n')
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write log file: %w", err)
+	}
+
+	return file.Close()
+}
+
+// LokiSink pushes entries to Loki, labelled with the instance, stream and level
+type LokiSink struct {
+	pushURL    string
+	httpClient *http.Client
+}
+
+type lokiStream struct {
+	Labels map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Ship pushes the entries to Loki
+func (s *LokiSink) Ship(ctx context.Context, stream Stream, entries []docker.LogEntry) error {
+	streams := make(map[string]*lokiStream)
+	var order []string
+	for _, entry := range entries {
+		key := entry.Stream + "/" + entry.Level
+		ls, ok := streams[key]
+		if !ok {
+			ls = &lokiStream{Labels: map[string]string{
+				"service":     "pocketploy",
+				"instance_id": stream.InstanceID,
+				"subdomain":   stream.Subdomain,
+				"stream":      entry.Stream,
+				"level":       entry.Level,
+			}}
+			streams[key] = ls
+			order = append(order, key)
+		}
+
+		timestamp := time.Now()
+		if entry.Timestamp != nil {
+			timestamp = *entry.Timestamp
+		}
+		ls.Values = append(ls.Values, [2]string{strconv.FormatInt(timestamp.UnixNano(), 10), entry.Message})
+	}
+
+	body := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		body.Streams = append(body.Streams, streams[key])
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode logs: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.pushURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build Loki request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push logs to Loki: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("loki responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
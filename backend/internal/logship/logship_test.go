@@ -0,0 +1,70 @@
+package logship
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pocketploy/internal/docker"
+)
+
+var testEntries = []docker.LogEntry{{Stream: "stdout", Message: "written by the instance"}}
+
+func TestFileSinkShips(t *testing.T) {
+	dataPath := t.TempDir()
+	sink := &FileSink{maxSize: 1}
+
+	for i := 0; i < 2; i++ {
+		if err := sink.Ship(context.Background(), Stream{DataPath: dataPath}, testEntries); err != nil {
+			t.Fatalf("Ship() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{logFile, rotatedLog} {
+		data, err := os.ReadFile(filepath.Join(dataPath, logDir, name))
+		if err != nil || string(data) != "written by the instance\n" {
+			t.Errorf("%s = %q (%v), want one entry", name, data, err)
+		}
+	}
+}
+
+func TestFileSinkDoesNotFollowSymlinks(t *testing.T) {
+	tests := []struct {
+		name string
+		link string // path in the data directory that points at the host file
+	}{
+		{"log directory", logDir},
+		{"log file", filepath.Join(logDir, logFile)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataPath, outside := t.TempDir(), t.TempDir()
+			hostFile := filepath.Join(outside, logFile)
+			if err := os.WriteFile(hostFile, []byte("host\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(dataPath, logDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			target := hostFile
+			if tt.link == logDir {
+				target = outside
+				os.Remove(filepath.Join(dataPath, logDir))
+			}
+			if err := os.Symlink(target, filepath.Join(dataPath, tt.link)); err != nil {
+				t.Fatal(err)
+			}
+
+			sink := &FileSink{}
+			if err := sink.Ship(context.Background(), Stream{DataPath: dataPath}, testEntries); err == nil {
+				t.Fatal("Ship() wrote through a symlink")
+			}
+			if data, _ := os.ReadFile(hostFile); string(data) != "host\n" {
+				t.Errorf("host file = %q, want it unchanged", data)
+			}
+		})
+	}
+}
@@ -15,15 +15,29 @@ type contextKey string
 const UserIDKey contextKey = "user_id"
 const UserClaimsKey contextKey = "user_claims"
 
-// Auth middleware validates JWT token and adds user ID to context
-func Auth(cfg *config.Config) func(http.Handler) http.Handler {
+// TokenChecker reports whether a validated access token has been revoked and
+// audits requests made with impersonation tokens
+type TokenChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, claims *utils.Claims) bool
+	RecordImpersonatedRequest(r *http.Request, claims *utils.Claims, status int)
+}
+
+// Auth middleware validates JWT token and adds user ID to context. Requests
+// made by an admin impersonating the user are recorded in the audit log.
+func Auth(cfg *config.Config, tokens TokenChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				respondWithError(w, http.StatusUnauthorized, "Authorization header required")
-				return
+				// Browsers can't set headers on WebSocket handshakes, so the
+				// token may be offered as a subprotocol instead: "bearer, <token>"
+				if token, ok := WebSocketToken(r); ok {
+					authHeader = "Bearer " + token
+				} else {
+					respondWithError(w, http.StatusUnauthorized, "Authorization header required")
+					return
+				}
 			}
 
 			// Check if it's a Bearer token
@@ -42,10 +56,71 @@ func Auth(cfg *config.Config) func(http.Handler) http.Handler {
 				return
 			}
 
+			// Reject tokens revoked by logout or a revoke-all (e.g. password change)
+			if tokens.IsAccessTokenRevoked(r.Context(), claims) {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+
+			setLogUserID(r, claims.UserID)
+
 			// Add user ID and full claims to context
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, UserClaimsKey, claims)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			r = r.WithContext(ctx)
+
+			if !claims.Impersonated() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+			tokens.RecordImpersonatedRequest(r, claims, wrapped.statusCode)
+		})
+	}
+}
+
+// WebSocketToken extracts an access token sent in the Sec-WebSocket-Protocol
+// header as "bearer, <token>"
+func WebSocketToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Sec-WebSocket-Protocol")
+	if header == "" {
+		return "", false
+	}
+
+	parts := strings.Split(header, ",")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) != "bearer" {
+		return "", false
+	}
+
+	token := strings.TrimSpace(parts[1])
+	return token, token != ""
+}
+
+// AdminChecker reports whether a user is a platform administrator
+type AdminChecker interface {
+	IsAdmin(userID string) (bool, error)
+}
+
+// RequireAdmin middleware only lets platform administrators through.
+// It must run after Auth so the user ID is in the context.
+func RequireAdmin(checker AdminChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r)
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+				return
+			}
+
+			isAdmin, err := checker.IsAdmin(userID)
+			if err != nil || !isAdmin {
+				respondWithError(w, http.StatusForbidden, "Admin access required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
 		})
 	}
 }
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"pocketploy/internal/models"
+)
+
+const DeployTokenKey contextKey = "deploy_token"
+
+// DeployTokenVerifier resolves the deploy tokens CI pipelines authenticate with
+type DeployTokenVerifier interface {
+	VerifyDeployToken(ctx context.Context, value string) (*models.DeployToken, error)
+}
+
+// DeployAuth middleware authenticates requests with a deploy token
+// ("Authorization: Bearer ppd_...") and adds it to the context. The token's
+// creator is set as the user, so the usual instance permissions still apply.
+func DeployAuth(tokens DeployTokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || value == "" {
+				respondWithError(w, http.StatusUnauthorized, "Deploy token required")
+				return
+			}
+
+			token, err := tokens.VerifyDeployToken(r.Context(), value)
+			if err != nil {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or expired deploy token")
+				return
+			}
+
+			setLogUserID(r, token.UserID.String())
+
+			ctx := context.WithValue(r.Context(), UserIDKey, token.UserID.String())
+			ctx = context.WithValue(ctx, DeployTokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetDeployToken extracts the deploy token from request context
+func GetDeployToken(r *http.Request) (*models.DeployToken, bool) {
+	token, ok := r.Context().Value(DeployTokenKey).(*models.DeployToken)
+	return token, ok
+}
@@ -1,11 +1,40 @@
 package middleware
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"os"
+	"strings"
 	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/utils"
 )
 
+// RequestIDKey holds the request ID, also returned in the X-Request-ID header
+const RequestIDKey contextKey = "request_id"
+
+// requestLogKey holds the *requestLog of the current request
+const requestLogKey contextKey = "request_log"
+
+const requestIDHeader = "X-Request-ID"
+
+// maxLoggedBody is the largest request body logged at debug level
+const maxLoggedBody = 8 << 10
+
+// redactedHeaders carry credentials and are never logged
+var redactedHeaders = map[string]bool{
+	"Authorization":          true,
+	"Cookie":                 true,
+	"X-Csrf-Token":           true,
+	"Sec-Websocket-Protocol": true, // may carry the access token
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -17,29 +46,191 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Logging middleware logs all HTTP requests
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+// Unwrap exposes the underlying writer so http.ResponseController can
+// reach Hijack and SetWriteDeadline (e.g. for WebSocket upgrades)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// requestLog collects fields known only to inner handlers, which see a
+// derived request whose context the logging middleware cannot read
+type requestLog struct {
+	userID string
+}
+
+// Logging middleware logs every HTTP request as a structured line with its
+// method, path, status, latency, client IP, request ID and user ID. Level and
+// sampling follow REQUEST_LOG_LEVEL and REQUEST_LOG_SAMPLE_RATE, which are
+// read per request so they can be changed on reload.
+func Logging(cfg *config.Config) func(http.Handler) http.Handler {
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	if cfg.RequestLogFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, nil)
+	}
+	logger := slog.New(handler)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			settings := cfg.Settings()
+
+			// Keep the ID assigned by a proxy in front of us, so log lines can be correlated
+			requestID := r.Header.Get(requestIDHeader)
+			if !validRequestID(requestID) {
+				requestID, _ = utils.GenerateRandomString(20)
+			}
+			w.Header().Set(requestIDHeader, requestID)
+
+			entry := &requestLog{}
+			ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+			ctx = context.WithValue(ctx, requestLogKey, entry)
+			r = r.WithContext(ctx)
+
+			var body []byte
+			if settings.RequestLogLevel == "debug" {
+				body = peekBody(r)
+			}
+
+			// Wrap response writer to capture status code
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			// Call next handler
+			next.ServeHTTP(wrapped, r)
+
+			if !shouldLog(settings, wrapped.statusCode) {
+				return
+			}
+
+			// Log request details
+			duration := time.Since(start)
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", wrapped.statusCode),
+				slog.Float64("latency_ms", float64(duration.Microseconds())/1000),
+				slog.String("client_ip", utils.ClientIP(r)),
+				slog.String("request_id", requestID),
+			}
+			if entry.userID != "" {
+				attrs = append(attrs, slog.String("user_id", entry.userID))
+			}
+			if settings.RequestLogLevel == "debug" {
+				attrs = append(attrs, slog.Any("headers", redactHeaders(r.Header)))
+				if body != nil {
+					attrs = append(attrs, slog.Any("body", body))
+				}
+			}
+
+			level := slog.LevelInfo
+			if wrapped.statusCode >= 500 {
+				level = slog.LevelError
+			} else if wrapped.statusCode >= 400 {
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
 
-		// Wrap response writer to capture status code
-		wrapped := &responseWriter{
-			ResponseWriter: w,
-			statusCode:     http.StatusOK,
+// GetRequestID returns the ID of the current request
+func GetRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(RequestIDKey).(string)
+	return requestID
+}
+
+// setLogUserID records the authenticated user in the request's log line
+func setLogUserID(r *http.Request, userID string) {
+	if entry, ok := r.Context().Value(requestLogKey).(*requestLog); ok {
+		entry.userID = userID
+	}
+}
+
+// shouldLog applies the log level and sampling to a finished request
+func shouldLog(settings *config.Settings, status int) bool {
+	switch settings.RequestLogLevel {
+	case "none":
+		return false
+	case "errors":
+		return status >= 400
+	}
+
+	return status >= 400 || rand.Float64() < settings.RequestLogSampleRate
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		if redactedHeaders[name] {
+			redacted[name] = "[REDACTED]"
+			continue
 		}
+		redacted[name] = strings.Join(values, ", ")
+	}
+	return redacted
+}
+
+// peekBody returns the request's JSON body with credential fields redacted,
+// leaving the body readable for the handler. Bodies that are not JSON, or too
+// large to log, are not returned.
+func peekBody(r *http.Request) json.RawMessage {
+	if r.Body == nil || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxLoggedBody+1))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if err != nil || len(buf) > maxLoggedBody {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(buf, &value); err != nil {
+		return nil
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// redactValue replaces the values of password, token and secret fields
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			name := strings.ToLower(key)
+			if strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret") {
+				v[key] = "[REDACTED]"
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
 
-		// Call next handler
-		next.ServeHTTP(wrapped, r)
-
-		// Log request details
-		duration := time.Since(start)
-		log.Printf(
-			"[%s] %s %s - %d - %v",
-			r.Method,
-			r.RequestURI,
-			r.RemoteAddr,
-			wrapped.statusCode,
-			duration,
-		)
-	})
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// MaintenanceChecker reports whether the platform is in maintenance mode
+type MaintenanceChecker interface {
+	MaintenanceMode() (bool, string)
+}
+
+// Maintenance middleware rejects mutating requests with 503 while maintenance
+// mode is enabled. Read-only requests are always let through.
+func Maintenance(checker MaintenanceChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if enabled, message := checker.MaintenanceMode(); enabled {
+				w.Header().Set("Retry-After", "300")
+				respondWithError(w, http.StatusServiceUnavailable, message)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
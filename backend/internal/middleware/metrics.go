@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pocketploy/internal/metrics"
+)
+
+// Metrics middleware records request counts and latencies per route.
+// It must be installed with Router.Use so the matched route template is known.
+func Metrics(registry *metrics.Registry) mux.MiddlewareFunc {
+	requests := registry.NewCounterVec(
+		"pocketploy_http_requests_total",
+		"Number of HTTP requests handled.",
+		"method", "route", "status",
+	)
+	duration := registry.NewHistogramVec(
+		"pocketploy_http_request_duration_seconds",
+		"Duration of HTTP requests in seconds.",
+		metrics.DefaultBuckets,
+		"method", "route",
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			// Wrap response writer to capture status code
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r)
+
+			// Use the route template to keep label cardinality bounded
+			route := "unmatched"
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+
+			requests.Inc(r.Method, route, strconv.Itoa(wrapped.statusCode))
+			duration.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
+
+// MetricsAuth middleware only lets requests with "Authorization: Bearer
+// <token>" through, so the metrics don't leak to the public
+func MetricsAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(value), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				respondWithError(w, http.StatusUnauthorized, "Metrics token required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/utils"
+)
+
+// RateLimit middleware allows at most limit requests per client IP within each
+// fixed window. Counters live in the given store so limits are shared across
+// backend processes when Redis is configured. Store errors fail open.
+func RateLimit(store cache.Store, name string, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := "ratelimit:" + name + ":" + utils.ClientIP(r)
+			count, err := store.Incr(r.Context(), key, window)
+			if err != nil {
+				log.Printf("Warning: rate limit store unavailable: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if count > int64(limit) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(window.Seconds())))
+				respondWithError(w, http.StatusTooManyRequests, "Too many requests, please try again later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Abuse report statuses
+const (
+	AbuseReportOpen      = "open"
+	AbuseReportActioned  = "actioned"  // the instance was taken down
+	AbuseReportDismissed = "dismissed" // no action was needed
+)
+
+// AbuseCategories are the kinds of abuse that can be reported
+var AbuseCategories = []string{"phishing", "malware", "spam", "illegal_content", "copyright", "other"}
+
+// ErrAbuseReportNotFound is returned for unknown report IDs
+var ErrAbuseReportNotFound = errors.New("abuse report not found")
+
+// ErrAbuseReportResolved is returned when reviewing a report that is no longer open
+var ErrAbuseReportResolved = errors.New("abuse report was already resolved")
+
+// AbuseReport is a report about an instance served on a subdomain
+type AbuseReport struct {
+	ID             string     `db:"id" json:"id"`
+	Subdomain      string     `db:"subdomain" json:"subdomain"`
+	InstanceID     *uuid.UUID `db:"instance_id" json:"instance_id,omitempty"`
+	Category       string     `db:"category" json:"category"`
+	Description    string     `db:"description" json:"description"`
+	ReporterEmail  *string    `db:"reporter_email" json:"reporter_email,omitempty"`
+	ReporterIP     string     `db:"reporter_ip" json:"reporter_ip"`
+	Status         string     `db:"status" json:"status"`
+	ResolutionNote *string    `db:"resolution_note" json:"resolution_note,omitempty"`
+	ReviewedBy     *string    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateAbuseReportRequest represents the body of POST /api/v1/abuse-reports
+type CreateAbuseReportRequest struct {
+	Subdomain     string `json:"subdomain" validate:"required,max=255"`
+	Category      string `json:"category" validate:"required,oneof=phishing malware spam illegal_content copyright other"`
+	Description   string `json:"description" validate:"required,min=10,max=5000"`
+	ReporterEmail string `json:"reporter_email,omitempty" validate:"omitempty,email,max=255"`
+	CaptchaToken  string `json:"captcha_token,omitempty"`
+}
+
+// CreateAbuseReport stores a new report
+func CreateAbuseReport(ctx context.Context, db *sqlx.DB, report *AbuseReport) error {
+	query := `
+		INSERT INTO abuse_reports (subdomain, instance_id, category, description, reporter_email, reporter_ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at
+	`
+	err := db.QueryRowxContext(ctx, query,
+		report.Subdomain,
+		report.InstanceID,
+		report.Category,
+		report.Description,
+		report.ReporterEmail,
+		report.ReporterIP,
+	).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create abuse report: %w", err)
+	}
+
+	return nil
+}
+
+// FindAbuseReportByID returns a report
+func FindAbuseReportByID(ctx context.Context, db *sqlx.DB, id string) (*AbuseReport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrAbuseReportNotFound
+	}
+
+	var report AbuseReport
+	if err := db.GetContext(ctx, &report, `SELECT * FROM abuse_reports WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAbuseReportNotFound
+		}
+		return nil, fmt.Errorf("failed to find abuse report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// ListAbuseReports returns reports with a status (all when empty), oldest
+// first so the review queue is worked in order
+func ListAbuseReports(ctx context.Context, db *sqlx.DB, status string, limit int) ([]AbuseReport, error) {
+	reports := []AbuseReport{}
+	query := `
+		SELECT * FROM abuse_reports
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at ASC
+		LIMIT $2
+	`
+	if err := db.SelectContext(ctx, &reports, query, status, limit); err != nil {
+		return nil, fmt.Errorf("failed to list abuse reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// CountRecentAbuseReports counts the reports about a subdomain since a time
+func CountRecentAbuseReports(ctx context.Context, db *sqlx.DB, subdomain string, since time.Time) (int, error) {
+	var count int
+	err := db.GetContext(ctx, &count, `SELECT COUNT(*) FROM abuse_reports WHERE subdomain = $1 AND created_at >= $2`, subdomain, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count abuse reports: %w", err)
+	}
+	return count, nil
+}
+
+// ResolveAbuseReport closes an open report with a status and note
+func ResolveAbuseReport(ctx context.Context, db *sqlx.DB, report *AbuseReport, status, reviewerID, note string) error {
+	var resolutionNote *string
+	if note != "" {
+		resolutionNote = &note
+	}
+
+	query := `
+		UPDATE abuse_reports
+		SET status = $1, resolution_note = $2, reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $4 AND status = $5
+		RETURNING reviewed_at
+	`
+	err := db.QueryRowxContext(ctx, query, status, resolutionNote, reviewerID, report.ID, AbuseReportOpen).Scan(&report.ReviewedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAbuseReportResolved
+		}
+		return fmt.Errorf("failed to resolve abuse report: %w", err)
+	}
+
+	report.Status = status
+	report.ResolutionNote = resolutionNote
+	report.ReviewedBy = &reviewerID
+
+	return nil
+}
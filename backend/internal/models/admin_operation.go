@@ -0,0 +1,153 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Destructive admin operations that need a second admin's approval
+const (
+	AdminOperationDeleteUser   = "user.delete"   // target is a user
+	AdminOperationPurgeArchive = "archive.purge" // target is an archived instance
+)
+
+// Admin operation statuses
+const (
+	AdminOperationPending  = "pending"
+	AdminOperationApproved = "approved" // approved and carried out
+	AdminOperationRejected = "rejected"
+	AdminOperationExpired  = "expired"
+	AdminOperationFailed   = "failed" // approved, but carrying it out failed
+)
+
+// ErrAdminOperationNotFound is returned for unknown operation IDs
+var ErrAdminOperationNotFound = errors.New("admin operation not found")
+
+// ErrAdminOperationDecided is returned when deciding an operation that is no
+// longer pending (or expired in the meantime)
+var ErrAdminOperationDecided = errors.New("admin operation is no longer pending")
+
+// ErrAdminOperationExists is returned when the target already has a pending
+// operation of the same kind
+var ErrAdminOperationExists = errors.New("an operation on this target is already pending")
+
+// AdminOperation is a destructive operation requested by one admin
+type AdminOperation struct {
+	ID          string     `db:"id" json:"id"`
+	Kind        string     `db:"kind" json:"kind"`
+	TargetID    uuid.UUID  `db:"target_id" json:"target_id"`
+	Reason      string     `db:"reason" json:"reason"`
+	Status      string     `db:"status" json:"status"`
+	RequestedBy *string    `db:"requested_by" json:"requested_by,omitempty"`
+	DecidedBy   *string    `db:"decided_by" json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `db:"decided_at" json:"decided_at,omitempty"`
+	Error       *string    `db:"error" json:"error,omitempty"`
+	ExpiresAt   time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateAdminOperation stores a pending operation
+func CreateAdminOperation(ctx context.Context, db *sqlx.DB, op *AdminOperation) error {
+	query := `
+		INSERT INTO admin_operations (kind, target_id, reason, requested_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+	err := db.QueryRowxContext(ctx, query, op.Kind, op.TargetID, op.Reason, op.RequestedBy, op.ExpiresAt).
+		Scan(&op.ID, &op.Status, &op.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrAdminOperationExists
+		}
+		return fmt.Errorf("failed to create admin operation: %w", err)
+	}
+
+	return nil
+}
+
+// FindAdminOperationByID returns an operation
+func FindAdminOperationByID(ctx context.Context, db *sqlx.DB, id string) (*AdminOperation, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrAdminOperationNotFound
+	}
+
+	var op AdminOperation
+	if err := db.GetContext(ctx, &op, `SELECT * FROM admin_operations WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAdminOperationNotFound
+		}
+		return nil, fmt.Errorf("failed to find admin operation: %w", err)
+	}
+
+	return &op, nil
+}
+
+// ListAdminOperations returns operations with a status (all when empty), newest first
+func ListAdminOperations(ctx context.Context, db *sqlx.DB, status string, limit int) ([]AdminOperation, error) {
+	ops := []AdminOperation{}
+	query := `
+		SELECT * FROM admin_operations
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	if err := db.SelectContext(ctx, &ops, query, status, limit); err != nil {
+		return nil, fmt.Errorf("failed to list admin operations: %w", err)
+	}
+
+	return ops, nil
+}
+
+// ExpireAdminOperations marks pending operations past their expiry as expired
+func ExpireAdminOperations(ctx context.Context, db *sqlx.DB) error {
+	query := `UPDATE admin_operations SET status = $1 WHERE status = $2 AND expires_at <= NOW()`
+	if _, err := db.ExecContext(ctx, query, AdminOperationExpired, AdminOperationPending); err != nil {
+		return fmt.Errorf("failed to expire admin operations: %w", err)
+	}
+	return nil
+}
+
+// DecideAdminOperation moves a pending, unexpired operation to status. Only
+// one decision wins when two admins decide at the same time.
+func DecideAdminOperation(ctx context.Context, db *sqlx.DB, op *AdminOperation, status, deciderID string) error {
+	query := `
+		UPDATE admin_operations
+		SET status = $1, decided_by = $2, decided_at = NOW()
+		WHERE id = $3 AND status = $4 AND expires_at > NOW()
+		RETURNING decided_at
+	`
+	err := db.QueryRowxContext(ctx, query, status, deciderID, op.ID, AdminOperationPending).Scan(&op.DecidedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAdminOperationDecided
+		}
+		return fmt.Errorf("failed to decide admin operation: %w", err)
+	}
+
+	op.Status = status
+	op.DecidedBy = &deciderID
+
+	return nil
+}
+
+// FailAdminOperation records that an approved operation could not be carried out
+func FailAdminOperation(ctx context.Context, db *sqlx.DB, op *AdminOperation, cause error) error {
+	message := cause.Error()
+	query := `UPDATE admin_operations SET status = $1, error = $2 WHERE id = $3`
+	if _, err := db.ExecContext(ctx, query, AdminOperationFailed, message, op.ID); err != nil {
+		return fmt.Errorf("failed to update admin operation: %w", err)
+	}
+
+	op.Status = AdminOperationFailed
+	op.Error = &message
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Audit log actions
+const (
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonationEnded   = "impersonation.ended"
+	AuditImpersonatedRequest  = "impersonation.request"
+	AuditAbuseTakedown        = "abuse.takedown"
+	AuditAbuseDismissed       = "abuse.dismissed"
+	AuditOperationRequested   = "admin_operation.requested"
+	AuditOperationApproved    = "admin_operation.approved"
+	AuditOperationRejected    = "admin_operation.rejected"
+	AuditLoginNewCountry      = "login.new_country"
+	AuditAnomalyThrottled     = "anomaly.throttled"
+	AuditAnomalyDismissed     = "anomaly.dismissed"
+	AuditAnomalySuspended     = "anomaly.suspended"
+)
+
+// AuditDetails are the action-specific fields of an audit entry
+type AuditDetails map[string]interface{}
+
+// Value stores the details as JSON (a string, as lib/pq would send []byte as bytea)
+func (d AuditDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads details stored as JSON
+func (d *AuditDetails) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, d)
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	case nil:
+		*d = AuditDetails{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into AuditDetails", src)
+	}
+}
+
+// AuditEntry records who did what to (or as) which account
+type AuditEntry struct {
+	ID        string       `db:"id" json:"id"`
+	ActorID   *string      `db:"actor_id" json:"actor_id,omitempty"`
+	UserID    *string      `db:"user_id" json:"user_id,omitempty"`
+	Action    string       `db:"action" json:"action"`
+	Details   AuditDetails `db:"details" json:"details"`
+	IPAddress string       `db:"ip_address" json:"ip_address"`
+	Country   string       `db:"country" json:"country,omitempty"` // from GeoIP, when configured
+	City      string       `db:"city" json:"city,omitempty"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
+// AuditFilter narrows ListAuditEntries; empty fields match everything
+type AuditFilter struct {
+	ActorID string
+	UserID  string
+	Action  string
+	Limit   int
+}
+
+// CreateAuditEntry appends an entry to the audit log
+func CreateAuditEntry(ctx context.Context, db *sqlx.DB, entry *AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor_id, user_id, action, details, ip_address, country, city)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := db.QueryRowxContext(ctx, query,
+		entry.ActorID,
+		entry.UserID,
+		entry.Action,
+		entry.Details,
+		entry.IPAddress,
+		entry.Country,
+		entry.City,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditEntries returns the newest entries matching the filter
+func ListAuditEntries(ctx context.Context, db *sqlx.DB, filter AuditFilter) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	query := `
+		SELECT * FROM audit_log
+		WHERE ($1 = '' OR actor_id::text = $1)
+		  AND ($2 = '' OR user_id::text = $2)
+		  AND ($3 = '' OR action = $3)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`
+	err := db.SelectContext(ctx, &entries, query, filter.ActorID, filter.UserID, filter.Action, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// StreamAuditEntries passes the entries created in [from, to) matching the
+// filter (its limit is ignored) to fn, oldest first
+func StreamAuditEntries(ctx context.Context, db *sqlx.DB, filter AuditFilter, from, to time.Time, fn func(*AuditEntry) error) error {
+	query := `
+		SELECT * FROM audit_log
+		WHERE ($1 = '' OR actor_id::text = $1)
+		  AND ($2 = '' OR user_id::text = $2)
+		  AND ($3 = '' OR action = $3)
+		  AND created_at >= $4 AND created_at < $5
+		ORDER BY created_at
+	`
+	err := streamRows(ctx, db, query, fn, filter.ActorID, filter.UserID, filter.Action, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to export audit entries: %w", err)
+	}
+
+	return nil
+}
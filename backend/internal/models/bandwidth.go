@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Bandwidth notice levels
+const (
+	BandwidthNoticeWarning  = "warning"
+	BandwidthNoticeExceeded = "exceeded"
+)
+
+// BandwidthUsage is a user's egress for a month together with their quota settings
+type BandwidthUsage struct {
+	UserID          string `db:"user_id"`
+	Plan            string `db:"plan"`
+	QuotaOverrideGB *int   `db:"bandwidth_quota_override_gb"`
+	UsedBytes       int64  `db:"used_bytes"`
+}
+
+// BandwidthMonth returns the first day of the UTC month containing t
+func BandwidthMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// FindMonthlyBandwidthUsage sums egress per user for the month starting at month.
+// Every user owning at least one instance is included, even without traffic, as
+// is every user with traffic in the month from instances deleted since.
+func FindMonthlyBandwidthUsage(ctx context.Context, db *sqlx.DB, month time.Time) ([]BandwidthUsage, error) {
+	var usage []BandwidthUsage
+	query := `
+		SELECT u.id AS user_id, u.plan, u.bandwidth_quota_override_gb,
+		       COALESCE(SUM(t.bytes_out), 0) AS used_bytes
+		FROM users u
+		LEFT JOIN instance_traffic_daily t
+		       ON t.user_id = u.id AND t.day >= $1 AND t.day < $2
+		WHERE t.user_id IS NOT NULL
+		   OR EXISTS (SELECT 1 FROM instances i WHERE i.user_id = u.id)
+		GROUP BY u.id, u.plan, u.bandwidth_quota_override_gb
+	`
+
+	err := db.SelectContext(ctx, &usage, query, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bandwidth usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetUserMonthlyBandwidth returns a user's egress in bytes for the month starting at month
+func GetUserMonthlyBandwidth(ctx context.Context, db *sqlx.DB, userID string, month time.Time) (int64, error) {
+	var used int64
+	query := `
+		SELECT COALESCE(SUM(t.bytes_out), 0)
+		FROM instance_traffic_daily t
+		WHERE t.user_id = $1 AND t.day >= $2 AND t.day < $3
+	`
+
+	err := db.GetContext(ctx, &used, query, userID, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get bandwidth usage: %w", err)
+	}
+
+	return used, nil
+}
+
+// RecordBandwidthNotice stores that a notice was sent and reports whether it is new
+func RecordBandwidthNotice(ctx context.Context, db *sqlx.DB, userID string, month time.Time, level string) (bool, error) {
+	query := `
+		INSERT INTO bandwidth_notices (user_id, month, level)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := db.ExecContext(ctx, query, userID, month, level)
+	if err != nil {
+		return false, fmt.Errorf("failed to record bandwidth notice: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
@@ -0,0 +1,265 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Credit ledger entry kinds
+const (
+	CreditKindGrant     = "grant"     // granted by an administrator (trials, refunds)
+	CreditKindPromo     = "promo"     // redeemed from a promo code
+	CreditKindDeduction = "deduction" // applied to an invoice
+)
+
+// Promo code redemption errors
+var (
+	ErrPromoCodeInvalid  = errors.New("promo code is invalid or expired")
+	ErrPromoCodeRedeemed = errors.New("promo code was already redeemed")
+)
+
+// CreditEntry is a movement on a user's credit balance
+type CreditEntry struct {
+	ID              int64     `db:"id" json:"id"`
+	UserID          string    `db:"user_id" json:"-"`
+	AmountCents     int64     `db:"amount_cents" json:"amount_cents"`
+	Kind            string    `db:"kind" json:"kind"`
+	Reason          *string   `db:"reason" json:"reason,omitempty"`
+	PromoCodeID     *string   `db:"promo_code_id" json:"-"`
+	Reference       *string   `db:"reference" json:"reference,omitempty"`
+	CreatedByUserID *string   `db:"created_by_user_id" json:"-"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
+
+// PromoCode is a code users redeem for account credit
+type PromoCode struct {
+	ID              string     `db:"id" json:"id"`
+	Code            string     `db:"code" json:"code"`
+	CreditCents     int64      `db:"credit_cents" json:"credit_cents"`
+	Note            *string    `db:"note" json:"note,omitempty"`
+	MaxRedemptions  int        `db:"max_redemptions" json:"max_redemptions"`
+	RedemptionCount int        `db:"redemption_count" json:"redemption_count"`
+	CreatedByUserID *string    `db:"created_by_user_id" json:"created_by_user_id,omitempty"`
+	ExpiresAt       *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt       *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreatePromoCodeRequest represents the request body for creating a promo code
+type CreatePromoCodeRequest struct {
+	Code           string `json:"code,omitempty" validate:"omitempty,min=4,max=64,alphanum_hyphen"`
+	CreditCents    int64  `json:"credit_cents" validate:"required,min=1"`
+	MaxRedemptions int    `json:"max_redemptions" validate:"omitempty,min=1,max=100000"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1"`
+	Note           string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// GrantCreditRequest represents the request body for granting credit to a user
+type GrantCreditRequest struct {
+	AmountCents int64  `json:"amount_cents" validate:"required,min=1"`
+	Reason      string `json:"reason" validate:"required,max=500"`
+}
+
+// GetCreditBalance returns a user's credit balance in cents
+func GetCreditBalance(ctx context.Context, db *sqlx.DB, userID string) (int64, error) {
+	var balance int64
+	query := `SELECT COALESCE(SUM(amount_cents), 0) FROM credit_ledger WHERE user_id = $1`
+	if err := db.GetContext(ctx, &balance, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to get credit balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// FindCreditEntries returns a user's most recent ledger entries, newest first
+func FindCreditEntries(ctx context.Context, db *sqlx.DB, userID string, limit int) ([]CreditEntry, error) {
+	entries := []CreditEntry{}
+	query := `
+		SELECT * FROM credit_ledger
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	if err := db.SelectContext(ctx, &entries, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("failed to find credit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CreateCreditEntry adds an entry to the ledger
+func CreateCreditEntry(ctx context.Context, db *sqlx.DB, entry *CreditEntry) error {
+	query := `
+		INSERT INTO credit_ledger (user_id, amount_cents, kind, reason, promo_code_id, reference, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := db.QueryRowxContext(ctx, query,
+		entry.UserID, entry.AmountCents, entry.Kind, entry.Reason, entry.PromoCodeID, entry.Reference, entry.CreatedByUserID,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record credit: %w", err)
+	}
+
+	return nil
+}
+
+// DeductCredit takes up to maxCents from a user's balance for the deduction
+// identified by reference and returns the entry (nil when the balance is
+// empty). A reference already deducted returns its existing entry.
+func DeductCredit(ctx context.Context, db *sqlx.DB, userID string, maxCents int64, reference, reason string) (*CreditEntry, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existing CreditEntry
+	err = tx.GetContext(ctx, &existing, `SELECT * FROM credit_ledger WHERE reference = $1`, reference)
+	if err == nil {
+		return &existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to find deduction: %w", err)
+	}
+
+	// Serialize deductions per user so two invoices cannot spend the same credit
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var balance int64
+	if err := tx.GetContext(ctx, &balance, `SELECT COALESCE(SUM(amount_cents), 0) FROM credit_ledger WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get credit balance: %w", err)
+	}
+	amount := min(balance, maxCents)
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	entry := &CreditEntry{UserID: userID, AmountCents: -amount, Kind: CreditKindDeduction, Reason: &reason, Reference: &reference}
+	query := `
+		INSERT INTO credit_ledger (user_id, amount_cents, kind, reason, reference)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRowxContext(ctx, query, userID, entry.AmountCents, entry.Kind, reason, reference).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record deduction: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit deduction: %w", err)
+	}
+	return entry, nil
+}
+
+// DeleteCreditEntry removes an entry (used to undo a deduction that could not be applied)
+func DeleteCreditEntry(ctx context.Context, db *sqlx.DB, id int64) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM credit_ledger WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete credit entry: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePromoCode stores a new promo code
+func CreatePromoCode(ctx context.Context, db *sqlx.DB, promo *PromoCode) error {
+	query := `
+		INSERT INTO promo_codes (id, code, credit_cents, note, max_redemptions, created_by_user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := db.ExecContext(ctx, query,
+		promo.ID, promo.Code, promo.CreditCents, promo.Note, promo.MaxRedemptions, promo.CreatedByUserID, promo.ExpiresAt, promo.CreatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("promo code already exists")
+		}
+		return fmt.Errorf("failed to create promo code: %w", err)
+	}
+
+	return nil
+}
+
+// ListPromoCodes returns all promo codes, newest first
+func ListPromoCodes(ctx context.Context, db *sqlx.DB) ([]PromoCode, error) {
+	promos := []PromoCode{}
+	if err := db.SelectContext(ctx, &promos, `SELECT * FROM promo_codes ORDER BY created_at DESC`); err != nil {
+		return nil, fmt.Errorf("failed to list promo codes: %w", err)
+	}
+
+	return promos, nil
+}
+
+// RevokePromoCode stops a promo code from being redeemed
+func RevokePromoCode(ctx context.Context, db *sqlx.DB, id string) error {
+	result, err := db.ExecContext(ctx, `UPDATE promo_codes SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke promo code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke promo code: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("promo code not found or already revoked")
+	}
+
+	return nil
+}
+
+// RedeemPromoCode credits a promo code to a user. Each user can redeem a code once.
+func RedeemPromoCode(ctx context.Context, db *sqlx.DB, userID, code string) (*CreditEntry, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var promo PromoCode
+	query := `
+		UPDATE promo_codes
+		SET redemption_count = redemption_count + 1
+		WHERE code = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND redemption_count < max_redemptions
+		RETURNING *
+	`
+	if err := tx.GetContext(ctx, &promo, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPromoCodeInvalid
+		}
+		return nil, fmt.Errorf("failed to redeem promo code: %w", err)
+	}
+
+	reason := "Promo code " + promo.Code
+	entry := &CreditEntry{UserID: userID, AmountCents: promo.CreditCents, Kind: CreditKindPromo, Reason: &reason, PromoCodeID: &promo.ID}
+	insert := `
+		INSERT INTO credit_ledger (user_id, amount_cents, kind, reason, promo_code_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRowxContext(ctx, insert, userID, entry.AmountCents, entry.Kind, reason, promo.ID).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrPromoCodeRedeemed
+		}
+		return nil, fmt.Errorf("failed to record credit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit redemption: %w", err)
+	}
+	return entry, nil
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Deploy token scopes
+const (
+	DeployScopeHooks   = "hooks"   // upload pb_hooks bundles
+	DeployScopePublic  = "public"  // upload pb_public bundles
+	DeployScopeMigrate = "migrate" // upload pb_migrations bundles and run them
+	DeployScopeRestart = "restart" // restart the instance
+	DeployScopePreview = "preview" // create and tear down preview environments of the instance
+)
+
+// DeployScopes lists every deploy token scope
+var DeployScopes = []string{DeployScopeHooks, DeployScopePublic, DeployScopeMigrate, DeployScopeRestart, DeployScopePreview}
+
+// ErrDeployTokenNotFound is returned for unknown, expired or foreign deploy tokens
+var ErrDeployTokenNotFound = errors.New("deploy token not found")
+
+// DeployToken lets a CI pipeline deploy to one instance on behalf of the user
+// who created it
+type DeployToken struct {
+	ID          string         `db:"id" json:"id"`
+	UserID      uuid.UUID      `db:"user_id" json:"user_id"`
+	InstanceID  uuid.UUID      `db:"instance_id" json:"instance_id"`
+	Name        string         `db:"name" json:"name"`
+	TokenHash   string         `db:"token_hash" json:"-"`
+	TokenPrefix string         `db:"token_prefix" json:"token_prefix"`
+	Scopes      pq.StringArray `db:"scopes" json:"scopes"`
+	LastUsedAt  *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	ExpiresAt   *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
+
+// HasScope reports whether the token allows a deploy operation
+func (t *DeployToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the token is past its expiry
+func (t *DeployToken) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// CreateDeployTokenRequest represents the body of POST /api/v1/instances/:id/deploy-tokens
+type CreateDeployTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=hooks public migrate restart preview"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=3650"`
+}
+
+// CreateDeployToken stores a new deploy token
+func CreateDeployToken(ctx context.Context, db *sqlx.DB, token *DeployToken) error {
+	query := `
+		INSERT INTO deploy_tokens (user_id, instance_id, name, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := db.QueryRowxContext(ctx, query,
+		token.UserID,
+		token.InstanceID,
+		token.Name,
+		token.TokenHash,
+		token.TokenPrefix,
+		token.Scopes,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create deploy token: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeployTokens returns an instance's deploy tokens, newest first
+func ListDeployTokens(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) ([]DeployToken, error) {
+	tokens := []DeployToken{}
+	query := `SELECT * FROM deploy_tokens WHERE instance_id = $1 ORDER BY created_at DESC`
+	if err := db.SelectContext(ctx, &tokens, query, instanceID); err != nil {
+		return nil, fmt.Errorf("failed to list deploy tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// CountDeployTokens counts an instance's deploy tokens
+func CountDeployTokens(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) (int, error) {
+	var count int
+	if err := db.GetContext(ctx, &count, `SELECT COUNT(*) FROM deploy_tokens WHERE instance_id = $1`, instanceID); err != nil {
+		return 0, fmt.Errorf("failed to count deploy tokens: %w", err)
+	}
+	return count, nil
+}
+
+// FindDeployTokenByHash looks up a deploy token by the hash of its value
+func FindDeployTokenByHash(ctx context.Context, db *sqlx.DB, hash string) (*DeployToken, error) {
+	var token DeployToken
+	if err := db.GetContext(ctx, &token, `SELECT * FROM deploy_tokens WHERE token_hash = $1`, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDeployTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to find deploy token: %w", err)
+	}
+	return &token, nil
+}
+
+// DeleteDeployToken revokes one of an instance's deploy tokens
+func DeleteDeployToken(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrDeployTokenNotFound
+	}
+
+	result, err := db.ExecContext(ctx, `DELETE FROM deploy_tokens WHERE id = $1 AND instance_id = $2`, id, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete deploy token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrDeployTokenNotFound
+	}
+	return nil
+}
+
+// DeleteDeployTokensByName revokes an instance's deploy tokens with a name
+func DeleteDeployTokensByName(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID, name string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM deploy_tokens WHERE instance_id = $1 AND name = $2`, instanceID, name); err != nil {
+		return fmt.Errorf("failed to delete deploy tokens: %w", err)
+	}
+	return nil
+}
+
+// TouchDeployToken records that a deploy token was used
+func TouchDeployToken(ctx context.Context, db *sqlx.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `UPDATE deploy_tokens SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to update deploy token: %w", err)
+	}
+	return nil
+}
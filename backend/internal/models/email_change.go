@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Errors returned while confirming an email change
+var (
+	ErrEmailChangeNotFound = errors.New("invalid or expired confirmation link")
+	ErrEmailTaken          = errors.New("email already exists")
+)
+
+// EmailChange is a change of address waiting for both confirmations
+type EmailChange struct {
+	UserID         string     `db:"user_id" json:"-"`
+	NewEmail       string     `db:"new_email" json:"new_email"`
+	OldTokenHash   string     `db:"old_token_hash" json:"-"`
+	NewTokenHash   string     `db:"new_token_hash" json:"-"`
+	OldConfirmedAt *time.Time `db:"old_confirmed_at" json:"old_confirmed_at,omitempty"`
+	NewConfirmedAt *time.Time `db:"new_confirmed_at" json:"new_confirmed_at,omitempty"`
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// SaveEmailChange stores a user's pending change, replacing any earlier one
+func SaveEmailChange(ctx context.Context, db *sqlx.DB, change *EmailChange) error {
+	query := `
+		INSERT INTO email_changes (user_id, new_email, old_token_hash, new_token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET new_email = EXCLUDED.new_email,
+		    old_token_hash = EXCLUDED.old_token_hash,
+		    new_token_hash = EXCLUDED.new_token_hash,
+		    old_confirmed_at = NULL,
+		    new_confirmed_at = NULL,
+		    expires_at = EXCLUDED.expires_at,
+		    created_at = NOW()
+		RETURNING created_at
+	`
+	err := db.QueryRowxContext(ctx, query,
+		change.UserID,
+		change.NewEmail,
+		change.OldTokenHash,
+		change.NewTokenHash,
+		change.ExpiresAt,
+	).Scan(&change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save email change: %w", err)
+	}
+
+	return nil
+}
+
+// GetEmailChange returns a user's unexpired pending change, or nil
+func GetEmailChange(ctx context.Context, db *sqlx.DB, userID string, now time.Time) (*EmailChange, error) {
+	var change EmailChange
+	err := db.GetContext(ctx, &change, `SELECT * FROM email_changes WHERE user_id = $1 AND expires_at > $2`, userID, now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get email change: %w", err)
+	}
+
+	return &change, nil
+}
+
+// DeleteEmailChange cancels a user's pending change
+func DeleteEmailChange(ctx context.Context, db *sqlx.DB, userID string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete email change: %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange records the confirmation behind tokenHash. Once both
+// addresses have confirmed, the user's email is replaced and the pending change
+// removed; applied reports whether that happened.
+func ConfirmEmailChange(ctx context.Context, db *sqlx.DB, tokenHash string, now time.Time) (change *EmailChange, applied bool, err error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	change = &EmailChange{}
+	err = tx.GetContext(ctx, change, `
+		SELECT * FROM email_changes
+		WHERE (old_token_hash = $1 OR new_token_hash = $1) AND expires_at > $2
+		FOR UPDATE
+	`, tokenHash, now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, ErrEmailChangeNotFound
+		}
+		return nil, false, fmt.Errorf("failed to find email change: %w", err)
+	}
+
+	if tokenHash == change.OldTokenHash && change.OldConfirmedAt == nil {
+		change.OldConfirmedAt = &now
+	}
+	if tokenHash == change.NewTokenHash && change.NewConfirmedAt == nil {
+		change.NewConfirmedAt = &now
+	}
+
+	if change.OldConfirmedAt == nil || change.NewConfirmedAt == nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE email_changes SET old_confirmed_at = $1, new_confirmed_at = $2 WHERE user_id = $3
+		`, change.OldConfirmedAt, change.NewConfirmedAt, change.UserID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to confirm email change: %w", err)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, `UPDATE users SET email = $1, updated_at = $2 WHERE id = $3`, change.NewEmail, now, change.UserID)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return nil, false, ErrEmailTaken
+			}
+			return nil, false, fmt.Errorf("failed to update email: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, change.UserID); err != nil {
+			return nil, false, fmt.Errorf("failed to delete email change: %w", err)
+		}
+		applied = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit email change: %w", err)
+	}
+
+	return change, applied, nil
+}
@@ -3,11 +3,14 @@ package models
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // Instance represents a PocketBase instance
@@ -15,23 +18,104 @@ type Instance struct {
 	ID             uuid.UUID  `db:"id" json:"id"`
 	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
 	Name           string     `db:"name" json:"name"`
+	Description    string     `db:"description" json:"description"`
 	Slug           string     `db:"slug" json:"slug"`
 	Subdomain      string     `db:"subdomain" json:"subdomain"`
 	ContainerID    *string    `db:"container_id" json:"container_id,omitempty"`
 	ContainerName  *string    `db:"container_name" json:"container_name,omitempty"`
 	Status         string     `db:"status" json:"status"`
 	DataPath       string     `db:"data_path" json:"data_path"`
+	RegionID       string     `db:"region_id" json:"region_id"`
 	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
 	LastAccessedAt *time.Time `db:"last_accessed_at" json:"last_accessed_at,omitempty"`
+	FailureReason  *string    `db:"failure_reason" json:"failure_reason,omitempty"`
+	FailureLogs    *string    `db:"failure_logs" json:"failure_logs,omitempty"`
+	FailedAt       *time.Time `db:"failed_at" json:"failed_at,omitempty"`
+
+	// Set while the instance is pending deletion
+	StatusBeforeDeletion  *string    `db:"status_before_deletion" json:"status_before_deletion,omitempty"`
+	DeletionScheduledFor  *time.Time `db:"deletion_scheduled_for" json:"deletion_scheduled_for,omitempty"`
+	DeletionRetentionDays *int       `db:"deletion_retention_days" json:"deletion_retention_days,omitempty"`
+
+	// Set while the owner is over their bandwidth quota
+	RoutingSuspended bool `db:"routing_suspended" json:"routing_suspended"`
+
+	// Set while the instance is suspended
+	SuspensionReason *string    `db:"suspension_reason" json:"suspension_reason,omitempty"`
+	SuspendedAt      *time.Time `db:"suspended_at" json:"suspended_at,omitempty"`
+
+	// Set while an uploaded bundle that failed the malware scan awaits review
+	QuarantineBundle *string    `db:"quarantine_bundle" json:"quarantine_bundle,omitempty"`
+	QuarantineReason *string    `db:"quarantine_reason" json:"quarantine_reason,omitempty"`
+	QuarantinedAt    *time.Time `db:"quarantined_at" json:"quarantined_at,omitempty"`
+
+	// Flags rendered into the container's `pocketbase serve` command
+	ServeOptions ServeOptions `db:"serve_options" json:"serve_options"`
+
+	// Basic auth and IP allowlist enforced by Traefik
+	AccessProtection AccessProtection `db:"access_protection" json:"access_protection"`
+
+	// Outbound connections the instance may make
+	EgressPolicy EgressPolicy `db:"egress_policy" json:"egress_policy"`
+
+	// Host port the container is published on (ROUTING_MODE=port)
+	HostPort *int `db:"host_port" json:"host_port,omitempty"`
+
+	// Free-form tags (env=prod, project=acme), stored in instance_tags
+	Tags Tags `db:"tags" json:"tags"`
+
+	// Pinned instances are listed first, then by the owner's sort order
+	Pinned    bool `db:"pinned" json:"pinned"`
+	SortOrder *int `db:"sort_order" json:"sort_order,omitempty"`
+
+	// Protected instances can't be deleted until protection is turned off
+	Protected bool `db:"protected" json:"protected"`
+
+	// Read-only token for the public status endpoint (only the hash is stored)
+	StatusTokenHash   *string `db:"status_token_hash" json:"-"`
+	StatusTokenPrefix *string `db:"status_token_prefix" json:"status_token_prefix,omitempty"`
+
+	// Set on ephemeral instances, which are archived once it passes
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+
+	// Set on preview environments: the instance they were cloned from and
+	// the external ref (e.g. pull request) they were created for
+	PreviewOf  *uuid.UUID `db:"preview_of" json:"preview_of,omitempty"`
+	PreviewRef *string    `db:"preview_ref" json:"preview_ref,omitempty"`
+
+	// Approved image the instance runs instead of POCKETBASE_IMAGE
+	ImageID *string `db:"image_id" json:"image_id,omitempty"`
 }
 
+// instanceColumns lists the instances columns scanned into Instance
+const instanceColumns = `id, user_id, name, description, slug, subdomain, container_id, container_name,
+		       status, data_path, region_id, created_at, updated_at, last_accessed_at,
+		       failure_reason, failure_logs, failed_at,
+		       status_before_deletion, deletion_scheduled_for, deletion_retention_days,
+		       routing_suspended, suspension_reason, suspended_at,
+		       quarantine_bundle, quarantine_reason, quarantined_at, serve_options,
+		       access_protection, egress_policy, host_port, pinned, sort_order, protected, expires_at,
+		       preview_of, preview_ref, status_token_hash, status_token_prefix, image_id,
+		       COALESCE((SELECT jsonb_object_agg(t.key, t.value) FROM instance_tags t WHERE t.instance_id = instances.id), '{}') AS tags`
+
 // InstanceStatus represents the possible states of an instance
 const (
 	InstanceStatusCreating = "creating"
 	InstanceStatusRunning  = "running"
 	InstanceStatusStopped  = "stopped"
 	InstanceStatusFailed   = "failed"
+
+	InstanceStatusPendingDeletion = "pending_deletion"
+	InstanceStatusSuspended       = "suspended"
+)
+
+// Reasons recorded when an instance is archived
+const (
+	DeletionReasonManual  = "manual"
+	DeletionReasonExpired = "expired"
+	DeletionReasonPreview = "preview_closed"
+	DeletionReasonAdmin   = "admin"
 )
 
 // ArchivedInstance represents a deleted instance with metadata for restore capability
@@ -63,7 +147,12 @@ type ArchiveInstanceParams struct {
 	DeletedByUserID   uuid.UUID
 	DeletionReason    string
 	DataSizeMB        int
-	DataRetentionDays int // Number of days to retain data (default 30)
+	DataRetentionDays int // Number of days to retain data (0 means the data is deleted immediately)
+
+	// RequireStatus, if set, only archives the instance while it still has
+	// this status and deletion protection is off, for workers archiving rows
+	// they read earlier
+	RequireStatus string
 }
 
 // CreateInstanceParams holds parameters for creating a new instance
@@ -76,20 +165,105 @@ type CreateInstanceParams struct {
 	ContainerName *string
 	Status        string
 	DataPath      string
+	RegionID      string
+	ExpiresAt     *time.Time // nil for instances that don't expire
+	PreviewOf     *uuid.UUID // set with PreviewRef for preview environments
+	PreviewRef    *string
+	ImageID       *string // nil runs POCKETBASE_IMAGE
+
+	// HostPortMin and HostPortMax give the range the instance's host port is
+	// allocated from (both 0 when instances are routed through Traefik)
+	HostPortMin int
+	HostPortMax int
+
+	// MaxPerUser is the most non-failed instances the user may have, including
+	// this one (0 means unlimited)
+	MaxPerUser int
+}
+
+// InstanceLimitError is returned when creating an instance would exceed the user's limit
+type InstanceLimitError struct {
+	Limit int
 }
 
-// Create creates a new instance in the database
+func (e *InstanceLimitError) Error() string {
+	return fmt.Sprintf("maximum number of instances reached (%d)", e.Limit)
+}
+
+// ErrSubdomainTaken is returned when an instance's subdomain (or container
+// name, which is derived from the same slug) is used by another instance or
+// retained by an archived one
+var ErrSubdomainTaken = errors.New("subdomain is already taken")
+
+// ErrInstanceStatusChanged is returned when ArchiveInstanceParams.RequireStatus
+// no longer matches, e.g. because the user cancelled a pending deletion
+var ErrInstanceStatusChanged = errors.New("instance status changed before it was archived")
+
+// ErrInstanceProtected is returned when ArchiveInstanceParams.RequireStatus is
+// set and the instance's deletion protection was turned on in the meantime
+var ErrInstanceProtected = errors.New("instance is protected from deletion")
+
+// ErrNoHostPort is returned when every port of the host port range is in use
+var ErrNoHostPort = errors.New("no free host port is available")
+
+// subdomainConstraints are the constraints that reject a taken subdomain
+var subdomainConstraints = map[string]bool{
+	"instances_subdomain_key":      true,
+	"instances_container_name_key": true,
+	"instances_subdomain_retained": true,
+}
+
+// Create creates a new instance in the database. The user's row is locked
+// while their instances are counted, so concurrent creates cannot exceed
+// params.MaxPerUser.
 func (i *Instance) Create(ctx context.Context, db *sqlx.DB, params CreateInstanceParams) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if params.MaxPerUser > 0 {
+		var locked uuid.UUID
+		if err := tx.GetContext(ctx, &locked, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, params.UserID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var count int
+		err := tx.GetContext(ctx, &count, `
+			SELECT COUNT(*)
+			FROM instances
+			WHERE user_id = $1 AND status != $2
+		`, params.UserID, InstanceStatusFailed)
+		if err != nil {
+			return fmt.Errorf("failed to count instances: %w", err)
+		}
+
+		if count >= params.MaxPerUser {
+			return &InstanceLimitError{Limit: params.MaxPerUser}
+		}
+	}
+
+	var hostPort *int
+	if params.HostPortMax > 0 {
+		port, err := allocateHostPort(ctx, tx, params.HostPortMin, params.HostPortMax)
+		if err != nil {
+			return err
+		}
+		hostPort = &port
+	}
+
 	query := `
 		INSERT INTO instances (
 			user_id, name, slug, subdomain, container_id, container_name, 
-			status, data_path, created_at, updated_at
+			status, data_path, region_id, host_port, expires_at, preview_of, preview_ref,
+			image_id, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW()
 		) RETURNING id, created_at, updated_at
 	`
 
-	err := db.QueryRowxContext(
+	err = tx.QueryRowxContext(
 		ctx,
 		query,
 		params.UserID,
@@ -100,12 +274,29 @@ func (i *Instance) Create(ctx context.Context, db *sqlx.DB, params CreateInstanc
 		params.ContainerName,
 		params.Status,
 		params.DataPath,
+		params.RegionID,
+		hostPort,
+		params.ExpiresAt,
+		params.PreviewOf,
+		params.PreviewRef,
+		params.ImageID,
 	).Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && subdomainConstraints[pqErr.Constraint] {
+			return ErrSubdomainTaken
+		}
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "instances_preview_ref_key" {
+			return ErrPreviewExists
+		}
 		return fmt.Errorf("failed to create instance: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Populate the instance object
 	i.UserID = params.UserID
 	i.Name = params.Name
@@ -115,16 +306,57 @@ func (i *Instance) Create(ctx context.Context, db *sqlx.DB, params CreateInstanc
 	i.ContainerName = params.ContainerName
 	i.Status = params.Status
 	i.DataPath = params.DataPath
+	i.RegionID = params.RegionID
+	i.HostPort = hostPort
+	i.ExpiresAt = params.ExpiresAt
+	i.PreviewOf = params.PreviewOf
+	i.PreviewRef = params.PreviewRef
+	i.ImageID = params.ImageID
+	i.Tags = Tags{}
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
 
 	return nil
 }
 
+// hostPortLock is the transaction-level advisory lock key serializing host
+// port allocation, so concurrent creates never pick the same port
+const hostPortLock = 0x706f7274
+
+// allocateHostPort returns the lowest port of the range no instance is published on
+func allocateHostPort(ctx context.Context, tx *sqlx.Tx, min, max int) (int, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, hostPortLock); err != nil {
+		return 0, fmt.Errorf("failed to lock host ports: %w", err)
+	}
+
+	var port int
+	err := tx.GetContext(ctx, &port, `
+		SELECT port
+		FROM generate_series($1::int, $2::int) AS port
+		WHERE NOT EXISTS (SELECT 1 FROM instances WHERE host_port = port)
+		ORDER BY port
+		LIMIT 1
+	`, min, max)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNoHostPort
+		}
+		return 0, fmt.Errorf("failed to allocate host port: %w", err)
+	}
+
+	return port, nil
+}
+
 // FindByID retrieves an instance by its ID
 func FindInstanceByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*Instance, error) {
+	if cached := getCachedInstance(ctx, id); cached != nil {
+		return cached, nil
+	}
+
 	var instance Instance
 	query := `
-		SELECT id, user_id, name, slug, subdomain, container_id, container_name,
-		       status, data_path, created_at, updated_at, last_accessed_at
+		SELECT ` + instanceColumns + `
 		FROM instances
 		WHERE id = $1
 	`
@@ -137,6 +369,8 @@ func FindInstanceByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*Instance
 		return nil, fmt.Errorf("failed to find instance: %w", err)
 	}
 
+	cacheInstance(ctx, &instance)
+
 	return &instance, nil
 }
 
@@ -144,11 +378,10 @@ func FindInstanceByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*Instance
 func FindInstancesByUserID(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]Instance, error) {
 	var instances []Instance
 	query := `
-		SELECT id, user_id, name, slug, subdomain, container_id, container_name,
-		       status, data_path, created_at, updated_at, last_accessed_at
+		SELECT ` + instanceColumns + `
 		FROM instances
 		WHERE user_id = $1
-		ORDER BY created_at DESC
+		ORDER BY pinned DESC, sort_order ASC NULLS LAST, created_at DESC
 	`
 
 	err := db.SelectContext(ctx, &instances, query, userID)
@@ -159,12 +392,57 @@ func FindInstancesByUserID(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (
 	return instances, nil
 }
 
+// SearchInstances finds instances whose name, slug or subdomain contains term.
+// When userID is nil the search spans all users.
+func SearchInstances(ctx context.Context, db *sqlx.DB, userID *uuid.UUID, term string, limit int) ([]Instance, error) {
+	// Escape LIKE wildcards so the term is matched literally
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term) + "%"
+
+	instances := []Instance{}
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE ($1::uuid IS NULL OR user_id = $1)
+		  AND (name ILIKE $2 OR slug ILIKE $2 OR subdomain ILIKE $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	err := db.SelectContext(ctx, &instances, query, userID, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search instances: %w", err)
+	}
+
+	return instances, nil
+}
+
+// SubdomainInUse reports whether a subdomain belongs to an instance or to an
+// archived instance whose data is still retained
+func SubdomainInUse(ctx context.Context, db *sqlx.DB, subdomain string) (bool, error) {
+	var inUse bool
+	query := `
+		SELECT EXISTS (SELECT 1 FROM instances WHERE subdomain = $1)
+			OR EXISTS (SELECT 1 FROM instances_archive WHERE subdomain = $1 AND data_available = true)
+	`
+
+	if err := db.GetContext(ctx, &inUse, query, subdomain); err != nil {
+		return false, fmt.Errorf("failed to check subdomain: %w", err)
+	}
+
+	return inUse, nil
+}
+
 // FindBySubdomain retrieves an instance by its subdomain
 func FindInstanceBySubdomain(ctx context.Context, db *sqlx.DB, subdomain string) (*Instance, error) {
+	if id, ok := getCachedInstanceIDBySubdomain(ctx, subdomain); ok {
+		if cached := getCachedInstance(ctx, id); cached != nil && cached.Subdomain == subdomain {
+			return cached, nil
+		}
+	}
+
 	var instance Instance
 	query := `
-		SELECT id, user_id, name, slug, subdomain, container_id, container_name,
-		       status, data_path, created_at, updated_at, last_accessed_at
+		SELECT ` + instanceColumns + `
 		FROM instances
 		WHERE subdomain = $1
 	`
@@ -177,6 +455,8 @@ func FindInstanceBySubdomain(ctx context.Context, db *sqlx.DB, subdomain string)
 		return nil, fmt.Errorf("failed to find instance: %w", err)
 	}
 
+	cacheInstance(ctx, &instance)
+
 	return &instance, nil
 }
 
@@ -204,6 +484,15 @@ func (i *Instance) UpdateStatus(ctx context.Context, db *sqlx.DB, status string)
 		SET status = $1, updated_at = NOW()
 		WHERE id = $2
 	`
+	// Leaving the failed state clears the recorded failure details
+	if status != InstanceStatusFailed {
+		query = `
+			UPDATE instances 
+			SET status = $1, updated_at = NOW(),
+			    failure_reason = NULL, failure_logs = NULL, failed_at = NULL
+			WHERE id = $2
+		`
+	}
 
 	result, err := db.ExecContext(ctx, query, status, i.ID)
 	if err != nil {
@@ -221,10 +510,241 @@ func (i *Instance) UpdateStatus(ctx context.Context, db *sqlx.DB, status string)
 
 	i.Status = status
 	i.UpdatedAt = time.Now().UTC()
+	if status != InstanceStatusFailed {
+		i.FailureReason = nil
+		i.FailureLogs = nil
+		i.FailedAt = nil
+	}
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// MarkFailed sets the instance status to failed and records the reason and last log lines
+func (i *Instance) MarkFailed(ctx context.Context, db *sqlx.DB, reason, logs string) error {
+	query := `
+		UPDATE instances 
+		SET status = $1, failure_reason = $2, failure_logs = $3, failed_at = NOW(), updated_at = NOW()
+		WHERE id = $4
+		RETURNING failed_at, updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, InstanceStatusFailed, reason, logs, i.ID).Scan(&i.FailedAt, &i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance not found")
+		}
+		return fmt.Errorf("failed to mark instance as failed: %w", err)
+	}
+
+	i.Status = InstanceStatusFailed
+	i.FailureReason = &reason
+	i.FailureLogs = &logs
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// ScheduleDeletion moves the instance into pending_deletion until the given time
+func (i *Instance) ScheduleDeletion(ctx context.Context, db *sqlx.DB, at time.Time, retentionDays int) error {
+	query := `
+		UPDATE instances 
+		SET status_before_deletion = status, status = $1,
+		    deletion_scheduled_for = $2, deletion_retention_days = $3, updated_at = NOW()
+		WHERE id = $4 AND status <> $1
+		RETURNING status_before_deletion, updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, InstanceStatusPendingDeletion, at, retentionDays, i.ID).
+		Scan(&i.StatusBeforeDeletion, &i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance is already pending deletion")
+		}
+		return fmt.Errorf("failed to schedule instance deletion: %w", err)
+	}
+
+	i.Status = InstanceStatusPendingDeletion
+	i.DeletionScheduledFor = &at
+	i.DeletionRetentionDays = &retentionDays
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
 
 	return nil
 }
 
+// CancelDeletion restores the status the instance had before its deletion was scheduled
+func (i *Instance) CancelDeletion(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		UPDATE instances 
+		SET status = COALESCE(status_before_deletion, $2), status_before_deletion = NULL,
+		    deletion_scheduled_for = NULL, deletion_retention_days = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = $3
+		RETURNING status, updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, i.ID, InstanceStatusStopped, InstanceStatusPendingDeletion).
+		Scan(&i.Status, &i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance is not pending deletion")
+		}
+		return fmt.Errorf("failed to cancel instance deletion: %w", err)
+	}
+
+	i.StatusBeforeDeletion = nil
+	i.DeletionScheduledFor = nil
+	i.DeletionRetentionDays = nil
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// FindInstancesByStatus retrieves all instances with the given status
+func FindInstancesByStatus(ctx context.Context, db *sqlx.DB, status string) ([]Instance, error) {
+	var instances []Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE status = $1
+	`
+
+	if err := db.SelectContext(ctx, &instances, query, status); err != nil {
+		return nil, fmt.Errorf("failed to find instances: %w", err)
+	}
+
+	return instances, nil
+}
+
+// FindInstancesDueForDeletion retrieves pending deletions whose grace period has ended
+func FindInstancesDueForDeletion(ctx context.Context, db *sqlx.DB) ([]Instance, error) {
+	var instances []Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE status = $1 AND deletion_scheduled_for <= NOW()
+		ORDER BY deletion_scheduled_for ASC
+	`
+
+	err := db.SelectContext(ctx, &instances, query, InstanceStatusPendingDeletion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instances due for deletion: %w", err)
+	}
+
+	return instances, nil
+}
+
+// SetRoutingSuspended records whether the instance is cut off from the proxy
+func (i *Instance) SetRoutingSuspended(ctx context.Context, db *sqlx.DB, suspended bool) error {
+	query := `
+		UPDATE instances 
+		SET routing_suspended = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := db.ExecContext(ctx, query, suspended, i.ID); err != nil {
+		return fmt.Errorf("failed to update instance routing: %w", err)
+	}
+
+	i.RoutingSuspended = suspended
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// UpdateDescription saves an instance's markdown description
+func (i *Instance) UpdateDescription(ctx context.Context, db *sqlx.DB, description string) error {
+	query := `
+		UPDATE instances
+		SET description = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := db.ExecContext(ctx, query, description, i.ID); err != nil {
+		return fmt.Errorf("failed to update instance description: %w", err)
+	}
+
+	i.Description = description
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// UpdateDataPath saves the directory an instance's data was moved to
+func (i *Instance) UpdateDataPath(ctx context.Context, db *sqlx.DB, dataPath string) error {
+	query := `
+		UPDATE instances
+		SET data_path = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := db.ExecContext(ctx, query, dataPath, i.ID); err != nil {
+		return fmt.Errorf("failed to update instance data path: %w", err)
+	}
+
+	i.DataPath = dataPath
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// SetProtected turns deletion protection on or off
+func (i *Instance) SetProtected(ctx context.Context, db *sqlx.DB, protected bool) error {
+	query := `
+		UPDATE instances
+		SET protected = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := db.ExecContext(ctx, query, protected, i.ID); err != nil {
+		return fmt.Errorf("failed to update instance protection: %w", err)
+	}
+
+	i.Protected = protected
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// FindInstanceByContainerID retrieves an instance by its Docker container ID
+func FindInstanceByContainerID(ctx context.Context, db *sqlx.DB, containerID string) (*Instance, error) {
+	var instance Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE container_id = $1
+	`
+
+	err := db.GetContext(ctx, &instance, query, containerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("instance not found")
+		}
+		return nil, fmt.Errorf("failed to find instance: %w", err)
+	}
+
+	return &instance, nil
+}
+
 // UpdateContainerInfo updates the container ID and name
 func (i *Instance) UpdateContainerInfo(ctx context.Context, db *sqlx.DB, containerID, containerName string) error {
 	query := `
@@ -251,6 +771,9 @@ func (i *Instance) UpdateContainerInfo(ctx context.Context, db *sqlx.DB, contain
 	i.ContainerName = &containerName
 	i.UpdatedAt = time.Now().UTC()
 
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
 	return nil
 }
 
@@ -270,40 +793,19 @@ func (i *Instance) UpdateLastAccessed(ctx context.Context, db *sqlx.DB) error {
 	now := time.Now().UTC()
 	i.LastAccessedAt = &now
 
-	return nil
-}
-
-// Delete permanently deletes an instance from the database (should be archived first)
-func (i *Instance) Delete(ctx context.Context, db *sqlx.DB) error {
-	query := `DELETE FROM instances WHERE id = $1`
-
-	result, err := db.ExecContext(ctx, query, i.ID)
-	if err != nil {
-		return fmt.Errorf("failed to delete instance: %w", err)
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
-		return fmt.Errorf("instance not found")
-	}
+	cacheInstance(ctx, i)
 
 	return nil
 }
 
-// ArchiveInstance moves an instance to the archive table with metadata
+// ArchiveInstance moves an instance to the archive table with metadata. The
+// archive row is written and the instance row deleted in one transaction, so
+// an instance is never in both tables or in neither.
 func ArchiveInstance(ctx context.Context, db *sqlx.DB, params ArchiveInstanceParams) (*ArchivedInstance, error) {
 	instance := params.Instance
 
-	// Calculate data retention date (default 30 days)
-	retentionDays := params.DataRetentionDays
-	if retentionDays == 0 {
-		retentionDays = 30
-	}
-	dataRetainedUntil := time.Now().UTC().AddDate(0, 0, retentionDays)
+	// Calculate data retention date
+	dataRetainedUntil := time.Now().UTC().AddDate(0, 0, params.DataRetentionDays)
 
 	archived := &ArchivedInstance{
 		ID:                instance.ID,
@@ -321,7 +823,7 @@ func ArchiveInstance(ctx context.Context, db *sqlx.DB, params ArchiveInstancePar
 		DeletedAt:         time.Now().UTC(),
 		DeletedByUserID:   params.DeletedByUserID,
 		DeletionReason:    params.DeletionReason,
-		DataAvailable:     true,
+		DataAvailable:     true, // cleared once the data is removed
 		DataRetainedUntil: dataRetainedUntil,
 		DataSizeMB:        params.DataSizeMB,
 		OriginalSubdomain: instance.Subdomain,
@@ -341,11 +843,48 @@ func ArchiveInstance(ctx context.Context, db *sqlx.DB, params ArchiveInstancePar
 		)
 	`
 
-	_, err := db.NamedExecContext(ctx, query, archived)
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, query, archived); err != nil {
 		return nil, fmt.Errorf("failed to archive instance: %w", err)
 	}
 
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM instances
+		WHERE id = $1 AND ($2 = '' OR (status = $2 AND NOT protected))
+	`, instance.ID, params.RequireStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete instance: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		if params.RequireStatus != "" {
+			var protected bool
+			err := tx.GetContext(ctx, &protected, `SELECT protected FROM instances WHERE id = $1`, instance.ID)
+			if err == nil && protected {
+				return nil, ErrInstanceProtected
+			}
+			return nil, ErrInstanceStatusChanged
+		}
+		return nil, fmt.Errorf("instance not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	InvalidateInstanceCache(ctx, instance.ID)
+	instanceDeleted(ctx, instance)
+
 	return archived, nil
 }
 
@@ -393,6 +932,29 @@ func FindArchivedInstanceByID(ctx context.Context, db *sqlx.DB, id uuid.UUID, us
 	return &archived, nil
 }
 
+// FindArchivedInstance retrieves an archived instance of any user
+func FindArchivedInstance(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*ArchivedInstance, error) {
+	var archived ArchivedInstance
+	query := `
+		SELECT id, user_id, name, slug, subdomain, container_id, container_name,
+		       original_status, data_path, created_at, updated_at, last_accessed_at,
+		       deleted_at, deleted_by_user_id, deletion_reason, data_available,
+		       data_retained_until, data_size_mb, original_subdomain
+		FROM instances_archive
+		WHERE id = $1
+	`
+
+	err := db.GetContext(ctx, &archived, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("archived instance not found")
+		}
+		return nil, fmt.Errorf("failed to find archived instance: %w", err)
+	}
+
+	return &archived, nil
+}
+
 // UpdateDataAvailability updates the data_available flag for an archived instance
 func UpdateArchivedDataAvailability(ctx context.Context, db *sqlx.DB, id uuid.UUID, available bool) error {
 	query := `
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Kinds of suspicious instance behavior
+const (
+	AnomalyCPUSaturation = "cpu_saturation" // value is the average CPU percent
+	AnomalyNetworkEgress = "network_egress" // value is outbound bytes per second
+	AnomalyDiskGrowth    = "disk_growth"    // value is the growth in bytes
+)
+
+// Instance anomaly statuses
+const (
+	AnomalyOpen      = "open"
+	AnomalyDismissed = "dismissed" // the behavior was expected
+	AnomalySuspended = "suspended" // the instance was suspended
+)
+
+// ErrAnomalyNotFound is returned for unknown anomaly IDs
+var ErrAnomalyNotFound = errors.New("anomaly not found")
+
+// ErrAnomalyResolved is returned when reviewing an anomaly that is no longer open
+var ErrAnomalyResolved = errors.New("anomaly was already resolved")
+
+// InstanceAnomaly is suspicious behavior of an instance awaiting admin review
+type InstanceAnomaly struct {
+	ID             string     `db:"id" json:"id"`
+	InstanceID     uuid.UUID  `db:"instance_id" json:"instance_id"`
+	Kind           string     `db:"kind" json:"kind"`
+	Description    string     `db:"description" json:"description"`
+	Value          float64    `db:"value" json:"value"`
+	Status         string     `db:"status" json:"status"`
+	Throttled      bool       `db:"throttled" json:"throttled"`
+	ResolutionNote *string    `db:"resolution_note" json:"resolution_note,omitempty"`
+	ReviewedBy     *string    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateInstanceAnomaly stores an open anomaly. It returns false (and stores
+// nothing) when the instance already has an open anomaly of the same kind.
+func CreateInstanceAnomaly(ctx context.Context, db *sqlx.DB, anomaly *InstanceAnomaly) (bool, error) {
+	query := `
+		INSERT INTO instance_anomalies (instance_id, kind, description, value, throttled)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (instance_id, kind) WHERE status = 'open' DO NOTHING
+		RETURNING id, status, created_at
+	`
+	err := db.QueryRowxContext(ctx, query,
+		anomaly.InstanceID,
+		anomaly.Kind,
+		anomaly.Description,
+		anomaly.Value,
+		anomaly.Throttled,
+	).Scan(&anomaly.ID, &anomaly.Status, &anomaly.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create instance anomaly: %w", err)
+	}
+
+	return true, nil
+}
+
+// FindInstanceAnomalyByID returns an anomaly
+func FindInstanceAnomalyByID(ctx context.Context, db *sqlx.DB, id string) (*InstanceAnomaly, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrAnomalyNotFound
+	}
+
+	var anomaly InstanceAnomaly
+	if err := db.GetContext(ctx, &anomaly, `SELECT * FROM instance_anomalies WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAnomalyNotFound
+		}
+		return nil, fmt.Errorf("failed to find instance anomaly: %w", err)
+	}
+
+	return &anomaly, nil
+}
+
+// ListInstanceAnomalies returns anomalies with a status (all when empty),
+// oldest first so the review queue is worked in order
+func ListInstanceAnomalies(ctx context.Context, db *sqlx.DB, status string, limit int) ([]InstanceAnomaly, error) {
+	anomalies := []InstanceAnomaly{}
+	query := `
+		SELECT * FROM instance_anomalies
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at ASC
+		LIMIT $2
+	`
+	if err := db.SelectContext(ctx, &anomalies, query, status, limit); err != nil {
+		return nil, fmt.Errorf("failed to list instance anomalies: %w", err)
+	}
+
+	return anomalies, nil
+}
+
+// HasThrottledAnomaly reports whether an instance has an open anomaly that
+// throttled it
+func HasThrottledAnomaly(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM instance_anomalies WHERE instance_id = $1 AND status = $2 AND throttled)`
+	if err := db.GetContext(ctx, &exists, query, instanceID, AnomalyOpen); err != nil {
+		return false, fmt.Errorf("failed to check instance anomalies: %w", err)
+	}
+	return exists, nil
+}
+
+// ResolveInstanceAnomaly closes an open anomaly with a status and note
+func ResolveInstanceAnomaly(ctx context.Context, db *sqlx.DB, anomaly *InstanceAnomaly, status, reviewerID, note string) error {
+	var resolutionNote *string
+	if note != "" {
+		resolutionNote = &note
+	}
+
+	query := `
+		UPDATE instance_anomalies
+		SET status = $1, resolution_note = $2, reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $4 AND status = $5
+		RETURNING reviewed_at
+	`
+	err := db.QueryRowxContext(ctx, query, status, resolutionNote, reviewerID, anomaly.ID, AnomalyOpen).Scan(&anomaly.ReviewedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAnomalyResolved
+		}
+		return fmt.Errorf("failed to resolve instance anomaly: %w", err)
+	}
+
+	anomaly.Status = status
+	anomaly.ResolutionNote = resolutionNote
+	anomaly.ReviewedBy = &reviewerID
+
+	return nil
+}
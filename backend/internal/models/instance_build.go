@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Build statuses
+const (
+	BuildStatusQueued    = "queued"
+	BuildStatusBuilding  = "building"
+	BuildStatusSucceeded = "succeeded"
+	BuildStatusFailed    = "failed"
+)
+
+// ErrBuildInProgress is returned when an instance already has a queued or
+// running build
+var ErrBuildInProgress = errors.New("a build is already in progress")
+
+// InstanceBuild is a custom PocketBase binary built from a user's Go module
+type InstanceBuild struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	InstanceID uuid.UUID  `db:"instance_id" json:"instance_id"`
+	UserID     uuid.UUID  `db:"user_id" json:"user_id"`
+	Status     string     `db:"status" json:"status"`
+	Image      *string    `db:"image" json:"image,omitempty"`
+	Log        *string    `db:"log" json:"log,omitempty"`
+	Error      *string    `db:"error" json:"error,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	StartedAt  *time.Time `db:"started_at" json:"started_at,omitempty"`
+	FinishedAt *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+	DeployedAt *time.Time `db:"deployed_at" json:"deployed_at,omitempty"`
+}
+
+const instanceBuildColumns = `id, instance_id, user_id, status, image, log, error, created_at, started_at, finished_at, deployed_at`
+
+// CreateInstanceBuild queues a build, under its ID if one is set. An instance
+// has at most one queued or running build (idx_instance_builds_in_progress).
+func CreateInstanceBuild(ctx context.Context, db *sqlx.DB, build *InstanceBuild) error {
+	query := `
+		INSERT INTO instance_builds (id, instance_id, user_id, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	if build.ID == uuid.Nil {
+		build.ID = uuid.New()
+	}
+	build.Status = BuildStatusQueued
+	if err := db.QueryRowxContext(ctx, query, build.ID, build.InstanceID, build.UserID, build.Status).Scan(&build.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_instance_builds_in_progress" {
+			return ErrBuildInProgress
+		}
+		return fmt.Errorf("failed to create build: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceBuilds retrieves an instance's builds, newest first, without their logs
+func FindInstanceBuilds(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) ([]InstanceBuild, error) {
+	builds := []InstanceBuild{}
+	query := `
+		SELECT id, instance_id, user_id, status, image, NULL AS log, error, created_at, started_at, finished_at, deployed_at
+		FROM instance_builds
+		WHERE instance_id = $1
+		ORDER BY created_at DESC
+	`
+
+	if err := db.SelectContext(ctx, &builds, query, instanceID); err != nil {
+		return nil, fmt.Errorf("failed to find builds: %w", err)
+	}
+
+	return builds, nil
+}
+
+// FindInstanceBuildByID retrieves a build with its log
+func FindInstanceBuildByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*InstanceBuild, error) {
+	var build InstanceBuild
+	query := `SELECT ` + instanceBuildColumns + ` FROM instance_builds WHERE id = $1`
+
+	if err := db.GetContext(ctx, &build, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("build not found")
+		}
+		return nil, fmt.Errorf("failed to find build: %w", err)
+	}
+
+	return &build, nil
+}
+
+// InstanceBuildInProgress reports whether an instance has a queued or running build
+func InstanceBuildInProgress(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) (bool, error) {
+	var inProgress bool
+	query := `SELECT EXISTS (SELECT 1 FROM instance_builds WHERE instance_id = $1 AND status IN ($2, $3))`
+
+	if err := db.GetContext(ctx, &inProgress, query, instanceID, BuildStatusQueued, BuildStatusBuilding); err != nil {
+		return false, fmt.Errorf("failed to check builds: %w", err)
+	}
+
+	return inProgress, nil
+}
+
+// UpdateInstanceBuild saves a build's status, image, log, error and timestamps
+func UpdateInstanceBuild(ctx context.Context, db *sqlx.DB, build *InstanceBuild) error {
+	query := `
+		UPDATE instance_builds
+		SET status = :status, image = :image, log = :log, error = :error,
+		    started_at = :started_at, finished_at = :finished_at, deployed_at = :deployed_at
+		WHERE id = :id
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, build); err != nil {
+		return fmt.Errorf("failed to update build: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pocketploy/internal/cache"
+)
+
+// instanceCache backs the read-through lookups in FindInstanceByID and
+// FindInstanceBySubdomain. It is nil (disabled) until ConfigureInstanceCache is called.
+var (
+	instanceCache    cache.Store
+	instanceCacheTTL time.Duration
+)
+
+// ConfigureInstanceCache enables read-through caching of instance lookups.
+// A nil store or non-positive ttl disables caching.
+func ConfigureInstanceCache(store cache.Store, ttl time.Duration) {
+	if store == nil || ttl <= 0 {
+		instanceCache = nil
+		return
+	}
+	instanceCache = store
+	instanceCacheTTL = ttl
+}
+
+// cachedInstance is how instances are stored in the cache. The API encoding of
+// Instance leaves out its json:"-" fields, so they are stored next to it;
+// every such field has to be listed here or cache hits would lack it.
+type cachedInstance struct {
+	Instance        Instance `json:"instance"`
+	StatusTokenHash *string  `json:"status_token_hash,omitempty"`
+}
+
+func instanceIDCacheKey(id uuid.UUID) string {
+	return "instance:id:" + id.String()
+}
+
+func instanceSubdomainCacheKey(subdomain string) string {
+	return "instance:subdomain:" + subdomain
+}
+
+// getCachedInstance returns a cached copy of the instance, or nil on a miss
+func getCachedInstance(ctx context.Context, id uuid.UUID) *Instance {
+	if instanceCache == nil {
+		return nil
+	}
+
+	data, ok, err := instanceCache.Get(ctx, instanceIDCacheKey(id))
+	if err != nil || !ok {
+		return nil
+	}
+
+	var cached cachedInstance
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Instance.ID == uuid.Nil {
+		return nil
+	}
+
+	instance := cached.Instance
+	instance.StatusTokenHash = cached.StatusTokenHash
+	return &instance
+}
+
+// getCachedInstanceIDBySubdomain returns the instance ID cached for a subdomain
+func getCachedInstanceIDBySubdomain(ctx context.Context, subdomain string) (uuid.UUID, bool) {
+	if instanceCache == nil {
+		return uuid.Nil, false
+	}
+
+	data, ok, err := instanceCache.Get(ctx, instanceSubdomainCacheKey(subdomain))
+	if err != nil || !ok {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.ParseBytes(data)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// cacheInstance stores a copy of the instance under its ID and subdomain
+func cacheInstance(ctx context.Context, instance *Instance) {
+	if instanceCache == nil {
+		return
+	}
+
+	data, err := json.Marshal(cachedInstance{Instance: *instance, StatusTokenHash: instance.StatusTokenHash})
+	if err != nil {
+		return
+	}
+
+	if err := instanceCache.Set(ctx, instanceIDCacheKey(instance.ID), data, instanceCacheTTL); err != nil {
+		log.Printf("Warning: failed to cache instance %s: %v", instance.ID, err)
+		return
+	}
+	_ = instanceCache.Set(ctx, instanceSubdomainCacheKey(instance.Subdomain), []byte(instance.ID.String()), instanceCacheTTL)
+}
+
+// InvalidateInstanceCache drops a cached instance after it was changed or removed
+func InvalidateInstanceCache(ctx context.Context, id uuid.UUID) {
+	if instanceCache == nil {
+		return
+	}
+
+	// Stale subdomain entries are harmless: lookups verify the subdomain and
+	// fall back to the database when the ID entry is gone
+	if err := instanceCache.Delete(ctx, instanceIDCacheKey(id)); err != nil {
+		log.Printf("Warning: failed to invalidate cached instance %s: %v", id, err)
+	}
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Scheduled task kinds
+const (
+	InstanceCronKindCommand = "command"
+	InstanceCronKindHTTP    = "http"
+)
+
+// InstanceCron is a user-defined scheduled task for an instance
+type InstanceCron struct {
+	ID             uuid.UUID      `db:"id" json:"id"`
+	InstanceID     uuid.UUID      `db:"instance_id" json:"instance_id"`
+	Name           string         `db:"name" json:"name"`
+	Schedule       string         `db:"schedule" json:"schedule"`
+	Kind           string         `db:"kind" json:"kind"`
+	CommandArgs    pq.StringArray `db:"command_args" json:"command_args,omitempty"`
+	HTTPMethod     *string        `db:"http_method" json:"http_method,omitempty"`
+	HTTPPath       *string        `db:"http_path" json:"http_path,omitempty"`
+	Enabled        bool           `db:"enabled" json:"enabled"`
+	NextRunAt      *time.Time     `db:"next_run_at" json:"next_run_at,omitempty"`
+	LastRunAt      *time.Time     `db:"last_run_at" json:"last_run_at,omitempty"`
+	LastRunSuccess *bool          `db:"last_run_success" json:"last_run_success,omitempty"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// InstanceCronRun is one execution of a scheduled task
+type InstanceCronRun struct {
+	ID         int64     `db:"id" json:"id"`
+	CronID     uuid.UUID `db:"cron_id" json:"cron_id"`
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	FinishedAt time.Time `db:"finished_at" json:"finished_at"`
+	Success    bool      `db:"success" json:"success"`
+	Output     *string   `db:"output" json:"output,omitempty"`
+	Error      *string   `db:"error" json:"error,omitempty"`
+}
+
+const instanceCronColumns = `id, instance_id, name, schedule, kind, command_args, http_method, http_path,
+		       enabled, next_run_at, last_run_at, last_run_success, created_at, updated_at`
+
+// Create inserts a new scheduled task
+func (c *InstanceCron) Create(ctx context.Context, db *sqlx.DB) error {
+	c.ID = uuid.New()
+	c.CreatedAt = time.Now().UTC()
+	c.UpdatedAt = c.CreatedAt
+
+	query := `
+		INSERT INTO instance_crons (
+			id, instance_id, name, schedule, kind, command_args, http_method, http_path,
+			enabled, next_run_at, created_at, updated_at
+		) VALUES (
+			:id, :instance_id, :name, :schedule, :kind, :command_args, :http_method, :http_path,
+			:enabled, :next_run_at, :created_at, :updated_at
+		)
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, c); err != nil {
+		return fmt.Errorf("failed to create scheduled task: %w", err)
+	}
+
+	return nil
+}
+
+// Update saves the task definition, enabled flag and next run time
+func (c *InstanceCron) Update(ctx context.Context, db *sqlx.DB) error {
+	c.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE instance_crons
+		SET name = :name, schedule = :schedule, kind = :kind, command_args = :command_args,
+		    http_method = :http_method, http_path = :http_path, enabled = :enabled,
+		    next_run_at = :next_run_at, updated_at = :updated_at
+		WHERE id = :id
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, c); err != nil {
+		return fmt.Errorf("failed to update scheduled task: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the task and its run history
+func (c *InstanceCron) Delete(ctx context.Context, db *sqlx.DB) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM instance_crons WHERE id = $1`, c.ID); err != nil {
+		return fmt.Errorf("failed to delete scheduled task: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceCrons retrieves an instance's scheduled tasks, oldest first
+func FindInstanceCrons(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) ([]InstanceCron, error) {
+	crons := []InstanceCron{}
+	query := `
+		SELECT ` + instanceCronColumns + `
+		FROM instance_crons
+		WHERE instance_id = $1
+		ORDER BY created_at ASC
+	`
+
+	if err := db.SelectContext(ctx, &crons, query, instanceID); err != nil {
+		return nil, fmt.Errorf("failed to find scheduled tasks: %w", err)
+	}
+
+	return crons, nil
+}
+
+// FindInstanceCron retrieves a scheduled task belonging to an instance
+func FindInstanceCron(ctx context.Context, db *sqlx.DB, id, instanceID uuid.UUID) (*InstanceCron, error) {
+	var cron InstanceCron
+	query := `
+		SELECT ` + instanceCronColumns + `
+		FROM instance_crons
+		WHERE id = $1 AND instance_id = $2
+	`
+
+	if err := db.GetContext(ctx, &cron, query, id, instanceID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("scheduled task not found")
+		}
+		return nil, fmt.Errorf("failed to find scheduled task: %w", err)
+	}
+
+	return &cron, nil
+}
+
+// CountInstanceCrons counts an instance's scheduled tasks
+func CountInstanceCrons(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) (int, error) {
+	var count int
+	if err := db.GetContext(ctx, &count, `SELECT COUNT(*) FROM instance_crons WHERE instance_id = $1`, instanceID); err != nil {
+		return 0, fmt.Errorf("failed to count scheduled tasks: %w", err)
+	}
+
+	return count, nil
+}
+
+// FindDueInstanceCrons retrieves enabled tasks whose next run time has passed
+func FindDueInstanceCrons(ctx context.Context, db *sqlx.DB, now time.Time, limit int) ([]InstanceCron, error) {
+	crons := []InstanceCron{}
+	query := `
+		SELECT ` + instanceCronColumns + `
+		FROM instance_crons
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at ASC
+		LIMIT $2
+	`
+
+	if err := db.SelectContext(ctx, &crons, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to find due scheduled tasks: %w", err)
+	}
+
+	return crons, nil
+}
+
+// Claim advances the task to its next run time, reporting false if another
+// scheduler already claimed this run
+func (c *InstanceCron) Claim(ctx context.Context, db *sqlx.DB, next *time.Time) (bool, error) {
+	now := time.Now().UTC()
+	query := `
+		UPDATE instance_crons
+		SET next_run_at = $1, last_run_at = $2
+		WHERE id = $3 AND enabled AND next_run_at = $4
+	`
+
+	result, err := db.ExecContext(ctx, query, next, now, c.ID, c.NextRunAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled task: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	c.NextRunAt = next
+	c.LastRunAt = &now
+	return true, nil
+}
+
+// RecordInstanceCronRun stores a run, updates the task's last result and keeps
+// only the most recent keep runs
+func RecordInstanceCronRun(ctx context.Context, db *sqlx.DB, run *InstanceCronRun, keep int) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := `
+		INSERT INTO instance_cron_runs (cron_id, started_at, finished_at, success, output, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	err = tx.GetContext(ctx, &run.ID, insert, run.CronID, run.StartedAt, run.FinishedAt, run.Success, run.Output, run.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record scheduled task run: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE instance_crons SET last_run_success = $1 WHERE id = $2`, run.Success, run.CronID); err != nil {
+		return fmt.Errorf("failed to update scheduled task: %w", err)
+	}
+
+	prune := `
+		DELETE FROM instance_cron_runs
+		WHERE cron_id = $1 AND id NOT IN (
+			SELECT id FROM instance_cron_runs WHERE cron_id = $1 ORDER BY started_at DESC LIMIT $2
+		)
+	`
+	if _, err := tx.ExecContext(ctx, prune, run.CronID, keep); err != nil {
+		return fmt.Errorf("failed to prune scheduled task runs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scheduled task run: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceCronRuns retrieves a task's run history, newest first
+func FindInstanceCronRuns(ctx context.Context, db *sqlx.DB, cronID uuid.UUID) ([]InstanceCronRun, error) {
+	runs := []InstanceCronRun{}
+	query := `
+		SELECT id, cron_id, started_at, finished_at, success, output, error
+		FROM instance_cron_runs
+		WHERE cron_id = $1
+		ORDER BY started_at DESC
+	`
+
+	if err := db.SelectContext(ctx, &runs, query, cronID); err != nil {
+		return nil, fmt.Errorf("failed to find scheduled task runs: %w", err)
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Egress policy modes
+const (
+	EgressAllowAll  = "allow_all" // no restrictions (the default)
+	EgressDenyAll   = "deny_all"  // no outbound connections, not even DNS
+	EgressAllowList = "allowlist" // only DNS and the listed hosts and ports
+)
+
+// EgressRule allows outbound connections to a host
+type EgressRule struct {
+	// Host is a hostname (resolved when the rules are applied), an IP
+	// address or a CIDR range
+	Host string `json:"host" validate:"required,max=253"`
+
+	// Ports are the allowed TCP and UDP ports (all if empty)
+	Ports []int `json:"ports,omitempty" validate:"max=15,dive,min=1,max=65535"`
+}
+
+// EgressPolicy restricts the outbound connections of an instance, so a hosted
+// backend can't be used to attack other hosts
+type EgressPolicy struct {
+	Mode  string       `json:"mode,omitempty"`
+	Rules []EgressRule `json:"rules,omitempty"`
+}
+
+// Restricted reports whether the policy limits outbound connections
+func (p EgressPolicy) Restricted() bool {
+	return p.Mode == EgressDenyAll || p.Mode == EgressAllowList
+}
+
+// Value stores the policy as JSON (a string, as lib/pq would send []byte as bytea)
+func (p EgressPolicy) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads a policy stored as JSON
+func (p *EgressPolicy) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil:
+		*p = EgressPolicy{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into EgressPolicy", src)
+	}
+}
+
+// UpdateEgressPolicy saves an instance's egress policy
+func (i *Instance) UpdateEgressPolicy(ctx context.Context, db *sqlx.DB, policy EgressPolicy) error {
+	query := `
+		UPDATE instances
+		SET egress_policy = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at
+	`
+
+	if err := db.QueryRowxContext(ctx, query, policy, i.ID).Scan(&i.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update egress policy: %w", err)
+	}
+
+	i.EgressPolicy = policy
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+)
+
+// instanceObserver is told about instance writes so they can be pushed to
+// clients. It is nil (disabled) until ObserveInstanceChanges is called.
+var instanceObserver func(ctx context.Context, instance *Instance, deleted bool)
+
+// ObserveInstanceChanges registers fn to be called after an instance is
+// created, changes state or is deleted
+func ObserveInstanceChanges(fn func(ctx context.Context, instance *Instance, deleted bool)) {
+	instanceObserver = fn
+}
+
+// instanceChanged reports a write to the observer
+func instanceChanged(ctx context.Context, instance *Instance) {
+	if instanceObserver != nil {
+		instanceObserver(ctx, instance, false)
+	}
+}
+
+// instanceDeleted reports a deletion to the observer
+func instanceDeleted(ctx context.Context, instance *Instance) {
+	if instanceObserver != nil {
+		instanceObserver(ctx, instance, true)
+	}
+}
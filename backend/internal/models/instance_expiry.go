@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MarshalJSON adds the remaining lifetime of expiring instances, so clients
+// don't have to compare expires_at with their own clock
+func (i Instance) MarshalJSON() ([]byte, error) {
+	type instanceJSON Instance
+
+	var expiresIn *int64
+	if i.ExpiresAt != nil {
+		seconds := int64(time.Until(*i.ExpiresAt).Seconds())
+		if seconds < 0 {
+			seconds = 0
+		}
+		expiresIn = &seconds
+	}
+
+	return json.Marshal(struct {
+		instanceJSON
+		ExpiresInSeconds *int64 `json:"expires_in_seconds,omitempty"`
+	}{instanceJSON(i), expiresIn})
+}
+
+// ExtendExpiry moves the expiry of an instance that hasn't expired yet
+func (i *Instance) ExtendExpiry(ctx context.Context, db *sqlx.DB, expiresAt time.Time) error {
+	query := `
+		UPDATE instances
+		SET expires_at = $1, updated_at = NOW()
+		WHERE id = $2 AND expires_at > NOW()
+		RETURNING updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, expiresAt, i.ID).Scan(&i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance has expired")
+		}
+		return fmt.Errorf("failed to extend instance expiry: %w", err)
+	}
+
+	i.ExpiresAt = &expiresAt
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// FindExpiredInstances retrieves instances past their expiry. Instances still
+// being created or already pending deletion are left alone.
+func FindExpiredInstances(ctx context.Context, db *sqlx.DB) ([]Instance, error) {
+	var instances []Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE expires_at <= NOW() AND status NOT IN ($1, $2)
+		ORDER BY expires_at ASC
+	`
+
+	err := db.SelectContext(ctx, &instances, query, InstanceStatusCreating, InstanceStatusPendingDeletion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired instances: %w", err)
+	}
+
+	return instances, nil
+}
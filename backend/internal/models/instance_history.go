@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Instance history events
+const (
+	InstanceEventCreated      = "created"
+	InstanceEventFailed       = "failed"
+	InstanceEventSuspended    = "suspended"
+	InstanceEventQuarantined  = "quarantined"
+	InstanceEventMigrationRun = "migration_run"
+	InstanceEventCronRun      = "cron_run"
+	InstanceEventDeleted      = "deleted"
+)
+
+// InstanceHistoryEvent is an event in the life of an instance. The history is
+// put together from the timestamps instances, the archive and the run
+// histories record, so only each instance's latest failure, suspension and
+// quarantine appear, and the runs of deleted instances are gone.
+type InstanceHistoryEvent struct {
+	OccurredAt   time.Time `db:"occurred_at" json:"occurred_at"`
+	InstanceID   uuid.UUID `db:"instance_id" json:"instance_id"`
+	InstanceName string    `db:"instance_name" json:"instance_name"`
+	UserID       uuid.UUID `db:"user_id" json:"user_id"`
+	Event        string    `db:"event" json:"event"`
+	Detail       string    `db:"detail" json:"detail"`
+}
+
+// InstanceHistoryFilter narrows StreamInstanceHistory; nil IDs match everything
+type InstanceHistoryFilter struct {
+	From, To   time.Time
+	UserID     *uuid.UUID
+	InstanceID *uuid.UUID
+}
+
+// StreamInstanceHistory passes the events in [filter.From, filter.To) to fn, oldest first
+func StreamInstanceHistory(ctx context.Context, db *sqlx.DB, filter InstanceHistoryFilter, fn func(*InstanceHistoryEvent) error) error {
+	query := `
+		SELECT * FROM (
+			SELECT created_at AS occurred_at, id AS instance_id, name AS instance_name, user_id,
+			       '` + InstanceEventCreated + `' AS event, region_id AS detail
+			FROM instances
+			UNION ALL
+			SELECT created_at, id, name, user_id, '` + InstanceEventCreated + `', ''
+			FROM instances_archive
+			UNION ALL
+			SELECT failed_at, id, name, user_id, '` + InstanceEventFailed + `', COALESCE(failure_reason, '')
+			FROM instances WHERE failed_at IS NOT NULL
+			UNION ALL
+			SELECT suspended_at, id, name, user_id, '` + InstanceEventSuspended + `', COALESCE(suspension_reason, '')
+			FROM instances WHERE suspended_at IS NOT NULL
+			UNION ALL
+			SELECT quarantined_at, id, name, user_id, '` + InstanceEventQuarantined + `', COALESCE(quarantine_reason, '')
+			FROM instances WHERE quarantined_at IS NOT NULL
+			UNION ALL
+			SELECT r.started_at, i.id, i.name, i.user_id, '` + InstanceEventMigrationRun + `',
+			       CASE WHEN r.success THEN 'succeeded' ELSE 'failed: ' || COALESCE(r.error, '') END
+			FROM instance_migration_runs r JOIN instances i ON i.id = r.instance_id
+			UNION ALL
+			SELECT r.started_at, i.id, i.name, i.user_id, '` + InstanceEventCronRun + `',
+			       c.name || CASE WHEN r.success THEN ': succeeded' ELSE ': failed' END
+			FROM instance_cron_runs r
+			JOIN instance_crons c ON c.id = r.cron_id
+			JOIN instances i ON i.id = c.instance_id
+			UNION ALL
+			SELECT deleted_at, id, name, user_id, '` + InstanceEventDeleted + `', deletion_reason
+			FROM instances_archive
+		) history
+		WHERE occurred_at >= $1 AND occurred_at < $2
+		  AND ($3::uuid IS NULL OR user_id = $3)
+		  AND ($4::uuid IS NULL OR instance_id = $4)
+		ORDER BY occurred_at, instance_id
+	`
+
+	err := streamRows(ctx, db, query, fn, filter.From, filter.To, filter.UserID, filter.InstanceID)
+	if err != nil {
+		return fmt.Errorf("failed to export instance history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// InstanceImage is an approved container image users may create instances
+// from instead of POCKETBASE_IMAGE
+type InstanceImage struct {
+	ID              string    `db:"id" json:"id"`
+	Name            string    `db:"name" json:"name"`
+	Description     string    `db:"description" json:"description"`
+	Reference       string    `db:"reference" json:"reference"`
+	PinnedReference string    `db:"pinned_reference" json:"pinned_reference"`
+	AllUsers        bool      `db:"all_users" json:"all_users"`
+	Enabled         bool      `db:"enabled" json:"enabled"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateInstanceImageRequest represents the request body for approving an
+// image. Digest, when given, must match the digest the reference resolves to.
+type CreateInstanceImageRequest struct {
+	ID          string `json:"id" validate:"required,min=2,max=32,alphanum_hyphen"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description,omitempty" validate:"max=1000"`
+	Reference   string `json:"reference" validate:"required,max=255"`
+	Digest      string `json:"digest,omitempty" validate:"omitempty,startswith=sha256:,len=71"`
+	AllUsers    bool   `json:"all_users"`
+}
+
+// UpdateInstanceImageRequest represents the request body for changing an
+// image. Omitted fields are left as they are; the image itself is fixed.
+type UpdateInstanceImageRequest struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
+	AllUsers    *bool   `json:"all_users,omitempty"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+}
+
+// ErrImageExists is returned when an image ID is already in use
+var ErrImageExists = errors.New("image already exists")
+
+const instanceImageColumns = `id, name, description, reference, pinned_reference, all_users, enabled, created_at, updated_at`
+
+// FindInstanceImages retrieves every approved image
+func FindInstanceImages(ctx context.Context, db *sqlx.DB) ([]InstanceImage, error) {
+	images := []InstanceImage{}
+	query := `SELECT ` + instanceImageColumns + ` FROM instance_images ORDER BY name ASC`
+
+	if err := db.SelectContext(ctx, &images, query); err != nil {
+		return nil, fmt.Errorf("failed to find images: %w", err)
+	}
+
+	return images, nil
+}
+
+// FindAvailableInstanceImages retrieves the enabled images a user may choose:
+// those offered to every user and those granted to them
+func FindAvailableInstanceImages(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]InstanceImage, error) {
+	images := []InstanceImage{}
+	query := `
+		SELECT ` + instanceImageColumns + `
+		FROM instance_images
+		WHERE enabled AND (all_users OR EXISTS (
+			SELECT 1 FROM instance_image_grants g WHERE g.image_id = instance_images.id AND g.user_id = $1
+		))
+		ORDER BY name ASC
+	`
+
+	if err := db.SelectContext(ctx, &images, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to find images: %w", err)
+	}
+
+	return images, nil
+}
+
+// FindInstanceImageByID retrieves an image by its ID
+func FindInstanceImageByID(ctx context.Context, db *sqlx.DB, id string) (*InstanceImage, error) {
+	var image InstanceImage
+	query := `SELECT ` + instanceImageColumns + ` FROM instance_images WHERE id = $1`
+
+	if err := db.GetContext(ctx, &image, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("image not found")
+		}
+		return nil, fmt.Errorf("failed to find image: %w", err)
+	}
+
+	return &image, nil
+}
+
+// Create inserts a new image
+func (i *InstanceImage) Create(ctx context.Context, db *sqlx.DB) error {
+	i.CreatedAt = time.Now().UTC()
+	i.UpdatedAt = i.CreatedAt
+
+	query := `
+		INSERT INTO instance_images (id, name, description, reference, pinned_reference, all_users, enabled, created_at, updated_at)
+		VALUES (:id, :name, :description, :reference, :pinned_reference, :all_users, :enabled, :created_at, :updated_at)
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, i); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrImageExists
+		}
+		return fmt.Errorf("failed to create image: %w", err)
+	}
+
+	return nil
+}
+
+// Update saves the image's name, description and availability. The pinned
+// reference is fixed, as existing instances were created from it.
+func (i *InstanceImage) Update(ctx context.Context, db *sqlx.DB) error {
+	i.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE instance_images
+		SET name = :name, description = :description, all_users = :all_users, enabled = :enabled, updated_at = :updated_at
+		WHERE id = :id
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, i); err != nil {
+		return fmt.Errorf("failed to update image: %w", err)
+	}
+
+	return nil
+}
+
+// InstanceImageGrant is a user allowed to choose an image
+type InstanceImageGrant struct {
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// FindInstanceImageGrants retrieves the users granted an image
+func FindInstanceImageGrants(ctx context.Context, db *sqlx.DB, imageID string) ([]InstanceImageGrant, error) {
+	grants := []InstanceImageGrant{}
+	query := `
+		SELECT g.user_id, u.username, g.created_at
+		FROM instance_image_grants g
+		JOIN users u ON u.id = g.user_id
+		WHERE g.image_id = $1
+		ORDER BY u.username ASC
+	`
+
+	if err := db.SelectContext(ctx, &grants, query, imageID); err != nil {
+		return nil, fmt.Errorf("failed to find image grants: %w", err)
+	}
+
+	return grants, nil
+}
+
+// GrantInstanceImage allows a user to choose an image (granting twice is a no-op)
+func GrantInstanceImage(ctx context.Context, db *sqlx.DB, imageID string, userID uuid.UUID) error {
+	query := `
+		INSERT INTO instance_image_grants (image_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (image_id, user_id) DO NOTHING
+	`
+
+	if _, err := db.ExecContext(ctx, query, imageID, userID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to grant image: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeInstanceImage withdraws a user's grant. Their existing instances keep
+// running the image.
+func RevokeInstanceImage(ctx context.Context, db *sqlx.DB, imageID string, userID uuid.UUID) error {
+	query := `DELETE FROM instance_image_grants WHERE image_id = $1 AND user_id = $2`
+
+	if _, err := db.ExecContext(ctx, query, imageID, userID); err != nil {
+		return fmt.Errorf("failed to revoke image: %w", err)
+	}
+
+	return nil
+}
+
+// InstanceImageGranted reports whether a user was granted an image
+func InstanceImageGranted(ctx context.Context, db *sqlx.DB, imageID string, userID uuid.UUID) (bool, error) {
+	var granted bool
+	query := `SELECT EXISTS (SELECT 1 FROM instance_image_grants WHERE image_id = $1 AND user_id = $2)`
+
+	if err := db.GetContext(ctx, &granted, query, imageID, userID); err != nil {
+		return false, fmt.Errorf("failed to check image grant: %w", err)
+	}
+
+	return granted, nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// InstanceIntegrityCheck is one `PRAGMA integrity_check` run against an
+// instance's data.db
+type InstanceIntegrityCheck struct {
+	ID          int64     `db:"id" json:"id"`
+	InstanceID  uuid.UUID `db:"instance_id" json:"instance_id"`
+	TriggeredBy *string   `db:"triggered_by" json:"triggered_by,omitempty"` // nil for scheduled checks
+	OK          bool      `db:"ok" json:"ok"`
+	Result      *string   `db:"result" json:"result,omitempty"`
+	Error       *string   `db:"error" json:"error,omitempty"`
+	StartedAt   time.Time `db:"started_at" json:"started_at"`
+	FinishedAt  time.Time `db:"finished_at" json:"finished_at"`
+}
+
+// Corrupt reports whether the check ran and found problems
+func (c *InstanceIntegrityCheck) Corrupt() bool {
+	return !c.OK && c.Error == nil
+}
+
+// RecordInstanceIntegrityCheck stores a check and keeps only the most recent
+// keep checks of the instance
+func RecordInstanceIntegrityCheck(ctx context.Context, db *sqlx.DB, check *InstanceIntegrityCheck, keep int) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := `
+		INSERT INTO instance_integrity_checks (instance_id, triggered_by, ok, result, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	err = tx.GetContext(ctx, &check.ID, insert, check.InstanceID, check.TriggeredBy, check.OK, check.Result, check.Error, check.StartedAt, check.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record integrity check: %w", err)
+	}
+
+	prune := `
+		DELETE FROM instance_integrity_checks
+		WHERE instance_id = $1 AND id NOT IN (
+			SELECT id FROM instance_integrity_checks WHERE instance_id = $1 ORDER BY started_at DESC LIMIT $2
+		)
+	`
+	if _, err := tx.ExecContext(ctx, prune, check.InstanceID, keep); err != nil {
+		return fmt.Errorf("failed to prune integrity checks: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit integrity check: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceIntegrityChecks retrieves an instance's integrity checks, newest first
+func FindInstanceIntegrityChecks(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) ([]InstanceIntegrityCheck, error) {
+	checks := []InstanceIntegrityCheck{}
+	query := `
+		SELECT id, instance_id, triggered_by, ok, result, error, started_at, finished_at
+		FROM instance_integrity_checks
+		WHERE instance_id = $1
+		ORDER BY started_at DESC
+	`
+
+	if err := db.SelectContext(ctx, &checks, query, instanceID); err != nil {
+		return nil, fmt.Errorf("failed to find integrity checks: %w", err)
+	}
+
+	return checks, nil
+}
+
+// FindInstancesDueForIntegrityCheck retrieves running and stopped instances
+// that haven't been checked since the given time, least recently checked first
+func FindInstancesDueForIntegrityCheck(ctx context.Context, db *sqlx.DB, since time.Time, limit int) ([]Instance, error) {
+	var instances []Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE status IN ($1, $2) AND NOT EXISTS (
+			SELECT 1 FROM instance_integrity_checks c
+			WHERE c.instance_id = instances.id AND c.started_at >= $3
+		)
+		ORDER BY (SELECT MAX(started_at) FROM instance_integrity_checks c WHERE c.instance_id = instances.id) ASC NULLS FIRST
+		LIMIT $4
+	`
+
+	err := db.SelectContext(ctx, &instances, query, InstanceStatusRunning, InstanceStatusStopped, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find instances due for an integrity check: %w", err)
+	}
+
+	return instances, nil
+}
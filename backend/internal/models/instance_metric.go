@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// InstanceMetric is a resource usage sample for an instance
+type InstanceMetric struct {
+	ID               int64     `db:"id" json:"-"`
+	InstanceID       uuid.UUID `db:"instance_id" json:"-"`
+	RecordedAt       time.Time `db:"recorded_at" json:"recorded_at"`
+	CPUPercent       float64   `db:"cpu_percent" json:"cpu_percent"`
+	MemoryBytes      int64     `db:"memory_bytes" json:"memory_bytes"`
+	MemoryLimitBytes int64     `db:"memory_limit_bytes" json:"memory_limit_bytes"`
+	DiskBytes        int64     `db:"disk_bytes" json:"disk_bytes"`
+	NetworkRxBytes   int64     `db:"network_rx_bytes" json:"network_rx_bytes"`
+	NetworkTxBytes   int64     `db:"network_tx_bytes" json:"network_tx_bytes"`
+}
+
+// CreateInstanceMetric stores a resource usage sample
+func CreateInstanceMetric(ctx context.Context, db *sqlx.DB, metric *InstanceMetric) error {
+	query := `
+		INSERT INTO instance_metrics (
+			instance_id, recorded_at, cpu_percent, memory_bytes, memory_limit_bytes,
+			disk_bytes, network_rx_bytes, network_tx_bytes
+		) VALUES (
+			:instance_id, :recorded_at, :cpu_percent, :memory_bytes, :memory_limit_bytes,
+			:disk_bytes, :network_rx_bytes, :network_tx_bytes
+		)
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, metric); err != nil {
+		return fmt.Errorf("failed to store instance metric: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceMetrics retrieves an instance's samples recorded since the given time, oldest first
+func FindInstanceMetrics(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID, since time.Time) ([]InstanceMetric, error) {
+	metrics := []InstanceMetric{}
+	query := `
+		SELECT id, instance_id, recorded_at, cpu_percent, memory_bytes, memory_limit_bytes,
+		       disk_bytes, network_rx_bytes, network_tx_bytes
+		FROM instance_metrics
+		WHERE instance_id = $1 AND recorded_at >= $2
+		ORDER BY recorded_at ASC
+	`
+
+	if err := db.SelectContext(ctx, &metrics, query, instanceID, since); err != nil {
+		return nil, fmt.Errorf("failed to find instance metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// DeleteInstanceMetricsBefore removes samples older than the given time
+func DeleteInstanceMetricsBefore(ctx context.Context, db *sqlx.DB, before time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM instance_metrics WHERE recorded_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune instance metrics: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetUserDiskUsage sums the most recent disk sample of each of a user's instances
+func GetUserDiskUsage(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (int64, error) {
+	var total int64
+	query := `
+		SELECT COALESCE(SUM(latest.disk_bytes), 0)
+		FROM instances i
+		CROSS JOIN LATERAL (
+			SELECT disk_bytes
+			FROM instance_metrics
+			WHERE instance_id = i.id
+			ORDER BY recorded_at DESC
+			LIMIT 1
+		) latest
+		WHERE i.user_id = $1
+	`
+
+	if err := db.GetContext(ctx, &total, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to sum disk usage: %w", err)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// InstanceMigrationRun is one `pocketbase migrate up` run
+type InstanceMigrationRun struct {
+	ID          int64          `db:"id" json:"id"`
+	InstanceID  uuid.UUID      `db:"instance_id" json:"instance_id"`
+	TriggeredBy *string        `db:"triggered_by" json:"triggered_by,omitempty"`
+	Success     bool           `db:"success" json:"success"`
+	Applied     pq.StringArray `db:"applied" json:"applied"`
+	Output      *string        `db:"output" json:"output,omitempty"`
+	Error       *string        `db:"error" json:"error,omitempty"`
+	StartedAt   time.Time      `db:"started_at" json:"started_at"`
+	FinishedAt  time.Time      `db:"finished_at" json:"finished_at"`
+}
+
+// RecordInstanceMigrationRun stores a run and keeps only the most recent keep
+// runs of the instance
+func RecordInstanceMigrationRun(ctx context.Context, db *sqlx.DB, run *InstanceMigrationRun, keep int) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if run.Applied == nil {
+		run.Applied = pq.StringArray{}
+	}
+
+	insert := `
+		INSERT INTO instance_migration_runs (instance_id, triggered_by, success, applied, output, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	err = tx.GetContext(ctx, &run.ID, insert, run.InstanceID, run.TriggeredBy, run.Success, run.Applied, run.Output, run.Error, run.StartedAt, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record migration run: %w", err)
+	}
+
+	prune := `
+		DELETE FROM instance_migration_runs
+		WHERE instance_id = $1 AND id NOT IN (
+			SELECT id FROM instance_migration_runs WHERE instance_id = $1 ORDER BY started_at DESC LIMIT $2
+		)
+	`
+	if _, err := tx.ExecContext(ctx, prune, run.InstanceID, keep); err != nil {
+		return fmt.Errorf("failed to prune migration runs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration run: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceMigrationRuns retrieves an instance's migration runs, newest first
+func FindInstanceMigrationRuns(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) ([]InstanceMigrationRun, error) {
+	runs := []InstanceMigrationRun{}
+	query := `
+		SELECT id, instance_id, triggered_by, success, applied, output, error, started_at, finished_at
+		FROM instance_migration_runs
+		WHERE instance_id = $1
+		ORDER BY started_at DESC
+	`
+
+	if err := db.SelectContext(ctx, &runs, query, instanceID); err != nil {
+		return nil, fmt.Errorf("failed to find migration runs: %w", err)
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SetPinned pins or unpins an instance at the top of its owner's list
+func (i *Instance) SetPinned(ctx context.Context, db *sqlx.DB, pinned bool) error {
+	query := `
+		UPDATE instances
+		SET pinned = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := db.ExecContext(ctx, query, pinned, i.ID); err != nil {
+		return fmt.Errorf("failed to update instance pin: %w", err)
+	}
+
+	i.Pinned = pinned
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// ReorderInstances gives the user's instances the positions of instanceIDs.
+// Instances left out of instanceIDs lose their position and are listed after
+// the ordered ones. Every ID must belong to the user.
+func ReorderInstances(ctx context.Context, db *sqlx.DB, userID uuid.UUID, instanceIDs []uuid.UUID) error {
+	ids := make([]string, len(instanceIDs))
+	for n, id := range instanceIDs {
+		ids[n] = id.String()
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owned int
+	err = tx.GetContext(ctx, &owned, `
+		SELECT COUNT(*)
+		FROM instances
+		WHERE user_id = $1 AND id = ANY($2::uuid[])
+	`, userID, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to reorder instances: %w", err)
+	}
+	if owned != len(ids) {
+		return fmt.Errorf("instance not found")
+	}
+
+	var changed []uuid.UUID
+	err = tx.SelectContext(ctx, &changed, `
+		UPDATE instances
+		SET sort_order = positions.position
+		FROM (
+			SELECT i.id, p.position
+			FROM instances i
+			LEFT JOIN unnest($2::uuid[]) WITH ORDINALITY AS p(id, position) ON p.id = i.id
+			WHERE i.user_id = $1
+		) AS positions
+		WHERE instances.id = positions.id
+		  AND instances.sort_order IS DISTINCT FROM positions.position
+		RETURNING instances.id
+	`, userID, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to reorder instances: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit instance order: %w", err)
+	}
+
+	for _, id := range changed {
+		InvalidateInstanceCache(ctx, id)
+	}
+
+	return nil
+}
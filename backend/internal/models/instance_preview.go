@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrPreviewExists is returned when an instance already has a preview for a ref
+var ErrPreviewExists = errors.New("preview already exists")
+
+// ErrPreviewNotFound is returned when an instance has no preview for a ref
+var ErrPreviewNotFound = errors.New("preview not found")
+
+// FindPreview retrieves the preview environment of an instance for a ref
+func FindPreview(ctx context.Context, db *sqlx.DB, sourceID uuid.UUID, ref string) (*Instance, error) {
+	var instance Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE preview_of = $1 AND preview_ref = $2
+	`
+
+	err := db.GetContext(ctx, &instance, query, sourceID, ref)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPreviewNotFound
+		}
+		return nil, fmt.Errorf("failed to find preview: %w", err)
+	}
+
+	return &instance, nil
+}
+
+// FindPreviews retrieves the preview environments of an instance, newest first
+func FindPreviews(ctx context.Context, db *sqlx.DB, sourceID uuid.UUID) ([]Instance, error) {
+	var instances []Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE preview_of = $1
+		ORDER BY created_at DESC
+	`
+
+	err := db.SelectContext(ctx, &instances, query, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find previews: %w", err)
+	}
+
+	return instances, nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Kinds of bundles that can be quarantined
+const (
+	QuarantineBundleHooks      = "hooks"
+	QuarantineBundleMigrations = "migrations"
+	QuarantineBundlePublic     = "public"
+)
+
+// Quarantine records that an uploaded bundle failed the malware scan. An
+// instance holds at most one quarantined bundle at a time.
+func (i *Instance) Quarantine(ctx context.Context, db *sqlx.DB, bundle, reason string) error {
+	query := `
+		UPDATE instances
+		SET quarantine_bundle = $1, quarantine_reason = $2, quarantined_at = NOW(), updated_at = NOW()
+		WHERE id = $3 AND quarantined_at IS NULL
+		RETURNING quarantined_at, updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, bundle, reason, i.ID).Scan(&i.QuarantinedAt, &i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance is quarantined")
+		}
+		return fmt.Errorf("failed to quarantine instance: %w", err)
+	}
+
+	i.QuarantineBundle = &bundle
+	i.QuarantineReason = &reason
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// ClearQuarantine lifts the quarantine once the bundle was approved or discarded
+func (i *Instance) ClearQuarantine(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		UPDATE instances
+		SET quarantine_bundle = NULL, quarantine_reason = NULL, quarantined_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND quarantined_at IS NOT NULL
+		RETURNING updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, i.ID).Scan(&i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance is not quarantined")
+		}
+		return fmt.Errorf("failed to lift instance quarantine: %w", err)
+	}
+
+	i.QuarantineBundle = nil
+	i.QuarantineReason = nil
+	i.QuarantinedAt = nil
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ServeOptions are the `pocketbase serve` flags an instance owner may set.
+// Only flags that cannot reach outside the instance are offered: directories
+// are relative to the instance's data directory, and the listen address and
+// data directory stay fixed.
+type ServeOptions struct {
+	// EncryptSettings encrypts the PocketBase settings with a per-instance key
+	// (--encryptionEnv). It cannot be turned off again.
+	EncryptSettings bool `json:"encrypt_settings"`
+
+	// Directories relative to the data directory (--hooksDir, --migrationsDir, --publicDir)
+	HooksDir      string `json:"hooks_dir,omitempty" validate:"omitempty,max=255"`
+	MigrationsDir string `json:"migrations_dir,omitempty" validate:"omitempty,max=255"`
+	PublicDir     string `json:"public_dir,omitempty" validate:"omitempty,max=255"`
+
+	// Origins are the CORS origins PocketBase allows (--origins, all if empty)
+	Origins []string `json:"origins,omitempty" validate:"max=20"`
+
+	// QueryTimeout is the SELECT query timeout in seconds (--queryTimeout, PocketBase's default if 0)
+	QueryTimeout int `json:"query_timeout,omitempty" validate:"min=0,max=600"`
+}
+
+// Value stores the options as JSON (a string, as lib/pq would send []byte as bytea)
+func (o ServeOptions) Value() (driver.Value, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads options stored as JSON
+func (o *ServeOptions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case nil:
+		*o = ServeOptions{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ServeOptions", src)
+	}
+}
+
+// Validate checks the values the struct tags cannot express
+func (o ServeOptions) Validate() error {
+	for _, dir := range []string{o.HooksDir, o.MigrationsDir, o.PublicDir} {
+		if dir != "" && !filepath.IsLocal(dir) {
+			return fmt.Errorf("directories must be relative paths inside the data directory")
+		}
+	}
+
+	for _, origin := range o.Origins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("origins must be http(s)://host[:port] URLs")
+		}
+	}
+
+	return nil
+}
+
+// UpdateServeOptions saves an instance's serve options
+func (i *Instance) UpdateServeOptions(ctx context.Context, db *sqlx.DB, options ServeOptions) error {
+	query := `
+		UPDATE instances
+		SET serve_options = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at
+	`
+
+	if err := db.QueryRowxContext(ctx, query, options, i.ID).Scan(&i.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update serve options: %w", err)
+	}
+
+	i.ServeOptions = options
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// EnsureInstanceEncryptionKey returns the instance's settings encryption key,
+// storing key first if it has none. The key is kept out of Instance so it is
+// neither cached nor returned by the API.
+func EnsureInstanceEncryptionKey(ctx context.Context, db *sqlx.DB, id uuid.UUID, key string) (string, error) {
+	query := `
+		UPDATE instances
+		SET encryption_key = COALESCE(encryption_key, $1)
+		WHERE id = $2
+		RETURNING encryption_key
+	`
+
+	var stored string
+	if err := db.GetContext(ctx, &stored, query, key, id); err != nil {
+		return "", fmt.Errorf("failed to store encryption key: %w", err)
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetStatusToken stores the hash and prefix of an instance's status token
+// (nil for both removes the token)
+func (i *Instance) SetStatusToken(ctx context.Context, db *sqlx.DB, hash, prefix *string) error {
+	query := `
+		UPDATE instances
+		SET status_token_hash = $1, status_token_prefix = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+
+	if _, err := db.ExecContext(ctx, query, hash, prefix, i.ID); err != nil {
+		return fmt.Errorf("failed to update instance status token: %w", err)
+	}
+
+	i.StatusTokenHash = hash
+	i.StatusTokenPrefix = prefix
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// RevokeStatusToken removes an instance's status token. The check and the
+// update are one statement, so it doesn't depend on a possibly stale copy.
+func (i *Instance) RevokeStatusToken(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		UPDATE instances
+		SET status_token_hash = NULL, status_token_prefix = NULL, updated_at = NOW()
+		WHERE id = $1 AND status_token_hash IS NOT NULL
+	`
+
+	result, err := db.ExecContext(ctx, query, i.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke instance status token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("instance has no status token")
+	}
+
+	i.StatusTokenHash = nil
+	i.StatusTokenPrefix = nil
+	i.UpdatedAt = time.Now().UTC()
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// FindInstanceByStatusToken retrieves the instance a status token hash belongs to
+func FindInstanceByStatusToken(ctx context.Context, db *sqlx.DB, hash string) (*Instance, error) {
+	var instance Instance
+	query := `
+		SELECT ` + instanceColumns + `
+		FROM instances
+		WHERE status_token_hash = $1
+	`
+
+	err := db.GetContext(ctx, &instance, query, hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("instance not found")
+		}
+		return nil, fmt.Errorf("failed to find instance: %w", err)
+	}
+
+	return &instance, nil
+}
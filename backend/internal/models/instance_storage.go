@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Managed storage statuses
+const (
+	StorageProvisioning = "provisioning" // the bucket is being created
+	StorageActive       = "active"       // PocketBase stores uploads in the bucket
+	StorageDeleting     = "deleting"     // the bucket is deleted in the background
+)
+
+// ErrStorageNotFound is returned when an instance has no managed storage
+var ErrStorageNotFound = errors.New("instance has no managed storage")
+
+// ErrStorageExists is returned when an instance already has managed storage
+var ErrStorageExists = errors.New("instance already has managed storage")
+
+// InstanceStorage is the managed S3 bucket of an instance
+type InstanceStorage struct {
+	ID             string     `db:"id" json:"id"`
+	InstanceID     *uuid.UUID `db:"instance_id" json:"instance_id,omitempty"`
+	Bucket         string     `db:"bucket" json:"bucket"`
+	Region         string     `db:"region" json:"region"`
+	Endpoint       string     `db:"endpoint" json:"endpoint"`
+	AccessKeyID    *string    `db:"access_key_id" json:"access_key_id,omitempty"`
+	Status         string     `db:"status" json:"status"`
+	BytesUsed      int64      `db:"bytes_used" json:"bytes_used"`
+	Objects        int64      `db:"objects" json:"objects"`
+	UsageUpdatedAt *time.Time `db:"usage_updated_at" json:"usage_updated_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateInstanceStorage records a bucket being provisioned
+func CreateInstanceStorage(ctx context.Context, db *sqlx.DB, storage *InstanceStorage) error {
+	query := `
+		INSERT INTO instance_storage (instance_id, bucket, region, endpoint)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at
+	`
+	err := db.QueryRowxContext(ctx, query, storage.InstanceID, storage.Bucket, storage.Region, storage.Endpoint).
+		Scan(&storage.ID, &storage.Status, &storage.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrStorageExists
+		}
+		return fmt.Errorf("failed to create instance storage: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceStorage returns the storage of an instance
+func FindInstanceStorage(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID) (*InstanceStorage, error) {
+	var storage InstanceStorage
+	if err := db.GetContext(ctx, &storage, `SELECT * FROM instance_storage WHERE instance_id = $1`, instanceID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStorageNotFound
+		}
+		return nil, fmt.Errorf("failed to find instance storage: %w", err)
+	}
+
+	return &storage, nil
+}
+
+// FindInstanceStorageByStatus returns the storage rows with a status, plus
+// (for StorageDeleting) those whose instance no longer exists
+func FindInstanceStorageByStatus(ctx context.Context, db *sqlx.DB, status string) ([]InstanceStorage, error) {
+	storage := []InstanceStorage{}
+	query := `
+		SELECT * FROM instance_storage
+		WHERE status = $1 OR ($1 = 'deleting' AND instance_id IS NULL)
+		ORDER BY created_at ASC
+	`
+	if err := db.SelectContext(ctx, &storage, query, status); err != nil {
+		return nil, fmt.Errorf("failed to list instance storage: %w", err)
+	}
+
+	return storage, nil
+}
+
+// ActivateInstanceStorage records the access key of a provisioned bucket
+func ActivateInstanceStorage(ctx context.Context, db *sqlx.DB, storage *InstanceStorage, accessKeyID string) error {
+	query := `UPDATE instance_storage SET status = $1, access_key_id = $2 WHERE id = $3`
+	if _, err := db.ExecContext(ctx, query, StorageActive, accessKeyID, storage.ID); err != nil {
+		return fmt.Errorf("failed to activate instance storage: %w", err)
+	}
+
+	storage.Status = StorageActive
+	storage.AccessKeyID = &accessKeyID
+
+	return nil
+}
+
+// MarkInstanceStorageDeleting detaches a bucket from its instance so it is
+// deleted in the background
+func MarkInstanceStorageDeleting(ctx context.Context, db *sqlx.DB, storage *InstanceStorage) error {
+	query := `UPDATE instance_storage SET status = $1, instance_id = NULL WHERE id = $2`
+	if _, err := db.ExecContext(ctx, query, StorageDeleting, storage.ID); err != nil {
+		return fmt.Errorf("failed to update instance storage: %w", err)
+	}
+
+	storage.Status = StorageDeleting
+	storage.InstanceID = nil
+
+	return nil
+}
+
+// UpdateInstanceStorageUsage records what a bucket stores
+func UpdateInstanceStorageUsage(ctx context.Context, db *sqlx.DB, id string, bytesUsed, objects int64) error {
+	query := `UPDATE instance_storage SET bytes_used = $1, objects = $2, usage_updated_at = NOW() WHERE id = $3`
+	if _, err := db.ExecContext(ctx, query, bytesUsed, objects, id); err != nil {
+		return fmt.Errorf("failed to update storage usage: %w", err)
+	}
+	return nil
+}
+
+// DeleteInstanceStorage removes the row of a deleted bucket
+func DeleteInstanceStorage(ctx context.Context, db *sqlx.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM instance_storage WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete instance storage: %w", err)
+	}
+	return nil
+}
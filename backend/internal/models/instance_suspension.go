@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Suspend moves the instance into the suspended state with the given reason.
+// Instances pending deletion cannot be suspended.
+func (i *Instance) Suspend(ctx context.Context, db *sqlx.DB, reason string) error {
+	query := `
+		UPDATE instances 
+		SET status = $1, suspension_reason = $2, suspended_at = NOW(), updated_at = NOW()
+		WHERE id = $3 AND status NOT IN ($1, $4)
+		RETURNING suspended_at, updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, InstanceStatusSuspended, reason, i.ID, InstanceStatusPendingDeletion).
+		Scan(&i.SuspendedAt, &i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance is already suspended or pending deletion")
+		}
+		return fmt.Errorf("failed to suspend instance: %w", err)
+	}
+
+	i.Status = InstanceStatusSuspended
+	i.SuspensionReason = &reason
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
+
+// Unsuspend lifts a suspension; the instance stays stopped until its owner starts it
+func (i *Instance) Unsuspend(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		UPDATE instances 
+		SET status = $1, suspension_reason = NULL, suspended_at = NULL, updated_at = NOW()
+		WHERE id = $2 AND status = $3
+		RETURNING updated_at
+	`
+
+	err := db.QueryRowxContext(ctx, query, InstanceStatusStopped, i.ID, InstanceStatusSuspended).Scan(&i.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("instance is not suspended")
+		}
+		return fmt.Errorf("failed to lift instance suspension: %w", err)
+	}
+
+	i.Status = InstanceStatusStopped
+	i.SuspensionReason = nil
+	i.SuspendedAt = nil
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxInstanceTags is the number of tags an instance may carry
+const MaxInstanceTags = 20
+
+// ErrInvalidTags wraps the reason tags were rejected
+var ErrInvalidTags = errors.New("invalid tags")
+
+// tagKeyPattern matches tag keys such as "env", "project" or "team.owner"
+var tagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// Tags are an instance's key=value tags, read from instance_tags
+type Tags map[string]string
+
+// Scan reads the tags aggregated as a JSON object by instanceColumns
+func (t *Tags) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case nil:
+		*t = Tags{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", src)
+	}
+}
+
+// Validate checks the number of tags and the format of keys and values
+func (t Tags) Validate() error {
+	if len(t) > MaxInstanceTags {
+		return fmt.Errorf("%w: an instance can have at most %d tags", ErrInvalidTags, MaxInstanceTags)
+	}
+
+	for key, value := range t {
+		if !tagKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: keys must be lowercase letters, digits, '.', '_' or '-' (at most 63 characters)", ErrInvalidTags)
+		}
+		if len(value) > 255 || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return fmt.Errorf("%w: values must be printable and at most 255 characters", ErrInvalidTags)
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether the tags satisfy every selector
+func (t Tags) Matches(selectors []TagSelector) bool {
+	for _, selector := range selectors {
+		value, ok := t[selector.Key]
+		if !ok || (selector.HasValue && value != selector.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// TagSelector selects instances by tag: "key=value" matches the value,
+// a bare "key" matches any value
+type TagSelector struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// ParseTagSelector parses "key=value" or "key"
+func ParseTagSelector(s string) (TagSelector, error) {
+	key, value, hasValue := strings.Cut(strings.TrimSpace(s), "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !tagKeyPattern.MatchString(key) {
+		return TagSelector{}, fmt.Errorf("invalid tag selector %q", s)
+	}
+
+	return TagSelector{Key: key, Value: value, HasValue: hasValue}, nil
+}
+
+// SetTags replaces all of an instance's tags
+func (i *Instance) SetTags(ctx context.Context, db *sqlx.DB, tags Tags) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM instance_tags WHERE instance_id = $1`, i.ID); err != nil {
+		return fmt.Errorf("failed to update tags: %w", err)
+	}
+	for key, value := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO instance_tags (instance_id, key, value) VALUES ($1, $2, $3)`, i.ID, key, value); err != nil {
+			return fmt.Errorf("failed to update tags: %w", err)
+		}
+	}
+	if err := tx.QueryRowxContext(ctx, `UPDATE instances SET updated_at = NOW() WHERE id = $1 RETURNING updated_at`, i.ID).Scan(&i.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update tags: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tags: %w", err)
+	}
+
+	i.Tags = tags
+
+	cacheInstance(ctx, i)
+	instanceChanged(ctx, i)
+
+	return nil
+}
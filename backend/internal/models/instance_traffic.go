@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// InstanceTrafficDay holds an instance's request analytics for one UTC day
+type InstanceTrafficDay struct {
+	InstanceID uuid.UUID `db:"instance_id" json:"-"`
+	Day        time.Time `db:"day" json:"day"`
+	Requests   int64     `db:"requests" json:"requests"`
+	BytesOut   int64     `db:"bytes_out" json:"bytes_out"`
+	Status2xx  int64     `db:"status_2xx" json:"status_2xx"`
+	Status3xx  int64     `db:"status_3xx" json:"status_3xx"`
+	Status4xx  int64     `db:"status_4xx" json:"status_4xx"`
+	Status5xx  int64     `db:"status_5xx" json:"status_5xx"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// GetAccessLogOffset returns the stored read position for an access log file
+func GetAccessLogOffset(ctx context.Context, db *sqlx.DB, path string) (int64, error) {
+	var offset int64
+	err := db.GetContext(ctx, &offset, `SELECT file_offset FROM access_log_offsets WHERE path = $1`, path)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get access log offset: %w", err)
+	}
+
+	return offset, nil
+}
+
+// RecordInstanceTraffic adds traffic counters and stores the new access log offset atomically
+func RecordInstanceTraffic(ctx context.Context, db *sqlx.DB, path string, offset int64, days []InstanceTrafficDay) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Rows are attributed to the instance's owner so they keep counting towards
+	// the owner's bandwidth after the instance is deleted. Traffic of instances
+	// that no longer exist is skipped.
+	upsert := `
+		INSERT INTO instance_traffic_daily (
+			instance_id, user_id, day, requests, bytes_out, status_2xx, status_3xx, status_4xx, status_5xx, updated_at
+		)
+		SELECT i.id, i.user_id, CAST(:day AS date), CAST(:requests AS bigint), CAST(:bytes_out AS bigint),
+		       CAST(:status_2xx AS bigint), CAST(:status_3xx AS bigint), CAST(:status_4xx AS bigint), CAST(:status_5xx AS bigint), NOW()
+		FROM instances i
+		WHERE i.id = :instance_id
+		ON CONFLICT (instance_id, day) DO UPDATE SET
+			requests = instance_traffic_daily.requests + EXCLUDED.requests,
+			bytes_out = instance_traffic_daily.bytes_out + EXCLUDED.bytes_out,
+			status_2xx = instance_traffic_daily.status_2xx + EXCLUDED.status_2xx,
+			status_3xx = instance_traffic_daily.status_3xx + EXCLUDED.status_3xx,
+			status_4xx = instance_traffic_daily.status_4xx + EXCLUDED.status_4xx,
+			status_5xx = instance_traffic_daily.status_5xx + EXCLUDED.status_5xx,
+			updated_at = NOW()
+	`
+	for i := range days {
+		if _, err := tx.NamedExecContext(ctx, upsert, &days[i]); err != nil {
+			return fmt.Errorf("failed to record instance traffic: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO access_log_offsets (path, file_offset, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (path) DO UPDATE SET file_offset = EXCLUDED.file_offset, updated_at = NOW()
+	`, path, offset)
+	if err != nil {
+		return fmt.Errorf("failed to store access log offset: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// FindInstanceTraffic retrieves an instance's daily traffic since the given day, oldest first
+func FindInstanceTraffic(ctx context.Context, db *sqlx.DB, instanceID uuid.UUID, since time.Time) ([]InstanceTrafficDay, error) {
+	days := []InstanceTrafficDay{}
+	query := `
+		SELECT instance_id, day, requests, bytes_out, status_2xx, status_3xx, status_4xx, status_5xx, updated_at
+		FROM instance_traffic_daily
+		WHERE instance_id = $1 AND day >= $2
+		ORDER BY day ASC
+	`
+
+	if err := db.SelectContext(ctx, &days, query, instanceID, since); err != nil {
+		return nil, fmt.Errorf("failed to find instance traffic: %w", err)
+	}
+
+	return days, nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// InviteCode represents a signup invitation code
+type InviteCode struct {
+	ID              string     `db:"id" json:"id"`
+	Code            string     `db:"code" json:"code"`
+	Note            *string    `db:"note" json:"note,omitempty"`
+	MaxUses         int        `db:"max_uses" json:"max_uses"`
+	UseCount        int        `db:"use_count" json:"use_count"`
+	CreatedByUserID *string    `db:"created_by_user_id" json:"created_by_user_id,omitempty"`
+	ExpiresAt       *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	RevokedAt       *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateInviteRequest represents the request body for generating invite codes
+type CreateInviteRequest struct {
+	MaxUses        int    `json:"max_uses" validate:"omitempty,min=1,max=10000"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1"`
+	Note           string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// bytesPerGB converts metered bytes to GB
+const bytesPerGB = 1024 * 1024 * 1024
+
+// MeteringRecord is a user's usage during one UTC hour
+type MeteringRecord struct {
+	UserID         uuid.UUID `db:"user_id" json:"user_id"`
+	Username       string    `db:"username" json:"username"`
+	Hour           time.Time `db:"hour" json:"hour"`
+	InstanceHours  float64   `db:"instance_hours" json:"instance_hours"`
+	StorageGBHours float64   `db:"storage_gb_hours" json:"storage_gb_hours"`
+	EgressGB       float64   `db:"egress_gb" json:"egress_gb"`
+}
+
+// RecordHourlyMetering meters the hour starting at hour from the resource
+// samples taken every sampleInterval and returns the number of users metered.
+// Running an hour again replaces its records.
+//
+// An instance running all hour counts one instance-hour (fewer samples count
+// proportionally); storage is the latest data size of each instance at the end
+// of the hour; egress sums the growth of each container's transmit counter,
+// which restarts from zero when the container does.
+func RecordHourlyMetering(ctx context.Context, db *sqlx.DB, hour time.Time, sampleInterval time.Duration) (int64, error) {
+	end := hour.Add(time.Hour)
+	// Samples just before the hour give the counters the first deltas start from
+	lookback := hour.Add(-2 * sampleInterval)
+
+	query := `
+		WITH samples AS (
+			SELECT i.user_id, m.instance_id, m.recorded_at, m.network_tx_bytes,
+			       m.network_tx_bytes - LAG(m.network_tx_bytes) OVER (PARTITION BY m.instance_id ORDER BY m.recorded_at) AS tx_delta
+			FROM instance_metrics m
+			JOIN instances i ON i.id = m.instance_id
+			WHERE m.recorded_at >= $3 AND m.recorded_at < $2
+		),
+		running AS (
+			SELECT user_id, instance_id,
+			       LEAST(COUNT(*) * $4::DOUBLE PRECISION / 3600, 1) AS instance_hours,
+			       SUM(CASE WHEN tx_delta IS NULL THEN 0 WHEN tx_delta < 0 THEN network_tx_bytes ELSE tx_delta END) AS egress_bytes
+			FROM samples
+			WHERE recorded_at >= $1
+			GROUP BY user_id, instance_id
+		),
+		usage AS (
+			SELECT user_id, SUM(instance_hours) AS instance_hours, SUM(egress_bytes) AS egress_bytes
+			FROM running
+			GROUP BY user_id
+		),
+		storage AS (
+			SELECT i.user_id, SUM(latest.disk_bytes) AS disk_bytes
+			FROM instances i
+			CROSS JOIN LATERAL (
+				SELECT disk_bytes
+				FROM instance_metrics
+				WHERE instance_id = i.id AND recorded_at < $2
+				ORDER BY recorded_at DESC
+				LIMIT 1
+			) latest
+			GROUP BY i.user_id
+		)
+		INSERT INTO usage_metering (user_id, hour, instance_hours, storage_gb_hours, egress_gb)
+		SELECT COALESCE(u.user_id, s.user_id), $1,
+		       COALESCE(u.instance_hours, 0),
+		       COALESCE(s.disk_bytes, 0)::DOUBLE PRECISION / $5,
+		       COALESCE(u.egress_bytes, 0)::DOUBLE PRECISION / $5
+		FROM usage u
+		FULL OUTER JOIN storage s ON s.user_id = u.user_id
+		ON CONFLICT (user_id, hour) DO UPDATE
+		SET instance_hours = EXCLUDED.instance_hours,
+		    storage_gb_hours = EXCLUDED.storage_gb_hours,
+		    egress_gb = EXCLUDED.egress_gb,
+		    created_at = NOW()
+	`
+
+	result, err := db.ExecContext(ctx, query, hour, end, lookback, sampleInterval.Seconds(), bytesPerGB)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record metering: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetLastMeteredHour returns the latest hour metered, or nil if none was
+func GetLastMeteredHour(ctx context.Context, db *sqlx.DB) (*time.Time, error) {
+	var hour sql.NullTime
+	if err := db.GetContext(ctx, &hour, `SELECT MAX(hour) FROM usage_metering`); err != nil {
+		return nil, fmt.Errorf("failed to get last metered hour: %w", err)
+	}
+	if !hour.Valid {
+		return nil, nil
+	}
+
+	return &hour.Time, nil
+}
+
+// meteringRecordsQuery selects the records of hours in [$1, $2), of user $3 if not null
+const meteringRecordsQuery = `
+	SELECT m.user_id, u.username, m.hour, m.instance_hours, m.storage_gb_hours, m.egress_gb
+	FROM usage_metering m
+	JOIN users u ON u.id = m.user_id
+	WHERE m.hour >= $1 AND m.hour < $2 AND ($3::uuid IS NULL OR m.user_id = $3)
+	ORDER BY m.hour, u.username
+`
+
+// FindMeteringRecords returns the records of hours in [from, to), optionally
+// of a single user, ordered by hour and user
+func FindMeteringRecords(ctx context.Context, db *sqlx.DB, from, to time.Time, userID *uuid.UUID) ([]MeteringRecord, error) {
+	records := []MeteringRecord{}
+	if err := db.SelectContext(ctx, &records, meteringRecordsQuery, from, to, userID); err != nil {
+		return nil, fmt.Errorf("failed to find metering records: %w", err)
+	}
+
+	return records, nil
+}
+
+// StreamMeteringRecords passes the records FindMeteringRecords would return to fn
+func StreamMeteringRecords(ctx context.Context, db *sqlx.DB, from, to time.Time, userID *uuid.UUID, fn func(*MeteringRecord) error) error {
+	if err := streamRows(ctx, db, meteringRecordsQuery, fn, from, to, userID); err != nil {
+		return fmt.Errorf("failed to export metering records: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// PlatformStatus holds the admin-managed maintenance flag and announcement banner
+type PlatformStatus struct {
+	MaintenanceEnabled bool      `db:"maintenance_enabled" json:"maintenance_enabled"`
+	MaintenanceMessage *string   `db:"maintenance_message" json:"maintenance_message,omitempty"`
+	Announcement       *string   `db:"announcement" json:"announcement,omitempty"`
+	UpdatedByUserID    *string   `db:"updated_by_user_id" json:"-"`
+	UpdatedAt          time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// UpdatePlatformStatusRequest represents the request body for changing the platform status
+type UpdatePlatformStatusRequest struct {
+	MaintenanceEnabled bool   `json:"maintenance_enabled"`
+	MaintenanceMessage string `json:"maintenance_message,omitempty" validate:"omitempty,max=500"`
+	Announcement       string `json:"announcement,omitempty" validate:"omitempty,max=1000"`
+}
+
+// PlatformSettings holds the administrators' overrides of environment settings
+// (nil keeps the environment value)
+type PlatformSettings struct {
+	MaxInstancesPerUser       *int      `db:"max_instances_per_user" json:"max_instances_per_user"`
+	InstanceDataRetentionDays *int      `db:"instance_data_retention_days" json:"instance_data_retention_days"`
+	PocketBaseImage           *string   `db:"pocketbase_image" json:"pocketbase_image"`
+	SignupEnabled             *bool     `db:"signup_enabled" json:"signup_enabled"`
+	UpdatedByUserID           *string   `db:"updated_by_user_id" json:"-"`
+	UpdatedAt                 time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// EffectivePlatformSettings are the values in use after applying the overrides
+type EffectivePlatformSettings struct {
+	MaxInstancesPerUser       int    `json:"max_instances_per_user"`
+	InstanceDataRetentionDays int    `json:"instance_data_retention_days"`
+	PocketBaseImage           string `json:"pocketbase_image"`
+	SignupEnabled             bool   `json:"signup_enabled"`
+}
+
+// UpdatePlatformSettingsRequest represents the request body for changing platform
+// settings. Omitted fields are left as they are; fields listed in Reset go back
+// to the environment value.
+type UpdatePlatformSettingsRequest struct {
+	MaxInstancesPerUser       *int     `json:"max_instances_per_user,omitempty" validate:"omitempty,min=0,max=1000"`
+	InstanceDataRetentionDays *int     `json:"instance_data_retention_days,omitempty" validate:"omitempty,min=0,max=3650"`
+	PocketBaseImage           *string  `json:"pocketbase_image,omitempty" validate:"omitempty,min=1,max=255"`
+	SignupEnabled             *bool    `json:"signup_enabled,omitempty"`
+	Reset                     []string `json:"reset,omitempty" validate:"omitempty,dive,oneof=max_instances_per_user instance_data_retention_days pocketbase_image signup_enabled"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlatformHealthSample is a point-in-time view of Docker and instance health
+type PlatformHealthSample struct {
+	ID               int64     `db:"id"`
+	CheckedAt        time.Time `db:"checked_at"`
+	DockerHealthy    bool      `db:"docker_healthy"`
+	InstancesRunning int       `db:"instances_running"`
+	InstancesFailed  int       `db:"instances_failed"`
+}
+
+// PlatformAvailability aggregates health samples over a period
+type PlatformAvailability struct {
+	Samples              int      `db:"samples"`
+	DockerUptimeRatio    *float64 `db:"docker_uptime_ratio"`
+	InstanceAvailability *float64 `db:"instance_availability"`
+}
+
+// CreatePlatformHealthSample stores a health sample
+func CreatePlatformHealthSample(ctx context.Context, db *sqlx.DB, sample *PlatformHealthSample) error {
+	query := `
+		INSERT INTO platform_health_samples (checked_at, docker_healthy, instances_running, instances_failed)
+		VALUES (:checked_at, :docker_healthy, :instances_running, :instances_failed)
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, sample); err != nil {
+		return fmt.Errorf("failed to store platform health sample: %w", err)
+	}
+
+	return nil
+}
+
+// GetPlatformAvailability aggregates samples recorded since the given time.
+// Instance availability is the share of running instances among those expected to be up.
+func GetPlatformAvailability(ctx context.Context, db *sqlx.DB, since time.Time) (*PlatformAvailability, error) {
+	var availability PlatformAvailability
+	query := `
+		SELECT
+			COUNT(*) AS samples,
+			AVG(CASE WHEN docker_healthy THEN 1.0 ELSE 0.0 END) AS docker_uptime_ratio,
+			SUM(instances_running)::float / NULLIF(SUM(instances_running + instances_failed), 0) AS instance_availability
+		FROM platform_health_samples
+		WHERE checked_at >= $1
+	`
+
+	if err := db.GetContext(ctx, &availability, query, since); err != nil {
+		return nil, fmt.Errorf("failed to get platform availability: %w", err)
+	}
+
+	return &availability, nil
+}
+
+// CountInstancesByStatus returns the number of instances in each status
+func CountInstancesByStatus(ctx context.Context, db *sqlx.DB) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	query := `SELECT status, COUNT(*) AS count FROM instances GROUP BY status`
+
+	if err := db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to count instances by status: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+// DeletePlatformHealthSamplesBefore removes samples older than the given time
+func DeletePlatformHealthSamplesBefore(ctx context.Context, db *sqlx.DB, before time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM platform_health_samples WHERE checked_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune platform health samples: %w", err)
+	}
+
+	return result.RowsAffected()
+}
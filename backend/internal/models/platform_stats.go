@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// PlatformStats are the platform-wide totals and daily series shown on the
+// admin dashboard
+type PlatformStats struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Days        int       `json:"days"` // length of the series
+
+	Users             int            `json:"users"`
+	Instances         int            `json:"instances"`
+	InstancesByStatus map[string]int `json:"instances_by_status"`
+
+	// Instances whose provisioning failed in the last 24 hours, including
+	// ones deleted since
+	FailedProvisions24h int `json:"failed_provisions_24h"`
+
+	Disk    []RegionDiskUsage `json:"disk"`
+	Host    HostDiskUsage     `json:"host"`
+	Volumes []HostDiskUsage   `json:"volumes"` // INSTANCES_BASE_PATH and STORAGE_VOLUMES
+
+	Series PlatformStatsSeries `json:"series"`
+}
+
+// PlatformStatsSeries are daily counts for charts, oldest first, with a bucket
+// for every day of the period (days without events count 0)
+type PlatformStatsSeries struct {
+	Signups          []StatsBucket `json:"signups"`
+	InstancesCreated []StatsBucket `json:"instances_created"`
+	FailedProvisions []StatsBucket `json:"failed_provisions"`
+}
+
+// StatsBucket is the count of events on one day (UTC)
+type StatsBucket struct {
+	Day   time.Time `db:"day" json:"day"`
+	Count int       `db:"count" json:"count"`
+}
+
+// RegionDiskUsage is the storage used by a region's instances, from their
+// latest resource usage samples
+type RegionDiskUsage struct {
+	RegionID  string `db:"region_id" json:"region_id"`
+	Instances int    `db:"instances" json:"instances"`
+	DiskBytes int64  `db:"disk_bytes" json:"disk_bytes"`
+}
+
+// HostDiskUsage is the space on a volume holding instances (-1 if it can't be
+// determined). Headroom is what new and restored instances may still use
+// before MIN_FREE_DISK_SPACE is reached.
+type HostDiskUsage struct {
+	Path          string `json:"path"`
+	TotalBytes    int64  `json:"total_bytes"`
+	FreeBytes     int64  `json:"free_bytes"`
+	ReserveBytes  int64  `json:"reserve_bytes"`
+	HeadroomBytes int64  `json:"headroom_bytes"`
+}
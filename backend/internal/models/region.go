@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DefaultRegionID is the region configured through BASE_DOMAIN, used when an
+// instance is created without choosing a region
+const DefaultRegionID = "default"
+
+// Region is a placement option for instances
+type Region struct {
+	ID                string    `db:"id" json:"id"`
+	Name              string    `db:"name" json:"name"`
+	BaseDomain        string    `db:"base_domain" json:"base_domain"`
+	TraefikEntrypoint string    `db:"traefik_entrypoint" json:"traefik_entrypoint"`
+	DockerNetwork     *string   `db:"docker_network" json:"docker_network,omitempty"`
+	Enabled           bool      `db:"enabled" json:"enabled"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateRegionRequest represents the request body for adding a region
+type CreateRegionRequest struct {
+	ID                string `json:"id" validate:"required,min=2,max=32,alphanum_hyphen"`
+	Name              string `json:"name" validate:"required,max=100"`
+	BaseDomain        string `json:"base_domain" validate:"required,fqdn,max=253"`
+	TraefikEntrypoint string `json:"traefik_entrypoint,omitempty" validate:"omitempty,max=64,alphanum_hyphen"`
+	DockerNetwork     string `json:"docker_network,omitempty" validate:"omitempty,max=255"`
+}
+
+// UpdateRegionRequest represents the request body for changing a region.
+// Omitted fields are left as they are.
+type UpdateRegionRequest struct {
+	Name              *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	TraefikEntrypoint *string `json:"traefik_entrypoint,omitempty" validate:"omitempty,min=1,max=64,alphanum_hyphen"`
+	Enabled           *bool   `json:"enabled,omitempty"`
+}
+
+// ErrRegionExists is returned when a region ID is already in use
+var ErrRegionExists = errors.New("region already exists")
+
+const regionColumns = `id, name, base_domain, traefik_entrypoint, docker_network, enabled, created_at, updated_at`
+
+// FindRegions retrieves all regions, or only the enabled ones
+func FindRegions(ctx context.Context, db *sqlx.DB, enabledOnly bool) ([]Region, error) {
+	regions := []Region{}
+	query := `
+		SELECT ` + regionColumns + `
+		FROM regions
+		WHERE enabled OR NOT $1
+		ORDER BY id = 'default' DESC, name ASC
+	`
+
+	if err := db.SelectContext(ctx, &regions, query, enabledOnly); err != nil {
+		return nil, fmt.Errorf("failed to find regions: %w", err)
+	}
+
+	return regions, nil
+}
+
+// FindRegionByID retrieves a region by its ID
+func FindRegionByID(ctx context.Context, db *sqlx.DB, id string) (*Region, error) {
+	var region Region
+	query := `SELECT ` + regionColumns + ` FROM regions WHERE id = $1`
+
+	if err := db.GetContext(ctx, &region, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("region not found")
+		}
+		return nil, fmt.Errorf("failed to find region: %w", err)
+	}
+
+	return &region, nil
+}
+
+// Create inserts a new region
+func (r *Region) Create(ctx context.Context, db *sqlx.DB) error {
+	r.CreatedAt = time.Now().UTC()
+	r.UpdatedAt = r.CreatedAt
+
+	query := `
+		INSERT INTO regions (id, name, base_domain, traefik_entrypoint, docker_network, enabled, created_at, updated_at)
+		VALUES (:id, :name, :base_domain, :traefik_entrypoint, :docker_network, :enabled, :created_at, :updated_at)
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, r); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrRegionExists
+		}
+		return fmt.Errorf("failed to create region: %w", err)
+	}
+
+	return nil
+}
+
+// Update saves the region's name, entrypoint and enabled flag (a new entrypoint
+// applies to instances created afterwards). The base domain and network are
+// fixed, as existing instances were created with them.
+func (r *Region) Update(ctx context.Context, db *sqlx.DB) error {
+	r.UpdatedAt = time.Now().UTC()
+
+	query := `
+		UPDATE regions
+		SET name = :name, traefik_entrypoint = :traefik_entrypoint, enabled = :enabled, updated_at = :updated_at
+		WHERE id = :id
+	`
+
+	if _, err := db.NamedExecContext(ctx, query, r); err != nil {
+		return fmt.Errorf("failed to update region: %w", err)
+	}
+
+	return nil
+}
+
+// SyncDefaultRegion sets the default region's base domain (from BASE_DOMAIN)
+func SyncDefaultRegion(ctx context.Context, db *sqlx.DB, baseDomain string) error {
+	query := `
+		UPDATE regions
+		SET base_domain = $1, updated_at = NOW()
+		WHERE id = $2 AND base_domain != $1
+	`
+
+	if _, err := db.ExecContext(ctx, query, baseDomain, DefaultRegionID); err != nil {
+		return fmt.Errorf("failed to sync default region: %w", err)
+	}
+
+	return nil
+}
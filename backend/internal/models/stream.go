@@ -0,0 +1,30 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// streamRows scans the rows of a query one at a time and passes them to fn,
+// so exports don't hold the whole result in memory. It stops at the first
+// error fn returns.
+func streamRows[T any](ctx context.Context, db *sqlx.DB, query string, fn func(*T) error, args ...interface{}) error {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Reasons a subdomain is unavailable
+const (
+	SubdomainReservedInUse    = "in_use"   // a live instance uses it
+	SubdomainReservedRetained = "retained" // a deleted instance's data is retained
+)
+
+// SubdomainReservation explains why a subdomain can't be used. Subdomains of
+// deleted instances stay reserved while their data is retained, so they can be
+// restored under the same address, and are released once the data is purged.
+type SubdomainReservation struct {
+	Subdomain     string     `db:"subdomain" json:"subdomain"`
+	Reason        string     `db:"reason" json:"reason"`
+	ReservedUntil *time.Time `db:"reserved_until" json:"reserved_until,omitempty"` // set for retained subdomains
+	Message       string     `db:"-" json:"message"`
+}
+
+// FindSubdomainReservation returns what reserves a subdomain, or nil when it's free
+func FindSubdomainReservation(ctx context.Context, db *sqlx.DB, subdomain string) (*SubdomainReservation, error) {
+	var reservation SubdomainReservation
+	query := `
+		SELECT subdomain, 'in_use' AS reason, NULL::timestamptz AS reserved_until
+		FROM instances
+		WHERE subdomain = $1
+		UNION ALL
+		(SELECT subdomain, 'retained' AS reason, data_retained_until AS reserved_until
+		 FROM instances_archive
+		 WHERE subdomain = $1 AND data_available = true
+		 ORDER BY data_retained_until DESC)
+		LIMIT 1
+	`
+
+	if err := db.GetContext(ctx, &reservation, query, subdomain); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check subdomain reservation: %w", err)
+	}
+
+	if reservation.Reason == SubdomainReservedRetained && reservation.ReservedUntil != nil {
+		reservation.Message = fmt.Sprintf("%s is reserved for a deleted instance until %s, when its data retention ends",
+			subdomain, reservation.ReservedUntil.UTC().Format("2006-01-02 15:04 MST"))
+	} else {
+		reservation.Message = fmt.Sprintf("%s is used by another instance", subdomain)
+	}
+
+	return &reservation, nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Subscription is the Stripe subscription behind a user's paid plan
+type Subscription struct {
+	UserID               string     `db:"user_id" json:"-"`
+	StripeCustomerID     string     `db:"stripe_customer_id" json:"-"`
+	StripeSubscriptionID *string    `db:"stripe_subscription_id" json:"-"`
+	Plan                 string     `db:"plan" json:"plan"`
+	Status               string     `db:"status" json:"status"`
+	CurrentPeriodEnd     *time.Time `db:"current_period_end" json:"current_period_end,omitempty"`
+	GraceUntil           *time.Time `db:"grace_until" json:"grace_until,omitempty"`
+	CreatedAt            time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// GetSubscription returns a user's subscription, or nil if they never subscribed
+func GetSubscription(ctx context.Context, db *sqlx.DB, userID string) (*Subscription, error) {
+	var subscription Subscription
+	err := db.GetContext(ctx, &subscription, `SELECT * FROM subscriptions WHERE user_id = $1`, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// FindSubscriptionByStripeID returns the subscription with a Stripe subscription ID, or nil
+func FindSubscriptionByStripeID(ctx context.Context, db *sqlx.DB, stripeSubscriptionID string) (*Subscription, error) {
+	var subscription Subscription
+	err := db.GetContext(ctx, &subscription, `SELECT * FROM subscriptions WHERE stripe_subscription_id = $1`, stripeSubscriptionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find subscription: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// FindSubscriptionByCustomerID returns the subscription of a Stripe customer, or nil
+func FindSubscriptionByCustomerID(ctx context.Context, db *sqlx.DB, stripeCustomerID string) (*Subscription, error) {
+	var subscription Subscription
+	err := db.GetContext(ctx, &subscription, `SELECT * FROM subscriptions WHERE stripe_customer_id = $1`, stripeCustomerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find subscription: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// FindSubscriptionsPastGrace returns the lapsed subscriptions whose grace period ended before now
+func FindSubscriptionsPastGrace(ctx context.Context, db *sqlx.DB, now time.Time) ([]Subscription, error) {
+	var subscriptions []Subscription
+	err := db.SelectContext(ctx, &subscriptions, `SELECT * FROM subscriptions WHERE grace_until <= $1`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lapsed subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// SaveSubscription stores a subscription and sets the owner's plan to userPlan
+// in the same transaction
+func SaveSubscription(ctx context.Context, db *sqlx.DB, subscription *Subscription, userPlan string) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO subscriptions (user_id, stripe_customer_id, stripe_subscription_id, plan, status, current_period_end, grace_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET stripe_customer_id = EXCLUDED.stripe_customer_id,
+		    stripe_subscription_id = EXCLUDED.stripe_subscription_id,
+		    plan = EXCLUDED.plan,
+		    status = EXCLUDED.status,
+		    current_period_end = EXCLUDED.current_period_end,
+		    grace_until = EXCLUDED.grace_until,
+		    updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRowxContext(ctx, query,
+		subscription.UserID,
+		subscription.StripeCustomerID,
+		subscription.StripeSubscriptionID,
+		subscription.Plan,
+		subscription.Status,
+		subscription.CurrentPeriodEnd,
+		subscription.GraceUntil,
+	).Scan(&subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET plan = $1, updated_at = NOW() WHERE id = $2`, userPlan, subscription.UserID); err != nil {
+		return fmt.Errorf("failed to update plan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subscription: %w", err)
+	}
+
+	return nil
+}
@@ -14,14 +14,27 @@ type RefreshToken struct {
 	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
 	IPAddress string     `db:"ip_address" json:"ip_address"`
 	UserAgent string     `db:"user_agent" json:"user_agent"`
+	Country   string     `db:"country" json:"country,omitempty"` // from GeoIP, when configured
+	City      string     `db:"city" json:"city,omitempty"`
+
+	// Label the user gave the session (e.g. "work laptop")
+	Name *string `db:"name" json:"name,omitempty"`
+
+	// Hash of the device cookie of a trusted device, and when the trust ends
+	DeviceTokenHash *string    `db:"device_token_hash" json:"-"`
+	TrustedUntil    *time.Time `db:"trusted_until" json:"trusted_until,omitempty"`
+
+	// Hash of the token of the sign-out link mailed for a new device login
+	RevokeTokenHash *string `db:"revoke_token_hash" json:"-"`
 }
 
-// RefreshRequest represents the request body for refreshing access token
-type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token" validate:"required"`
+// UpdateSessionRequest represents the request body for labelling a session
+type UpdateSessionRequest struct {
+	Name string `json:"name" validate:"max=100"`
 }
 
-// LogoutRequest represents the request body for logout
-type LogoutRequest struct {
+// RefreshRequest represents the request body for refreshing the access token
+// or logging out (when refresh tokens are delivered in the body)
+type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
@@ -11,28 +11,65 @@ type User struct {
 	Email        string     `db:"email" json:"email"`
 	PasswordHash string     `db:"password_hash" json:"-"`
 	IsActive     bool       `db:"is_active" json:"is_active"`
+	IsAdmin      bool       `db:"is_admin" json:"is_admin"`
+	Plan         string     `db:"plan" json:"plan"`
 	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
 	LastLoginAt  *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+
+	// Profile shown in the dashboard header
+	DisplayName string  `db:"display_name" json:"display_name"`
+	AvatarFile  *string `db:"avatar_file" json:"-"`
+	Timezone    string  `db:"timezone" json:"timezone"`
+	Locale      string  `db:"locale" json:"locale"`
+
+	BandwidthQuotaOverrideGB *int `db:"bandwidth_quota_override_gb" json:"-"`
 }
 
+// DefaultPlan is the plan assigned to new users
+const DefaultPlan = "free"
+
+// Profile defaults of new users
+const (
+	DefaultTimezone = "UTC"
+	DefaultLocale   = "en"
+)
+
 // SignupRequest represents the request body for user registration
 type SignupRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=50,alphanum_hyphen"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8,password_strength"`
+	Username     string `json:"username" validate:"required,min=3,max=50,alphanum_hyphen"`
+	Email        string `json:"email" validate:"required,email"`
+	Password     string `json:"password" validate:"required,min=8,password_strength"`
+	InviteCode   string `json:"invite_code,omitempty" validate:"omitempty,max=64"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // LoginRequest represents the request body for user login
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email        string `json:"email" validate:"required,email"`
+	Password     string `json:"password" validate:"required"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	DeviceToken  string `json:"device_token,omitempty"` // when the device cookie can't be used
 }
 
 // UpdateUserRequest represents the request body for updating user profile
 type UpdateUserRequest struct {
-	Username string `json:"username,omitempty" validate:"omitempty,min=3,max=50,alphanum_hyphen"`
-	Email    string `json:"email,omitempty" validate:"omitempty,email"`
+	Username    string  `json:"username,omitempty" validate:"omitempty,min=3,max=50,alphanum_hyphen"`
+	Email       string  `json:"email,omitempty" validate:"omitempty,email"`
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=100"`
+	Timezone    *string `json:"timezone,omitempty" validate:"omitempty,max=64"`
+	Locale      *string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
+}
+
+// UpdatePasswordRequest represents the request body for changing the password
+type UpdatePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,password_strength"`
+}
+
+// DeleteAccountRequest represents the request body for deleting the account
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // required when REAUTH_REQUIRED_FOR_DELETION is set
 }
 
 // UserResponse represents the public user data returned to clients
@@ -41,20 +78,40 @@ type UserResponse struct {
 	Username    string     `json:"username"`
 	Email       string     `json:"email"`
 	IsActive    bool       `json:"is_active"`
+	IsAdmin     bool       `json:"is_admin"`
+	Plan        string     `json:"plan"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	DisplayName string     `json:"display_name"`
+	AvatarURL   string     `json:"avatar_url,omitempty"`
+	Timezone    string     `json:"timezone"`
+	Locale      string     `json:"locale"`
 }
 
+// AvatarURLPrefix is the public path avatar files are served under
+const AvatarURLPrefix = "/api/v1/avatars/"
+
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
+	avatarURL := ""
+	if u.AvatarFile != nil {
+		avatarURL = AvatarURLPrefix + *u.AvatarFile
+	}
+
 	return UserResponse{
 		ID:          u.ID,
 		Username:    u.Username,
 		Email:       u.Email,
 		IsActive:    u.IsActive,
+		IsAdmin:     u.IsAdmin,
+		Plan:        u.Plan,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
 		LastLoginAt: u.LastLoginAt,
+		DisplayName: u.DisplayName,
+		AvatarURL:   avatarURL,
+		Timezone:    u.Timezone,
+		Locale:      u.Locale,
 	}
 }
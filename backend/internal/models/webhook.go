@@ -0,0 +1,224 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Webhook event types
+const (
+	WebhookEventInstanceCreated       = "instance.created"
+	WebhookEventInstanceStatusChanged = "instance.status_changed"
+	WebhookEventInstanceDeleted       = "instance.deleted"
+)
+
+// WebhookEvents lists every webhook event type
+var WebhookEvents = []string{WebhookEventInstanceCreated, WebhookEventInstanceStatusChanged, WebhookEventInstanceDeleted}
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // retries exhausted; can be redelivered
+)
+
+// ErrWebhookNotFound is returned for unknown or foreign webhook endpoints
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrWebhookDeliveryNotFound is returned for unknown or foreign deliveries
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookEndpoint receives a user's instance lifecycle events
+type WebhookEndpoint struct {
+	ID        uuid.UUID      `db:"id" json:"id"`
+	UserID    uuid.UUID      `db:"user_id" json:"user_id"`
+	URL       string         `db:"url" json:"url"`
+	Secret    string         `db:"secret" json:"-"`      // encrypted with the secrets vault
+	Events    pq.StringArray `db:"events" json:"events"` // empty means all
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent (or to be sent) to an endpoint
+type WebhookDelivery struct {
+	ID             uuid.UUID       `db:"id" json:"id"`
+	EndpointID     uuid.UUID       `db:"endpoint_id" json:"endpoint_id"`
+	EventType      string          `db:"event_type" json:"event_type"`
+	Payload        json.RawMessage `db:"payload" json:"payload"`
+	Status         string          `db:"status" json:"status"`
+	Attempts       int             `db:"attempts" json:"attempts"`
+	ResponseStatus *int            `db:"response_status" json:"response_status,omitempty"`
+	ResponseBody   *string         `db:"response_body" json:"response_body,omitempty"`
+	LastError      *string         `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at" json:"delivered_at,omitempty"`
+}
+
+// CreateWebhookRequest represents the body of POST /api/v1/webhooks
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2000"`
+	Events []string `json:"events" validate:"omitempty,dive,oneof=instance.created instance.status_changed instance.deleted"`
+}
+
+// CreateWebhookEndpoint stores a new webhook endpoint
+func CreateWebhookEndpoint(ctx context.Context, db *sqlx.DB, endpoint *WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	err := db.QueryRowxContext(ctx, query, endpoint.UserID, endpoint.URL, endpoint.Secret, endpoint.Events).
+		Scan(&endpoint.ID, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookEndpoints returns a user's webhook endpoints, newest first
+func ListWebhookEndpoints(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]WebhookEndpoint, error) {
+	endpoints := []WebhookEndpoint{}
+	query := `SELECT * FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at DESC`
+	if err := db.SelectContext(ctx, &endpoints, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return endpoints, nil
+}
+
+// CountWebhookEndpoints counts a user's webhook endpoints
+func CountWebhookEndpoints(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (int, error) {
+	var count int
+	if err := db.GetContext(ctx, &count, `SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	return count, nil
+}
+
+// FindWebhookEndpoint retrieves one of a user's webhook endpoints
+func FindWebhookEndpoint(ctx context.Context, db *sqlx.DB, id, userID uuid.UUID) (*WebhookEndpoint, error) {
+	var endpoint WebhookEndpoint
+	query := `SELECT * FROM webhook_endpoints WHERE id = $1 AND user_id = $2`
+	if err := db.GetContext(ctx, &endpoint, query, id, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to find webhook: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// DeleteWebhookEndpoint removes one of a user's webhook endpoints and its deliveries
+func DeleteWebhookEndpoint(ctx context.Context, db *sqlx.DB, id, userID uuid.UUID) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// UpdateSecret replaces the secret deliveries are signed with
+func (e *WebhookEndpoint) UpdateSecret(ctx context.Context, db *sqlx.DB, secret string) error {
+	query := `UPDATE webhook_endpoints SET secret = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := db.ExecContext(ctx, query, secret, e.ID); err != nil {
+		return fmt.Errorf("failed to update webhook secret: %w", err)
+	}
+	e.Secret = secret
+	e.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// CreateWebhookDeliveries records an event for each of a user's endpoints
+// subscribed to it
+func CreateWebhookDeliveries(ctx context.Context, db *sqlx.DB, userID uuid.UUID, eventType string, payload []byte) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	query := `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT id, $2, $3
+		FROM webhook_endpoints
+		WHERE user_id = $1 AND (cardinality(events) = 0 OR $2 = ANY(events))
+		RETURNING *
+	`
+	// The payload is passed as a string: lib/pq would encode []byte as bytea
+	if err := db.SelectContext(ctx, &deliveries, query, userID, eventType, string(payload)); err != nil {
+		return nil, fmt.Errorf("failed to record webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// FindWebhookEndpointByID retrieves a webhook endpoint regardless of its owner
+func FindWebhookEndpointByID(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*WebhookEndpoint, error) {
+	var endpoint WebhookEndpoint
+	if err := db.GetContext(ctx, &endpoint, `SELECT * FROM webhook_endpoints WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to find webhook: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// FindWebhookDelivery retrieves a delivery
+func FindWebhookDelivery(ctx context.Context, db *sqlx.DB, id uuid.UUID) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := db.GetContext(ctx, &delivery, `SELECT * FROM webhook_deliveries WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ListWebhookDeliveries returns an endpoint's most recent deliveries, newest first
+func ListWebhookDeliveries(ctx context.Context, db *sqlx.DB, endpointID uuid.UUID, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	query := `
+		SELECT * FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	if err := db.SelectContext(ctx, &deliveries, query, endpointID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt saves the outcome of sending the delivery
+func (d *WebhookDelivery) RecordAttempt(ctx context.Context, db *sqlx.DB, status string, responseStatus *int, responseBody, lastError *string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, response_status = $2, response_body = $3, last_error = $4,
+		    delivered_at = CASE WHEN $1 = 'succeeded' THEN NOW() ELSE delivered_at END
+		WHERE id = $5
+		RETURNING attempts, delivered_at
+	`
+	if err := db.QueryRowxContext(ctx, query, status, responseStatus, responseBody, lastError, d.ID).Scan(&d.Attempts, &d.DeliveredAt); err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	d.Status = status
+	d.ResponseStatus = responseStatus
+	d.ResponseBody = responseBody
+	d.LastError = lastError
+	return nil
+}
+
+// ResetForRedelivery marks a delivery pending again
+func (d *WebhookDelivery) ResetForRedelivery(ctx context.Context, db *sqlx.DB) error {
+	if _, err := db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = 'pending' WHERE id = $1`, d.ID); err != nil {
+		return fmt.Errorf("failed to reset webhook delivery: %w", err)
+	}
+	d.Status = WebhookDeliveryPending
+	return nil
+}
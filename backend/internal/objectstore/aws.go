@@ -0,0 +1,329 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pocketploy/internal/sigv4"
+)
+
+const (
+	iamEndpoint = "https://iam.amazonaws.com/"
+	iamRegion   = "us-east-1" // IAM is global; requests are signed for us-east-1
+	iamVersion  = "2010-05-08"
+
+	// iamPath groups the users created for buckets
+	iamPath = "/pocketploy/"
+
+	// bucketPolicyName is the inline policy granting a user its bucket
+	bucketPolicyName = "bucket-access"
+)
+
+// aws provisions S3 buckets with an IAM user per bucket
+type aws struct {
+	region      string
+	endpoint    string
+	pathStyle   bool // path-style URLs for custom endpoints, virtual-hosted for AWS
+	credentials sigv4.Credentials
+	httpClient  *http.Client
+}
+
+// apiError is an error response of S3 or IAM
+type apiError struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *apiError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d %s", e.Status, e.Code)
+}
+
+// isCode reports whether err is an API error with one of the codes
+func isCode(err error, codes ...string) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *aws) CreateBucket(ctx context.Context, name string) (*Bucket, error) {
+	var body []byte
+	if a.region != "us-east-1" {
+		body = []byte(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>` +
+			a.region + `</LocationConstraint></CreateBucketConfiguration>`)
+	}
+	err := a.s3(ctx, http.MethodPut, name, "", nil, body, nil)
+	if err != nil && !isCode(err, "BucketAlreadyOwnedByYou") {
+		return nil, fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	err = a.iam(ctx, url.Values{"Action": {"CreateUser"}, "UserName": {name}, "Path": {iamPath}}, nil)
+	if err != nil && !isCode(err, "EntityAlreadyExists") {
+		return nil, fmt.Errorf("failed to create IAM user: %w", err)
+	}
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:ListBucket", "s3:GetBucketLocation"},
+				"Resource": "arn:aws:s3:::" + name,
+			},
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:GetObjectAcl", "s3:PutObjectAcl"},
+				"Resource": "arn:aws:s3:::" + name + "/*",
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bucket policy: %w", err)
+	}
+	err = a.iam(ctx, url.Values{
+		"Action":         {"PutUserPolicy"},
+		"UserName":       {name},
+		"PolicyName":     {bucketPolicyName},
+		"PolicyDocument": {string(policy)},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant bucket access: %w", err)
+	}
+
+	// Keys of an earlier attempt can't be read back, and a user may only have
+	// two, so they are replaced rather than added to
+	if err := a.deleteAccessKeys(ctx, name); err != nil {
+		return nil, err
+	}
+
+	var created struct {
+		AccessKeyID     string `xml:"CreateAccessKeyResult>AccessKey>AccessKeyId"`
+		SecretAccessKey string `xml:"CreateAccessKeyResult>AccessKey>SecretAccessKey"`
+	}
+	if err := a.iam(ctx, url.Values{"Action": {"CreateAccessKey"}, "UserName": {name}}, &created); err != nil {
+		return nil, fmt.Errorf("failed to create access key: %w", err)
+	}
+
+	return &Bucket{
+		Name:            name,
+		Region:          a.region,
+		Endpoint:        a.endpoint,
+		ForcePathStyle:  a.pathStyle,
+		User:            name,
+		AccessKeyID:     created.AccessKeyID,
+		SecretAccessKey: created.SecretAccessKey,
+	}, nil
+}
+
+func (a *aws) DeleteBucket(ctx context.Context, name string) error {
+	if err := a.deleteAccessKeys(ctx, name); err != nil {
+		return err
+	}
+
+	err := a.iam(ctx, url.Values{"Action": {"DeleteUserPolicy"}, "UserName": {name}, "PolicyName": {bucketPolicyName}}, nil)
+	if err != nil && !isCode(err, "NoSuchEntity") {
+		return fmt.Errorf("failed to delete bucket policy: %w", err)
+	}
+	err = a.iam(ctx, url.Values{"Action": {"DeleteUser"}, "UserName": {name}}, nil)
+	if err != nil && !isCode(err, "NoSuchEntity") {
+		return fmt.Errorf("failed to delete IAM user: %w", err)
+	}
+
+	err = a.listObjects(ctx, name, func(key string, _ int64) error {
+		if err := a.s3(ctx, http.MethodDelete, name, key, nil, nil, nil); err != nil && !isCode(err, "NoSuchKey") {
+			return fmt.Errorf("failed to delete object %s: %w", key, err)
+		}
+		return nil
+	})
+	if isCode(err, "NoSuchBucket") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := a.s3(ctx, http.MethodDelete, name, "", nil, nil, nil); err != nil && !isCode(err, "NoSuchBucket") {
+		return fmt.Errorf("failed to delete bucket: %w", err)
+	}
+	return nil
+}
+
+// deleteAccessKeys deletes the access keys of a bucket's IAM user
+func (a *aws) deleteAccessKeys(ctx context.Context, name string) error {
+	var keys struct {
+		IDs []string `xml:"ListAccessKeysResult>AccessKeyMetadata>member>AccessKeyId"`
+	}
+	err := a.iam(ctx, url.Values{"Action": {"ListAccessKeys"}, "UserName": {name}}, &keys)
+	if err != nil && !isCode(err, "NoSuchEntity") {
+		return fmt.Errorf("failed to list access keys: %w", err)
+	}
+	for _, id := range keys.IDs {
+		err := a.iam(ctx, url.Values{"Action": {"DeleteAccessKey"}, "UserName": {name}, "AccessKeyId": {id}}, nil)
+		if err != nil && !isCode(err, "NoSuchEntity") {
+			return fmt.Errorf("failed to delete access key: %w", err)
+		}
+	}
+	return nil
+}
+
+func (a *aws) Usage(ctx context.Context, name string) (Usage, error) {
+	var usage Usage
+	err := a.listObjects(ctx, name, func(_ string, size int64) error {
+		usage.Bytes += size
+		usage.Objects++
+		return nil
+	})
+	if err != nil {
+		return Usage{}, err
+	}
+	return usage, nil
+}
+
+// listObjects calls fn for every object of a bucket, a page at a time
+func (a *aws) listObjects(ctx context.Context, name string, fn func(key string, size int64) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		var page struct {
+			Contents []struct {
+				Key  string `xml:"Key"`
+				Size int64  `xml:"Size"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := a.s3(ctx, http.MethodGet, name, "", query, nil, &page); err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if err := fn(object.Key, object.Size); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// s3 calls the S3 API on a bucket (or one of its objects when key is set)
+// and decodes the XML response into out (if not nil)
+func (a *aws) s3(ctx context.Context, method, bucket, key string, query url.Values, body []byte, out interface{}) error {
+	base, err := url.Parse(a.endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid storage endpoint: %w", err)
+	}
+
+	path := "/" + uriEncode(key)
+	if a.pathStyle {
+		path = strings.TrimSuffix(base.Path, "/") + "/" + bucket + path
+	} else {
+		base.Host = bucket + "." + base.Host
+	}
+
+	endpoint := base.Scheme + "://" + base.Host + path
+	if rawQuery := sigv4.CanonicalQuery(query); rawQuery != "" {
+		endpoint += "?" + rawQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	sigv4.Sign(req, body, a.credentials, a.region, "s3", time.Now())
+
+	return a.do(req, out)
+}
+
+// iam calls an IAM action and decodes the XML response into out (if not nil)
+func (a *aws) iam(ctx context.Context, params url.Values, out interface{}) error {
+	params.Set("Version", iamVersion)
+	body := []byte(sigv4.CanonicalQuery(params))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, iamEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build IAM request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	sigv4.Sign(req, body, a.credentials, iamRegion, "iam", time.Now())
+
+	return a.do(req, out)
+}
+
+// do sends a signed request and decodes its XML response
+func (a *aws) do(req *http.Request, out interface{}) error {
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &apiError{Status: resp.StatusCode}
+		var wrapped struct {
+			Error apiError `xml:"Error"`
+		}
+		// IAM wraps the error in an ErrorResponse, S3 doesn't
+		if xml.Unmarshal(data, &wrapped) == nil && wrapped.Error.Code != "" {
+			apiErr.Code, apiErr.Message = wrapped.Error.Code, wrapped.Error.Message
+		} else {
+			_ = xml.Unmarshal(data, apiErr)
+		}
+		return apiErr
+	}
+
+	if out != nil {
+		if err := xml.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// uriEncode escapes an object key for the request path, keeping slashes
+func uriEncode(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeAWS answers S3 and IAM requests, recording the IAM actions called
+type fakeAWS struct {
+	accessKeys []string
+	actions    []string
+}
+
+func (f *fakeAWS) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.URL.Host == "iam.amazonaws.com" {
+		data, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(data))
+		action := params.Get("Action")
+		f.actions = append(f.actions, action)
+
+		switch action {
+		case "ListAccessKeys":
+			body = "<ListAccessKeysResponse><ListAccessKeysResult><AccessKeyMetadata>"
+			for _, id := range f.accessKeys {
+				body += "<member><AccessKeyId>" + id + "</AccessKeyId></member>"
+			}
+			body += "</AccessKeyMetadata></ListAccessKeysResult></ListAccessKeysResponse>"
+		case "DeleteAccessKey":
+			for i, id := range f.accessKeys {
+				if id == params.Get("AccessKeyId") {
+					f.accessKeys = append(f.accessKeys[:i], f.accessKeys[i+1:]...)
+					break
+				}
+			}
+		case "CreateAccessKey":
+			f.accessKeys = append(f.accessKeys, "NEWKEY")
+			body = "<CreateAccessKeyResponse><CreateAccessKeyResult><AccessKey>" +
+				"<AccessKeyId>NEWKEY</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>" +
+				"</AccessKey></CreateAccessKeyResult></CreateAccessKeyResponse>"
+		}
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/xml"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestCreateBucketReplacesEarlierAccessKeys(t *testing.T) {
+	fake := &fakeAWS{accessKeys: []string{"OLDKEY1", "OLDKEY2"}}
+	provider := &aws{
+		region:     "us-east-1",
+		endpoint:   "https://s3.us-east-1.amazonaws.com",
+		httpClient: &http.Client{Transport: fake},
+	}
+
+	bucket, err := provider.CreateBucket(context.Background(), "pocketploy-test")
+	if err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	if bucket.AccessKeyID != "NEWKEY" || bucket.SecretAccessKey != "secret" {
+		t.Errorf("bucket credentials = %s/%s, want the new key", bucket.AccessKeyID, bucket.SecretAccessKey)
+	}
+
+	wantActions := []string{"CreateUser", "PutUserPolicy", "ListAccessKeys", "DeleteAccessKey", "DeleteAccessKey", "CreateAccessKey"}
+	if !reflect.DeepEqual(fake.actions, wantActions) {
+		t.Errorf("IAM actions = %v, want %v", fake.actions, wantActions)
+	}
+	if !reflect.DeepEqual(fake.accessKeys, []string{"NEWKEY"}) {
+		t.Errorf("access keys = %v, want only the new key", fake.accessKeys)
+	}
+}
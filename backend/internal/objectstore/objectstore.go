@@ -0,0 +1,90 @@
+// Package objectstore provisions S3 buckets for instance file uploads, with
+// credentials limited to each bucket, and measures how much they store.
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"pocketploy/internal/sigv4"
+)
+
+// Supported providers
+const (
+	ProviderAWS = "aws"
+)
+
+// Bucket is a provisioned bucket and the credentials PocketBase uses for it
+type Bucket struct {
+	Name           string
+	Region         string
+	Endpoint       string
+	ForcePathStyle bool
+
+	// User owns the access key; only the bucket's objects are granted to it
+	User            string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Usage is what a bucket stores
+type Usage struct {
+	Bytes   int64
+	Objects int64
+}
+
+// Provider creates and deletes buckets at an object storage service
+type Provider interface {
+	// CreateBucket creates a private bucket and credentials limited to it.
+	// Parts created before a failure are removed by DeleteBucket.
+	CreateBucket(ctx context.Context, name string) (*Bucket, error)
+
+	// DeleteBucket revokes the bucket's credentials, then deletes its objects
+	// and the bucket. Parts that no longer exist are skipped.
+	DeleteBucket(ctx context.Context, name string) error
+
+	// Usage adds up the objects of a bucket
+	Usage(ctx context.Context, name string) (Usage, error)
+}
+
+// Config configures the provider; only the fields of the chosen provider are used
+type Config struct {
+	Region   string
+	Endpoint string // overrides the provider's S3 endpoint for the region
+
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+}
+
+// NewProvider creates the client for the given provider.
+// It returns nil when provider is empty, which disables managed storage.
+func NewProvider(provider string, cfg Config) (Provider, error) {
+	httpClient := &http.Client{Timeout: 60 * time.Second}
+
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "":
+		return nil, nil
+	case ProviderAWS:
+		endpoint, pathStyle := cfg.Endpoint, true
+		if endpoint == "" {
+			endpoint, pathStyle = "https://s3."+cfg.Region+".amazonaws.com", false
+		}
+		return &aws{
+			region:    cfg.Region,
+			endpoint:  endpoint,
+			pathStyle: pathStyle,
+			credentials: sigv4.Credentials{
+				AccessKeyID:     cfg.AWSAccessKeyID,
+				SecretAccessKey: cfg.AWSSecretAccessKey,
+				SessionToken:    cfg.AWSSessionToken,
+			},
+			httpClient: httpClient,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage provider: %s", provider)
+	}
+}
@@ -1,324 +1,421 @@
 package repositories
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
 	"time"
 
+	"github.com/google/uuid"
+
 	"pocketploy/internal/database"
 	"pocketploy/internal/models"
+	"pocketploy/internal/secrets"
 )
 
-// InstanceRepository handles all database operations for instances
+// InstanceRepository handles all database operations for instances and their
+// archive. The queries live in the models package (shared with the background
+// workers, which keep the instance cache and change events consistent); this
+// type binds them to a database for the service layer.
 type InstanceRepository struct {
-	db *database.DB
+	db    *database.DB
+	vault *secrets.Vault // encrypts settings encryption keys at rest
 }
 
 // NewInstanceRepository creates a new instance repository
-func NewInstanceRepository(db *database.DB) *InstanceRepository {
-	return &InstanceRepository{db: db}
-}
-
-// Create inserts a new instance into the database
-func (r *InstanceRepository) Create(instance *models.Instance) error {
-	query := `
-		INSERT INTO instances (
-			id, user_id, name, slug, subdomain, container_id, container_name,
-			status, data_path, created_at, updated_at
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
-	_, err := r.db.Exec(query,
-		instance.ID,
-		instance.UserID,
-		instance.Name,
-		instance.Slug,
-		instance.Subdomain,
-		instance.ContainerID,
-		instance.ContainerName,
-		instance.Status,
-		instance.DataPath,
-		instance.CreatedAt,
-		instance.UpdatedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create instance: %w", err)
-	}
-	return nil
+func NewInstanceRepository(db *database.DB, vault *secrets.Vault) *InstanceRepository {
+	return &InstanceRepository{db: db, vault: vault}
 }
 
-// GetByID retrieves an instance by its ID
-func (r *InstanceRepository) GetByID(id string) (*models.Instance, error) {
-	var instance models.Instance
-	query := `SELECT * FROM instances WHERE id = $1`
-	err := r.db.Get(&instance, query, id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("instance not found")
-		}
-		return nil, fmt.Errorf("failed to get instance: %w", err)
-	}
-	return &instance, nil
-}
-
-// GetByUserID retrieves all instances for a specific user
-func (r *InstanceRepository) GetByUserID(userID string) ([]*models.Instance, error) {
-	var instances []*models.Instance
-	query := `
-		SELECT * FROM instances 
-		WHERE user_id = $1 
-		ORDER BY created_at DESC
-	`
-	err := r.db.Select(&instances, query, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get instances for user: %w", err)
-	}
-	return instances, nil
+// CreateInstance inserts a new instance, enforcing params.MaxPerUser
+func (r *InstanceRepository) CreateInstance(ctx context.Context, instance *models.Instance, params models.CreateInstanceParams) error {
+	return instance.Create(ctx, r.db.DB, params)
 }
 
-// GetByName retrieves an instance by name and user ID
-func (r *InstanceRepository) GetByName(userID, name string) (*models.Instance, error) {
-	var instance models.Instance
-	query := `SELECT * FROM instances WHERE user_id = $1 AND name = $2`
-	err := r.db.Get(&instance, query, userID, name)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("instance not found")
-		}
-		return nil, fmt.Errorf("failed to get instance by name: %w", err)
-	}
-	return &instance, nil
+// FindInstanceByID retrieves an instance by its ID
+func (r *InstanceRepository) FindInstanceByID(ctx context.Context, id uuid.UUID) (*models.Instance, error) {
+	return models.FindInstanceByID(ctx, r.db.DB, id)
 }
 
-// GetByContainerID retrieves an instance by its container ID
-func (r *InstanceRepository) GetByContainerID(containerID string) (*models.Instance, error) {
-	var instance models.Instance
-	query := `SELECT * FROM instances WHERE container_id = $1`
-	err := r.db.Get(&instance, query, containerID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("instance not found")
-		}
-		return nil, fmt.Errorf("failed to get instance by container id: %w", err)
-	}
-	return &instance, nil
-}
-
-// Update updates an existing instance
-func (r *InstanceRepository) Update(instance *models.Instance) error {
-	instance.UpdatedAt = time.Now().UTC()
-	query := `
-		UPDATE instances 
-		SET name = $1, slug = $2, status = $3, container_id = $4, 
-		    container_name = $5, subdomain = $6, data_path = $7, updated_at = $8
-		WHERE id = $9
-	`
-	result, err := r.db.Exec(query,
-		instance.Name,
-		instance.Slug,
-		instance.Status,
-		instance.ContainerID,
-		instance.ContainerName,
-		instance.Subdomain,
-		instance.DataPath,
-		instance.UpdatedAt,
-		instance.ID,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update instance: %w", err)
-	}
+// FindInstanceBySubdomain retrieves an instance by its subdomain
+func (r *InstanceRepository) FindInstanceBySubdomain(ctx context.Context, subdomain string) (*models.Instance, error) {
+	return models.FindInstanceBySubdomain(ctx, r.db.DB, subdomain)
+}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rows == 0 {
-		return fmt.Errorf("instance not found")
-	}
+// FindInstancesByUserID retrieves all instances of a user
+func (r *InstanceRepository) FindInstancesByUserID(ctx context.Context, userID uuid.UUID) ([]models.Instance, error) {
+	return models.FindInstancesByUserID(ctx, r.db.DB, userID)
+}
 
-	return nil
+// FindInstancesDueForDeletion retrieves pending deletions whose grace period has ended
+func (r *InstanceRepository) FindInstancesDueForDeletion(ctx context.Context) ([]models.Instance, error) {
+	return models.FindInstancesDueForDeletion(ctx, r.db.DB)
 }
 
-// UpdateStatus updates only the status of an instance
-func (r *InstanceRepository) UpdateStatus(id, status string) error {
-	query := `UPDATE instances SET status = $1, updated_at = $2 WHERE id = $3`
-	result, err := r.db.Exec(query, status, time.Now().UTC(), id)
-	if err != nil {
-		return fmt.Errorf("failed to update instance status: %w", err)
-	}
+// FindExpiredInstances retrieves ephemeral instances past their expiry
+func (r *InstanceRepository) FindExpiredInstances(ctx context.Context) ([]models.Instance, error) {
+	return models.FindExpiredInstances(ctx, r.db.DB)
+}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rows == 0 {
-		return fmt.Errorf("instance not found")
-	}
+// FindPreview retrieves an instance's preview environment for a ref
+func (r *InstanceRepository) FindPreview(ctx context.Context, sourceID uuid.UUID, ref string) (*models.Instance, error) {
+	return models.FindPreview(ctx, r.db.DB, sourceID, ref)
+}
 
-	return nil
+// FindPreviews retrieves an instance's preview environments
+func (r *InstanceRepository) FindPreviews(ctx context.Context, sourceID uuid.UUID) ([]models.Instance, error) {
+	return models.FindPreviews(ctx, r.db.DB, sourceID)
 }
 
-// UpdateContainerID updates only the container ID of an instance
-func (r *InstanceRepository) UpdateContainerID(id, containerID string) error {
-	query := `UPDATE instances SET container_id = $1, updated_at = $2 WHERE id = $3`
-	result, err := r.db.Exec(query, containerID, time.Now().UTC(), id)
-	if err != nil {
-		return fmt.Errorf("failed to update container id: %w", err)
-	}
+// SearchInstances searches instances, optionally limited to one user
+func (r *InstanceRepository) SearchInstances(ctx context.Context, userID *uuid.UUID, term string, limit int) ([]models.Instance, error) {
+	return models.SearchInstances(ctx, r.db.DB, userID, term, limit)
+}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rows == 0 {
-		return fmt.Errorf("instance not found")
-	}
+// CountUserInstances counts a user's instances that count against their limit
+func (r *InstanceRepository) CountUserInstances(ctx context.Context, userID uuid.UUID) (int, error) {
+	return models.CountUserInstances(ctx, r.db.DB, userID)
+}
 
-	return nil
+// SubdomainInUse reports whether a live or retained archived instance uses a subdomain
+func (r *InstanceRepository) SubdomainInUse(ctx context.Context, subdomain string) (bool, error) {
+	return models.SubdomainInUse(ctx, r.db.DB, subdomain)
 }
 
-// Delete permanently removes an instance from the database
-func (r *InstanceRepository) Delete(id string) error {
-	query := `DELETE FROM instances WHERE id = $1`
-	result, err := r.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete instance: %w", err)
-	}
+// FindSubdomainReservation explains why a subdomain is unavailable (nil when it's free)
+func (r *InstanceRepository) FindSubdomainReservation(ctx context.Context, subdomain string) (*models.SubdomainReservation, error) {
+	return models.FindSubdomainReservation(ctx, r.db.DB, subdomain)
+}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rows == 0 {
-		return fmt.Errorf("instance not found")
-	}
+// UpdateStatus updates an instance's status
+func (r *InstanceRepository) UpdateStatus(ctx context.Context, instance *models.Instance, status string) error {
+	return instance.UpdateStatus(ctx, r.db.DB, status)
+}
+
+// UpdateContainerInfo stores the container backing an instance
+func (r *InstanceRepository) UpdateContainerInfo(ctx context.Context, instance *models.Instance, containerID, containerName string) error {
+	return instance.UpdateContainerInfo(ctx, r.db.DB, containerID, containerName)
+}
 
-	return nil
+// UpdateServeOptions saves an instance's pocketbase serve flags
+func (r *InstanceRepository) UpdateServeOptions(ctx context.Context, instance *models.Instance, options models.ServeOptions) error {
+	return instance.UpdateServeOptions(ctx, r.db.DB, options)
 }
 
-// Archive moves an instance to the archive table
-func (r *InstanceRepository) Archive(instance *models.Instance) error {
-	// Begin transaction
-	tx, err := r.db.Begin()
+// UpdateDescription saves an instance's description
+func (r *InstanceRepository) UpdateDescription(ctx context.Context, instance *models.Instance, description string) error {
+	return instance.UpdateDescription(ctx, r.db.DB, description)
+}
+
+// UpdateDataPath saves the directory an instance's data lives in
+func (r *InstanceRepository) UpdateDataPath(ctx context.Context, instance *models.Instance, dataPath string) error {
+	return instance.UpdateDataPath(ctx, r.db.DB, dataPath)
+}
+
+// SetProtected turns an instance's deletion protection on or off
+func (r *InstanceRepository) SetProtected(ctx context.Context, instance *models.Instance, protected bool) error {
+	return instance.SetProtected(ctx, r.db.DB, protected)
+}
+
+// SetStatusToken stores (or, with nil values, removes) an instance's status token
+func (r *InstanceRepository) SetStatusToken(ctx context.Context, instance *models.Instance, hash, prefix *string) error {
+	return instance.SetStatusToken(ctx, r.db.DB, hash, prefix)
+}
+
+// RevokeStatusToken removes an instance's status token, failing if it has none
+func (r *InstanceRepository) RevokeStatusToken(ctx context.Context, instance *models.Instance) error {
+	return instance.RevokeStatusToken(ctx, r.db.DB)
+}
+
+// FindInstanceByStatusToken retrieves the instance a status token hash belongs to
+func (r *InstanceRepository) FindInstanceByStatusToken(ctx context.Context, hash string) (*models.Instance, error) {
+	return models.FindInstanceByStatusToken(ctx, r.db.DB, hash)
+}
+
+// SetTags replaces an instance's tags
+func (r *InstanceRepository) SetTags(ctx context.Context, instance *models.Instance, tags models.Tags) error {
+	return instance.SetTags(ctx, r.db.DB, tags)
+}
+
+// SetPinned pins or unpins an instance
+func (r *InstanceRepository) SetPinned(ctx context.Context, instance *models.Instance, pinned bool) error {
+	return instance.SetPinned(ctx, r.db.DB, pinned)
+}
+
+// RecordMigrationRun stores a migration run, keeping the most recent keep runs
+func (r *InstanceRepository) RecordMigrationRun(ctx context.Context, run *models.InstanceMigrationRun, keep int) error {
+	return models.RecordInstanceMigrationRun(ctx, r.db.DB, run, keep)
+}
+
+// FindMigrationRuns retrieves an instance's migration runs
+func (r *InstanceRepository) FindMigrationRuns(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceMigrationRun, error) {
+	return models.FindInstanceMigrationRuns(ctx, r.db.DB, instanceID)
+}
+
+// CreateBuild queues a custom build
+func (r *InstanceRepository) CreateBuild(ctx context.Context, build *models.InstanceBuild) error {
+	return models.CreateInstanceBuild(ctx, r.db.DB, build)
+}
+
+// FindBuilds retrieves an instance's builds, newest first
+func (r *InstanceRepository) FindBuilds(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceBuild, error) {
+	return models.FindInstanceBuilds(ctx, r.db.DB, instanceID)
+}
+
+// FindBuild retrieves a build with its log
+func (r *InstanceRepository) FindBuild(ctx context.Context, id uuid.UUID) (*models.InstanceBuild, error) {
+	return models.FindInstanceBuildByID(ctx, r.db.DB, id)
+}
+
+// BuildInProgress reports whether an instance has a queued or running build
+func (r *InstanceRepository) BuildInProgress(ctx context.Context, instanceID uuid.UUID) (bool, error) {
+	return models.InstanceBuildInProgress(ctx, r.db.DB, instanceID)
+}
+
+// UpdateBuild saves a build's progress
+func (r *InstanceRepository) UpdateBuild(ctx context.Context, build *models.InstanceBuild) error {
+	return models.UpdateInstanceBuild(ctx, r.db.DB, build)
+}
+
+// RecordIntegrityCheck stores an integrity check, keeping the most recent keep checks
+func (r *InstanceRepository) RecordIntegrityCheck(ctx context.Context, check *models.InstanceIntegrityCheck, keep int) error {
+	return models.RecordInstanceIntegrityCheck(ctx, r.db.DB, check, keep)
+}
+
+// FindIntegrityChecks retrieves an instance's integrity checks
+func (r *InstanceRepository) FindIntegrityChecks(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceIntegrityCheck, error) {
+	return models.FindInstanceIntegrityChecks(ctx, r.db.DB, instanceID)
+}
+
+// FindInstancesDueForIntegrityCheck retrieves instances not checked since the given time
+func (r *InstanceRepository) FindInstancesDueForIntegrityCheck(ctx context.Context, since time.Time, limit int) ([]models.Instance, error) {
+	return models.FindInstancesDueForIntegrityCheck(ctx, r.db.DB, since, limit)
+}
+
+// ReorderInstances stores the order of a user's instances
+func (r *InstanceRepository) ReorderInstances(ctx context.Context, userID uuid.UUID, instanceIDs []uuid.UUID) error {
+	return models.ReorderInstances(ctx, r.db.DB, userID, instanceIDs)
+}
+
+// EnsureEncryptionKey returns an instance's settings encryption key, storing key if it has none
+func (r *InstanceRepository) EnsureEncryptionKey(ctx context.Context, id uuid.UUID, key string) (string, error) {
+	encrypted, err := r.vault.Encrypt(ctx, key)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return "", err
 	}
-	defer tx.Rollback()
-
-	// Insert into archive table
-	archiveQuery := `
-		INSERT INTO instances_archive (
-			id, user_id, name, slug, subdomain, container_id, container_name,
-			original_status, data_path, created_at, updated_at, deleted_at,
-			deleted_by_user_id, deletion_reason, data_available, 
-			data_retained_until, data_size_mb, original_subdomain
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
-	`
-	deletedAt := time.Now().UTC()
-	dataRetainedUntil := deletedAt.AddDate(0, 0, 30) // 30 days retention
-
-	_, err = tx.Exec(archiveQuery,
-		instance.ID,
-		instance.UserID,
-		instance.Name,
-		instance.Slug,
-		instance.Subdomain,
-		instance.ContainerID,
-		instance.ContainerName,
-		instance.Status,
-		instance.DataPath,
-		instance.CreatedAt,
-		instance.UpdatedAt,
-		deletedAt,
-		instance.UserID, // deleted_by_user_id
-		"Archived via repository",
-		true, // data_available
-		dataRetainedUntil,
-		0, // data_size_mb - calculate if needed
-		instance.Subdomain,
-	)
+	stored, err := models.EnsureInstanceEncryptionKey(ctx, r.db.DB, id, encrypted)
 	if err != nil {
-		return fmt.Errorf("failed to archive instance: %w", err)
+		return "", err
 	}
+	return r.vault.Decrypt(ctx, stored)
+}
 
-	// Delete from instances table
-	deleteQuery := `DELETE FROM instances WHERE id = $1`
-	_, err = tx.Exec(deleteQuery, instance.ID)
-	if err != nil {
-		return fmt.Errorf("failed to delete instance after archive: %w", err)
-	}
+// UpdateAccessProtection saves an instance's access protection and basic auth password hash
+func (r *InstanceRepository) UpdateAccessProtection(ctx context.Context, instance *models.Instance, protection models.AccessProtection, passwordHash string) error {
+	return instance.UpdateAccessProtection(ctx, r.db.DB, protection, passwordHash)
+}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
+// UpdateEgressPolicy saves an instance's egress policy
+func (r *InstanceRepository) UpdateEgressPolicy(ctx context.Context, instance *models.Instance, policy models.EgressPolicy) error {
+	return instance.UpdateEgressPolicy(ctx, r.db.DB, policy)
+}
 
-	return nil
+// FindAccessPasswordHash returns an instance's basic auth password hash
+func (r *InstanceRepository) FindAccessPasswordHash(ctx context.Context, id uuid.UUID) (string, error) {
+	return models.FindInstanceAccessPasswordHash(ctx, r.db.DB, id)
 }
 
-// ExistsByName checks if an instance with the given name exists for a user
-func (r *InstanceRepository) ExistsByName(userID, name string) (bool, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM instances WHERE user_id = $1 AND name = $2`
-	err := r.db.QueryRow(query, userID, name).Scan(&count)
-	if err != nil {
-		return false, fmt.Errorf("failed to check instance name existence: %w", err)
-	}
-	return count > 0, nil
+// UpdateLastAccessed records that an instance was accessed
+func (r *InstanceRepository) UpdateLastAccessed(ctx context.Context, instance *models.Instance) error {
+	return instance.UpdateLastAccessed(ctx, r.db.DB)
 }
 
-// List retrieves all instances (admin function)
-func (r *InstanceRepository) List() ([]*models.Instance, error) {
-	var instances []*models.Instance
-	query := `SELECT * FROM instances ORDER BY created_at DESC`
-	err := r.db.Select(&instances, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list instances: %w", err)
-	}
-	return instances, nil
+// ScheduleDeletion puts an instance into its deletion grace period
+func (r *InstanceRepository) ScheduleDeletion(ctx context.Context, instance *models.Instance, at time.Time, retentionDays int) error {
+	return instance.ScheduleDeletion(ctx, r.db.DB, at, retentionDays)
 }
 
-// Count returns the total number of instances
-func (r *InstanceRepository) Count() (int, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM instances`
-	err := r.db.QueryRow(query).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count instances: %w", err)
-	}
-	return count, nil
+// CancelDeletion restores an instance that is pending deletion
+func (r *InstanceRepository) CancelDeletion(ctx context.Context, instance *models.Instance) error {
+	return instance.CancelDeletion(ctx, r.db.DB)
 }
 
-// CountByUserID returns the total number of instances for a user
-func (r *InstanceRepository) CountByUserID(userID string) (int, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM instances WHERE user_id = $1`
-	err := r.db.QueryRow(query, userID).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count instances for user: %w", err)
-	}
-	return count, nil
+// Suspend suspends an instance
+func (r *InstanceRepository) Suspend(ctx context.Context, instance *models.Instance, reason string) error {
+	return instance.Suspend(ctx, r.db.DB, reason)
 }
 
-// CountByStatus returns the number of instances with a specific status
-func (r *InstanceRepository) CountByStatus(status string) (int, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM instances WHERE status = $1`
-	err := r.db.QueryRow(query, status).Scan(&count)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count instances by status: %w", err)
-	}
-	return count, nil
+// Unsuspend lifts an instance's suspension
+func (r *InstanceRepository) Unsuspend(ctx context.Context, instance *models.Instance) error {
+	return instance.Unsuspend(ctx, r.db.DB)
 }
 
-// GetByStatus retrieves all instances with a specific status
-func (r *InstanceRepository) GetByStatus(status string) ([]*models.Instance, error) {
-	var instances []*models.Instance
-	query := `SELECT * FROM instances WHERE status = $1 ORDER BY created_at DESC`
-	err := r.db.Select(&instances, query, status)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get instances by status: %w", err)
-	}
-	return instances, nil
+// ExtendExpiry moves an ephemeral instance's expiry
+func (r *InstanceRepository) ExtendExpiry(ctx context.Context, instance *models.Instance, expiresAt time.Time) error {
+	return instance.ExtendExpiry(ctx, r.db.DB, expiresAt)
+}
+
+// Quarantine records a quarantined bundle on an instance
+func (r *InstanceRepository) Quarantine(ctx context.Context, instance *models.Instance, bundle, reason string) error {
+	return instance.Quarantine(ctx, r.db.DB, bundle, reason)
+}
+
+// ClearQuarantine lifts an instance's quarantine
+func (r *InstanceRepository) ClearQuarantine(ctx context.Context, instance *models.Instance) error {
+	return instance.ClearQuarantine(ctx, r.db.DB)
+}
+
+// ArchiveInstance moves an instance to the archive in a single transaction
+func (r *InstanceRepository) ArchiveInstance(ctx context.Context, params models.ArchiveInstanceParams) (*models.ArchivedInstance, error) {
+	return models.ArchiveInstance(ctx, r.db.DB, params)
+}
+
+// FindArchivedInstancesByUserID retrieves a user's archived instances
+func (r *InstanceRepository) FindArchivedInstancesByUserID(ctx context.Context, userID uuid.UUID) ([]models.ArchivedInstance, error) {
+	return models.FindArchivedInstancesByUserID(ctx, r.db.DB, userID)
+}
+
+// FindExpiredArchivedInstances retrieves archived instances whose data retention has ended
+func (r *InstanceRepository) FindExpiredArchivedInstances(ctx context.Context) ([]models.ArchivedInstance, error) {
+	return models.FindExpiredArchivedInstances(ctx, r.db.DB)
+}
+
+// FindArchivedInstanceByID retrieves one of a user's archived instances
+func (r *InstanceRepository) FindArchivedInstanceByID(ctx context.Context, id, userID uuid.UUID) (*models.ArchivedInstance, error) {
+	return models.FindArchivedInstanceByID(ctx, r.db.DB, id, userID)
+}
+
+// FindArchivedInstance retrieves an archived instance of any user
+func (r *InstanceRepository) FindArchivedInstance(ctx context.Context, id uuid.UUID) (*models.ArchivedInstance, error) {
+	return models.FindArchivedInstance(ctx, r.db.DB, id)
+}
+
+// UpdateArchivedDataAvailability records whether an archived instance's data still exists
+func (r *InstanceRepository) UpdateArchivedDataAvailability(ctx context.Context, id uuid.UUID, available bool) error {
+	return models.UpdateArchivedDataAvailability(ctx, r.db.DB, id, available)
+}
+
+// FindInstanceMetrics retrieves an instance's resource usage samples since a time
+func (r *InstanceRepository) FindInstanceMetrics(ctx context.Context, instanceID uuid.UUID, since time.Time) ([]models.InstanceMetric, error) {
+	return models.FindInstanceMetrics(ctx, r.db.DB, instanceID, since)
+}
+
+// FindInstanceTraffic retrieves an instance's daily request analytics since a time
+func (r *InstanceRepository) FindInstanceTraffic(ctx context.Context, instanceID uuid.UUID, since time.Time) ([]models.InstanceTrafficDay, error) {
+	return models.FindInstanceTraffic(ctx, r.db.DB, instanceID, since)
+}
+
+// FindInstancesByStatus retrieves all instances with a status
+func (r *InstanceRepository) FindInstancesByStatus(ctx context.Context, status string) ([]models.Instance, error) {
+	return models.FindInstancesByStatus(ctx, r.db.DB, status)
+}
+
+// FindInstanceByContainerID retrieves the instance running in a container
+func (r *InstanceRepository) FindInstanceByContainerID(ctx context.Context, containerID string) (*models.Instance, error) {
+	return models.FindInstanceByContainerID(ctx, r.db.DB, containerID)
+}
+
+// MarkFailed sets an instance's status to failed and records the reason and last log lines
+func (r *InstanceRepository) MarkFailed(ctx context.Context, instance *models.Instance, reason, logs string) error {
+	return instance.MarkFailed(ctx, r.db.DB, reason, logs)
+}
+
+// SetRoutingSuspended records whether an instance is cut off from the proxy
+func (r *InstanceRepository) SetRoutingSuspended(ctx context.Context, instance *models.Instance, suspended bool) error {
+	return instance.SetRoutingSuspended(ctx, r.db.DB, suspended)
+}
+
+// FindCrons retrieves an instance's scheduled tasks
+func (r *InstanceRepository) FindCrons(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceCron, error) {
+	return models.FindInstanceCrons(ctx, r.db.DB, instanceID)
+}
+
+// FindCron retrieves one of an instance's scheduled tasks
+func (r *InstanceRepository) FindCron(ctx context.Context, id, instanceID uuid.UUID) (*models.InstanceCron, error) {
+	return models.FindInstanceCron(ctx, r.db.DB, id, instanceID)
+}
+
+// CountCrons counts an instance's scheduled tasks
+func (r *InstanceRepository) CountCrons(ctx context.Context, instanceID uuid.UUID) (int, error) {
+	return models.CountInstanceCrons(ctx, r.db.DB, instanceID)
+}
+
+// CreateCron inserts a scheduled task
+func (r *InstanceRepository) CreateCron(ctx context.Context, cron *models.InstanceCron) error {
+	return cron.Create(ctx, r.db.DB)
+}
+
+// UpdateCron saves a scheduled task
+func (r *InstanceRepository) UpdateCron(ctx context.Context, cron *models.InstanceCron) error {
+	return cron.Update(ctx, r.db.DB)
+}
+
+// DeleteCron deletes a scheduled task
+func (r *InstanceRepository) DeleteCron(ctx context.Context, cron *models.InstanceCron) error {
+	return cron.Delete(ctx, r.db.DB)
+}
+
+// FindDueCrons retrieves enabled scheduled tasks whose next run time has passed
+func (r *InstanceRepository) FindDueCrons(ctx context.Context, now time.Time, limit int) ([]models.InstanceCron, error) {
+	return models.FindDueInstanceCrons(ctx, r.db.DB, now, limit)
+}
+
+// ClaimCron advances a scheduled task to its next run time, reporting false
+// if another scheduler already claimed this run
+func (r *InstanceRepository) ClaimCron(ctx context.Context, cron *models.InstanceCron, next *time.Time) (bool, error) {
+	return cron.Claim(ctx, r.db.DB, next)
+}
+
+// RecordCronRun stores a scheduled task's run, keeping the latest keep runs
+func (r *InstanceRepository) RecordCronRun(ctx context.Context, run *models.InstanceCronRun, keep int) error {
+	return models.RecordInstanceCronRun(ctx, r.db.DB, run, keep)
+}
+
+// FindCronRuns retrieves a scheduled task's run history, newest first
+func (r *InstanceRepository) FindCronRuns(ctx context.Context, cronID uuid.UUID) ([]models.InstanceCronRun, error) {
+	return models.FindInstanceCronRuns(ctx, r.db.DB, cronID)
+}
+
+// CreateInstanceMetric stores a resource usage sample
+func (r *InstanceRepository) CreateInstanceMetric(ctx context.Context, metric *models.InstanceMetric) error {
+	return models.CreateInstanceMetric(ctx, r.db.DB, metric)
+}
+
+// DeleteInstanceMetricsBefore deletes resource usage samples older than a time
+func (r *InstanceRepository) DeleteInstanceMetricsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return models.DeleteInstanceMetricsBefore(ctx, r.db.DB, before)
+}
+
+// CreateAnomaly stores an open anomaly, reporting false when the instance
+// already has an open anomaly of the same kind
+func (r *InstanceRepository) CreateAnomaly(ctx context.Context, anomaly *models.InstanceAnomaly) (bool, error) {
+	return models.CreateInstanceAnomaly(ctx, r.db.DB, anomaly)
+}
+
+// ListAnomalies retrieves anomalies with a status (all when empty), oldest first
+func (r *InstanceRepository) ListAnomalies(ctx context.Context, status string, limit int) ([]models.InstanceAnomaly, error) {
+	return models.ListInstanceAnomalies(ctx, r.db.DB, status, limit)
+}
+
+// FindAnomaly retrieves an anomaly by its ID
+func (r *InstanceRepository) FindAnomaly(ctx context.Context, id string) (*models.InstanceAnomaly, error) {
+	return models.FindInstanceAnomalyByID(ctx, r.db.DB, id)
+}
+
+// ResolveAnomaly records an admin's decision on an anomaly
+func (r *InstanceRepository) ResolveAnomaly(ctx context.Context, anomaly *models.InstanceAnomaly, status, reviewerID, note string) error {
+	return models.ResolveInstanceAnomaly(ctx, r.db.DB, anomaly, status, reviewerID, note)
+}
+
+// HasThrottledAnomaly reports whether an instance has an open anomaly that throttled it
+func (r *InstanceRepository) HasThrottledAnomaly(ctx context.Context, instanceID uuid.UUID) (bool, error) {
+	return models.HasThrottledAnomaly(ctx, r.db.DB, instanceID)
+}
+
+// GetAccessLogOffset returns how far an access log has been ingested
+func (r *InstanceRepository) GetAccessLogOffset(ctx context.Context, path string) (int64, error) {
+	return models.GetAccessLogOffset(ctx, r.db.DB, path)
+}
+
+// RecordInstanceTraffic adds traffic counters and stores the new access log offset atomically
+func (r *InstanceRepository) RecordInstanceTraffic(ctx context.Context, path string, offset int64, days []models.InstanceTrafficDay) error {
+	return models.RecordInstanceTraffic(ctx, r.db.DB, path, offset, days)
 }
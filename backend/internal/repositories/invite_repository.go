@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"pocketploy/internal/database"
+	"pocketploy/internal/models"
+)
+
+// InviteRepository handles all database operations for invite codes
+type InviteRepository struct {
+	db *database.DB
+}
+
+// NewInviteRepository creates a new invite repository
+func NewInviteRepository(db *database.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+// Create inserts a new invite code into the database
+func (r *InviteRepository) Create(invite *models.InviteCode) error {
+	query := `
+		INSERT INTO invite_codes (id, code, note, max_uses, use_count, created_by_user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.Exec(query,
+		invite.ID,
+		invite.Code,
+		invite.Note,
+		invite.MaxUses,
+		invite.UseCount,
+		invite.CreatedByUserID,
+		invite.ExpiresAt,
+		invite.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create invite code: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an invite code by its ID
+func (r *InviteRepository) GetByID(id string) (*models.InviteCode, error) {
+	var invite models.InviteCode
+	query := `SELECT * FROM invite_codes WHERE id = $1`
+	err := r.db.Get(&invite, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invite code not found")
+		}
+		return nil, fmt.Errorf("failed to get invite code: %w", err)
+	}
+	return &invite, nil
+}
+
+// List retrieves all invite codes, newest first
+func (r *InviteRepository) List() ([]*models.InviteCode, error) {
+	var invites []*models.InviteCode
+	query := `SELECT * FROM invite_codes ORDER BY created_at DESC`
+	err := r.db.Select(&invites, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+	return invites, nil
+}
+
+// Redeem atomically consumes one use of a valid (unrevoked, unexpired, not exhausted) code
+func (r *InviteRepository) Redeem(code string) error {
+	query := `
+		UPDATE invite_codes 
+		SET use_count = use_count + 1
+		WHERE code = $1 
+		AND revoked_at IS NULL 
+		AND (expires_at IS NULL OR expires_at > $2)
+		AND use_count < max_uses
+	`
+	result, err := r.db.Exec(query, code, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("invalid invite code")
+	}
+
+	return nil
+}
+
+// Release gives back a use consumed by Redeem (e.g. when signup fails afterwards)
+func (r *InviteRepository) Release(code string) error {
+	query := `UPDATE invite_codes SET use_count = use_count - 1 WHERE code = $1 AND use_count > 0`
+	_, err := r.db.Exec(query, code)
+	if err != nil {
+		return fmt.Errorf("failed to release invite code: %w", err)
+	}
+	return nil
+}
+
+// Revoke marks an invite code as revoked
+func (r *InviteRepository) Revoke(id string) error {
+	query := `UPDATE invite_codes SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("invite code not found or already revoked")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"pocketploy/internal/database"
+	"pocketploy/internal/models"
+)
+
+// PlatformRepository handles database operations for platform-wide settings
+type PlatformRepository struct {
+	db *database.DB
+}
+
+// NewPlatformRepository creates a new platform repository
+func NewPlatformRepository(db *database.DB) *PlatformRepository {
+	return &PlatformRepository{db: db}
+}
+
+// GetStatus retrieves the current platform status
+func (r *PlatformRepository) GetStatus() (*models.PlatformStatus, error) {
+	var status models.PlatformStatus
+	query := `
+		SELECT maintenance_enabled, maintenance_message, announcement, updated_by_user_id, updated_at
+		FROM platform_status WHERE id = 1
+	`
+	if err := r.db.Get(&status, query); err != nil {
+		return nil, fmt.Errorf("failed to get platform status: %w", err)
+	}
+	return &status, nil
+}
+
+// UpdateStatus stores a new platform status
+func (r *PlatformRepository) UpdateStatus(status *models.PlatformStatus) error {
+	status.UpdatedAt = time.Now().UTC()
+	query := `
+		INSERT INTO platform_status (id, maintenance_enabled, maintenance_message, announcement, updated_by_user_id, updated_at)
+		VALUES (1, $1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			maintenance_enabled = EXCLUDED.maintenance_enabled,
+			maintenance_message = EXCLUDED.maintenance_message,
+			announcement = EXCLUDED.announcement,
+			updated_by_user_id = EXCLUDED.updated_by_user_id,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.Exec(query,
+		status.MaintenanceEnabled,
+		status.MaintenanceMessage,
+		status.Announcement,
+		status.UpdatedByUserID,
+		status.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update platform status: %w", err)
+	}
+	return nil
+}
+
+// GetSettings retrieves the administrators' setting overrides
+func (r *PlatformRepository) GetSettings() (*models.PlatformSettings, error) {
+	var settings models.PlatformSettings
+	query := `
+		SELECT max_instances_per_user, instance_data_retention_days, pocketbase_image, signup_enabled,
+		       updated_by_user_id, updated_at
+		FROM platform_settings WHERE id = 1
+	`
+	if err := r.db.Get(&settings, query); err != nil {
+		return nil, fmt.Errorf("failed to get platform settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// UpdateSettings stores the administrators' setting overrides
+func (r *PlatformRepository) UpdateSettings(settings *models.PlatformSettings) error {
+	settings.UpdatedAt = time.Now().UTC()
+	query := `
+		INSERT INTO platform_settings (id, max_instances_per_user, instance_data_retention_days, pocketbase_image,
+		                               signup_enabled, updated_by_user_id, updated_at)
+		VALUES (1, $1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			max_instances_per_user = EXCLUDED.max_instances_per_user,
+			instance_data_retention_days = EXCLUDED.instance_data_retention_days,
+			pocketbase_image = EXCLUDED.pocketbase_image,
+			signup_enabled = EXCLUDED.signup_enabled,
+			updated_by_user_id = EXCLUDED.updated_by_user_id,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.Exec(query,
+		settings.MaxInstancesPerUser,
+		settings.InstanceDataRetentionDays,
+		settings.PocketBaseImage,
+		settings.SignupEnabled,
+		settings.UpdatedByUserID,
+		settings.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update platform settings: %w", err)
+	}
+	return nil
+}
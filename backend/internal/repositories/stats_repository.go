@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pocketploy/internal/database"
+	"pocketploy/internal/models"
+)
+
+// StatsRepository runs the aggregate queries behind the admin dashboard
+type StatsRepository struct {
+	db *database.DB
+}
+
+// NewStatsRepository creates a new stats repository
+func NewStatsRepository(db *database.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// CountUsers counts all users
+func (r *StatsRepository) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM users`); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// CountInstancesByStatus counts instances per status
+func (r *StatsRepository) CountInstancesByStatus(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	query := `SELECT status, COUNT(*) AS count FROM instances GROUP BY status`
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to count instances by status: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CountFailedProvisions counts instances created since the given time whose
+// provisioning failed, archived ones included
+func (r *StatsRepository) CountFailedProvisions(ctx context.Context, since time.Time) (int, error) {
+	var count int
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM instances WHERE status = $1 AND created_at >= $2) +
+			(SELECT COUNT(*) FROM instances_archive WHERE original_status = $1 AND created_at >= $2)
+	`
+	if err := r.db.GetContext(ctx, &count, query, models.InstanceStatusFailed, since); err != nil {
+		return 0, fmt.Errorf("failed to count failed provisions: %w", err)
+	}
+	return count, nil
+}
+
+// DiskUsageByRegion sums the latest disk usage sample of each instance per region
+func (r *StatsRepository) DiskUsageByRegion(ctx context.Context) ([]models.RegionDiskUsage, error) {
+	usage := []models.RegionDiskUsage{}
+	query := `
+		SELECT i.region_id, COUNT(*) AS instances, COALESCE(SUM(latest.disk_bytes), 0) AS disk_bytes
+		FROM instances i
+		LEFT JOIN LATERAL (
+			SELECT disk_bytes
+			FROM instance_metrics
+			WHERE instance_id = i.id
+			ORDER BY recorded_at DESC
+			LIMIT 1
+		) latest ON true
+		GROUP BY i.region_id
+		ORDER BY i.region_id
+	`
+	if err := r.db.SelectContext(ctx, &usage, query); err != nil {
+		return nil, fmt.Errorf("failed to sum disk usage by region: %w", err)
+	}
+	return usage, nil
+}
+
+// DailySignups counts new users per day since the given day
+func (r *StatsRepository) DailySignups(ctx context.Context, since time.Time) ([]models.StatsBucket, error) {
+	return r.daily(ctx, `SELECT created_at FROM users`, since)
+}
+
+// DailyInstancesCreated counts instances created per day since the given
+// day, archived ones included
+func (r *StatsRepository) DailyInstancesCreated(ctx context.Context, since time.Time) ([]models.StatsBucket, error) {
+	return r.daily(ctx, `
+		SELECT created_at FROM instances
+		UNION ALL
+		SELECT created_at FROM instances_archive
+	`, since)
+}
+
+// DailyFailedProvisions counts instances created per day since the given day
+// whose provisioning failed, archived ones included
+func (r *StatsRepository) DailyFailedProvisions(ctx context.Context, since time.Time) ([]models.StatsBucket, error) {
+	return r.daily(ctx, `
+		SELECT created_at FROM instances WHERE status = '`+models.InstanceStatusFailed+`'
+		UNION ALL
+		SELECT created_at FROM instances_archive WHERE original_status = '`+models.InstanceStatusFailed+`'
+	`, since)
+}
+
+// daily buckets the created_at timestamps of events per UTC day, with a zero
+// bucket for every day without events
+func (r *StatsRepository) daily(ctx context.Context, events string, since time.Time) ([]models.StatsBucket, error) {
+	buckets := []models.StatsBucket{}
+	query := `
+		SELECT days.day, COUNT(events.created_at) AS count
+		FROM generate_series(date_trunc('day', $1::timestamp), date_trunc('day', NOW() AT TIME ZONE 'UTC'), interval '1 day') AS days(day)
+		LEFT JOIN (` + events + `) events
+			ON events.created_at >= days.day AND events.created_at < days.day + interval '1 day'
+		GROUP BY days.day
+		ORDER BY days.day
+	`
+	if err := r.db.SelectContext(ctx, &buckets, query, since.UTC()); err != nil {
+		return nil, fmt.Errorf("failed to count daily events: %w", err)
+	}
+	return buckets, nil
+}
@@ -22,8 +22,8 @@ func NewTokenRepository(db *database.DB) *TokenRepository {
 // Create inserts a new refresh token into the database
 func (r *TokenRepository) Create(token *models.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at, ip_address, user_agent)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at, ip_address, user_agent, country, city)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 	_, err := r.db.Exec(query,
 		token.ID,
@@ -33,6 +33,8 @@ func (r *TokenRepository) Create(token *models.RefreshToken) error {
 		token.CreatedAt,
 		token.IPAddress,
 		token.UserAgent,
+		token.Country,
+		token.City,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create refresh token: %w", err)
@@ -143,10 +145,15 @@ func (r *TokenRepository) RevokeByID(id string) error {
 	return nil
 }
 
-// RevokeAllForUser revokes all tokens for a specific user
+// RevokeAllForUser revokes all tokens for a specific user, along with the
+// trust of all their devices
 func (r *TokenRepository) RevokeAllForUser(userID string) error {
 	now := time.Now().UTC()
-	query := `UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = COALESCE(revoked_at, $1), device_token_hash = NULL, trusted_until = NULL
+		WHERE user_id = $2 AND (revoked_at IS NULL OR device_token_hash IS NOT NULL)
+	`
 	_, err := r.db.Exec(query, now, userID)
 	if err != nil {
 		return fmt.Errorf("failed to revoke all tokens for user: %w", err)
@@ -154,9 +161,249 @@ func (r *TokenRepository) RevokeAllForUser(userID string) error {
 	return nil
 }
 
-// DeleteExpired permanently removes expired tokens from the database
+// RevokeAllForUserExcept revokes all tokens of a user except the one with
+// keepID, along with the trust of the devices they were used on
+func (r *TokenRepository) RevokeAllForUserExcept(userID, keepID string) error {
+	now := time.Now().UTC()
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = COALESCE(revoked_at, $1), device_token_hash = NULL, trusted_until = NULL
+		WHERE user_id = $2 AND id != $3 AND (revoked_at IS NULL OR device_token_hash IS NOT NULL)
+	`
+	_, err := r.db.Exec(query, now, userID, keepID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens for user: %w", err)
+	}
+	return nil
+}
+
+// RevokeForUser revokes one of a user's sessions and the trust of its device
+func (r *TokenRepository) RevokeForUser(id, userID string) error {
+	now := time.Now().UTC()
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = COALESCE(revoked_at, $1), device_token_hash = NULL, trusted_until = NULL
+		WHERE id = $2 AND user_id = $3 AND (revoked_at IS NULL OR device_token_hash IS NOT NULL)
+	`
+	result, err := r.db.Exec(query, now, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
+
+// SetName labels one of a user's active sessions; an empty name removes the label
+func (r *TokenRepository) SetName(id, userID, name string) error {
+	query := `
+		UPDATE refresh_tokens SET name = NULLIF($1, '')
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL AND expires_at > $4
+	`
+	result, err := r.db.Exec(query, name, id, userID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
+
+// Trust marks the device of one of a user's active sessions as trusted until
+// the given time, identified by the hash of its device cookie
+func (r *TokenRepository) Trust(id, userID, deviceTokenHash string, until time.Time) error {
+	query := `
+		UPDATE refresh_tokens SET device_token_hash = $1, trusted_until = $2
+		WHERE id = $3 AND user_id = $4 AND revoked_at IS NULL AND expires_at > $5
+	`
+	result, err := r.db.Exec(query, deviceTokenHash, until, id, userID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to trust device: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
+
+// GetTrustedDevice retrieves the session holding a device's unexpired trust.
+// The session itself may have been revoked (logged out) or have expired.
+func (r *TokenRepository) GetTrustedDevice(deviceTokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	query := `SELECT * FROM refresh_tokens WHERE device_token_hash = $1 AND trusted_until > $2`
+	err := r.db.Get(&token, query, deviceTokenHash, time.Now().UTC())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("device not trusted")
+		}
+		return nil, fmt.Errorf("failed to get trusted device: %w", err)
+	}
+	return &token, nil
+}
+
+// GetTrustedByUserID retrieves the sessions holding the trust of a user's devices
+func (r *TokenRepository) GetTrustedByUserID(userID string) ([]*models.RefreshToken, error) {
+	var tokens []*models.RefreshToken
+	query := `
+		SELECT * FROM refresh_tokens
+		WHERE user_id = $1 AND trusted_until > $2
+		ORDER BY created_at DESC
+	`
+	err := r.db.Select(&tokens, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trusted devices for user: %w", err)
+	}
+	return tokens, nil
+}
+
+// TransferTrust moves a device's trust and label from the session holding
+// them to a new session on the same device
+func (r *TokenRepository) TransferTrust(fromID, toID string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var from models.RefreshToken
+	if err := tx.Get(&from, `SELECT * FROM refresh_tokens WHERE id = $1 FOR UPDATE`, fromID); err != nil {
+		return fmt.Errorf("failed to get trusted device: %w", err)
+	}
+
+	release := `UPDATE refresh_tokens SET device_token_hash = NULL, trusted_until = NULL WHERE id = $1`
+	if _, err := tx.Exec(release, fromID); err != nil {
+		return fmt.Errorf("failed to transfer device trust: %w", err)
+	}
+
+	claim := `
+		UPDATE refresh_tokens SET name = COALESCE(name, $1), device_token_hash = $2, trusted_until = $3
+		WHERE id = $4
+	`
+	if _, err := tx.Exec(claim, from.Name, from.DeviceTokenHash, from.TrustedUntil, toID); err != nil {
+		return fmt.Errorf("failed to transfer device trust: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Untrust revokes the trust of one of a user's devices
+func (r *TokenRepository) Untrust(id, userID string) error {
+	query := `
+		UPDATE refresh_tokens SET device_token_hash = NULL, trusted_until = NULL
+		WHERE id = $1 AND user_id = $2 AND device_token_hash IS NOT NULL
+	`
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke device trust: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("device not found")
+	}
+
+	return nil
+}
+
+// HasDevice reports whether a user has had a session (of any state) from an
+// IP address with a user agent
+func (r *TokenRepository) HasDevice(userID, ipAddress, userAgent string) (bool, error) {
+	var seen bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE user_id = $1
+			AND ip_address IS NOT DISTINCT FROM NULLIF($2, '')::inet
+			AND user_agent = $3
+		)
+	`
+	if err := r.db.QueryRow(query, userID, ipAddress, userAgent).Scan(&seen); err != nil {
+		return false, fmt.Errorf("failed to check known devices: %w", err)
+	}
+	return seen, nil
+}
+
+// HasCountry reports whether a user has had a session (of any state) located
+// in a country
+func (r *TokenRepository) HasCountry(userID, country string) (bool, error) {
+	var seen bool
+	query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE user_id = $1 AND country = $2)`
+	if err := r.db.QueryRow(query, userID, country).Scan(&seen); err != nil {
+		return false, fmt.Errorf("failed to check known countries: %w", err)
+	}
+	return seen, nil
+}
+
+// HasLocatedSession reports whether any of a user's sessions has a country,
+// i.e. whether there is a location history to compare a login with
+func (r *TokenRepository) HasLocatedSession(userID string) (bool, error) {
+	var located bool
+	query := `SELECT EXISTS (SELECT 1 FROM refresh_tokens WHERE user_id = $1 AND country != '')`
+	if err := r.db.QueryRow(query, userID).Scan(&located); err != nil {
+		return false, fmt.Errorf("failed to check session locations: %w", err)
+	}
+	return located, nil
+}
+
+// SetRevokeToken stores the hash of a session's sign-out link token
+func (r *TokenRepository) SetRevokeToken(id, revokeTokenHash string) error {
+	query := `UPDATE refresh_tokens SET revoke_token_hash = $1 WHERE id = $2`
+	if _, err := r.db.Exec(query, revokeTokenHash, id); err != nil {
+		return fmt.Errorf("failed to store sign-out link: %w", err)
+	}
+	return nil
+}
+
+// RevokeByRevokeToken revokes the session of a sign-out link, and the trust
+// of its device. The link works once.
+func (r *TokenRepository) RevokeByRevokeToken(revokeTokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = COALESCE(revoked_at, $1), device_token_hash = NULL, trusted_until = NULL, revoke_token_hash = NULL
+		WHERE revoke_token_hash = $2
+		RETURNING *
+	`
+	err := r.db.Get(&token, query, time.Now().UTC(), revokeTokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invalid or used sign-out link")
+		}
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return &token, nil
+}
+
+// DeleteExpired permanently removes expired tokens from the database, except
+// those still holding a device's trust
 func (r *TokenRepository) DeleteExpired() (int64, error) {
-	query := `DELETE FROM refresh_tokens WHERE expires_at < $1`
+	query := `DELETE FROM refresh_tokens WHERE expires_at < $1 AND (trusted_until IS NULL OR trusted_until < $1)`
 	result, err := r.db.Exec(query, time.Now().UTC())
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete expired tokens: %w", err)
@@ -170,10 +417,11 @@ func (r *TokenRepository) DeleteExpired() (int64, error) {
 	return rows, nil
 }
 
-// DeleteRevoked permanently removes revoked tokens from the database
+// DeleteRevoked permanently removes revoked tokens from the database, except
+// those still holding a device's trust
 func (r *TokenRepository) DeleteRevoked() (int64, error) {
-	query := `DELETE FROM refresh_tokens WHERE revoked_at IS NOT NULL`
-	result, err := r.db.Exec(query)
+	query := `DELETE FROM refresh_tokens WHERE revoked_at IS NOT NULL AND (trusted_until IS NULL OR trusted_until < $1)`
+	result, err := r.db.Exec(query, time.Now().UTC())
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete revoked tokens: %w", err)
 	}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -22,8 +23,8 @@ func NewUserRepository(db *database.DB) *UserRepository {
 // Create inserts a new user into the database
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (id, username, email, password_hash, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (id, username, email, password_hash, is_active, plan, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err := r.db.Exec(query,
 		user.ID,
@@ -31,6 +32,7 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.Email,
 		user.PasswordHash,
 		user.IsActive,
+		user.Plan,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -87,14 +89,18 @@ func (r *UserRepository) Update(user *models.User) error {
 	user.UpdatedAt = time.Now().UTC()
 	query := `
 		UPDATE users 
-		SET username = $1, email = $2, password_hash = $3, is_active = $4, updated_at = $5
-		WHERE id = $6
+		SET username = $1, email = $2, password_hash = $3, is_active = $4,
+		    display_name = $5, timezone = $6, locale = $7, updated_at = $8
+		WHERE id = $9
 	`
 	result, err := r.db.Exec(query,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
 		user.IsActive,
+		user.DisplayName,
+		user.Timezone,
+		user.Locale,
 		user.UpdatedAt,
 		user.ID,
 	)
@@ -133,6 +139,79 @@ func (r *UserRepository) UpdateLastLogin(id string) error {
 	return nil
 }
 
+// SetAvatarFile sets or clears (nil) a user's avatar file
+func (r *UserRepository) SetAvatarFile(id string, file *string) error {
+	query := `UPDATE users SET avatar_file = $1, updated_at = $2 WHERE id = $3`
+	result, err := r.db.Exec(query, file, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SaveEmailChange stores a user's pending email change
+func (r *UserRepository) SaveEmailChange(ctx context.Context, change *models.EmailChange) error {
+	return models.SaveEmailChange(ctx, r.db.DB, change)
+}
+
+// GetEmailChange returns a user's pending email change, or nil
+func (r *UserRepository) GetEmailChange(ctx context.Context, userID string) (*models.EmailChange, error) {
+	return models.GetEmailChange(ctx, r.db.DB, userID, time.Now().UTC())
+}
+
+// DeleteEmailChange cancels a user's pending email change
+func (r *UserRepository) DeleteEmailChange(ctx context.Context, userID string) error {
+	return models.DeleteEmailChange(ctx, r.db.DB, userID)
+}
+
+// ConfirmEmailChange records a confirmation and applies the change once both addresses confirmed
+func (r *UserRepository) ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.EmailChange, bool, error) {
+	return models.ConfirmEmailChange(ctx, r.db.DB, tokenHash, time.Now().UTC())
+}
+
+// SetBandwidthQuotaOverride sets or clears (nil) a user's bandwidth quota override in GB
+func (r *UserRepository) SetBandwidthQuotaOverride(id string, quotaGB *int) error {
+	query := `UPDATE users SET bandwidth_quota_override_gb = $1, updated_at = $2 WHERE id = $3`
+	result, err := r.db.Exec(query, quotaGB, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update bandwidth quota: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetMonthlyBandwidth returns a user's egress in bytes for the month starting at month
+func (r *UserRepository) GetMonthlyBandwidth(ctx context.Context, userID string, month time.Time) (int64, error) {
+	return models.GetUserMonthlyBandwidth(ctx, r.db.DB, userID, month)
+}
+
+// FindMonthlyBandwidthUsage sums egress per instance owner for the month starting at month
+func (r *UserRepository) FindMonthlyBandwidthUsage(ctx context.Context, month time.Time) ([]models.BandwidthUsage, error) {
+	return models.FindMonthlyBandwidthUsage(ctx, r.db.DB, month)
+}
+
+// RecordBandwidthNotice stores that a notice was sent and reports whether it is new
+func (r *UserRepository) RecordBandwidthNotice(ctx context.Context, userID string, month time.Time, level string) (bool, error) {
+	return models.RecordBandwidthNotice(ctx, r.db.DB, userID, month, level)
+}
+
 // Delete soft deletes a user by setting is_active to false
 func (r *UserRepository) Delete(id string) error {
 	query := `UPDATE users SET is_active = false, updated_at = $1 WHERE id = $2`
@@ -2,81 +2,355 @@ package router
 
 import (
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 
+	"pocketploy/internal/cache"
 	"pocketploy/internal/config"
 	"pocketploy/internal/database"
+	"pocketploy/internal/events"
 	appHandlers "pocketploy/internal/handlers"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/metrics"
 	"pocketploy/internal/middleware"
 	"pocketploy/internal/services"
 )
 
+// Services are the services the routes are served by
+type Services struct {
+	AuthService      *services.AuthService
+	UserService      *services.UserService
+	TokenService     *services.TokenService
+	InstanceService  *services.InstanceService
+	InviteService    *services.InviteService
+	BandwidthService *services.BandwidthService
+	UsageService     *services.UsageService
+	CreditService    *services.CreditService
+	AuditService     *services.AuditService
+	AbuseService     *services.AbuseService
+	ApprovalService  *services.AdminApprovalService
+	AnomalyDetector  *services.AnomalyDetector
+	DeployService    *services.DeployService
+	BillingService   *services.BillingService  // nil when billing is disabled
+	MeteringService  *services.MeteringService // nil when metering is disabled
+	PlatformService  *services.PlatformService
+	StatsService     *services.StatsService
+	ExportService    *services.ExportService
+	WebhookService   *services.WebhookService
+	StatusMonitor    *services.StatusMonitor
+	CronService      *services.CronService
+	ManifestService  *services.ManifestService
+	StorageService   *services.StorageService
+	DownloadSigner   *services.DownloadSigner
+	RegionService    *services.RegionService
+	ImageService     *services.ImageService
+	DNSService       *services.DNSService
+	Readiness        *services.ReadinessChecker
+	JobQueue         *jobs.Queue
+	Broker           *events.Broker
+	MetricsRegistry  *metrics.Registry // nil when METRICS_ENABLED is off
+}
+
 // New creates a new router with all routes configured
-func New(cfg *config.Config, db *database.DB, authService *services.AuthService, userService *services.UserService, tokenService *services.TokenService, instanceService *services.InstanceService) http.Handler {
+func New(cfg *config.Config, db *database.DB, store cache.Store, deps Services) http.Handler {
 	r := mux.NewRouter()
 
+	// Prometheus metrics (disabled when no registry is provided), scraped
+	// with METRICS_TOKEN
+	if deps.MetricsRegistry != nil {
+		r.Use(middleware.Metrics(deps.MetricsRegistry))
+		r.Handle("/metrics", middleware.MetricsAuth(cfg.MetricsToken)(deps.MetricsRegistry.Handler())).Methods("GET")
+	}
+
 	// Initialize handlers with services (thin controllers)
-	healthHandler := appHandlers.NewHealthHandler(db)
-	authHandler := appHandlers.NewAuthHandler(authService)
-	userHandler := appHandlers.NewUserHandler(userService)
-	instanceHandler := appHandlers.NewInstanceHandler(instanceService)
+	healthHandler := appHandlers.NewHealthHandler(db, deps.Readiness, cfg.DBPoolHealthThreshold)
+	statusHandler := appHandlers.NewStatusHandler(deps.PlatformService, deps.StatusMonitor)
+	authHandler := appHandlers.NewAuthHandler(deps.AuthService, deps.TokenService, cfg)
+	userHandler := appHandlers.NewUserHandler(deps.UserService, deps.InstanceService, deps.BandwidthService, deps.UsageService)
+	instanceHandler := appHandlers.NewInstanceHandler(deps.InstanceService, cfg)
+	cronHandler := appHandlers.NewCronHandler(deps.CronService)
+	manifestHandler := appHandlers.NewManifestHandler(deps.ManifestService)
+	storageHandler := appHandlers.NewStorageHandler(deps.StorageService)
+	regionHandler := appHandlers.NewRegionHandler(deps.RegionService)
+	imageHandler := appHandlers.NewImageHandler(deps.ImageService)
+	dnsHandler := appHandlers.NewDNSHandler(deps.DNSService, deps.InstanceService)
+	creditHandler := appHandlers.NewCreditHandler(deps.CreditService)
+	auditHandler := appHandlers.NewAuditHandler(deps.AuditService)
+	abuseHandler := appHandlers.NewAbuseHandler(deps.AbuseService)
+	operationHandler := appHandlers.NewAdminOperationHandler(deps.ApprovalService)
+	anomalyHandler := appHandlers.NewAnomalyHandler(deps.AnomalyDetector)
+	deployHandler := appHandlers.NewDeployHandler(deps.DeployService, cfg)
+	wsHandler := appHandlers.NewWebSocketHandler(deps.Broker, cfg.OriginAllowed)
+	unavailableHandler := appHandlers.NewUnavailableHandler(deps.InstanceService)
+	statsHandler := appHandlers.NewStatsHandler(deps.StatsService)
+	exportHandler := appHandlers.NewExportHandler(deps.ExportService)
+	webhookHandler := appHandlers.NewWebhookHandler(deps.WebhookService)
+	downloadHandler := appHandlers.NewDownloadHandler(deps.DownloadSigner, deps.InstanceService, deps.UserService, exportHandler)
+	adminHandler := appHandlers.NewAdminHandler(deps.InviteService, deps.InstanceService, deps.BandwidthService, deps.PlatformService, deps.JobQueue, cfg)
 
-	// Health check routes (no auth required)
+	// Health check routes (no auth required). Liveness only means the process
+	// serves requests; readiness also needs the database, Docker and the schema.
 	r.HandleFunc("/health", healthHandler.Health).Methods("GET")
+	r.HandleFunc("/health/live", healthHandler.Health).Methods("GET")
+	r.HandleFunc("/health/ready", healthHandler.Ready).Methods("GET")
 	r.HandleFunc("/health/db", healthHandler.HealthDB).Methods("GET")
 
+	// Fallback page for instance subdomains Traefik has no route for
+	// (traefik.dynamic.yml rewrites those requests to this path)
+	r.HandleFunc("/unavailable", unavailableHandler.ServePage)
+
 	// API v1 routes
 	api := r.PathPrefix("/api/v1").Subrouter()
 
-	// Auth routes (no auth required)
+	// Platform status and health (no auth required, polled by the frontend and status page)
+	api.HandleFunc("/status", statusHandler.GetStatus).Methods("GET")
+	api.HandleFunc("/platform/status", statusHandler.GetPlatformHealth).Methods("GET")
+
+	// Instance status for external uptime monitors (no auth required, the
+	// read-only status token in the path identifies the instance)
+	api.HandleFunc("/public/instances/{token}/status", instanceHandler.GetPublicStatus).Methods("GET")
+
+	// Backup and export downloads (no auth required, the signed link grants
+	// them and the requester's access is checked again)
+	api.HandleFunc("/downloads/{token}", downloadHandler.Download).Methods("GET")
+
+	// Profile pictures (no auth required, used directly as image URLs)
+	api.HandleFunc("/avatars/{file}", userHandler.GetAvatar).Methods("GET")
+
+	// Abuse reports (no auth required, captcha protected and rate limited per client IP)
+	abuse := api.PathPrefix("/abuse-reports").Subrouter()
+	abuse.Use(middleware.RateLimit(store, "abuse", cfg.RateLimitAuthRequests, cfg.RateLimitAuthWindow))
+	abuse.HandleFunc("", abuseHandler.CreateReport).Methods("POST")
+
+	// Auth routes (no auth required, rate limited per client IP)
 	auth := api.PathPrefix("/auth").Subrouter()
+	auth.Use(middleware.RateLimit(store, "auth", cfg.RateLimitAuthRequests, cfg.RateLimitAuthWindow))
 	auth.HandleFunc("/signup", authHandler.Signup).Methods("POST")
 	auth.HandleFunc("/login", authHandler.Login).Methods("POST")
 	auth.HandleFunc("/refresh", authHandler.Refresh).Methods("POST")
+	auth.HandleFunc("/captcha", authHandler.Captcha).Methods("GET")
+	auth.HandleFunc("/confirm-email", userHandler.ConfirmEmailChange).Methods("POST")
+	auth.HandleFunc("/revoke-session", authHandler.RevokeSessionByLink).Methods("POST")
 
 	// Protected auth routes
 	authProtected := api.PathPrefix("/auth").Subrouter()
-	authProtected.Use(middleware.Auth(cfg))
+	authProtected.Use(middleware.Auth(cfg, deps.AuthService))
 	authProtected.HandleFunc("/logout", authHandler.Logout).Methods("POST")
 	authProtected.HandleFunc("/me", authHandler.Me).Methods("GET")
+	authProtected.HandleFunc("/impersonation/end", authHandler.EndImpersonation).Methods("POST")
+	authProtected.HandleFunc("/sessions", authHandler.ListSessions).Methods("GET")
+	authProtected.HandleFunc("/sessions/{id}", authHandler.UpdateSession).Methods("PATCH")
+	authProtected.HandleFunc("/sessions/{id}", authHandler.RevokeSession).Methods("DELETE")
+	authProtected.HandleFunc("/devices", authHandler.ListTrustedDevices).Methods("GET")
+	authProtected.HandleFunc("/devices", authHandler.TrustDevice).Methods("POST")
+	authProtected.HandleFunc("/devices/{id}", authHandler.UntrustDevice).Methods("DELETE")
 
 	// User routes (auth required)
 	users := api.PathPrefix("/users").Subrouter()
-	users.Use(middleware.Auth(cfg))
+	users.Use(middleware.Auth(cfg, deps.AuthService))
 	users.HandleFunc("/me", userHandler.GetMe).Methods("GET")
 	users.HandleFunc("/me", userHandler.UpdateMe).Methods("PATCH")
+	users.HandleFunc("/me", userHandler.DeleteMe).Methods("DELETE")
+	users.HandleFunc("/me/password", userHandler.UpdatePassword).Methods("PATCH")
+	users.HandleFunc("/me/avatar", userHandler.UploadAvatar).Methods("PUT")
+	users.HandleFunc("/me/avatar", userHandler.DeleteAvatar).Methods("DELETE")
+	users.HandleFunc("/me/email-change", userHandler.GetEmailChange).Methods("GET")
+	users.HandleFunc("/me/email-change", userHandler.CancelEmailChange).Methods("DELETE")
+	users.HandleFunc("/me/bandwidth", userHandler.GetBandwidth).Methods("GET")
+	users.HandleFunc("/me/usage", userHandler.GetUsage).Methods("GET")
+	users.HandleFunc("/me/credits", creditHandler.GetCredits).Methods("GET")
+	users.HandleFunc("/me/credits/redeem", creditHandler.RedeemPromoCode).Methods("POST")
+
+	// Webhook routes (protected)
+	webhooks := api.PathPrefix("/webhooks").Subrouter()
+	webhooks.Use(middleware.Auth(cfg, deps.AuthService))
+	webhooks.HandleFunc("", webhookHandler.ListWebhooks).Methods("GET")
+	webhooks.HandleFunc("", webhookHandler.CreateWebhook).Methods("POST")
+	webhooks.HandleFunc("/{id}", webhookHandler.DeleteWebhook).Methods("DELETE")
+	webhooks.HandleFunc("/{id}/rotate-secret", webhookHandler.RotateSecret).Methods("POST")
+	webhooks.HandleFunc("/{id}/deliveries", webhookHandler.ListDeliveries).Methods("GET")
+	webhooks.HandleFunc("/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.Redeliver).Methods("POST")
+
+	// Billing routes (only when Stripe is configured). Stripe calls the
+	// webhook, which authenticates by its signature instead of a token.
+	if deps.BillingService != nil {
+		billingHandler := appHandlers.NewBillingHandler(deps.BillingService)
+		api.HandleFunc("/billing/webhook", billingHandler.Webhook).Methods("POST")
+
+		billingRoutes := api.PathPrefix("/billing").Subrouter()
+		billingRoutes.Use(middleware.Auth(cfg, deps.AuthService))
+		billingRoutes.HandleFunc("/plans", billingHandler.ListPlans).Methods("GET")
+		billingRoutes.HandleFunc("/subscription", billingHandler.GetSubscription).Methods("GET")
+		billingRoutes.HandleFunc("/checkout", billingHandler.CreateCheckout).Methods("POST")
+	}
+
+	// Region routes (auth required)
+	regions := api.PathPrefix("/regions").Subrouter()
+	regions.Use(middleware.Auth(cfg, deps.AuthService))
+	regions.HandleFunc("", regionHandler.ListRegions).Methods("GET")
+
+	// Approved image routes (auth required)
+	images := api.PathPrefix("/images").Subrouter()
+	images.Use(middleware.Auth(cfg, deps.AuthService))
+	images.HandleFunc("", imageHandler.ListImages).Methods("GET")
 
 	// Instance routes (auth required)
 	instances := api.PathPrefix("/instances").Subrouter()
-	instances.Use(middleware.Auth(cfg))
+	instances.Use(middleware.Auth(cfg, deps.AuthService))
+	instances.Use(middleware.Maintenance(deps.PlatformService))
 	instances.HandleFunc("", instanceHandler.CreateInstance).Methods("POST")
+	instances.HandleFunc("/validate", instanceHandler.ValidateInstance).Methods("POST")
+	instances.HandleFunc("/import", manifestHandler.ImportManifest).Methods("POST")
+	instances.HandleFunc("/bulk", instanceHandler.BulkOperation).Methods("POST")
+	instances.HandleFunc("/order", instanceHandler.ReorderInstances).Methods("PUT")
 	instances.HandleFunc("", instanceHandler.ListInstances).Methods("GET")
+	instances.HandleFunc("/archive", instanceHandler.ListArchivedInstances).Methods("GET")
+	instances.HandleFunc("/archive/{id}", instanceHandler.GetArchivedInstance).Methods("GET")
+	instances.HandleFunc("/archive/{id}", instanceHandler.PurgeArchivedInstance).Methods("DELETE")
 	instances.HandleFunc("/{id}", instanceHandler.GetInstance).Methods("GET")
+	instances.HandleFunc("/{id}", instanceHandler.UpdateInstance).Methods("PATCH")
 	instances.HandleFunc("/{id}", instanceHandler.DeleteInstance).Methods("DELETE")
+	instances.HandleFunc("/{id}/delete-confirmation", instanceHandler.GetDeletionConfirmation).Methods("GET")
+	instances.HandleFunc("/{id}/status-token", instanceHandler.RotateStatusToken).Methods("POST")
+	instances.HandleFunc("/{id}/status-token", instanceHandler.RevokeStatusToken).Methods("DELETE")
 	instances.HandleFunc("/{id}/logs", instanceHandler.GetInstanceLogs).Methods("GET")
+	instances.HandleFunc("/{id}/logs/download", instanceHandler.DownloadInstanceLogs).Methods("GET")
 	instances.HandleFunc("/{id}/stats", instanceHandler.GetInstanceStats).Methods("GET")
+	instances.HandleFunc("/{id}/metrics", instanceHandler.GetInstanceMetrics).Methods("GET")
+	instances.HandleFunc("/{id}/analytics", instanceHandler.GetInstanceAnalytics).Methods("GET")
 	instances.HandleFunc("/{id}/start", instanceHandler.StartInstance).Methods("POST")
 	instances.HandleFunc("/{id}/stop", instanceHandler.StopInstance).Methods("POST")
 	instances.HandleFunc("/{id}/restart", instanceHandler.RestartInstance).Methods("POST")
+	instances.HandleFunc("/{id}/cancel-deletion", instanceHandler.CancelDeletion).Methods("POST")
+	instances.HandleFunc("/{id}/extend", instanceHandler.ExtendInstance).Methods("POST")
+	instances.HandleFunc("/{id}/admin-credentials", instanceHandler.RotateAdminCredentials).Methods("POST")
+	instances.HandleFunc("/{id}/commands", instanceHandler.RunCommand).Methods("POST")
+	instances.HandleFunc("/{id}/serve-options", instanceHandler.UpdateServeOptions).Methods("PUT")
+	instances.HandleFunc("/{id}/access", instanceHandler.UpdateAccessProtection).Methods("PUT")
+	instances.HandleFunc("/{id}/egress", instanceHandler.UpdateEgressPolicy).Methods("PUT")
+	instances.HandleFunc("/{id}/tags", instanceHandler.UpdateTags).Methods("PUT")
+	instances.HandleFunc("/{id}/pin", instanceHandler.SetPinned).Methods("PUT")
+	instances.HandleFunc("/{id}/dns", dnsHandler.GetInstanceDNS).Methods("GET")
+	instances.HandleFunc("/{id}/storage", storageHandler.GetStorage).Methods("GET")
+	instances.HandleFunc("/{id}/storage", storageHandler.EnableStorage).Methods("POST")
+	instances.HandleFunc("/{id}/storage", storageHandler.DisableStorage).Methods("DELETE")
+	instances.HandleFunc("/{id}/manifest", manifestHandler.ExportManifest).Methods("GET")
+	instances.HandleFunc("/{id}/hooks", instanceHandler.GetHooks).Methods("GET")
+	instances.HandleFunc("/{id}/hooks", instanceHandler.UploadHooks).Methods("PUT")
+	instances.HandleFunc("/{id}/hooks", instanceHandler.DeleteHooks).Methods("DELETE")
+	instances.HandleFunc("/{id}/migrations", instanceHandler.GetMigrations).Methods("GET")
+	instances.HandleFunc("/{id}/migrations", instanceHandler.UploadMigrations).Methods("PUT")
+	instances.HandleFunc("/{id}/migrations/run", instanceHandler.RunMigrations).Methods("POST")
+	instances.HandleFunc("/{id}/builds", instanceHandler.ListBuilds).Methods("GET")
+	instances.HandleFunc("/{id}/builds", instanceHandler.UploadBuild).Methods("POST")
+	instances.HandleFunc("/{id}/builds/{buildId}", instanceHandler.GetBuild).Methods("GET")
+	instances.HandleFunc("/{id}/integrity-checks", instanceHandler.ListIntegrityChecks).Methods("GET")
+	instances.HandleFunc("/{id}/integrity-checks", instanceHandler.CheckIntegrity).Methods("POST")
+	instances.HandleFunc("/{id}/backups", instanceHandler.ListBackups).Methods("GET")
+	instances.HandleFunc("/{id}/backups/{backupId}/download-url", downloadHandler.CreateBackupLink).Methods("POST")
+	instances.HandleFunc("/{id}/restore", instanceHandler.RestoreBackup).Methods("POST")
+	instances.HandleFunc("/{id}/copy-data", instanceHandler.CopyData).Methods("POST")
+	instances.HandleFunc("/{id}/public", instanceHandler.GetPublicFiles).Methods("GET")
+	instances.HandleFunc("/{id}/public", instanceHandler.UploadPublicFiles).Methods("POST")
+	instances.HandleFunc("/{id}/public", instanceHandler.DeletePublicFiles).Methods("DELETE")
+	instances.HandleFunc("/{id}/previews", instanceHandler.ListPreviews).Methods("GET")
+	instances.HandleFunc("/{id}/previews", instanceHandler.CreatePreview).Methods("POST")
+	instances.HandleFunc("/{id}/previews/{ref}", instanceHandler.DeletePreview).Methods("DELETE")
+	instances.HandleFunc("/{id}/deploy-tokens", deployHandler.ListTokens).Methods("GET")
+	instances.HandleFunc("/{id}/deploy-tokens", deployHandler.CreateToken).Methods("POST")
+	instances.HandleFunc("/{id}/deploy-tokens/{tokenId}", deployHandler.RevokeToken).Methods("DELETE")
+	instances.HandleFunc("/{id}/crons", cronHandler.ListCrons).Methods("GET")
+	instances.HandleFunc("/{id}/crons", cronHandler.CreateCron).Methods("POST")
+	instances.HandleFunc("/{id}/crons/{cronId}", cronHandler.UpdateCron).Methods("PATCH")
+	instances.HandleFunc("/{id}/crons/{cronId}", cronHandler.DeleteCron).Methods("DELETE")
+	instances.HandleFunc("/{id}/crons/{cronId}/runs", cronHandler.ListCronRuns).Methods("GET")
 
-	// Apply logging middleware
-	loggedRouter := middleware.Logging(r)
+	// Deploy routes for CI pipelines (deploy token required, scoped to one instance)
+	deploy := api.PathPrefix("/deploy").Subrouter()
+	deploy.Use(middleware.DeployAuth(deps.DeployService))
+	deploy.Use(middleware.Maintenance(deps.PlatformService))
+	deploy.HandleFunc("/hooks", deployHandler.DeployHooks).Methods("PUT")
+	deploy.HandleFunc("/public", deployHandler.DeployPublicFiles).Methods("PUT")
+	deploy.HandleFunc("/migrations", deployHandler.DeployMigrations).Methods("PUT")
+	deploy.HandleFunc("/migrate", deployHandler.Migrate).Methods("POST")
+	deploy.HandleFunc("/restart", deployHandler.Restart).Methods("POST")
+	deploy.HandleFunc("/previews/{ref}", deployHandler.DeployPreview).Methods("PUT")
+	deploy.HandleFunc("/previews/{ref}", deployHandler.ClosePreview).Methods("DELETE")
+
+	// Real-time events (auth required; browsers send the token as a WebSocket subprotocol)
+	realtime := api.PathPrefix("/ws").Subrouter()
+	realtime.Use(middleware.Auth(cfg, deps.AuthService))
+	realtime.HandleFunc("", wsHandler.Serve).Methods("GET")
 
-	// Parse allowed origins (comma-separated string to slice)
-	allowedOrigins := strings.Split(cfg.AllowedOrigins, ",")
-	for i := range allowedOrigins {
-		allowedOrigins[i] = strings.TrimSpace(allowedOrigins[i])
+	// Admin routes (auth + admin role required)
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.Auth(cfg, deps.AuthService))
+	admin.Use(middleware.RequireAdmin(deps.UserService))
+	admin.HandleFunc("/invites", adminHandler.CreateInvite).Methods("POST")
+	admin.HandleFunc("/invites", adminHandler.ListInvites).Methods("GET")
+	admin.HandleFunc("/invites/{id}", adminHandler.RevokeInvite).Methods("DELETE")
+	admin.HandleFunc("/stats", statsHandler.GetStats).Methods("GET")
+	admin.HandleFunc("/instances", adminHandler.SearchInstances).Methods("GET")
+	admin.HandleFunc("/instances/{id}/suspend", adminHandler.SuspendInstance).Methods("POST")
+	admin.HandleFunc("/instances/{id}/unsuspend", adminHandler.UnsuspendInstance).Methods("POST")
+	admin.HandleFunc("/instances/{id}/quarantine/release", adminHandler.ReleaseQuarantine).Methods("POST")
+	admin.HandleFunc("/instances/{id}/relocate", adminHandler.RelocateStorage).Methods("POST")
+	admin.HandleFunc("/status", adminHandler.UpdatePlatformStatus).Methods("PUT")
+	admin.HandleFunc("/settings", adminHandler.GetPlatformSettings).Methods("GET")
+	admin.HandleFunc("/settings", adminHandler.UpdatePlatformSettings).Methods("PATCH")
+	admin.HandleFunc("/jobs", adminHandler.ListJobs).Methods("GET")
+	admin.HandleFunc("/jobs/{id}", adminHandler.GetJob).Methods("GET")
+	admin.HandleFunc("/jobs/{id}/requeue", adminHandler.RequeueJob).Methods("POST")
+	admin.HandleFunc("/users/{id}/bandwidth-quota", adminHandler.SetBandwidthQuota).Methods("PUT")
+	admin.HandleFunc("/users/{id}/credits", creditHandler.GrantCredit).Methods("POST")
+	admin.HandleFunc("/users/{id}/impersonate", authHandler.Impersonate).Methods("POST")
+	admin.HandleFunc("/users/{id}", operationHandler.RequestUserDeletion).Methods("DELETE")
+	admin.HandleFunc("/archived-instances/{id}", operationHandler.RequestArchivePurge).Methods("DELETE")
+	admin.HandleFunc("/operations", operationHandler.ListOperations).Methods("GET")
+	admin.HandleFunc("/operations/{id}/approve", operationHandler.Approve).Methods("POST")
+	admin.HandleFunc("/operations/{id}/reject", operationHandler.Reject).Methods("POST")
+	admin.HandleFunc("/audit-log", auditHandler.ListAuditLog).Methods("GET")
+	admin.HandleFunc("/exports/audit-log", exportHandler.ExportAuditLog).Methods("GET")
+	admin.HandleFunc("/exports/metering", exportHandler.ExportMetering).Methods("GET")
+	admin.HandleFunc("/exports/instance-history", exportHandler.ExportInstanceHistory).Methods("GET")
+	admin.HandleFunc("/exports/{export}/download-url", downloadHandler.CreateExportLink).Methods("POST")
+	admin.HandleFunc("/abuse-reports", abuseHandler.ListReports).Methods("GET")
+	admin.HandleFunc("/abuse-reports/{id}", abuseHandler.GetReport).Methods("GET")
+	admin.HandleFunc("/abuse-reports/{id}/takedown", abuseHandler.TakeDown).Methods("POST")
+	admin.HandleFunc("/abuse-reports/{id}/dismiss", abuseHandler.Dismiss).Methods("POST")
+	admin.HandleFunc("/anomalies", anomalyHandler.ListAnomalies).Methods("GET")
+	admin.HandleFunc("/anomalies/{id}/dismiss", anomalyHandler.Dismiss).Methods("POST")
+	admin.HandleFunc("/anomalies/{id}/suspend", anomalyHandler.Suspend).Methods("POST")
+	admin.HandleFunc("/promo-codes", creditHandler.ListPromoCodes).Methods("GET")
+	admin.HandleFunc("/promo-codes", creditHandler.CreatePromoCode).Methods("POST")
+	admin.HandleFunc("/promo-codes/{id}", creditHandler.RevokePromoCode).Methods("DELETE")
+	admin.HandleFunc("/config/reload", adminHandler.ReloadConfig).Methods("POST")
+	admin.HandleFunc("/regions", regionHandler.ListAllRegions).Methods("GET")
+	admin.HandleFunc("/regions", regionHandler.CreateRegion).Methods("POST")
+	admin.HandleFunc("/regions/{id}", regionHandler.UpdateRegion).Methods("PATCH")
+	admin.HandleFunc("/images", imageHandler.ListAllImages).Methods("GET")
+	admin.HandleFunc("/images", imageHandler.CreateImage).Methods("POST")
+	admin.HandleFunc("/images/{id}", imageHandler.UpdateImage).Methods("PATCH")
+	admin.HandleFunc("/images/{id}/users", imageHandler.ListGrants).Methods("GET")
+	admin.HandleFunc("/images/{id}/users/{userId}", imageHandler.GrantImage).Methods("PUT")
+	admin.HandleFunc("/images/{id}/users/{userId}", imageHandler.RevokeImage).Methods("DELETE")
+	admin.HandleFunc("/dns", dnsHandler.ListDomains).Methods("GET")
+	admin.HandleFunc("/dns/sync", dnsHandler.SyncDomains).Methods("POST")
+	if deps.MeteringService != nil {
+		meteringHandler := appHandlers.NewMeteringHandler(deps.MeteringService)
+		admin.HandleFunc("/metering", meteringHandler.Export).Methods("GET")
 	}
 
-	// Apply CORS middleware
+	// Apply logging middleware
+	loggedRouter := middleware.Logging(cfg)(r)
+
+	// Apply CORS middleware (origins are matched against ALLOWED_ORIGINS, including wildcard patterns)
 	corsRouter := handlers.CORS(
-		handlers.AllowedOrigins(allowedOrigins),
+		handlers.AllowedOriginValidator(cfg.OriginAllowed),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-CSRF-Token"}),
 		handlers.AllowCredentials(),
 		handlers.MaxAge(int((12 * time.Hour).Seconds())),
 	)(loggedRouter)
@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Supported providers
+const (
+	ProviderClamAV = "clamav"
+)
+
+// Result is the verdict on a scanned file
+type Result struct {
+	Infected  bool
+	Signature string // name of the matched signature when infected
+}
+
+// Scanner checks uploaded content for malware before it reaches a container
+type Scanner interface {
+	Scan(ctx context.Context, content io.Reader) (*Result, error)
+}
+
+// NewScanner creates a scanner for the given provider.
+// It returns nil when provider is empty, which disables scanning.
+func NewScanner(provider, address string, timeout time.Duration) (Scanner, error) {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "":
+		return nil, nil
+	case ProviderClamAV:
+		if address == "" {
+			return nil, fmt.Errorf("clamd address is required")
+		}
+		return &clamAVScanner{address: address, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scanner provider: %s", provider)
+	}
+}
+
+// clamAVChunkSize is the largest chunk sent to clamd in one INSTREAM frame
+const clamAVChunkSize = 64 * 1024
+
+// clamAVScanner streams content to a clamd daemon over TCP (INSTREAM command)
+type clamAVScanner struct {
+	address string
+	timeout time.Duration
+}
+
+// Scan sends the content to clamd and parses its verdict
+func (s *clamAVScanner) Scan(ctx context.Context, content io.Reader) (*Result, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed to set clamd deadline: %w", err)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	// Each chunk is prefixed with its length; a zero length ends the stream
+	buf := make([]byte, clamAVChunkSize)
+	header := make([]byte, 4)
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(header, uint32(n))
+			if _, err := conn.Write(header); err != nil {
+				return nil, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read content: %w", readErr)
+		}
+	}
+	binary.BigEndian.PutUint32(header, 0)
+	if _, err := conn.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseClamAVReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamAVReply parses "stream: OK", "stream: <signature> FOUND" and
+// "<message> ERROR" replies
+func parseClamAVReply(reply string) (*Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("clamd error: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
@@ -0,0 +1,320 @@
+// Package secrets encrypts the secrets the platform stores with envelope
+// encryption: values are sealed with a data key, and the data key is stored
+// next to them wrapped by a master key (a local key or an AWS KMS key), so
+// the master key never touches the database.
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"pocketploy/internal/sigv4"
+)
+
+// envelopePrefix marks encrypted values: enc:v1:<key id>:<wrapped data key>:<sealed value>
+const envelopePrefix = "enc:v1:"
+
+// dataKeyLifetime is how long a data key encrypts new values before it is replaced
+const dataKeyLifetime = time.Hour
+
+// masterKeyLength is the length of local master keys (AES-256)
+const masterKeyLength = 32
+
+// ErrUnknownKey is returned for values encrypted with a master key that is not configured
+var ErrUnknownKey = errors.New("secret was encrypted with an unknown master key")
+
+// MasterKey wraps the data keys values are sealed with
+type MasterKey interface {
+	// ID identifies the key in encrypted values
+	ID() string
+	Wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Config selects the master keys; KMSKeyID takes precedence over MasterKey
+type Config struct {
+	MasterKey          string   // base64, 32 bytes
+	PreviousMasterKeys []string // only used to decrypt
+	KMSKeyID           string
+	KMSRegion          string
+
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+}
+
+// dataKey is the data key new values are encrypted with
+type dataKey struct {
+	plain   []byte
+	wrapped string
+	created time.Time
+}
+
+// Vault encrypts and decrypts secrets. Without a master key it stores them
+// in plaintext, and values that were never encrypted always decrypt to
+// themselves, so existing rows keep working until they are re-encrypted.
+type Vault struct {
+	current MasterKey // nil when no master key is configured
+	keys    map[string]MasterKey
+
+	mu        sync.Mutex
+	dataKey   *dataKey
+	unwrapped map[string][]byte // wrapped data key -> data key
+}
+
+// NewVault creates a vault with the configured master keys
+func NewVault(cfg Config) (*Vault, error) {
+	v := &Vault{keys: make(map[string]MasterKey), unwrapped: make(map[string][]byte)}
+
+	for i, encoded := range append([]string{cfg.MasterKey}, cfg.PreviousMasterKeys...) {
+		if encoded == "" {
+			continue
+		}
+		key, err := newLocalKey(encoded)
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("invalid SECRETS_MASTER_KEY: %w", err)
+			}
+			return nil, fmt.Errorf("invalid key in SECRETS_PREVIOUS_MASTER_KEYS: %w", err)
+		}
+		v.keys[key.ID()] = key
+		if i == 0 {
+			v.current = key
+		}
+	}
+
+	if cfg.KMSKeyID != "" {
+		// The AWS secret may be given encrypted, but only with a local key:
+		// the KMS key can't unlock the credentials it is reached with
+		secretAccessKey, err := v.Decrypt(context.Background(), cfg.AWSSecretAccessKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt AWS_SECRET_ACCESS_KEY: %w", err)
+		}
+		key := newKMSKey(cfg.KMSKeyID, cfg.KMSRegion, sigv4.Credentials{
+			AccessKeyID:     cfg.AWSAccessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    cfg.AWSSessionToken,
+		})
+		v.keys[key.ID()] = key
+		v.current = key
+	}
+
+	return v, nil
+}
+
+// Enabled reports whether new values are encrypted
+func (v *Vault) Enabled() bool {
+	return v.current != nil
+}
+
+// Encrypt seals a value; it is returned unchanged when no master key is configured
+func (v *Vault) Encrypt(ctx context.Context, plaintext string) (string, error) {
+	if v.current == nil {
+		return plaintext, nil
+	}
+
+	key, err := v.currentDataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(key.plain, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + v.current.ID() + ":" + key.wrapped + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt. Values without the envelope
+// prefix are plaintext and returned as they are.
+func (v *Vault) Decrypt(ctx context.Context, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+	keyID, wrapped, encoded := parts[0], parts[1], parts[2]
+
+	masterKey, ok := v.keys[keyID]
+	if !ok {
+		// KMS finds the key from the wrapped data key itself, so values of a
+		// replaced KMS key still decrypt if the credentials may use it
+		current, isKMS := v.current.(*kmsKey)
+		if !isKMS || !strings.HasPrefix(keyID, kmsKeyIDPrefix) {
+			return "", ErrUnknownKey
+		}
+		masterKey = current
+	}
+	plainKey, err := v.unwrap(ctx, masterKey, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+	plaintext, err := open(plainKey, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// DecryptAll decrypts each value in place
+func (v *Vault) DecryptAll(ctx context.Context, values ...*string) error {
+	for _, value := range values {
+		plaintext, err := v.Decrypt(ctx, *value)
+		if err != nil {
+			return err
+		}
+		*value = plaintext
+	}
+	return nil
+}
+
+// NeedsRotation reports whether a value is stored in plaintext or under a
+// master key other than the current one
+func (v *Vault) NeedsRotation(value string) bool {
+	if v.current == nil || value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, envelopePrefix+v.current.ID()+":")
+}
+
+// IsEncrypted reports whether a value was sealed by a vault
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// GenerateKey creates a local master key for SECRETS_MASTER_KEY
+func GenerateKey() (string, error) {
+	key := make([]byte, masterKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// currentDataKey returns the data key for new values, creating one when the
+// previous one expired
+func (v *Vault) currentDataKey(ctx context.Context) (*dataKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.dataKey != nil && time.Since(v.dataKey.created) < dataKeyLifetime {
+		return v.dataKey, nil
+	}
+
+	plain := make([]byte, 32)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := v.current.Wrap(ctx, plain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	v.dataKey = &dataKey{plain: plain, wrapped: base64.RawURLEncoding.EncodeToString(wrapped), created: time.Now()}
+	v.unwrapped[v.dataKey.wrapped] = plain
+	return v.dataKey, nil
+}
+
+// unwrap returns the data key of an encrypted value, caching it so a KMS key
+// is only called once per data key
+func (v *Vault) unwrap(ctx context.Context, masterKey MasterKey, wrapped string) ([]byte, error) {
+	v.mu.Lock()
+	plain, ok := v.unwrapped[wrapped]
+	v.mu.Unlock()
+	if ok {
+		return plain, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("malformed encrypted secret")
+	}
+	plain, err = masterKey.Unwrap(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	v.mu.Lock()
+	v.unwrapped[wrapped] = plain
+	v.mu.Unlock()
+	return plain, nil
+}
+
+// localKey is a master key given in the configuration
+type localKey struct {
+	id  string
+	key []byte
+}
+
+func newLocalKey(encoded string) (*localKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != masterKeyLength {
+		return nil, fmt.Errorf("must be %d bytes encoded as base64", masterKeyLength)
+	}
+
+	// The ID tells keys apart without revealing them
+	sum := sha256.Sum256(key)
+	return &localKey{id: "local-" + hex.EncodeToString(sum[:4]), key: key}, nil
+}
+
+func (k *localKey) ID() string { return k.id }
+
+func (k *localKey) Wrap(_ context.Context, dataKey []byte) ([]byte, error) {
+	return seal(k.key, dataKey)
+}
+
+func (k *localKey) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	return open(k.key, wrapped)
+}
+
+// seal encrypts with AES-GCM, prefixing the random nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts what seal produced
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"pocketploy/internal/captcha"
+	"pocketploy/internal/config"
+	"pocketploy/internal/mail"
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxAbuseReports caps the reports returned by one review queue query
+const maxAbuseReports = 200
+
+// maxReportsPerSubdomain caps the reports accepted about one subdomain per
+// day, so a single instance can't be used to flood the review queue
+const maxReportsPerSubdomain = 20
+
+// AbuseService accepts abuse reports and carries out admin takedowns
+type AbuseService struct {
+	db              *sqlx.DB
+	instanceService *InstanceService
+	userRepo        *repositories.UserRepository
+	captcha         captcha.Verifier
+	notifier        InstanceNotifier
+	mailer          mail.Mailer
+	audit           *AuditService
+	config          *config.Config
+}
+
+// NewAbuseService creates a new abuse service
+func NewAbuseService(db *sqlx.DB, instanceService *InstanceService, userRepo *repositories.UserRepository, captchaVerifier captcha.Verifier, notifier InstanceNotifier, mailer mail.Mailer, audit *AuditService, cfg *config.Config) *AbuseService {
+	return &AbuseService{
+		db:              db,
+		instanceService: instanceService,
+		userRepo:        userRepo,
+		captcha:         captchaVerifier,
+		notifier:        notifier,
+		mailer:          mailer,
+		audit:           audit,
+		config:          cfg,
+	}
+}
+
+// Report stores an abuse report about the instance served on a subdomain. The
+// subdomain may be given as a URL, a host, or just the instance's label.
+func (s *AbuseService) Report(ctx context.Context, req models.CreateAbuseReportRequest, reporterIP string) (*models.AbuseReport, error) {
+	if err := s.verifyCaptcha(ctx, req.CaptchaToken, reporterIP); err != nil {
+		return nil, err
+	}
+
+	subdomain := s.normalizeSubdomain(req.Subdomain)
+	if subdomain == "" {
+		return nil, fmt.Errorf("invalid subdomain")
+	}
+
+	instance, err := s.instanceService.FindInstanceByHost(ctx, subdomain)
+	if err != nil {
+		return nil, fmt.Errorf("no instance is served on this subdomain")
+	}
+
+	recent, err := models.CountRecentAbuseReports(ctx, s.db, subdomain, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	if recent >= maxReportsPerSubdomain {
+		return nil, fmt.Errorf("this subdomain has already been reported")
+	}
+
+	report := &models.AbuseReport{
+		Subdomain:   subdomain,
+		InstanceID:  &instance.ID,
+		Category:    req.Category,
+		Description: strings.TrimSpace(req.Description),
+		ReporterIP:  reporterIP,
+	}
+	if email := strings.TrimSpace(req.ReporterEmail); email != "" {
+		report.ReporterEmail = &email
+	}
+
+	if err := models.CreateAbuseReport(ctx, s.db, report); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Abuse report %s received for %s (%s)", report.ID, subdomain, report.Category)
+	return report, nil
+}
+
+// List returns the reports with a status (all when empty), oldest first
+func (s *AbuseService) List(ctx context.Context, status string) ([]models.AbuseReport, error) {
+	switch status {
+	case "", models.AbuseReportOpen, models.AbuseReportActioned, models.AbuseReportDismissed:
+	default:
+		return nil, fmt.Errorf("status must be open, actioned or dismissed")
+	}
+	return models.ListAbuseReports(ctx, s.db, status, maxAbuseReports)
+}
+
+// Get returns a report
+func (s *AbuseService) Get(ctx context.Context, id string) (*models.AbuseReport, error) {
+	return models.FindAbuseReportByID(ctx, s.db, id)
+}
+
+// TakeDown suspends the reported instance, notifies its owner and closes the
+// report as actioned. An instance that is already suspended (e.g. by an
+// earlier report) is left as it is.
+func (s *AbuseService) TakeDown(ctx context.Context, id, adminID, note, ipAddress string) (*models.AbuseReport, error) {
+	report, err := models.FindAbuseReportByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != models.AbuseReportOpen {
+		return nil, models.ErrAbuseReportResolved
+	}
+	if report.InstanceID == nil {
+		return nil, fmt.Errorf("the reported instance no longer exists")
+	}
+
+	reason := "Abuse report: " + report.Category
+	instance, err := s.instanceService.SuspendInstance(ctx, *report.InstanceID, reason)
+	if err != nil {
+		if err.Error() != "instance is already suspended" {
+			return nil, err
+		}
+		if instance, err = s.instanceService.store.FindInstanceByID(ctx, *report.InstanceID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := models.ResolveAbuseReport(ctx, s.db, report, models.AbuseReportActioned, adminID, note); err != nil {
+		return nil, err
+	}
+
+	s.notifier.InstanceTakenDown(ctx, instance, reason)
+	s.emailOwner(ctx, instance, reason)
+
+	ownerID := instance.UserID.String()
+	s.audit.Record(ctx, models.AuditEntry{
+		ActorID:   &adminID,
+		UserID:    &ownerID,
+		Action:    models.AuditAbuseTakedown,
+		IPAddress: ipAddress,
+		Details: models.AuditDetails{
+			"report_id":   report.ID,
+			"instance_id": instance.ID.String(),
+			"subdomain":   report.Subdomain,
+			"category":    report.Category,
+			"note":        note,
+		},
+	})
+
+	return report, nil
+}
+
+// Dismiss closes a report without acting on the instance
+func (s *AbuseService) Dismiss(ctx context.Context, id, adminID, note, ipAddress string) (*models.AbuseReport, error) {
+	report, err := models.FindAbuseReportByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.ResolveAbuseReport(ctx, s.db, report, models.AbuseReportDismissed, adminID, note); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, models.AuditEntry{
+		ActorID:   &adminID,
+		Action:    models.AuditAbuseDismissed,
+		IPAddress: ipAddress,
+		Details: models.AuditDetails{
+			"report_id": report.ID,
+			"subdomain": report.Subdomain,
+			"note":      note,
+		},
+	})
+
+	return report, nil
+}
+
+// emailOwner tells the owner their instance was taken down. Failures are only
+// logged; the in-app notification has already been sent.
+func (s *AbuseService) emailOwner(ctx context.Context, instance *models.Instance, reason string) {
+	owner, err := s.userRepo.GetByID(instance.UserID.String())
+	if err != nil {
+		log.Printf("Warning: failed to find owner of instance %s: %v", instance.ID, err)
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nYour instance %s (%s) has been suspended following an abuse report.\n\nReason: %s\n\nIf you believe this is a mistake, please reply to this email.\n",
+		owner.Username, instance.Name, instance.Subdomain, reason,
+	)
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      owner.Email,
+		Subject: "Your instance " + instance.Name + " has been suspended",
+		Body:    body,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to email owner of instance %s about takedown: %v", instance.ID, err)
+	}
+}
+
+// normalizeSubdomain reduces a URL or host to the lowercase host without a
+// port, and completes bare labels with the base domain
+func (s *AbuseService) normalizeSubdomain(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	if host != "" && !strings.Contains(host, ".") {
+		host += "." + s.config.BaseDomain
+	}
+	return host
+}
+
+// verifyCaptcha checks a captcha token when a provider is configured
+func (s *AbuseService) verifyCaptcha(ctx context.Context, token, remoteIP string) error {
+	if s.captcha == nil {
+		return nil
+	}
+
+	if err := s.captcha.Verify(ctx, token, remoteIP); err != nil {
+		if err.Error() == "captcha is required" {
+			return err
+		}
+		log.Printf("Warning: captcha verification failed: %v", err)
+		return fmt.Errorf("captcha verification failed")
+	}
+
+	return nil
+}
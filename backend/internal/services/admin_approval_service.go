@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxAdminOperations caps the operations returned by one list query
+const maxAdminOperations = 200
+
+// ErrSelfApproval is returned when an admin decides an operation they requested
+var ErrSelfApproval = errors.New("an operation must be approved by another admin")
+
+// AdminApprovalService holds platform-destructive admin operations (hard
+// deleting a user, purging retained instance data) until a second admin
+// approves them. Requests that aren't decided within ADMIN_APPROVAL_TTL expire.
+type AdminApprovalService struct {
+	db              *sqlx.DB
+	instanceService *InstanceService
+	userService     *UserService
+	audit           *AuditService
+	config          *config.Config
+}
+
+// NewAdminApprovalService creates a new admin approval service
+func NewAdminApprovalService(db *sqlx.DB, instanceService *InstanceService, userService *UserService, audit *AuditService, cfg *config.Config) *AdminApprovalService {
+	return &AdminApprovalService{
+		db:              db,
+		instanceService: instanceService,
+		userService:     userService,
+		audit:           audit,
+		config:          cfg,
+	}
+}
+
+// RequestUserDeletion asks for a user and all their instances to be deleted
+// for good
+func (s *AdminApprovalService) RequestUserDeletion(ctx context.Context, userID, adminID, reason, ipAddress string) (*models.AdminOperation, error) {
+	targetID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if userID == adminID {
+		return nil, fmt.Errorf("you cannot delete your own account")
+	}
+	if _, err := s.userService.GetUserProfile(userID); err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return s.request(ctx, models.AdminOperationDeleteUser, targetID, &userID, adminID, reason, ipAddress)
+}
+
+// RequestArchivePurge asks for the retained data of an archived instance to
+// be deleted before its retention period ends
+func (s *AdminApprovalService) RequestArchivePurge(ctx context.Context, archivedID, adminID, reason, ipAddress string) (*models.AdminOperation, error) {
+	targetID, err := uuid.Parse(archivedID)
+	if err != nil {
+		return nil, fmt.Errorf("archived instance not found")
+	}
+
+	archived, err := s.instanceService.store.FindArchivedInstance(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !archived.DataAvailable {
+		return nil, fmt.Errorf("archived instance data already purged")
+	}
+
+	ownerID := archived.UserID.String()
+	return s.request(ctx, models.AdminOperationPurgeArchive, targetID, &ownerID, adminID, reason, ipAddress)
+}
+
+// request stores a pending operation; requests that expired no longer block
+// a new one on the same target
+func (s *AdminApprovalService) request(ctx context.Context, kind string, targetID uuid.UUID, ownerID *string, adminID, reason, ipAddress string) (*models.AdminOperation, error) {
+	if err := models.ExpireAdminOperations(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	op := &models.AdminOperation{
+		Kind:        kind,
+		TargetID:    targetID,
+		Reason:      reason,
+		RequestedBy: &adminID,
+		ExpiresAt:   time.Now().Add(s.config.AdminApprovalTTL),
+	}
+	if err := models.CreateAdminOperation(ctx, s.db, op); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Admin %s requested %s of %s (operation %s)", adminID, kind, targetID, op.ID)
+	s.record(ctx, op, models.AuditOperationRequested, adminID, ownerID, ipAddress)
+
+	return op, nil
+}
+
+// List returns the operations with a status (all when empty), newest first
+func (s *AdminApprovalService) List(ctx context.Context, status string) ([]models.AdminOperation, error) {
+	switch status {
+	case "", models.AdminOperationPending, models.AdminOperationApproved, models.AdminOperationRejected,
+		models.AdminOperationExpired, models.AdminOperationFailed:
+	default:
+		return nil, fmt.Errorf("status must be pending, approved, rejected, expired or failed")
+	}
+
+	if err := models.ExpireAdminOperations(ctx, s.db); err != nil {
+		return nil, err
+	}
+	return models.ListAdminOperations(ctx, s.db, status, maxAdminOperations)
+}
+
+// Approve carries out a pending operation requested by another admin. An
+// operation that fails is marked failed with the cause and is not retried;
+// it can be requested again.
+func (s *AdminApprovalService) Approve(ctx context.Context, id, adminID, ipAddress string) (*models.AdminOperation, error) {
+	op, err := models.FindAdminOperationByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if op.RequestedBy != nil && *op.RequestedBy == adminID {
+		return nil, ErrSelfApproval
+	}
+
+	if err := models.DecideAdminOperation(ctx, s.db, op, models.AdminOperationApproved, adminID); err != nil {
+		return nil, err
+	}
+
+	ownerID, err := s.execute(ctx, op, adminID)
+	if err != nil {
+		log.Printf("Admin operation %s (%s of %s) failed: %v", op.ID, op.Kind, op.TargetID, err)
+		if failErr := models.FailAdminOperation(ctx, s.db, op, err); failErr != nil {
+			return nil, failErr
+		}
+	}
+
+	s.record(ctx, op, models.AuditOperationApproved, adminID, ownerID, ipAddress)
+	return op, nil
+}
+
+// Reject closes a pending operation without carrying it out. The requester
+// may withdraw their own request this way.
+func (s *AdminApprovalService) Reject(ctx context.Context, id, adminID, ipAddress string) (*models.AdminOperation, error) {
+	op, err := models.FindAdminOperationByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.DecideAdminOperation(ctx, s.db, op, models.AdminOperationRejected, adminID); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, op, models.AuditOperationRejected, adminID, nil, ipAddress)
+	return op, nil
+}
+
+// execute carries out an approved operation and returns the affected user
+func (s *AdminApprovalService) execute(ctx context.Context, op *models.AdminOperation, adminID string) (*string, error) {
+	switch op.Kind {
+	case models.AdminOperationDeleteUser:
+		userID := op.TargetID.String()
+		approverID, err := uuid.Parse(adminID)
+		if err != nil {
+			return &userID, err
+		}
+		if err := s.instanceService.PurgeUserInstances(ctx, op.TargetID, approverID); err != nil {
+			return &userID, err
+		}
+		if err := s.userService.HardDeleteUser(userID); err != nil {
+			return &userID, err
+		}
+		// The audit entry can't reference a user that no longer exists;
+		// the target ID is kept in its details
+		return nil, nil
+
+	case models.AdminOperationPurgeArchive:
+		archived, err := s.instanceService.PurgeArchivedData(ctx, op.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		ownerID := archived.UserID.String()
+		return &ownerID, nil
+
+	default:
+		return nil, fmt.Errorf("unknown admin operation %s", op.Kind)
+	}
+}
+
+// record writes an audit entry about an operation
+func (s *AdminApprovalService) record(ctx context.Context, op *models.AdminOperation, action, adminID string, ownerID *string, ipAddress string) {
+	details := models.AuditDetails{
+		"operation_id": op.ID,
+		"kind":         op.Kind,
+		"target_id":    op.TargetID.String(),
+		"status":       op.Status,
+	}
+	if op.Reason != "" {
+		details["reason"] = op.Reason
+	}
+	if op.Error != nil {
+		details["error"] = *op.Error
+	}
+
+	s.audit.Record(ctx, models.AuditEntry{
+		ActorID:   &adminID,
+		UserID:    ownerID,
+		Action:    action,
+		IPAddress: ipAddress,
+		Details:   details,
+	})
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+)
+
+// maxAnomalies caps the anomalies returned by one review queue query
+const maxAnomalies = 200
+
+// anomalyBaseline is how much usage history before the window a sudden CPU
+// increase is measured against
+const anomalyBaseline = 24 * time.Hour
+
+// AnomalyDetector periodically checks the usage history of running instances
+// for suspicious behavior and queues what it finds for admin review
+type AnomalyDetector struct {
+	store           InstanceStore
+	dockerClient    ContainerRuntime
+	instanceService *InstanceService
+	audit           *AuditService
+	config          *config.Config
+}
+
+// NewAnomalyDetector creates a new anomaly detector
+func NewAnomalyDetector(store InstanceStore, dockerClient ContainerRuntime, instanceService *InstanceService, audit *AuditService, cfg *config.Config) *AnomalyDetector {
+	return &AnomalyDetector{
+		store:           store,
+		dockerClient:    dockerClient,
+		instanceService: instanceService,
+		audit:           audit,
+		config:          cfg,
+	}
+}
+
+// Run checks instances every ANOMALY_CHECK_INTERVAL until ctx is cancelled
+func (d *AnomalyDetector) Run(ctx context.Context) {
+	if d.config.AnomalyCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(d.config.AnomalyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.check(ctx)
+		}
+	}
+}
+
+// check looks for anomalies in the recent samples of every running instance
+func (d *AnomalyDetector) check(ctx context.Context) {
+	instances, err := d.store.FindInstancesByStatus(ctx, models.InstanceStatusRunning)
+	if err != nil {
+		log.Printf("Warning: anomaly check skipped: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for i := range instances {
+		instance := &instances[i]
+
+		samples, err := d.store.FindInstanceMetrics(ctx, instance.ID, now.Add(-d.config.AnomalyWindow-anomalyBaseline))
+		if err != nil {
+			log.Printf("Warning: failed to read metrics of instance %s: %v", instance.ID, err)
+			continue
+		}
+
+		split := 0
+		for split < len(samples) && samples[split].RecordedAt.Before(now.Add(-d.config.AnomalyWindow)) {
+			split++
+		}
+		baseline, window := samples[:split], samples[split:]
+		if len(window) < 2 {
+			continue
+		}
+
+		for _, anomaly := range d.detect(baseline, window) {
+			anomaly.InstanceID = instance.ID
+			d.flag(ctx, instance, anomaly)
+		}
+	}
+}
+
+// detect applies the heuristics to the samples of the window and the
+// baseline before it
+func (d *AnomalyDetector) detect(baseline, window []models.InstanceMetric) []*models.InstanceAnomaly {
+	var anomalies []*models.InstanceAnomaly
+	threshold := float64(d.config.AnomalyCPUPercent)
+	minutes := d.config.AnomalyWindow.Minutes()
+
+	// Sudden CPU saturation: the window averages the threshold after the
+	// baseline stayed well below it (steadily busy instances are not sudden)
+	if cpu := averageCPU(window); cpu >= threshold && (len(baseline) == 0 || averageCPU(baseline) < threshold/2) {
+		anomalies = append(anomalies, &models.InstanceAnomaly{
+			Kind:        models.AnomalyCPUSaturation,
+			Description: fmt.Sprintf("CPU usage averaged %.0f%% over the last %.0f minutes", cpu, minutes),
+			Value:       cpu,
+		})
+	}
+
+	// Outbound traffic floods. The counters are cumulative but reset when the
+	// container restarts, in which case the new reading is all new traffic.
+	if d.config.AnomalyEgressRate > 0 {
+		var sent int64
+		for i := 1; i < len(window); i++ {
+			delta := window[i].NetworkTxBytes - window[i-1].NetworkTxBytes
+			if delta < 0 {
+				delta = window[i].NetworkTxBytes
+			}
+			sent += delta
+		}
+		elapsed := window[len(window)-1].RecordedAt.Sub(window[0].RecordedAt).Seconds()
+		if elapsed > 0 {
+			if rate := float64(sent) / elapsed; rate > float64(d.config.AnomalyEgressRate) {
+				anomalies = append(anomalies, &models.InstanceAnomaly{
+					Kind:        models.AnomalyNetworkEgress,
+					Description: fmt.Sprintf("Sent %.1f MB/s on average over the last %.0f minutes", rate/(1<<20), minutes),
+					Value:       rate,
+				})
+			}
+		}
+	}
+
+	// Disk growth spikes: growth from the smallest size seen in the window
+	if d.config.AnomalyDiskGrowth > 0 {
+		smallest := window[0].DiskBytes
+		for _, sample := range window {
+			if sample.DiskBytes < smallest {
+				smallest = sample.DiskBytes
+			}
+		}
+		if growth := window[len(window)-1].DiskBytes - smallest; growth > d.config.AnomalyDiskGrowth {
+			anomalies = append(anomalies, &models.InstanceAnomaly{
+				Kind:        models.AnomalyDiskGrowth,
+				Description: fmt.Sprintf("Data grew by %.1f MB over the last %.0f minutes", float64(growth)/(1<<20), minutes),
+				Value:       float64(growth),
+			})
+		}
+	}
+
+	return anomalies
+}
+
+// flag queues an anomaly unless the instance already has an open one of the
+// same kind, throttling the instance first when auto-throttling is on
+func (d *AnomalyDetector) flag(ctx context.Context, instance *models.Instance, anomaly *models.InstanceAnomaly) {
+	throttle := d.config.AnomalyAutoThrottle && instance.ContainerID != nil && *instance.ContainerID != ""
+
+	anomaly.Throttled = throttle
+	created, err := d.store.CreateAnomaly(ctx, anomaly)
+	if err != nil {
+		log.Printf("Warning: failed to record anomaly of instance %s: %v", instance.ID, err)
+		return
+	}
+	if !created {
+		return
+	}
+
+	log.Printf("Anomaly %s detected on instance %s: %s", anomaly.Kind, instance.ID, anomaly.Description)
+
+	if !throttle {
+		return
+	}
+	if err := d.dockerClient.SetCPULimit(ctx, *instance.ContainerID, d.config.AnomalyThrottleCPUs); err != nil {
+		log.Printf("Warning: failed to throttle instance %s: %v", instance.ID, err)
+		return
+	}
+
+	ownerID := instance.UserID.String()
+	d.audit.Record(ctx, models.AuditEntry{
+		UserID: &ownerID,
+		Action: models.AuditAnomalyThrottled,
+		Details: models.AuditDetails{
+			"anomaly_id":  anomaly.ID,
+			"instance_id": instance.ID.String(),
+			"kind":        anomaly.Kind,
+			"cpus":        d.config.AnomalyThrottleCPUs,
+		},
+	})
+}
+
+// List returns the anomalies with a status (all when empty), oldest first
+func (d *AnomalyDetector) List(ctx context.Context, status string) ([]models.InstanceAnomaly, error) {
+	switch status {
+	case "", models.AnomalyOpen, models.AnomalyDismissed, models.AnomalySuspended:
+	default:
+		return nil, fmt.Errorf("status must be open, dismissed or suspended")
+	}
+	return d.store.ListAnomalies(ctx, status, maxAnomalies)
+}
+
+// Dismiss closes an anomaly as expected behavior. A throttled instance gets
+// its CPUs back once none of its open anomalies throttle it.
+func (d *AnomalyDetector) Dismiss(ctx context.Context, id, adminID, note, ipAddress string) (*models.InstanceAnomaly, error) {
+	anomaly, err := d.store.FindAnomaly(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.store.ResolveAnomaly(ctx, anomaly, models.AnomalyDismissed, adminID, note); err != nil {
+		return nil, err
+	}
+
+	if anomaly.Throttled {
+		d.unthrottle(ctx, anomaly)
+	}
+
+	d.record(ctx, anomaly, models.AuditAnomalyDismissed, adminID, note, ipAddress)
+	return anomaly, nil
+}
+
+// Suspend suspends the instance and closes the anomaly. An instance that is
+// already suspended is left as it is.
+func (d *AnomalyDetector) Suspend(ctx context.Context, id, adminID, note, ipAddress string) (*models.InstanceAnomaly, error) {
+	anomaly, err := d.store.FindAnomaly(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if anomaly.Status != models.AnomalyOpen {
+		return nil, models.ErrAnomalyResolved
+	}
+
+	reason := "Suspicious activity: " + anomaly.Description
+	if _, err := d.instanceService.SuspendInstance(ctx, anomaly.InstanceID, reason); err != nil && err.Error() != "instance is already suspended" {
+		return nil, err
+	}
+
+	if err := d.store.ResolveAnomaly(ctx, anomaly, models.AnomalySuspended, adminID, note); err != nil {
+		return nil, err
+	}
+
+	d.record(ctx, anomaly, models.AuditAnomalySuspended, adminID, note, ipAddress)
+	return anomaly, nil
+}
+
+// unthrottle lifts the CPU limit of a dismissed anomaly's instance unless
+// another open anomaly still throttles it
+func (d *AnomalyDetector) unthrottle(ctx context.Context, anomaly *models.InstanceAnomaly) {
+	throttled, err := d.store.HasThrottledAnomaly(ctx, anomaly.InstanceID)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if throttled {
+		return
+	}
+
+	instance, err := d.instanceService.store.FindInstanceByID(ctx, anomaly.InstanceID)
+	if err != nil {
+		log.Printf("Warning: failed to find instance %s: %v", anomaly.InstanceID, err)
+		return
+	}
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return
+	}
+
+	if err := d.dockerClient.SetCPULimit(ctx, *instance.ContainerID, 0); err != nil {
+		log.Printf("Warning: failed to lift CPU limit of instance %s: %v", instance.ID, err)
+	}
+}
+
+// record writes the audit entry of a review
+func (d *AnomalyDetector) record(ctx context.Context, anomaly *models.InstanceAnomaly, action, adminID, note, ipAddress string) {
+	d.audit.Record(ctx, models.AuditEntry{
+		ActorID:   &adminID,
+		Action:    action,
+		IPAddress: ipAddress,
+		Details: models.AuditDetails{
+			"anomaly_id":  anomaly.ID,
+			"instance_id": anomaly.InstanceID.String(),
+			"kind":        anomaly.Kind,
+			"note":        note,
+		},
+	})
+}
+
+// averageCPU is the mean CPU percent of samples
+func averageCPU(samples []models.InstanceMetric) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var total float64
+	for _, sample := range samples {
+		total += sample.CPUPercent
+	}
+	return total / float64(len(samples))
+}
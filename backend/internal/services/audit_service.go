@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"pocketploy/internal/geoip"
+	"pocketploy/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxAuditEntries caps the entries returned by one audit log query
+const maxAuditEntries = 500
+
+// AuditService writes and reads the audit log
+type AuditService struct {
+	db  *sqlx.DB
+	geo geoip.Locator // nil when GeoIP is disabled
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db *sqlx.DB, geo geoip.Locator) *AuditService {
+	return &AuditService{db: db, geo: geo}
+}
+
+// Record appends an entry to the audit log, located by its IP address when
+// GeoIP is enabled. Failures are logged rather than returned so auditing never
+// breaks the action being audited.
+func (s *AuditService) Record(ctx context.Context, entry models.AuditEntry) {
+	if s.geo != nil && entry.IPAddress != "" && entry.Country == "" {
+		if location, ok := s.geo.Lookup(entry.IPAddress); ok {
+			entry.Country = location.CountryCode
+			entry.City = location.City
+		}
+	}
+
+	if err := models.CreateAuditEntry(ctx, s.db, &entry); err != nil {
+		log.Printf("Warning: failed to record audit entry %s: %v (details: %v)", entry.Action, err, entry.Details)
+	}
+}
+
+// List returns the newest audit entries matching the filter
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 || filter.Limit > maxAuditEntries {
+		filter.Limit = maxAuditEntries
+	}
+	return models.ListAuditEntries(ctx, s.db, filter)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+)
+
+// Impersonation is a token an admin minted to act as a user
+type Impersonation struct {
+	AccessToken string       `json:"access_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	User        *models.User `json:"-"`
+}
+
+// Impersonate mints a short-lived access token for an admin to act as a user,
+// e.g. to reproduce a support issue. The token is marked as impersonated in its
+// claims, can't be refreshed, and every request made with it is audited.
+// Other admins can't be impersonated.
+func (s *AuthService) Impersonate(ctx context.Context, adminID, userID, reason, ipAddress string) (*Impersonation, error) {
+	if adminID == userID {
+		return nil, fmt.Errorf("cannot impersonate yourself")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("account is inactive")
+	}
+	if user.IsAdmin {
+		return nil, fmt.Errorf("administrators cannot be impersonated")
+	}
+
+	token, claims, err := utils.GenerateImpersonationToken(user.ID, user.Username, user.Email, adminID, s.config.JWTAccessSecret, s.config.ImpersonationTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEntry{
+		ActorID:   &adminID,
+		UserID:    &user.ID,
+		Action:    models.AuditImpersonationStarted,
+		IPAddress: ipAddress,
+		Details: models.AuditDetails{
+			"reason":     reason,
+			"token_id":   claims.ID,
+			"expires_at": claims.ExpiresAt.Time,
+		},
+	})
+
+	return &Impersonation{
+		AccessToken: token,
+		ExpiresAt:   claims.ExpiresAt.Time,
+		User:        user,
+	}, nil
+}
+
+// EndImpersonation revokes the impersonation token of the current request
+func (s *AuthService) EndImpersonation(ctx context.Context, claims *utils.Claims, ipAddress string) error {
+	if !claims.Impersonated() {
+		return fmt.Errorf("not impersonating")
+	}
+
+	s.RevokeAccessToken(claims)
+
+	s.audit.Record(ctx, models.AuditEntry{
+		ActorID:   &claims.ImpersonatorID,
+		UserID:    &claims.UserID,
+		Action:    models.AuditImpersonationEnded,
+		IPAddress: ipAddress,
+		Details:   models.AuditDetails{"token_id": claims.ID},
+	})
+
+	return nil
+}
+
+// RecordImpersonatedRequest audits a request made with an impersonation token
+func (s *AuthService) RecordImpersonatedRequest(r *http.Request, claims *utils.Claims, status int) {
+	s.audit.Record(r.Context(), models.AuditEntry{
+		ActorID:   &claims.ImpersonatorID,
+		UserID:    &claims.UserID,
+		Action:    models.AuditImpersonatedRequest,
+		IPAddress: utils.ClientIP(r),
+		Details: models.AuditDetails{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"status":   status,
+			"token_id": claims.ID,
+		},
+	})
+}
@@ -1,12 +1,19 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"pocketploy/internal/cache"
+	"pocketploy/internal/captcha"
 	"pocketploy/internal/config"
+	"pocketploy/internal/geoip"
+	"pocketploy/internal/mail"
 	"pocketploy/internal/models"
 	"pocketploy/internal/repositories"
 	"pocketploy/internal/utils"
@@ -16,33 +23,54 @@ import (
 
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo  *repositories.UserRepository
-	tokenRepo *repositories.TokenRepository
-	config    *config.Config
+	userRepo    *repositories.UserRepository
+	tokenRepo   *repositories.TokenRepository
+	inviteRepo  *repositories.InviteRepository
+	revocations *RevocationList
+	captcha     captcha.Verifier // nil when captcha is disabled
+	geo         geoip.Locator    // nil when GeoIP is disabled
+	store       cache.Store
+	mailer      mail.Mailer
+	audit       *AuditService
+	config      *config.Config
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(userRepo *repositories.UserRepository, tokenRepo *repositories.TokenRepository, cfg *config.Config) *AuthService {
+func NewAuthService(userRepo *repositories.UserRepository, tokenRepo *repositories.TokenRepository, inviteRepo *repositories.InviteRepository, revocations *RevocationList, captchaVerifier captcha.Verifier, geo geoip.Locator, store cache.Store, mailer mail.Mailer, audit *AuditService, cfg *config.Config) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenRepo: tokenRepo,
-		config:    cfg,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		inviteRepo:  inviteRepo,
+		revocations: revocations,
+		captcha:     captchaVerifier,
+		geo:         geo,
+		store:       store,
+		mailer:      mailer,
+		audit:       audit,
+		config:      cfg,
 	}
 }
 
+// loginFailureWindow is how long failed login attempts are remembered
+const loginFailureWindow = 15 * time.Minute
+
 // SignupParams contains parameters for user registration
 type SignupParams struct {
-	Username string
-	Email    string
-	Password string
-	Request  *http.Request // HTTP request for extracting IP and User-Agent
+	Username     string
+	Email        string
+	Password     string
+	InviteCode   string        // Required when SIGNUP_REQUIRE_INVITE is enabled
+	CaptchaToken string        // Required when a captcha provider is configured
+	Request      *http.Request // HTTP request for extracting IP and User-Agent
 }
 
 // LoginParams contains parameters for user login
 type LoginParams struct {
-	Email    string
-	Password string
-	Request  *http.Request // HTTP request for extracting IP and User-Agent
+	Email        string
+	Password     string
+	CaptchaToken string        // Required after repeated failed logins
+	DeviceToken  string        // Device token of a trusted device, which skips the captcha
+	Request      *http.Request // HTTP request for extracting IP and User-Agent
 }
 
 // TokenPair contains access and refresh tokens
@@ -50,6 +78,8 @@ type TokenPair struct {
 	AccessToken  string
 	RefreshToken string
 	ExpiresAt    time.Time
+	SessionID    string
+	TrustedUntil *time.Time // Set when the session was started on a trusted device
 }
 
 // RegisterUser creates a new user account
@@ -58,6 +88,15 @@ func (s *AuthService) RegisterUser(params SignupParams) (*models.User, *TokenPai
 	params.Username = strings.ToLower(strings.TrimSpace(params.Username))
 	params.Email = strings.ToLower(strings.TrimSpace(params.Email))
 
+	if !s.config.Settings().SignupEnabled {
+		return nil, nil, fmt.Errorf("signups are disabled")
+	}
+
+	// Stop automated account creation before touching the database
+	if err := s.verifyCaptcha(params.CaptchaToken, params.Request); err != nil {
+		return nil, nil, err
+	}
+
 	// Validate username format
 	if err := utils.ValidateStruct(models.SignupRequest{
 		Username: params.Username,
@@ -67,6 +106,11 @@ func (s *AuthService) RegisterUser(params SignupParams) (*models.User, *TokenPai
 		return nil, nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// Usernames become part of instance hostnames, so keep sensitive names out
+	if utils.IsReservedName(params.Username, s.config.Settings().ReservedNames) {
+		return nil, nil, fmt.Errorf("username is reserved")
+	}
+
 	// Check if username exists
 	exists, err := s.userRepo.ExistsByUsername(params.Username)
 	if err != nil {
@@ -85,6 +129,20 @@ func (s *AuthService) RegisterUser(params SignupParams) (*models.User, *TokenPai
 		return nil, nil, fmt.Errorf("email already exists")
 	}
 
+	// Closed-beta mode: consume one use of the invite code
+	inviteCode := strings.TrimSpace(params.InviteCode)
+	if s.config.Settings().SignupRequireInvite {
+		if inviteCode == "" {
+			return nil, nil, fmt.Errorf("invite code is required")
+		}
+		if err := s.inviteRepo.Redeem(inviteCode); err != nil {
+			if err.Error() == "invalid invite code" {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("failed to redeem invite code: %w", err)
+		}
+	}
+
 	// Hash password
 	fmt.Printf("[DEBUG] Hashing password with bcrypt cost: %d\n", s.config.BcryptCost)
 	passwordHash, err := utils.HashPassword(params.Password, s.config.BcryptCost)
@@ -101,12 +159,18 @@ func (s *AuthService) RegisterUser(params SignupParams) (*models.User, *TokenPai
 		Email:        params.Email,
 		PasswordHash: passwordHash,
 		IsActive:     true,
+		Plan:         models.DefaultPlan,
+		Timezone:     models.DefaultTimezone,
+		Locale:       models.DefaultLocale,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
 	// Save user to database
 	if err := s.userRepo.Create(user); err != nil {
+		if s.config.Settings().SignupRequireInvite {
+			_ = s.inviteRepo.Release(inviteCode)
+		}
 		return nil, nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -126,10 +190,20 @@ func (s *AuthService) AuthenticateUser(params LoginParams) (*models.User, *Token
 
 	fmt.Printf("[DEBUG] Login attempt for email: %s\n", params.Email)
 
+	// Require a captcha once an account has seen repeated failed logins,
+	// unless the login comes from a device the user trusts
+	trusted := s.trustedDevice(params.Email, params.DeviceToken)
+	if trusted == nil && s.loginCaptchaRequired(params.Email) {
+		if err := s.verifyCaptcha(params.CaptchaToken, params.Request); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(params.Email)
 	if err != nil {
 		fmt.Printf("[DEBUG] Failed to get user by email: %v\n", err)
+		s.recordLoginFailure(params.Email)
 		return nil, nil, fmt.Errorf("invalid email or password")
 	}
 
@@ -145,9 +219,13 @@ func (s *AuthService) AuthenticateUser(params LoginParams) (*models.User, *Token
 	fmt.Printf("[DEBUG] Verifying password (hash length: %d)\n", len(user.PasswordHash))
 	if err := utils.CheckPassword(params.Password, user.PasswordHash); err != nil {
 		fmt.Printf("[DEBUG] Password verification failed: %v\n", err)
+		s.recordLoginFailure(params.Email)
 		return nil, nil, fmt.Errorf("invalid email or password")
 	}
 
+	// Successful login clears the failure counter
+	_ = s.store.Delete(context.Background(), loginFailureKey(params.Email))
+
 	fmt.Printf("[DEBUG] Password verified successfully\n")
 
 	// Update last login timestamp
@@ -156,20 +234,177 @@ func (s *AuthService) AuthenticateUser(params LoginParams) (*models.User, *Token
 		fmt.Printf("Warning: failed to update last login: %v\n", err)
 	}
 
+	// Look the device and its country up before its new session is stored
+	newDevice := trusted == nil && s.isNewDevice(user.ID, params.Request)
+	location, located := s.locate(params.Request)
+	newCountry := located && s.isNewCountry(user.ID, location.CountryCode)
+
 	// Generate tokens with request context for IP/UserAgent
 	tokens, err := s.generateTokenPair(user.ID, user.Username, user.Email, params.Request)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
+	if newCountry {
+		s.audit.Record(context.Background(), models.AuditEntry{
+			ActorID:   &user.ID,
+			UserID:    &user.ID,
+			Action:    models.AuditLoginNewCountry,
+			IPAddress: utils.ClientIP(params.Request),
+			Country:   location.CountryCode,
+			City:      location.City,
+			Details: models.AuditDetails{
+				"session_id": tokens.SessionID,
+				"location":   location.String(),
+				"trusted":    trusted != nil,
+			},
+		})
+	}
+
+	if newDevice || (newCountry && trusted == nil) {
+		s.sendNewDeviceEmail(user, tokens.SessionID, params.Request, location, newCountry)
+	}
+
+	// The device's trust (and label) moves on to the new session
+	if trusted != nil && trusted.UserID == user.ID {
+		if err := s.tokenRepo.TransferTrust(trusted.ID, tokens.SessionID); err != nil {
+			fmt.Printf("Warning: failed to transfer device trust: %v\n", err)
+		} else {
+			tokens.TrustedUntil = trusted.TrustedUntil
+		}
+	}
+
 	return user, tokens, nil
 }
 
+// trustedDevice returns the session holding the trust of the device a login
+// comes from, if the device is trusted by the account being logged in to
+func (s *AuthService) trustedDevice(email, deviceToken string) *models.RefreshToken {
+	if deviceToken == "" {
+		return nil
+	}
+
+	trusted, err := s.tokenRepo.GetTrustedDevice(utils.HashRefreshToken(deviceToken))
+	if err != nil {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(trusted.UserID)
+	if err != nil || !strings.EqualFold(user.Email, email) {
+		return nil
+	}
+
+	return trusted
+}
+
+// isNewDevice reports whether a login comes from an IP address and user agent
+// combination the account has never had a session from
+func (s *AuthService) isNewDevice(userID string, r *http.Request) bool {
+	if !s.config.LoginNotificationsEnabled || r == nil {
+		return false
+	}
+
+	seen, err := s.tokenRepo.HasDevice(userID, utils.ClientIP(r), r.Header.Get("User-Agent"))
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return false
+	}
+	return !seen
+}
+
+// locate returns the location of a request's client IP address
+func (s *AuthService) locate(r *http.Request) (geoip.Location, bool) {
+	if s.geo == nil || r == nil {
+		return geoip.Location{}, false
+	}
+	return s.geo.Lookup(utils.ClientIP(r))
+}
+
+// isNewCountry reports whether a login comes from a country the account has
+// never had a session from. Accounts without any located session (e.g. from
+// before GeoIP was enabled) have nothing to compare with and aren't flagged.
+func (s *AuthService) isNewCountry(userID, country string) bool {
+	if country == "" {
+		return false
+	}
+
+	located, err := s.tokenRepo.HasLocatedSession(userID)
+	if err != nil || !located {
+		return false
+	}
+
+	seen, err := s.tokenRepo.HasCountry(userID, country)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return false
+	}
+	return !seen
+}
+
+// sendNewDeviceEmail tells the user about a login from a new device or
+// country, with a link that signs the session out. Failures are only logged.
+func (s *AuthService) sendNewDeviceEmail(user *models.User, sessionID string, r *http.Request, location geoip.Location, newCountry bool) {
+	revokeToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		fmt.Printf("Warning: failed to generate sign-out link: %v\n", err)
+		return
+	}
+	if err := s.tokenRepo.SetRevokeToken(sessionID, utils.HashRefreshToken(revokeToken)); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	if userAgent == "" {
+		userAgent = "unknown"
+	}
+
+	origin := "a new device"
+	if newCountry {
+		origin = "a country it hasn't been used from before"
+	}
+	place := ""
+	if location.Country != "" {
+		place = "Location: " + location.String() + "\n"
+	}
+
+	err = s.mailer.Send(context.Background(), mail.Message{
+		To:      user.Email,
+		Subject: "New login to your Pocketploy account",
+		Body: fmt.Sprintf("Your Pocketploy account (%s) was just logged in to from %s.\n\n"+
+			"Time: %s\nIP address: %s\n%sBrowser: %s\n\n"+
+			"If this was you, there's nothing to do.\n\n"+
+			"If it wasn't, open this link to sign that session out, then change your password:\n%s\n",
+			user.Username, origin, time.Now().UTC().Format("2006-01-02 15:04 MST"), utils.ClientIP(r), place, userAgent,
+			s.sessionRevokeLink(revokeToken)),
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to send login notification to user %s: %v\n", user.ID, err)
+	}
+}
+
+// sessionRevokeLink returns the sign-out page URL for a token
+func (s *AuthService) sessionRevokeLink(token string) string {
+	link, err := url.Parse(s.config.SessionRevokeURL)
+	if err != nil {
+		return s.config.SessionRevokeURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
 // RefreshAccessToken generates a new access token using a refresh token
 func (s *AuthService) RefreshAccessToken(refreshTokenString string) (string, time.Time, error) {
 	// Hash the token to look up in database
 	tokenHash := utils.HashRefreshToken(refreshTokenString)
 
+	// Reject recently revoked tokens without a database round trip
+	if s.revocations.IsRevoked(context.Background(), "refresh:"+tokenHash) {
+		return "", time.Time{}, fmt.Errorf("invalid or expired refresh token")
+	}
+
 	// Get refresh token from database
 	token, err := s.tokenRepo.GetByTokenHash(tokenHash)
 	if err != nil {
@@ -188,8 +423,8 @@ func (s *AuthService) RefreshAccessToken(refreshTokenString string) (string, tim
 	}
 
 	// Generate new access token
-	accessExpiry, _ := utils.ParseDuration(s.config.JWTAccessExpiry)
-	accessToken, err := utils.GenerateAccessToken(user.ID, user.Username, user.Email, s.config.JWTAccessSecret, accessExpiry)
+	accessExpiry := s.config.JWTAccessExpiry
+	accessToken, err := utils.GenerateAccessToken(user.ID, user.Username, user.Email, token.ID, s.config.JWTAccessSecret, accessExpiry)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -208,14 +443,36 @@ func (s *AuthService) RevokeRefreshToken(refreshTokenString string) error {
 		return fmt.Errorf("failed to revoke token: %w", err)
 	}
 
+	// Share the revocation with other backend processes until the token expires
+	refreshExpiry := s.config.JWTRefreshExpiry
+	s.revocations.Revoke(context.Background(), "refresh:"+tokenHash, refreshExpiry)
+
 	return nil
 }
 
-// RevokeAllUserTokens revokes all refresh tokens for a user
+// RevokeAccessToken revokes an access token for the rest of its lifetime
+func (s *AuthService) RevokeAccessToken(claims *utils.Claims) {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return
+	}
+
+	s.revocations.Revoke(context.Background(), "access:"+claims.ID, time.Until(claims.ExpiresAt.Time))
+}
+
+// IsAccessTokenRevoked reports whether an access token has been revoked
+func (s *AuthService) IsAccessTokenRevoked(ctx context.Context, claims *utils.Claims) bool {
+	return s.revocations.IsAccessTokenRevoked(ctx, claims)
+}
+
+// RevokeAllUserTokens revokes all refresh and access tokens for a user
 func (s *AuthService) RevokeAllUserTokens(userID string) error {
 	if err := s.tokenRepo.RevokeAllForUser(userID); err != nil {
 		return fmt.Errorf("failed to revoke all tokens: %w", err)
 	}
+
+	accessExpiry := s.config.JWTAccessExpiry
+	s.revocations.RevokeAllForUser(context.Background(), userID, accessExpiry)
+
 	return nil
 }
 
@@ -233,11 +490,72 @@ func (s *AuthService) GetCurrentUser(userID string) (*models.User, error) {
 	return user, nil
 }
 
+// CaptchaSettings returns the public captcha settings clients need to render a widget
+func (s *AuthService) CaptchaSettings() (provider, siteKey string, enabled bool) {
+	if s.captcha == nil {
+		return "", "", false
+	}
+	return s.config.CaptchaProvider, s.config.CaptchaSiteKey, true
+}
+
+// verifyCaptcha checks a captcha token when a provider is configured
+func (s *AuthService) verifyCaptcha(token string, r *http.Request) error {
+	if s.captcha == nil {
+		return nil
+	}
+
+	var remoteIP string
+	if r != nil {
+		remoteIP = utils.ClientIP(r)
+	}
+
+	if err := s.captcha.Verify(context.Background(), token, remoteIP); err != nil {
+		if err.Error() == "captcha is required" {
+			return err
+		}
+		fmt.Printf("Warning: captcha verification failed: %v\n", err)
+		return fmt.Errorf("captcha verification failed")
+	}
+
+	return nil
+}
+
+// loginCaptchaRequired reports whether the account has too many recent failed logins
+func (s *AuthService) loginCaptchaRequired(email string) bool {
+	if s.captcha == nil {
+		return false
+	}
+
+	value, found, err := s.store.Get(context.Background(), loginFailureKey(email))
+	if err != nil || !found {
+		return false
+	}
+
+	failures, _ := strconv.Atoi(string(value))
+	return failures >= s.config.Settings().CaptchaLoginFailureThreshold
+}
+
+// recordLoginFailure counts a failed login attempt for an email address
+func (s *AuthService) recordLoginFailure(email string) {
+	if s.captcha == nil {
+		return
+	}
+
+	if _, err := s.store.Incr(context.Background(), loginFailureKey(email), loginFailureWindow); err != nil {
+		fmt.Printf("Warning: failed to record login failure: %v\n", err)
+	}
+}
+
+func loginFailureKey(email string) string {
+	return "login_failures:" + email
+}
+
 // generateTokenPair generates both access and refresh tokens
 func (s *AuthService) generateTokenPair(userID, username, email string, r *http.Request) (*TokenPair, error) {
-	// Generate access token
-	accessExpiry, _ := utils.ParseDuration(s.config.JWTAccessExpiry)
-	accessToken, err := utils.GenerateAccessToken(userID, username, email, s.config.JWTAccessSecret, accessExpiry)
+	// Generate access token, tied to the session (refresh token) it belongs to
+	sessionID := uuid.New().String()
+	accessExpiry := s.config.JWTAccessExpiry
+	accessToken, err := utils.GenerateAccessToken(userID, username, email, sessionID, s.config.JWTAccessSecret, accessExpiry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -252,26 +570,29 @@ func (s *AuthService) generateTokenPair(userID, username, email string, r *http.
 	tokenHash := utils.HashRefreshToken(refreshToken)
 
 	// Calculate expiry
-	refreshExpiry, _ := utils.ParseDuration(s.config.JWTRefreshExpiry)
+	refreshExpiry := s.config.JWTRefreshExpiry
 	expiresAt := time.Now().UTC().Add(refreshExpiry)
 
 	// Extract metadata from request (if available)
 	var ipAddress string
 	var userAgent string
 	if r != nil {
-		ipAddress = extractIPAddress(r)
+		ipAddress = utils.ClientIP(r)
 		userAgent = r.Header.Get("User-Agent")
 	}
 
 	// Store refresh token in database
+	location, _ := s.locate(r)
 	token := &models.RefreshToken{
-		ID:        uuid.New().String(),
+		ID:        sessionID,
 		UserID:    userID,
 		TokenHash: tokenHash,
 		ExpiresAt: expiresAt,
 		CreatedAt: time.Now().UTC(),
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
+		Country:   location.CountryCode,
+		City:      location.City,
 	}
 
 	if err := s.tokenRepo.Create(token); err != nil {
@@ -282,38 +603,6 @@ func (s *AuthService) generateTokenPair(userID, username, email string, r *http.
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresAt:    expiresAt,
+		SessionID:    sessionID,
 	}, nil
 }
-
-// extractIPAddress extracts the client IP address from the request
-func extractIPAddress(r *http.Request) string {
-	// Check X-Forwarded-For header first (proxy)
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		// Take the first IP in the chain
-		if idx := strings.Index(forwarded, ","); idx != -1 {
-			return strings.TrimSpace(forwarded[:idx])
-		}
-		return strings.TrimSpace(forwarded)
-	}
-
-	// Get from RemoteAddr
-	ipAddress := r.RemoteAddr
-
-	// Strip port from IP address
-	if colonIndex := strings.LastIndex(ipAddress, ":"); colonIndex != -1 {
-		// Check if it's IPv6 (contains multiple colons)
-		if strings.Count(ipAddress, ":") > 1 {
-			// IPv6 address - remove the port after the last ]
-			if bracketIndex := strings.LastIndex(ipAddress, "]"); bracketIndex != -1 {
-				ipAddress = ipAddress[:bracketIndex+1]
-				// Remove brackets for PostgreSQL INET type
-				ipAddress = strings.Trim(ipAddress, "[]")
-			}
-		} else {
-			// IPv4 address
-			ipAddress = ipAddress[:colonIndex]
-		}
-	}
-
-	return ipAddress
-}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+// BandwidthStatus describes a user's bandwidth usage for the current month
+type BandwidthStatus struct {
+	Month      time.Time `json:"month"`
+	UsedBytes  int64     `json:"used_bytes"`
+	QuotaBytes int64     `json:"quota_bytes"` // 0 means unlimited
+	Percent    float64   `json:"percent"`
+	Exceeded   bool      `json:"exceeded"`
+}
+
+// BandwidthService tracks monthly egress against plan quotas and enforces them
+type BandwidthService struct {
+	store        InstanceStore
+	userRepo     *repositories.UserRepository
+	dockerClient ContainerRuntime
+	notifier     UsageNotifier
+	config       *config.Config
+}
+
+// NewBandwidthService creates a new bandwidth service
+func NewBandwidthService(store InstanceStore, userRepo *repositories.UserRepository, dockerClient ContainerRuntime, notifier UsageNotifier, cfg *config.Config) *BandwidthService {
+	return &BandwidthService{
+		store:        store,
+		userRepo:     userRepo,
+		dockerClient: dockerClient,
+		notifier:     notifier,
+		config:       cfg,
+	}
+}
+
+// quotaBytes returns the monthly quota for a plan and optional override (0 means unlimited)
+func (s *BandwidthService) quotaBytes(plan string, overrideGB *int) int64 {
+	if overrideGB != nil {
+		return int64(*overrideGB) * bytesPerGB
+	}
+
+	return s.config.Settings().PlanBandwidthQuotas[plan]
+}
+
+// GetUserBandwidth returns the user's bandwidth usage for the current month
+func (s *BandwidthService) GetUserBandwidth(ctx context.Context, userID string) (*BandwidthStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	month := models.BandwidthMonth(time.Now())
+	used, err := s.userRepo.GetMonthlyBandwidth(ctx, userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBandwidthStatus(month, used, s.quotaBytes(user.Plan, user.BandwidthQuotaOverrideGB)), nil
+}
+
+// SetQuotaOverride sets or clears (nil) a user's quota override and re-applies enforcement
+func (s *BandwidthService) SetQuotaOverride(ctx context.Context, userID string, quotaGB *int) (*BandwidthStatus, error) {
+	if quotaGB != nil && *quotaGB < 0 {
+		return nil, fmt.Errorf("quota must not be negative")
+	}
+
+	if err := s.userRepo.SetBandwidthQuotaOverride(userID, quotaGB); err != nil {
+		return nil, err
+	}
+
+	status, err := s.GetUserBandwidth(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyRouting(ctx, userID, status.Exceeded)
+
+	return status, nil
+}
+
+// Run checks all users against their quotas until ctx is cancelled
+func (s *BandwidthService) Run(ctx context.Context) {
+	interval := s.config.BandwidthCheckInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.enforce(ctx)
+		}
+	}
+}
+
+// enforce sends quota notices and suspends or restores routing as needed
+func (s *BandwidthService) enforce(ctx context.Context) {
+	month := models.BandwidthMonth(time.Now())
+	usage, err := s.userRepo.FindMonthlyBandwidthUsage(ctx, month)
+	if err != nil {
+		log.Printf("Warning: bandwidth check skipped: %v", err)
+		return
+	}
+
+	for _, u := range usage {
+		status := newBandwidthStatus(month, u.UsedBytes, s.quotaBytes(u.Plan, u.QuotaOverrideGB))
+
+		if status.Exceeded {
+			s.notifyOnce(ctx, u.UserID, month, models.BandwidthNoticeExceeded, status, s.notifier.BandwidthQuotaExceeded)
+		} else if status.QuotaBytes > 0 && status.Percent >= float64(s.config.Settings().BandwidthWarningPercent) {
+			s.notifyOnce(ctx, u.UserID, month, models.BandwidthNoticeWarning, status, s.notifier.BandwidthQuotaWarning)
+		}
+
+		s.applyRouting(ctx, u.UserID, status.Exceeded)
+	}
+}
+
+// notifyOnce sends a notice unless the same level was already sent this month
+func (s *BandwidthService) notifyOnce(ctx context.Context, userID string, month time.Time, level string, status *BandwidthStatus, notify func(context.Context, string, int64, int64)) {
+	isNew, err := s.userRepo.RecordBandwidthNotice(ctx, userID, month, level)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+
+	if isNew {
+		notify(ctx, userID, status.UsedBytes, status.QuotaBytes)
+	}
+}
+
+// applyRouting suspends a user's instances when over quota (if enabled) and restores them otherwise
+func (s *BandwidthService) applyRouting(ctx context.Context, userID string, exceeded bool) {
+	suspend := exceeded && s.config.Settings().BandwidthSuspendOnExceed
+
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+
+	instances, err := s.store.FindInstancesByUserID(ctx, ownerID)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+
+	for i := range instances {
+		instance := &instances[i]
+		if instance.RoutingSuspended == suspend || instance.ContainerID == nil || *instance.ContainerID == "" {
+			continue
+		}
+
+		if suspend {
+			err = s.dockerClient.SuspendRouting(ctx, *instance.ContainerID)
+		} else {
+			err = s.dockerClient.ResumeRouting(ctx, *instance.ContainerID)
+		}
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+
+		if err := s.store.SetRoutingSuspended(ctx, instance, suspend); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+func newBandwidthStatus(month time.Time, used, quota int64) *BandwidthStatus {
+	status := &BandwidthStatus{
+		Month:      month,
+		UsedBytes:  used,
+		QuotaBytes: quota,
+	}
+
+	if quota > 0 {
+		status.Percent = float64(used) / float64(quota) * 100
+		status.Exceeded = used >= quota
+	}
+
+	return status
+}
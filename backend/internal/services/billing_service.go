@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"pocketploy/internal/billing"
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// billingCheckInterval is how often lapsed subscriptions are looked for
+	billingCheckInterval = 10 * time.Minute
+
+	// BillingSuspensionReason marks instances suspended because their owner's
+	// plan was downgraded; they are restored when the subscription is renewed
+	BillingSuspensionReason = "plan downgraded: instance exceeds the free plan limit"
+)
+
+// Plan is a plan users can be on, with its limits
+type Plan struct {
+	Name                string `json:"name"`
+	Paid                bool   `json:"paid"`
+	InstanceLimit       int    `json:"instance_limit"`
+	BandwidthQuotaBytes int64  `json:"bandwidth_quota_bytes"` // 0 means unlimited
+}
+
+// BillingService sells paid plans through Stripe Checkout and keeps plans in
+// line with the subscription lifecycle. A lapsed subscription (past due,
+// unpaid or cancelled) keeps its plan for BILLING_GRACE_PERIOD; after that
+// the account drops to the free plan and the instances beyond its limit are
+// suspended.
+type BillingService struct {
+	db        *sqlx.DB
+	stripe    *billing.Client
+	userRepo  *repositories.UserRepository
+	store     InstanceStore
+	instances *InstanceService
+	credits   *CreditService
+	config    *config.Config
+}
+
+// NewBillingService creates a new billing service
+func NewBillingService(db *sqlx.DB, stripe *billing.Client, userRepo *repositories.UserRepository, store InstanceStore, instances *InstanceService, credits *CreditService, cfg *config.Config) *BillingService {
+	return &BillingService{
+		db:        db,
+		stripe:    stripe,
+		userRepo:  userRepo,
+		store:     store,
+		instances: instances,
+		credits:   credits,
+		config:    cfg,
+	}
+}
+
+// Plans lists the free plan and the plans sold through Stripe
+func (s *BillingService) Plans() []Plan {
+	settings := s.config.Settings()
+	plans := []Plan{{
+		Name:                models.DefaultPlan,
+		InstanceLimit:       settings.InstanceLimit(models.DefaultPlan),
+		BandwidthQuotaBytes: settings.PlanBandwidthQuotas[models.DefaultPlan],
+	}}
+
+	names := make([]string, 0, len(s.config.StripePlanPrices))
+	for name := range s.config.StripePlanPrices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		plans = append(plans, Plan{
+			Name:                name,
+			Paid:                true,
+			InstanceLimit:       settings.InstanceLimit(name),
+			BandwidthQuotaBytes: settings.PlanBandwidthQuotas[name],
+		})
+	}
+	return plans
+}
+
+// GetSubscription returns a user's subscription, or nil if they never subscribed
+func (s *BillingService) GetSubscription(ctx context.Context, userID string) (*models.Subscription, error) {
+	return models.GetSubscription(ctx, s.db, userID)
+}
+
+// CreateCheckout starts a Stripe Checkout session for a paid plan and returns
+// the URL to send the user to
+func (s *BillingService) CreateCheckout(ctx context.Context, userID, plan string) (*billing.CheckoutSession, error) {
+	priceID, ok := s.config.StripePlanPrices[plan]
+	if !ok {
+		return nil, fmt.Errorf("unknown plan")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := models.GetSubscription(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := billing.CheckoutParams{
+		PriceID:    priceID,
+		Email:      user.Email,
+		SuccessURL: s.config.BillingSuccessURL,
+		CancelURL:  s.config.BillingCancelURL,
+		Metadata:   map[string]string{"user_id": userID, "plan": plan},
+	}
+	if subscription != nil {
+		if subscriptionActive(subscription.Status) {
+			return nil, fmt.Errorf("already subscribed")
+		}
+		params.CustomerID = subscription.StripeCustomerID
+	}
+
+	return s.stripe.CreateCheckoutSession(ctx, params)
+}
+
+// HandleWebhook verifies and applies a Stripe webhook event. Unhandled event
+// types are acknowledged and ignored.
+func (s *BillingService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := s.stripe.ParseEvent(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case billing.EventCheckoutCompleted:
+		var checkout billing.CompletedCheckout
+		if err := json.Unmarshal(event.Data.Object, &checkout); err != nil {
+			return fmt.Errorf("failed to decode checkout session: %w", err)
+		}
+		return s.checkoutCompleted(ctx, &checkout)
+
+	case billing.EventSubscriptionCreated, billing.EventSubscriptionUpdated, billing.EventSubscriptionDeleted:
+		var subscription billing.Subscription
+		if err := json.Unmarshal(event.Data.Object, &subscription); err != nil {
+			return fmt.Errorf("failed to decode subscription: %w", err)
+		}
+		if event.Type == billing.EventSubscriptionDeleted {
+			subscription.Status = "canceled"
+		}
+		return s.subscriptionChanged(ctx, &subscription)
+
+	case billing.EventInvoiceCreated:
+		var invoice billing.Invoice
+		if err := json.Unmarshal(event.Data.Object, &invoice); err != nil {
+			return fmt.Errorf("failed to decode invoice: %w", err)
+		}
+		return s.applyCredit(ctx, &invoice)
+	}
+
+	return nil
+}
+
+// applyCredit deducts the customer's account credit from a draft invoice
+// (renewals stay drafts for about an hour before Stripe finalizes them)
+func (s *BillingService) applyCredit(ctx context.Context, invoice *billing.Invoice) error {
+	if invoice.Status != "draft" || invoice.AmountDue <= 0 {
+		return nil
+	}
+
+	subscription, err := models.FindSubscriptionByCustomerID(ctx, s.db, invoice.Customer)
+	if err != nil || subscription == nil {
+		return err
+	}
+
+	entry, err := s.credits.Deduct(ctx, subscription.UserID, invoice.AmountDue, "stripe:"+invoice.ID, "Invoice "+invoice.ID)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	err = s.stripe.CreateInvoiceItem(ctx, billing.InvoiceItemParams{
+		Customer:       invoice.Customer,
+		Invoice:        invoice.ID,
+		AmountCents:    entry.AmountCents,
+		Currency:       invoice.Currency,
+		Description:    "Account credit",
+		IdempotencyKey: "credit-" + invoice.ID,
+	})
+	if err != nil {
+		if restoreErr := s.credits.Restore(ctx, entry); restoreErr != nil {
+			log.Printf("Warning: failed to restore credit of user %s: %v", subscription.UserID, restoreErr)
+		}
+		return err
+	}
+
+	log.Printf("Applied %d of credit to invoice %s of user %s", -entry.AmountCents, invoice.ID, subscription.UserID)
+	return nil
+}
+
+// checkoutCompleted links the Stripe customer and subscription to the user
+// and activates the plan once the first payment went through
+func (s *BillingService) checkoutCompleted(ctx context.Context, checkout *billing.CompletedCheckout) error {
+	userID := checkout.ClientReferenceID
+	plan := checkout.Metadata["plan"]
+	if userID == "" || plan == "" {
+		log.Printf("Warning: ignoring checkout session %s without user or plan", checkout.ID)
+		return nil
+	}
+
+	status := "incomplete"
+	if checkout.PaymentStatus == "paid" || checkout.PaymentStatus == "no_payment_required" {
+		status = "active"
+	}
+
+	existing, err := models.GetSubscription(ctx, s.db, userID)
+	if err != nil {
+		return err
+	}
+	subscription := &models.Subscription{UserID: userID, Status: status}
+	if existing != nil {
+		subscription = existing
+		// A subscription event may have arrived first
+		if !subscriptionActive(existing.Status) {
+			subscription.Status = status
+		}
+	}
+	subscription.StripeCustomerID = checkout.Customer
+	if checkout.Subscription != "" {
+		subscription.StripeSubscriptionID = &checkout.Subscription
+	}
+	subscription.Plan = plan
+
+	return s.apply(ctx, subscription)
+}
+
+// subscriptionChanged records the new state of a Stripe subscription
+func (s *BillingService) subscriptionChanged(ctx context.Context, event *billing.Subscription) error {
+	subscription, err := models.FindSubscriptionByStripeID(ctx, s.db, event.ID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		// The checkout event has not been processed yet; the metadata set at
+		// checkout names the user
+		userID := event.Metadata["user_id"]
+		if userID == "" {
+			log.Printf("Warning: ignoring unknown subscription %s", event.ID)
+			return nil
+		}
+		if subscription, err = models.GetSubscription(ctx, s.db, userID); err != nil {
+			return err
+		}
+		if subscription == nil {
+			subscription = &models.Subscription{UserID: userID}
+		} else if subscription.StripeSubscriptionID != nil && subscriptionActive(subscription.Status) {
+			// An older subscription ending does not affect the current one
+			log.Printf("Warning: ignoring subscription %s, user %s has another one", event.ID, userID)
+			return nil
+		}
+	}
+
+	subscription.StripeCustomerID = event.Customer
+	subscription.StripeSubscriptionID = &event.ID
+	subscription.Status = event.Status
+	if plan := s.planForPrice(event.PriceID()); plan != "" {
+		subscription.Plan = plan
+	} else if plan := event.Metadata["plan"]; plan != "" {
+		subscription.Plan = plan
+	}
+	if event.CurrentPeriodEnd > 0 {
+		periodEnd := time.Unix(event.CurrentPeriodEnd, 0).UTC()
+		subscription.CurrentPeriodEnd = &periodEnd
+	}
+
+	return s.apply(ctx, subscription)
+}
+
+// apply saves a subscription and updates the owner's plan: an active
+// subscription grants its plan (and lifts billing suspensions), a lapsed one
+// starts the grace period, and an incomplete one changes nothing yet
+func (s *BillingService) apply(ctx context.Context, subscription *models.Subscription) error {
+	user, err := s.userRepo.GetByID(subscription.UserID)
+	if err != nil {
+		return err
+	}
+	userPlan := user.Plan
+
+	switch {
+	case subscriptionActive(subscription.Status):
+		subscription.GraceUntil = nil
+		userPlan = subscription.Plan
+	case subscriptionLapsed(subscription.Status):
+		if subscription.GraceUntil == nil && user.Plan != models.DefaultPlan {
+			graceUntil := time.Now().Add(s.config.BillingGracePeriod)
+			subscription.GraceUntil = &graceUntil
+		}
+	}
+
+	if err := models.SaveSubscription(ctx, s.db, subscription, userPlan); err != nil {
+		return err
+	}
+
+	if subscriptionActive(subscription.Status) && user.Plan != userPlan {
+		log.Printf("User %s upgraded to plan %s", user.ID, userPlan)
+		s.restoreInstances(ctx, subscription.UserID)
+	}
+	return nil
+}
+
+// planForPrice maps a Stripe price back to the plan it sells
+func (s *BillingService) planForPrice(priceID string) string {
+	for plan, price := range s.config.StripePlanPrices {
+		if price == priceID {
+			return plan
+		}
+	}
+	return ""
+}
+
+// Run downgrades accounts whose grace period has ended until ctx is cancelled
+func (s *BillingService) Run(ctx context.Context) {
+	ticker := time.NewTicker(billingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.downgradeLapsed(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// downgradeLapsed moves the owners of lapsed subscriptions to the free plan
+func (s *BillingService) downgradeLapsed(ctx context.Context) {
+	subscriptions, err := models.FindSubscriptionsPastGrace(ctx, s.db, time.Now())
+	if err != nil {
+		log.Printf("Warning: billing check skipped: %v", err)
+		return
+	}
+
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		subscription.GraceUntil = nil
+		if err := models.SaveSubscription(ctx, s.db, subscription, models.DefaultPlan); err != nil {
+			log.Printf("Warning: failed to downgrade user %s: %v", subscription.UserID, err)
+			continue
+		}
+
+		log.Printf("User %s downgraded to plan %s (subscription %s)", subscription.UserID, models.DefaultPlan, subscription.Status)
+		s.suspendExcessInstances(ctx, subscription.UserID)
+	}
+}
+
+// suspendExcessInstances suspends a user's newest instances beyond the free
+// plan's instance limit
+func (s *BillingService) suspendExcessInstances(ctx context.Context, userID string) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+
+	instances, err := s.store.FindInstancesByUserID(ctx, id)
+	if err != nil {
+		log.Printf("Warning: failed to list instances of user %s: %v", userID, err)
+		return
+	}
+
+	// Instances are listed newest first; the oldest ones stay up
+	limit := s.config.Settings().InstanceLimit(models.DefaultPlan)
+	kept := 0
+	for i := len(instances) - 1; i >= 0; i-- {
+		instance := instances[i]
+		switch instance.Status {
+		case models.InstanceStatusFailed, models.InstanceStatusPendingDeletion, models.InstanceStatusSuspended:
+			continue
+		}
+
+		if kept < limit {
+			kept++
+			continue
+		}
+		if _, err := s.instances.SuspendInstance(ctx, instance.ID, BillingSuspensionReason); err != nil {
+			log.Printf("Warning: failed to suspend instance %s: %v", instance.ID, err)
+		}
+	}
+}
+
+// restoreInstances lifts the suspensions a downgrade put on a user's instances
+func (s *BillingService) restoreInstances(ctx context.Context, userID string) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+
+	instances, err := s.store.FindInstancesByUserID(ctx, id)
+	if err != nil {
+		log.Printf("Warning: failed to list instances of user %s: %v", userID, err)
+		return
+	}
+
+	for _, instance := range instances {
+		if instance.Status != models.InstanceStatusSuspended || instance.SuspensionReason == nil || *instance.SuspensionReason != BillingSuspensionReason {
+			continue
+		}
+		if _, err := s.instances.UnsuspendInstance(ctx, instance.ID); err != nil {
+			log.Printf("Warning: failed to restore instance %s: %v", instance.ID, err)
+		}
+	}
+}
+
+// subscriptionActive reports whether a Stripe subscription status grants its plan
+func subscriptionActive(status string) bool {
+	return status == "active" || status == "trialing"
+}
+
+// subscriptionLapsed reports whether a Stripe subscription status starts the grace period
+func subscriptionLapsed(status string) bool {
+	switch status {
+	case "past_due", "unpaid", "canceled", "incomplete_expired":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+)
+
+// crashLogTail is the number of log lines kept when an instance is marked failed
+const crashLogTail = "50"
+
+// ContainerStartHandler is told about containers that Docker started,
+// including restarts by a restart policy
+type ContainerStartHandler interface {
+	ContainerStarted(ctx context.Context, containerID string)
+}
+
+// CrashMonitor watches Docker events and stops instances that crash-loop. It
+// also hands container starts to an optional ContainerStartHandler.
+type CrashMonitor struct {
+	store        InstanceStore
+	dockerClient ContainerRuntime
+	notifier     InstanceNotifier
+	starts       ContainerStartHandler
+	maxRestarts  int
+	window       time.Duration
+
+	mu    sync.Mutex
+	exits map[string][]time.Time // container ID -> recent crash times
+}
+
+// NewCrashMonitor creates a monitor that marks an instance failed after
+// maxRestarts crashes within window. starts may be nil.
+func NewCrashMonitor(store InstanceStore, dockerClient ContainerRuntime, notifier InstanceNotifier, starts ContainerStartHandler, maxRestarts int, window time.Duration) *CrashMonitor {
+	return &CrashMonitor{
+		store:        store,
+		dockerClient: dockerClient,
+		notifier:     notifier,
+		starts:       starts,
+		maxRestarts:  maxRestarts,
+		window:       window,
+		exits:        make(map[string][]time.Time),
+	}
+}
+
+// Run consumes container events until ctx is cancelled, reconnecting if the stream breaks
+func (m *CrashMonitor) Run(ctx context.Context) {
+	if m.maxRestarts <= 0 && m.starts == nil {
+		return
+	}
+
+	for {
+		containerEvents, errs := m.dockerClient.WatchContainerEvents(ctx)
+		m.consume(ctx, containerEvents, errs)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (m *CrashMonitor) consume(ctx context.Context, containerEvents <-chan docker.ContainerEvent, errs <-chan error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-errs:
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Warning: docker event stream closed: %v", err)
+			}
+			return
+		case event, ok := <-containerEvents:
+			if !ok {
+				return
+			}
+			switch event.Action {
+			case events.ActionStart:
+				if m.starts != nil {
+					m.starts.ContainerStarted(ctx, event.ContainerID)
+				}
+			case events.ActionDie:
+				m.handleExit(ctx, event)
+			}
+		}
+	}
+}
+
+// handleExit records a crash and marks the instance failed once the threshold is reached
+func (m *CrashMonitor) handleExit(ctx context.Context, exit docker.ContainerEvent) {
+	if m.maxRestarts <= 0 {
+		return
+	}
+
+	// Clean shutdowns (exit 0) and docker stop (SIGTERM) are not crashes
+	if exit.ExitCode == "0" || exit.ExitCode == "143" {
+		return
+	}
+
+	if !m.recordCrash(exit.ContainerID, exit.Time) {
+		return
+	}
+
+	instance, err := m.store.FindInstanceByContainerID(ctx, exit.ContainerID)
+	if err != nil {
+		// Not a PocketBase instance (or already deleted)
+		return
+	}
+
+	if instance.Status == models.InstanceStatusFailed {
+		return
+	}
+
+	reason := fmt.Sprintf("crash loop detected: %d restarts within %s (last exit code %s)", m.maxRestarts, m.window, exit.ExitCode)
+	if err := m.markFailed(ctx, instance, reason); err != nil {
+		log.Printf("Warning: failed to mark instance %s as failed: %v", instance.ID, err)
+		return
+	}
+
+	log.Printf("Instance %s marked failed: %s", instance.ID, reason)
+	m.notifier.InstanceFailed(ctx, instance, reason)
+}
+
+// recordCrash stores a crash time and reports whether the container is crash-looping
+func (m *CrashMonitor) recordCrash(containerID string, at time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := at.Add(-m.window)
+	recent := m.exits[containerID][:0]
+	for _, t := range m.exits[containerID] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, at)
+
+	if len(recent) >= m.maxRestarts {
+		delete(m.exits, containerID)
+		return true
+	}
+
+	m.exits[containerID] = recent
+	return false
+}
+
+// markFailed stops restarting the container, captures its last logs and updates the instance
+func (m *CrashMonitor) markFailed(ctx context.Context, instance *models.Instance, reason string) error {
+	containerID := *instance.ContainerID
+
+	if err := m.dockerClient.SetRestartPolicy(ctx, containerID, container.RestartPolicyDisabled); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if err := m.dockerClient.StopContainer(ctx, containerID); err != nil {
+		log.Printf("Warning: failed to stop container %s: %v", containerID, err)
+	}
+
+	logs, err := m.dockerClient.GetContainerLogs(ctx, containerID, docker.LogOptions{Tail: crashLogTail})
+	if err != nil {
+		log.Printf("Warning: failed to capture logs for container %s: %v", containerID, err)
+	}
+
+	return m.store.MarkFailed(ctx, instance, reason, logs)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxCreditEntries is how many ledger entries are listed with a balance
+const maxCreditEntries = 100
+
+// CreditService manages account credit: administrator grants, promo codes and
+// the deductions applied to invoices. Credit is kept in the smallest unit of
+// the billing currency.
+type CreditService struct {
+	db       *sqlx.DB
+	userRepo *repositories.UserRepository
+}
+
+// NewCreditService creates a new credit service
+func NewCreditService(db *sqlx.DB, userRepo *repositories.UserRepository) *CreditService {
+	return &CreditService{db: db, userRepo: userRepo}
+}
+
+// CreditBalance is a user's balance with their recent ledger entries
+type CreditBalance struct {
+	BalanceCents int64                `json:"balance_cents"`
+	Entries      []models.CreditEntry `json:"entries"`
+}
+
+// GetBalance returns a user's credit balance and recent ledger entries
+func (s *CreditService) GetBalance(ctx context.Context, userID string) (*CreditBalance, error) {
+	balance, err := models.GetCreditBalance(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := models.FindCreditEntries(ctx, s.db, userID, maxCreditEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreditBalance{BalanceCents: balance, Entries: entries}, nil
+}
+
+// Grant adds credit to a user's account, e.g. for a trial or a refund
+func (s *CreditService) Grant(ctx context.Context, adminID, userID string, amountCents int64, reason string) (*models.CreditEntry, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+
+	entry := &models.CreditEntry{
+		UserID:          userID,
+		AmountCents:     amountCents,
+		Kind:            models.CreditKindGrant,
+		Reason:          &reason,
+		CreatedByUserID: &adminID,
+	}
+	if err := models.CreateCreditEntry(ctx, s.db, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Redeem credits a promo code to a user
+func (s *CreditService) Redeem(ctx context.Context, userID, code string) (*models.CreditEntry, error) {
+	return models.RedeemPromoCode(ctx, s.db, userID, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// Deduct applies up to maxCents of a user's credit to the charge identified
+// by reference (nil when there is no credit)
+func (s *CreditService) Deduct(ctx context.Context, userID string, maxCents int64, reference, reason string) (*models.CreditEntry, error) {
+	return models.DeductCredit(ctx, s.db, userID, maxCents, reference, reason)
+}
+
+// Restore undoes a deduction that could not be applied
+func (s *CreditService) Restore(ctx context.Context, entry *models.CreditEntry) error {
+	return models.DeleteCreditEntry(ctx, s.db, entry.ID)
+}
+
+// CreatePromoCodeParams contains parameters for creating a promo code
+type CreatePromoCodeParams struct {
+	CreatedByUserID string
+	Code            string // generated when empty
+	CreditCents     int64
+	MaxRedemptions  int
+	ExpiresIn       time.Duration
+	Note            string
+}
+
+// CreatePromoCode creates a promo code
+func (s *CreditService) CreatePromoCode(ctx context.Context, params CreatePromoCodeParams) (*models.PromoCode, error) {
+	code := strings.ToUpper(strings.TrimSpace(params.Code))
+	if code == "" {
+		generated, err := utils.GenerateRandomString(10)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate promo code: %w", err)
+		}
+		code = strings.ToUpper(generated)
+	}
+
+	maxRedemptions := params.MaxRedemptions
+	if maxRedemptions == 0 {
+		maxRedemptions = 1
+	}
+
+	promo := &models.PromoCode{
+		ID:              uuid.New().String(),
+		Code:            code,
+		CreditCents:     params.CreditCents,
+		MaxRedemptions:  maxRedemptions,
+		CreatedByUserID: &params.CreatedByUserID,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	if note := strings.TrimSpace(params.Note); note != "" {
+		promo.Note = &note
+	}
+
+	if params.ExpiresIn > 0 {
+		expiresAt := promo.CreatedAt.Add(params.ExpiresIn)
+		promo.ExpiresAt = &expiresAt
+	}
+
+	if err := models.CreatePromoCode(ctx, s.db, promo); err != nil {
+		return nil, err
+	}
+
+	return promo, nil
+}
+
+// ListPromoCodes retrieves all promo codes
+func (s *CreditService) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	return models.ListPromoCodes(ctx, s.db)
+}
+
+// RevokePromoCode revokes a promo code so it can no longer be redeemed
+func (s *CreditService) RevokePromoCode(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("promo code not found or already revoked")
+	}
+
+	return models.RevokePromoCode(ctx, s.db, id)
+}
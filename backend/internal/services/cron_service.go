@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/cron"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// cronSchedulerInterval is how often due tasks are looked up
+	cronSchedulerInterval = 30 * time.Second
+
+	// cronBatchSize bounds how many due tasks are picked up per tick
+	cronBatchSize = 100
+
+	// cronMaxParallel bounds concurrently executing tasks
+	cronMaxParallel = 4
+
+	// cronRunHistory is the number of runs kept per task
+	cronRunHistory = 50
+
+	// cronMaxOutput bounds the stored output of a run
+	cronMaxOutput = 4096
+
+	// cronHTTPTimeout bounds HTTP ping tasks
+	cronHTTPTimeout = 30 * time.Second
+)
+
+// InstanceCronParams contains the user-editable fields of a scheduled task.
+// On update, nil fields are left unchanged.
+type InstanceCronParams struct {
+	Name        *string  `json:"name"`
+	Schedule    *string  `json:"schedule"`
+	Kind        *string  `json:"kind"`
+	CommandArgs []string `json:"command_args"`
+	HTTPMethod  *string  `json:"http_method"`
+	HTTPPath    *string  `json:"http_path"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// CronService manages and runs user-defined scheduled tasks for instances
+type CronService struct {
+	store           InstanceStore
+	dockerClient    ContainerRuntime
+	instanceService *InstanceService
+	notifier        InstanceNotifier
+	httpClient      *http.Client
+	config          *config.Config
+}
+
+// NewCronService creates a new cron service
+func NewCronService(store InstanceStore, dockerClient ContainerRuntime, instanceService *InstanceService, notifier InstanceNotifier, cfg *config.Config) *CronService {
+	return &CronService{
+		store:           store,
+		dockerClient:    dockerClient,
+		instanceService: instanceService,
+		notifier:        notifier,
+		httpClient: &http.Client{
+			Timeout: cronHTTPTimeout,
+			// Pings only target the instance itself, never follow it elsewhere
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: cfg,
+	}
+}
+
+// ListCrons retrieves an instance's scheduled tasks
+func (s *CronService) ListCrons(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceCron, error) {
+	if _, err := s.instanceService.GetInstance(ctx, instanceID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.store.FindCrons(ctx, instanceID)
+}
+
+// CreateCron adds a scheduled task to an instance
+func (s *CronService) CreateCron(ctx context.Context, instanceID, userID uuid.UUID, params InstanceCronParams) (*models.InstanceCron, error) {
+	// Scheduled tasks run maintenance commands, so they need the same permission
+	if _, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage); err != nil {
+		return nil, err
+	}
+
+	count, err := s.store.CountCrons(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.config.Settings().MaxCronsPerInstance {
+		return nil, fmt.Errorf("scheduled task limit reached")
+	}
+
+	c := &models.InstanceCron{
+		InstanceID: instanceID,
+		Kind:       models.InstanceCronKindCommand,
+		Enabled:    true,
+	}
+	if err := s.apply(c, params); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.CreateCron(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// UpdateCron changes a scheduled task
+func (s *CronService) UpdateCron(ctx context.Context, instanceID, cronID, userID uuid.UUID, params InstanceCronParams) (*models.InstanceCron, error) {
+	c, err := s.getCron(ctx, instanceID, cronID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.apply(c, params); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpdateCron(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// DeleteCron removes a scheduled task
+func (s *CronService) DeleteCron(ctx context.Context, instanceID, cronID, userID uuid.UUID) error {
+	c, err := s.getCron(ctx, instanceID, cronID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return err
+	}
+
+	return s.store.DeleteCron(ctx, c)
+}
+
+// ListCronRuns retrieves the run history of a scheduled task
+func (s *CronService) ListCronRuns(ctx context.Context, instanceID, cronID, userID uuid.UUID) ([]models.InstanceCronRun, error) {
+	c, err := s.getCron(ctx, instanceID, cronID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.FindCronRuns(ctx, c.ID)
+}
+
+func (s *CronService) getCron(ctx context.Context, instanceID, cronID, userID uuid.UUID, action authz.Action) (*models.InstanceCron, error) {
+	if _, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, action); err != nil {
+		return nil, err
+	}
+
+	return s.store.FindCron(ctx, cronID, instanceID)
+}
+
+// apply validates params, copies them onto c and recomputes the next run time
+func (s *CronService) apply(c *models.InstanceCron, params InstanceCronParams) error {
+	if params.Name != nil {
+		c.Name = strings.TrimSpace(*params.Name)
+	}
+	if length := utf8.RuneCountInString(c.Name); length < 1 || length > 100 {
+		return fmt.Errorf("task name must be between 1 and 100 characters")
+	}
+
+	if params.Schedule != nil {
+		c.Schedule = strings.TrimSpace(*params.Schedule)
+	}
+	schedule, err := cron.Parse(c.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule")
+	}
+
+	if params.Kind != nil {
+		c.Kind = *params.Kind
+	}
+	if params.CommandArgs != nil {
+		c.CommandArgs = params.CommandArgs
+	}
+	if params.HTTPMethod != nil {
+		method := strings.ToUpper(*params.HTTPMethod)
+		c.HTTPMethod = &method
+	}
+	if params.HTTPPath != nil {
+		c.HTTPPath = params.HTTPPath
+	}
+	if params.Enabled != nil {
+		c.Enabled = *params.Enabled
+	}
+
+	switch c.Kind {
+	case models.InstanceCronKindCommand:
+		if err := docker.ValidatePocketBaseCommand(c.CommandArgs); err != nil {
+			return err
+		}
+		c.HTTPMethod, c.HTTPPath = nil, nil
+	case models.InstanceCronKindHTTP:
+		if c.HTTPMethod == nil {
+			method := http.MethodGet
+			c.HTTPMethod = &method
+		}
+		switch *c.HTTPMethod {
+		case http.MethodGet, http.MethodHead, http.MethodPost:
+		default:
+			return fmt.Errorf("http method must be GET, HEAD or POST")
+		}
+		if c.HTTPPath == nil || !validCronPath(*c.HTTPPath) {
+			return fmt.Errorf("http path must start with /")
+		}
+		c.CommandArgs = nil
+	default:
+		return fmt.Errorf("task kind must be command or http")
+	}
+
+	c.NextRunAt = nil
+	if c.Enabled {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("invalid schedule")
+		}
+		c.NextRunAt = &next
+	}
+
+	return nil
+}
+
+// validCronPath accepts only a path (and query) on the instance's own host
+func validCronPath(path string) bool {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return false
+	}
+	u, err := url.Parse(path)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// Run executes due scheduled tasks until ctx is cancelled
+func (s *CronService) Run(ctx context.Context) {
+	if s.config.Settings().MaxCronsPerInstance <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cronSchedulerInterval)
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, cronMaxParallel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue(ctx, slots)
+		}
+	}
+}
+
+// runDue claims and starts every due task. Missed runs are not replayed: the
+// next run is computed from now.
+func (s *CronService) runDue(ctx context.Context, slots chan struct{}) {
+	due, err := s.store.FindDueCrons(ctx, time.Now().UTC(), cronBatchSize)
+	if err != nil {
+		log.Printf("Warning: scheduled tasks skipped: %v", err)
+		return
+	}
+
+	for i := range due {
+		c := due[i]
+
+		schedule, err := cron.Parse(c.Schedule)
+		if err != nil {
+			log.Printf("Warning: scheduled task %s has an invalid schedule: %v", c.ID, err)
+			continue
+		}
+
+		var next *time.Time
+		if t := schedule.Next(time.Now()); !t.IsZero() {
+			next = &t
+		}
+
+		claimed, err := s.store.ClaimCron(ctx, &c, next)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+
+		go func() {
+			defer func() { <-slots }()
+			s.execute(ctx, &c)
+		}()
+	}
+}
+
+// execute runs a task once and records the result
+func (s *CronService) execute(ctx context.Context, c *models.InstanceCron) {
+	run := &models.InstanceCronRun{
+		CronID:    c.ID,
+		StartedAt: time.Now().UTC(),
+	}
+
+	instance, err := s.store.FindInstanceByID(ctx, c.InstanceID)
+	if err != nil {
+		log.Printf("Warning: scheduled task %s skipped: %v", c.ID, err)
+		return
+	}
+
+	var output string
+	if instance.Status != models.InstanceStatusRunning {
+		err = fmt.Errorf("instance is not running")
+	} else if c.Kind == models.InstanceCronKindHTTP {
+		output, err = s.ping(ctx, instance, c)
+	} else if instance.ContainerID == nil || *instance.ContainerID == "" {
+		err = fmt.Errorf("instance has no container")
+	} else {
+		output, err = s.dockerClient.RunPocketBaseCommand(ctx, *instance.ContainerID, c.CommandArgs)
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	run.Success = err == nil
+	if output != "" {
+		output = outputTail(output, cronMaxOutput)
+		run.Output = &output
+	}
+	if err != nil {
+		message := err.Error()
+		run.Error = &message
+	}
+
+	if err := s.store.RecordCronRun(ctx, run, cronRunHistory); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Only notify when a task starts failing, not on every failed run
+	if err != nil && (c.LastRunSuccess == nil || *c.LastRunSuccess) {
+		s.notifier.CronFailed(ctx, instance, c, err.Error())
+	}
+}
+
+// ping requests the task's path on the instance's own URL
+func (s *CronService) ping(ctx context.Context, instance *models.Instance, c *models.InstanceCron) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, *c.HTTPMethod, s.instanceService.InstanceURL(instance)+*c.HTTPPath, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, cronMaxOutput))
+
+	output := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		return output, fmt.Errorf("request returned status %d", resp.StatusCode)
+	}
+
+	return output, nil
+}
+
+// outputTail returns the end of a command's output, at most max bytes. It
+// starts on a character boundary and replaces invalid UTF-8, which PostgreSQL
+// would reject.
+func outputTail(output string, max int) string {
+	if len(output) > max {
+		start := len(output) - max
+		for start < len(output) && !utf8.RuneStart(output[start]) {
+			start++
+		}
+		output = output[start:]
+	}
+	return strings.ToValidUTF8(output, "\uFFFD")
+}
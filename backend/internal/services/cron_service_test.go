@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestOutputTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		max    int
+		want   string
+	}{
+		{"short", "done", 10, "done"},
+		{"ascii", "abcdef", 3, "def"},
+		{"cut inside a character", "aé€", 4, "€"},
+		{"invalid bytes", "ok\xff", 10, "ok�"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := outputTail(tt.output, tt.max)
+			if got != tt.want {
+				t.Errorf("outputTail(%q, %d) = %q, want %q", tt.output, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("outputTail(%q, %d) is not valid UTF-8", tt.output, tt.max)
+			}
+		})
+	}
+
+	long := strings.Repeat("é", 1000)
+	if got := outputTail(long, 1001); len(got) > 1001 || !utf8.ValidString(got) {
+		t.Errorf("outputTail() of a long output = %d bytes, valid %v", len(got), utf8.ValidString(got))
+	}
+}
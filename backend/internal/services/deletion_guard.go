@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// deleteConfirmationTTL is how long a deletion confirmation token stays valid
+const deleteConfirmationTTL = 5 * time.Minute
+
+// DeletionConfirmation is issued before an instance is deleted; the delete
+// request hands the token back along with the instance name typed by the user
+type DeletionConfirmation struct {
+	Token     string    `json:"token"`
+	Name      string    `json:"name"` // what has to be typed to confirm
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DeletionCheck is what the user supplied to confirm a deletion
+type DeletionCheck struct {
+	ConfirmToken string
+	ConfirmName  string
+	Password     string
+}
+
+// deletionConfirmationEntry is stored in the cache under the token
+type deletionConfirmationEntry struct {
+	InstanceID uuid.UUID `json:"instance_id"`
+	UserID     uuid.UUID `json:"user_id"`
+}
+
+// DeletionGuard checks confirmations and re-authentication for destructive
+// actions. Tokens live in the shared cache store, so they work across replicas.
+type DeletionGuard struct {
+	store  cache.Store
+	users  *UserService
+	config *config.Config
+}
+
+// NewDeletionGuard creates a new deletion guard
+func NewDeletionGuard(store cache.Store, users *UserService, cfg *config.Config) *DeletionGuard {
+	return &DeletionGuard{
+		store:  store,
+		users:  users,
+		config: cfg,
+	}
+}
+
+// IssueConfirmation creates a single-use token for deleting instance
+func (g *DeletionGuard) IssueConfirmation(ctx context.Context, instance *models.Instance, userID uuid.UUID) (*DeletionConfirmation, error) {
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(deletionConfirmationEntry{InstanceID: instance.ID, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode confirmation: %w", err)
+	}
+	if err := g.store.Set(ctx, deleteConfirmationKey(token), value, deleteConfirmationTTL); err != nil {
+		return nil, fmt.Errorf("failed to store confirmation: %w", err)
+	}
+
+	return &DeletionConfirmation{
+		Token:     token,
+		Name:      instance.Name,
+		ExpiresAt: time.Now().UTC().Add(deleteConfirmationTTL),
+	}, nil
+}
+
+// CheckInstanceDeletion verifies the confirmation (required when
+// DELETE_CONFIRMATION_REQUIRED is set, checked whenever one is supplied) and
+// the password (when REAUTH_REQUIRED_FOR_DELETION is set). The token is only
+// consumed once everything else matched.
+func (g *DeletionGuard) CheckInstanceDeletion(ctx context.Context, instance *models.Instance, userID uuid.UUID, check DeletionCheck) error {
+	confirm := g.config.DeleteConfirmationRequired || check.ConfirmToken != ""
+	if confirm && check.ConfirmToken == "" {
+		return fmt.Errorf("deletion confirmation is required")
+	}
+
+	if g.config.DeleteConfirmationRequired || check.ConfirmName != "" {
+		if check.ConfirmName != instance.Name {
+			return fmt.Errorf("confirmation name does not match the instance name")
+		}
+	}
+
+	if err := g.checkPassword(userID.String(), check.Password); err != nil {
+		return err
+	}
+
+	if confirm {
+		return g.consumeConfirmation(ctx, instance, userID, check.ConfirmToken)
+	}
+	return nil
+}
+
+// consumeConfirmation deletes the token if it was issued for instance and user
+func (g *DeletionGuard) consumeConfirmation(ctx context.Context, instance *models.Instance, userID uuid.UUID, token string) error {
+	key := deleteConfirmationKey(token)
+	value, found, err := g.store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to look up confirmation: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("deletion confirmation is invalid or expired")
+	}
+
+	var entry deletionConfirmationEntry
+	if err := json.Unmarshal(value, &entry); err != nil || entry.InstanceID != instance.ID || entry.UserID != userID {
+		return fmt.Errorf("deletion confirmation is invalid or expired")
+	}
+
+	if err := g.store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to consume confirmation: %w", err)
+	}
+	return nil
+}
+
+// checkPassword re-authenticates the user when the configuration requires it
+func (g *DeletionGuard) checkPassword(userID, password string) error {
+	if !g.config.ReauthRequiredForDeletion {
+		return nil
+	}
+	return g.users.VerifyPassword(userID, password)
+}
+
+func deleteConfirmationKey(token string) string {
+	return "delete_confirmation:" + utils.HashRefreshToken(token)
+}
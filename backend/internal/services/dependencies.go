@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"pocketploy/internal/docker"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/models"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/google/uuid"
+)
+
+// ContainerRuntime manages instance containers. *docker.Client implements it;
+// tests and alternative runtimes can substitute their own.
+type ContainerRuntime interface {
+	CreatePocketBaseContainer(ctx context.Context, cfg docker.ContainerConfig) (string, error)
+	UpsertSuperuser(ctx context.Context, containerID, email, password string) error
+	RunPocketBaseCommand(ctx context.Context, containerID string, args []string) (string, error)
+	UpdateServeFlags(ctx context.Context, containerID string, flags docker.ServeFlags) (string, error)
+	MigrateUp(ctx context.Context, containerID string, flags docker.ServeFlags) (string, error)
+	CheckDatabaseIntegrity(ctx context.Context, storagePath string) (string, error)
+	RunSQLite(ctx context.Context, dataDirs map[string]string, args []string) (string, int64, error)
+	ChownStoragePath(storagePath string)
+
+	StartContainer(ctx context.Context, containerID string) error
+	StopContainer(ctx context.Context, containerID string) error
+	RestartContainer(ctx context.Context, containerID string) error
+	RemoveContainer(ctx context.Context, containerID string) error
+	SetRestartPolicy(ctx context.Context, containerID string, policy container.RestartPolicyMode) error
+	SetCPULimit(ctx context.Context, containerID string, cpus float64) error
+	ApplyEgressRules(ctx context.Context, containerID string, rules docker.EgressRules) error
+	ContainerStartedAt(ctx context.Context, containerID string) (time.Time, error)
+	WatchContainerEvents(ctx context.Context) (<-chan docker.ContainerEvent, <-chan error)
+
+	GetContainerLogs(ctx context.Context, containerID string, opts docker.LogOptions) (string, error)
+	ReadContainerLogs(ctx context.Context, containerID string, opts docker.LogOptions) ([]docker.LogEntry, error)
+	StreamContainerLogs(ctx context.Context, containerID string, opts docker.LogOptions) (io.ReadCloser, error)
+	GetContainerStats(ctx context.Context, containerID string) (*docker.ContainerStats, error)
+	GetResourceUsage(ctx context.Context, containerID string) (*docker.ResourceUsage, error)
+
+	SuspendRouting(ctx context.Context, containerID string) error
+	ResumeRouting(ctx context.Context, containerID string) error
+	SetProxyEnabled(ctx context.Context, containerID string, enabled bool) (string, error)
+	UpdateAccessRules(ctx context.Context, containerID string, rules docker.AccessRules) (string, error)
+	RelocateStorage(ctx context.Context, containerID, storagePath string) (string, error)
+	UpdateImage(ctx context.Context, containerID, ref string) (string, error)
+	ContainerImage(ctx context.Context, containerID string) (string, error)
+
+	PullImage(ctx context.Context, ref string) error
+	InspectImage(ctx context.Context, ref string) (*docker.ImageInfo, error)
+	EnsureWarmContainer(ctx context.Context, ref string) error
+	BuildPocketBase(ctx context.Context, sourceDir, tag string) (string, error)
+	RemoveImage(ctx context.Context, ref string) error
+	Ping(ctx context.Context) error
+}
+
+var _ ContainerRuntime = (*docker.Client)(nil)
+
+// InstanceStore persists instances, their archive and the data recorded about
+// them (scheduled tasks, metrics, traffic and anomalies).
+// repositories.InstanceRepository implements it on PostgreSQL.
+type InstanceStore interface {
+	CreateInstance(ctx context.Context, instance *models.Instance, params models.CreateInstanceParams) error
+	FindInstanceByID(ctx context.Context, id uuid.UUID) (*models.Instance, error)
+	FindInstanceBySubdomain(ctx context.Context, subdomain string) (*models.Instance, error)
+	FindInstancesByUserID(ctx context.Context, userID uuid.UUID) ([]models.Instance, error)
+	FindInstancesDueForDeletion(ctx context.Context) ([]models.Instance, error)
+	FindExpiredInstances(ctx context.Context) ([]models.Instance, error)
+	FindPreview(ctx context.Context, sourceID uuid.UUID, ref string) (*models.Instance, error)
+	FindPreviews(ctx context.Context, sourceID uuid.UUID) ([]models.Instance, error)
+	SearchInstances(ctx context.Context, userID *uuid.UUID, term string, limit int) ([]models.Instance, error)
+	CountUserInstances(ctx context.Context, userID uuid.UUID) (int, error)
+	SubdomainInUse(ctx context.Context, subdomain string) (bool, error)
+	FindSubdomainReservation(ctx context.Context, subdomain string) (*models.SubdomainReservation, error)
+
+	UpdateStatus(ctx context.Context, instance *models.Instance, status string) error
+	UpdateContainerInfo(ctx context.Context, instance *models.Instance, containerID, containerName string) error
+	UpdateLastAccessed(ctx context.Context, instance *models.Instance) error
+	UpdateServeOptions(ctx context.Context, instance *models.Instance, options models.ServeOptions) error
+	UpdateDescription(ctx context.Context, instance *models.Instance, description string) error
+	UpdateDataPath(ctx context.Context, instance *models.Instance, dataPath string) error
+	SetProtected(ctx context.Context, instance *models.Instance, protected bool) error
+	SetStatusToken(ctx context.Context, instance *models.Instance, hash, prefix *string) error
+	RevokeStatusToken(ctx context.Context, instance *models.Instance) error
+	FindInstanceByStatusToken(ctx context.Context, hash string) (*models.Instance, error)
+	SetTags(ctx context.Context, instance *models.Instance, tags models.Tags) error
+	SetPinned(ctx context.Context, instance *models.Instance, pinned bool) error
+	ReorderInstances(ctx context.Context, userID uuid.UUID, instanceIDs []uuid.UUID) error
+	RecordMigrationRun(ctx context.Context, run *models.InstanceMigrationRun, keep int) error
+	FindMigrationRuns(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceMigrationRun, error)
+	CreateBuild(ctx context.Context, build *models.InstanceBuild) error
+	FindBuilds(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceBuild, error)
+	FindBuild(ctx context.Context, id uuid.UUID) (*models.InstanceBuild, error)
+	BuildInProgress(ctx context.Context, instanceID uuid.UUID) (bool, error)
+	UpdateBuild(ctx context.Context, build *models.InstanceBuild) error
+	RecordIntegrityCheck(ctx context.Context, check *models.InstanceIntegrityCheck, keep int) error
+	FindIntegrityChecks(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceIntegrityCheck, error)
+	FindInstancesDueForIntegrityCheck(ctx context.Context, since time.Time, limit int) ([]models.Instance, error)
+	EnsureEncryptionKey(ctx context.Context, id uuid.UUID, key string) (string, error)
+	UpdateAccessProtection(ctx context.Context, instance *models.Instance, protection models.AccessProtection, passwordHash string) error
+	FindAccessPasswordHash(ctx context.Context, id uuid.UUID) (string, error)
+	UpdateEgressPolicy(ctx context.Context, instance *models.Instance, policy models.EgressPolicy) error
+	ScheduleDeletion(ctx context.Context, instance *models.Instance, at time.Time, retentionDays int) error
+	CancelDeletion(ctx context.Context, instance *models.Instance) error
+	Suspend(ctx context.Context, instance *models.Instance, reason string) error
+	Unsuspend(ctx context.Context, instance *models.Instance) error
+	ExtendExpiry(ctx context.Context, instance *models.Instance, expiresAt time.Time) error
+	Quarantine(ctx context.Context, instance *models.Instance, bundle, reason string) error
+	ClearQuarantine(ctx context.Context, instance *models.Instance) error
+
+	ArchiveInstance(ctx context.Context, params models.ArchiveInstanceParams) (*models.ArchivedInstance, error)
+	FindArchivedInstancesByUserID(ctx context.Context, userID uuid.UUID) ([]models.ArchivedInstance, error)
+	FindArchivedInstanceByID(ctx context.Context, id, userID uuid.UUID) (*models.ArchivedInstance, error)
+	FindArchivedInstance(ctx context.Context, id uuid.UUID) (*models.ArchivedInstance, error)
+	UpdateArchivedDataAvailability(ctx context.Context, id uuid.UUID, available bool) error
+	FindExpiredArchivedInstances(ctx context.Context) ([]models.ArchivedInstance, error)
+
+	FindInstancesByStatus(ctx context.Context, status string) ([]models.Instance, error)
+	FindInstanceByContainerID(ctx context.Context, containerID string) (*models.Instance, error)
+	MarkFailed(ctx context.Context, instance *models.Instance, reason, logs string) error
+	SetRoutingSuspended(ctx context.Context, instance *models.Instance, suspended bool) error
+
+	FindCrons(ctx context.Context, instanceID uuid.UUID) ([]models.InstanceCron, error)
+	FindCron(ctx context.Context, id, instanceID uuid.UUID) (*models.InstanceCron, error)
+	CountCrons(ctx context.Context, instanceID uuid.UUID) (int, error)
+	CreateCron(ctx context.Context, cron *models.InstanceCron) error
+	UpdateCron(ctx context.Context, cron *models.InstanceCron) error
+	DeleteCron(ctx context.Context, cron *models.InstanceCron) error
+	FindDueCrons(ctx context.Context, now time.Time, limit int) ([]models.InstanceCron, error)
+	ClaimCron(ctx context.Context, cron *models.InstanceCron, next *time.Time) (bool, error)
+	RecordCronRun(ctx context.Context, run *models.InstanceCronRun, keep int) error
+	FindCronRuns(ctx context.Context, cronID uuid.UUID) ([]models.InstanceCronRun, error)
+
+	FindInstanceMetrics(ctx context.Context, instanceID uuid.UUID, since time.Time) ([]models.InstanceMetric, error)
+	CreateInstanceMetric(ctx context.Context, metric *models.InstanceMetric) error
+	DeleteInstanceMetricsBefore(ctx context.Context, before time.Time) (int64, error)
+	FindInstanceTraffic(ctx context.Context, instanceID uuid.UUID, since time.Time) ([]models.InstanceTrafficDay, error)
+	GetAccessLogOffset(ctx context.Context, path string) (int64, error)
+	RecordInstanceTraffic(ctx context.Context, path string, offset int64, days []models.InstanceTrafficDay) error
+
+	CreateAnomaly(ctx context.Context, anomaly *models.InstanceAnomaly) (bool, error)
+	ListAnomalies(ctx context.Context, status string, limit int) ([]models.InstanceAnomaly, error)
+	FindAnomaly(ctx context.Context, id string) (*models.InstanceAnomaly, error)
+	ResolveAnomaly(ctx context.Context, anomaly *models.InstanceAnomaly, status, reviewerID, note string) error
+	HasThrottledAnomaly(ctx context.Context, instanceID uuid.UUID) (bool, error)
+}
+
+// PlanResolver looks up the plan a user is on, which sets their limits
+// (implemented by *UserService)
+type PlanResolver interface {
+	UserPlan(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// ImageResolver returns the approved image a user chose for a new instance
+// (implemented by *ImageService)
+type ImageResolver interface {
+	ResolveImage(ctx context.Context, id string, userID uuid.UUID) (*models.InstanceImage, error)
+	FindImage(ctx context.Context, id string) (*models.InstanceImage, error)
+}
+
+// RegionResolver picks the region a new instance is placed in
+// (implemented by *RegionService)
+type RegionResolver interface {
+	ResolveRegion(ctx context.Context, id string) (*models.Region, error)
+}
+
+// JobQueue enqueues background jobs (implemented by *jobs.Queue)
+type JobQueue interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}, opts jobs.EnqueueOptions) (*jobs.Job, error)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DeployTokenPrefix marks deploy tokens so they are recognizable in CI
+	// secrets and secret scanners
+	DeployTokenPrefix = "ppd_"
+
+	// maxDeployTokensPerInstance bounds the deploy tokens of one instance
+	maxDeployTokensPerInstance = 20
+
+	// previewTokenName names the deploy tokens issued for preview environments
+	previewTokenName = "preview pipeline"
+)
+
+// ErrDeployTokenLimit is returned when an instance already has the most deploy tokens allowed
+var ErrDeployTokenLimit = fmt.Errorf("deploy token limit reached (%d per instance)", maxDeployTokensPerInstance)
+
+// DeployService manages deploy tokens and runs the deploy operations CI
+// pipelines perform with them
+type DeployService struct {
+	db              *sqlx.DB
+	instanceService *InstanceService
+}
+
+// NewDeployService creates a new deploy service
+func NewDeployService(db *sqlx.DB, instanceService *InstanceService) *DeployService {
+	return &DeployService{db: db, instanceService: instanceService}
+}
+
+// CreateToken creates a deploy token for an instance. The token value is
+// returned once and only its hash is stored.
+func (s *DeployService) CreateToken(ctx context.Context, instanceID, userID uuid.UUID, req models.CreateDeployTokenRequest) (*models.DeployToken, string, error) {
+	if _, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage); err != nil {
+		return nil, "", err
+	}
+
+	token := &models.DeployToken{
+		UserID:     userID,
+		InstanceID: instanceID,
+		Name:       strings.TrimSpace(req.Name),
+		Scopes:     uniqueScopes(req.Scopes),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		token.ExpiresAt = &expiresAt
+	}
+
+	value, err := s.issueToken(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return token, value, nil
+}
+
+// issueToken generates the value of a new deploy token and stores its hash
+func (s *DeployService) issueToken(ctx context.Context, token *models.DeployToken) (string, error) {
+	count, err := models.CountDeployTokens(ctx, s.db, token.InstanceID)
+	if err != nil {
+		return "", err
+	}
+	if count >= maxDeployTokensPerInstance {
+		return "", ErrDeployTokenLimit
+	}
+
+	secret, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	value := DeployTokenPrefix + secret
+
+	token.TokenHash = utils.HashRefreshToken(value)
+	token.TokenPrefix = value[:len(DeployTokenPrefix)+8]
+
+	if err := models.CreateDeployToken(ctx, s.db, token); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// ListTokens returns an instance's deploy tokens
+func (s *DeployService) ListTokens(ctx context.Context, instanceID, userID uuid.UUID) ([]models.DeployToken, error) {
+	if _, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage); err != nil {
+		return nil, err
+	}
+	return models.ListDeployTokens(ctx, s.db, instanceID)
+}
+
+// RevokeToken deletes one of an instance's deploy tokens
+func (s *DeployService) RevokeToken(ctx context.Context, instanceID, userID uuid.UUID, tokenID string) error {
+	if _, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage); err != nil {
+		return err
+	}
+	return models.DeleteDeployToken(ctx, s.db, instanceID, tokenID)
+}
+
+// VerifyDeployToken resolves a deploy token value, rejecting unknown and
+// expired tokens
+func (s *DeployService) VerifyDeployToken(ctx context.Context, value string) (*models.DeployToken, error) {
+	if !strings.HasPrefix(value, DeployTokenPrefix) {
+		return nil, models.ErrDeployTokenNotFound
+	}
+
+	token, err := models.FindDeployTokenByHash(ctx, s.db, utils.HashRefreshToken(value))
+	if err != nil {
+		return nil, err
+	}
+	if token.Expired() {
+		return nil, models.ErrDeployTokenNotFound
+	}
+
+	if err := models.TouchDeployToken(ctx, s.db, token.ID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	return token, nil
+}
+
+// DeployHooks replaces the instance's pb_hooks bundle
+func (s *DeployService) DeployHooks(ctx context.Context, token *models.DeployToken, bundle []byte) (*Bundle, error) {
+	if !token.HasScope(models.DeployScopeHooks) {
+		return nil, errDeployScope(models.DeployScopeHooks)
+	}
+	return s.instanceService.UploadHooks(ctx, token.InstanceID, token.UserID, bundle)
+}
+
+// DeployPublicFiles replaces the instance's pb_public bundle
+func (s *DeployService) DeployPublicFiles(ctx context.Context, token *models.DeployToken, bundle []byte) (*Bundle, error) {
+	if !token.HasScope(models.DeployScopePublic) {
+		return nil, errDeployScope(models.DeployScopePublic)
+	}
+	return s.instanceService.UploadPublicFiles(ctx, token.InstanceID, token.UserID, bundle)
+}
+
+// DeployMigrations replaces the instance's pb_migrations bundle
+func (s *DeployService) DeployMigrations(ctx context.Context, token *models.DeployToken, bundle []byte) (*Bundle, error) {
+	if !token.HasScope(models.DeployScopeMigrate) {
+		return nil, errDeployScope(models.DeployScopeMigrate)
+	}
+	return s.instanceService.UploadMigrations(ctx, token.InstanceID, token.UserID, bundle)
+}
+
+// Migrate applies the instance's pending migrations (pocketbase migrate up)
+func (s *DeployService) Migrate(ctx context.Context, token *models.DeployToken) (*models.InstanceMigrationRun, error) {
+	if !token.HasScope(models.DeployScopeMigrate) {
+		return nil, errDeployScope(models.DeployScopeMigrate)
+	}
+	return s.instanceService.RunMigrations(ctx, token.InstanceID, token.UserID)
+}
+
+// Restart restarts the instance
+func (s *DeployService) Restart(ctx context.Context, token *models.DeployToken) error {
+	if !token.HasScope(models.DeployScopeRestart) {
+		return errDeployScope(models.DeployScopeRestart)
+	}
+	return s.instanceService.RestartInstance(ctx, token.InstanceID, token.UserID)
+}
+
+// PreviewDeployment is a preview environment requested with a deploy token,
+// with a token the pipeline deploys to the preview with
+type PreviewDeployment struct {
+	*PreviewResult
+	Token      *models.DeployToken // nil when the requesting token has no other scopes
+	TokenValue string
+}
+
+// DeployPreview creates the preview environment of the token's instance for a
+// ref, or returns the existing one. Every call issues a new deploy token for
+// the preview (replacing the previous one, whose value can't be shown again)
+// with the requesting token's other scopes, expiring with the preview.
+func (s *DeployService) DeployPreview(ctx context.Context, token *models.DeployToken, ref string, ttl time.Duration) (*PreviewDeployment, error) {
+	if !token.HasScope(models.DeployScopePreview) {
+		return nil, errDeployScope(models.DeployScopePreview)
+	}
+
+	result, err := s.instanceService.CreatePreview(ctx, token.InstanceID, token.UserID, CreatePreviewRequest{Ref: ref, TTL: ttl})
+	if err != nil {
+		return nil, err
+	}
+	deployment := &PreviewDeployment{PreviewResult: result}
+
+	var scopes []string
+	for _, scope := range token.Scopes {
+		if scope != models.DeployScopePreview {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return deployment, nil
+	}
+
+	if err := models.DeleteDeployTokensByName(ctx, s.db, result.Instance.ID, previewTokenName); err != nil {
+		return nil, err
+	}
+
+	deployment.Token = &models.DeployToken{
+		UserID:     token.UserID,
+		InstanceID: result.Instance.ID,
+		Name:       previewTokenName,
+		Scopes:     scopes,
+		ExpiresAt:  result.Instance.ExpiresAt,
+	}
+	deployment.TokenValue, err = s.issueToken(ctx, deployment.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// ClosePreview tears down the preview environment of the token's instance for a ref
+func (s *DeployService) ClosePreview(ctx context.Context, token *models.DeployToken, ref string) (*models.ArchivedInstance, error) {
+	if !token.HasScope(models.DeployScopePreview) {
+		return nil, errDeployScope(models.DeployScopePreview)
+	}
+	return s.instanceService.DeletePreview(ctx, token.InstanceID, token.UserID, ref)
+}
+
+// errDeployScope reports a deploy operation the token doesn't allow
+func errDeployScope(scope string) error {
+	return fmt.Errorf("%w: deploy token lacks the %s scope", authz.ErrForbidden, scope)
+}
+
+// uniqueScopes drops duplicate scopes, keeping their order
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
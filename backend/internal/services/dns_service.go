@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/dns"
+	"pocketploy/internal/models"
+)
+
+// DNSService keeps the wildcard records of the base domain and the region
+// domains at the DNS provider and reports whether they have propagated
+type DNSService struct {
+	provider dns.Provider // nil when DNS_PROVIDER is not set
+	regions  *RegionService
+	config   *config.Config
+}
+
+// NewDNSService creates a new DNS service
+func NewDNSService(provider dns.Provider, regions *RegionService, cfg *config.Config) *DNSService {
+	return &DNSService{provider: provider, regions: regions, config: cfg}
+}
+
+// DomainDNS is the state of a domain's wildcard record
+type DomainDNS struct {
+	Domain string `json:"domain"`
+
+	// Managed is set when a DNS provider is configured; Record is the
+	// wildcard record it holds (nil if missing)
+	Managed     bool        `json:"managed"`
+	Record      *dns.Record `json:"record,omitempty"`
+	RecordError string      `json:"record_error,omitempty"`
+
+	Propagation dns.Propagation `json:"propagation"`
+}
+
+// DomainStatuses reports the wildcard record of every domain instances are
+// created under
+func (s *DNSService) DomainStatuses(ctx context.Context) ([]DomainDNS, error) {
+	domains, err := s.domains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]DomainDNS, 0, len(domains))
+	for _, domain := range domains {
+		status := DomainDNS{Domain: domain, Managed: s.provider != nil}
+		if s.provider != nil {
+			record, err := s.provider.FindRecord(ctx, "*."+domain)
+			if err != nil {
+				status.RecordError = err.Error()
+			}
+			status.Record = record
+		}
+		status.Propagation = dns.CheckWildcard(ctx, domain, s.config.DNSTarget)
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// EnsureWildcards creates the wildcard records missing at the provider and
+// returns the ones it created
+func (s *DNSService) EnsureWildcards(ctx context.Context) ([]dns.Record, error) {
+	if s.provider == nil {
+		return nil, fmt.Errorf("no DNS provider is configured")
+	}
+
+	domains, err := s.domains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	created := []dns.Record{}
+	for _, domain := range domains {
+		wildcard := dns.WildcardRecord(domain, s.config.DNSTarget)
+
+		existing, err := s.provider.FindRecord(ctx, wildcard.Name)
+		if err != nil {
+			return created, err
+		}
+		// Records pointing elsewhere were set up by hand and are left alone
+		if existing != nil {
+			if existing.Content != wildcard.Content {
+				log.Printf("Warning: %s points at %s, not DNS_TARGET %s", wildcard.Name, existing.Content, wildcard.Content)
+			}
+			continue
+		}
+
+		if err := s.provider.CreateRecord(ctx, wildcard); err != nil {
+			return created, fmt.Errorf("failed to create %s: %w", wildcard.Name, err)
+		}
+		log.Printf("Created DNS record %s %s %s", wildcard.Type, wildcard.Name, wildcard.Content)
+		created = append(created, wildcard)
+	}
+
+	return created, nil
+}
+
+// InstancePropagation reports whether an instance's subdomain resolves to this server
+func (s *DNSService) InstancePropagation(ctx context.Context, instance *models.Instance) dns.Propagation {
+	return dns.CheckHost(ctx, instance.Subdomain, s.config.DNSTarget)
+}
+
+// domains lists BASE_DOMAIN and the domains of all regions, without duplicates
+func (s *DNSService) domains(ctx context.Context) ([]string, error) {
+	regions, err := s.regions.ListAllRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []string{s.config.BaseDomain}
+	seen := map[string]bool{s.config.BaseDomain: true}
+	for _, region := range regions {
+		if !seen[region.BaseDomain] {
+			seen[region.BaseDomain] = true
+			domains = append(domains, region.BaseDomain)
+		}
+	}
+
+	return domains, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/config"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of signed downloads
+const (
+	DownloadBackup = "backup" // Name is a backup ID of InstanceID
+	DownloadExport = "export" // Name is an admin export, Query its filters
+)
+
+// downloadKeyLabel derives the signing key from JWT_ACCESS_SECRET, so links
+// can't be forged from (or passed off as) access tokens
+const downloadKeyLabel = "pocketploy signed downloads"
+
+// ErrDownloadLinkInvalid is returned for links that weren't signed by this server
+var ErrDownloadLinkInvalid = errors.New("download link is invalid")
+
+// ErrDownloadLinkExpired is returned for links past their expiry or already used
+var ErrDownloadLinkExpired = errors.New("download link has expired or was already used")
+
+// SignedDownload is what a signed download URL grants
+type SignedDownload struct {
+	Kind       string    `json:"k"`
+	UserID     uuid.UUID `json:"u"` // the requester, whose access is checked again on download
+	InstanceID string    `json:"i,omitempty"`
+	Name       string    `json:"n"`
+	Query      string    `json:"q,omitempty"`
+	ExpiresAt  int64     `json:"e"`
+	Nonce      string    `json:"o,omitempty"` // set for single-use links
+}
+
+// DownloadLink is a signed download URL handed to the frontend
+type DownloadLink struct {
+	URL       string    `json:"url"` // relative to the API origin
+	ExpiresAt time.Time `json:"expires_at"`
+	SingleUse bool      `json:"single_use"`
+}
+
+// DownloadSigner creates and verifies short-lived download URLs, so the
+// frontend can hand downloads to the browser without putting a bearer token
+// in a query string
+type DownloadSigner struct {
+	key   []byte
+	store cache.Store // remembers used single-use links
+	ttl   time.Duration
+}
+
+// NewDownloadSigner creates a new download signer
+func NewDownloadSigner(store cache.Store, cfg *config.Config) *DownloadSigner {
+	mac := hmac.New(sha256.New, []byte(cfg.JWTAccessSecret))
+	mac.Write([]byte(downloadKeyLabel))
+	return &DownloadSigner{key: mac.Sum(nil), store: store, ttl: cfg.DownloadURLTTL}
+}
+
+// Sign creates a link to a download valid for DOWNLOAD_URL_TTL. A single-use
+// link stops working after its first request.
+func (s *DownloadSigner) Sign(download SignedDownload, singleUse bool) (*DownloadLink, error) {
+	expiresAt := time.Now().UTC().Add(s.ttl).Truncate(time.Second)
+	download.ExpiresAt = expiresAt.Unix()
+	if singleUse {
+		nonce, err := utils.GenerateRandomString(16)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate link: %w", err)
+		}
+		download.Nonce = nonce
+	}
+
+	payload, err := json.Marshal(download)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode link: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	token := encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+
+	return &DownloadLink{URL: "/api/v1/downloads/" + token, ExpiresAt: expiresAt, SingleUse: singleUse}, nil
+}
+
+// Verify checks a link's signature and expiry and consumes single-use links
+func (s *DownloadSigner) Verify(ctx context.Context, token string) (*SignedDownload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrDownloadLinkInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(encoded)) {
+		return nil, ErrDownloadLinkInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrDownloadLinkInvalid
+	}
+	var download SignedDownload
+	if err := json.Unmarshal(payload, &download); err != nil {
+		return nil, ErrDownloadLinkInvalid
+	}
+
+	remaining := time.Until(time.Unix(download.ExpiresAt, 0))
+	if remaining <= 0 {
+		return nil, ErrDownloadLinkExpired
+	}
+
+	if download.Nonce != "" {
+		// The counter outlives the link, so a replay can't reset it
+		uses, err := s.store.Incr(ctx, "download:"+download.Nonce, remaining+time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check download link: %w", err)
+		}
+		if uses > 1 {
+			return nil, ErrDownloadLinkExpired
+		}
+	}
+
+	return &download, nil
+}
+
+func (s *DownloadSigner) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
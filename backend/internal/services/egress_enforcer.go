@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"pocketploy/internal/models"
+)
+
+// appliedEgress is what the enforcer last applied to a container
+type appliedEgress struct {
+	startedAt time.Time
+	rules     string
+}
+
+// EgressEnforcer keeps the egress policies of running instances applied.
+// Rules live in the container's network namespace, which a restart replaces,
+// and allowlisted hostnames may resolve to new addresses.
+type EgressEnforcer struct {
+	store           InstanceStore
+	dockerClient    ContainerRuntime
+	instanceService *InstanceService
+	interval        time.Duration
+	applied         map[string]appliedEgress // by container ID
+}
+
+// NewEgressEnforcer creates an enforcer checking every interval
+func NewEgressEnforcer(store InstanceStore, dockerClient ContainerRuntime, instanceService *InstanceService, interval time.Duration) *EgressEnforcer {
+	return &EgressEnforcer{
+		store:           store,
+		dockerClient:    dockerClient,
+		instanceService: instanceService,
+		interval:        interval,
+		applied:         make(map[string]appliedEgress),
+	}
+}
+
+// Run applies policies until ctx is cancelled
+func (e *EgressEnforcer) Run(ctx context.Context) {
+	if e.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.enforce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ContainerStarted applies the policy of a running instance as soon as Docker
+// (re)starts its container, rather than at the next check. Starts through the
+// API apply it themselves before the instance is marked running.
+func (e *EgressEnforcer) ContainerStarted(ctx context.Context, containerID string) {
+	instance, err := e.store.FindInstanceByContainerID(ctx, containerID)
+	if err != nil || instance.Status != models.InstanceStatusRunning {
+		return
+	}
+
+	if err := e.instanceService.applyEgressPolicy(ctx, instance); err != nil {
+		log.Printf("Warning: stopped instance %s: %v", instance.ID, err)
+	}
+}
+
+// enforce applies the policy of every running, restricted instance whose
+// container restarted or whose rules changed since they were last applied
+func (e *EgressEnforcer) enforce(ctx context.Context) {
+	instances, err := e.store.FindInstancesByStatus(ctx, models.InstanceStatusRunning)
+	if err != nil {
+		log.Printf("Warning: egress enforcement skipped: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i := range instances {
+		instance := &instances[i]
+		if !instance.EgressPolicy.Restricted() || instance.ContainerID == nil || *instance.ContainerID == "" {
+			continue
+		}
+		containerID := *instance.ContainerID
+		seen[containerID] = true
+
+		startedAt, err := e.dockerClient.ContainerStartedAt(ctx, containerID)
+		if err != nil {
+			continue // stopped since it was listed
+		}
+
+		rules, err := e.instanceService.egressRules(ctx, instance.EgressPolicy)
+		if err != nil {
+			log.Printf("Warning: failed to resolve egress policy of instance %s: %v", instance.ID, err)
+			continue
+		}
+		rendered := fmt.Sprint(rules) // addresses are sorted, so this only changes with them
+
+		if last, ok := e.applied[containerID]; ok && last.startedAt.Equal(startedAt) && last.rules == rendered {
+			continue
+		}
+
+		if err := e.dockerClient.ApplyEgressRules(ctx, containerID, rules); err != nil {
+			log.Printf("Warning: failed to apply egress policy of instance %s: %v", instance.ID, err)
+			continue
+		}
+		e.applied[containerID] = appliedEgress{startedAt: startedAt, rules: rendered}
+	}
+
+	for containerID := range e.applied {
+		if !seen[containerID] {
+			delete(e.applied, containerID)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ExportService streams the audit log, metering records and instance history
+// for operators to analyze in external tools
+type ExportService struct {
+	db *sqlx.DB
+}
+
+// NewExportService creates a new export service
+func NewExportService(db *sqlx.DB) *ExportService {
+	return &ExportService{db: db}
+}
+
+// ExportAuditLog passes the audit entries of the filter created in [from, to) to fn
+func (s *ExportService) ExportAuditLog(ctx context.Context, filter models.AuditFilter, from, to time.Time, fn func(*models.AuditEntry) error) error {
+	return models.StreamAuditEntries(ctx, s.db, filter, from, to, fn)
+}
+
+// ExportMetering passes the metering records of hours in [from, to) to fn
+func (s *ExportService) ExportMetering(ctx context.Context, from, to time.Time, userID *uuid.UUID, fn func(*models.MeteringRecord) error) error {
+	return models.StreamMeteringRecords(ctx, s.db, from, to, userID, fn)
+}
+
+// ExportInstanceHistory passes the instance events of the filter to fn
+func (s *ExportService) ExportInstanceHistory(ctx context.Context, filter models.InstanceHistoryFilter, fn func(*models.InstanceHistoryEvent) error) error {
+	return models.StreamInstanceHistory(ctx, s.db, filter, fn)
+}
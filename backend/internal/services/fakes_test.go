@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// fakeInstanceStore keeps instances in memory. Methods it doesn't override
+// panic through the nil embedded interface, so tests notice unexpected calls.
+type fakeInstanceStore struct {
+	InstanceStore
+	instances  map[uuid.UUID]*models.Instance
+	archived   map[uuid.UUID]*models.ArchivedInstance
+	subdomains map[string]bool // taken subdomains
+}
+
+func newFakeInstanceStore(instances ...*models.Instance) *fakeInstanceStore {
+	store := &fakeInstanceStore{
+		instances: make(map[uuid.UUID]*models.Instance),
+		archived:  make(map[uuid.UUID]*models.ArchivedInstance),
+	}
+	for _, instance := range instances {
+		store.instances[instance.ID] = instance
+	}
+	return store
+}
+
+func (f *fakeInstanceStore) FindInstanceByID(ctx context.Context, id uuid.UUID) (*models.Instance, error) {
+	instance, ok := f.instances[id]
+	if !ok {
+		return nil, fmt.Errorf("instance not found")
+	}
+	copied := *instance
+	return &copied, nil
+}
+
+func (f *fakeInstanceStore) SubdomainInUse(ctx context.Context, subdomain string) (bool, error) {
+	return f.subdomains[subdomain], nil
+}
+
+func (f *fakeInstanceStore) UpdateStatus(ctx context.Context, instance *models.Instance, status string) error {
+	instance.Status = status
+	f.instances[instance.ID].Status = status
+	return nil
+}
+
+func (f *fakeInstanceStore) SetProtected(ctx context.Context, instance *models.Instance, protected bool) error {
+	instance.Protected = protected
+	f.instances[instance.ID].Protected = protected
+	return nil
+}
+
+// ArchiveInstance removes the instance under the same conditions as
+// models.ArchiveInstance
+func (f *fakeInstanceStore) ArchiveInstance(ctx context.Context, params models.ArchiveInstanceParams) (*models.ArchivedInstance, error) {
+	stored, ok := f.instances[params.Instance.ID]
+	if !ok {
+		return nil, fmt.Errorf("instance not found")
+	}
+	if params.RequireStatus != "" {
+		if stored.Protected {
+			return nil, models.ErrInstanceProtected
+		}
+		if stored.Status != params.RequireStatus {
+			return nil, models.ErrInstanceStatusChanged
+		}
+	}
+
+	delete(f.instances, stored.ID)
+	archived := &models.ArchivedInstance{
+		ID:            stored.ID,
+		UserID:        stored.UserID,
+		Name:          stored.Name,
+		DataPath:      stored.DataPath,
+		DataAvailable: true,
+	}
+	f.archived[archived.ID] = archived
+
+	copied := *archived
+	return &copied, nil
+}
+
+func (f *fakeInstanceStore) UpdateArchivedDataAvailability(ctx context.Context, id uuid.UUID, available bool) error {
+	f.archived[id].DataAvailable = available
+	return nil
+}
+
+// fakeRuntime records the container operations it is asked for
+type fakeRuntime struct {
+	ContainerRuntime
+	calls     []string
+	egressErr error
+}
+
+func (f *fakeRuntime) StartContainer(ctx context.Context, containerID string) error {
+	f.calls = append(f.calls, "start "+containerID)
+	return nil
+}
+
+func (f *fakeRuntime) StopContainer(ctx context.Context, containerID string) error {
+	f.calls = append(f.calls, "stop "+containerID)
+	return nil
+}
+
+func (f *fakeRuntime) RemoveContainer(ctx context.Context, containerID string) error {
+	f.calls = append(f.calls, "remove "+containerID)
+	return nil
+}
+
+func (f *fakeRuntime) RestartContainer(ctx context.Context, containerID string) error {
+	f.calls = append(f.calls, "restart "+containerID)
+	return nil
+}
+
+func (f *fakeRuntime) ApplyEgressRules(ctx context.Context, containerID string, rules docker.EgressRules) error {
+	f.calls = append(f.calls, "egress "+containerID)
+	return f.egressErr
+}
+
+// fakeJobQueue records the unique keys of the jobs it enqueued and, like
+// jobs.Queue, returns no job when the key was used before
+type fakeJobQueue struct {
+	enqueued []string
+}
+
+func (f *fakeJobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts jobs.EnqueueOptions) (*jobs.Job, error) {
+	for _, key := range f.enqueued {
+		if opts.UniqueKey != "" && key == opts.UniqueKey {
+			return nil, nil
+		}
+	}
+	f.enqueued = append(f.enqueued, opts.UniqueKey)
+	return &jobs.Job{ID: uuid.New(), Type: jobType}, nil
+}
+
+// newTestInstanceService creates an instance service backed by fakes
+func newTestInstanceService(store InstanceStore, runtime ContainerRuntime) *InstanceService {
+	return NewInstanceService(store, runtime, nil, nil, nil, authz.NewEvaluator(authz.DefaultPolicy),
+		&fakeJobQueue{}, nil, nil, nil, nil, nil, &config.Config{})
+}
+
+// newTestInstance returns an instance with a container owned by userID
+func newTestInstance(userID uuid.UUID, status string) *models.Instance {
+	containerID := "container-" + status
+	return &models.Instance{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Name:        "test",
+		Status:      status,
+		ContainerID: &containerID,
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// pocketBasePort is the port approved images must expose, as instances are
+// served and health-checked on it
+const pocketBasePort = "8090/tcp"
+
+// imagePullTimeout bounds pulling an image while it is registered
+const imagePullTimeout = 10 * time.Minute
+
+// ErrInvalidImage is returned (wrapped with the reason) when an image can't
+// be approved
+var ErrInvalidImage = errors.New("invalid image")
+
+// ImageService manages the approved images users can create instances from
+// besides POCKETBASE_IMAGE, such as PocketBase forks with Go hooks compiled in.
+// Images must run the PocketBase binary at /usr/local/bin/pocketbase.
+type ImageService struct {
+	db      *sqlx.DB
+	runtime ContainerRuntime
+	config  *config.Config
+}
+
+// NewImageService creates a new image service
+func NewImageService(db *sqlx.DB, runtime ContainerRuntime, cfg *config.Config) *ImageService {
+	return &ImageService{db: db, runtime: runtime, config: cfg}
+}
+
+// ListImages lists the images a user can choose from
+func (s *ImageService) ListImages(ctx context.Context, userID uuid.UUID) ([]models.InstanceImage, error) {
+	return models.FindAvailableInstanceImages(ctx, s.db, userID)
+}
+
+// ListAllImages lists every approved image, including disabled ones
+func (s *ImageService) ListAllImages(ctx context.Context) ([]models.InstanceImage, error) {
+	return models.FindInstanceImages(ctx, s.db)
+}
+
+// FindImage returns an image whether or not it is enabled
+func (s *ImageService) FindImage(ctx context.Context, id string) (*models.InstanceImage, error) {
+	return models.FindInstanceImageByID(ctx, s.db, id)
+}
+
+// ResolveImage returns the image a user chose for a new instance (nil for
+// POCKETBASE_IMAGE when id is empty). Disabled images and images the user
+// wasn't granted cannot be chosen.
+func (s *ImageService) ResolveImage(ctx context.Context, id string, userID uuid.UUID) (*models.InstanceImage, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	image, err := models.FindInstanceImageByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !image.Enabled {
+		return nil, fmt.Errorf("image is not available")
+	}
+	if !image.AllUsers {
+		granted, err := models.InstanceImageGranted(ctx, s.db, image.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			return nil, fmt.Errorf("image is not available")
+		}
+	}
+
+	return image, nil
+}
+
+// CreateImage approves an image: it is pulled, must expose port 8090 and is
+// pinned to the digest the registry served (which must match req.Digest when
+// given), so a moved tag doesn't change what new instances run
+func (s *ImageService) CreateImage(ctx context.Context, req models.CreateInstanceImageRequest) (*models.InstanceImage, error) {
+	ref := strings.TrimSpace(req.Reference)
+
+	pullCtx, cancel := context.WithTimeout(ctx, imagePullTimeout)
+	defer cancel()
+	if err := s.runtime.PullImage(pullCtx, ref); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	info, err := s.runtime.InspectImage(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Exposes(pocketBasePort) {
+		return nil, fmt.Errorf("%w: image does not expose port 8090", ErrInvalidImage)
+	}
+
+	pinned, err := docker.PinnedReference(ref, info.RepoDigests)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if pinned == "" {
+		return nil, fmt.Errorf("%w: image has no registry digest to pin", ErrInvalidImage)
+	}
+	if req.Digest != "" && !strings.HasSuffix(pinned, "@"+req.Digest) {
+		return nil, fmt.Errorf("%w: image resolved to %s, not the expected digest", ErrInvalidImage, pinned)
+	}
+
+	image := &models.InstanceImage{
+		ID:              req.ID,
+		Name:            strings.TrimSpace(req.Name),
+		Description:     strings.TrimSpace(req.Description),
+		Reference:       ref,
+		PinnedReference: pinned,
+		AllUsers:        req.AllUsers,
+		Enabled:         true,
+	}
+	if err := image.Create(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// UpdateImage renames, describes, enables or disables an image or changes who
+// may choose it. Instances created from it keep running it either way.
+func (s *ImageService) UpdateImage(ctx context.Context, id string, req models.UpdateInstanceImageRequest) (*models.InstanceImage, error) {
+	image, err := models.FindInstanceImageByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		image.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		image.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.AllUsers != nil {
+		image.AllUsers = *req.AllUsers
+	}
+	if req.Enabled != nil {
+		image.Enabled = *req.Enabled
+	}
+
+	if err := image.Update(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// ListGrants lists the users granted an image
+func (s *ImageService) ListGrants(ctx context.Context, id string) ([]models.InstanceImageGrant, error) {
+	if _, err := models.FindInstanceImageByID(ctx, s.db, id); err != nil {
+		return nil, err
+	}
+
+	return models.FindInstanceImageGrants(ctx, s.db, id)
+}
+
+// GrantImage allows a user to choose an image
+func (s *ImageService) GrantImage(ctx context.Context, id string, userID uuid.UUID) error {
+	if _, err := models.FindInstanceImageByID(ctx, s.db, id); err != nil {
+		return err
+	}
+
+	return models.GrantInstanceImage(ctx, s.db, id, userID)
+}
+
+// RevokeImage withdraws a user's grant
+func (s *ImageService) RevokeImage(ctx context.Context, id string, userID uuid.UUID) error {
+	if _, err := models.FindInstanceImageByID(ctx, s.db, id); err != nil {
+		return err
+	}
+
+	return models.RevokeInstanceImage(ctx, s.db, id, userID)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ImageWarmer pre-pulls the instance images so that creating an instance never
+// waits for an image download
+type ImageWarmer struct {
+	dockerClient  ContainerRuntime
+	images        func() []string
+	interval      time.Duration
+	warmContainer bool
+}
+
+// NewImageWarmer creates a warmer pulling images at startup and then every interval (0 only at startup).
+// images is called on every pull, so images configured at runtime are picked up.
+func NewImageWarmer(dockerClient ContainerRuntime, images func() []string, interval time.Duration, warmContainer bool) *ImageWarmer {
+	return &ImageWarmer{
+		dockerClient:  dockerClient,
+		images:        images,
+		interval:      interval,
+		warmContainer: warmContainer,
+	}
+}
+
+// Run pulls the images immediately and then periodically until ctx is cancelled
+func (w *ImageWarmer) Run(ctx context.Context) {
+	w.pull(ctx)
+
+	if w.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.pull(ctx)
+		}
+	}
+}
+
+// pull refreshes every image and its warm container; failures are retried on the next tick
+func (w *ImageWarmer) pull(ctx context.Context) {
+	for _, ref := range w.images() {
+		if err := w.dockerClient.PullImage(ctx, ref); err != nil {
+			log.Printf("Warning: image pre-pull failed: %v", err)
+			continue
+		}
+
+		if w.warmContainer {
+			if err := w.dockerClient.EnsureWarmContainer(ctx, ref); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
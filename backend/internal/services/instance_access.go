@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// accessPasswordCost is the bcrypt cost of basic auth passwords. Traefik
+// checks the hash on every request, so it stays well below BCRYPT_COST.
+const accessPasswordCost = 6
+
+// UpdateAccessRequest sets an instance's access protection. An empty
+// username disables basic auth; the password may be left empty to keep the
+// current one when the username does not change.
+type UpdateAccessRequest struct {
+	Username   string   `json:"username" validate:"omitempty,max=64,basic_auth_user"`
+	Password   string   `json:"password" validate:"omitempty,min=8,max=72"`
+	AllowedIPs []string `json:"allowed_ips" validate:"max=50"`
+}
+
+// UpdateAccessProtection puts an instance behind basic auth and/or a client IP
+// allowlist. Traefik reads them from the container labels, so the container
+// is recreated (a running instance restarts).
+func (s *InstanceService) UpdateAccessProtection(ctx context.Context, instanceID, userID uuid.UUID, req UpdateAccessRequest) (*models.Instance, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	protection := models.AccessProtection{Username: req.Username}
+	for _, entry := range req.AllowedIPs {
+		prefix, err := parseAllowedIP(entry)
+		if err != nil {
+			return nil, err
+		}
+		protection.AllowedIPs = append(protection.AllowedIPs, prefix.String())
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	// Only Traefik enforces the protection; published ports bypass any proxy
+	if s.config.RoutingMode != config.RoutingModeTraefik || instance.HostPort != nil {
+		return nil, fmt.Errorf("access protection requires Traefik routing")
+	}
+
+	passwordHash, err := s.accessPasswordHash(ctx, instance, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := docker.AccessRules{AllowedIPs: protection.AllowedIPs}
+	if protection.Username != "" {
+		rules.BasicAuthUser = protection.Username + ":" + passwordHash
+	}
+
+	containerID, err := s.dockerClient.UpdateAccessRules(ctx, *instance.ContainerID, rules)
+	if updateErr := s.recordRecreatedContainer(ctx, instance, containerID); updateErr != nil {
+		return nil, updateErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply access protection: %w", err)
+	}
+	if err := s.applyRecreatedEgressPolicy(ctx, instance); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpdateAccessProtection(ctx, instance, protection, passwordHash); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// accessPasswordHash hashes the requested basic auth password, or returns the
+// current hash when the username is unchanged and no password was given
+func (s *InstanceService) accessPasswordHash(ctx context.Context, instance *models.Instance, req UpdateAccessRequest) (string, error) {
+	if req.Username == "" {
+		return "", nil
+	}
+
+	if req.Password != "" {
+		hash, err := utils.HashPassword(req.Password, accessPasswordCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return hash, nil
+	}
+
+	if req.Username == instance.AccessProtection.Username {
+		hash, err := s.store.FindAccessPasswordHash(ctx, instance.ID)
+		if err != nil {
+			return "", err
+		}
+		if hash != "" {
+			return hash, nil
+		}
+	}
+
+	return "", fmt.Errorf("a password is required for basic auth")
+}
+
+// parseAllowedIP accepts an IP address or CIDR range
+func parseAllowedIP(entry string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return prefix.Masked(), nil
+	}
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.Prefix{}, fmt.Errorf("allowed IPs must be IP addresses or CIDR ranges")
+}
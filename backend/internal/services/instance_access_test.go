@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+
+	"pocketploy/internal/utils"
+)
+
+func TestUpdateAccessRequestUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"", true},
+		{"admin", true},
+		{"Jane.Doe+ops@example.com", true},
+		{"user:name", false},
+		{"alice,bob:$2y$05$hash", false},
+		{"two words", false},
+		{"line\nbreak", false},
+	}
+
+	for _, tt := range tests {
+		err := utils.ValidateStruct(UpdateAccessRequest{Username: tt.username})
+		if (err == nil) != tt.valid {
+			t.Errorf("username %q: error = %v, want valid %v", tt.username, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/events"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// JobInstanceBuild compiles an uploaded Go module and deploys the image
+const JobInstanceBuild = "instance.build"
+
+const (
+	// maxBuildFiles bounds the number of files of an uploaded module
+	maxBuildFiles = 1000
+
+	// maxBuildLog bounds the stored build output; the end is kept, since
+	// that is where compiler errors are
+	maxBuildLog = 65536
+
+	// buildImagePrefix names the images of custom builds
+	buildImagePrefix = "pocketploy/build-"
+)
+
+// ErrBuildsDisabled is returned when BUILDS_ENABLED is off
+var ErrBuildsDisabled = errors.New("custom builds are disabled")
+
+// ErrBuildInProgress is returned when an instance already has a queued or
+// running build
+var ErrBuildInProgress = models.ErrBuildInProgress
+
+// ErrBuildFailed is returned when an uploaded module doesn't compile
+var ErrBuildFailed = docker.ErrBuildFailed
+
+// instanceBuild is the payload of a JobInstanceBuild job
+type instanceBuild struct {
+	BuildID uuid.UUID `json:"build_id"`
+}
+
+// UploadBuild queues a build of a zip of a Go module whose main package runs
+// PocketBase (importing github.com/pocketbase/pocketbase and registering Go
+// hooks). The build runs in the background; once it succeeds the instance is
+// switched to the new image, and back to the previous one if it doesn't boot.
+func (s *InstanceService) UploadBuild(ctx context.Context, instanceID, userID uuid.UUID, source []byte) (*models.InstanceBuild, error) {
+	if !s.config.BuildsEnabled {
+		return nil, ErrBuildsDisabled
+	}
+
+	instance, err := s.authorizeBundleChange(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.QuarantinedAt != nil {
+		return nil, fmt.Errorf("instance is quarantined")
+	}
+
+	inProgress, err := s.store.BuildInProgress(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+	if inProgress {
+		return nil, ErrBuildInProgress
+	}
+
+	// Each build gets its own source directory, so a concurrent upload can't
+	// replace the module of a build that is already queued
+	build := &models.InstanceBuild{ID: uuid.New(), InstanceID: instance.ID, UserID: userID}
+	sourceDir := s.buildSourceDir(build.ID)
+	if err := extractBundle(source, sourceDir, buildBundleRules(s.config.BuildSourceMaxSize)); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.CreateBuild(ctx, build); err != nil {
+		_ = os.RemoveAll(sourceDir)
+		return nil, err
+	}
+
+	_, err = s.jobs.Enqueue(ctx, JobInstanceBuild, instanceBuild{BuildID: build.ID}, jobs.EnqueueOptions{
+		MaxAttempts: 1,
+		UniqueKey:   "instance-build:" + build.ID.String(),
+	})
+	if err != nil {
+		_ = os.RemoveAll(sourceDir)
+		_ = s.failBuild(ctx, build, "", fmt.Errorf("failed to queue build: %w", err))
+		return nil, fmt.Errorf("failed to queue build: %w", err)
+	}
+
+	return build, nil
+}
+
+// ListBuilds returns an instance's builds, newest first, without their logs
+func (s *InstanceService) ListBuilds(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceBuild, error) {
+	if _, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView); err != nil {
+		return nil, err
+	}
+
+	return s.store.FindBuilds(ctx, instanceID)
+}
+
+// GetBuild returns one of an instance's builds with its log
+func (s *InstanceService) GetBuild(ctx context.Context, instanceID, buildID, userID uuid.UUID) (*models.InstanceBuild, error) {
+	if _, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView); err != nil {
+		return nil, err
+	}
+
+	build, err := s.store.FindBuild(ctx, buildID)
+	if err != nil {
+		return nil, err
+	}
+	if build.InstanceID != instanceID {
+		return nil, fmt.Errorf("build not found")
+	}
+
+	return build, nil
+}
+
+// HandleBuildJob compiles a queued build and deploys it. Build and deployment
+// failures are recorded on the build (and reported to the owner), so the job
+// itself only fails when the build can't be recorded.
+func (s *InstanceService) HandleBuildJob(ctx context.Context, job *jobs.Job) error {
+	var payload instanceBuild
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid build payload: %w", err)
+	}
+
+	build, err := s.store.FindBuild(ctx, payload.BuildID)
+	if err != nil {
+		if err.Error() == "build not found" {
+			return nil
+		}
+		return err
+	}
+
+	sourceDir := s.buildSourceDir(build.ID)
+	defer os.RemoveAll(sourceDir)
+
+	if build.Status != models.BuildStatusQueued {
+		return nil
+	}
+
+	instance, err := s.store.FindInstanceByID(ctx, build.InstanceID)
+	if err != nil {
+		return s.failBuild(ctx, build, "", err)
+	}
+
+	startedAt := time.Now().UTC()
+	build.Status = models.BuildStatusBuilding
+	build.StartedAt = &startedAt
+	if err := s.store.UpdateBuild(ctx, build); err != nil {
+		return err
+	}
+	s.publishBuild(ctx, instance, build)
+
+	tag := buildImagePrefix + instance.ID.String() + ":" + build.ID.String()
+
+	buildCtx, cancel := context.WithTimeout(ctx, s.config.BuildTimeout)
+	output, err := s.dockerClient.BuildPocketBase(buildCtx, sourceDir, tag)
+	cancel()
+	if err != nil {
+		if errors.Is(buildCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: timed out after %s", ErrBuildFailed, s.config.BuildTimeout)
+		}
+		return s.failBuild(ctx, build, output, err)
+	}
+
+	build.Image = &tag
+	if err := s.deployBuild(ctx, instance, tag); err != nil {
+		if removeErr := s.dockerClient.RemoveImage(ctx, tag); removeErr != nil {
+			log.Printf("Warning: %v", removeErr)
+		}
+		return s.failBuild(ctx, build, output, err)
+	}
+
+	finishedAt := time.Now().UTC()
+	build.Status = models.BuildStatusSucceeded
+	build.Log = buildLog(output)
+	build.FinishedAt = &finishedAt
+	build.DeployedAt = &finishedAt
+	if err := s.store.UpdateBuild(ctx, build); err != nil {
+		return err
+	}
+	s.publishBuild(ctx, instance, build)
+
+	return nil
+}
+
+// deployBuild switches an instance's container to a built image. A running
+// instance must boot with it; otherwise the previous image is put back. The
+// image of the build it replaced is removed.
+func (s *InstanceService) deployBuild(ctx context.Context, instance *models.Instance, tag string) error {
+	// The instance may have been deleted or stopped for good while building
+	instance, err := s.store.FindInstanceByID(ctx, instance.ID)
+	if err != nil {
+		return err
+	}
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return fmt.Errorf("instance has no container")
+	}
+	if instance.Status != models.InstanceStatusRunning && instance.Status != models.InstanceStatusStopped {
+		return fmt.Errorf("instance must be running or stopped to deploy a build")
+	}
+
+	previous, err := s.dockerClient.ContainerImage(ctx, *instance.ContainerID)
+	if err != nil {
+		return err
+	}
+
+	containerID, err := s.dockerClient.UpdateImage(ctx, *instance.ContainerID, tag)
+	if recordErr := s.recordRecreatedContainer(ctx, instance, containerID); recordErr != nil {
+		return recordErr
+	}
+	if err == nil {
+		err = s.applyRecreatedEgressPolicy(ctx, instance)
+	}
+	if err == nil && instance.Status == models.InstanceStatusRunning {
+		err = s.waitForBoot(ctx, containerID)
+	}
+
+	if err != nil && containerID == "" {
+		return err
+	}
+	if err != nil {
+		log.Printf("Build of instance %s failed to start, restoring %s: %v", instance.ID, previous, err)
+		restoredID, restoreErr := s.dockerClient.UpdateImage(ctx, containerID, previous)
+		if recordErr := s.recordRecreatedContainer(ctx, instance, restoredID); recordErr != nil {
+			return recordErr
+		}
+		if restoreErr == nil {
+			restoreErr = s.applyRecreatedEgressPolicy(ctx, instance)
+		}
+		if restoreErr != nil {
+			return fmt.Errorf("build failed to start: %w (restoring the previous image failed: %v)", err, restoreErr)
+		}
+		return fmt.Errorf("build failed to start, the previous image was restored: %w", err)
+	}
+
+	if previous != tag && strings.HasPrefix(previous, buildImagePrefix) {
+		if err := s.dockerClient.RemoveImage(ctx, previous); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// failBuild records a failed build with its output. It returns an error only
+// if the build could not be recorded.
+func (s *InstanceService) failBuild(ctx context.Context, build *models.InstanceBuild, output string, cause error) error {
+	finishedAt := time.Now().UTC()
+	message := cause.Error()
+	build.Status = models.BuildStatusFailed
+	build.Error = &message
+	build.Log = buildLog(output)
+	build.FinishedAt = &finishedAt
+	if err := s.store.UpdateBuild(ctx, build); err != nil {
+		return err
+	}
+
+	if instance, err := s.store.FindInstanceByID(ctx, build.InstanceID); err == nil {
+		s.publishBuild(ctx, instance, build)
+	}
+	return nil
+}
+
+// publishBuild tells the instance owner about a build's progress
+func (s *InstanceService) publishBuild(ctx context.Context, instance *models.Instance, build *models.InstanceBuild) {
+	update := *build
+	update.Log = nil
+	s.events.Publish(ctx, instance.UserID.String(), events.TypeInstanceBuild, update)
+}
+
+// buildSourceDir is where the uploaded module of a pending build is kept
+func (s *InstanceService) buildSourceDir(buildID uuid.UUID) string {
+	return filepath.Join(s.config.BuildsPath, buildID.String())
+}
+
+// buildBundleRules accept a Go module: go.mod and the main package at the top level
+func buildBundleRules(maxSize int64) bundleRules {
+	return bundleRules{
+		maxSize:  maxSize,
+		maxFiles: maxBuildFiles,
+		checkBundle: func(names []string) error {
+			hasModule, hasMain := false, false
+			for _, name := range names {
+				switch {
+				case name == "go.mod":
+					hasModule = true
+				case !strings.Contains(name, "/") && path.Ext(name) == ".go":
+					hasMain = true
+				}
+			}
+			if !hasModule {
+				return fmt.Errorf("%w: no go.mod at the top level", ErrInvalidBundle)
+			}
+			if !hasMain {
+				return fmt.Errorf("%w: no .go files at the top level", ErrInvalidBundle)
+			}
+			return nil
+		},
+	}
+}
+
+// buildLog trims build output to its last maxBuildLog bytes
+func buildLog(output string) *string {
+	if output == "" {
+		return nil
+	}
+	output = outputTail(output, maxBuildLog)
+	return &output
+}
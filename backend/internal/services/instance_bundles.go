@@ -0,0 +1,219 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrInvalidBundle is returned (wrapped with the reason) for uploaded zip
+// bundles (hooks, public files) that fail validation
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+// BundleFile is a file of an uploaded bundle
+type BundleFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Bundle describes the files installed in one of an instance's directories
+type Bundle struct {
+	Dir       string       `json:"dir"` // relative to the data directory
+	Files     []BundleFile `json:"files"`
+	UpdatedAt *time.Time   `json:"updated_at,omitempty"`
+}
+
+// bundleRules describe what an uploaded bundle may contain
+type bundleRules struct {
+	maxSize  int64  // compressed and extracted
+	maxFiles int    // regular files
+	topDir   string // a single top-level folder with this name is stripped
+
+	// checkFile rejects an entry by its (stripped) name; nil allows every file
+	checkFile func(name string) error
+
+	// checkBundle validates the complete list of names; nil accepts any non-empty bundle
+	checkBundle func(names []string) error
+
+	// scan checks the extracted files in the staging directory before they
+	// replace the current ones; it may move the directory away. nil skips scanning.
+	scan func(staging string) error
+}
+
+// extractBundle validates a zip bundle and replaces target with its contents.
+// Entry names must be local paths (no absolute paths, .. or symlinks), so
+// nothing is written outside target. The bundle is extracted next to target
+// and swapped in, so a rejected upload leaves the current files untouched.
+func extractBundle(bundle []byte, target string, rules bundleRules) error {
+	if int64(len(bundle)) > rules.maxSize {
+		return fmt.Errorf("%w: larger than %d bytes", ErrInvalidBundle, rules.maxSize)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return fmt.Errorf("%w: not a zip archive", ErrInvalidBundle)
+	}
+
+	var files []*zip.File
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%w: no files", ErrInvalidBundle)
+	}
+	if len(files) > rules.maxFiles {
+		return fmt.Errorf("%w: more than %d files", ErrInvalidBundle, rules.maxFiles)
+	}
+
+	prefix := rules.topDir + "/"
+	for _, file := range files {
+		if rules.topDir == "" || !strings.HasPrefix(file.Name, prefix) {
+			prefix = ""
+			break
+		}
+	}
+
+	names := make([]string, len(files))
+	for i, file := range files {
+		name := strings.TrimPrefix(file.Name, prefix)
+		if !file.Mode().IsRegular() || !filepath.IsLocal(name) || path.Clean(name) != name {
+			return fmt.Errorf("%w: invalid entry %s", ErrInvalidBundle, file.Name)
+		}
+		if rules.checkFile != nil {
+			if err := rules.checkFile(name); err != nil {
+				return err
+			}
+		}
+		names[i] = name
+	}
+	if rules.checkBundle != nil {
+		if err := rules.checkBundle(names); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(target), ".upload-")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	// MkdirTemp creates the directory private; the container user must read it
+	if err := os.Chmod(staging, 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	remaining := rules.maxSize
+	for i, file := range files {
+		written, err := extractBundleFile(file, filepath.Join(staging, filepath.FromSlash(names[i])), remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= written
+	}
+
+	if rules.scan != nil {
+		if err := rules.scan(staging); err != nil {
+			return err
+		}
+	}
+
+	return replaceBundleDir(staging, target)
+}
+
+// replaceBundleDir swaps source in as target, restoring the previous target
+// if that fails
+func replaceBundleDir(source, target string) error {
+	previous := target + ".previous"
+	_ = os.RemoveAll(previous)
+	if err := os.Rename(target, previous); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace bundle: %w", err)
+	}
+	if err := os.Rename(source, target); err != nil {
+		_ = os.Rename(previous, target)
+		return fmt.Errorf("failed to replace bundle: %w", err)
+	}
+	_ = os.RemoveAll(previous)
+
+	return nil
+}
+
+// extractBundleFile writes one bundle entry, failing once more than limit bytes were written
+func extractBundleFile(file *zip.File, dest string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid entry %s", ErrInvalidBundle, file.Name)
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		return 0, fmt.Errorf("%w: duplicate entry %s", ErrInvalidBundle, file.Name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write bundle file: %w", err)
+	}
+	defer out.Close()
+
+	written, err := io.Copy(out, io.LimitReader(src, limit+1))
+	if err != nil {
+		return written, fmt.Errorf("%w: invalid entry %s", ErrInvalidBundle, file.Name)
+	}
+	if written > limit {
+		return written, fmt.Errorf("%w: larger than %d bytes once extracted", ErrInvalidBundle, limit)
+	}
+
+	return written, nil
+}
+
+// readBundle lists the files under one of an instance's directories
+func readBundle(dataPath, dir string) (*Bundle, error) {
+	bundle := &Bundle{Dir: dir, Files: []BundleFile{}}
+	root := filepath.Join(dataPath, dir)
+
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		bundle.Files = append(bundle.Files, BundleFile{Path: filepath.ToSlash(rel), Size: info.Size()})
+		if modified := info.ModTime().UTC(); bundle.UpdatedAt == nil || modified.After(*bundle.UpdatedAt) {
+			bundle.UpdatedAt = &modified
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"pocketploy/internal/models"
+)
+
+// Certificate statuses reported in instance details
+const (
+	CertificateIssued    = "issued"    // a valid certificate covers the subdomain
+	CertificatePending   = "pending"   // Traefik has not obtained one yet
+	CertificateError     = "error"     // the certificate expired or cannot be read
+	CertificateUnmanaged = "unmanaged" // TLS is terminated outside Traefik (or not at all)
+)
+
+// CertificateStatus describes the TLS certificate serving an instance
+type CertificateStatus struct {
+	Status    string     `json:"status"`
+	Domain    string     `json:"domain,omitempty"` // main domain of the certificate
+	Issuer    string     `json:"issuer,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// InstanceCertificate reports the certificate Traefik holds for an
+// instance's subdomain, read from TRAEFIK_ACME_PATH
+func (s *InstanceService) InstanceCertificate(instance *models.Instance) CertificateStatus {
+	if s.config.TraefikACMEPath == "" || instance.HostPort != nil {
+		return CertificateStatus{Status: CertificateUnmanaged}
+	}
+
+	certificates, err := s.certificates.load()
+	if err != nil {
+		return CertificateStatus{Status: CertificateError, Error: err.Error()}
+	}
+
+	for _, cert := range certificates {
+		if !cert.covers(instance.Subdomain) {
+			continue
+		}
+
+		status := CertificateStatus{Status: CertificateIssued, Domain: cert.domain, Issuer: cert.issuer, ExpiresAt: &cert.expiresAt}
+		if cert.err != nil {
+			status.Status = CertificateError
+			status.Error = cert.err.Error()
+		} else if time.Now().After(cert.expiresAt) {
+			status.Status = CertificateError
+			status.Error = "certificate expired"
+		}
+		return status
+	}
+
+	return CertificateStatus{Status: CertificatePending}
+}
+
+// acmeCertificate is a certificate from acme.json with its parsed details
+type acmeCertificate struct {
+	domain    string
+	names     []string
+	issuer    string
+	expiresAt time.Time
+	err       error // set when the certificate could not be decoded
+}
+
+// covers reports whether the certificate is valid for host (single-label wildcards included)
+func (c acmeCertificate) covers(host string) bool {
+	for _, name := range c.names {
+		if strings.EqualFold(name, host) {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(name, "*."); ok {
+			if label, rest, found := strings.Cut(host, "."); found && label != "" && strings.EqualFold(rest, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acmeStore reads Traefik's ACME storage file, parsing it again only after it changed
+type acmeStore struct {
+	path string
+
+	mu           sync.Mutex
+	modTime      time.Time
+	certificates []acmeCertificate
+}
+
+func newACMEStore(path string) *acmeStore {
+	return &acmeStore{path: path}
+}
+
+// acmeFile is the layout of acme.json: certificates grouped by resolver
+type acmeFile map[string]struct {
+	Certificates []struct {
+		Domain struct {
+			Main string   `json:"main"`
+			SANs []string `json:"sans"`
+		} `json:"domain"`
+		Certificate string `json:"certificate"` // base64-encoded PEM chain
+	} `json:"Certificates"`
+}
+
+func (s *acmeStore) load() ([]acmeCertificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate storage: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return s.certificates, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate storage: %w", err)
+	}
+	var file acmeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate storage: %w", err)
+	}
+
+	var certificates []acmeCertificate
+	for _, resolver := range file {
+		for _, entry := range resolver.Certificates {
+			cert := acmeCertificate{
+				domain: entry.Domain.Main,
+				names:  append([]string{entry.Domain.Main}, entry.Domain.SANs...),
+			}
+			leaf, err := parseCertificateChain(entry.Certificate)
+			if err != nil {
+				cert.err = err
+			} else {
+				cert.issuer = leaf.Issuer.CommonName
+				cert.expiresAt = leaf.NotAfter
+			}
+			certificates = append(certificates, cert)
+		}
+	}
+
+	s.modTime = info.ModTime()
+	s.certificates = certificates
+	return certificates, nil
+}
+
+// parseCertificateChain decodes the leaf of a base64-encoded PEM chain
+func parseCertificateChain(encoded string) (*x509.Certificate, error) {
+	chain, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate encoding")
+	}
+
+	block, _ := pem.Decode(chain)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("invalid certificate")
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	return leaf, nil
+}
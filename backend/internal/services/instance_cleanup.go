@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// JobInstanceCleanup removes the container (and, without retention, the data)
+// of an archived instance
+const JobInstanceCleanup = "instance.cleanup"
+
+const (
+	// instanceCleanupDelay gives the inline cleanup in archiveInstance time to
+	// finish before the job checks its work
+	instanceCleanupDelay = time.Minute
+
+	instanceCleanupAttempts = 10
+)
+
+// instanceCleanup is the payload of a JobInstanceCleanup job
+type instanceCleanup struct {
+	InstanceID  uuid.UUID `json:"instance_id"`
+	ContainerID string    `json:"container_id,omitempty"`
+	DataPath    string    `json:"data_path,omitempty"`
+	RemoveData  bool      `json:"remove_data"`
+}
+
+// scheduleCleanup records the cleanup of an instance before it is archived.
+// If the process dies or Docker fails after the archive is committed, the job
+// finishes the deletion; if the archive is rolled back, the job finds the
+// instance still in place and does nothing. Each attempt gets its own job, as
+// the job of an earlier, rolled back attempt may still be in the queue.
+func (s *InstanceService) scheduleCleanup(ctx context.Context, instance *models.Instance, removeData bool) error {
+	payload := instanceCleanup{
+		InstanceID: instance.ID,
+		DataPath:   instance.DataPath,
+		RemoveData: removeData,
+	}
+	if instance.ContainerID != nil {
+		payload.ContainerID = *instance.ContainerID
+	}
+
+	job, err := s.jobs.Enqueue(ctx, JobInstanceCleanup, payload, jobs.EnqueueOptions{
+		RunAt:       time.Now().Add(instanceCleanupDelay),
+		MaxAttempts: instanceCleanupAttempts,
+		UniqueKey:   "instance-cleanup:" + instance.ID.String() + ":" + uuid.NewString(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule instance cleanup: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("failed to schedule instance cleanup: a job with the same key exists")
+	}
+
+	return nil
+}
+
+// HandleCleanupJob removes what is left of an archived instance. Container and
+// data removal are idempotent, so the job is safe to run after the inline
+// cleanup succeeded and to retry after a failure.
+func (s *InstanceService) HandleCleanupJob(ctx context.Context, job *jobs.Job) error {
+	var payload instanceCleanup
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid cleanup payload: %w", err)
+	}
+
+	// The archive transaction was rolled back: the instance is still live
+	if _, err := s.store.FindInstanceByID(ctx, payload.InstanceID); err == nil {
+		return nil
+	} else if err.Error() != "instance not found" {
+		return err
+	}
+
+	if payload.ContainerID != "" {
+		if err := s.dockerClient.RemoveContainer(ctx, payload.ContainerID); err != nil {
+			return err
+		}
+	}
+
+	if payload.RemoveData {
+		if err := s.removeInstanceData(payload.DataPath); err != nil {
+			return err
+		}
+		if err := s.store.UpdateArchivedDataAvailability(ctx, payload.InstanceID, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// processExpiredArchives deletes the data of archived instances whose retention
+// period has ended, which releases their subdomains for new instances
+func (s *InstanceService) processExpiredArchives(ctx context.Context) {
+	archived, err := s.store.FindExpiredArchivedInstances(ctx)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+
+	for _, instance := range archived {
+		if err := s.removeInstanceData(instance.DataPath); err != nil {
+			fmt.Printf("Warning: failed to purge data of archived instance %s: %v\n", instance.ID, err)
+			continue
+		}
+
+		if err := s.store.UpdateArchivedDataAvailability(ctx, instance.ID, false); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+
+		fmt.Printf("Retention ended for archived instance %s, subdomain %s released\n", instance.Name, instance.Subdomain)
+	}
+}
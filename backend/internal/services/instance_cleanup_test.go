@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestScheduleCleanupPerAttempt(t *testing.T) {
+	instance := newTestInstance(uuid.New(), models.InstanceStatusPendingDeletion)
+	queue := &fakeJobQueue{}
+	service := newTestInstanceService(newFakeInstanceStore(instance), &fakeRuntime{})
+	service.jobs = queue
+
+	// The job of a rolled back attempt is still queued (or succeeded and not
+	// pruned yet) when the instance is archived again
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := service.scheduleCleanup(context.Background(), instance, true); err != nil {
+			t.Fatalf("scheduleCleanup() attempt %d error = %v", attempt, err)
+		}
+	}
+
+	if len(queue.enqueued) != 2 {
+		t.Errorf("enqueued %d cleanup jobs, want one per attempt", len(queue.enqueued))
+	}
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// copyBackupPrefix names the copies of the target's data taken before a data copy
+	copyBackupPrefix = "pocketploy_pre_copy_"
+
+	// maxCopyCollections bounds the collections copied at once
+	maxCopyCollections = 100
+
+	// dataCopyTimeout bounds each sqlite3 run of a copy
+	dataCopyTimeout = 10 * time.Minute
+
+	// Where the data directories are mounted in the sqlite3 container
+	copySourceDir = "/source"
+	copyTargetDir = "/target"
+)
+
+// collectionName matches user collections; PocketBase's own (_superusers,
+// _otps, ...) start with an underscore and are never copied
+var collectionName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,99}$`)
+
+// ErrCollectionNotFound is returned (wrapped with the name) when a selected
+// collection doesn't exist in the source instance
+var ErrCollectionNotFound = errors.New("collection not found in the source instance")
+
+// ErrDataCopyFailed is returned (wrapped with SQLite's output) when the copy
+// was rejected; the target is left unchanged
+var ErrDataCopyFailed = errors.New("data copy failed")
+
+// DataCopyResult reports a copy and the backup of the target taken before it
+type DataCopyResult struct {
+	SourceID     uuid.UUID      `json:"source_id"`
+	TargetID     uuid.UUID      `json:"target_id"`
+	Collections  []string       `json:"collections"`
+	SafetyBackup InstanceBackup `json:"safety_backup"`
+}
+
+// schemaObject is a sqlite_master row of a selected collection, or (with db
+// "collection") its _collections row
+type schemaObject struct {
+	DB      string  `json:"db"`
+	Type    string  `json:"type"`
+	Name    string  `json:"name"`
+	TblName string  `json:"tbl_name"`
+	SQL     *string `json:"sql"`
+}
+
+// CopyCollections copies the schema and records of the selected collections
+// from one of a user's instances to another, e.g. to promote staging data to
+// production. Selected collections are replaced in the target; others are
+// left alone, so relations to collections that weren't copied may dangle.
+// The target is stopped during the copy and backed up first; the copy runs in
+// a single transaction, so a failure leaves the target unchanged.
+func (s *InstanceService) CopyCollections(ctx context.Context, sourceID, targetID, userID uuid.UUID, collections []string) (*DataCopyResult, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("source and target must be different instances")
+	}
+	if len(collections) == 0 || len(collections) > maxCopyCollections {
+		return nil, fmt.Errorf("between 1 and %d collections must be selected", maxCopyCollections)
+	}
+	seen := make(map[string]bool, len(collections))
+	for _, name := range collections {
+		if !collectionName.MatchString(name) {
+			return nil, fmt.Errorf("invalid collection name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("collection %q is selected twice", name)
+		}
+		seen[name] = true
+	}
+
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	source, err := s.AuthorizeInstance(ctx, sourceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.AuthorizeInstance(ctx, targetID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.ContainerID == nil || *target.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+	if target.Status != models.InstanceStatusRunning && target.Status != models.InstanceStatusStopped {
+		return nil, fmt.Errorf("target instance must be running or stopped")
+	}
+
+	dataDirs := map[string]string{copySourceDir: source.DataPath, copyTargetDir: target.DataPath}
+
+	objects, err := s.readCollectionSchemas(ctx, dataDirs, collections)
+	if err != nil {
+		return nil, err
+	}
+
+	wasRunning := target.Status == models.InstanceStatusRunning
+	if wasRunning {
+		if err := s.dockerClient.StopContainer(ctx, *target.ContainerID); err != nil {
+			return nil, fmt.Errorf("failed to stop container: %w", err)
+		}
+		if err := s.store.UpdateStatus(ctx, target, models.InstanceStatusStopped); err != nil {
+			return nil, fmt.Errorf("failed to update instance status: %w", err)
+		}
+	}
+
+	safety, err := snapshotData(target.DataPath, copyBackupPrefix)
+	if err == nil {
+		err = s.runDataCopy(ctx, dataDirs, collections, objects)
+	}
+
+	if wasRunning {
+		if startErr := s.startContainer(ctx, target); startErr != nil {
+			return nil, fmt.Errorf("failed to start container: %w", startErr)
+		}
+		if statusErr := s.store.UpdateStatus(ctx, target, models.InstanceStatusRunning); statusErr != nil {
+			return nil, fmt.Errorf("failed to update instance status: %w", statusErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataCopyResult{
+		SourceID:     source.ID,
+		TargetID:     target.ID,
+		Collections:  collections,
+		SafetyBackup: *safety,
+	}, nil
+}
+
+// readCollectionSchemas reads the tables, views, indexes and triggers of the
+// selected collections in both databases, and their source _collections rows
+func (s *InstanceService) readCollectionSchemas(ctx context.Context, dataDirs map[string]string, collections []string) ([]schemaObject, error) {
+	names := make([]string, len(collections))
+	for i, name := range collections {
+		names[i] = sqlQuote(name)
+	}
+	list := strings.Join(names, ", ")
+
+	query := "ATTACH " + sqlQuote(copySourceDir+"/data.db") + " AS src; " +
+		"SELECT 'source' AS db, type, name, tbl_name, sql FROM src.sqlite_master WHERE tbl_name IN (" + list + ") AND sql IS NOT NULL " +
+		"UNION ALL SELECT 'target', type, name, tbl_name, sql FROM main.sqlite_master WHERE tbl_name IN (" + list + ") AND sql IS NOT NULL " +
+		"UNION ALL SELECT 'collection', type, name, id, NULL FROM src._collections WHERE name IN (" + list + ");"
+
+	ctx, cancel := context.WithTimeout(ctx, dataCopyTimeout)
+	defer cancel()
+
+	output, exitCode, err := s.dockerClient.RunSQLite(ctx, dataDirs, []string{"-json", "-readonly", copyTargetDir + "/data.db", query})
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDataCopyFailed, strings.TrimSpace(output))
+	}
+
+	objects := []schemaObject{}
+	if trimmed := strings.TrimSpace(output); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &objects); err != nil {
+			return nil, fmt.Errorf("failed to read collection schemas: %w", err)
+		}
+	}
+
+	for _, name := range collections {
+		found := false
+		for _, object := range objects {
+			if object.DB == "collection" && object.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+		}
+	}
+
+	return objects, nil
+}
+
+// runDataCopy replaces the selected collections in the target with the
+// source's, in one transaction
+func (s *InstanceService) runDataCopy(ctx context.Context, dataDirs map[string]string, collections []string, objects []schemaObject) error {
+	var script strings.Builder
+	script.WriteString("PRAGMA foreign_keys = OFF;\n")
+	script.WriteString("ATTACH " + sqlQuote(copySourceDir+"/data.db") + " AS src;\n")
+	script.WriteString("BEGIN;\n")
+
+	for _, name := range collections {
+		var collectionID, collectionType string
+		for _, object := range objects {
+			if object.DB == "collection" && object.Name == name {
+				collectionID, collectionType = object.TblName, object.Type
+			}
+		}
+
+		script.WriteString("DELETE FROM main._collections WHERE name = " + sqlQuote(name) + " OR id = " + sqlQuote(collectionID) + ";\n")
+		for _, object := range objects {
+			if object.DB == "target" && object.Name == name && (object.Type == "table" || object.Type == "view") {
+				script.WriteString("DROP " + strings.ToUpper(object.Type) + " main." + sqlIdentifier(name) + ";\n")
+			}
+		}
+
+		// Tables and views first, then their indexes and triggers
+		for _, types := range [][]string{{"table", "view"}, {"index", "trigger"}} {
+			for _, object := range objects {
+				if object.DB == "source" && object.TblName == name && object.SQL != nil && (object.Type == types[0] || object.Type == types[1]) {
+					script.WriteString(*object.SQL + ";\n")
+				}
+			}
+			if types[0] == "table" && collectionType != "view" {
+				script.WriteString("INSERT INTO main." + sqlIdentifier(name) + " SELECT * FROM src." + sqlIdentifier(name) + ";\n")
+			}
+		}
+
+		script.WriteString("INSERT INTO main._collections SELECT * FROM src._collections WHERE id = " + sqlQuote(collectionID) + ";\n")
+	}
+
+	script.WriteString("COMMIT;\n")
+
+	ctx, cancel := context.WithTimeout(ctx, dataCopyTimeout)
+	defer cancel()
+
+	// -bail stops at the first error; the open transaction is then rolled back
+	output, exitCode, err := s.dockerClient.RunSQLite(ctx, dataDirs, []string{"-bail", copyTargetDir + "/data.db", script.String()})
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("%w: %s", ErrDataCopyFailed, strings.TrimSpace(output))
+	}
+
+	return nil
+}
+
+// sqlQuote quotes a string literal for SQLite
+func sqlQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// sqlIdentifier quotes an identifier for SQLite
+func sqlIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
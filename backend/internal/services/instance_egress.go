@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// UpdateEgressRequest sets an instance's egress policy. Rules are only kept
+// for the allowlist mode.
+type UpdateEgressRequest struct {
+	Mode  string              `json:"mode" validate:"required,oneof=allow_all deny_all allowlist"`
+	Rules []models.EgressRule `json:"rules" validate:"max=50,dive"`
+}
+
+// UpdateEgressPolicy restricts the outbound connections of an instance. The
+// rules take effect right away on a running instance; stopped instances get
+// them once they are running again.
+func (s *InstanceService) UpdateEgressPolicy(ctx context.Context, instanceID, userID uuid.UUID, req UpdateEgressRequest) (*models.Instance, error) {
+	if s.config.EgressFirewallImage == "" {
+		return nil, fmt.Errorf("egress policies are not enabled")
+	}
+
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	policy := models.EgressPolicy{Mode: req.Mode}
+	if req.Mode == models.EgressAllowList {
+		if len(req.Rules) == 0 {
+			return nil, fmt.Errorf("the allowlist needs at least one host")
+		}
+		for _, rule := range req.Rules {
+			host := strings.ToLower(strings.TrimSpace(rule.Host))
+			if !validEgressHost(host) {
+				return nil, fmt.Errorf("hosts must be hostnames, IP addresses or CIDR ranges")
+			}
+			policy.Rules = append(policy.Rules, models.EgressRule{Host: host, Ports: rule.Ports})
+		}
+	}
+
+	if instance.Status == models.InstanceStatusRunning && instance.ContainerID != nil && *instance.ContainerID != "" {
+		rules, err := s.egressRules(ctx, policy)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.dockerClient.ApplyEgressRules(ctx, *instance.ContainerID, rules); err != nil {
+			return nil, fmt.Errorf("failed to apply egress policy: %w", err)
+		}
+	}
+
+	if err := s.store.UpdateEgressPolicy(ctx, instance, policy); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// startContainer starts an instance's container and applies its egress policy
+// before returning
+func (s *InstanceService) startContainer(ctx context.Context, instance *models.Instance) error {
+	if err := s.dockerClient.StartContainer(ctx, *instance.ContainerID); err != nil {
+		return err
+	}
+	return s.applyEgressPolicy(ctx, instance)
+}
+
+// restartContainer restarts an instance's container and applies its egress
+// policy before returning
+func (s *InstanceService) restartContainer(ctx context.Context, instance *models.Instance) error {
+	if err := s.dockerClient.RestartContainer(ctx, *instance.ContainerID); err != nil {
+		return err
+	}
+	return s.applyEgressPolicy(ctx, instance)
+}
+
+// applyRecreatedEgressPolicy applies the egress policy of a running instance
+// whose container was just recreated
+func (s *InstanceService) applyRecreatedEgressPolicy(ctx context.Context, instance *models.Instance) error {
+	if instance.Status != models.InstanceStatusRunning {
+		return nil
+	}
+	return s.applyEgressPolicy(ctx, instance)
+}
+
+// applyEgressPolicy applies an instance's egress policy to its container,
+// whose rules are lost with every new network namespace. A container that
+// can't get them is stopped rather than left running unrestricted.
+func (s *InstanceService) applyEgressPolicy(ctx context.Context, instance *models.Instance) error {
+	if !instance.EgressPolicy.Restricted() || instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil
+	}
+
+	rules, err := s.egressRules(ctx, instance.EgressPolicy)
+	if err == nil {
+		err = s.dockerClient.ApplyEgressRules(ctx, *instance.ContainerID, rules)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if stopErr := s.dockerClient.StopContainer(ctx, *instance.ContainerID); stopErr != nil {
+		log.Printf("Warning: failed to stop container %s: %v", *instance.ContainerID, stopErr)
+	}
+	if instance.Status == models.InstanceStatusRunning {
+		if statusErr := s.store.UpdateStatus(ctx, instance, models.InstanceStatusStopped); statusErr != nil {
+			log.Printf("Warning: failed to update status of instance %s: %v", instance.ID, statusErr)
+		}
+	}
+
+	return fmt.Errorf("failed to apply egress policy: %w", err)
+}
+
+// egressRules resolves a policy into the addresses the container may connect to
+func (s *InstanceService) egressRules(ctx context.Context, policy models.EgressPolicy) (docker.EgressRules, error) {
+	rules := docker.EgressRules{
+		Restricted: policy.Restricted(),
+		AllowDNS:   policy.Mode == models.EgressAllowList,
+	}
+	if policy.Mode != models.EgressAllowList {
+		return rules, nil
+	}
+
+	for _, rule := range policy.Rules {
+		prefixes, err := resolveEgressHost(ctx, rule.Host)
+		if err != nil {
+			return docker.EgressRules{}, err
+		}
+		for _, prefix := range prefixes {
+			rules.Destinations = append(rules.Destinations, docker.EgressDestination{Prefix: prefix, Ports: rule.Ports})
+		}
+	}
+
+	return rules, nil
+}
+
+// resolveEgressHost returns the address ranges of an allowlist host, sorted so
+// the rendered rules only change when the addresses do
+func resolveEgressHost(ctx context.Context, host string) ([]netip.Prefix, error) {
+	if prefix, err := parseAllowedIP(host); err == nil {
+		return []netip.Prefix{prefix}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return nil, fmt.Errorf("failed to resolve %s", host)
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(addrs))
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Addr().Less(prefixes[j].Addr()) })
+
+	return prefixes, nil
+}
+
+// validEgressHost accepts IP addresses, CIDR ranges and hostnames
+func validEgressHost(host string) bool {
+	if _, err := parseAllowedIP(host); err == nil {
+		return true
+	}
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
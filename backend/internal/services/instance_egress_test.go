@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestStartInstanceAppliesEgressPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     models.EgressPolicy
+		egressErr  error
+		wantCalls  []string
+		wantErr    bool
+		wantStatus string
+	}{
+		{
+			name:       "unrestricted",
+			policy:     models.EgressPolicy{Mode: models.EgressAllowAll},
+			wantCalls:  []string{"start container-stopped"},
+			wantStatus: models.InstanceStatusRunning,
+		},
+		{
+			name:       "restricted",
+			policy:     models.EgressPolicy{Mode: models.EgressDenyAll},
+			wantCalls:  []string{"start container-stopped", "egress container-stopped"},
+			wantStatus: models.InstanceStatusRunning,
+		},
+		{
+			name:       "rules fail to apply",
+			policy:     models.EgressPolicy{Mode: models.EgressDenyAll},
+			egressErr:  errors.New("firewall unavailable"),
+			wantCalls:  []string{"start container-stopped", "egress container-stopped", "stop container-stopped"},
+			wantErr:    true,
+			wantStatus: models.InstanceStatusStopped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			instance := newTestInstance(userID, models.InstanceStatusStopped)
+			instance.EgressPolicy = tt.policy
+			store := newFakeInstanceStore(instance)
+			runtime := &fakeRuntime{egressErr: tt.egressErr}
+
+			err := newTestInstanceService(store, runtime).StartInstance(context.Background(), instance.ID, userID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartInstance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(runtime.calls, tt.wantCalls) {
+				t.Errorf("container calls = %v, want %v", runtime.calls, tt.wantCalls)
+			}
+			if got := store.instances[instance.ID].Status; got != tt.wantStatus {
+				t.Errorf("status = %q, want %q", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRestartInstanceStopsContainerWithoutEgressRules(t *testing.T) {
+	userID := uuid.New()
+	instance := newTestInstance(userID, models.InstanceStatusRunning)
+	instance.EgressPolicy = models.EgressPolicy{Mode: models.EgressDenyAll}
+	store := newFakeInstanceStore(instance)
+	runtime := &fakeRuntime{egressErr: errors.New("firewall unavailable")}
+
+	err := newTestInstanceService(store, runtime).RestartInstance(context.Background(), instance.ID, userID)
+	if err == nil {
+		t.Fatal("RestartInstance() succeeded without applying the egress policy")
+	}
+
+	want := []string{"restart container-running", "egress container-running", "stop container-running"}
+	if !reflect.DeepEqual(runtime.calls, want) {
+		t.Errorf("container calls = %v, want %v", runtime.calls, want)
+	}
+	if got := store.instances[instance.ID].Status; got != models.InstanceStatusStopped {
+		t.Errorf("status = %q, want %q", got, models.InstanceStatusStopped)
+	}
+}
+
+func TestStartInstanceAuthorization(t *testing.T) {
+	instance := newTestInstance(uuid.New(), models.InstanceStatusStopped)
+	runtime := &fakeRuntime{}
+	service := newTestInstanceService(newFakeInstanceStore(instance), runtime)
+
+	err := service.StartInstance(context.Background(), instance.ID, uuid.New())
+	if err == nil || err.Error() != "instance not found" {
+		t.Fatalf("StartInstance() by another user error = %v, want instance not found", err)
+	}
+	if errors.Is(err, authz.ErrForbidden) {
+		t.Error("instances of other users must look missing, not forbidden")
+	}
+	if len(runtime.calls) != 0 {
+		t.Errorf("container calls = %v, want none", runtime.calls)
+	}
+}
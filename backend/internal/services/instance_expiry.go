@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// minInstanceTTL is the shortest lifetime an ephemeral instance may have
+const minInstanceTTL = time.Hour
+
+// ExtendInstance pushes back the expiry of an ephemeral instance by ttl. It
+// has to happen before the instance expires, and the remaining lifetime may
+// not exceed INSTANCE_MAX_TTL.
+func (s *InstanceService) ExtendInstance(ctx context.Context, instanceID, userID uuid.UUID, ttl time.Duration) (*models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.ExpiresAt == nil {
+		return nil, fmt.Errorf("instance does not expire")
+	}
+	if !instance.ExpiresAt.After(time.Now()) || instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance has expired")
+	}
+	if ttl < minInstanceTTL {
+		return nil, fmt.Errorf("ttl must be at least %s", minInstanceTTL)
+	}
+
+	expiresAt := instance.ExpiresAt.Add(ttl)
+	if maxTTL := s.config.Settings().InstanceMaxTTL; time.Until(expiresAt) > maxTTL {
+		return nil, fmt.Errorf("instance lifetime cannot exceed %s", maxTTL)
+	}
+
+	if err := s.store.ExtendExpiry(ctx, instance, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// validateTTL checks the lifetime an ephemeral instance is created with
+func (s *InstanceService) validateTTL(ttl time.Duration) error {
+	maxTTL := s.config.Settings().InstanceMaxTTL
+	if ttl < minInstanceTTL || ttl > maxTTL {
+		return fmt.Errorf("ttl must be between %s and %s", minInstanceTTL, maxTTL)
+	}
+	return nil
+}
+
+// processExpiredInstances archives ephemeral instances past their expiry,
+// keeping their data for the default retention period
+func (s *InstanceService) processExpiredInstances(ctx context.Context) {
+	instances, err := s.store.FindExpiredInstances(ctx)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+
+	for i := range instances {
+		instance := &instances[i]
+
+		retentionDays := s.config.Settings().InstanceDataRetentionDays
+		if _, err := s.archiveInstance(ctx, instance, instance.UserID, retentionDays, models.DeletionReasonExpired, ""); err != nil {
+			fmt.Printf("Warning: failed to archive expired instance %s: %v\n", instance.ID, err)
+		}
+	}
+}
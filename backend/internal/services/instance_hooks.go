@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultHooksDir is where an uploaded bundle goes when the instance has no hooks_dir
+	defaultHooksDir = "pb_hooks"
+
+	// maxHookFiles bounds the number of files in a hooks bundle
+	maxHookFiles = 200
+)
+
+// GetHooks lists the files of an instance's hooks bundle
+func (s *InstanceService) GetHooks(ctx context.Context, instanceID, userID uuid.UUID) (*Bundle, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
+	}
+
+	return readBundle(instance.DataPath, hooksDir(instance))
+}
+
+// UploadHooks replaces an instance's hooks with a zip bundle of .js and .json
+// files and restarts the instance to load them. The first upload points
+// --hooksDir at the bundle, which recreates the container.
+func (s *InstanceService) UploadHooks(ctx context.Context, instanceID, userID uuid.UUID, bundle []byte) (*Bundle, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.authorizeBundleChange(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.QuarantinedAt != nil {
+		return nil, fmt.Errorf("instance is quarantined")
+	}
+
+	dir := hooksDir(instance)
+	rules := s.hooksBundleRules()
+	rules.scan = s.bundleScan(ctx, instance, models.QuarantineBundleHooks)
+	if err := extractBundle(bundle, filepath.Join(instance.DataPath, dir), rules); err != nil {
+		return nil, err
+	}
+
+	if err := s.activateHooks(ctx, instance, dir); err != nil {
+		return nil, err
+	}
+
+	return readBundle(instance.DataPath, dir)
+}
+
+// activateHooks loads a newly installed hooks bundle
+func (s *InstanceService) activateHooks(ctx context.Context, instance *models.Instance, dir string) error {
+	if instance.ServeOptions.HooksDir == "" {
+		options := instance.ServeOptions
+		options.HooksDir = dir
+		return s.applyServeOptions(ctx, instance, options)
+	}
+	return s.reloadHooks(ctx, instance)
+}
+
+// DeleteHooks removes an instance's hooks bundle and restarts the instance
+func (s *InstanceService) DeleteHooks(ctx context.Context, instanceID, userID uuid.UUID) error {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	instance, err := s.authorizeBundleChange(ctx, instanceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(instance.DataPath, hooksDir(instance))); err != nil {
+		return fmt.Errorf("failed to remove hooks: %w", err)
+	}
+
+	return s.reloadHooks(ctx, instance)
+}
+
+// authorizeBundleChange loads an instance whose hooks or public files the user may change
+func (s *InstanceService) authorizeBundleChange(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	return instance, nil
+}
+
+// reloadHooks restarts a running instance so PocketBase loads its hooks again
+// (a stopped instance loads them when started)
+func (s *InstanceService) reloadHooks(ctx context.Context, instance *models.Instance) error {
+	if instance.Status != models.InstanceStatusRunning {
+		return nil
+	}
+
+	if err := s.restartContainer(ctx, instance); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+
+	return nil
+}
+
+// hooksDir returns the hooks directory of an instance, relative to its data directory
+func hooksDir(instance *models.Instance) string {
+	if instance.ServeOptions.HooksDir != "" {
+		return instance.ServeOptions.HooksDir
+	}
+	return defaultHooksDir
+}
+
+// hooksBundleRules accept .js and .json files with at least one *.pb.js hook
+func (s *InstanceService) hooksBundleRules() bundleRules {
+	return bundleRules{
+		maxSize:  s.config.HooksMaxSize,
+		maxFiles: maxHookFiles,
+		topDir:   defaultHooksDir,
+		checkFile: func(name string) error {
+			if ext := path.Ext(name); ext != ".js" && ext != ".json" {
+				return fmt.Errorf("%w: %s is not a .js or .json file", ErrInvalidBundle, name)
+			}
+			return nil
+		},
+		checkBundle: func(names []string) error {
+			for _, name := range names {
+				if strings.HasSuffix(name, ".pb.js") {
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: no *.pb.js file", ErrInvalidBundle)
+		},
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// integrityCheckHistory is the number of integrity checks kept per instance
+	integrityCheckHistory = 50
+
+	// integrityCheckMaxResult bounds the stored SQLite report
+	integrityCheckMaxResult = 16384
+
+	// integrityCheckSweep is how often the scheduler looks for instances due
+	// for a check, and integrityCheckBatch how many it checks each time, so
+	// checks spread out instead of all running at once
+	integrityCheckSweep = 10 * time.Minute
+	integrityCheckBatch = 10
+)
+
+// ErrNoDatabase is returned when an instance has not created its data.db yet
+var ErrNoDatabase = docker.ErrNoDatabase
+
+// CheckIntegrity runs `PRAGMA integrity_check` against an instance's data.db
+// and records the result. The owner is notified when corruption is found.
+func (s *InstanceService) CheckIntegrity(ctx context.Context, instanceID, userID uuid.UUID) (*models.InstanceIntegrityCheck, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	triggeredBy := userID.String()
+	return s.checkIntegrity(ctx, instance, &triggeredBy)
+}
+
+// ListIntegrityChecks returns an instance's integrity check history, newest first
+func (s *InstanceService) ListIntegrityChecks(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceIntegrityCheck, error) {
+	if _, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView); err != nil {
+		return nil, err
+	}
+
+	return s.store.FindIntegrityChecks(ctx, instanceID)
+}
+
+// RunIntegrityCheckWorker checks every running or stopped instance once per
+// INTEGRITY_CHECK_INTERVAL until ctx is cancelled
+func (s *InstanceService) RunIntegrityCheckWorker(ctx context.Context) {
+	if s.config.IntegrityCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(integrityCheckSweep)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processDueIntegrityChecks(ctx)
+		}
+	}
+}
+
+func (s *InstanceService) processDueIntegrityChecks(ctx context.Context) {
+	since := time.Now().UTC().Add(-s.config.IntegrityCheckInterval)
+	instances, err := s.store.FindInstancesDueForIntegrityCheck(ctx, since, integrityCheckBatch)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+
+	for i := range instances {
+		if _, err := s.checkIntegrity(ctx, &instances[i], nil); err != nil && !errors.Is(err, ErrNoDatabase) {
+			log.Printf("Warning: integrity check of instance %s failed: %v", instances[i].ID, err)
+		}
+	}
+}
+
+// checkIntegrity runs and records a check. Checks that could not run are
+// recorded with their error, so the scheduler doesn't retry them right away.
+func (s *InstanceService) checkIntegrity(ctx context.Context, instance *models.Instance, triggeredBy *string) (*models.InstanceIntegrityCheck, error) {
+	check := &models.InstanceIntegrityCheck{
+		InstanceID:  instance.ID,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now().UTC(),
+	}
+
+	result, checkErr := s.dockerClient.CheckDatabaseIntegrity(ctx, instance.DataPath)
+	check.FinishedAt = time.Now().UTC()
+	check.OK = checkErr == nil && result == "ok"
+	if result != "" {
+		if len(result) > integrityCheckMaxResult {
+			result = result[:integrityCheckMaxResult]
+		}
+		check.Result = &result
+	}
+	if checkErr != nil {
+		message := checkErr.Error()
+		check.Error = &message
+	}
+
+	if err := s.store.RecordIntegrityCheck(ctx, check, integrityCheckHistory); err != nil {
+		return nil, err
+	}
+
+	if checkErr != nil {
+		return check, checkErr
+	}
+
+	if check.Corrupt() {
+		s.notifier.DatabaseCorrupted(ctx, instance, result)
+	}
+
+	return check, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultMigrationsDir is where an uploaded bundle goes when the instance has no migrations_dir
+	defaultMigrationsDir = "pb_migrations"
+
+	// maxMigrationFiles bounds the number of files in a migrations bundle
+	maxMigrationFiles = 1000
+
+	// migrationRunHistory is the number of migration runs kept per instance
+	migrationRunHistory = 50
+
+	// migrationMaxOutput bounds the stored output of a run
+	migrationMaxOutput = 16384
+)
+
+// ErrMigrationsFailed is returned (wrapped with the cause) when `migrate up`
+// fails; the run is still recorded
+var ErrMigrationsFailed = errors.New("migrations failed")
+
+// ansiEscape matches terminal color codes in PocketBase output
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// GetMigrations lists the files of an instance's migrations bundle
+func (s *InstanceService) GetMigrations(ctx context.Context, instanceID, userID uuid.UUID) (*Bundle, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
+	}
+
+	return readBundle(instance.DataPath, migrationsDir(instance))
+}
+
+// UploadMigrations replaces an instance's JS migrations with a zip bundle of
+// .js files. Migrations are not applied until RunMigrations, except that the
+// first upload points --migrationsDir at the bundle, which recreates the
+// container, and PocketBase applies pending migrations when it starts.
+func (s *InstanceService) UploadMigrations(ctx context.Context, instanceID, userID uuid.UUID, bundle []byte) (*Bundle, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.authorizeBundleChange(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.QuarantinedAt != nil {
+		return nil, fmt.Errorf("instance is quarantined")
+	}
+
+	dir := migrationsDir(instance)
+	rules := s.migrationsBundleRules()
+	rules.scan = s.bundleScan(ctx, instance, models.QuarantineBundleMigrations)
+	if err := extractBundle(bundle, filepath.Join(instance.DataPath, dir), rules); err != nil {
+		return nil, err
+	}
+
+	if err := s.activateMigrations(ctx, instance, dir); err != nil {
+		return nil, err
+	}
+
+	return readBundle(instance.DataPath, dir)
+}
+
+// RunMigrations applies an instance's pending migrations with `pocketbase
+// migrate up` and records the run. A failed run is returned along with an
+// error wrapping ErrMigrationsFailed.
+func (s *InstanceService) RunMigrations(ctx context.Context, instanceID, userID uuid.UUID) (*models.InstanceMigrationRun, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status != models.InstanceStatusRunning {
+		return nil, fmt.Errorf("instance is not running")
+	}
+
+	flags, err := s.serveFlags(ctx, instance.ID, instance.ServeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	triggeredBy := userID.String()
+	run := &models.InstanceMigrationRun{
+		InstanceID:  instance.ID,
+		TriggeredBy: &triggeredBy,
+		StartedAt:   time.Now().UTC(),
+	}
+
+	output, runErr := s.dockerClient.MigrateUp(ctx, *instance.ContainerID, flags)
+	output = ansiEscape.ReplaceAllString(output, "")
+
+	run.FinishedAt = time.Now().UTC()
+	run.Success = runErr == nil
+	run.Applied = appliedMigrations(output)
+	if output != "" {
+		output = outputTail(output, migrationMaxOutput)
+		run.Output = &output
+	}
+	if runErr != nil {
+		message := runErr.Error()
+		run.Error = &message
+	}
+
+	if err := s.store.RecordMigrationRun(ctx, run, migrationRunHistory); err != nil {
+		return nil, err
+	}
+
+	if runErr != nil {
+		return run, fmt.Errorf("%w: %v", ErrMigrationsFailed, runErr)
+	}
+
+	return run, nil
+}
+
+// ListMigrationRuns returns an instance's migration run history, newest first
+func (s *InstanceService) ListMigrationRuns(ctx context.Context, instanceID, userID uuid.UUID) ([]models.InstanceMigrationRun, error) {
+	if _, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView); err != nil {
+		return nil, err
+	}
+
+	return s.store.FindMigrationRuns(ctx, instanceID)
+}
+
+// activateMigrations points --migrationsDir at a newly installed migrations
+// bundle (once set, `migrate up` picks up new files without a restart)
+func (s *InstanceService) activateMigrations(ctx context.Context, instance *models.Instance, dir string) error {
+	if instance.ServeOptions.MigrationsDir != "" {
+		return nil
+	}
+	options := instance.ServeOptions
+	options.MigrationsDir = dir
+	return s.applyServeOptions(ctx, instance, options)
+}
+
+// migrationsDir returns the migrations directory of an instance, relative to its data directory
+func migrationsDir(instance *models.Instance) string {
+	if instance.ServeOptions.MigrationsDir != "" {
+		return instance.ServeOptions.MigrationsDir
+	}
+	return defaultMigrationsDir
+}
+
+// migrationsBundleRules accept .js migration files (Go migrations are
+// compiled into the PocketBase binary and can't be uploaded)
+func (s *InstanceService) migrationsBundleRules() bundleRules {
+	return bundleRules{
+		maxSize:  s.config.MigrationsMaxSize,
+		maxFiles: maxMigrationFiles,
+		topDir:   defaultMigrationsDir,
+		checkFile: func(name string) error {
+			if path.Ext(name) != ".js" {
+				return fmt.Errorf("%w: %s is not a .js file", ErrInvalidBundle, name)
+			}
+			return nil
+		},
+	}
+}
+
+// appliedMigrations parses the "Applied <file>" lines of `migrate up` output
+func appliedMigrations(output string) []string {
+	applied := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if file, ok := strings.CutPrefix(strings.TrimSpace(line), "Applied "); ok {
+			applied = append(applied, strings.TrimSpace(file))
+		}
+	}
+	return applied
+}
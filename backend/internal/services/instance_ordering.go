@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SetInstancePinned pins or unpins an instance in its owner's list
+func (s *InstanceService) SetInstancePinned(ctx context.Context, instanceID, userID uuid.UUID, pinned bool) (*models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.Pinned == pinned {
+		return instance, nil
+	}
+
+	if err := s.store.SetPinned(ctx, instance, pinned); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// ReorderInstances stores the order the user wants their instances listed in.
+// Instances missing from instanceIDs are listed after the ordered ones, newest
+// first; pinned instances always come first.
+func (s *InstanceService) ReorderInstances(ctx context.Context, userID uuid.UUID, instanceIDs []uuid.UUID) ([]models.Instance, error) {
+	seen := make(map[uuid.UUID]bool, len(instanceIDs))
+	for _, id := range instanceIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("instance_ids must not contain duplicates")
+		}
+		seen[id] = true
+	}
+
+	if err := s.store.ReorderInstances(ctx, userID, instanceIDs); err != nil {
+		return nil, err
+	}
+
+	return s.ListUserInstances(ctx, userID)
+}
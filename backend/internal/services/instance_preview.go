@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultPreviewTTL is how long a preview lives unless asked otherwise
+	// (capped at INSTANCE_MAX_TTL)
+	defaultPreviewTTL = 7 * 24 * time.Hour
+
+	// previewSkippedDir holds PocketBase backups, which aren't cloned
+	previewSkippedDir = "backups"
+)
+
+// previewRefPattern matches external refs such as "pr-42", "42" or "feature.login"
+var previewRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// CreatePreviewRequest describes a preview environment of an instance
+type CreatePreviewRequest struct {
+	Ref string        // external ref the preview is for, e.g. a pull request number
+	TTL time.Duration // 0 uses the default
+}
+
+// PreviewResult is a preview environment and whether this request created it
+type PreviewResult struct {
+	Instance *models.Instance
+	URL      string
+	Created  bool
+}
+
+// CreatePreview clones an instance into a short-lived preview environment for
+// an external ref. Asking again for the same ref returns the existing preview,
+// so CI pipelines can call it on every push.
+func (s *InstanceService) CreatePreview(ctx context.Context, sourceID, userID uuid.UUID, req CreatePreviewRequest) (*PreviewResult, error) {
+	source, err := s.AuthorizeInstance(ctx, sourceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if !previewRefPattern.MatchString(req.Ref) {
+		return nil, fmt.Errorf("ref must be 1-64 letters, numbers, dots, hyphens or underscores")
+	}
+
+	if existing, err := s.store.FindPreview(ctx, source.ID, req.Ref); err == nil {
+		return &PreviewResult{Instance: existing, URL: s.InstanceURL(existing)}, nil
+	} else if !errors.Is(err, models.ErrPreviewNotFound) {
+		return nil, err
+	}
+
+	if source.PreviewOf != nil {
+		return nil, fmt.Errorf("previews cannot be created from a preview")
+	}
+	if source.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = min(defaultPreviewTTL, s.config.Settings().InstanceMaxTTL)
+	}
+
+	// The preview belongs to the source's owner, whose username its storage path starts with
+	result, err := s.CreateInstance(ctx, CreateInstanceRequest{
+		UserID:     source.UserID,
+		Username:   filepath.Base(filepath.Dir(source.DataPath)),
+		Name:       previewName(source.Name, req.Ref),
+		RegionID:   source.RegionID,
+		TTL:        ttl,
+		CloneFrom:  source,
+		PreviewRef: req.Ref,
+	})
+	if err != nil {
+		// Lost a race with a concurrent request for the same ref
+		if errors.Is(err, models.ErrPreviewExists) {
+			if existing, findErr := s.store.FindPreview(ctx, source.ID, req.Ref); findErr == nil {
+				return &PreviewResult{Instance: existing, URL: s.InstanceURL(existing)}, nil
+			}
+		}
+		return nil, err
+	}
+
+	return &PreviewResult{Instance: result.Instance, URL: result.URL, Created: true}, nil
+}
+
+// ListPreviews returns the preview environments of an instance
+func (s *InstanceService) ListPreviews(ctx context.Context, sourceID, userID uuid.UUID) ([]models.Instance, error) {
+	source, err := s.AuthorizeInstance(ctx, sourceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.FindPreviews(ctx, source.ID)
+}
+
+// DeletePreview tears down the preview environment of an instance for a ref
+// (e.g. once its pull request is closed). Its data is deleted right away.
+func (s *InstanceService) DeletePreview(ctx context.Context, sourceID, userID uuid.UUID, ref string) (*models.ArchivedInstance, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "delete")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	source, err := s.AuthorizeInstance(ctx, sourceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := s.store.FindPreview(ctx, source.ID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.archiveInstance(ctx, preview, userID, 0, models.DeletionReasonPreview, "")
+}
+
+// prepareClone copies the source instance's data into a new instance's
+// storage path and carries over its serve options (with the settings
+// encryption key) and access protection. The clone keeps the source's
+// superusers, so none is created.
+func (s *InstanceService) prepareClone(ctx context.Context, source, instance *models.Instance, cfg *docker.ContainerConfig) error {
+	if err := copyDataDir(source.DataPath, instance.DataPath); err != nil {
+		return fmt.Errorf("failed to copy instance data: %w", err)
+	}
+
+	flags, err := s.serveFlags(ctx, source.ID, source.ServeOptions)
+	if err != nil {
+		return err
+	}
+	if flags.EncryptionKey != "" {
+		if _, err := s.store.EnsureEncryptionKey(ctx, instance.ID, flags.EncryptionKey); err != nil {
+			return err
+		}
+	}
+	if err := s.store.UpdateServeOptions(ctx, instance, source.ServeOptions); err != nil {
+		return err
+	}
+	cfg.Serve = flags
+
+	protection := source.AccessProtection
+	if protection.Username != "" || len(protection.AllowedIPs) > 0 {
+		passwordHash, err := s.store.FindAccessPasswordHash(ctx, source.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.store.UpdateAccessProtection(ctx, instance, protection, passwordHash); err != nil {
+			return err
+		}
+
+		cfg.Access = docker.AccessRules{AllowedIPs: protection.AllowedIPs}
+		if protection.Username != "" {
+			cfg.Access.BasicAuthUser = protection.Username + ":" + passwordHash
+		}
+	}
+
+	cfg.AdminEmail, cfg.AdminPassword = "", ""
+	return nil
+}
+
+// previewName names a preview after its source and ref, within the instance
+// name rules
+func previewName(sourceName, ref string) string {
+	ref = strings.NewReplacer(".", "-").Replace(ref)
+	name := sourceName + " " + ref
+	if utf8.RuneCountInString(name) > 100 {
+		runes := []rune(sourceName)
+		name = string(runes[:100-len(ref)-1]) + " " + ref
+	}
+	return name
+}
+
+// copyDataDir copies an instance's data directory, except its backups, to a
+// new one. The source keeps running, so a write landing mid-copy may leave
+// the clone a little behind it; PocketBase replays the copied SQLite WAL on
+// start.
+func copyDataDir(source, target string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && rel == previewSkippedDir {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(target, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(dest, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, dest, info.Mode().Perm())
+		default:
+			// Symlinks and sockets aren't part of an instance's data
+			return nil
+		}
+	})
+}
+
+// copyFile copies a regular file
+func copyFile(source, target string, perm fs.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
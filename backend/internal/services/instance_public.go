@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultPublicDir is where an uploaded site goes when the instance has no public_dir
+	defaultPublicDir = "pb_public"
+
+	// maxPublicFiles bounds the number of files in a static site bundle
+	maxPublicFiles = 5000
+)
+
+// GetPublicFiles lists the files of an instance's static site
+func (s *InstanceService) GetPublicFiles(ctx context.Context, instanceID, userID uuid.UUID) (*Bundle, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
+	}
+
+	return readBundle(instance.DataPath, publicDir(instance))
+}
+
+// UploadPublicFiles replaces an instance's static site with a zip bundle.
+// PocketBase serves the directory from disk, so later uploads are live at
+// once; the first upload points --publicDir at it, which recreates the
+// container.
+func (s *InstanceService) UploadPublicFiles(ctx context.Context, instanceID, userID uuid.UUID, bundle []byte) (*Bundle, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.authorizeBundleChange(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.QuarantinedAt != nil {
+		return nil, fmt.Errorf("instance is quarantined")
+	}
+
+	dir := publicDir(instance)
+	rules := s.publicBundleRules()
+	rules.scan = s.bundleScan(ctx, instance, models.QuarantineBundlePublic)
+	if err := extractBundle(bundle, filepath.Join(instance.DataPath, dir), rules); err != nil {
+		return nil, err
+	}
+
+	if err := s.activatePublicFiles(ctx, instance, dir); err != nil {
+		return nil, err
+	}
+
+	return readBundle(instance.DataPath, dir)
+}
+
+// activatePublicFiles points --publicDir at a newly installed static site
+// (once set, PocketBase picks up changes without a restart)
+func (s *InstanceService) activatePublicFiles(ctx context.Context, instance *models.Instance, dir string) error {
+	if instance.ServeOptions.PublicDir != "" {
+		return nil
+	}
+	options := instance.ServeOptions
+	options.PublicDir = dir
+	return s.applyServeOptions(ctx, instance, options)
+}
+
+// DeletePublicFiles removes an instance's static site
+func (s *InstanceService) DeletePublicFiles(ctx context.Context, instanceID, userID uuid.UUID) error {
+	instance, err := s.authorizeBundleChange(ctx, instanceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(instance.DataPath, publicDir(instance))); err != nil {
+		return fmt.Errorf("failed to remove public files: %w", err)
+	}
+
+	return nil
+}
+
+// publicDir returns the static site directory of an instance, relative to its data directory
+func publicDir(instance *models.Instance) string {
+	if instance.ServeOptions.PublicDir != "" {
+		return instance.ServeOptions.PublicDir
+	}
+	return defaultPublicDir
+}
+
+// publicBundleRules accept any regular files
+func (s *InstanceService) publicBundleRules() bundleRules {
+	return bundleRules{
+		maxSize:  s.config.PublicMaxSize,
+		maxFiles: maxPublicFiles,
+		topDir:   defaultPublicDir,
+	}
+}
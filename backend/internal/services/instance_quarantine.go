@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/scanner"
+
+	"github.com/google/uuid"
+)
+
+// ErrBundleQuarantined is returned (wrapped with the finding) for uploaded
+// bundles the malware scanner flagged
+var ErrBundleQuarantined = errors.New("bundle quarantined")
+
+// ErrScannerUnavailable is returned when an upload can't be scanned; bundles
+// are never installed unscanned while scanning is enabled
+var ErrScannerUnavailable = errors.New("malware scanner is unavailable")
+
+// bundleScan returns the scan step for a bundle upload: every extracted file
+// is streamed to the scanner, and a flagged bundle is moved to the quarantine
+// directory and recorded on the instance instead of being installed. It
+// returns nil when scanning is disabled.
+func (s *InstanceService) bundleScan(ctx context.Context, instance *models.Instance, kind string) func(staging string) error {
+	if s.scanner == nil {
+		return nil
+	}
+
+	return func(staging string) error {
+		name, result, err := scanDir(ctx, s.scanner, staging)
+		if err != nil {
+			log.Printf("Warning: failed to scan bundle for instance %s: %v", instance.ID, err)
+			return ErrScannerUnavailable
+		}
+		if result == nil {
+			return nil
+		}
+
+		reason := fmt.Sprintf("%s: %s", name, result.Signature)
+		target := s.quarantinePath(instance.ID)
+		_ = os.RemoveAll(target)
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+		if err := os.Rename(staging, target); err != nil {
+			return fmt.Errorf("failed to quarantine bundle: %w", err)
+		}
+
+		if err := s.store.Quarantine(ctx, instance, kind, reason); err != nil {
+			return err
+		}
+
+		log.Printf("Instance %s (%s): %s bundle quarantined (%s)", instance.Name, instance.ID, kind, reason)
+		return fmt.Errorf("%w: %s", ErrBundleQuarantined, reason)
+	}
+}
+
+// ReleaseQuarantine resolves an instance's quarantined bundle. Approving
+// installs it as if the upload had passed the scan (for false positives);
+// otherwise it is deleted. Either way the owner may upload again.
+func (s *InstanceService) ReleaseQuarantine(ctx context.Context, instanceID uuid.UUID, approve bool) (*models.Instance, error) {
+	instance, err := s.store.FindInstanceByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.QuarantinedAt == nil || instance.QuarantineBundle == nil {
+		return nil, fmt.Errorf("instance is not quarantined")
+	}
+
+	source := s.quarantinePath(instance.ID)
+	if approve {
+		if err := s.installQuarantinedBundle(ctx, instance, *instance.QuarantineBundle, source); err != nil {
+			return nil, err
+		}
+	} else if err := os.RemoveAll(source); err != nil {
+		return nil, fmt.Errorf("failed to remove quarantined bundle: %w", err)
+	}
+
+	if err := s.store.ClearQuarantine(ctx, instance); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Instance quarantine lifted: %s (approved: %t)\n", instance.Name, approve)
+	return instance, nil
+}
+
+// installQuarantinedBundle moves an approved bundle into the instance's data
+// directory and activates it
+func (s *InstanceService) installQuarantinedBundle(ctx context.Context, instance *models.Instance, kind, source string) error {
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return fmt.Errorf("instance has no container")
+	}
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return fmt.Errorf("instance is pending deletion")
+	}
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("quarantined bundle is missing: %w", err)
+	}
+
+	switch kind {
+	case models.QuarantineBundleHooks:
+		dir := hooksDir(instance)
+		if err := replaceBundleDir(source, filepath.Join(instance.DataPath, dir)); err != nil {
+			return err
+		}
+		return s.activateHooks(ctx, instance, dir)
+	case models.QuarantineBundleMigrations:
+		dir := migrationsDir(instance)
+		if err := replaceBundleDir(source, filepath.Join(instance.DataPath, dir)); err != nil {
+			return err
+		}
+		return s.activateMigrations(ctx, instance, dir)
+	case models.QuarantineBundlePublic:
+		dir := publicDir(instance)
+		if err := replaceBundleDir(source, filepath.Join(instance.DataPath, dir)); err != nil {
+			return err
+		}
+		return s.activatePublicFiles(ctx, instance, dir)
+	default:
+		return fmt.Errorf("unknown quarantined bundle kind: %s", kind)
+	}
+}
+
+// quarantinePath returns where an instance's quarantined bundle is kept
+func (s *InstanceService) quarantinePath(instanceID uuid.UUID) string {
+	return filepath.Join(s.config.QuarantinePath, instanceID.String())
+}
+
+// scanDir scans the regular files under root, stopping at the first finding.
+// It returns the flagged file's path relative to root and the result, or a
+// nil result when every file is clean.
+func scanDir(ctx context.Context, sc scanner.Scanner, root string) (string, *scanner.Result, error) {
+	var flagged string
+	var finding *scanner.Result
+
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		result, err := sc.Scan(ctx, file)
+		if err != nil {
+			return err
+		}
+		if result.Infected {
+			rel, _ := filepath.Rel(root, p)
+			flagged, finding = filepath.ToSlash(rel), result
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return flagged, finding, nil
+}
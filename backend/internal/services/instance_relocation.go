@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pocketploy/internal/docker"
+	"pocketploy/internal/events"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Relocation steps reported to the administrator and the owner
+const (
+	RelocationStopping            = "stopping"
+	RelocationCopying             = "copying"
+	RelocationVerifying           = "verifying"
+	RelocationRecreatingContainer = "recreating_container"
+	RelocationStarting            = "starting"
+	RelocationDone                = "done"
+	RelocationFailed              = "failed"
+)
+
+// relocationReportEvery is how many copied bytes pass between progress reports
+const relocationReportEvery = 64 << 20
+
+// ErrInvalidRelocationTarget is returned (wrapped with the reason) when a
+// data directory can't be moved to the requested path
+var ErrInvalidRelocationTarget = errors.New("invalid relocation target")
+
+// ErrRelocationFailed is returned (wrapped with the cause) when a relocation
+// was rolled back; the instance keeps its previous data directory
+var ErrRelocationFailed = errors.New("relocation failed")
+
+// RelocationProgress is pushed while an instance's data is moved
+type RelocationProgress struct {
+	InstanceID  uuid.UUID `json:"instance_id"`
+	Name        string    `json:"name"`
+	Step        string    `json:"step"`
+	CopiedBytes int64     `json:"copied_bytes,omitempty"`
+	TotalBytes  int64     `json:"total_bytes,omitempty"`
+}
+
+// RelocationResult reports a completed relocation
+type RelocationResult struct {
+	Instance     *models.Instance `json:"instance"`
+	PreviousPath string           `json:"previous_path"`
+	Files        int              `json:"files"`
+	Bytes        int64            `json:"bytes"`
+}
+
+// RelocateStorage moves an instance's data directory to targetPath, e.g. onto
+// another disk: the container is stopped, the data copied and every file's
+// SHA-256 checksum verified, the container recreated with the new mount and
+// started again. The previous directory is only removed once the instance has
+// booted from the new one; any failure before that rolls back. targetPath
+// must not exist yet and lie under INSTANCES_BASE_PATH or STORAGE_VOLUMES.
+// Instances stay on the configured Docker host. It is meant for admins, so no
+// user is authorized; progress is pushed to adminID and the owner.
+func (s *InstanceService) RelocateStorage(ctx context.Context, instanceID, adminID uuid.UUID, targetPath string) (*RelocationResult, error) {
+	instance, err := s.store.FindInstanceByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Limit concurrent Docker operations per owner
+	release, err := s.operations.Acquire(instance.UserID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+	if instance.Status != models.InstanceStatusRunning && instance.Status != models.InstanceStatusStopped {
+		return nil, fmt.Errorf("instance must be running or stopped to be relocated")
+	}
+
+	source, err := filepath.Abs(instance.DataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve data path: %w", err)
+	}
+	target, err := s.relocationTarget(source, targetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := docker.DirectorySize(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure instance data: %w", err)
+	}
+	if err := s.checkDiskHeadroom(target, total, "relocate the instance"); err != nil {
+		return nil, err
+	}
+
+	report := func(step string, copied int64) {
+		progress := RelocationProgress{
+			InstanceID:  instance.ID,
+			Name:        instance.Name,
+			Step:        step,
+			CopiedBytes: copied,
+			TotalBytes:  total,
+		}
+		s.events.Publish(ctx, adminID.String(), events.TypeInstanceRelocation, progress)
+		if instance.UserID != adminID {
+			s.events.Publish(ctx, instance.UserID.String(), events.TypeInstanceRelocation, progress)
+		}
+	}
+
+	wasRunning := instance.Status == models.InstanceStatusRunning
+	if wasRunning {
+		report(RelocationStopping, 0)
+		if err := s.dockerClient.StopContainer(ctx, *instance.ContainerID); err != nil {
+			return nil, fmt.Errorf("failed to stop container: %w", err)
+		}
+		if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusStopped); err != nil {
+			return nil, fmt.Errorf("failed to update instance status: %w", err)
+		}
+	}
+
+	report(RelocationCopying, 0)
+	checksums, copied, err := copyWithChecksums(source, target, func(copied int64) {
+		report(RelocationCopying, copied)
+	})
+	if err != nil {
+		report(RelocationFailed, 0)
+		return nil, s.abortRelocation(ctx, instance, target, wasRunning, err)
+	}
+
+	report(RelocationVerifying, copied)
+	if err := verifyChecksums(target, checksums); err != nil {
+		report(RelocationFailed, 0)
+		return nil, s.abortRelocation(ctx, instance, target, wasRunning, err)
+	}
+	s.dockerClient.ChownStoragePath(target)
+
+	report(RelocationRecreatingContainer, copied)
+	if err := s.switchStorage(ctx, instance, target); err != nil {
+		report(RelocationFailed, 0)
+		return nil, s.abortRelocation(ctx, instance, target, wasRunning, err)
+	}
+
+	if wasRunning {
+		report(RelocationStarting, copied)
+		err := s.startContainer(ctx, instance)
+		if err == nil {
+			err = s.waitForBoot(ctx, *instance.ContainerID)
+		}
+		if err != nil {
+			report(RelocationFailed, 0)
+			_ = s.dockerClient.StopContainer(ctx, *instance.ContainerID)
+			if switchErr := s.switchStorage(ctx, instance, source); switchErr != nil {
+				return nil, fmt.Errorf("relocation failed (%v) and so did switching back to %s: %w", err, source, switchErr)
+			}
+			return nil, s.abortRelocation(ctx, instance, target, wasRunning, err)
+		}
+		if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning); err != nil {
+			return nil, fmt.Errorf("failed to update instance status: %w", err)
+		}
+	}
+
+	if err := os.RemoveAll(source); err != nil {
+		fmt.Printf("Warning: failed to remove previous data of instance %s at %s: %v\n", instance.ID, source, err)
+	}
+	report(RelocationDone, copied)
+
+	fmt.Printf("Instance relocated: %s (%s -> %s)\n", instance.Name, source, target)
+	return &RelocationResult{
+		Instance:     instance,
+		PreviousPath: source,
+		Files:        len(checksums),
+		Bytes:        copied,
+	}, nil
+}
+
+// relocationTarget validates a requested data directory: an absolute path
+// under one of the storage roots that doesn't exist yet and is outside the
+// current one
+func (s *InstanceService) relocationTarget(source, targetPath string) (string, error) {
+	if !filepath.IsAbs(targetPath) {
+		return "", fmt.Errorf("%w: path must be absolute", ErrInvalidRelocationTarget)
+	}
+	target := filepath.Clean(targetPath)
+
+	if storageRoot(s.config.StorageRoots(), target) == "" {
+		return "", fmt.Errorf("%w: path must be inside INSTANCES_BASE_PATH or STORAGE_VOLUMES", ErrInvalidRelocationTarget)
+	}
+	if target == source || strings.HasPrefix(target, source+string(filepath.Separator)) || strings.HasPrefix(source, target+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: path overlaps the current data directory", ErrInvalidRelocationTarget)
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("%w: path already exists", ErrInvalidRelocationTarget)
+	}
+	if info, err := os.Stat(filepath.Dir(target)); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: parent directory does not exist", ErrInvalidRelocationTarget)
+	}
+
+	return target, nil
+}
+
+// storageRoot returns the storage root containing path, or "" if none does
+func storageRoot(roots []string, path string) string {
+	for _, root := range roots {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return ""
+}
+
+// switchStorage recreates an instance's container with its data mounted from
+// dataPath and saves the new path
+func (s *InstanceService) switchStorage(ctx context.Context, instance *models.Instance, dataPath string) error {
+	containerID, err := s.dockerClient.RelocateStorage(ctx, *instance.ContainerID, dataPath)
+	if updateErr := s.recordRecreatedContainer(ctx, instance, containerID); updateErr != nil {
+		return updateErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to recreate container: %w", err)
+	}
+
+	return s.store.UpdateDataPath(ctx, instance, dataPath)
+}
+
+// abortRelocation removes the partial copy at target and starts the instance
+// again if it was running. It returns cause wrapped with ErrRelocationFailed.
+func (s *InstanceService) abortRelocation(ctx context.Context, instance *models.Instance, target string, wasRunning bool, cause error) error {
+	if err := os.RemoveAll(target); err != nil {
+		fmt.Printf("Warning: failed to remove partial copy %s: %v\n", target, err)
+	}
+
+	if wasRunning {
+		if err := s.startContainer(ctx, instance); err != nil {
+			return fmt.Errorf("%w: %v (starting the instance again failed: %v)", ErrRelocationFailed, cause, err)
+		}
+		if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning); err != nil {
+			return fmt.Errorf("%w: %v", ErrRelocationFailed, cause)
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrRelocationFailed, cause)
+}
+
+// copyWithChecksums copies a data directory, recording the SHA-256 checksum of
+// every regular file read from the source. progress is called every
+// relocationReportEvery bytes. It returns the checksums by relative path and
+// the bytes copied.
+func copyWithChecksums(source, target string, progress func(copied int64)) (map[string]string, int64, error) {
+	checksums := make(map[string]string)
+	var copied, reported int64
+
+	err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(target, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(dest, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			sum, n, err := copyFileWithChecksum(path, dest, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			checksums[rel] = sum
+			copied += n
+			if copied-reported >= relocationReportEvery {
+				reported = copied
+				progress(copied)
+			}
+			return nil
+		default:
+			// Symlinks and sockets aren't part of an instance's data
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to copy instance data: %w", err)
+	}
+
+	return checksums, copied, nil
+}
+
+// copyFileWithChecksum copies a regular file, syncing it to disk, and returns
+// the checksum of what was read and the bytes copied
+func copyFileWithChecksum(source, target string, perm fs.FileMode) (string, int64, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return "", 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(out, io.TeeReader(in, hash))
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
+
+// verifyChecksums reads every copied file back and compares it with the
+// checksum of its source
+func verifyChecksums(target string, checksums map[string]string) error {
+	for rel, expected := range checksums {
+		file, err := os.Open(filepath.Join(target, rel))
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", rel, err)
+		}
+
+		hash := sha256.New()
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", rel, err)
+		}
+
+		if hex.EncodeToString(hash.Sum(nil)) != expected {
+			return fmt.Errorf("checksum mismatch for %s", rel)
+		}
+	}
+
+	return nil
+}
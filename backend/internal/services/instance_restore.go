@@ -0,0 +1,551 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/datadir"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// backupsDir is where PocketBase keeps its backups, relative to the data directory
+	backupsDir = "backups"
+
+	// safetyBackupPrefix names the copies of the data taken before a restore
+	safetyBackupPrefix = "pocketploy_pre_restore_"
+
+	// restoreBootTimeout bounds how long a restored instance may take to come up
+	restoreBootTimeout = 2 * time.Minute
+
+	// restoreBootGrace is how long a container without a health check must
+	// stay up to count as booted
+	restoreBootGrace = 10 * time.Second
+)
+
+// restoreKeptDirs are left in place by a restore: the backup catalog itself
+// and the shipped container logs (LOG_SHIPPER=file)
+var restoreKeptDirs = map[string]bool{backupsDir: true, "logs": true}
+
+// ErrBackupNotFound is returned for unknown backup IDs
+var ErrBackupNotFound = errors.New("backup not found")
+
+// ErrRestoreFailed is returned (wrapped with the cause) when the restored
+// instance didn't boot and the previous data was put back
+var ErrRestoreFailed = errors.New("restored instance failed to start, previous data was restored")
+
+// InstanceBackup is a PocketBase backup archive in an instance's backups directory
+type InstanceBackup struct {
+	ID        string    `json:"id"` // the archive's file name
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RestoreResult reports a restore and the safety copy taken before it, which
+// can itself be restored to undo it
+type RestoreResult struct {
+	Restored     InstanceBackup `json:"restored"`
+	SafetyBackup InstanceBackup `json:"safety_backup"`
+}
+
+// ListBackups returns the backups of an instance, newest first. They are
+// created through PocketBase (its dashboard or API), plus the safety copies
+// taken by restores.
+func (s *InstanceService) ListBackups(ctx context.Context, instanceID, userID uuid.UUID) ([]InstanceBackup, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
+	}
+
+	return listBackups(instance.DataPath)
+}
+
+// FindBackup returns one of an instance's backups for a user who may download
+// it. Backups hold all of the instance's data, so this needs manage access.
+func (s *InstanceService) FindBackup(ctx context.Context, instanceID, userID uuid.UUID, backupID string) (*InstanceBackup, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	return findBackup(instance.DataPath, backupID)
+}
+
+// OpenBackup opens one of an instance's backups for download. The caller
+// closes the file.
+func (s *InstanceService) OpenBackup(ctx context.Context, instanceID, userID uuid.UUID, backupID string) (*InstanceBackup, *os.File, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The file is opened once, without following symlinks, so what was
+	// checked is what gets served
+	return openBackup(instance.DataPath, backupID)
+}
+
+// RestoreBackup replaces an instance's data with one of its backups. The
+// container is stopped, the current data is saved as a new backup, the
+// selected backup is extracted and the container restarted. If PocketBase
+// doesn't come up healthy, the previous data is put back.
+func (s *InstanceService) RestoreBackup(ctx context.Context, instanceID, userID uuid.UUID, backupID string) (*RestoreResult, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status != models.InstanceStatusRunning && instance.Status != models.InstanceStatusStopped {
+		return nil, fmt.Errorf("instance must be running or stopped to restore a backup")
+	}
+
+	backup, file, err := openBackup(instance.DataPath, backupID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, backup.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a zip archive", ErrInvalidBundle)
+	}
+	if err := checkBackupEntries(archive); err != nil {
+		return nil, err
+	}
+	if err := s.checkDiskHeadroom(instance.DataPath, restoreSpaceNeeded(archive, instance.DataPath), "restore the backup"); err != nil {
+		return nil, err
+	}
+
+	containerID := *instance.ContainerID
+	if instance.Status == models.InstanceStatusRunning {
+		if err := s.dockerClient.StopContainer(ctx, containerID); err != nil {
+			return nil, fmt.Errorf("failed to stop container: %w", err)
+		}
+		if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusStopped); err != nil {
+			return nil, fmt.Errorf("failed to update instance status: %w", err)
+		}
+	}
+
+	safety, err := snapshotData(instance.DataPath, safetyBackupPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move the current data aside (next to the data directory, so it isn't
+	// mounted into the container) until the restored instance has booted
+	previous := filepath.Join(filepath.Dir(instance.DataPath), "."+filepath.Base(instance.DataPath)+".pre-restore")
+	if err := os.RemoveAll(previous); err != nil {
+		return nil, fmt.Errorf("failed to prepare restore: %w", err)
+	}
+	if err := os.MkdirAll(previous, 0755); err != nil {
+		return nil, fmt.Errorf("failed to prepare restore: %w", err)
+	}
+	if err := moveDataEntries(instance.DataPath, previous); err != nil {
+		return nil, s.rollbackRestore(ctx, instance, previous, false, err)
+	}
+
+	if err := extractBackup(archive, instance.DataPath); err != nil {
+		return nil, s.rollbackRestore(ctx, instance, previous, true, err)
+	}
+	s.dockerClient.ChownStoragePath(instance.DataPath)
+
+	if err := s.startContainer(ctx, instance); err != nil {
+		return nil, s.rollbackRestore(ctx, instance, previous, true, err)
+	}
+	if err := s.waitForBoot(ctx, containerID); err != nil {
+		return nil, s.rollbackRestore(ctx, instance, previous, true, err)
+	}
+
+	if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning); err != nil {
+		return nil, fmt.Errorf("failed to update instance status: %w", err)
+	}
+	_ = os.RemoveAll(previous)
+
+	return &RestoreResult{Restored: *backup, SafetyBackup: *safety}, nil
+}
+
+// rollbackRestore puts the data moved aside back in place (removing the
+// extracted backup first when restored is set) and starts the instance again.
+// It returns cause wrapped with ErrRestoreFailed, or the rollback error if the
+// previous data couldn't be put back either (it is then still in the
+// .pre-restore directory and in the safety backup).
+func (s *InstanceService) rollbackRestore(ctx context.Context, instance *models.Instance, previous string, restored bool, cause error) error {
+	containerID := *instance.ContainerID
+	_ = s.dockerClient.StopContainer(ctx, containerID)
+
+	if restored {
+		if err := removeDataEntries(instance.DataPath); err != nil {
+			return fmt.Errorf("restore failed (%v) and so did the rollback: %w", cause, err)
+		}
+	}
+	if err := moveDataEntries(previous, instance.DataPath); err != nil {
+		return fmt.Errorf("restore failed (%v) and so did the rollback: %w", cause, err)
+	}
+	_ = os.RemoveAll(previous)
+	s.dockerClient.ChownStoragePath(instance.DataPath)
+
+	if err := s.startContainer(ctx, instance); err != nil {
+		return fmt.Errorf("%w: %v (starting the previous data failed: %v)", ErrRestoreFailed, cause, err)
+	}
+	if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestoreFailed, cause)
+	}
+
+	return fmt.Errorf("%w: %v", ErrRestoreFailed, cause)
+}
+
+// waitForBoot waits until the container reports healthy, or has stayed up
+// for restoreBootGrace when its image has no health check
+func (s *InstanceService) waitForBoot(ctx context.Context, containerID string) error {
+	started := time.Now()
+	deadline := started.Add(restoreBootTimeout)
+
+	for {
+		stats, err := s.dockerClient.GetContainerStats(ctx, containerID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case stats.Status != "running":
+			return fmt.Errorf("PocketBase exited during startup (%s)", stats.Status)
+		case stats.Health == "healthy":
+			return nil
+		case stats.Health == "unhealthy":
+			return fmt.Errorf("PocketBase is unhealthy")
+		case stats.Health == "" && time.Since(started) >= restoreBootGrace:
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("PocketBase did not become healthy within %s", restoreBootTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// listBackups lists the zip archives in a data directory's backups directory.
+// The instance can write to it, so symlinks and other special files are skipped.
+func listBackups(dataPath string) ([]InstanceBackup, error) {
+	dir, err := datadir.OpenDir(dataPath, backupsDir, false)
+	if os.IsNotExist(err) {
+		return []InstanceBackup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+	defer dir.Close()
+
+	entries, err := dir.ReadDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	backups := []InstanceBackup{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".zip" {
+			continue
+		}
+		backup, err := statBackup(dir, entry.Name())
+		if err != nil {
+			continue
+		}
+		backups = append(backups, *backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// findBackup looks up a backup by ID
+func findBackup(dataPath, backupID string) (*InstanceBackup, error) {
+	backup, file, err := openBackup(dataPath, backupID)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	return backup, nil
+}
+
+// openBackup opens a backup by ID; the caller closes the file. IDs are plain
+// file names, so a request can't point outside the backups directory, and
+// neither the directory nor the archive is followed if it is a symlink.
+func openBackup(dataPath, backupID string) (*InstanceBackup, *os.File, error) {
+	if backupID == "" || filepath.Base(backupID) != backupID || filepath.Ext(backupID) != ".zip" {
+		return nil, nil, ErrBackupNotFound
+	}
+
+	dir, err := datadir.OpenDir(dataPath, backupsDir, false)
+	if err != nil {
+		return nil, nil, ErrBackupNotFound
+	}
+	defer dir.Close()
+
+	file, err := dir.Open(backupID)
+	if err != nil {
+		return nil, nil, ErrBackupNotFound
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	return &InstanceBackup{ID: backupID, Size: info.Size(), CreatedAt: info.ModTime().UTC()}, file, nil
+}
+
+// statBackup describes a backup in an opened backups directory
+func statBackup(dir *datadir.Dir, name string) (*InstanceBackup, error) {
+	file, err := dir.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &InstanceBackup{ID: name, Size: info.Size(), CreatedAt: info.ModTime().UTC()}, nil
+}
+
+// checkBackupEntries rejects archives with entries outside the data directory
+func checkBackupEntries(archive *zip.Reader) error {
+	found := false
+	for _, file := range archive.File {
+		name := strings.TrimSuffix(file.Name, "/")
+		if !filepath.IsLocal(name) || path.Clean(name) != name {
+			return fmt.Errorf("%w: invalid entry %s", ErrInvalidBundle, file.Name)
+		}
+		if !file.FileInfo().IsDir() && !file.Mode().IsRegular() {
+			return fmt.Errorf("%w: invalid entry %s", ErrInvalidBundle, file.Name)
+		}
+		if name == "data.db" {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: not a PocketBase backup (no data.db)", ErrInvalidBundle)
+	}
+	return nil
+}
+
+// extractBackup writes a checked backup archive into the data directory,
+// skipping the directories a restore leaves in place
+func extractBackup(archive *zip.Reader, dataPath string) error {
+	for _, file := range archive.File {
+		name := strings.TrimSuffix(file.Name, "/")
+		if restoreKeptDirs[strings.SplitN(name, "/", 2)[0]] {
+			continue
+		}
+
+		dest := filepath.Join(dataPath, filepath.FromSlash(name))
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return fmt.Errorf("failed to extract backup: %w", err)
+			}
+			continue
+		}
+
+		if err := extractBackupFile(file, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractBackupFile(file *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to extract backup: %w", err)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to extract backup: %w", err)
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to extract backup: %w", err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract backup: %w", err)
+	}
+	return out.Close()
+}
+
+// restoreSpaceNeeded estimates the bytes a restore writes: the extracted
+// backup plus the safety backup, which is at most the size of the current
+// data outside the backups directory (the current data is only moved aside)
+func restoreSpaceNeeded(archive *zip.Reader, dataPath string) int64 {
+	var needed int64
+	for _, file := range archive.File {
+		needed += int64(file.UncompressedSize64)
+	}
+
+	current, _ := docker.DirectorySize(dataPath)
+	backups, _ := docker.DirectorySize(filepath.Join(dataPath, backupsDir))
+	if current > backups {
+		needed += current - backups
+	}
+
+	return needed
+}
+
+// snapshotData archives the data directory (in PocketBase's backup layout)
+// into its backups directory, naming the archive prefix plus the time. The
+// archive is written next to the data directory, where the instance can't
+// reach it, and only moved into the backups directory once it is complete.
+// The instance's container must be stopped, so its files can't change (or
+// be swapped for symlinks) while they are read.
+func snapshotData(dataPath, prefix string) (*InstanceBackup, error) {
+	dir, err := datadir.OpenDir(dataPath, backupsDir, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backups directory: %w", err)
+	}
+	defer dir.Close()
+
+	name := prefix + time.Now().UTC().Format("20060102150405") + ".zip"
+	staging := filepath.Join(filepath.Dir(dataPath), "."+filepath.Base(dataPath)+".snapshot")
+	_ = os.Remove(staging)
+	out, err := os.OpenFile(staging, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create safety backup: %w", err)
+	}
+
+	archive := zip.NewWriter(out)
+	err = filepath.WalkDir(dataPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dataPath, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() && restoreKeptDirs[rel] {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		in, err := openRegularFile(p, d)
+		if err != nil || in == nil {
+			return err
+		}
+		defer in.Close()
+		w, err := archive.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, in)
+		return err
+	})
+	if err == nil {
+		err = archive.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = dir.MoveIn(staging, name)
+	}
+	if err != nil {
+		_ = os.Remove(staging)
+		return nil, fmt.Errorf("failed to create safety backup: %w", err)
+	}
+
+	return statBackup(dir, name)
+}
+
+// openRegularFile opens a file found by a directory walk, or returns nil when
+// it is no longer the regular file the walk saw
+func openRegularFile(path string, entry fs.DirEntry) (*os.File, error) {
+	walked, err := entry.Info()
+	if err != nil {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if opened, err := file.Stat(); err != nil || !os.SameFile(walked, opened) {
+		file.Close()
+		return nil, nil
+	}
+	return file, nil
+}
+
+// moveDataEntries moves the top-level entries of a data directory, except the
+// ones a restore leaves in place, into another directory
+func moveDataEntries(from, to string) error {
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if restoreKeptDirs[entry.Name()] {
+			continue
+		}
+		if err := os.Rename(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+			return fmt.Errorf("failed to move %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// removeDataEntries removes the top-level entries of a data directory, except
+// the ones a restore leaves in place
+func removeDataEntries(dataPath string) error {
+	entries, err := os.ReadDir(dataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if restoreKeptDirs[entry.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dataPath, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestDataDir creates a data directory with a database, one backup and a
+// file outside of it (as another instance's data or a host file)
+func newTestDataDir(t *testing.T) (dataPath, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	dataPath = filepath.Join(base, "instance")
+	outside = filepath.Join(base, "other.zip")
+
+	writeTestFile(t, filepath.Join(dataPath, "data.db"), "data")
+	writeTestFile(t, filepath.Join(dataPath, backupsDir, "own.zip"), "own")
+	writeTestFile(t, outside, "secret")
+	return dataPath, outside
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindBackupIgnoresSymlinks(t *testing.T) {
+	dataPath, outside := newTestDataDir(t)
+	if err := os.Symlink(outside, filepath.Join(dataPath, backupsDir, "planted.zip")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := findBackup(dataPath, "planted.zip"); !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("findBackup() of a symlink error = %v, want ErrBackupNotFound", err)
+	}
+	if _, err := findBackup(dataPath, "own.zip"); err != nil {
+		t.Errorf("findBackup() error = %v", err)
+	}
+
+	backups, err := listBackups(dataPath)
+	if err != nil {
+		t.Fatalf("listBackups() error = %v", err)
+	}
+	if len(backups) != 1 || backups[0].ID != "own.zip" {
+		t.Errorf("listBackups() = %v, want only own.zip", backups)
+	}
+}
+
+func TestFindBackupIgnoresSymlinkedDirectory(t *testing.T) {
+	dataPath, outside := newTestDataDir(t)
+	os.RemoveAll(filepath.Join(dataPath, backupsDir))
+	if err := os.Symlink(filepath.Dir(outside), filepath.Join(dataPath, backupsDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := findBackup(dataPath, filepath.Base(outside)); !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("findBackup() through a symlinked directory error = %v, want ErrBackupNotFound", err)
+	}
+	if _, err := snapshotData(dataPath, safetyBackupPrefix); err == nil {
+		t.Error("snapshotData() wrote through a symlinked backups directory")
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(outside))
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".zip" && entry.Name() != filepath.Base(outside) {
+			t.Errorf("snapshot %s was written outside the data directory", entry.Name())
+		}
+	}
+}
+
+func TestSnapshotDataSkipsSymlinks(t *testing.T) {
+	dataPath, outside := newTestDataDir(t)
+	if err := os.Symlink(outside, filepath.Join(dataPath, "planted.db")); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := snapshotData(dataPath, safetyBackupPrefix)
+	if err != nil {
+		t.Fatalf("snapshotData() error = %v", err)
+	}
+
+	archive, err := zip.OpenReader(filepath.Join(dataPath, backupsDir, backup.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"data.db"}) {
+		t.Errorf("snapshot entries = %v, want only data.db", names)
+	}
+
+	staging := filepath.Join(filepath.Dir(dataPath), ".instance.snapshot")
+	if _, err := os.Lstat(staging); !os.IsNotExist(err) {
+		t.Errorf("staging file was left behind: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// encryptionKeyLength is the key length PocketBase requires for settings encryption
+const encryptionKeyLength = 32
+
+// UpdateServeOptions changes an instance's pocketbase serve flags. The
+// container is recreated with the new command (a running instance restarts);
+// the options are saved once that succeeded.
+func (s *InstanceService) UpdateServeOptions(ctx context.Context, instanceID, userID uuid.UUID, options models.ServeOptions) (*models.Instance, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Settings encrypted with the key cannot be read without it
+	if instance.ServeOptions.EncryptSettings && !options.EncryptSettings {
+		return nil, fmt.Errorf("settings encryption cannot be disabled")
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	if err := s.applyServeOptions(ctx, instance, options); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// applyServeOptions recreates an instance's container with the given options
+// and saves them once that succeeded
+func (s *InstanceService) applyServeOptions(ctx context.Context, instance *models.Instance, options models.ServeOptions) error {
+	flags, err := s.serveFlags(ctx, instance.ID, options)
+	if err != nil {
+		return err
+	}
+
+	containerID, err := s.dockerClient.UpdateServeFlags(ctx, *instance.ContainerID, flags)
+	if updateErr := s.recordRecreatedContainer(ctx, instance, containerID); updateErr != nil {
+		return updateErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply serve options: %w", err)
+	}
+	if err := s.applyRecreatedEgressPolicy(ctx, instance); err != nil {
+		return err
+	}
+
+	return s.store.UpdateServeOptions(ctx, instance, options)
+}
+
+// recordRecreatedContainer stores the ID of an instance's container after it
+// was recreated (or restored) under a new one
+func (s *InstanceService) recordRecreatedContainer(ctx context.Context, instance *models.Instance, containerID string) error {
+	if containerID == "" || containerID == *instance.ContainerID {
+		return nil
+	}
+
+	containerName := ""
+	if instance.ContainerName != nil {
+		containerName = *instance.ContainerName
+	}
+	if err := s.store.UpdateContainerInfo(ctx, instance, containerID, containerName); err != nil {
+		return fmt.Errorf("failed to update instance with container info: %w", err)
+	}
+
+	return nil
+}
+
+// serveFlags converts an instance's serve options into container flags,
+// creating the settings encryption key the first time encryption is enabled
+func (s *InstanceService) serveFlags(ctx context.Context, instanceID uuid.UUID, options models.ServeOptions) (docker.ServeFlags, error) {
+	flags := docker.ServeFlags{
+		HooksDir:      options.HooksDir,
+		MigrationsDir: options.MigrationsDir,
+		PublicDir:     options.PublicDir,
+		Origins:       options.Origins,
+		QueryTimeout:  options.QueryTimeout,
+	}
+
+	if options.EncryptSettings {
+		key, err := utils.GenerateRandomString(encryptionKeyLength)
+		if err != nil {
+			return flags, err
+		}
+
+		// An existing key is kept: the settings are already encrypted with it
+		flags.EncryptionKey, err = s.store.EnsureEncryptionKey(ctx, instanceID, key)
+		if err != nil {
+			return flags, err
+		}
+	}
+
+	return flags, nil
+}
@@ -2,37 +2,105 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"pocketploy/internal/authz"
 	"pocketploy/internal/config"
 	"pocketploy/internal/docker"
+	"pocketploy/internal/events"
 	"pocketploy/internal/models"
+	"pocketploy/internal/scanner"
+	"pocketploy/internal/utils"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
+)
+
+const (
+	maxSlugLength    = 60 // slug column limit, before the hostname limit below
+	maxLabelLength   = 63 // DNS limit for the "username-slug" subdomain label
+	slugSuffixLength = 6
+	slugAttempts     = 5
+	maxSearchResults = 100
+
+	pendingDeletionInterval = time.Minute
+	maxAnalyticsDays        = 365
 )
 
 // InstanceService handles business logic for PocketBase instances
 type InstanceService struct {
-	db           *sqlx.DB
-	dockerClient *docker.Client
+	store        InstanceStore
+	dockerClient ContainerRuntime
+	operations   *OperationLimiter
+	events       *events.Broker
+	notifier     InstanceNotifier
+	authz        *authz.Evaluator
+	jobs         JobQueue
+	regions      RegionResolver
+	images       ImageResolver
+	plans        PlanResolver
+	certificates *acmeStore
+	scanner      scanner.Scanner // nil when scanning is disabled
+	deletions    *DeletionGuard
 	config       *config.Config
 }
 
 // NewInstanceService creates a new instance service
-func NewInstanceService(db *sqlx.DB, dockerClient *docker.Client, cfg *config.Config) *InstanceService {
+func NewInstanceService(store InstanceStore, dockerClient ContainerRuntime, operations *OperationLimiter, broker *events.Broker, notifier InstanceNotifier, authorizer *authz.Evaluator, jobQueue JobQueue, regions RegionResolver, images ImageResolver, plans PlanResolver, bundleScanner scanner.Scanner, deletions *DeletionGuard, cfg *config.Config) *InstanceService {
 	return &InstanceService{
-		db:           db,
+		store:        store,
 		dockerClient: dockerClient,
+		operations:   operations,
+		events:       broker,
+		notifier:     notifier,
+		authz:        authorizer,
+		jobs:         jobQueue,
+		regions:      regions,
+		images:       images,
+		plans:        plans,
+		certificates: newACMEStore(cfg.TraefikACMEPath),
+		scanner:      bundleScanner,
+		deletions:    deletions,
 		config:       cfg,
 	}
 }
 
+// Provisioning steps reported while an instance is created
+const (
+	ProvisioningReserving         = "reserving"
+	ProvisioningCreatingContainer = "creating_container"
+	ProvisioningFinalizing        = "finalizing"
+	ProvisioningReady             = "ready"
+	ProvisioningFailed            = "failed"
+)
+
+// ProvisioningProgress is pushed to the owner while an instance is created
+type ProvisioningProgress struct {
+	InstanceID uuid.UUID `json:"instance_id"`
+	Name       string    `json:"name"`
+	Step       string    `json:"step"`
+}
+
+// reportProgress publishes a provisioning step for an instance being created
+func (s *InstanceService) reportProgress(ctx context.Context, instance *models.Instance, step string) {
+	progress := ProvisioningProgress{
+		InstanceID: instance.ID,
+		Name:       instance.Name,
+		Step:       step,
+	}
+	s.events.Publish(ctx, instance.UserID.String(), events.TypeInstanceProvisioning, progress)
+}
+
 // CreateInstanceRequest represents the request to create a new instance
 type CreateInstanceRequest struct {
 	UserID        uuid.UUID
@@ -40,6 +108,15 @@ type CreateInstanceRequest struct {
 	Name          string
 	AdminEmail    string
 	AdminPassword string
+	RegionID      string        // empty places the instance in the default region
+	ImageID       string        // approved image to run, empty for POCKETBASE_IMAGE
+	TTL           time.Duration // archive the instance once it has run this long (0 keeps it)
+
+	// CloneFrom copies another instance's data, serve options and access
+	// protection (no superuser is created); PreviewRef marks the clone as
+	// that instance's preview environment for the ref
+	CloneFrom  *models.Instance
+	PreviewRef string
 }
 
 // CreateInstanceResponse represents the response after creating an instance
@@ -50,42 +127,99 @@ type CreateInstanceResponse struct {
 
 // CreateInstance creates a new PocketBase instance for a user
 func (s *InstanceService) CreateInstance(ctx context.Context, req CreateInstanceRequest) (*CreateInstanceResponse, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(req.UserID, "create")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Validate instance name
 	if err := s.validateInstanceName(req.Name); err != nil {
 		return nil, err
 	}
 
-	// Check if user has reached the maximum number of instances
-	count, err := models.CountUserInstances(ctx, s.db, req.UserID)
+	var expiresAt *time.Time
+	if req.TTL != 0 {
+		if err := s.validateTTL(req.TTL); err != nil {
+			return nil, err
+		}
+		at := time.Now().UTC().Add(req.TTL)
+		expiresAt = &at
+	}
+
+	// Fail fast if the user has reached the maximum number of instances
+	// (Create re-checks atomically, as parallel requests may race past this)
+	count, err := s.store.CountUserInstances(ctx, req.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count user instances: %w", err)
 	}
 
-	if count >= s.config.MaxInstancesPerUser {
-		return nil, fmt.Errorf("maximum number of instances reached (%d)", s.config.MaxInstancesPerUser)
+	maxInstances, err := s.instanceLimit(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxInstances {
+		return nil, &models.InstanceLimitError{Limit: maxInstances}
 	}
 
-	// Generate slug from instance name
-	slug := s.generateSlug(req.Name)
+	if err := s.checkDiskSpace(); err != nil {
+		return nil, err
+	}
 
-	// Generate subdomain
-	subdomain := s.generateSubdomain(req.Username, slug)
+	// The region determines the domain and network of the instance
+	region, err := s.regions.ResolveRegion(ctx, req.RegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := s.resolveImage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var imageID *string
+	var imageRef string
+	if image != nil {
+		imageID, imageRef = &image.ID, image.PinnedReference
+	}
+
+	// Generate slug from instance name
+	baseSlug, err := s.generateSlug(req.Username, req.Name)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if subdomain already exists
-	existing, _ := models.FindInstanceBySubdomain(ctx, s.db, subdomain)
-	if existing != nil {
-		return nil, fmt.Errorf("instance with this name already exists")
+	// Pick a slug whose subdomain and storage path are not taken yet
+	slug, err := s.uniqueSlug(ctx, req.Username, baseSlug, region)
+	if err != nil {
+		return nil, err
 	}
 
+	// Generate subdomain
+	subdomain := s.generateSubdomain(req.Username, slug, region)
+
 	// Generate container name
 	containerName := s.generateContainerName(req.Username, slug)
 
 	// Generate storage path
 	storagePath := s.generateStoragePath(req.Username, slug)
 
+	// In port routing mode the instance is published on a host port allocated
+	// with its row
+	var hostPortMin, hostPortMax int
+	if s.config.RoutingMode == config.RoutingModePort {
+		hostPortMin, hostPortMax = s.config.InstancePortMin, s.config.InstancePortMax
+	}
+
+	var previewOf *uuid.UUID
+	var previewRef *string
+	if req.CloneFrom != nil && req.PreviewRef != "" {
+		previewOf, previewRef = &req.CloneFrom.ID, &req.PreviewRef
+	}
+
 	// Create instance in database with creating status
 	instance := &models.Instance{}
-	err = instance.Create(ctx, s.db, models.CreateInstanceParams{
+	err = s.store.CreateInstance(ctx, instance, models.CreateInstanceParams{
 		UserID:        req.UserID,
 		Name:          req.Name,
 		Slug:          slug,
@@ -94,59 +228,119 @@ func (s *InstanceService) CreateInstance(ctx context.Context, req CreateInstance
 		ContainerName: &containerName,
 		Status:        models.InstanceStatusCreating,
 		DataPath:      storagePath,
+		RegionID:      region.ID,
+		ExpiresAt:     expiresAt,
+		PreviewOf:     previewOf,
+		PreviewRef:    previewRef,
+		ImageID:       imageID,
+		HostPortMin:   hostPortMin,
+		HostPortMax:   hostPortMax,
+		MaxPerUser:    maxInstances,
 	})
 	if err != nil {
+		var limitErr *models.InstanceLimitError
+		if errors.As(err, &limitErr) || errors.Is(err, models.ErrSubdomainTaken) || errors.Is(err, models.ErrNoHostPort) || errors.Is(err, models.ErrPreviewExists) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create instance in database: %w", err)
 	}
+	s.reportProgress(ctx, instance, ProvisioningReserving)
 
 	// Create Docker container
-	containerID, err := s.dockerClient.CreatePocketBaseContainer(ctx, docker.ContainerConfig{
-		ContainerName: containerName,
-		Subdomain:     subdomain,
-		StoragePath:   storagePath,
-		Username:      req.Username,
-		InstanceSlug:  slug,
-		AdminEmail:    req.AdminEmail,
-		AdminPassword: req.AdminPassword,
-	})
+	s.reportProgress(ctx, instance, ProvisioningCreatingContainer)
+	hostPort := 0
+	if instance.HostPort != nil {
+		hostPort = *instance.HostPort
+	}
+	containerConfig := docker.ContainerConfig{
+		ContainerName:     containerName,
+		Subdomain:         subdomain,
+		TraefikEntrypoint: region.TraefikEntrypoint,
+		Network:           regionNetwork(region),
+		HostPort:          hostPort,
+		StoragePath:       storagePath,
+		Username:          req.Username,
+		InstanceSlug:      slug,
+		AdminEmail:        req.AdminEmail,
+		AdminPassword:     req.AdminPassword,
+		Image:             imageRef,
+	}
+	if req.CloneFrom != nil {
+		if err := s.prepareClone(ctx, req.CloneFrom, instance, &containerConfig); err != nil {
+			_ = s.store.UpdateStatus(ctx, instance, models.InstanceStatusFailed)
+			s.reportProgress(ctx, instance, ProvisioningFailed)
+			return nil, err
+		}
+	}
 
+	containerID, err := s.dockerClient.CreatePocketBaseContainer(ctx, containerConfig)
 	if err != nil {
 		// If container creation fails, update instance status to failed
-		_ = instance.UpdateStatus(ctx, s.db, models.InstanceStatusFailed)
+		_ = s.store.UpdateStatus(ctx, instance, models.InstanceStatusFailed)
+		s.reportProgress(ctx, instance, ProvisioningFailed)
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
 
 	// Update instance with container ID and set status to running
-	err = instance.UpdateContainerInfo(ctx, s.db, containerID, containerName)
+	s.reportProgress(ctx, instance, ProvisioningFinalizing)
+	err = s.store.UpdateContainerInfo(ctx, instance, containerID, containerName)
 	if err != nil {
 		// Try to clean up container
 		_ = s.dockerClient.RemoveContainer(ctx, containerID)
-		_ = instance.UpdateStatus(ctx, s.db, models.InstanceStatusFailed)
+		_ = s.store.UpdateStatus(ctx, instance, models.InstanceStatusFailed)
+		s.reportProgress(ctx, instance, ProvisioningFailed)
 		return nil, fmt.Errorf("failed to update instance with container info: %w", err)
 	}
 
 	// Update status to running
-	err = instance.UpdateStatus(ctx, s.db, models.InstanceStatusRunning)
+	err = s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning)
 	if err != nil {
+		s.reportProgress(ctx, instance, ProvisioningFailed)
 		return nil, fmt.Errorf("failed to update instance status: %w", err)
 	}
+	s.reportProgress(ctx, instance, ProvisioningReady)
+
+	return &CreateInstanceResponse{
+		Instance: instance,
+		URL:      s.InstanceURL(instance),
+	}, nil
+}
+
+// resolveImage returns the approved image a new instance runs (nil for
+// POCKETBASE_IMAGE). Clones run their source's image, even if it has been
+// disabled since.
+func (s *InstanceService) resolveImage(ctx context.Context, req CreateInstanceRequest) (*models.InstanceImage, error) {
+	if req.CloneFrom != nil {
+		if req.CloneFrom.ImageID == nil {
+			return nil, nil
+		}
+		return s.images.FindImage(ctx, *req.CloneFrom.ImageID)
+	}
 
-	// Generate the full URL based on environment
+	return s.images.ResolveImage(ctx, req.ImageID, req.UserID)
+}
+
+// InstanceURL returns the public URL of an instance: the host port it is
+// published on in port routing mode, its subdomain otherwise
+func (s *InstanceService) InstanceURL(instance *models.Instance) string {
+	if instance.HostPort != nil {
+		return fmt.Sprintf("http://%s", net.JoinHostPort(s.config.InstancePortHost, strconv.Itoa(*instance.HostPort)))
+	}
+	return s.SubdomainURL(instance.Subdomain)
+}
+
+// SubdomainURL returns the public URL of an instance subdomain based on environment
+func (s *InstanceService) SubdomainURL(subdomain string) string {
 	protocol := "http"
 	if s.config.Env == "production" {
 		protocol = "https"
 	}
-	url := fmt.Sprintf("%s://%s", protocol, subdomain)
-
-	return &CreateInstanceResponse{
-		Instance: instance,
-		URL:      url,
-	}, nil
+	return fmt.Sprintf("%s://%s", protocol, subdomain)
 }
 
 // ListUserInstances retrieves all instances for a user
 func (s *InstanceService) ListUserInstances(ctx context.Context, userID uuid.UUID) ([]models.Instance, error) {
-	instances, err := models.FindInstancesByUserID(ctx, s.db, userID)
+	instances, err := s.store.FindInstancesByUserID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list user instances: %w", err)
 	}
@@ -154,35 +348,236 @@ func (s *InstanceService) ListUserInstances(ctx context.Context, userID uuid.UUI
 	return instances, nil
 }
 
-// GetInstance retrieves a specific instance by ID
-func (s *InstanceService) GetInstance(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error) {
-	instance, err := models.FindInstanceByID(ctx, s.db, instanceID)
+// SearchUserInstances searches a user's instances by name, slug or subdomain
+func (s *InstanceService) SearchUserInstances(ctx context.Context, userID uuid.UUID, term string) ([]models.Instance, error) {
+	return s.store.SearchInstances(ctx, &userID, strings.TrimSpace(term), maxSearchResults)
+}
+
+// SearchAllInstances searches instances across all users (admin only)
+func (s *InstanceService) SearchAllInstances(ctx context.Context, term string) ([]models.Instance, error) {
+	return s.store.SearchInstances(ctx, nil, strings.TrimSpace(term), maxSearchResults)
+}
+
+// AuthorizeInstance retrieves an instance and checks that the user may perform
+// the action on it. Users without any role on the instance get "instance not
+// found" so its existence isn't leaked.
+func (s *InstanceService) AuthorizeInstance(ctx context.Context, instanceID, userID uuid.UUID, action authz.Action) (*models.Instance, error) {
+	instance, err := s.store.FindInstanceByID(ctx, instanceID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify the instance belongs to the user
-	if instance.UserID != userID {
-		return nil, fmt.Errorf("instance not found")
+	resource := authz.Resource{Kind: "instance", ID: instance.ID, OwnerID: instance.UserID}
+	if err := s.authz.Authorize(ctx, userID, resource, action); err != nil {
+		if errors.Is(err, authz.ErrNoAccess) {
+			return nil, fmt.Errorf("instance not found")
+		}
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// GetInstance retrieves a specific instance by ID
+func (s *InstanceService) GetInstance(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update last accessed timestamp
-	_ = instance.UpdateLastAccessed(ctx, s.db)
+	_ = s.store.UpdateLastAccessed(ctx, instance)
 
 	return instance, nil
 }
 
-// DeleteInstance archives an instance and removes its container (keeps data for 30 days)
-func (s *InstanceService) DeleteInstance(ctx context.Context, instanceID, userID uuid.UUID) error {
+// DeleteInstanceOptions controls what happens to an instance's data on deletion
+type DeleteInstanceOptions struct {
+	RetentionDays *int // nil uses the configured default, 0 deletes the data immediately
+	Check         DeletionCheck
+}
+
+// DeleteInstanceResult describes the outcome of a deletion request
+type DeleteInstanceResult struct {
+	Instance *models.Instance         // Set while the deletion is pending (grace period)
+	Archived *models.ArchivedInstance // Set once the instance has been archived
+}
+
+// DeleteInstance schedules an instance for deletion, or archives it right away
+// when no grace period is configured
+func (s *InstanceService) DeleteInstance(ctx context.Context, instanceID, userID uuid.UUID, opts DeleteInstanceOptions) (*DeleteInstanceResult, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "delete")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Get the instance
-	instance, err := models.FindInstanceByID(ctx, s.db, instanceID)
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceDelete)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is already pending deletion")
+	}
+
+	if instance.Protected {
+		return nil, fmt.Errorf("instance is protected from deletion")
+	}
+
+	if err := s.deletions.CheckInstanceDeletion(ctx, instance, userID, opts.Check); err != nil {
+		return nil, err
+	}
+
+	// Users may shorten the retention period but not extend it
+	settings := s.config.Settings()
+	retentionDays := settings.InstanceDataRetentionDays
+	if opts.RetentionDays != nil {
+		if *opts.RetentionDays < 0 || *opts.RetentionDays > retentionDays {
+			return nil, fmt.Errorf("retention days must be between 0 and %d", retentionDays)
+		}
+		retentionDays = *opts.RetentionDays
+	}
+
+	// With a grace period the instance only stops; container and data stay in place
+	gracePeriod := settings.InstanceDeletionGracePeriod
+	if gracePeriod > 0 {
+		wasRunning := instance.Status == models.InstanceStatusRunning
+
+		scheduledFor := time.Now().UTC().Add(gracePeriod)
+		if err := s.store.ScheduleDeletion(ctx, instance, scheduledFor, retentionDays); err != nil {
+			return nil, err
+		}
+
+		if wasRunning && instance.ContainerID != nil && *instance.ContainerID != "" {
+			if err := s.dockerClient.StopContainer(ctx, *instance.ContainerID); err != nil {
+				fmt.Printf("Warning: failed to stop container %s: %v\n", *instance.ContainerID, err)
+			}
+		}
+
+		fmt.Printf("Instance scheduled for deletion: %s (at %s)\n", instance.Name, scheduledFor.Format(time.RFC3339))
+		return &DeleteInstanceResult{Instance: instance}, nil
+	}
+
+	archived, err := s.archiveInstance(ctx, instance, userID, retentionDays, models.DeletionReasonManual, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteInstanceResult{Archived: archived}, nil
+}
+
+// IssueDeletionConfirmation returns a token the user hands back when deleting
+// the instance, along with the name they have to type
+func (s *InstanceService) IssueDeletionConfirmation(ctx context.Context, instanceID, userID uuid.UUID) (*DeletionConfirmation, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.deletions.IssueConfirmation(ctx, instance, userID)
+}
+
+// CancelDeletion restores an instance that is pending deletion
+func (s *InstanceService) CancelDeletion(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error) {
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "cancel_deletion")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.CancelDeletion(ctx, instance); err != nil {
+		return nil, err
 	}
 
-	// Verify the instance belongs to the user
-	if instance.UserID != userID {
-		return fmt.Errorf("instance not found")
+	// Bring back instances that were running when the deletion was requested
+	if instance.Status == models.InstanceStatusRunning && instance.ContainerID != nil && *instance.ContainerID != "" {
+		if err := s.startContainer(ctx, instance); err != nil {
+			fmt.Printf("Warning: failed to restart container %s: %v\n", *instance.ContainerID, err)
+			if err := s.store.UpdateStatus(ctx, instance, models.InstanceStatusStopped); err != nil {
+				return nil, fmt.Errorf("failed to update instance status: %w", err)
+			}
+		}
+	}
+
+	return instance, nil
+}
+
+// RunPendingDeletionWorker archives instances whose deletion grace period has
+// ended and ephemeral instances that expired, and purges archived data whose
+// retention ended, until ctx is cancelled
+func (s *InstanceService) RunPendingDeletionWorker(ctx context.Context) {
+	ticker := time.NewTicker(pendingDeletionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processPendingDeletions(ctx)
+			s.processExpiredInstances(ctx)
+			s.processExpiredArchives(ctx)
+		}
+	}
+}
+
+func (s *InstanceService) processPendingDeletions(ctx context.Context) {
+	instances, err := s.store.FindInstancesDueForDeletion(ctx)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+
+	for i := range instances {
+		instance := &instances[i]
+
+		retentionDays := s.config.Settings().InstanceDataRetentionDays
+		if instance.DeletionRetentionDays != nil {
+			retentionDays = *instance.DeletionRetentionDays
+		}
+
+		s.archivePendingDeletion(ctx, instance, retentionDays)
+	}
+}
+
+// archivePendingDeletion archives an instance whose grace period ended. It
+// counts against the owner's concurrent operations like a user's own
+// deletion, and the archive only goes through while the instance is still
+// pending deletion and unprotected, so a cancellation or protection that raced
+// the worker wins and its container and data are left alone.
+func (s *InstanceService) archivePendingDeletion(ctx context.Context, instance *models.Instance, retentionDays int) {
+	release, err := s.operations.Acquire(instance.UserID, "delete")
+	if err != nil {
+		return // retried on the next tick
+	}
+	defer release()
+
+	_, err = s.archiveInstance(ctx, instance, instance.UserID, retentionDays, models.DeletionReasonManual, models.InstanceStatusPendingDeletion)
+	if errors.Is(err, models.ErrInstanceStatusChanged) {
+		fmt.Printf("Deletion of instance %s was cancelled, skipping it\n", instance.ID)
+	} else if errors.Is(err, models.ErrInstanceProtected) {
+		fmt.Printf("Warning: instance %s is pending deletion but protected, skipping it\n", instance.ID)
+	} else if err != nil {
+		fmt.Printf("Warning: failed to delete instance %s: %v\n", instance.ID, err)
+	}
+}
+
+// archiveInstance moves an instance to the archive, removes its container and
+// deletes or retains its data as requested. With requireStatus set, nothing
+// is removed unless the instance still has that status when it is archived.
+func (s *InstanceService) archiveInstance(ctx context.Context, instance *models.Instance, deletedBy uuid.UUID, retentionDays int, reason, requireStatus string) (*models.ArchivedInstance, error) {
+	// Record the status the instance had before its deletion was scheduled
+	if instance.StatusBeforeDeletion != nil {
+		instance.Status = *instance.StatusBeforeDeletion
 	}
 
 	// Calculate data directory size for metadata
@@ -193,52 +588,177 @@ func (s *InstanceService) DeleteInstance(ctx context.Context, instanceID, userID
 		}
 	}
 
-	// Archive the instance (moves to instances_archive table)
-	_, err = models.ArchiveInstance(ctx, s.db, models.ArchiveInstanceParams{
+	// Record the container and data cleanup first, so it is retried in the
+	// background if it fails below or the process stops after archiving
+	if err := s.scheduleCleanup(ctx, instance, retentionDays == 0); err != nil {
+		return nil, err
+	}
+
+	// Archive the instance (moves it to the instances_archive table in one transaction)
+	archived, err := s.store.ArchiveInstance(ctx, models.ArchiveInstanceParams{
 		Instance:          instance,
-		DeletedByUserID:   userID,
-		DeletionReason:    "manual",
+		DeletedByUserID:   deletedBy,
+		DeletionReason:    reason,
 		DataSizeMB:        dataSizeMB,
-		DataRetentionDays: 30, // Keep data for 30 days
+		DataRetentionDays: retentionDays,
+		RequireStatus:     requireStatus,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to archive instance: %w", err)
+		return nil, fmt.Errorf("failed to archive instance: %w", err)
 	}
 
-	// Stop and remove the container if it exists
+	// Stop (gracefully, so PocketBase flushes its database) and remove the
+	// container right away; removal failures are left to the cleanup job
 	if instance.ContainerID != nil && *instance.ContainerID != "" {
-		// Stop the container
-		err = s.dockerClient.StopContainer(ctx, *instance.ContainerID)
-		if err != nil {
-			// Log error but continue with deletion
+		if err := s.dockerClient.StopContainer(ctx, *instance.ContainerID); err != nil {
 			fmt.Printf("Warning: failed to stop container %s: %v\n", *instance.ContainerID, err)
 		}
-
-		// Remove the container
-		err = s.dockerClient.RemoveContainer(ctx, *instance.ContainerID)
-		if err != nil {
-			// Log error but continue with deletion
-			fmt.Printf("Warning: failed to remove container %s: %v\n", *instance.ContainerID, err)
+		if err := s.dockerClient.RemoveContainer(ctx, *instance.ContainerID); err != nil {
+			fmt.Printf("Warning: failed to remove container %s, cleanup will be retried: %v\n", *instance.ContainerID, err)
 		}
 	}
 
-	// Delete instance from main table (it's now in archive)
-	err = instance.Delete(ctx, s.db)
-	if err != nil {
-		return fmt.Errorf("failed to delete instance from main table: %w", err)
+	// No retention requested: remove the data folder right away. The archive
+	// keeps data_available set until the data is gone, so a failure is
+	// retried by the cleanup job and the subdomain stays reserved meanwhile.
+	if retentionDays == 0 {
+		if err := s.removeInstanceData(instance.DataPath); err != nil {
+			fmt.Printf("Warning: failed to remove data for instance %s, cleanup will be retried: %v\n", instance.ID, err)
+			return archived, nil
+		}
+		if err := s.store.UpdateArchivedDataAvailability(ctx, archived.ID, false); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			return archived, nil
+		}
+		archived.DataAvailable = false
+
+		fmt.Printf("Instance archived: %s (data deleted)\n", instance.Name)
+		return archived, nil
 	}
 
-	// Keep data folder for 30 days (don't delete yet)
+	// Keep data folder until the retention period ends
 	// A background job will clean up expired data based on data_retained_until
 	fmt.Printf("Instance archived: %s (data retained until %s)\n",
 		instance.Name,
-		time.Now().AddDate(0, 0, 30).Format("2006-01-02"))
+		archived.DataRetainedUntil.Format("2006-01-02"))
+
+	return archived, nil
+}
+
+// ListArchivedInstances lists a user's deleted instances with their retention details
+func (s *InstanceService) ListArchivedInstances(ctx context.Context, userID uuid.UUID) ([]models.ArchivedInstance, error) {
+	archived, err := s.store.FindArchivedInstancesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if archived == nil {
+		archived = []models.ArchivedInstance{}
+	}
+
+	return archived, nil
+}
+
+// GetArchivedInstance retrieves a specific archived instance owned by the user
+func (s *InstanceService) GetArchivedInstance(ctx context.Context, archivedID, userID uuid.UUID) (*models.ArchivedInstance, error) {
+	return s.store.FindArchivedInstanceByID(ctx, archivedID, userID)
+}
+
+// PurgeArchivedInstance deletes an archived instance's data before its retention period ends
+func (s *InstanceService) PurgeArchivedInstance(ctx context.Context, archivedID, userID uuid.UUID) error {
+	archived, err := s.store.FindArchivedInstanceByID(ctx, archivedID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.purgeArchivedData(ctx, archived)
+}
+
+// PurgeArchivedData deletes the retained data of any user's archived instance
+// (admin function)
+func (s *InstanceService) PurgeArchivedData(ctx context.Context, archivedID uuid.UUID) (*models.ArchivedInstance, error) {
+	archived, err := s.store.FindArchivedInstance(ctx, archivedID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.purgeArchivedData(ctx, archived); err != nil {
+		return nil, err
+	}
+
+	archived.DataAvailable = false
+	return archived, nil
+}
+
+// PurgeUserInstances archives all of a user's instances and deletes their
+// data, along with the data retained for instances they deleted earlier, so
+// the user can be removed without leaving containers or files behind. Grace
+// periods, retention and deletion protection don't apply.
+func (s *InstanceService) PurgeUserInstances(ctx context.Context, userID, adminID uuid.UUID) error {
+	instances, err := s.store.FindInstancesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range instances {
+		if _, err := s.archiveInstance(ctx, &instances[i], adminID, 0, models.DeletionReasonAdmin, ""); err != nil {
+			return fmt.Errorf("failed to delete instance %s: %w", instances[i].ID, err)
+		}
+	}
+
+	archived, err := s.store.FindArchivedInstancesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range archived {
+		if !archived[i].DataAvailable {
+			continue
+		}
+		if err := s.purgeArchivedData(ctx, &archived[i]); err != nil {
+			return fmt.Errorf("failed to purge data of instance %s: %w", archived[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// purgeArchivedData removes an archived instance's retained data
+func (s *InstanceService) purgeArchivedData(ctx context.Context, archived *models.ArchivedInstance) error {
+	if !archived.DataAvailable {
+		return fmt.Errorf("archived instance data already purged")
+	}
+
+	if err := s.removeInstanceData(archived.DataPath); err != nil {
+		return err
+	}
+
+	return s.store.UpdateArchivedDataAvailability(ctx, archived.ID, false)
+}
+
+// removeInstanceData deletes an instance data folder, refusing paths outside
+// the instances base path and STORAGE_VOLUMES
+func (s *InstanceService) removeInstanceData(dataPath string) error {
+	if dataPath == "" {
+		return nil
+	}
+
+	target, err := filepath.Abs(dataPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve data path: %w", err)
+	}
+
+	if storageRoot(s.config.StorageRoots(), target) == "" {
+		return fmt.Errorf("refusing to remove data outside the storage volumes: %s", dataPath)
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		return fmt.Errorf("failed to remove instance data: %w", err)
+	}
 
 	return nil
 }
 
 // GetInstanceLogs retrieves logs from an instance's container
-func (s *InstanceService) GetInstanceLogs(ctx context.Context, instanceID, userID uuid.UUID, tail string) (string, error) {
+func (s *InstanceService) GetInstanceLogs(ctx context.Context, instanceID, userID uuid.UUID, opts docker.LogOptions) (string, error) {
 	instance, err := s.GetInstance(ctx, instanceID, userID)
 	if err != nil {
 		return "", err
@@ -248,7 +768,7 @@ func (s *InstanceService) GetInstanceLogs(ctx context.Context, instanceID, userI
 		return "", fmt.Errorf("instance has no container")
 	}
 
-	logs, err := s.dockerClient.GetContainerLogs(ctx, *instance.ContainerID, tail)
+	logs, err := s.dockerClient.GetContainerLogs(ctx, *instance.ContainerID, opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to get container logs: %w", err)
 	}
@@ -256,6 +776,56 @@ func (s *InstanceService) GetInstanceLogs(ctx context.Context, instanceID, userI
 	return logs, nil
 }
 
+// GetInstanceLogEntries retrieves parsed log entries at or above minLevel (empty for all levels)
+func (s *InstanceService) GetInstanceLogEntries(ctx context.Context, instanceID, userID uuid.UUID, opts docker.LogOptions, minLevel string) ([]docker.LogEntry, error) {
+	instance, err := s.GetInstance(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, fmt.Errorf("instance has no container")
+	}
+
+	entries, err := s.dockerClient.ReadContainerLogs(ctx, *instance.ContainerID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container logs: %w", err)
+	}
+
+	if minLevel == "" {
+		return entries, nil
+	}
+
+	filtered := make([]docker.LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.AtLeast(minLevel) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered, nil
+}
+
+// StreamInstanceLogs opens a plain-text log stream for an instance's container.
+// The caller must close the returned reader.
+func (s *InstanceService) StreamInstanceLogs(ctx context.Context, instanceID, userID uuid.UUID, opts docker.LogOptions) (*models.Instance, io.ReadCloser, error) {
+	instance, err := s.GetInstance(ctx, instanceID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, nil, fmt.Errorf("instance has no container")
+	}
+
+	logs, err := s.dockerClient.StreamContainerLogs(ctx, *instance.ContainerID, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get container logs: %w", err)
+	}
+
+	return instance, logs, nil
+}
+
 // GetInstanceStats retrieves statistics for an instance
 func (s *InstanceService) GetInstanceStats(ctx context.Context, instanceID, userID uuid.UUID) (*docker.ContainerStats, error) {
 	instance, err := s.GetInstance(ctx, instanceID, userID)
@@ -275,9 +845,46 @@ func (s *InstanceService) GetInstanceStats(ctx context.Context, instanceID, user
 	return stats, nil
 }
 
+// GetInstanceMetrics retrieves an instance's resource usage samples for the given time range
+func (s *InstanceService) GetInstanceMetrics(ctx context.Context, instanceID, userID uuid.UUID, timeRange time.Duration) ([]models.InstanceMetric, error) {
+	retention := s.config.InstanceMetricsRetention
+	if timeRange <= 0 || (retention > 0 && timeRange > retention) {
+		return nil, fmt.Errorf("range must be between 1s and %s", retention)
+	}
+
+	instance, err := s.GetInstance(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.FindInstanceMetrics(ctx, instance.ID, time.Now().UTC().Add(-timeRange))
+}
+
+// GetInstanceAnalytics retrieves an instance's daily request analytics for the last days
+func (s *InstanceService) GetInstanceAnalytics(ctx context.Context, instanceID, userID uuid.UUID, days int) ([]models.InstanceTrafficDay, error) {
+	if days < 1 || days > maxAnalyticsDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", maxAnalyticsDays)
+	}
+
+	instance, err := s.GetInstance(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	return s.store.FindInstanceTraffic(ctx, instance.ID, since)
+}
+
 // StartInstance starts a stopped instance
 func (s *InstanceService) StartInstance(ctx context.Context, instanceID, userID uuid.UUID) error {
-	instance, err := s.GetInstance(ctx, instanceID, userID)
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "start")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceOperate)
 	if err != nil {
 		return err
 	}
@@ -290,13 +897,29 @@ func (s *InstanceService) StartInstance(ctx context.Context, instanceID, userID
 		return fmt.Errorf("instance is already running")
 	}
 
-	err = s.dockerClient.StartContainer(ctx, *instance.ContainerID)
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return fmt.Errorf("instance is pending deletion")
+	}
+
+	if instance.Status == models.InstanceStatusSuspended {
+		return fmt.Errorf("instance is suspended")
+	}
+
+	// Crash-looping instances had automatic restarts disabled; re-enable them
+	if instance.Status == models.InstanceStatusFailed {
+		err = s.dockerClient.SetRestartPolicy(ctx, *instance.ContainerID, container.RestartPolicyUnlessStopped)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.startContainer(ctx, instance)
 	if err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
 	// Update status
-	err = instance.UpdateStatus(ctx, s.db, models.InstanceStatusRunning)
+	err = s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning)
 	if err != nil {
 		return fmt.Errorf("failed to update instance status: %w", err)
 	}
@@ -306,7 +929,14 @@ func (s *InstanceService) StartInstance(ctx context.Context, instanceID, userID
 
 // StopInstance stops a running instance
 func (s *InstanceService) StopInstance(ctx context.Context, instanceID, userID uuid.UUID) error {
-	instance, err := s.GetInstance(ctx, instanceID, userID)
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "stop")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceOperate)
 	if err != nil {
 		return err
 	}
@@ -315,6 +945,14 @@ func (s *InstanceService) StopInstance(ctx context.Context, instanceID, userID u
 		return fmt.Errorf("instance has no container")
 	}
 
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return fmt.Errorf("instance is pending deletion")
+	}
+
+	if instance.Status == models.InstanceStatusSuspended {
+		return fmt.Errorf("instance is suspended")
+	}
+
 	if instance.Status == models.InstanceStatusStopped {
 		return fmt.Errorf("instance is already stopped")
 	}
@@ -325,7 +963,7 @@ func (s *InstanceService) StopInstance(ctx context.Context, instanceID, userID u
 	}
 
 	// Update status
-	err = instance.UpdateStatus(ctx, s.db, models.InstanceStatusStopped)
+	err = s.store.UpdateStatus(ctx, instance, models.InstanceStatusStopped)
 	if err != nil {
 		return fmt.Errorf("failed to update instance status: %w", err)
 	}
@@ -335,7 +973,14 @@ func (s *InstanceService) StopInstance(ctx context.Context, instanceID, userID u
 
 // RestartInstance restarts an instance
 func (s *InstanceService) RestartInstance(ctx context.Context, instanceID, userID uuid.UUID) error {
-	instance, err := s.GetInstance(ctx, instanceID, userID)
+	// Limit concurrent Docker operations per user
+	release, err := s.operations.Acquire(userID, "restart")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceOperate)
 	if err != nil {
 		return err
 	}
@@ -344,13 +989,21 @@ func (s *InstanceService) RestartInstance(ctx context.Context, instanceID, userI
 		return fmt.Errorf("instance has no container")
 	}
 
-	err = s.dockerClient.RestartContainer(ctx, *instance.ContainerID)
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return fmt.Errorf("instance is pending deletion")
+	}
+
+	if instance.Status == models.InstanceStatusSuspended {
+		return fmt.Errorf("instance is suspended")
+	}
+
+	err = s.restartContainer(ctx, instance)
 	if err != nil {
 		return fmt.Errorf("failed to restart container: %w", err)
 	}
 
 	// Update status
-	err = instance.UpdateStatus(ctx, s.db, models.InstanceStatusRunning)
+	err = s.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning)
 	if err != nil {
 		return fmt.Errorf("failed to update instance status: %w", err)
 	}
@@ -358,14 +1011,80 @@ func (s *InstanceService) RestartInstance(ctx context.Context, instanceID, userI
 	return nil
 }
 
+// RotateAdminCredentials resets the PocketBase superuser password for an instance.
+// The generated password is returned once and never stored.
+func (s *InstanceService) RotateAdminCredentials(ctx context.Context, instanceID, userID uuid.UUID, adminEmail string) (string, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return "", err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return "", fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status != models.InstanceStatusRunning {
+		return "", fmt.Errorf("instance is not running")
+	}
+
+	password, err := utils.GenerateSecurePassword(24)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.dockerClient.UpsertSuperuser(ctx, *instance.ContainerID, adminEmail, password)
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate admin credentials: %w", err)
+	}
+
+	return password, nil
+}
+
+// RunInstanceCommand runs a whitelisted pocketbase maintenance command inside an instance
+func (s *InstanceService) RunInstanceCommand(ctx context.Context, instanceID, userID uuid.UUID, args []string) (string, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return "", err
+	}
+
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return "", fmt.Errorf("instance has no container")
+	}
+
+	if instance.Status != models.InstanceStatusRunning {
+		return "", fmt.Errorf("instance is not running")
+	}
+
+	output, err := s.dockerClient.RunPocketBaseCommand(ctx, *instance.ContainerID, args)
+	if err != nil {
+		if err.Error() == "command is not allowed" {
+			return "", err
+		}
+		return output, fmt.Errorf("failed to run instance command: %w", err)
+	}
+
+	return output, nil
+}
+
 // validateInstanceName validates the instance name
+// instanceLimit returns the most instances a user's plan allows
+func (s *InstanceService) instanceLimit(ctx context.Context, userID uuid.UUID) (int, error) {
+	plan, err := s.plans.UserPlan(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user plan: %w", err)
+	}
+
+	return s.config.Settings().InstanceLimit(plan), nil
+}
+
 func (s *InstanceService) validateInstanceName(name string) error {
-	if len(name) < 3 || len(name) > 100 {
+	length := utf8.RuneCountInString(name)
+	if length < 3 || length > 100 {
 		return fmt.Errorf("instance name must be between 3 and 100 characters")
 	}
 
-	// Allow alphanumeric, spaces, hyphens, and underscores
-	validName := regexp.MustCompile(`^[a-zA-Z0-9\s\-_]+$`)
+	// Allow letters (any script), numbers, spaces, hyphens, and underscores
+	validName := regexp.MustCompile(`^[\p{L}\p{N}\s\-_]+$`)
 	if !validName.MatchString(name) {
 		return fmt.Errorf("instance name can only contain letters, numbers, spaces, hyphens, and underscores")
 	}
@@ -373,10 +1092,11 @@ func (s *InstanceService) validateInstanceName(name string) error {
 	return nil
 }
 
-// generateSlug creates a URL-safe slug from the instance name
-func (s *InstanceService) generateSlug(name string) string {
-	// Convert to lowercase
-	slug := strings.ToLower(name)
+// generateSlug creates a URL-safe slug from the instance name, short enough
+// that the username's subdomain label fits a collision suffix
+func (s *InstanceService) generateSlug(username, name string) (string, error) {
+	// Convert to lowercase ASCII, transliterating accented and non-Latin letters
+	slug := utils.Transliterate(name)
 
 	// Replace spaces with hyphens
 	slug = strings.ReplaceAll(slug, " ", "-")
@@ -395,12 +1115,87 @@ func (s *InstanceService) generateSlug(name string) string {
 	// Trim hyphens from start and end
 	slug = strings.Trim(slug, "-")
 
-	return slug
+	// Names in scripts without a transliteration get a random slug instead
+	if slug == "" {
+		suffix, err := utils.GenerateRandomString(slugSuffixLength)
+		if err != nil {
+			return "", err
+		}
+		slug = "instance-" + strings.ToLower(suffix)
+	}
+
+	slug, err := truncateSlug(username, slug)
+	if err != nil {
+		return "", err
+	}
+
+	if utils.IsReservedName(slug, s.config.Settings().ReservedNames) {
+		return "", fmt.Errorf("instance name is reserved")
+	}
+
+	return slug, nil
+}
+
+// truncateSlug shortens a slug so that it fits the slug column and the
+// "username-slug" subdomain label, with room left for a collision suffix
+func truncateSlug(username, slug string) (string, error) {
+	maxLength := min(maxSlugLength, maxLabelLength-len(username)-1-(1+slugSuffixLength))
+	if maxLength <= 0 {
+		return "", fmt.Errorf("username is too long for instance subdomains")
+	}
+	if len(slug) > maxLength {
+		slug = strings.TrimRight(slug[:maxLength], "-")
+	}
+	return slug, nil
+}
+
+// uniqueSlug returns baseSlug, or baseSlug with a random suffix when the
+// resulting subdomain or storage path is already in use
+func (s *InstanceService) uniqueSlug(ctx context.Context, username, baseSlug string, region *models.Region) (string, error) {
+	slug := baseSlug
+	for attempt := 0; attempt < slugAttempts; attempt++ {
+		if attempt > 0 {
+			suffix, err := utils.GenerateRandomString(slugSuffixLength)
+			if err != nil {
+				return "", err
+			}
+			slug = baseSlug + "-" + strings.ToLower(suffix)
+		}
+
+		if len(username)+1+len(slug) > maxLabelLength {
+			return "", fmt.Errorf("subdomain label %s-%s is longer than %d characters", username, slug, maxLabelLength)
+		}
+
+		inUse, err := s.store.SubdomainInUse(ctx, s.generateSubdomain(username, slug, region))
+		if err != nil {
+			return "", err
+		}
+		if inUse {
+			continue
+		}
+
+		// Archived instances keep their data folder for a while
+		if _, err := os.Stat(s.generateStoragePath(username, slug)); err == nil {
+			continue
+		}
+
+		return slug, nil
+	}
+
+	return "", fmt.Errorf("failed to generate a unique slug")
 }
 
 // generateSubdomain creates the full subdomain for the instance
-func (s *InstanceService) generateSubdomain(username, slug string) string {
-	return fmt.Sprintf("%s-%s.%s", username, slug, s.config.BaseDomain)
+func (s *InstanceService) generateSubdomain(username, slug string, region *models.Region) string {
+	return fmt.Sprintf("%s-%s.%s", username, slug, region.BaseDomain)
+}
+
+// regionNetwork returns the Docker network of a region ("" for the configured default)
+func regionNetwork(region *models.Region) string {
+	if region.DockerNetwork == nil {
+		return ""
+	}
+	return *region.DockerNetwork
 }
 
 // generateContainerName creates a unique container name
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestArchivePendingDeletion(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(stored *models.Instance) // applied after the worker read the instance
+		wantArchived bool
+	}{
+		{
+			name:         "grace period ended",
+			change:       func(stored *models.Instance) {},
+			wantArchived: true,
+		},
+		{
+			name:   "deletion cancelled",
+			change: func(stored *models.Instance) { stored.Status = models.InstanceStatusRunning },
+		},
+		{
+			name:   "protection turned on",
+			change: func(stored *models.Instance) { stored.Protected = true },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			dataPath := filepath.Join(base, "instance")
+			writeTestFile(t, filepath.Join(dataPath, "data.db"), "data")
+
+			instance := newTestInstance(uuid.New(), models.InstanceStatusPendingDeletion)
+			instance.DataPath = dataPath
+			store := newFakeInstanceStore(instance)
+			runtime := &fakeRuntime{}
+			service := newTestInstanceService(store, runtime)
+			service.config.InstancesBasePath = base
+
+			pending := *instance
+			tt.change(store.instances[instance.ID])
+
+			service.archivePendingDeletion(context.Background(), &pending, 0)
+
+			_, live := store.instances[instance.ID]
+			_, statErr := os.Stat(filepath.Join(dataPath, "data.db"))
+			if tt.wantArchived {
+				if live || len(runtime.calls) != 2 || !os.IsNotExist(statErr) {
+					t.Errorf("live = %v, container calls = %v, data error = %v, want the instance archived and removed", live, runtime.calls, statErr)
+				}
+				return
+			}
+
+			if !live {
+				t.Fatal("the instance was archived")
+			}
+			if len(runtime.calls) != 0 {
+				t.Errorf("container calls = %v, want none", runtime.calls)
+			}
+			if statErr != nil {
+				t.Errorf("instance data was removed: %v", statErr)
+			}
+		})
+	}
+}
+
+func TestArchiveInstanceWithoutRetention(t *testing.T) {
+	tests := []struct {
+		name          string
+		storageRoot   bool // the data path is inside a storage volume, so it can be removed
+		wantAvailable bool
+	}{
+		{"data removed", true, false},
+		{"removal failed", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			dataPath := filepath.Join(base, "instance")
+			writeTestFile(t, filepath.Join(dataPath, "data.db"), "data")
+
+			userID := uuid.New()
+			instance := newTestInstance(userID, models.InstanceStatusStopped)
+			instance.DataPath = dataPath
+			store := newFakeInstanceStore(instance)
+			service := newTestInstanceService(store, &fakeRuntime{})
+			if tt.storageRoot {
+				service.config.InstancesBasePath = base
+			}
+
+			archived, err := service.archiveInstance(context.Background(), instance, userID, 0, models.DeletionReasonManual, "")
+			if err != nil {
+				t.Fatalf("archiveInstance() error = %v", err)
+			}
+
+			if archived.DataAvailable != tt.wantAvailable || store.archived[instance.ID].DataAvailable != tt.wantAvailable {
+				t.Errorf("data available = %v (stored %v), want %v",
+					archived.DataAvailable, store.archived[instance.ID].DataAvailable, tt.wantAvailable)
+			}
+			if _, err := os.Stat(dataPath); os.IsNotExist(err) == tt.wantAvailable {
+				t.Errorf("data directory exists = %v, want %v", err == nil, tt.wantAvailable)
+			}
+		})
+	}
+}
+
+func TestUniqueSlugFitsSubdomainLabel(t *testing.T) {
+	region := &models.Region{BaseDomain: "example.com"}
+	longSlug := strings.Repeat("my-pocketbase-project-", 4)
+
+	tests := []struct {
+		name     string
+		username string
+		taken    bool // the first subdomain is in use, so a suffix is added
+	}{
+		{"short username", "alice", false},
+		{"short username with suffix", "alice", true},
+		{"50 character username", strings.Repeat("u", 50), false},
+		{"50 character username with suffix", strings.Repeat("u", 50), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeInstanceStore()
+			service := newTestInstanceService(store, &fakeRuntime{})
+
+			baseSlug, err := truncateSlug(tt.username, longSlug)
+			if err != nil {
+				t.Fatalf("truncateSlug() error = %v", err)
+			}
+			if tt.taken {
+				store.subdomains = map[string]bool{service.generateSubdomain(tt.username, baseSlug, region): true}
+			}
+
+			slug, err := service.uniqueSlug(context.Background(), tt.username, baseSlug, region)
+			if err != nil {
+				t.Fatalf("uniqueSlug() error = %v", err)
+			}
+			if tt.taken == (slug == baseSlug) {
+				t.Errorf("uniqueSlug() = %q from %q, want a suffix only when taken", slug, baseSlug)
+			}
+
+			subdomain := service.generateSubdomain(tt.username, slug, region)
+			label := strings.TrimSuffix(subdomain, "."+region.BaseDomain)
+			if len(label) > maxLabelLength {
+				t.Errorf("subdomain label %q is %d characters, want at most %d", label, len(label), maxLabelLength)
+			}
+			if strings.HasSuffix(slug, "-") || strings.Contains(slug, "--") {
+				t.Errorf("slug %q has stray hyphens", slug)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// StatusTokenPrefix marks instance status tokens, which only allow reading
+// an instance's status and are meant to be pasted into uptime monitors
+const StatusTokenPrefix = "pps_"
+
+// PublicInstanceStatus is what the public status endpoint reveals about an instance
+type PublicInstanceStatus struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	Healthy   bool       `json:"healthy"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	CheckedAt time.Time  `json:"checked_at"`
+}
+
+// RotateStatusToken creates a new status token for an instance, replacing the
+// previous one. The token is only returned here.
+func (s *InstanceService) RotateStatusToken(ctx context.Context, instanceID, userID uuid.UUID) (string, *models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := StatusTokenPrefix + secret
+
+	hash := utils.HashRefreshToken(token)
+	prefix := token[:len(StatusTokenPrefix)+8]
+	if err := s.store.SetStatusToken(ctx, instance, &hash, &prefix); err != nil {
+		return "", nil, err
+	}
+
+	return token, instance, nil
+}
+
+// RevokeStatusToken disables an instance's public status endpoint
+func (s *InstanceService) RevokeStatusToken(ctx context.Context, instanceID, userID uuid.UUID) (*models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.RevokeStatusToken(ctx, instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// GetPublicStatus reports the status of the instance a status token belongs
+// to. An instance is healthy while it is running, reachable through the proxy
+// and its container is up.
+func (s *InstanceService) GetPublicStatus(ctx context.Context, token string) (*PublicInstanceStatus, error) {
+	instance, err := s.store.FindInstanceByStatusToken(ctx, utils.HashRefreshToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &PublicInstanceStatus{
+		Name:      instance.Name,
+		Status:    instance.Status,
+		CheckedAt: time.Now().UTC(),
+	}
+
+	if instance.Status != models.InstanceStatusRunning || instance.RoutingSuspended || instance.ContainerID == nil || *instance.ContainerID == "" {
+		return status, nil
+	}
+
+	stats, err := s.dockerClient.GetContainerStats(ctx, *instance.ContainerID)
+	if err != nil {
+		// The container being gone or Docker being unreachable both mean the
+		// instance can't serve requests
+		return status, nil
+	}
+
+	status.Healthy = stats.Status == "running" && stats.Health == "healthy"
+	if startedAt, err := time.Parse(time.RFC3339Nano, stats.StartedAt); err == nil {
+		status.StartedAt = &startedAt
+	}
+
+	return status, nil
+}
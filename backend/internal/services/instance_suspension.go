@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SuspendInstance stops an instance and takes it off the proxy until the
+// suspension is lifted; its owner cannot start it in the meantime. It is
+// meant for admins and quota enforcement, so no user is authorized.
+func (s *InstanceService) SuspendInstance(ctx context.Context, instanceID uuid.UUID, reason string) (*models.Instance, error) {
+	instance, err := s.store.FindInstanceByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	if instance.Status == models.InstanceStatusSuspended {
+		return nil, fmt.Errorf("instance is already suspended")
+	}
+
+	if instance.ContainerID != nil && *instance.ContainerID != "" {
+		if instance.Status == models.InstanceStatusRunning {
+			if err := s.dockerClient.StopContainer(ctx, *instance.ContainerID); err != nil {
+				return nil, fmt.Errorf("failed to stop container: %w", err)
+			}
+		}
+
+		// Without Traefik labels the subdomain falls through to the suspension page
+		if err := s.setProxyEnabled(ctx, instance, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.store.Suspend(ctx, instance, reason); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Instance suspended: %s (%s)\n", instance.Name, reason)
+	return instance, nil
+}
+
+// UnsuspendInstance lifts a suspension. The instance stays stopped until its
+// owner starts it.
+func (s *InstanceService) UnsuspendInstance(ctx context.Context, instanceID uuid.UUID) (*models.Instance, error) {
+	instance, err := s.store.FindInstanceByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.Status != models.InstanceStatusSuspended {
+		return nil, fmt.Errorf("instance is not suspended")
+	}
+
+	if instance.ContainerID != nil && *instance.ContainerID != "" {
+		if err := s.setProxyEnabled(ctx, instance, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.store.Unsuspend(ctx, instance); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Instance suspension lifted: %s\n", instance.Name)
+	return instance, nil
+}
+
+// FindInstanceByHost returns the instance served on a request host (the
+// subdomain, optionally with a port)
+func (s *InstanceService) FindInstanceByHost(ctx context.Context, host string) (*models.Instance, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return s.store.FindInstanceBySubdomain(ctx, strings.ToLower(host))
+}
+
+// setProxyEnabled switches Traefik routing of an instance's container, which
+// recreates the container
+func (s *InstanceService) setProxyEnabled(ctx context.Context, instance *models.Instance, enabled bool) error {
+	containerID, err := s.dockerClient.SetProxyEnabled(ctx, *instance.ContainerID, enabled)
+	if updateErr := s.recordRecreatedContainer(ctx, instance, containerID); updateErr != nil {
+		return updateErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update proxy routing: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Operations that can be run on all instances matching a tag selector
+const (
+	BulkActionStart   = "start"
+	BulkActionStop    = "stop"
+	BulkActionRestart = "restart"
+)
+
+// BulkResult is the outcome of a bulk operation on one instance
+type BulkResult struct {
+	InstanceID uuid.UUID `json:"instance_id"`
+	Name       string    `json:"name"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SetInstanceTags replaces the tags of an instance. Keys are lowercased.
+func (s *InstanceService) SetInstanceTags(ctx context.Context, instanceID, userID uuid.UUID, tags models.Tags) (*models.Instance, error) {
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make(models.Tags, len(tags))
+	for key, value := range tags {
+		normalized[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if err := normalized.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.SetTags(ctx, instance, normalized); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// FilterByTags keeps the instances matching every selector
+func FilterByTags(instances []models.Instance, selectors []models.TagSelector) []models.Instance {
+	if len(selectors) == 0 {
+		return instances
+	}
+
+	matched := []models.Instance{}
+	for _, instance := range instances {
+		if instance.Tags.Matches(selectors) {
+			matched = append(matched, instance)
+		}
+	}
+	return matched
+}
+
+// BulkOperation runs an action on each of the user's instances matching every
+// selector, one after the other, and reports the outcome per instance.
+// Instances that are already in the requested state are skipped.
+func (s *InstanceService) BulkOperation(ctx context.Context, userID uuid.UUID, action string, selectors []models.TagSelector) ([]BulkResult, error) {
+	var run func(ctx context.Context, instanceID, userID uuid.UUID) error
+	switch action {
+	case BulkActionStart:
+		run = s.StartInstance
+	case BulkActionStop:
+		run = s.StopInstance
+	case BulkActionRestart:
+		run = s.RestartInstance
+	default:
+		return nil, fmt.Errorf("action must be start, stop or restart")
+	}
+
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("at least one tag selector is required")
+	}
+
+	instances, err := s.ListUserInstances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []BulkResult{}
+	for _, instance := range FilterByTags(instances, selectors) {
+		if (action == BulkActionStart && instance.Status == models.InstanceStatusRunning) ||
+			(action == BulkActionStop && instance.Status == models.InstanceStatusStopped) {
+			continue
+		}
+
+		result := BulkResult{InstanceID: instance.ID, Name: instance.Name, Success: true}
+		if err := run(ctx, instance.ID, userID); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MaxInstanceDescriptionLength is the longest description, in characters
+const MaxInstanceDescriptionLength = 4000
+
+// UpdateInstanceParams are the instance fields a PATCH may change; nil fields
+// are left as they are
+type UpdateInstanceParams struct {
+	Description *string `json:"description"`
+	Protected   *bool   `json:"protected"` // deletion protection
+}
+
+// UpdateInstance changes an instance's editable details
+func (s *InstanceService) UpdateInstance(ctx context.Context, instanceID, userID uuid.UUID, params UpdateInstanceParams) (*models.Instance, error) {
+	if params.Description == nil && params.Protected == nil {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	var description string
+	if params.Description != nil {
+		description = strings.TrimSpace(*params.Description)
+		if utf8.RuneCountInString(description) > MaxInstanceDescriptionLength {
+			return nil, fmt.Errorf("description must be at most %d characters", MaxInstanceDescriptionLength)
+		}
+	}
+
+	instance, err := s.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Protection doesn't stop a deletion that is already scheduled; the user
+	// cancels the deletion instead
+	if params.Protected != nil && *params.Protected && instance.Status == models.InstanceStatusPendingDeletion {
+		return nil, fmt.Errorf("instance is pending deletion")
+	}
+
+	if params.Description != nil && description != instance.Description {
+		if err := s.store.UpdateDescription(ctx, instance, description); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.Protected != nil && *params.Protected != instance.Protected {
+		if err := s.store.SetProtected(ctx, instance, *params.Protected); err != nil {
+			return nil, err
+		}
+	}
+
+	return instance, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateInstanceProtection(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		wantErr   bool
+		protected bool
+	}{
+		{"running", models.InstanceStatusRunning, false, true},
+		{"pending deletion", models.InstanceStatusPendingDeletion, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			instance := newTestInstance(userID, tt.status)
+			store := newFakeInstanceStore(instance)
+			protected := true
+
+			_, err := newTestInstanceService(store, &fakeRuntime{}).UpdateInstance(context.Background(), instance.ID, userID,
+				UpdateInstanceParams{Protected: &protected})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateInstance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if store.instances[instance.ID].Protected != tt.protected {
+				t.Errorf("protected = %v, want %v", store.instances[instance.ID].Protected, tt.protected)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+)
+
+// InstanceValidation is the outcome of a dry run of CreateInstance
+type InstanceValidation struct {
+	Valid bool `json:"valid"`
+
+	// The slug and subdomain the instance would get if created now (a
+	// collision suffix is random, so a later create may pick another one)
+	Slug      string `json:"slug,omitempty"`
+	Subdomain string `json:"subdomain,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Region    string `json:"region,omitempty"`
+
+	// Why the name's own subdomain is unavailable, when a suffix was needed
+	SubdomainReserved *models.SubdomainReservation `json:"subdomain_reserved,omitempty"`
+
+	Violations []string `json:"violations"`
+}
+
+// ValidateInstance runs the checks of CreateInstance without creating
+// anything, collecting every violation instead of stopping at the first.
+// Only unexpected failures (e.g. the database being unavailable) return an error.
+func (s *InstanceService) ValidateInstance(ctx context.Context, req CreateInstanceRequest) (*InstanceValidation, error) {
+	result := &InstanceValidation{Violations: []string{}}
+	violation := func(err error) {
+		result.Violations = append(result.Violations, err.Error())
+	}
+
+	nameValid := true
+	if err := s.validateInstanceName(req.Name); err != nil {
+		violation(err)
+		nameValid = false
+	}
+
+	count, err := s.store.CountUserInstances(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count user instances: %w", err)
+	}
+	maxInstances, err := s.instanceLimit(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxInstances {
+		violation(&models.InstanceLimitError{Limit: maxInstances})
+	}
+
+	if err := s.checkDiskSpace(); err != nil {
+		violation(err)
+	}
+
+	region, err := s.regions.ResolveRegion(ctx, req.RegionID)
+	if err != nil {
+		if err.Error() != "region not found" && err.Error() != "region is not available" {
+			return nil, err
+		}
+		violation(err)
+	} else {
+		result.Region = region.ID
+	}
+
+	if _, err := s.images.ResolveImage(ctx, req.ImageID, req.UserID); err != nil {
+		if err.Error() != "image not found" && err.Error() != "image is not available" {
+			return nil, err
+		}
+		violation(err)
+	}
+
+	// The subdomain depends on a valid name and region
+	if nameValid && region != nil {
+		baseSlug, err := s.generateSlug(req.Username, req.Name)
+		if err == nil {
+			reservation, err := s.store.FindSubdomainReservation(ctx, s.generateSubdomain(req.Username, baseSlug, region))
+			if err != nil {
+				return nil, err
+			}
+			result.SubdomainReserved = reservation
+		}
+		if err == nil {
+			baseSlug, err = s.uniqueSlug(ctx, req.Username, baseSlug, region)
+		}
+
+		switch {
+		case err == nil:
+			result.Slug = baseSlug
+			result.Subdomain = s.generateSubdomain(req.Username, baseSlug, region)
+			// The host port of port routing mode is only allocated on create
+			if s.config.RoutingMode != config.RoutingModePort {
+				result.URL = s.SubdomainURL(result.Subdomain)
+			}
+		case err.Error() == "instance name is reserved" || err.Error() == "failed to generate a unique slug":
+			violation(err)
+		default:
+			return nil, err
+		}
+	}
+
+	result.Valid = len(result.Violations) == 0
+
+	return result, nil
+}
+
+// ErrInsufficientDiskSpace is returned (wrapped with the refused action) when
+// an operation would leave less than MIN_FREE_DISK_SPACE free
+var ErrInsufficientDiskSpace = errors.New("not enough disk space")
+
+// checkDiskSpace fails when the instances volume has less than MIN_FREE_DISK_SPACE
+// available. If free space cannot be determined the check is skipped.
+func (s *InstanceService) checkDiskSpace() error {
+	return s.checkDiskHeadroom(s.config.InstancesBasePath, 0, "create an instance")
+}
+
+// checkDiskHeadroom fails when writing needed bytes to the filesystem holding
+// path would leave less than MIN_FREE_DISK_SPACE available. The error reads
+// "not enough disk space to <action>"; host details are only logged.
+func (s *InstanceService) checkDiskHeadroom(path string, needed int64, action string) error {
+	if s.config.MinFreeDiskSpace <= 0 {
+		return nil
+	}
+
+	free, err := docker.FreeDiskSpace(path)
+	if err != nil {
+		log.Printf("Warning: skipping disk space check: %v", err)
+		return nil
+	}
+
+	if free-needed < s.config.MinFreeDiskSpace {
+		log.Printf("Refusing to %s: %d bytes free on %s, %d needed and %d kept in reserve", action, free, path, needed, s.config.MinFreeDiskSpace)
+		return fmt.Errorf("%w to %s", ErrInsufficientDiskSpace, action)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// InviteService handles invitation code business logic
+type InviteService struct {
+	inviteRepo *repositories.InviteRepository
+	config     *config.Config
+}
+
+// NewInviteService creates a new invite service
+func NewInviteService(inviteRepo *repositories.InviteRepository, cfg *config.Config) *InviteService {
+	return &InviteService{
+		inviteRepo: inviteRepo,
+		config:     cfg,
+	}
+}
+
+// GenerateInviteParams contains parameters for generating an invite code
+type GenerateInviteParams struct {
+	CreatedByUserID string
+	MaxUses         int
+	ExpiresIn       time.Duration
+	Note            string
+}
+
+// GenerateInvite creates a new invite code
+func (s *InviteService) GenerateInvite(params GenerateInviteParams) (*models.InviteCode, error) {
+	code, err := utils.GenerateRandomString(12)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	maxUses := params.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+
+	invite := &models.InviteCode{
+		ID:              uuid.New().String(),
+		Code:            code,
+		MaxUses:         maxUses,
+		CreatedByUserID: &params.CreatedByUserID,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	if note := strings.TrimSpace(params.Note); note != "" {
+		invite.Note = &note
+	}
+
+	if params.ExpiresIn > 0 {
+		expiresAt := invite.CreatedAt.Add(params.ExpiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.inviteRepo.Create(invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// ListInvites retrieves all invite codes
+func (s *InviteService) ListInvites() ([]*models.InviteCode, error) {
+	invites, err := s.inviteRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+	return invites, nil
+}
+
+// RevokeInvite revokes an invite code so it can no longer be redeemed
+func (s *InviteService) RevokeInvite(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("invite code not found")
+	}
+
+	if err := s.inviteRepo.Revoke(id); err != nil {
+		if err.Error() == "invite code not found or already revoked" {
+			return err
+		}
+		return fmt.Errorf("failed to revoke invite code: %w", err)
+	}
+	return nil
+}
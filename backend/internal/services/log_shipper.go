@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/logship"
+	"pocketploy/internal/models"
+)
+
+// logShipperCursorTTL bounds how long the position in a stopped instance's
+// logs is remembered
+const logShipperCursorTTL = 30 * 24 * time.Hour
+
+// LogShipper periodically copies the logs of running instances to a sink, so
+// they survive the container being recreated. The timestamp of the last
+// shipped line is kept per instance (not per container) in the cache store.
+type LogShipper struct {
+	instances    InstanceStore
+	dockerClient ContainerRuntime
+	store        cache.Store
+	sink         logship.Sink
+	interval     time.Duration
+}
+
+// NewLogShipper creates a shipper copying new log lines every interval
+func NewLogShipper(instances InstanceStore, dockerClient ContainerRuntime, store cache.Store, sink logship.Sink, interval time.Duration) *LogShipper {
+	return &LogShipper{
+		instances:    instances,
+		dockerClient: dockerClient,
+		store:        store,
+		sink:         sink,
+		interval:     interval,
+	}
+}
+
+// Run ships logs until ctx is cancelled
+func (s *LogShipper) Run(ctx context.Context) {
+	if s.sink == nil || s.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.ship(ctx)
+		}
+	}
+}
+
+// ship copies the lines written since the last run for every running instance
+func (s *LogShipper) ship(ctx context.Context) {
+	instances, err := s.instances.FindInstancesByStatus(ctx, models.InstanceStatusRunning)
+	if err != nil {
+		log.Printf("Warning: log shipping skipped: %v", err)
+		return
+	}
+
+	for i := range instances {
+		instance := &instances[i]
+		if instance.ContainerID == nil || *instance.ContainerID == "" {
+			continue
+		}
+		if err := s.shipInstance(ctx, instance); err != nil {
+			log.Printf("Warning: failed to ship logs of instance %s: %v", instance.ID, err)
+		}
+	}
+}
+
+func (s *LogShipper) shipInstance(ctx context.Context, instance *models.Instance) error {
+	key := "log_shipper:cursor:" + instance.ID.String()
+
+	var cursor time.Time
+	value, found, err := s.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	opts := docker.LogOptions{Tail: "all"}
+	if found {
+		if cursor, err = time.Parse(time.RFC3339Nano, string(value)); err == nil {
+			opts.Since = cursor.Format(time.RFC3339Nano)
+		}
+	}
+
+	entries, err := s.dockerClient.ReadContainerLogs(ctx, *instance.ContainerID, opts)
+	if err != nil {
+		return err
+	}
+
+	// Since is inclusive, so the last shipped line comes back
+	fresh := entries[:0]
+	for _, entry := range entries {
+		if entry.Timestamp != nil && !entry.Timestamp.After(cursor) {
+			continue
+		}
+		fresh = append(fresh, entry)
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	stream := logship.Stream{
+		InstanceID: instance.ID.String(),
+		Subdomain:  instance.Subdomain,
+		DataPath:   instance.DataPath,
+	}
+	if err := s.sink.Ship(ctx, stream, fresh); err != nil {
+		return err
+	}
+
+	for i := len(fresh) - 1; i >= 0; i-- {
+		if fresh[i].Timestamp != nil {
+			return s.store.Set(ctx, key, []byte(fresh[i].Timestamp.Format(time.RFC3339Nano)), logShipperCursorTTL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ManifestVersion is the format version of exported manifests
+const ManifestVersion = 1
+
+// ErrInvalidManifest is returned (wrapped with the reason) for manifests that
+// can't be imported
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// InstanceManifest is a portable description of an instance's configuration,
+// used to recreate it on another platform (disaster recovery, moving to a
+// self-hosted deployment). It carries no data and no secrets: the data moves
+// with a backup, basic auth passwords and the settings encryption key are not
+// exported. Backup schedules and resource limits are platform-wide settings
+// in this release, so they are not part of the manifest.
+type InstanceManifest struct {
+	Version     int       `json:"version" yaml:"version"`
+	ExportedAt  time.Time `json:"exported_at" yaml:"exported_at"`
+	Name        string    `json:"name" yaml:"name"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Where the instance was served; an import gets its own subdomain
+	Subdomain string `json:"subdomain,omitempty" yaml:"subdomain,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+
+	// Image is the container image reference, which determines the PocketBase
+	// version; on import it must be POCKETBASE_IMAGE or an approved image
+	Image string `json:"image" yaml:"image"`
+
+	Protected bool              `json:"protected,omitempty" yaml:"protected,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	Serve  ManifestServe  `json:"serve" yaml:"serve"`
+	Access ManifestAccess `json:"access" yaml:"access"`
+	Crons  []ManifestCron `json:"crons,omitempty" yaml:"crons,omitempty"`
+}
+
+// ManifestServe mirrors models.ServeOptions
+type ManifestServe struct {
+	EncryptSettings bool     `json:"encrypt_settings,omitempty" yaml:"encrypt_settings,omitempty"`
+	HooksDir        string   `json:"hooks_dir,omitempty" yaml:"hooks_dir,omitempty"`
+	MigrationsDir   string   `json:"migrations_dir,omitempty" yaml:"migrations_dir,omitempty"`
+	PublicDir       string   `json:"public_dir,omitempty" yaml:"public_dir,omitempty"`
+	Origins         []string `json:"origins,omitempty" yaml:"origins,omitempty"`
+	QueryTimeout    int      `json:"query_timeout,omitempty" yaml:"query_timeout,omitempty"`
+}
+
+// ManifestAccess is the IP allowlist of an instance (basic auth needs a
+// password, which is never exported)
+type ManifestAccess struct {
+	AllowedIPs []string `json:"allowed_ips,omitempty" yaml:"allowed_ips,omitempty"`
+}
+
+// ManifestCron is a scheduled task
+type ManifestCron struct {
+	Name        string   `json:"name" yaml:"name"`
+	Schedule    string   `json:"schedule" yaml:"schedule"`
+	Kind        string   `json:"kind" yaml:"kind"`
+	CommandArgs []string `json:"command_args,omitempty" yaml:"command_args,omitempty"`
+	HTTPMethod  string   `json:"http_method,omitempty" yaml:"http_method,omitempty"`
+	HTTPPath    string   `json:"http_path,omitempty" yaml:"http_path,omitempty"`
+	Enabled     bool     `json:"enabled" yaml:"enabled"`
+}
+
+// ImportManifestRequest creates an instance from a manifest. Name and Region
+// override the manifest's when set.
+type ImportManifestRequest struct {
+	UserID        uuid.UUID
+	Username      string
+	AdminEmail    string
+	AdminPassword string
+	Name          string
+	Region        string
+	Manifest      InstanceManifest
+}
+
+// ImportManifestResult is the created instance. Settings that could not be
+// applied after it was created are listed in Warnings; the instance is kept.
+type ImportManifestResult struct {
+	Instance *models.Instance `json:"instance"`
+	URL      string           `json:"url"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// ManifestService exports instance configuration as manifests and creates
+// instances from them
+type ManifestService struct {
+	instances *InstanceService
+	crons     *CronService
+	images    *ImageService
+	config    *config.Config
+}
+
+// NewManifestService creates a new manifest service
+func NewManifestService(instances *InstanceService, crons *CronService, images *ImageService, cfg *config.Config) *ManifestService {
+	return &ManifestService{instances: instances, crons: crons, images: images, config: cfg}
+}
+
+// ExportManifest describes an instance's configuration
+func (s *ManifestService) ExportManifest(ctx context.Context, instanceID, userID uuid.UUID) (*InstanceManifest, error) {
+	instance, err := s.instances.GetInstance(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	image := s.config.Settings().PocketBaseImage
+	if instance.ImageID != nil {
+		approved, err := s.images.FindImage(ctx, *instance.ImageID)
+		if err != nil {
+			return nil, err
+		}
+		image = approved.Reference
+	}
+
+	crons, err := s.crons.ListCrons(ctx, instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &InstanceManifest{
+		Version:     ManifestVersion,
+		ExportedAt:  time.Now().UTC(),
+		Name:        instance.Name,
+		Description: instance.Description,
+		Subdomain:   instance.Subdomain,
+		URL:         s.instances.InstanceURL(instance),
+		Region:      instance.RegionID,
+		Image:       image,
+		Protected:   instance.Protected,
+		Tags:        instance.Tags,
+		Serve: ManifestServe{
+			EncryptSettings: instance.ServeOptions.EncryptSettings,
+			HooksDir:        instance.ServeOptions.HooksDir,
+			MigrationsDir:   instance.ServeOptions.MigrationsDir,
+			PublicDir:       instance.ServeOptions.PublicDir,
+			Origins:         instance.ServeOptions.Origins,
+			QueryTimeout:    instance.ServeOptions.QueryTimeout,
+		},
+		Access: ManifestAccess{AllowedIPs: instance.AccessProtection.AllowedIPs},
+	}
+
+	for _, cron := range crons {
+		entry := ManifestCron{
+			Name:        cron.Name,
+			Schedule:    cron.Schedule,
+			Kind:        cron.Kind,
+			CommandArgs: cron.CommandArgs,
+			Enabled:     cron.Enabled,
+		}
+		if cron.HTTPMethod != nil {
+			entry.HTTPMethod = *cron.HTTPMethod
+		}
+		if cron.HTTPPath != nil {
+			entry.HTTPPath = *cron.HTTPPath
+		}
+		manifest.Crons = append(manifest.Crons, entry)
+	}
+
+	return manifest, nil
+}
+
+// ImportManifest creates an instance from a manifest and applies its serve
+// options, access allowlist, tags, deletion protection and scheduled tasks
+func (s *ManifestService) ImportManifest(ctx context.Context, req ImportManifestRequest) (*ImportManifestResult, error) {
+	manifest := req.Manifest
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d (expected %d)", ErrInvalidManifest, manifest.Version, ManifestVersion)
+	}
+
+	name := manifest.Name
+	if req.Name != "" {
+		name = req.Name
+	}
+	if len(name) < 3 || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be between 3 and 100 characters", ErrInvalidManifest)
+	}
+	region := manifest.Region
+	if req.Region != "" {
+		region = req.Region
+	}
+
+	imageID, err := s.resolveManifestImage(ctx, manifest.Image, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.instances.CreateInstance(ctx, CreateInstanceRequest{
+		UserID:        req.UserID,
+		Username:      req.Username,
+		Name:          name,
+		AdminEmail:    req.AdminEmail,
+		AdminPassword: req.AdminPassword,
+		RegionID:      region,
+		ImageID:       imageID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instanceID := created.Instance.ID
+	result := &ImportManifestResult{Instance: created.Instance, URL: created.URL}
+	warn := func(setting string, err error) {
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %v", setting, err))
+		}
+	}
+
+	if manifest.Description != "" || manifest.Protected {
+		params := UpdateInstanceParams{}
+		if manifest.Description != "" {
+			params.Description = &manifest.Description
+		}
+		if manifest.Protected {
+			params.Protected = &manifest.Protected
+		}
+		_, err := s.instances.UpdateInstance(ctx, instanceID, req.UserID, params)
+		warn("details", err)
+	}
+
+	serve := models.ServeOptions{
+		EncryptSettings: manifest.Serve.EncryptSettings,
+		HooksDir:        manifest.Serve.HooksDir,
+		MigrationsDir:   manifest.Serve.MigrationsDir,
+		PublicDir:       manifest.Serve.PublicDir,
+		Origins:         manifest.Serve.Origins,
+		QueryTimeout:    manifest.Serve.QueryTimeout,
+	}
+	if serve.EncryptSettings || serve.HooksDir != "" || serve.MigrationsDir != "" || serve.PublicDir != "" ||
+		len(serve.Origins) > 0 || serve.QueryTimeout > 0 {
+		_, err := s.instances.UpdateServeOptions(ctx, instanceID, req.UserID, serve)
+		warn("serve options", err)
+	}
+
+	if len(manifest.Access.AllowedIPs) > 0 {
+		_, err := s.instances.UpdateAccessProtection(ctx, instanceID, req.UserID, UpdateAccessRequest{AllowedIPs: manifest.Access.AllowedIPs})
+		warn("access", err)
+	}
+
+	if len(manifest.Tags) > 0 {
+		_, err := s.instances.SetInstanceTags(ctx, instanceID, req.UserID, manifest.Tags)
+		warn("tags", err)
+	}
+
+	for _, cron := range manifest.Crons {
+		params := InstanceCronParams{
+			Name:        &cron.Name,
+			Schedule:    &cron.Schedule,
+			Kind:        &cron.Kind,
+			CommandArgs: cron.CommandArgs,
+			Enabled:     &cron.Enabled,
+		}
+		if cron.HTTPMethod != "" {
+			params.HTTPMethod = &cron.HTTPMethod
+		}
+		if cron.HTTPPath != "" {
+			params.HTTPPath = &cron.HTTPPath
+		}
+		_, err := s.crons.CreateCron(ctx, instanceID, req.UserID, params)
+		warn("scheduled task "+cron.Name, err)
+	}
+
+	if instance, err := s.instances.GetInstance(ctx, instanceID, req.UserID); err == nil {
+		result.Instance = instance
+	}
+
+	return result, nil
+}
+
+// resolveManifestImage finds the approved image (by reference or pinned
+// reference) a manifest's image refers to; POCKETBASE_IMAGE resolves to ""
+func (s *ManifestService) resolveManifestImage(ctx context.Context, ref string, userID uuid.UUID) (string, error) {
+	if ref == "" || ref == s.config.Settings().PocketBaseImage {
+		return "", nil
+	}
+
+	images, err := s.images.ListImages(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, image := range images {
+		if image.Reference == ref || image.PinnedReference == ref {
+			return image.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: image %s is not available", ErrInvalidManifest, ref)
+}
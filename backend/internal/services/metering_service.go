@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// meteringCheckInterval is how often completed hours are looked for
+	meteringCheckInterval = 5 * time.Minute
+
+	// maxMeteringBackfill bounds how many missed hours are metered after downtime
+	maxMeteringBackfill = 7 * 24 * time.Hour
+)
+
+// MeteringService records hourly usage per user (instance-hours, storage
+// GB-hours and GB of egress) so operators can bill outside Stripe
+type MeteringService struct {
+	db             *sqlx.DB
+	sampleInterval time.Duration
+}
+
+// NewMeteringService creates a metering service reading the resource samples
+// taken every sampleInterval
+func NewMeteringService(db *sqlx.DB, sampleInterval time.Duration) *MeteringService {
+	return &MeteringService{db: db, sampleInterval: sampleInterval}
+}
+
+// Run meters each hour once it has ended until ctx is cancelled
+func (s *MeteringService) Run(ctx context.Context) {
+	ticker := time.NewTicker(meteringCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.meter(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// meter records the completed hours since the last metered one
+func (s *MeteringService) meter(ctx context.Context) {
+	// The last sample of an hour may still be written shortly after it ends
+	current := time.Now().UTC().Add(-s.sampleInterval).Truncate(time.Hour)
+	next := current.Add(-time.Hour)
+
+	last, err := models.GetLastMeteredHour(ctx, s.db)
+	if err != nil {
+		log.Printf("Warning: metering skipped: %v", err)
+		return
+	}
+	if last != nil {
+		next = last.UTC().Add(time.Hour)
+	}
+	if oldest := current.Add(-maxMeteringBackfill); next.Before(oldest) {
+		next = oldest
+	}
+
+	for hour := next; hour.Before(current); hour = hour.Add(time.Hour) {
+		if _, err := models.RecordHourlyMetering(ctx, s.db, hour, s.sampleInterval); err != nil {
+			log.Printf("Warning: failed to meter %s: %v", hour.Format(time.RFC3339), err)
+			return
+		}
+	}
+}
+
+// Export returns the metering records of the hours in [from, to), optionally
+// of a single user
+func (s *MeteringService) Export(ctx context.Context, from, to time.Time, userID *uuid.UUID) ([]models.MeteringRecord, error) {
+	return models.FindMeteringRecords(ctx, s.db, from.UTC(), to.UTC(), userID)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+)
+
+// MetricsCollector periodically records resource usage samples for running instances
+type MetricsCollector struct {
+	store        InstanceStore
+	dockerClient ContainerRuntime
+	interval     time.Duration
+	retention    time.Duration
+}
+
+// NewMetricsCollector creates a collector sampling every interval and keeping samples for retention
+func NewMetricsCollector(store InstanceStore, dockerClient ContainerRuntime, interval, retention time.Duration) *MetricsCollector {
+	return &MetricsCollector{
+		store:        store,
+		dockerClient: dockerClient,
+		interval:     interval,
+		retention:    retention,
+	}
+}
+
+// Run collects samples until ctx is cancelled
+func (c *MetricsCollector) Run(ctx context.Context) {
+	if c.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.collect(ctx)
+			c.prune(ctx)
+		}
+	}
+}
+
+// collect records one sample for every running instance
+func (c *MetricsCollector) collect(ctx context.Context) {
+	instances, err := c.store.FindInstancesByStatus(ctx, models.InstanceStatusRunning)
+	if err != nil {
+		log.Printf("Warning: metrics collection skipped: %v", err)
+		return
+	}
+
+	for i := range instances {
+		instance := &instances[i]
+		if instance.ContainerID == nil || *instance.ContainerID == "" {
+			continue
+		}
+
+		usage, err := c.dockerClient.GetResourceUsage(ctx, *instance.ContainerID)
+		if err != nil {
+			log.Printf("Warning: failed to sample instance %s: %v", instance.ID, err)
+			continue
+		}
+
+		diskBytes, err := docker.DirectorySize(instance.DataPath)
+		if err != nil {
+			log.Printf("Warning: failed to measure data size of instance %s: %v", instance.ID, err)
+		}
+
+		err = c.store.CreateInstanceMetric(ctx, &models.InstanceMetric{
+			InstanceID:       instance.ID,
+			RecordedAt:       time.Now().UTC(),
+			CPUPercent:       usage.CPUPercent,
+			MemoryBytes:      int64(usage.MemoryBytes),
+			MemoryLimitBytes: int64(usage.MemoryLimitBytes),
+			DiskBytes:        diskBytes,
+			NetworkRxBytes:   int64(usage.NetworkRxBytes),
+			NetworkTxBytes:   int64(usage.NetworkTxBytes),
+		})
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// prune removes samples older than the retention period
+func (c *MetricsCollector) prune(ctx context.Context) {
+	if c.retention <= 0 {
+		return
+	}
+
+	if _, err := c.store.DeleteInstanceMetricsBefore(ctx, time.Now().UTC().Add(-c.retention)); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"pocketploy/internal/events"
+	"pocketploy/internal/models"
+)
+
+// InstanceNotifier informs instance owners about problems with their instances
+type InstanceNotifier interface {
+	InstanceFailed(ctx context.Context, instance *models.Instance, reason string)
+	CronFailed(ctx context.Context, instance *models.Instance, cron *models.InstanceCron, reason string)
+	InstanceTakenDown(ctx context.Context, instance *models.Instance, reason string)
+	DatabaseCorrupted(ctx context.Context, instance *models.Instance, report string)
+}
+
+// UsageNotifier informs users about their resource quotas
+type UsageNotifier interface {
+	BandwidthQuotaWarning(ctx context.Context, userID string, usedBytes, quotaBytes int64)
+	BandwidthQuotaExceeded(ctx context.Context, userID string, usedBytes, quotaBytes int64)
+}
+
+// LogNotifier writes owner notifications to the server log
+type LogNotifier struct{}
+
+// InstanceFailed logs that an instance was marked failed
+func (LogNotifier) InstanceFailed(ctx context.Context, instance *models.Instance, reason string) {
+	log.Printf("Notify user %s: instance %s (%s) failed: %s", instance.UserID, instance.Name, instance.ID, reason)
+}
+
+// CronFailed logs that a scheduled task started failing
+func (LogNotifier) CronFailed(ctx context.Context, instance *models.Instance, cron *models.InstanceCron, reason string) {
+	log.Printf("Notify user %s: scheduled task %q on instance %s (%s) failed: %s", instance.UserID, cron.Name, instance.Name, instance.ID, reason)
+}
+
+// InstanceTakenDown logs that an instance was suspended after an abuse report
+func (LogNotifier) InstanceTakenDown(ctx context.Context, instance *models.Instance, reason string) {
+	log.Printf("Notify user %s: instance %s (%s) was taken down: %s", instance.UserID, instance.Name, instance.ID, reason)
+}
+
+// DatabaseCorrupted logs that an integrity check found problems in an instance's database
+func (LogNotifier) DatabaseCorrupted(ctx context.Context, instance *models.Instance, report string) {
+	log.Printf("Notify user %s: database of instance %s (%s) failed its integrity check: %s", instance.UserID, instance.Name, instance.ID, report)
+}
+
+// BandwidthQuotaWarning logs that a user is approaching their monthly bandwidth quota
+func (LogNotifier) BandwidthQuotaWarning(ctx context.Context, userID string, usedBytes, quotaBytes int64) {
+	log.Printf("Notify user %s: %d of %d bytes of monthly bandwidth used", userID, usedBytes, quotaBytes)
+}
+
+// BandwidthQuotaExceeded logs that a user has used up their monthly bandwidth quota
+func (LogNotifier) BandwidthQuotaExceeded(ctx context.Context, userID string, usedBytes, quotaBytes int64) {
+	log.Printf("Notify user %s: monthly bandwidth quota exceeded (%d of %d bytes)", userID, usedBytes, quotaBytes)
+}
+
+// Notification is pushed to a user's open dashboard sessions
+type Notification struct {
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	InstanceID string `json:"instance_id,omitempty"`
+}
+
+// Notification kinds
+const (
+	NotificationInstanceFailed    = "instance_failed"
+	NotificationCronFailed        = "cron_failed"
+	NotificationInstanceTakenDown = "instance_taken_down"
+	NotificationDatabaseCorrupted = "database_corrupted"
+	NotificationBandwidthWarning  = "bandwidth_warning"
+	NotificationBandwidthExceeded = "bandwidth_exceeded"
+)
+
+// EventNotifier logs notifications and pushes them to the user in real time
+type EventNotifier struct {
+	LogNotifier
+	Broker *events.Broker
+}
+
+// InstanceFailed notifies the owner that an instance was marked failed
+func (n EventNotifier) InstanceFailed(ctx context.Context, instance *models.Instance, reason string) {
+	n.LogNotifier.InstanceFailed(ctx, instance, reason)
+	n.Broker.Publish(ctx, instance.UserID.String(), events.TypeNotification, Notification{
+		Kind:       NotificationInstanceFailed,
+		Message:    fmt.Sprintf("Instance %s failed: %s", instance.Name, reason),
+		InstanceID: instance.ID.String(),
+	})
+}
+
+// CronFailed notifies the owner that a scheduled task started failing
+func (n EventNotifier) CronFailed(ctx context.Context, instance *models.Instance, cron *models.InstanceCron, reason string) {
+	n.LogNotifier.CronFailed(ctx, instance, cron, reason)
+	n.Broker.Publish(ctx, instance.UserID.String(), events.TypeNotification, Notification{
+		Kind:       NotificationCronFailed,
+		Message:    fmt.Sprintf("Scheduled task %q on instance %s failed: %s", cron.Name, instance.Name, reason),
+		InstanceID: instance.ID.String(),
+	})
+}
+
+// InstanceTakenDown notifies the owner that an instance was suspended after an abuse report
+func (n EventNotifier) InstanceTakenDown(ctx context.Context, instance *models.Instance, reason string) {
+	n.LogNotifier.InstanceTakenDown(ctx, instance, reason)
+	n.Broker.Publish(ctx, instance.UserID.String(), events.TypeNotification, Notification{
+		Kind:       NotificationInstanceTakenDown,
+		Message:    fmt.Sprintf("Instance %s was suspended following an abuse report", instance.Name),
+		InstanceID: instance.ID.String(),
+	})
+}
+
+// DatabaseCorrupted notifies the owner that an integrity check found problems in an instance's database
+func (n EventNotifier) DatabaseCorrupted(ctx context.Context, instance *models.Instance, report string) {
+	n.LogNotifier.DatabaseCorrupted(ctx, instance, report)
+	n.Broker.Publish(ctx, instance.UserID.String(), events.TypeNotification, Notification{
+		Kind:       NotificationDatabaseCorrupted,
+		Message:    fmt.Sprintf("The database of instance %s failed its integrity check", instance.Name),
+		InstanceID: instance.ID.String(),
+	})
+}
+
+// BandwidthQuotaWarning notifies a user that they are approaching their monthly bandwidth quota
+func (n EventNotifier) BandwidthQuotaWarning(ctx context.Context, userID string, usedBytes, quotaBytes int64) {
+	n.LogNotifier.BandwidthQuotaWarning(ctx, userID, usedBytes, quotaBytes)
+	n.Broker.Publish(ctx, userID, events.TypeNotification, Notification{
+		Kind:    NotificationBandwidthWarning,
+		Message: fmt.Sprintf("%d%% of your monthly bandwidth has been used", usedBytes*100/quotaBytes),
+	})
+}
+
+// BandwidthQuotaExceeded notifies a user that they have used up their monthly bandwidth quota
+func (n EventNotifier) BandwidthQuotaExceeded(ctx context.Context, userID string, usedBytes, quotaBytes int64) {
+	n.LogNotifier.BandwidthQuotaExceeded(ctx, userID, usedBytes, quotaBytes)
+	n.Broker.Publish(ctx, userID, events.TypeNotification, Notification{
+		Kind:    NotificationBandwidthExceeded,
+		Message: "Your monthly bandwidth quota has been exceeded",
+	})
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"pocketploy/internal/metrics"
+
+	"github.com/google/uuid"
+)
+
+// OperationLimiter caps the number of concurrent Docker operations per user
+type OperationLimiter struct {
+	mu       sync.Mutex
+	inFlight map[uuid.UUID]int
+	total    int
+	max      func() int
+	rejected *metrics.CounterVec
+}
+
+// NewOperationLimiter creates a limiter allowing max() concurrent operations per
+// user. max is called on every Acquire so the limit can change at runtime; a
+// max of 0 or less disables the limit.
+func NewOperationLimiter(max func() int, registry *metrics.Registry) *OperationLimiter {
+	l := &OperationLimiter{
+		inFlight: make(map[uuid.UUID]int),
+		max:      max,
+	}
+	if registry != nil {
+		registry.NewGaugeFunc(
+			"pocketploy_instance_operations_in_flight",
+			"Number of instance operations currently running.",
+			l.inFlightTotal,
+		)
+		registry.NewGaugeFunc(
+			"pocketploy_instance_operations_users_active",
+			"Number of users with at least one instance operation running.",
+			l.activeUsers,
+		)
+		l.rejected = registry.NewCounterVec(
+			"pocketploy_instance_operations_rejected_total",
+			"Number of instance operations rejected by the per-user concurrency limit.",
+			"operation",
+		)
+	}
+	return l
+}
+
+// Acquire reserves an operation slot for the user.
+// The returned release function must be called once the operation finishes.
+func (l *OperationLimiter) Acquire(userID uuid.UUID, operation string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	max := l.max()
+	if max <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	if l.inFlight[userID] >= max {
+		l.mu.Unlock()
+		if l.rejected != nil {
+			l.rejected.Inc(operation)
+		}
+		return nil, fmt.Errorf("too many concurrent operations")
+	}
+	l.inFlight[userID]++
+	l.total++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			l.total--
+			if l.inFlight[userID] <= 1 {
+				delete(l.inFlight, userID)
+			} else {
+				l.inFlight[userID]--
+			}
+		})
+	}, nil
+}
+
+func (l *OperationLimiter) inFlightTotal() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return float64(l.total)
+}
+
+func (l *OperationLimiter) activeUsers() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return float64(len(l.inFlight))
+}
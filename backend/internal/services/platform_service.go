@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+)
+
+// platformStatusTTL bounds how long other backend processes may serve a stale status
+const platformStatusTTL = 5 * time.Second
+
+// platformSettingsInterval is how often setting overrides are re-read from the database
+const platformSettingsInterval = 30 * time.Second
+
+// defaultMaintenanceMessage is returned when maintenance is enabled without a message
+const defaultMaintenanceMessage = "The platform is undergoing maintenance, please try again later"
+
+// ErrInvalidSetting is returned (wrapped with the reason) for setting changes
+// that can't be applied
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// PlatformService manages maintenance mode, the announcement banner and the
+// administrators' setting overrides
+type PlatformService struct {
+	platformRepo *repositories.PlatformRepository
+	config       *config.Config
+
+	mu       sync.Mutex
+	status   *models.PlatformStatus
+	loadedAt time.Time
+}
+
+// NewPlatformService creates a new platform service
+func NewPlatformService(platformRepo *repositories.PlatformRepository, cfg *config.Config) *PlatformService {
+	return &PlatformService{platformRepo: platformRepo, config: cfg}
+}
+
+// GetStatus returns the current platform status, cached briefly since it is read on every mutating request
+func (s *PlatformService) GetStatus() (*models.PlatformStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status != nil && time.Since(s.loadedAt) < platformStatusTTL {
+		return s.status, nil
+	}
+
+	status, err := s.platformRepo.GetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	s.status = status
+	s.loadedAt = time.Now()
+	return status, nil
+}
+
+// UpdateStatus changes the maintenance flag and announcement
+func (s *PlatformService) UpdateStatus(userID string, req models.UpdatePlatformStatusRequest) (*models.PlatformStatus, error) {
+	status := &models.PlatformStatus{
+		MaintenanceEnabled: req.MaintenanceEnabled,
+		UpdatedByUserID:    &userID,
+	}
+	if message := strings.TrimSpace(req.MaintenanceMessage); message != "" {
+		status.MaintenanceMessage = &message
+	}
+	if announcement := strings.TrimSpace(req.Announcement); announcement != "" {
+		status.Announcement = &announcement
+	}
+
+	if err := s.platformRepo.UpdateStatus(status); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.status = status
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return status, nil
+}
+
+// MaintenanceMode reports whether maintenance mode is enabled and the message to show.
+// A status lookup failure does not block requests.
+func (s *PlatformService) MaintenanceMode() (bool, string) {
+	status, err := s.GetStatus()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return false, ""
+	}
+
+	if !status.MaintenanceEnabled {
+		return false, ""
+	}
+	if status.MaintenanceMessage != nil {
+		return true, *status.MaintenanceMessage
+	}
+	return true, defaultMaintenanceMessage
+}
+
+// LoadSettings applies the administrators' setting overrides from the database
+func (s *PlatformService) LoadSettings() error {
+	settings, err := s.platformRepo.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	s.config.SetOverrides(overridesFrom(settings))
+	return nil
+}
+
+// RunSettingsSync keeps the setting overrides in sync with the database until
+// ctx is cancelled, so changes made through another backend process apply here too
+func (s *PlatformService) RunSettingsSync(ctx context.Context) {
+	ticker := time.NewTicker(platformSettingsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.LoadSettings(); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// GetSettings returns the setting overrides and the values currently in effect
+func (s *PlatformService) GetSettings() (*models.PlatformSettings, *models.EffectivePlatformSettings, error) {
+	settings, err := s.platformRepo.GetSettings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.config.SetOverrides(overridesFrom(settings))
+	return settings, s.effectiveSettings(), nil
+}
+
+// UpdateSettings changes the setting overrides and applies them to this process
+func (s *PlatformService) UpdateSettings(userID string, req models.UpdatePlatformSettingsRequest) (*models.PlatformSettings, *models.EffectivePlatformSettings, error) {
+	settings, err := s.platformRepo.GetSettings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, field := range req.Reset {
+		switch field {
+		case "max_instances_per_user":
+			settings.MaxInstancesPerUser = nil
+		case "instance_data_retention_days":
+			settings.InstanceDataRetentionDays = nil
+		case "pocketbase_image":
+			settings.PocketBaseImage = nil
+		case "signup_enabled":
+			settings.SignupEnabled = nil
+		default:
+			return nil, nil, fmt.Errorf("%w: unknown setting %q in reset", ErrInvalidSetting, field)
+		}
+	}
+
+	if req.MaxInstancesPerUser != nil {
+		if *req.MaxInstancesPerUser < 0 {
+			return nil, nil, fmt.Errorf("%w: max_instances_per_user must not be negative", ErrInvalidSetting)
+		}
+		settings.MaxInstancesPerUser = req.MaxInstancesPerUser
+	}
+	if req.InstanceDataRetentionDays != nil {
+		if *req.InstanceDataRetentionDays < 0 {
+			return nil, nil, fmt.Errorf("%w: instance_data_retention_days must not be negative", ErrInvalidSetting)
+		}
+		settings.InstanceDataRetentionDays = req.InstanceDataRetentionDays
+	}
+	if req.PocketBaseImage != nil {
+		image := strings.TrimSpace(*req.PocketBaseImage)
+		if image == "" || strings.ContainsAny(image, " \t\n") {
+			return nil, nil, fmt.Errorf("invalid image reference")
+		}
+		settings.PocketBaseImage = &image
+	}
+	if req.SignupEnabled != nil {
+		settings.SignupEnabled = req.SignupEnabled
+	}
+
+	settings.UpdatedByUserID = &userID
+	if err := s.platformRepo.UpdateSettings(settings); err != nil {
+		return nil, nil, err
+	}
+
+	s.config.SetOverrides(overridesFrom(settings))
+	return settings, s.effectiveSettings(), nil
+}
+
+// effectiveSettings returns the overridable settings currently in effect
+func (s *PlatformService) effectiveSettings() *models.EffectivePlatformSettings {
+	current := s.config.Settings()
+	return &models.EffectivePlatformSettings{
+		MaxInstancesPerUser:       current.MaxInstancesPerUser,
+		InstanceDataRetentionDays: current.InstanceDataRetentionDays,
+		PocketBaseImage:           current.PocketBaseImage,
+		SignupEnabled:             current.SignupEnabled,
+	}
+}
+
+// overridesFrom converts stored settings to configuration overrides
+func overridesFrom(settings *models.PlatformSettings) config.Overrides {
+	return config.Overrides{
+		MaxInstancesPerUser:       settings.MaxInstancesPerUser,
+		InstanceDataRetentionDays: settings.InstanceDataRetentionDays,
+		PocketBaseImage:           settings.PocketBaseImage,
+		SignupEnabled:             settings.SignupEnabled,
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"pocketploy/internal/database"
+)
+
+// readinessCacheTTL is how long Docker and migration results are reused, so
+// frequent probes do not hammer the Docker daemon
+const readinessCacheTTL = 10 * time.Second
+
+// ReadinessCheck is the result of one readiness dependency
+type ReadinessCheck struct {
+	Status string `json:"status"` // "ok" or "error"
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness reports whether the process can serve traffic
+type Readiness struct {
+	Ready  bool                      `json:"ready"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+}
+
+// ReadinessChecker checks the dependencies the API needs: the database, the
+// Docker daemon and an up-to-date schema. The database is pinged on every
+// check; the Docker ping and migration lookup run at most once per
+// readinessCacheTTL, and only one probe runs them at a time.
+type ReadinessChecker struct {
+	db           *database.DB
+	dockerClient ContainerRuntime
+
+	mu           sync.Mutex
+	checkedAt    time.Time
+	dockerErr    error
+	migrationErr error
+}
+
+// NewReadinessChecker creates a readiness checker
+func NewReadinessChecker(db *database.DB, dockerClient ContainerRuntime) *ReadinessChecker {
+	return &ReadinessChecker{db: db, dockerClient: dockerClient}
+}
+
+// Check runs the readiness checks
+func (c *ReadinessChecker) Check(ctx context.Context) Readiness {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	dbErr := c.db.PingContext(ctx)
+	dockerErr, migrationErr := c.cachedChecks(ctx, dbErr == nil)
+
+	readiness := Readiness{Ready: true, Checks: make(map[string]ReadinessCheck)}
+	for name, err := range map[string]error{"database": dbErr, "docker": dockerErr, "migrations": migrationErr} {
+		check := ReadinessCheck{Status: "ok"}
+		if err != nil {
+			check = ReadinessCheck{Status: "error", Error: err.Error()}
+			readiness.Ready = false
+		}
+		readiness.Checks[name] = check
+	}
+
+	return readiness
+}
+
+// cachedChecks returns the Docker and migration results, refreshing them once
+// they are older than readinessCacheTTL
+func (c *ReadinessChecker) cachedChecks(ctx context.Context, dbReachable bool) (error, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < readinessCacheTTL {
+		return c.dockerErr, c.migrationErr
+	}
+
+	c.dockerErr = c.dockerClient.Ping(ctx)
+
+	if !dbReachable {
+		c.migrationErr = fmt.Errorf("database unreachable")
+	} else if pending, err := c.db.PendingMigrations(ctx); err != nil {
+		c.migrationErr = err
+	} else if len(pending) > 0 {
+		c.migrationErr = fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+	} else {
+		c.migrationErr = nil
+	}
+
+	c.checkedAt = time.Now()
+	return c.dockerErr, c.migrationErr
+}
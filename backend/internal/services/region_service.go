@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RegionService manages the regions users can place instances in
+type RegionService struct {
+	db     *sqlx.DB
+	config *config.Config
+}
+
+// NewRegionService creates a new region service
+func NewRegionService(db *sqlx.DB, cfg *config.Config) *RegionService {
+	return &RegionService{db: db, config: cfg}
+}
+
+// ErrRegionDomainAllowed is returned (wrapped with the pattern) when a region's
+// base domain is covered by a wildcard in ALLOWED_ORIGINS
+var ErrRegionDomainAllowed = errors.New("base domain is covered by a wildcard in ALLOWED_ORIGINS")
+
+// SyncDefaultRegion points the default region at BASE_DOMAIN and checks that
+// no region's instances are allowed as CORS origins
+func (s *RegionService) SyncDefaultRegion(ctx context.Context) error {
+	if err := models.SyncDefaultRegion(ctx, s.db, s.config.BaseDomain); err != nil {
+		return err
+	}
+
+	regions, err := models.FindRegions(ctx, s.db, false)
+	if err != nil {
+		return err
+	}
+	for _, region := range regions {
+		if err := s.checkAllowedOrigins(region.BaseDomain); err != nil {
+			return fmt.Errorf("region %s: %w", region.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// checkAllowedOrigins rejects base domains whose instances would be allowed to
+// make credentialed requests to the API
+func (s *RegionService) checkAllowedOrigins(baseDomain string) error {
+	if origin := s.config.WildcardOriginFor(baseDomain); origin != "" {
+		return fmt.Errorf("%w (%s)", ErrRegionDomainAllowed, origin)
+	}
+	return nil
+}
+
+// ListRegions lists the regions users can choose from
+func (s *RegionService) ListRegions(ctx context.Context) ([]models.Region, error) {
+	return models.FindRegions(ctx, s.db, true)
+}
+
+// ListAllRegions lists every region, including disabled ones
+func (s *RegionService) ListAllRegions(ctx context.Context) ([]models.Region, error) {
+	return models.FindRegions(ctx, s.db, false)
+}
+
+// ResolveRegion returns the region a new instance is placed in (the default
+// region when id is empty). Disabled regions cannot be chosen.
+func (s *RegionService) ResolveRegion(ctx context.Context, id string) (*models.Region, error) {
+	if id == "" {
+		id = models.DefaultRegionID
+	}
+
+	region, err := models.FindRegionByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !region.Enabled {
+		return nil, fmt.Errorf("region is not available")
+	}
+
+	return region, nil
+}
+
+// CreateRegion adds a region
+func (s *RegionService) CreateRegion(ctx context.Context, req models.CreateRegionRequest) (*models.Region, error) {
+	region := &models.Region{
+		ID:                req.ID,
+		Name:              strings.TrimSpace(req.Name),
+		BaseDomain:        strings.ToLower(strings.TrimSpace(req.BaseDomain)),
+		TraefikEntrypoint: req.TraefikEntrypoint,
+		Enabled:           true,
+	}
+	if region.TraefikEntrypoint == "" {
+		region.TraefikEntrypoint = "web"
+	}
+	if network := strings.TrimSpace(req.DockerNetwork); network != "" {
+		region.DockerNetwork = &network
+	}
+
+	if err := s.checkAllowedOrigins(region.BaseDomain); err != nil {
+		return nil, err
+	}
+
+	if err := region.Create(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	return region, nil
+}
+
+// UpdateRegion renames, enables or disables a region or changes its entrypoint
+func (s *RegionService) UpdateRegion(ctx context.Context, id string, req models.UpdateRegionRequest) (*models.Region, error) {
+	region, err := models.FindRegionByID(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		region.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.TraefikEntrypoint != nil {
+		region.TraefikEntrypoint = *req.TraefikEntrypoint
+	}
+	if req.Enabled != nil {
+		if !*req.Enabled && region.ID == models.DefaultRegionID {
+			return nil, fmt.Errorf("the default region cannot be disabled")
+		}
+		region.Enabled = *req.Enabled
+	}
+
+	if err := region.Update(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	return region, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/utils"
+)
+
+// RevocationList tracks revoked credentials until they would have expired anyway.
+// It is backed by the shared cache store, so with Redis configured a revocation
+// takes effect on every backend process immediately.
+type RevocationList struct {
+	store cache.Store
+}
+
+// NewRevocationList creates a new revocation list
+func NewRevocationList(store cache.Store) *RevocationList {
+	return &RevocationList{store: store}
+}
+
+// Revoke marks key as revoked for ttl
+func (l *RevocationList) Revoke(ctx context.Context, key string, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	if err := l.store.Set(ctx, "revoked:"+key, []byte("1"), ttl); err != nil {
+		log.Printf("Warning: failed to record revocation: %v", err)
+	}
+}
+
+// IsRevoked reports whether key has been revoked. Store errors are treated as
+// not revoked; callers must still rely on their authoritative check.
+func (l *RevocationList) IsRevoked(ctx context.Context, key string) bool {
+	_, found, err := l.store.Get(ctx, "revoked:"+key)
+	if err != nil {
+		log.Printf("Warning: failed to check revocation: %v", err)
+		return false
+	}
+	return found
+}
+
+// RevokeAllForUser invalidates every access token issued to a user up to now
+func (l *RevocationList) RevokeAllForUser(ctx context.Context, userID string, ttl time.Duration) {
+	l.RevokeAllForUserExcept(ctx, userID, "", ttl)
+}
+
+// RevokeAllForUserExcept invalidates every access token issued to a user up to
+// now, except those of the session keepSessionID
+func (l *RevocationList) RevokeAllForUserExcept(ctx context.Context, userID, keepSessionID string, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	// Stored as "<unix time>" or "<unix time>:<kept session>"
+	value := strconv.FormatInt(time.Now().Unix(), 10)
+	if keepSessionID != "" {
+		value += ":" + keepSessionID
+	}
+	if err := l.store.Set(ctx, "revoked:user:"+userID, []byte(value), ttl); err != nil {
+		log.Printf("Warning: failed to record user revocation: %v", err)
+	}
+}
+
+// IsAccessTokenRevoked reports whether an access token was revoked, either
+// individually (by jti), with its session, or through a revoke-all for its user
+func (l *RevocationList) IsAccessTokenRevoked(ctx context.Context, claims *utils.Claims) bool {
+	if claims.ID != "" && l.IsRevoked(ctx, "access:"+claims.ID) {
+		return true
+	}
+	if claims.SessionID != "" && l.IsRevoked(ctx, "session:"+claims.SessionID) {
+		return true
+	}
+
+	value, found, err := l.store.Get(ctx, "revoked:user:"+claims.UserID)
+	if err != nil || !found || claims.IssuedAt == nil {
+		return false
+	}
+
+	before, keptSession, _ := strings.Cut(string(value), ":")
+	if keptSession != "" && claims.SessionID == keptSession {
+		return false
+	}
+
+	revokedBefore, err := strconv.ParseInt(before, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	// IssuedAt has second precision, so tokens from the same second are revoked too
+	return claims.IssuedAt.Unix() <= revokedBefore
+}
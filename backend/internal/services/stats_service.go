@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/config"
+	"pocketploy/internal/docker"
+	"pocketploy/internal/models"
+	"pocketploy/internal/repositories"
+)
+
+const (
+	// platformStatsTTL is how long computed stats are served from the cache;
+	// the aggregate queries scan whole tables
+	platformStatsTTL = time.Minute
+
+	// DefaultStatsDays and MaxStatsDays bound the length of the daily series
+	DefaultStatsDays = 30
+	MaxStatsDays     = 365
+)
+
+// StatsService computes the platform statistics of the admin dashboard. They
+// are cached in the shared store, so every backend process serves the same
+// numbers until they expire.
+type StatsService struct {
+	statsRepo *repositories.StatsRepository
+	store     cache.Store
+	config    *config.Config
+}
+
+// NewStatsService creates a new stats service
+func NewStatsService(statsRepo *repositories.StatsRepository, store cache.Store, cfg *config.Config) *StatsService {
+	return &StatsService{statsRepo: statsRepo, store: store, config: cfg}
+}
+
+// GetStats returns the platform statistics with daily series covering the
+// given number of days, from the cache unless refresh is set
+func (s *StatsService) GetStats(ctx context.Context, days int, refresh bool) (*models.PlatformStats, error) {
+	if days < 1 || days > MaxStatsDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxStatsDays)
+	}
+
+	key := "platform_stats:" + strconv.Itoa(days)
+	if !refresh {
+		if stats := s.cached(ctx, key); stats != nil {
+			return stats, nil
+		}
+	}
+
+	stats, err := s.compute(ctx, days)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(stats); err == nil {
+		if err := s.store.Set(ctx, key, data, platformStatsTTL); err != nil {
+			log.Printf("Warning: failed to cache platform stats: %v", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// cached returns the cached stats, nil if there are none (or they can't be read)
+func (s *StatsService) cached(ctx context.Context, key string) *models.PlatformStats {
+	data, found, err := s.store.Get(ctx, key)
+	if err != nil {
+		log.Printf("Warning: failed to read cached platform stats: %v", err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+
+	var stats models.PlatformStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil
+	}
+	return &stats
+}
+
+// compute runs the aggregate queries
+func (s *StatsService) compute(ctx context.Context, days int) (*models.PlatformStats, error) {
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -(days - 1))
+
+	stats := &models.PlatformStats{GeneratedAt: now, Days: days}
+
+	var err error
+	if stats.Users, err = s.statsRepo.CountUsers(ctx); err != nil {
+		return nil, err
+	}
+	if stats.InstancesByStatus, err = s.statsRepo.CountInstancesByStatus(ctx); err != nil {
+		return nil, err
+	}
+	for _, count := range stats.InstancesByStatus {
+		stats.Instances += count
+	}
+	if stats.FailedProvisions24h, err = s.statsRepo.CountFailedProvisions(ctx, now.Add(-24*time.Hour)); err != nil {
+		return nil, err
+	}
+	if stats.Disk, err = s.statsRepo.DiskUsageByRegion(ctx); err != nil {
+		return nil, err
+	}
+
+	stats.Host = s.hostDiskUsage(s.config.InstancesBasePath)
+	stats.Volumes = []models.HostDiskUsage{}
+	for _, root := range s.config.StorageRoots() {
+		stats.Volumes = append(stats.Volumes, s.hostDiskUsage(root))
+	}
+
+	if stats.Series.Signups, err = s.statsRepo.DailySignups(ctx, since); err != nil {
+		return nil, err
+	}
+	if stats.Series.InstancesCreated, err = s.statsRepo.DailyInstancesCreated(ctx, since); err != nil {
+		return nil, err
+	}
+	if stats.Series.FailedProvisions, err = s.statsRepo.DailyFailedProvisions(ctx, since); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// hostDiskUsage reads the size and free space of the volume holding path
+func (s *StatsService) hostDiskUsage(path string) models.HostDiskUsage {
+	usage := models.HostDiskUsage{
+		Path:          path,
+		TotalBytes:    -1,
+		FreeBytes:     -1,
+		ReserveBytes:  s.config.MinFreeDiskSpace,
+		HeadroomBytes: -1,
+	}
+	if total, err := docker.DiskSize(path); err == nil {
+		usage.TotalBytes = total
+	}
+	if free, err := docker.FreeDiskSpace(path); err == nil {
+		usage.FreeBytes = free
+		usage.HeadroomBytes = max(free-s.config.MinFreeDiskSpace, 0)
+	}
+
+	return usage
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"pocketploy/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// statusSampleInterval is how often platform health is sampled
+	statusSampleInterval = time.Minute
+
+	// statusSampleRetention is how long health samples are kept
+	statusSampleRetention = 7 * 24 * time.Hour
+
+	// statusSummaryTTL limits how often the public endpoint hits the database and Docker
+	statusSummaryTTL = 30 * time.Second
+
+	// statusAvailabilityPeriod is the window reported on the status page
+	statusAvailabilityPeriod = 24 * time.Hour
+
+	// statusDegradedThreshold is the availability below which a component is degraded
+	statusDegradedThreshold = 0.99
+)
+
+// Component states reported on the status page
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusOutage      = "outage"
+)
+
+// ComponentStatus describes the health of a platform component
+type ComponentStatus struct {
+	Status          string   `json:"status"`
+	Availability24h *float64 `json:"availability_24h,omitempty"`
+}
+
+// InstanceAvailability summarizes hosted instances
+type InstanceAvailability struct {
+	ComponentStatus
+	Total   int `json:"total"`
+	Running int `json:"running"`
+	Failed  int `json:"failed"`
+}
+
+// PlatformHealth is the public status page summary
+type PlatformHealth struct {
+	Status       string               `json:"status"`
+	ControlPlane ComponentStatus      `json:"control_plane"`
+	Docker       ComponentStatus      `json:"docker"`
+	Instances    InstanceAvailability `json:"instances"`
+	CheckedAt    time.Time            `json:"checked_at"`
+}
+
+// StatusMonitor samples platform health and builds the public status summary
+type StatusMonitor struct {
+	db           *sqlx.DB
+	dockerClient ContainerRuntime
+
+	mu       sync.Mutex
+	summary  *PlatformHealth
+	cachedAt time.Time
+}
+
+// NewStatusMonitor creates a new status monitor
+func NewStatusMonitor(db *sqlx.DB, dockerClient ContainerRuntime) *StatusMonitor {
+	return &StatusMonitor{
+		db:           db,
+		dockerClient: dockerClient,
+	}
+}
+
+// Run records health samples until ctx is cancelled
+func (m *StatusMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(statusSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sample(ctx)
+		}
+	}
+}
+
+// sample records the current Docker and instance health
+func (m *StatusMonitor) sample(ctx context.Context) {
+	counts, err := models.CountInstancesByStatus(ctx, m.db)
+	if err != nil {
+		log.Printf("Warning: status sample skipped: %v", err)
+		return
+	}
+
+	err = models.CreatePlatformHealthSample(ctx, m.db, &models.PlatformHealthSample{
+		CheckedAt:        time.Now().UTC(),
+		DockerHealthy:    m.pingDocker(ctx) == nil,
+		InstancesRunning: counts[models.InstanceStatusRunning],
+		InstancesFailed:  counts[models.InstanceStatusFailed],
+	})
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if _, err := models.DeletePlatformHealthSamplesBefore(ctx, m.db, time.Now().UTC().Add(-statusSampleRetention)); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// Summary returns the current platform health, cached briefly
+func (m *StatusMonitor) Summary(ctx context.Context) *PlatformHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.summary != nil && time.Since(m.cachedAt) < statusSummaryTTL {
+		return m.summary
+	}
+
+	m.summary = m.buildSummary(ctx)
+	m.cachedAt = time.Now()
+	return m.summary
+}
+
+func (m *StatusMonitor) buildSummary(ctx context.Context) *PlatformHealth {
+	health := &PlatformHealth{
+		ControlPlane: ComponentStatus{Status: StatusOperational},
+		Docker:       ComponentStatus{Status: StatusOperational},
+		Instances:    InstanceAvailability{ComponentStatus: ComponentStatus{Status: StatusOperational}},
+		CheckedAt:    time.Now().UTC(),
+	}
+
+	if err := m.pingDocker(ctx); err != nil {
+		health.Docker.Status = StatusOutage
+	}
+
+	if err := m.db.PingContext(ctx); err != nil {
+		// Without the database nothing else can be reported
+		health.ControlPlane.Status = StatusOutage
+		health.Instances.Status = StatusOutage
+		health.Status = StatusOutage
+		return health
+	}
+
+	if counts, err := models.CountInstancesByStatus(ctx, m.db); err != nil {
+		log.Printf("Warning: %v", err)
+	} else {
+		for _, count := range counts {
+			health.Instances.Total += count
+		}
+		health.Instances.Running = counts[models.InstanceStatusRunning]
+		health.Instances.Failed = counts[models.InstanceStatusFailed]
+	}
+
+	availability, err := models.GetPlatformAvailability(ctx, m.db, time.Now().UTC().Add(-statusAvailabilityPeriod))
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	} else {
+		health.Docker.Availability24h = availability.DockerUptimeRatio
+		health.Instances.Availability24h = availability.InstanceAvailability
+	}
+
+	if health.Docker.Status == StatusOutage {
+		// Instances cannot be served while Docker is down
+		health.Instances.Status = StatusOutage
+	} else {
+		if isDegraded(health.Docker.Availability24h) {
+			health.Docker.Status = StatusDegraded
+		}
+		if isDegraded(health.Instances.Availability24h) {
+			health.Instances.Status = StatusDegraded
+		}
+	}
+
+	health.Status = worstStatus(health.ControlPlane.Status, health.Docker.Status, health.Instances.Status)
+	return health
+}
+
+func (m *StatusMonitor) pingDocker(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return m.dockerClient.Ping(ctx)
+}
+
+func isDegraded(availability *float64) bool {
+	return availability != nil && *availability < statusDegradedThreshold
+}
+
+// worstStatus returns the most severe of the given component states
+func worstStatus(statuses ...string) string {
+	worst := StatusOperational
+	for _, status := range statuses {
+		if status == StatusOutage {
+			return StatusOutage
+		}
+		if status == StatusDegraded {
+			worst = StatusDegraded
+		}
+	}
+	return worst
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"pocketploy/internal/authz"
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+	"pocketploy/internal/objectstore"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// storageSettingsTimeout bounds the sqlite3 run updating PocketBase's settings
+const storageSettingsTimeout = 2 * time.Minute
+
+// s3Settings are PocketBase's S3 file storage settings
+type s3Settings struct {
+	Enabled        bool   `json:"enabled"`
+	Bucket         string `json:"bucket"`
+	Region         string `json:"region"`
+	Endpoint       string `json:"endpoint"`
+	AccessKey      string `json:"accessKey"`
+	Secret         string `json:"secret"`
+	ForcePathStyle bool   `json:"forcePathStyle"`
+}
+
+// StorageService gives instances a managed S3 bucket for their file uploads,
+// so they don't live on the host's bind mount, and records bucket usage
+type StorageService struct {
+	db              *sqlx.DB
+	instanceService *InstanceService
+	provider        objectstore.Provider // nil when managed storage is disabled
+	config          *config.Config
+}
+
+// NewStorageService creates a new storage service
+func NewStorageService(db *sqlx.DB, instanceService *InstanceService, provider objectstore.Provider, cfg *config.Config) *StorageService {
+	return &StorageService{db: db, instanceService: instanceService, provider: provider, config: cfg}
+}
+
+// GetStorage returns the managed storage of an instance
+func (s *StorageService) GetStorage(ctx context.Context, instanceID, userID uuid.UUID) (*models.InstanceStorage, error) {
+	if _, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceView); err != nil {
+		return nil, err
+	}
+	return models.FindInstanceStorage(ctx, s.db, instanceID)
+}
+
+// EnableStorage creates a bucket for an instance and sets it as PocketBase's
+// S3 storage. The instance restarts if it is running. Files uploaded before
+// stay on the host and are no longer served.
+func (s *StorageService) EnableStorage(ctx context.Context, instanceID, userID uuid.UUID) (*models.InstanceStorage, error) {
+	if s.provider == nil {
+		return nil, fmt.Errorf("managed storage is not enabled")
+	}
+
+	// Limit concurrent Docker operations per user
+	release, err := s.instanceService.operations.Acquire(userID, "update")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	instance, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkInstance(instance); err != nil {
+		return nil, err
+	}
+
+	storage := &models.InstanceStorage{
+		InstanceID: &instance.ID,
+		Bucket:     s.config.StorageBucketPrefix + "-" + strings.ReplaceAll(instance.ID.String(), "-", "")[:16],
+		Region:     s.config.StorageRegion,
+	}
+	if err := models.CreateInstanceStorage(ctx, s.db, storage); err != nil {
+		return nil, err
+	}
+
+	bucket, err := s.provider.CreateBucket(ctx, storage.Bucket)
+	if err == nil {
+		storage.Endpoint = bucket.Endpoint
+		err = s.applySettings(ctx, instance, s3Settings{
+			Enabled:        true,
+			Bucket:         bucket.Name,
+			Region:         bucket.Region,
+			Endpoint:       bucket.Endpoint,
+			AccessKey:      bucket.AccessKeyID,
+			Secret:         bucket.SecretAccessKey,
+			ForcePathStyle: bucket.ForcePathStyle,
+		})
+	}
+	if err != nil {
+		// Whatever was created is removed in the background
+		if markErr := models.MarkInstanceStorageDeleting(ctx, s.db, storage); markErr != nil {
+			log.Printf("Warning: %v", markErr)
+		}
+		return nil, fmt.Errorf("failed to set up managed storage: %w", err)
+	}
+
+	if err := models.ActivateInstanceStorage(ctx, s.db, storage, bucket.AccessKeyID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Managed storage enabled for instance %s (bucket %s)", instance.ID, storage.Bucket)
+	return storage, nil
+}
+
+// DisableStorage switches PocketBase back to local storage and deletes the
+// bucket with every file in it. The instance restarts if it is running.
+func (s *StorageService) DisableStorage(ctx context.Context, instanceID, userID uuid.UUID) error {
+	// Limit concurrent Docker operations per user
+	release, err := s.instanceService.operations.Acquire(userID, "update")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	instance, err := s.instanceService.AuthorizeInstance(ctx, instanceID, userID, authz.ActionInstanceManage)
+	if err != nil {
+		return err
+	}
+
+	storage, err := models.FindInstanceStorage(ctx, s.db, instance.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkInstance(instance); err != nil {
+		return err
+	}
+
+	if err := s.applySettings(ctx, instance, s3Settings{}); err != nil {
+		return fmt.Errorf("failed to switch to local storage: %w", err)
+	}
+
+	return models.MarkInstanceStorageDeleting(ctx, s.db, storage)
+}
+
+// Run records bucket usage and deletes detached buckets every
+// STORAGE_USAGE_INTERVAL until ctx is cancelled
+func (s *StorageService) Run(ctx context.Context) {
+	if s.provider == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.StorageUsageInterval)
+	defer ticker.Stop()
+
+	for {
+		s.cleanup(ctx)
+		s.measure(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanup deletes the buckets of disabled storage and deleted instances
+func (s *StorageService) cleanup(ctx context.Context) {
+	detached, err := models.FindInstanceStorageByStatus(ctx, s.db, models.StorageDeleting)
+	if err != nil {
+		log.Printf("Warning: storage cleanup skipped: %v", err)
+		return
+	}
+
+	for _, storage := range detached {
+		if err := s.provider.DeleteBucket(ctx, storage.Bucket); err != nil {
+			log.Printf("Warning: failed to delete bucket %s: %v", storage.Bucket, err)
+			continue
+		}
+		if err := models.DeleteInstanceStorage(ctx, s.db, storage.ID); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		log.Printf("Deleted bucket %s", storage.Bucket)
+	}
+}
+
+// measure records the usage of active buckets
+func (s *StorageService) measure(ctx context.Context) {
+	active, err := models.FindInstanceStorageByStatus(ctx, s.db, models.StorageActive)
+	if err != nil {
+		log.Printf("Warning: storage usage skipped: %v", err)
+		return
+	}
+
+	for _, storage := range active {
+		usage, err := s.provider.Usage(ctx, storage.Bucket)
+		if err != nil {
+			log.Printf("Warning: failed to measure bucket %s: %v", storage.Bucket, err)
+			continue
+		}
+		if err := models.UpdateInstanceStorageUsage(ctx, s.db, storage.ID, usage.Bytes, usage.Objects); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// checkInstance rejects instances whose settings can't be changed
+func (s *StorageService) checkInstance(instance *models.Instance) error {
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return fmt.Errorf("instance has no container")
+	}
+	if instance.Status != models.InstanceStatusRunning && instance.Status != models.InstanceStatusStopped {
+		return fmt.Errorf("instance must be running or stopped")
+	}
+	// Encrypted settings can't be edited in the database
+	if instance.ServeOptions.EncryptSettings {
+		return fmt.Errorf("managed storage can't be configured while settings encryption is enabled")
+	}
+	return nil
+}
+
+// applySettings writes PocketBase's S3 settings into the instance's database.
+// PocketBase reads them at startup, so a running instance is stopped for the
+// change and started again.
+func (s *StorageService) applySettings(ctx context.Context, instance *models.Instance, settings s3Settings) error {
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	wasRunning := instance.Status == models.InstanceStatusRunning
+	if wasRunning {
+		if err := s.instanceService.dockerClient.StopContainer(ctx, *instance.ContainerID); err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
+		}
+		if err := s.instanceService.store.UpdateStatus(ctx, instance, models.InstanceStatusStopped); err != nil {
+			return fmt.Errorf("failed to update instance status: %w", err)
+		}
+	}
+
+	err = s.writeSettings(ctx, instance, string(value))
+
+	if wasRunning {
+		if startErr := s.instanceService.startContainer(ctx, instance); startErr != nil {
+			return fmt.Errorf("failed to start container: %w", startErr)
+		}
+		if statusErr := s.instanceService.store.UpdateStatus(ctx, instance, models.InstanceStatusRunning); statusErr != nil {
+			return fmt.Errorf("failed to update instance status: %w", statusErr)
+		}
+	}
+
+	return err
+}
+
+// writeSettings replaces the s3 section of the settings row PocketBase
+// creates on its first start
+func (s *StorageService) writeSettings(ctx context.Context, instance *models.Instance, value string) error {
+	script := "UPDATE _params SET value = json_set(value, '$.s3', json(" + sqlQuote(value) + ")) WHERE id = 'settings';\n" +
+		"SELECT changes();\n"
+
+	ctx, cancel := context.WithTimeout(ctx, storageSettingsTimeout)
+	defer cancel()
+
+	output, exitCode, err := s.instanceService.dockerClient.RunSQLite(ctx, map[string]string{copyTargetDir: instance.DataPath},
+		[]string{"-bail", copyTargetDir + "/data.db", script})
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("failed to update settings: %s", strings.TrimSpace(output))
+	}
+	if strings.TrimSpace(output) != "1" {
+		return fmt.Errorf("the instance has no settings yet; start it once first")
+	}
+
+	return nil
+}
@@ -1,24 +1,30 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"pocketploy/internal/config"
+	"pocketploy/internal/models"
 	"pocketploy/internal/repositories"
+	"pocketploy/internal/utils"
 )
 
 // TokenService handles refresh token management business logic
 type TokenService struct {
-	tokenRepo *repositories.TokenRepository
-	config    *config.Config
+	tokenRepo   *repositories.TokenRepository
+	revocations *RevocationList
+	config      *config.Config
 }
 
 // NewTokenService creates a new token service
-func NewTokenService(tokenRepo *repositories.TokenRepository, cfg *config.Config) *TokenService {
+func NewTokenService(tokenRepo *repositories.TokenRepository, revocations *RevocationList, cfg *config.Config) *TokenService {
 	return &TokenService{
-		tokenRepo: tokenRepo,
-		config:    cfg,
+		tokenRepo:   tokenRepo,
+		revocations: revocations,
+		config:      cfg,
 	}
 }
 
@@ -63,6 +69,24 @@ func (s *TokenService) RevokeAllUserSessions(userID string) error {
 	if err := s.tokenRepo.RevokeAllForUser(userID); err != nil {
 		return fmt.Errorf("failed to revoke all user sessions: %w", err)
 	}
+
+	// Also invalidate access tokens that are still within their lifetime
+	accessExpiry := s.config.JWTAccessExpiry
+	s.revocations.RevokeAllForUser(context.Background(), userID, accessExpiry)
+
+	return nil
+}
+
+// RevokeOtherUserSessions revokes every session of a user except sessionID,
+// the one the request came from (e.g., on password change)
+func (s *TokenService) RevokeOtherUserSessions(userID, sessionID string) error {
+	if err := s.tokenRepo.RevokeAllForUserExcept(userID, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke other user sessions: %w", err)
+	}
+
+	accessExpiry := s.config.JWTAccessExpiry
+	s.revocations.RevokeAllForUserExcept(context.Background(), userID, sessionID, accessExpiry)
+
 	return nil
 }
 
@@ -74,6 +98,89 @@ func (s *TokenService) RevokeSession(tokenHash string) error {
 	return nil
 }
 
+// ListSessions returns a user's active sessions, marking currentSessionID
+func (s *TokenService) ListSessions(userID, currentSessionID string) ([]TokenInfo, error) {
+	tokens, err := s.tokenRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sessions: %w", err)
+	}
+
+	return tokenInfos(tokens, currentSessionID), nil
+}
+
+// RenameSession labels one of a user's sessions (e.g. "work laptop"); an
+// empty name removes the label
+func (s *TokenService) RenameSession(userID, sessionID, name string) error {
+	return s.tokenRepo.SetName(sessionID, userID, strings.TrimSpace(name))
+}
+
+// RevokeUserSession signs a user out of one of their sessions, and removes the
+// trust of its device
+func (s *TokenService) RevokeUserSession(userID, sessionID string) error {
+	token, err := s.tokenRepo.GetByID(sessionID)
+	if err != nil || token.UserID != userID {
+		return fmt.Errorf("session not found")
+	}
+
+	if err := s.tokenRepo.RevokeForUser(sessionID, userID); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	s.revocations.Revoke(ctx, "refresh:"+token.TokenHash, s.config.JWTRefreshExpiry)
+	s.revocations.Revoke(ctx, "session:"+sessionID, s.config.JWTAccessExpiry)
+
+	return nil
+}
+
+// RevokeSessionByLink signs out the session of a sign-out link from a new
+// device login notification. No login is needed, so it works from any browser.
+func (s *TokenService) RevokeSessionByLink(token string) (*models.RefreshToken, error) {
+	session, err := s.tokenRepo.RevokeByRevokeToken(utils.HashRefreshToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	s.revocations.Revoke(ctx, "refresh:"+session.TokenHash, s.config.JWTRefreshExpiry)
+	s.revocations.Revoke(ctx, "session:"+session.ID, s.config.JWTAccessExpiry)
+
+	return session, nil
+}
+
+// TrustDevice marks the device of a session as trusted for TRUSTED_DEVICE_TTL.
+// It returns the device token the client presents when logging in again.
+func (s *TokenService) TrustDevice(userID, sessionID string) (string, time.Time, error) {
+	deviceToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate device token: %w", err)
+	}
+
+	until := time.Now().UTC().Add(s.config.TrustedDeviceTTL)
+	if err := s.tokenRepo.Trust(sessionID, userID, utils.HashRefreshToken(deviceToken), until); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return deviceToken, until, nil
+}
+
+// ListTrustedDevices returns a user's trusted devices, each identified by the
+// session currently holding its trust
+func (s *TokenService) ListTrustedDevices(userID, currentSessionID string) ([]TokenInfo, error) {
+	tokens, err := s.tokenRepo.GetTrustedByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trusted devices: %w", err)
+	}
+
+	return tokenInfos(tokens, currentSessionID), nil
+}
+
+// UntrustDevice revokes the trust of one of a user's devices; its session
+// stays signed in
+func (s *TokenService) UntrustDevice(userID, sessionID string) error {
+	return s.tokenRepo.Untrust(sessionID, userID)
+}
+
 // GetUserTokens retrieves all tokens (active and inactive) for a user
 func (s *TokenService) GetUserTokens(userID string) ([]TokenInfo, error) {
 	tokens, err := s.tokenRepo.GetByUserID(userID)
@@ -81,31 +188,48 @@ func (s *TokenService) GetUserTokens(userID string) ([]TokenInfo, error) {
 		return nil, fmt.Errorf("failed to get user tokens: %w", err)
 	}
 
-	tokenInfos := make([]TokenInfo, len(tokens))
+	return tokenInfos(tokens, ""), nil
+}
+
+// tokenInfos converts tokens for display, marking currentSessionID
+func tokenInfos(tokens []*models.RefreshToken, currentSessionID string) []TokenInfo {
+	now := time.Now().UTC()
+	infos := make([]TokenInfo, len(tokens))
 	for i, token := range tokens {
-		tokenInfos[i] = TokenInfo{
+		infos[i] = TokenInfo{
 			ID:        token.ID,
+			Name:      token.Name,
 			CreatedAt: token.CreatedAt,
 			ExpiresAt: token.ExpiresAt,
 			RevokedAt: token.RevokedAt,
 			IPAddress: token.IPAddress,
 			UserAgent: token.UserAgent,
-			IsActive:  token.RevokedAt == nil && token.ExpiresAt.After(time.Now().UTC()),
-			IsExpired: token.ExpiresAt.Before(time.Now().UTC()),
+			Country:   token.Country,
+			City:      token.City,
+			IsActive:  token.RevokedAt == nil && token.ExpiresAt.After(now),
+			IsExpired: token.ExpiresAt.Before(now),
+			IsCurrent: currentSessionID != "" && token.ID == currentSessionID,
+		}
+		if token.TrustedUntil != nil && token.TrustedUntil.After(now) {
+			infos[i].TrustedUntil = token.TrustedUntil
 		}
 	}
-
-	return tokenInfos, nil
+	return infos
 }
 
 // TokenInfo represents display information about a token
 type TokenInfo struct {
-	ID        string     `json:"id"`
-	CreatedAt time.Time  `json:"created_at"`
-	ExpiresAt time.Time  `json:"expires_at"`
-	RevokedAt *time.Time `json:"revoked_at,omitempty"`
-	IPAddress string     `json:"ip_address"`
-	UserAgent string     `json:"user_agent"`
-	IsActive  bool       `json:"is_active"`
-	IsExpired bool       `json:"is_expired"`
+	ID           string     `json:"id"`
+	Name         *string    `json:"name,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	IPAddress    string     `json:"ip_address"`
+	UserAgent    string     `json:"user_agent"`
+	Country      string     `json:"country,omitempty"`
+	City         string     `json:"city,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	IsExpired    bool       `json:"is_expired"`
+	IsCurrent    bool       `json:"is_current"`
+	TrustedUntil *time.Time `json:"trusted_until,omitempty"`
 }
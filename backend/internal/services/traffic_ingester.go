@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// maxAccessLogLine bounds a single access log entry; longer lines are skipped
+const maxAccessLogLine = 64 * 1024
+
+// unavailableRouter is the Traefik router serving the page of unrouted
+// instances (traefik.dynamic.yml); its requests never reached an instance
+const unavailableRouter = "instance-unavailable@file"
+
+// accessLogEntry holds the Traefik JSON access log fields used for analytics
+type accessLogEntry struct {
+	RequestHost           string `json:"RequestHost"`
+	RouterName            string `json:"RouterName"`
+	DownstreamStatus      int    `json:"DownstreamStatus"`
+	DownstreamContentSize int64  `json:"DownstreamContentSize"`
+	StartUTC              string `json:"StartUTC"`
+}
+
+// TrafficIngester aggregates Traefik access logs into daily per-instance analytics
+type TrafficIngester struct {
+	store    InstanceStore
+	path     string
+	interval time.Duration
+}
+
+// NewTrafficIngester creates an ingester reading the access log at path every interval
+func NewTrafficIngester(store InstanceStore, path string, interval time.Duration) *TrafficIngester {
+	return &TrafficIngester{
+		store:    store,
+		path:     path,
+		interval: interval,
+	}
+}
+
+// Run ingests new access log entries until ctx is cancelled
+func (t *TrafficIngester) Run(ctx context.Context) {
+	if t.path == "" || t.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.ingest(ctx); err != nil {
+				log.Printf("Warning: access log ingestion failed: %v", err)
+			}
+		}
+	}
+}
+
+// ingest reads complete lines appended since the stored offset and records their counters
+func (t *TrafficIngester) ingest(ctx context.Context) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset, err := t.store.GetAccessLogOffset(ctx, t.path)
+	if err != nil {
+		return err
+	}
+
+	// The file shrank, so it was rotated or truncated: start over
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return nil
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	days := make(map[trafficKey]*models.InstanceTrafficDay)
+	hosts := make(map[string]*uuid.UUID)
+
+	reader := bufio.NewReaderSize(file, maxAccessLogLine)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Skip the rest of an oversized line
+			offset += int64(len(line))
+			for err == bufio.ErrBufferFull {
+				line, err = reader.ReadSlice('\n')
+				offset += int64(len(line))
+			}
+			continue
+		}
+		if err != nil {
+			// Partial last line: leave it for the next run
+			break
+		}
+		offset += int64(len(line))
+
+		var entry accessLogEntry
+		if json.Unmarshal(line, &entry) != nil || entry.RequestHost == "" || entry.RouterName == unavailableRouter {
+			continue
+		}
+
+		instanceID := t.resolveHost(ctx, hosts, entry.RequestHost)
+		if instanceID == nil {
+			continue
+		}
+
+		t.count(days, *instanceID, entry)
+	}
+
+	records := make([]models.InstanceTrafficDay, 0, len(days))
+	for _, day := range days {
+		records = append(records, *day)
+	}
+
+	return t.store.RecordInstanceTraffic(ctx, t.path, offset, records)
+}
+
+// trafficKey identifies an instance's counters for one day
+type trafficKey struct {
+	instanceID uuid.UUID
+	day        string
+}
+
+// count adds a single request to the day's counters
+func (t *TrafficIngester) count(days map[trafficKey]*models.InstanceTrafficDay, instanceID uuid.UUID, entry accessLogEntry) {
+	started, err := time.Parse(time.RFC3339Nano, entry.StartUTC)
+	if err != nil {
+		started = time.Now().UTC()
+	}
+	day := started.UTC().Truncate(24 * time.Hour)
+
+	key := trafficKey{instanceID: instanceID, day: day.Format("2006-01-02")}
+	counters, ok := days[key]
+	if !ok {
+		counters = &models.InstanceTrafficDay{InstanceID: instanceID, Day: day}
+		days[key] = counters
+	}
+
+	counters.Requests++
+	counters.BytesOut += entry.DownstreamContentSize
+	switch entry.DownstreamStatus / 100 {
+	case 2:
+		counters.Status2xx++
+	case 3:
+		counters.Status3xx++
+	case 4:
+		counters.Status4xx++
+	case 5:
+		counters.Status5xx++
+	}
+}
+
+// resolveHost maps a request host to an instance ID, caching lookups for this run
+func (t *TrafficIngester) resolveHost(ctx context.Context, hosts map[string]*uuid.UUID, host string) *uuid.UUID {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	if id, ok := hosts[host]; ok {
+		return id
+	}
+
+	var id *uuid.UUID
+	if instance, err := t.store.FindInstanceBySubdomain(ctx, host); err == nil {
+		id = &instance.ID
+	}
+	hosts[host] = id
+
+	return id
+}
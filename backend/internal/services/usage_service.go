@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+
+	"pocketploy/internal/config"
+	"pocketploy/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// UsageService summarizes what an account uses against its limits
+type UsageService struct {
+	db        *sqlx.DB
+	instances InstanceStore
+	plans     PlanResolver
+	bandwidth *BandwidthService
+	tokens    *TokenService
+	config    *config.Config
+}
+
+// NewUsageService creates a new usage service
+func NewUsageService(db *sqlx.DB, instances InstanceStore, plans PlanResolver, bandwidth *BandwidthService, tokens *TokenService, cfg *config.Config) *UsageService {
+	return &UsageService{db: db, instances: instances, plans: plans, bandwidth: bandwidth, tokens: tokens, config: cfg}
+}
+
+// InstanceUsage counts an account's instances (failed ones excluded, as for the limit)
+type InstanceUsage struct {
+	Count int `json:"count"`
+	Limit int `json:"limit"` // 0 means unlimited
+}
+
+// DiskUsage is the storage used by an account's instances, from the latest
+// resource samples (so up to INSTANCE_METRICS_INTERVAL old)
+type DiskUsage struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	QuotaBytes int64 `json:"quota_bytes"` // 0 means unlimited
+}
+
+// AccountUsage is everything the dashboard home page shows about an account
+type AccountUsage struct {
+	Instances      InstanceUsage    `json:"instances"`
+	Disk           DiskUsage        `json:"disk"`
+	Bandwidth      *BandwidthStatus `json:"bandwidth"`
+	ActiveSessions int              `json:"active_sessions"`
+}
+
+// GetAccountUsage gathers the usage of an account
+func (s *UsageService) GetAccountUsage(ctx context.Context, userID string) (*AccountUsage, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bandwidth, err := s.bandwidth.GetUserBandwidth(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.instances.CountUserInstances(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	diskBytes, err := models.GetUserDiskUsage(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.plans.UserPlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.tokens.GetUserActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountUsage{
+		Instances:      InstanceUsage{Count: count, Limit: s.config.Settings().InstanceLimit(plan)},
+		Disk:           DiskUsage{UsedBytes: diskBytes},
+		Bandwidth:      bandwidth,
+		ActiveSessions: sessions,
+	}, nil
+}
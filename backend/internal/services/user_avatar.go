@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders accepted for uploads
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+)
+
+// avatarSize is the width and height avatars are stored at
+const avatarSize = 256
+
+// maxAvatarSourcePixels bounds the decoded size of an upload (about 40
+// megapixels), so small files can't expand into huge images
+const maxAvatarSourcePixels = 40_000_000
+
+// ErrInvalidAvatar is returned for uploads that aren't a usable image
+var ErrInvalidAvatar = errors.New("invalid avatar")
+
+// avatarFilePattern matches the file names generated by UploadAvatar
+var avatarFilePattern = regexp.MustCompile(`^[a-f0-9]{32}\.png$`)
+
+// UploadAvatar stores a JPEG, PNG or GIF as the user's profile picture. The
+// image is cropped to a square and resized; each upload gets a new file name
+// so browsers never show a cached old picture.
+func (s *UserService) UploadAvatar(userID string, body io.Reader) (*models.User, error) {
+	user, err := s.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read one byte past the limit so oversized uploads are reported as such
+	data, err := io.ReadAll(io.LimitReader(body, s.config.AvatarMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read upload", ErrInvalidAvatar)
+	}
+	if int64(len(data)) > s.config.AvatarMaxSize {
+		return nil, fmt.Errorf("%w: image must be at most %d bytes", ErrInvalidAvatar, s.config.AvatarMaxSize)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: image must be a JPEG, PNG or GIF", ErrInvalidAvatar)
+	}
+	if cfg.Width*cfg.Height > maxAvatarSourcePixels {
+		return nil, fmt.Errorf("%w: image dimensions are too large", ErrInvalidAvatar)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: image could not be decoded", ErrInvalidAvatar)
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, utils.SquareThumbnail(src, avatarSize)); err != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %w", err)
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return nil, fmt.Errorf("failed to generate avatar name: %w", err)
+	}
+	file := hex.EncodeToString(name) + ".png"
+
+	if err := os.MkdirAll(s.config.AvatarsPath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create avatars directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.config.AvatarsPath, file), encoded.Bytes(), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save avatar: %w", err)
+	}
+
+	if err := s.userRepo.SetAvatarFile(user.ID, &file); err != nil {
+		_ = os.Remove(filepath.Join(s.config.AvatarsPath, file))
+		return nil, err
+	}
+
+	s.removeAvatarFile(user.AvatarFile)
+	user.AvatarFile = &file
+
+	return user, nil
+}
+
+// DeleteAvatar removes the user's profile picture
+func (s *UserService) DeleteAvatar(userID string) (*models.User, error) {
+	user, err := s.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AvatarFile == nil {
+		return user, nil
+	}
+
+	if err := s.userRepo.SetAvatarFile(user.ID, nil); err != nil {
+		return nil, err
+	}
+
+	s.removeAvatarFile(user.AvatarFile)
+	user.AvatarFile = nil
+
+	return user, nil
+}
+
+// AvatarPath returns where an avatar file is stored, or false for names that
+// UploadAvatar can't have generated
+func (s *UserService) AvatarPath(file string) (string, bool) {
+	if !avatarFilePattern.MatchString(file) {
+		return "", false
+	}
+	return filepath.Join(s.config.AvatarsPath, file), true
+}
+
+// removeAvatarFile deletes a replaced avatar; failures only leave an orphan file
+func (s *UserService) removeAvatarFile(file *string) {
+	if file == nil {
+		return
+	}
+	path, ok := s.AvatarPath(*file)
+	if !ok {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to remove avatar %s: %v", path, err)
+	}
+}
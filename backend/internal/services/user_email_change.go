@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"pocketploy/internal/mail"
+	"pocketploy/internal/models"
+	"pocketploy/internal/utils"
+)
+
+// requestEmailChange starts a change of the user's address. Nothing changes
+// until the links mailed to both the current and the new address are opened
+// (see ConfirmEmailChange); a new request replaces a pending one.
+func (s *UserService) requestEmailChange(ctx context.Context, user *models.User, newEmail string) (*models.EmailChange, error) {
+	oldToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	newToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	change := &models.EmailChange{
+		UserID:       user.ID,
+		NewEmail:     newEmail,
+		OldTokenHash: utils.HashRefreshToken(oldToken),
+		NewTokenHash: utils.HashRefreshToken(newToken),
+		ExpiresAt:    time.Now().UTC().Add(s.config.EmailChangeTTL),
+	}
+	if err := s.userRepo.SaveEmailChange(ctx, change); err != nil {
+		return nil, err
+	}
+
+	expires := change.ExpiresAt.Format("2006-01-02 15:04 MST")
+	messages := []mail.Message{
+		{
+			To:      user.Email,
+			Subject: "Confirm your email change",
+			Body: fmt.Sprintf("A change of your Pocketploy account email from %s to %s was requested.\n\n"+
+				"Open this link to approve it:\n%s\n\n"+
+				"The change is only applied once the new address has been confirmed as well. "+
+				"The link expires at %s.\n\n"+
+				"If you didn't request this, ignore this email and change your password.\n",
+				user.Email, newEmail, s.emailChangeLink(oldToken), expires),
+		},
+		{
+			To:      newEmail,
+			Subject: "Confirm your new email address",
+			Body: fmt.Sprintf("Open this link to use %s for your Pocketploy account (%s):\n%s\n\n"+
+				"The change is only applied once it has been approved from %s as well. "+
+				"The link expires at %s.\n\n"+
+				"If you didn't request this, ignore this email.\n",
+				newEmail, user.Username, s.emailChangeLink(newToken), user.Email, expires),
+		},
+	}
+	for _, msg := range messages {
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			// Don't leave a change behind that can't be confirmed
+			_ = s.userRepo.DeleteEmailChange(ctx, user.ID)
+			return nil, err
+		}
+	}
+
+	return change, nil
+}
+
+// emailChangeLink returns the confirmation page URL for a token
+func (s *UserService) emailChangeLink(token string) string {
+	link, err := url.Parse(s.config.EmailChangeConfirmURL)
+	if err != nil {
+		return s.config.EmailChangeConfirmURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// GetEmailChange returns the user's pending email change, or nil
+func (s *UserService) GetEmailChange(ctx context.Context, userID string) (*models.EmailChange, error) {
+	return s.userRepo.GetEmailChange(ctx, userID)
+}
+
+// CancelEmailChange discards the user's pending email change
+func (s *UserService) CancelEmailChange(ctx context.Context, userID string) error {
+	return s.userRepo.DeleteEmailChange(ctx, userID)
+}
+
+// ConfirmEmailChange records the confirmation behind a mailed token. When it
+// completes the change, every session is signed out, since access tokens carry
+// the old address.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, token string) (*models.EmailChange, bool, error) {
+	change, applied, err := s.userRepo.ConfirmEmailChange(ctx, utils.HashRefreshToken(token))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if applied {
+		if err := s.tokenService.RevokeAllUserSessions(change.UserID); err != nil {
+			log.Printf("Warning: failed to revoke sessions of user %s after email change: %v", change.UserID, err)
+		}
+	}
+
+	return change, applied, nil
+}
@@ -1,39 +1,56 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"pocketploy/internal/config"
+	"pocketploy/internal/mail"
 	"pocketploy/internal/models"
 	"pocketploy/internal/repositories"
 	"pocketploy/internal/utils"
+
+	"github.com/google/uuid"
 )
 
 // UserService handles user management business logic
 type UserService struct {
-	userRepo *repositories.UserRepository
-	config   *config.Config
+	userRepo     *repositories.UserRepository
+	tokenService *TokenService
+	mailer       mail.Mailer
+	config       *config.Config
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *repositories.UserRepository, cfg *config.Config) *UserService {
+func NewUserService(userRepo *repositories.UserRepository, tokenService *TokenService, mailer mail.Mailer, cfg *config.Config) *UserService {
 	return &UserService{
-		userRepo: userRepo,
-		config:   cfg,
+		userRepo:     userRepo,
+		tokenService: tokenService,
+		mailer:       mailer,
+		config:       cfg,
 	}
 }
 
 // UpdateProfileParams contains parameters for updating user profile
 type UpdateProfileParams struct {
-	Username *string
-	Email    *string
+	Username    *string
+	Email       *string
+	DisplayName *string
+	Timezone    *string // IANA name, e.g. Europe/Berlin
+	Locale      *string
 }
 
 // UpdatePasswordParams contains parameters for updating user password
 type UpdatePasswordParams struct {
 	CurrentPassword string
 	NewPassword     string
+
+	// The session making the change stays signed in
+	SessionID string
+	IPAddress string
 }
 
 // GetUserProfile retrieves a user's profile by ID
@@ -50,20 +67,43 @@ func (s *UserService) GetUserProfile(userID string) (*models.User, error) {
 	return user, nil
 }
 
-// UpdateUserProfile updates a user's profile information
-func (s *UserService) UpdateUserProfile(userID string, params UpdateProfileParams) (*models.User, error) {
+// UserPlan returns the plan a user is on
+func (s *UserService) UserPlan(ctx context.Context, userID uuid.UUID) (string, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return "", err
+	}
+
+	return user.Plan, nil
+}
+
+// IsAdmin reports whether a user is an active platform administrator
+func (s *UserService) IsAdmin(userID string) (bool, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return false, fmt.Errorf("user not found")
+	}
+
+	return user.IsActive && user.IsAdmin, nil
+}
+
+// UpdateUserProfile updates a user's profile information. A new email address
+// isn't applied here: it's returned as a pending change that both the current
+// and the new address have to confirm.
+func (s *UserService) UpdateUserProfile(ctx context.Context, userID string, params UpdateProfileParams) (*models.User, *models.EmailChange, error) {
 	// Get current user
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, nil, fmt.Errorf("user not found")
 	}
 
 	if !user.IsActive {
-		return nil, fmt.Errorf("account is inactive")
+		return nil, nil, fmt.Errorf("account is inactive")
 	}
 
 	// Update fields if provided
 	updated := false
+	pendingEmail := ""
 
 	if params.Username != nil {
 		newUsername := strings.ToLower(strings.TrimSpace(*params.Username))
@@ -71,10 +111,10 @@ func (s *UserService) UpdateUserProfile(userID string, params UpdateProfileParam
 			// Check if username is already taken
 			exists, err := s.userRepo.ExistsByUsername(newUsername)
 			if err != nil {
-				return nil, fmt.Errorf("failed to check username: %w", err)
+				return nil, nil, fmt.Errorf("failed to check username: %w", err)
 			}
 			if exists {
-				return nil, fmt.Errorf("username already exists")
+				return nil, nil, fmt.Errorf("username already exists")
 			}
 			user.Username = newUsername
 			updated = true
@@ -87,28 +127,61 @@ func (s *UserService) UpdateUserProfile(userID string, params UpdateProfileParam
 			// Check if email is already taken
 			exists, err := s.userRepo.ExistsByEmail(newEmail)
 			if err != nil {
-				return nil, fmt.Errorf("failed to check email: %w", err)
+				return nil, nil, fmt.Errorf("failed to check email: %w", err)
 			}
 			if exists {
-				return nil, fmt.Errorf("email already exists")
+				return nil, nil, fmt.Errorf("email already exists")
 			}
-			user.Email = newEmail
+			pendingEmail = newEmail
+		}
+	}
+
+	if params.DisplayName != nil {
+		displayName := strings.TrimSpace(*params.DisplayName)
+		if displayName != user.DisplayName {
+			user.DisplayName = displayName
 			updated = true
 		}
 	}
 
+	if params.Timezone != nil {
+		timezone := strings.TrimSpace(*params.Timezone)
+		// LoadLocation also accepts "" and "Local", which mean the server's zone
+		if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+			return nil, nil, fmt.Errorf("invalid timezone")
+		}
+		if timezone != user.Timezone {
+			user.Timezone = timezone
+			updated = true
+		}
+	}
+
+	if params.Locale != nil && *params.Locale != user.Locale {
+		user.Locale = *params.Locale
+		updated = true
+	}
+
 	// Save if anything changed
 	if updated {
 		if err := s.userRepo.Update(user); err != nil {
-			return nil, fmt.Errorf("failed to update user: %w", err)
+			return nil, nil, fmt.Errorf("failed to update user: %w", err)
 		}
 	}
 
-	return user, nil
+	var change *models.EmailChange
+	if pendingEmail != "" {
+		change, err = s.requestEmailChange(ctx, user, pendingEmail)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to request email change: %w", err)
+		}
+	}
+
+	return user, change, nil
 }
 
-// UpdateUserPassword updates a user's password
-func (s *UserService) UpdateUserPassword(userID string, params UpdatePasswordParams) error {
+// UpdateUserPassword updates a user's password, signs out every other session
+// and emails the user about the change
+func (s *UserService) UpdateUserPassword(ctx context.Context, userID string, params UpdatePasswordParams) error {
 	// Get current user
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -128,6 +201,9 @@ func (s *UserService) UpdateUserPassword(userID string, params UpdatePasswordPar
 	if len(params.NewPassword) < 8 {
 		return fmt.Errorf("new password must be at least 8 characters long")
 	}
+	if utils.CheckPassword(params.NewPassword, user.PasswordHash) == nil {
+		return fmt.Errorf("new password must be different from the current password")
+	}
 
 	// Hash new password
 	newPasswordHash, err := utils.HashPassword(params.NewPassword, s.config.BcryptCost)
@@ -141,9 +217,37 @@ func (s *UserService) UpdateUserPassword(userID string, params UpdatePasswordPar
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	// Sign out every other session
+	if err := s.tokenService.RevokeOtherUserSessions(userID, params.SessionID); err != nil {
+		return err
+	}
+
+	s.sendPasswordChangedEmail(ctx, user, params.IPAddress)
+
 	return nil
 }
 
+// sendPasswordChangedEmail tells the user their password was changed, so an
+// unexpected change is noticed. Delivery failures are only logged.
+func (s *UserService) sendPasswordChangedEmail(ctx context.Context, user *models.User, ipAddress string) {
+	origin := ""
+	if ipAddress != "" {
+		origin = " from " + ipAddress
+	}
+
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("The password of your Pocketploy account (%s) was changed at %s%s.\n\n"+
+			"All other sessions have been signed out.\n\n"+
+			"If you didn't make this change, reset your password immediately and contact support.\n",
+			user.Username, time.Now().UTC().Format("2006-01-02 15:04 MST"), origin),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to send password change notification to user %s: %v", user.ID, err)
+	}
+}
+
 // DeactivateUser soft deletes a user account
 func (s *UserService) DeactivateUser(userID string) error {
 	// Get current user
@@ -164,6 +268,61 @@ func (s *UserService) DeactivateUser(userID string) error {
 	return nil
 }
 
+// VerifyPassword re-authenticates a user before a sensitive action
+func (s *UserService) VerifyPassword(userID, password string) error {
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if err := utils.CheckPassword(password, user.PasswordHash); err != nil {
+		return fmt.Errorf("password is incorrect")
+	}
+
+	return nil
+}
+
+// DeleteAccount deactivates a user's own account and signs out every session.
+// With REAUTH_REQUIRED_FOR_DELETION the account password has to be supplied.
+func (s *UserService) DeleteAccount(userID, password string) error {
+	if s.config.ReauthRequiredForDeletion {
+		if err := s.VerifyPassword(userID, password); err != nil {
+			return err
+		}
+	}
+
+	if err := s.DeactivateUser(userID); err != nil {
+		return err
+	}
+
+	return s.tokenService.RevokeAllUserSessions(userID)
+}
+
+// HardDeleteUser removes a user and everything stored with their account,
+// signing out every session first (admin function). Their instances must
+// already be gone; see InstanceService.PurgeUserInstances.
+func (s *UserService) HardDeleteUser(userID string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if err := s.tokenService.RevokeAllUserSessions(userID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.HardDelete(userID); err != nil {
+		return err
+	}
+
+	s.removeAvatarFile(user.AvatarFile)
+	return nil
+}
+
 // GetUserByEmail retrieves a user by email (admin function)
 func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"pocketploy/internal/cache"
+	"pocketploy/internal/config"
+	"pocketploy/internal/jobs"
+	"pocketploy/internal/models"
+	"pocketploy/internal/secrets"
+	"pocketploy/internal/utils"
+	"pocketploy/internal/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// JobWebhookDelivery sends one webhook delivery
+const JobWebhookDelivery = "webhook.deliver"
+
+const (
+	// WebhookSecretPrefix marks webhook signing secrets
+	WebhookSecretPrefix = "whsec_"
+
+	maxWebhooksPerUser      = 10
+	webhookDeliveryAttempts = 8 // about 20 minutes of retries with the queue's backoff
+	webhookDeliveryHistory  = 50
+)
+
+// ErrWebhookLimit is returned when a user already has the maximum number of webhooks
+var ErrWebhookLimit = fmt.Errorf("a user can have at most %d webhooks", maxWebhooksPerUser)
+
+// webhookDeliveryJob is the payload of a JobWebhookDelivery job
+type webhookDeliveryJob struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// WebhookEvent is the JSON body of a delivery
+type WebhookEvent struct {
+	ID        uuid.UUID       `json:"id"` // the delivery ID, also sent as X-Pocketploy-Delivery
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// InstanceStatusChange is the data of an instance.status_changed event
+type InstanceStatusChange struct {
+	Instance       *models.Instance `json:"instance"`
+	PreviousStatus string           `json:"previous_status"`
+}
+
+// WebhookService manages users' webhook endpoints and delivers instance
+// lifecycle events to them through the job queue
+type WebhookService struct {
+	db     *sqlx.DB
+	jobs   *jobs.Queue
+	store  cache.Store
+	sender *webhook.Sender
+	vault  *secrets.Vault // signing secrets are stored encrypted
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(db *sqlx.DB, jobQueue *jobs.Queue, store cache.Store, vault *secrets.Vault, cfg *config.Config) *WebhookService {
+	return &WebhookService{
+		db:     db,
+		jobs:   jobQueue,
+		store:  store,
+		sender: webhook.NewSender(cfg.WebhookTimeout, cfg.WebhookAllowPrivateTargets),
+		vault:  vault,
+	}
+}
+
+// CreateEndpoint registers a webhook endpoint. The signing secret is only
+// returned here (and when it is rotated).
+func (s *WebhookService) CreateEndpoint(ctx context.Context, userID uuid.UUID, req models.CreateWebhookRequest) (*models.WebhookEndpoint, string, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, "", err
+	}
+
+	count, err := models.CountWebhookEndpoints(ctx, s.db, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, "", ErrWebhookLimit
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	encrypted, err := s.vault.Encrypt(ctx, secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events := req.Events
+	if events == nil {
+		events = []string{}
+	}
+	endpoint := &models.WebhookEndpoint{
+		UserID: userID,
+		URL:    req.URL,
+		Secret: encrypted,
+		Events: pq.StringArray(events),
+	}
+	if err := models.CreateWebhookEndpoint(ctx, s.db, endpoint); err != nil {
+		return nil, "", err
+	}
+
+	return endpoint, secret, nil
+}
+
+// ListEndpoints returns a user's webhook endpoints
+func (s *WebhookService) ListEndpoints(ctx context.Context, userID uuid.UUID) ([]models.WebhookEndpoint, error) {
+	return models.ListWebhookEndpoints(ctx, s.db, userID)
+}
+
+// DeleteEndpoint removes one of a user's webhook endpoints
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, endpointID, userID uuid.UUID) error {
+	return models.DeleteWebhookEndpoint(ctx, s.db, endpointID, userID)
+}
+
+// RotateSecret replaces an endpoint's signing secret. Deliveries sent from now
+// on, including redeliveries of older events, are signed with the new one.
+func (s *WebhookService) RotateSecret(ctx context.Context, endpointID, userID uuid.UUID) (string, error) {
+	endpoint, err := models.FindWebhookEndpoint(ctx, s.db, endpointID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := s.vault.Encrypt(ctx, secret)
+	if err != nil {
+		return "", err
+	}
+	if err := endpoint.UpdateSecret(ctx, s.db, encrypted); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// ListDeliveries returns an endpoint's most recent deliveries
+func (s *WebhookService) ListDeliveries(ctx context.Context, endpointID, userID uuid.UUID) ([]models.WebhookDelivery, error) {
+	if _, err := models.FindWebhookEndpoint(ctx, s.db, endpointID, userID); err != nil {
+		return nil, err
+	}
+	return models.ListWebhookDeliveries(ctx, s.db, endpointID, webhookDeliveryHistory)
+}
+
+// Redeliver sends a delivery again with the same delivery ID, e.g. after the
+// consumer was down for longer than the retries lasted
+func (s *WebhookService) Redeliver(ctx context.Context, endpointID, deliveryID, userID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := models.FindWebhookEndpoint(ctx, s.db, endpointID, userID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := models.FindWebhookDelivery(ctx, s.db, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.EndpointID != endpointID {
+		return nil, models.ErrWebhookDeliveryNotFound
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		return nil, fmt.Errorf("delivery is already pending")
+	}
+
+	if err := delivery.ResetForRedelivery(ctx, s.db); err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(ctx, delivery.ID); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Dispatch records an event for every endpoint of the user subscribed to it
+// and queues the deliveries. Failures are only logged: webhooks must not
+// break the operation that triggered them.
+func (s *WebhookService) Dispatch(ctx context.Context, userID uuid.UUID, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Warning: failed to encode %s webhook: %v", eventType, err)
+		return
+	}
+
+	deliveries, err := models.CreateWebhookDeliveries(ctx, s.db, userID, eventType, payload)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if err := s.enqueue(ctx, delivery.ID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// InstanceChanged turns instance writes into lifecycle events: creation,
+// status changes and deletion. The last status seen is kept in the cache
+// store (shared when Redis is configured), so other writes to an instance
+// don't trigger events.
+func (s *WebhookService) InstanceChanged(ctx context.Context, instance *models.Instance, deleted bool) {
+	key := "webhook:instance_status:" + instance.ID.String()
+
+	if deleted {
+		_ = s.store.Delete(ctx, key)
+		s.Dispatch(ctx, instance.UserID, models.WebhookEventInstanceDeleted, instance)
+		return
+	}
+
+	previous, found, err := s.store.Get(ctx, key)
+	if err != nil {
+		log.Printf("Warning: failed to read instance status for webhooks: %v", err)
+		return
+	}
+	if found && string(previous) == instance.Status {
+		return
+	}
+	if err := s.store.Set(ctx, key, []byte(instance.Status), 0); err != nil {
+		log.Printf("Warning: failed to record instance status for webhooks: %v", err)
+	}
+
+	switch {
+	case instance.Status == models.InstanceStatusCreating && !found:
+		s.Dispatch(ctx, instance.UserID, models.WebhookEventInstanceCreated, instance)
+	case found:
+		s.Dispatch(ctx, instance.UserID, models.WebhookEventInstanceStatusChanged, InstanceStatusChange{
+			Instance:       instance,
+			PreviousStatus: string(previous),
+		})
+	}
+}
+
+// HandleDeliveryJob sends a delivery. Failed attempts are retried by the
+// queue with backoff; the delivery is marked failed once they run out.
+func (s *WebhookService) HandleDeliveryJob(ctx context.Context, job *jobs.Job) error {
+	var payload webhookDeliveryJob
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid webhook delivery payload: %w", err)
+	}
+
+	delivery, err := models.FindWebhookDelivery(ctx, s.db, payload.DeliveryID)
+	if errors.Is(err, models.ErrWebhookDeliveryNotFound) {
+		return nil // the endpoint was deleted
+	} else if err != nil {
+		return err
+	}
+
+	endpoint, err := models.FindWebhookEndpointByID(ctx, s.db, delivery.EndpointID)
+	if errors.Is(err, models.ErrWebhookNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(WebhookEvent{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook: %w", err)
+	}
+	secret, err := s.vault.Decrypt(ctx, endpoint.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	resp, sendErr := s.sender.Send(ctx, webhook.Request{
+		URL:        endpoint.URL,
+		Secret:     secret,
+		DeliveryID: delivery.ID.String(),
+		Event:      delivery.EventType,
+		Body:       body,
+	})
+	if sendErr == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		sendErr = fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	status := models.WebhookDeliverySucceeded
+	var responseStatus *int
+	var responseBody, lastError *string
+	if resp != nil {
+		responseStatus, responseBody = &resp.StatusCode, &resp.Body
+	}
+	if sendErr != nil {
+		message := sendErr.Error()
+		lastError = &message
+		status = models.WebhookDeliveryPending
+		if job.Attempts >= job.MaxAttempts {
+			status = models.WebhookDeliveryFailed
+		}
+	}
+
+	if err := delivery.RecordAttempt(ctx, s.db, status, responseStatus, responseBody, lastError); err != nil {
+		return err
+	}
+
+	return sendErr
+}
+
+// enqueue queues a delivery to be sent
+func (s *WebhookService) enqueue(ctx context.Context, deliveryID uuid.UUID) error {
+	_, err := s.jobs.Enqueue(ctx, JobWebhookDelivery, webhookDeliveryJob{DeliveryID: deliveryID}, jobs.EnqueueOptions{
+		MaxAttempts: webhookDeliveryAttempts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// validateWebhookURL requires an absolute http(s) URL. Where it resolves to is
+// checked when connecting, since DNS can change after registration.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	return nil
+}
+
+// generateWebhookSecret creates a new signing secret
+func generateWebhookSecret() (string, error) {
+	secret, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return WebhookSecretPrefix + secret, nil
+}
@@ -0,0 +1,48 @@
+package sigv4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testCredentials = Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+var testTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+// TestSignVanilla checks the get-vanilla case of the AWS SigV4 test suite
+func TestSignVanilla(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	Sign(req, nil, testCredentials, "us-east-1", "service", testTime)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "" {
+		t.Error("X-Amz-Security-Token is set without a session token")
+	}
+}
+
+func TestSignSessionToken(t *testing.T) {
+	credentials := testCredentials
+	credentials.SessionToken = "session-token"
+
+	req, _ := http.NewRequest(http.MethodPost, "https://kms.us-east-1.amazonaws.com/", nil)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Encrypt")
+	Sign(req, []byte("{}"), credentials, "us-east-1", "kms", testTime)
+
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session-token" {
+		t.Errorf("X-Amz-Security-Token = %q, want the session token", got)
+	}
+	want := "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target,"
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, want) {
+		t.Errorf("Authorization = %q, want it to contain %q", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+)
+
+// SquareThumbnail crops the centre square of src and scales it to size×size.
+// Each output pixel averages the source pixels it covers, which keeps
+// downscaled photos smooth without pulling in an imaging library.
+func SquareThumbnail(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0 := y0 + y*side/size
+		sy1 := y0 + (y+1)*side/size
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < size; x++ {
+			sx0 := x0 + x*side/size
+			sx1 := x0 + (x+1)*side/size
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R) * uint64(c.A)
+					g += uint64(c.G) * uint64(c.A)
+					b += uint64(c.B) * uint64(c.A)
+					a += uint64(c.A)
+					n++
+				}
+			}
+
+			// Weight colours by alpha so transparent pixels don't darken edges
+			var out color.NRGBA
+			if a > 0 {
+				out = color.NRGBA{
+					R: uint8(r / a >> 8),
+					G: uint8(g / a >> 8),
+					B: uint8(b / a >> 8),
+					A: uint8(a / n >> 8),
+				}
+			}
+			dst.SetNRGBA(x, y, out)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// trustedProxies are the networks whose X-Forwarded-For entries are believed.
+// Until ConfigureTrustedProxies is called no proxy is trusted.
+var trustedProxies atomic.Pointer[[]netip.Prefix]
+
+// ConfigureTrustedProxies sets the networks of the reverse proxies in front of
+// the backend (TRUSTED_PROXIES)
+func ConfigureTrustedProxies(prefixes []netip.Prefix) {
+	trustedProxies.Store(&prefixes)
+}
+
+// isTrustedProxy reports whether addr belongs to a trusted proxy
+func isTrustedProxy(addr netip.Addr) bool {
+	prefixes := trustedProxies.Load()
+	if prefixes == nil {
+		return false
+	}
+	for _, prefix := range *prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent the request. The
+// connection's peer is used unless it is a trusted proxy; X-Forwarded-For is
+// then walked from the right (the entries appended by our own proxies) to the
+// first address that is not a trusted proxy, so clients cannot spoof it by
+// sending the header themselves. IPv6 addresses are returned without brackets
+// or zone, and IPv4-mapped IPv6 addresses as IPv4, as expected by PostgreSQL's
+// INET type.
+func ClientIP(r *http.Request) string {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	client := peer
+	if isTrustedProxy(peer) {
+		hops := r.Header.Values("X-Forwarded-For")
+		for i := len(hops) - 1; i >= 0 && isTrustedProxy(client); i-- {
+			entries := strings.Split(hops[i], ",")
+			for j := len(entries) - 1; j >= 0 && isTrustedProxy(client); j-- {
+				addr, ok := parseIP(entries[j])
+				if !ok {
+					// A malformed entry ends the chain we can vouch for
+					return client.String()
+				}
+				client = addr
+			}
+		}
+	}
+
+	return client.String()
+}
+
+// parseIP parses an address with or without port ("203.0.113.7",
+// "203.0.113.7:443", "[2001:db8::1]:443", "2001:db8::1")
+func parseIP(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.Trim(value, "[]")
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap().WithZone(""), true
+}
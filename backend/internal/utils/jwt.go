@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Claims represents JWT claims
@@ -14,23 +15,57 @@ type Claims struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Type     string `json:"type"`
+
+	// SessionID is the ID of the refresh token the access token was issued for
+	SessionID string `json:"sid,omitempty"`
+
+	// ImpersonatorID is set on tokens an admin minted to act as the user
+	ImpersonatorID string `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateAccessToken generates a new JWT access token
-func GenerateAccessToken(userID, username, email, secret string, expiry time.Duration) (string, error) {
-	now := time.Now()
+// Impersonated reports whether the token was minted for an admin acting as the user
+func (c *Claims) Impersonated() bool {
+	return c.ImpersonatorID != ""
+}
+
+// GenerateAccessToken generates a new JWT access token for a session
+func GenerateAccessToken(userID, username, email, sessionID, secret string, expiry time.Duration) (string, error) {
+	return signAccessToken(&Claims{
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Type:      "access",
+		SessionID: sessionID,
+	}, secret, expiry)
+}
+
+// GenerateImpersonationToken generates an access token that lets an admin act
+// as a user. It has no session, so it can't be refreshed.
+func GenerateImpersonationToken(userID, username, email, impersonatorID, secret string, expiry time.Duration) (string, *Claims, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Type:     "access",
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   userID,
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
-			NotBefore: jwt.NewNumericDate(now),
-		},
+		UserID:         userID,
+		Username:       username,
+		Email:          email,
+		Type:           "access",
+		ImpersonatorID: impersonatorID,
+	}
+	token, err := signAccessToken(claims, secret, expiry)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// signAccessToken fills in the registered claims and signs the token
+func signAccessToken(claims *Claims, secret string, expiry time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(), // jti, used to revoke individual tokens
+		Subject:   claims.UserID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+		NotBefore: jwt.NewNumericDate(now),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -61,8 +96,3 @@ func ValidateAccessToken(tokenString, secret string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
-
-// ParseDuration parses a duration string (e.g., "15m", "24h")
-func ParseDuration(s string) (time.Duration, error) {
-	return time.ParseDuration(s)
-}
@@ -0,0 +1,37 @@
+package utils
+
+import "strings"
+
+// defaultReservedNames are names that must never become part of a public hostname
+// because they impersonate platform services or common infrastructure
+var defaultReservedNames = []string{
+	"admin", "administrator", "api", "app", "assets", "auth", "billing",
+	"blog", "cdn", "dashboard", "dev", "docs", "ftp", "help", "imap",
+	"localhost", "login", "mail", "metrics", "mx", "ns", "ns1", "ns2",
+	"pocketbase", "pocketploy", "pop", "pop3", "postmaster", "root",
+	"signup", "smtp", "staging", "static", "status", "support", "system",
+	"test", "traefik", "webmail", "www",
+}
+
+// IsReservedName reports whether name is reserved, either by default or through
+// extra, a comma-separated list of additional reserved names
+func IsReservedName(name, extra string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return false
+	}
+
+	for _, reserved := range defaultReservedNames {
+		if name == reserved {
+			return true
+		}
+	}
+
+	for _, reserved := range strings.Split(extra, ",") {
+		if name == strings.ToLower(strings.TrimSpace(reserved)) {
+			return true
+		}
+	}
+
+	return false
+}
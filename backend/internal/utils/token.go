@@ -5,8 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 )
 
+// passwordAlphabet avoids characters that need quoting in shells or URLs
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
 // GenerateRefreshToken generates a secure random refresh token
 func GenerateRefreshToken() (string, error) {
 	// Generate 32 random bytes
@@ -24,3 +28,24 @@ func HashRefreshToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(hash[:])
 }
+
+// GenerateSecurePassword generates a random password of the given length
+func GenerateSecurePassword(length int) (string, error) {
+	return GenerateRandomString(length)
+}
+
+// GenerateRandomString generates a random, unambiguous alphanumeric string
+func GenerateRandomString(length int) (string, error) {
+	result := make([]byte, length)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+
+	for i := range result {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random string: %w", err)
+		}
+		result[i] = passwordAlphabet[n.Int64()]
+	}
+
+	return string(result), nil
+}
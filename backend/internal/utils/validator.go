@@ -15,6 +15,7 @@ func init() {
 	// Register custom validators
 	validate.RegisterValidation("alphanum_hyphen", validateAlphanumHyphen)
 	validate.RegisterValidation("password_strength", validatePasswordStrength)
+	validate.RegisterValidation("basic_auth_user", validateBasicAuthUser)
 }
 
 // ValidateStruct validates a struct using validator tags
@@ -29,6 +30,14 @@ func validateAlphanumHyphen(fl validator.FieldLevel) bool {
 	return matched
 }
 
+// validateBasicAuthUser validates a basic auth username. The users list
+// Traefik is given separates users with commas and the username from the
+// password hash with a colon, so only a plain character set is allowed.
+func validateBasicAuthUser(fl validator.FieldLevel) bool {
+	matched, _ := regexp.MatchString(`^[A-Za-z0-9._@+-]+$`, fl.Field().String())
+	return matched
+}
+
 // validatePasswordStrength validates password strength
 func validatePasswordStrength(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
@@ -80,6 +89,10 @@ func GetValidationErrors(err error) map[string]string {
 				errors[field] = field + " must be at most " + fieldError.Param() + " characters"
 			case "alphanum_hyphen":
 				errors[field] = field + " must contain only lowercase letters, numbers, and hyphens"
+			case "basic_auth_user":
+				errors[field] = field + " must contain only letters, numbers, and . _ @ + -"
+			case "bcp47_language_tag":
+				errors[field] = field + " must be a language tag such as en or pt-BR"
 			case "password_strength":
 				errors[field] = "Password must contain at least one uppercase letter, one lowercase letter, one number, and one special character"
 			default:
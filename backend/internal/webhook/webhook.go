@@ -0,0 +1,173 @@
+// Package webhook signs and sends webhook deliveries.
+//
+// Every delivery is a POST with a JSON body and these headers:
+//
+//	X-Pocketploy-Delivery:  unique delivery ID (kept on redelivery, so consumers can drop duplicates)
+//	X-Pocketploy-Event:     event type, e.g. instance.status_changed
+//	X-Pocketploy-Timestamp: Unix time the request was signed
+//	X-Pocketploy-Signature: v1=<hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the endpoint secret>
+//
+// Consumers recompute the signature over the raw body, compare it in constant
+// time and reject timestamps older than a few minutes to stop replays. In Go:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	err := webhook.Verify(secret, r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, 5*time.Minute)
+//
+// In Node.js:
+//
+//	const expected = "v1=" + crypto.createHmac("sha256", secret).update(`${timestamp}.${rawBody}`).digest("hex");
+//	const valid = crypto.timingSafeEqual(Buffer.from(expected), Buffer.from(signature))
+//	  && Math.abs(Date.now() / 1000 - Number(timestamp)) < 300;
+//
+// In Python:
+//
+//	expected = "v1=" + hmac.new(secret.encode(), f"{timestamp}.".encode() + raw_body, hashlib.sha256).hexdigest()
+//	valid = hmac.compare_digest(expected, signature) and abs(time.time() - int(timestamp)) < 300
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Delivery headers
+const (
+	HeaderDelivery  = "X-Pocketploy-Delivery"
+	HeaderEvent     = "X-Pocketploy-Event"
+	HeaderTimestamp = "X-Pocketploy-Timestamp"
+	HeaderSignature = "X-Pocketploy-Signature"
+
+	signatureVersion = "v1="
+)
+
+// maxResponseBody is how much of a consumer's response is kept for debugging
+const maxResponseBody = 1024
+
+// ErrPrivateAddress is returned when a webhook URL resolves to an address on
+// the platform's own network
+var ErrPrivateAddress = errors.New("webhook URL resolves to a private address")
+
+// Sign returns the signature header value for a body signed at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and that it was signed within tolerance
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid webhook timestamp")
+	}
+
+	age := time.Since(time.Unix(signedAt, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("webhook timestamp is outside the tolerance")
+	}
+
+	if !hmac.Equal([]byte(Sign(secret, signedAt, body)), []byte(signature)) {
+		return fmt.Errorf("invalid webhook signature")
+	}
+
+	return nil
+}
+
+// Request is a delivery to send
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryID string
+	Event      string
+	Body       []byte
+}
+
+// Response is what the consumer answered
+type Response struct {
+	StatusCode int
+	Body       string // truncated
+}
+
+// Sender posts signed deliveries
+type Sender struct {
+	httpClient *http.Client
+}
+
+// NewSender creates a sender. Unless allowPrivate is set, connections to
+// loopback, private and link-local addresses are refused, so users can't
+// point webhooks at the platform's internal services.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = rejectPrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Sender{
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// A redirect would be followed without the signature check on our side
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send signs and posts a delivery. A response is returned whenever the
+// consumer answered, whatever the status code.
+func (s *Sender) Send(ctx context.Context, req Request) (*Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "Pocketploy-Webhooks/1")
+	httpReq.Header.Set(HeaderDelivery, req.DeliveryID)
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return &Response{StatusCode: resp.StatusCode, Body: string(body)}, nil
+}
+
+// rejectPrivate refuses connections to addresses that aren't publicly routable.
+// It runs after DNS resolution, so hostnames resolving to them are caught too.
+func rejectPrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
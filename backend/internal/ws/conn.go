@@ -0,0 +1,138 @@
+// Package ws pushes JSON messages to browsers over WebSocket connections
+// (gorilla/websocket underneath). Client messages other than control frames
+// are read and discarded.
+package ws
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxClientMessage bounds the messages read from clients
+const maxClientMessage = 64 * 1024
+
+// writeTimeout bounds a single frame write
+const writeTimeout = 10 * time.Second
+
+// ErrClosed is returned when writing to a closed connection
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Upgrader accepts WebSocket connections from browsers on the API's own
+// origin and on the origins originAllowed accepts. Requests without an
+// Origin header don't come from browsers and are accepted.
+type Upgrader struct {
+	originAllowed func(origin string) bool
+}
+
+// NewUpgrader creates an upgrader that checks origins with originAllowed
+func NewUpgrader(originAllowed func(origin string) bool) *Upgrader {
+	return &Upgrader{originAllowed: originAllowed}
+}
+
+// Upgrade performs the opening handshake and takes over the connection. On
+// failure an error response has already been written. protocol, if not
+// empty, is echoed in Sec-WebSocket-Protocol.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, protocol string) (*Conn, error) {
+	upgrader := websocket.Upgrader{CheckOrigin: u.checkOrigin}
+	if protocol != "" {
+		upgrader.Subprotocols = []string{protocol}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(maxClientMessage)
+
+	return &Conn{conn: conn}, nil
+}
+
+func (u *Upgrader) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	return u.originAllowed != nil && u.originAllowed(origin)
+}
+
+// Conn is an upgraded WebSocket connection. Writes are safe for concurrent use.
+type Conn struct {
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// WriteJSON sends v as a text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.conn.WriteJSON(v); err != nil {
+		c.closed = true
+		c.conn.Close()
+		return err
+	}
+	return nil
+}
+
+// Ping sends a ping control frame
+func (c *Conn) Ping() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+// Close sends a normal closure frame and closes the connection
+func (c *Conn) Close() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
+	return c.conn.Close()
+}
+
+// ReadLoop reads client messages until the connection closes, answering
+// pings and close frames. idleTimeout is the longest allowed silence from
+// the client; pongs to Ping count as activity. It returns the reason the
+// loop ended.
+func (c *Conn) ReadLoop(idleTimeout time.Duration) error {
+	defer c.Close()
+
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	})
+
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+
+		// The next call discards whatever is left of this message
+		if _, _, err := c.conn.NextReader(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer upgrades every request, sends one message and waits for the client to leave
+func newTestServer(t *testing.T, originAllowed func(string) bool) *httptest.Server {
+	upgrader := NewUpgrader(originAllowed)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, "bearer")
+		if err != nil {
+			return
+		}
+		if err := conn.WriteJSON(map[string]string{"type": "hello"}); err != nil {
+			t.Errorf("WriteJSON() error = %v", err)
+		}
+		conn.ReadLoop(time.Second)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpgradeSendsJSON(t *testing.T) {
+	server := newTestServer(t, nil)
+	header := http.Header{"Sec-WebSocket-Protocol": {"bearer, token"}}
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "bearer" {
+		t.Errorf("Sec-WebSocket-Protocol = %q, want bearer", got)
+	}
+	var message map[string]string
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	if message["type"] != "hello" {
+		t.Errorf("message = %v, want the hello message", message)
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	allowed := func(origin string) bool { return origin == "https://app.example.com" }
+	server := newTestServer(t, allowed)
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{server.URL, true},
+		{"https://app.example.com", true},
+		{"https://evil.example.com", false},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if conn != nil {
+			conn.Close()
+		}
+		if (err == nil) != tt.want {
+			t.Errorf("origin %q: error = %v, want accepted %v", tt.origin, err, tt.want)
+		}
+		if !tt.want && resp != nil && resp.StatusCode != http.StatusForbidden {
+			t.Errorf("origin %q: status = %d, want %d", tt.origin, resp.StatusCode, http.StatusForbidden)
+		}
+	}
+}
@@ -113,6 +113,56 @@ MIGRATION_FILES=(
     "003_create_instances_table.sql"
     "004_create_instances_archive_table.sql"
     "005_update_instances_status_constraint.sql"
+    "006_create_invite_codes_table.sql"
+    "007_add_instance_failure_details.sql"
+    "008_add_instance_search_indexes.sql"
+    "009_add_instance_pending_deletion.sql"
+    "010_create_instance_metrics_table.sql"
+    "011_create_instance_traffic_tables.sql"
+    "012_add_bandwidth_quotas.sql"
+    "013_create_platform_status_table.sql"
+    "014_create_platform_health_samples_table.sql"
+    "015_create_instance_crons_tables.sql"
+    "016_create_jobs_table.sql"
+    "017_enforce_unique_subdomains.sql"
+    "018_create_platform_settings_table.sql"
+    "019_create_regions_table.sql"
+    "020_add_instance_serve_options.sql"
+    "021_add_instance_suspension.sql"
+    "022_add_instance_access_protection.sql"
+    "023_create_schema_migrations_table.sql"
+    "024_add_instance_host_port.sql"
+    "025_create_subscriptions_table.sql"
+    "026_create_usage_metering_table.sql"
+    "027_create_credits_tables.sql"
+    "028_create_instance_tags_table.sql"
+    "029_add_instance_ordering.sql"
+    "030_add_instance_description.sql"
+    "031_add_user_profile_fields.sql"
+    "032_create_email_changes_table.sql"
+    "033_create_audit_log_table.sql"
+    "034_create_abuse_reports_table.sql"
+    "035_add_instance_quarantine.sql"
+    "036_create_deploy_tokens_table.sql"
+    "037_create_instance_migration_runs_table.sql"
+    "038_add_instance_expiry.sql"
+    "039_add_instance_previews.sql"
+    "040_add_instance_deletion_protection.sql"
+    "041_add_instance_status_tokens.sql"
+    "042_create_webhooks_tables.sql"
+    "043_create_instance_integrity_checks_table.sql"
+    "044_create_instance_images_tables.sql"
+    "045_create_instance_builds_table.sql"
+    "046_create_admin_operations_table.sql"
+    "047_add_session_devices.sql"
+    "048_add_session_revoke_tokens.sql"
+    "049_add_geoip_locations.sql"
+    "050_create_instance_anomalies_table.sql"
+    "051_add_instance_egress_policy.sql"
+    "052_create_instance_storage_table.sql"
+    "053_widen_secret_columns.sql"
+    "054_add_instance_builds_in_progress_index.sql"
+    "055_keep_traffic_of_deleted_instances.sql"
 )
 
 for migration in "${MIGRATION_FILES[@]}"; do